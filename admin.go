@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+	"github.com/abrookins/radar/internal/usage"
+)
+
+var usageFilename = flag.String("usage", "", "file to keep query usage counts in across restarts")
+var warmCells = flag.Int("warm-cells", 0, "number of the most searched cells to keep precomputed searches for, or 0 for none")
+
+// How often usage counts are saved to the -usage file.
+const USAGE_SAVE_INTERVAL = time.Minute
+
+// How often the search cache is rewarmed, so that it follows the most
+// searched cells as they change.
+const WARM_INTERVAL = 10 * time.Minute
+
+// The number of cells /admin/usage lists unless it's given a limit.
+const DEFAULT_USAGE_LIMIT = 20
+
+// tracker counts where crimes are searched for. Until main loads it from a
+// file, it's only kept in memory.
+var tracker, _ = usage.NewTracker(usage.DEFAULT_CELL_SIZE, "")
+
+// saveUsage saves the usage counts every interval, forever.
+func saveUsage(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := tracker.Save(); err != nil {
+			log.Println("Could not save usage. ", err)
+		}
+	}
+}
+
+// warmCache fills the finder's search cache for the most searched cells.
+// It's called after data is loaded, since loading empties the cache.
+func warmCache() {
+	if *warmCells < 1 {
+		return
+	}
+	cells := tracker.Top(*warmCells)
+	points := make([]radar.Point, 0, len(cells))
+	for _, cell := range cells {
+		points = append(points, radar.Point{Lat: cell.Lat, Lng: cell.Lng})
+	}
+	finderLock.RLock()
+	warmed := finder.Warm(points)
+	finderLock.RUnlock()
+	log.Printf("Warmed the search cache for %v cells", warmed)
+}
+
+// rewarmCache warms the search cache every interval, forever.
+func rewarmCache(interval time.Duration) {
+	for range time.Tick(interval) {
+		warmCache()
+	}
+}
+
+// usageHandler reports the most searched areas and the busiest times.
+func usageHandler(w http.ResponseWriter, r *http.Request) {
+	limit := DEFAULT_USAGE_LIMIT
+	if param := r.URL.Query().Get("limit"); param != "" {
+		var err error
+		if limit, err = strconv.Atoi(param); err != nil || limit < 1 {
+			http.Error(w, http.StatusText(400), 400)
+			return
+		}
+	}
+	report := tracker.Report(limit)
+	count := len(report.Cells)
+	writeValue(w, r, 200, report, responseMeta{Query: map[string]interface{}{"limit": limit}, Count: &count})
+}
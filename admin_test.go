@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/abrookins/radar/crimes/sample"
+	"github.com/abrookins/radar/internal/usage"
+)
+
+func TestUsage(t *testing.T) {
+	tracker, _ = usage.NewTracker(usage.DEFAULT_CELL_SIZE, "")
+	get(t, "/crimes/near/45.5184/-122.6554")
+	get(t, "/crimes/near/45.5186/-122.6556")
+	get(t, "/crimes/near/45.4/-122.6")
+
+	resp := get(t, "/admin/usage?limit=1")
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	var report usage.Report
+	if err := json.Unmarshal(data(t, resp), &report); err != nil {
+		t.Fatal("Response was not a usage report: ", resp.Body.String())
+	}
+	if report.Total != 3 || len(report.Cells) != 1 || report.Cells[0].Count != 2 {
+		t.Error("Wrong usage report: ", report)
+	}
+	if resp := get(t, "/admin/usage?limit=none"); resp.Code != 400 {
+		t.Error("Wrong status code for a bad limit: ", resp.Code)
+	}
+}
+
+func TestWarmCache(t *testing.T) {
+	defer func() {
+		*warmCells = 0
+		finder = sample.NewFinder()
+		updateDatasetVersion()
+	}()
+	tracker, _ = usage.NewTracker(usage.DEFAULT_CELL_SIZE, "")
+	get(t, "/crimes/near/45.53435699129174/-122.66469510763777")
+	*warmCells = 1
+	finder.EnableCache(tracker.CellSize())
+	warmCache()
+
+	resp := get(t, "/crimes/near/45.5341/-122.6641?explain=true")
+	var body struct {
+		Explain struct{ Cache string }
+	}
+	if err := json.Unmarshal(data(t, resp), &body); err != nil || body.Explain.Cache != "hit" {
+		t.Error("Search from a warm cell should hit the cache: ", resp.Body.String())
+	}
+}
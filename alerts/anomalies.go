@@ -0,0 +1,83 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// An AnomalyNotifier tells a geofence's target that cells of the grid
+// inside the geofence have unusually many recent crimes.
+type AnomalyNotifier interface {
+	NotifyAnomalies(geofence Geofence, report radar.AnomalyReport) error
+}
+
+// NotifyAnomalies POSTs the anomalies in report as JSON to a geofence's
+// webhook URL:
+//
+//	{"geofence": {...}, "anomalies": {"from": ..., "to": ..., "anomalies": [...]}}
+func (notifier *WebhookNotifier) NotifyAnomalies(geofence Geofence, report radar.AnomalyReport) error {
+	reportJson, err := report.ToJson()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(struct {
+		Geofence  Geofence        `json:"geofence"`
+		Anomalies json.RawMessage `json:"anomalies"`
+	}{geofence, reportJson})
+	if err != nil {
+		return err
+	}
+	resp, err := notifier.Client.Post(geofence.Target.Webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook for geofence %v returned %v", geofence.Id, resp.Status)
+	}
+	return nil
+}
+
+// MatchAnomalies returns the anomalies in report whose cells' centers are
+// inside the geofence.
+func (geofence *Geofence) MatchAnomalies(report radar.AnomalyReport) radar.AnomalyReport {
+	matched := report
+	matched.Anomalies = make([]radar.Anomaly, 0)
+	for _, anomaly := range report.Anomalies {
+		if geofence.Contains(anomaly.Cell.Center()) {
+			matched.Anomalies = append(matched.Anomalies, anomaly)
+		}
+	}
+	return matched
+}
+
+// AlertAnomalies tells the webhooks of geofences about the anomalies in
+// report inside them. As with changes, only webhooks hear about anomalies.
+// It returns the errors from notifiers, like Alert.
+func (alerter *Alerter) AlertAnomalies(report radar.AnomalyReport) []error {
+	errs := make([]error, 0)
+	notifier, ok := alerter.Webhook.(AnomalyNotifier)
+	if !ok {
+		return errs
+	}
+	for _, geofence := range alerter.Store.List() {
+		if geofence.Target.Webhook == "" {
+			continue
+		}
+		matched := geofence.MatchAnomalies(report)
+		switch {
+		case len(matched.Anomalies) == 0:
+			continue
+		case alerter.Limiter != nil && !alerter.Limiter.Allow(geofence.Target.Webhook):
+			errs = append(errs, fmt.Errorf("webhook alert for geofence %v was rate limited", geofence.Id))
+		default:
+			if err := notifier.NotifyAnomalies(geofence, matched); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,83 @@
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+func newAnomalies() radar.AnomalyReport {
+	return radar.AnomalyReport{
+		From:    time.Date(2011, 12, 2, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC),
+		Periods: 11,
+		Anomalies: []radar.Anomaly{
+			{Cell: radar.GridCellOf(radar.Point{Lat: 45.53, Lng: -122.66}), Recent: 12, Mean: 2, StdDev: 2, Score: 5},
+			{Cell: radar.GridCellOf(radar.Point{Lat: 45.4, Lng: -122.6}), Recent: 9, Mean: 1, StdDev: 1, Score: 8},
+		},
+	}
+}
+
+func TestMatchAnomalies(t *testing.T) {
+	geofence := Geofence{Polygon: lloyd}
+	matched := geofence.MatchAnomalies(newAnomalies())
+	if len(matched.Anomalies) != 1 || matched.Anomalies[0].Recent != 12 || matched.Periods != 11 {
+		t.Error("Geofence matched the wrong anomalies: ", matched)
+	}
+}
+
+func TestWebhookNotifyAnomalies(t *testing.T) {
+	var body struct {
+		Geofence  Geofence
+		Anomalies struct {
+			From      string
+			Anomalies []struct{ Cell string }
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer server.Close()
+	geofence := Geofence{Id: "abc", Polygon: lloyd, Target: Target{Webhook: server.URL}}
+	report := geofence.MatchAnomalies(newAnomalies())
+	if err := NewWebhookNotifier(time.Second).NotifyAnomalies(geofence, report); err != nil {
+		t.Fatal("NotifyAnomalies returned an error: ", err)
+	}
+	if body.Geofence.Id != "abc" || body.Anomalies.From != "2011-12-02" || len(body.Anomalies.Anomalies) != 1 ||
+		body.Anomalies.Anomalies[0].Cell != report.Anomalies[0].Cell.String() {
+		t.Error("Webhook received the wrong body: ", body)
+	}
+}
+
+// anomalyNotifier records the geofences it's told about anomalies in.
+type anomalyNotifier struct {
+	recordingNotifier
+}
+
+func (notifier *anomalyNotifier) NotifyAnomalies(geofence Geofence, report radar.AnomalyReport) error {
+	notifier.notified = append(notifier.notified, geofence.Id)
+	return nil
+}
+
+func TestAlertAnomalies(t *testing.T) {
+	store, _ := NewStore("")
+	inside, _ := store.Create(Geofence{Polygon: lloyd, Target: webhook})
+	store.Create(Geofence{Center: &Point{45.6, -122.6}, RadiusMiles: 0.5, Target: webhook})
+	store.Create(Geofence{Polygon: lloyd, Target: Target{Email: "someone@example.com"}})
+	notifier := &anomalyNotifier{}
+	alerter := &Alerter{Store: store, Webhook: notifier}
+
+	if errs := alerter.AlertAnomalies(newAnomalies()); len(errs) != 0 {
+		t.Error("AlertAnomalies returned errors: ", errs)
+	}
+	if len(notifier.notified) != 1 || notifier.notified[0] != inside.Id {
+		t.Error("Alerter notified the wrong geofences: ", notifier.notified)
+	}
+	if errs := (&Alerter{Store: store, Webhook: &recordingNotifier{}}).AlertAnomalies(newAnomalies()); len(errs) != 0 {
+		t.Error("Webhooks that can't be told about anomalies should be skipped: ", errs)
+	}
+}
@@ -0,0 +1,89 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// A ChangeNotifier tells a geofence's target that crimes inside the
+// geofence were removed or corrected.
+type ChangeNotifier interface {
+	NotifyChanges(geofence Geofence, changes radar.Changes) error
+}
+
+// NotifyChanges POSTs changes as JSON to a geofence's webhook URL:
+//
+//	{"geofence": {...}, "changes": {"from": ..., "removed": [...], "corrected": [...]}}
+func (notifier *WebhookNotifier) NotifyChanges(geofence Geofence, changes radar.Changes) error {
+	changesJson, err := changes.ToJson()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(struct {
+		Geofence Geofence        `json:"geofence"`
+		Changes  json.RawMessage `json:"changes"`
+	}{geofence, changesJson})
+	if err != nil {
+		return err
+	}
+	resp, err := notifier.Client.Post(geofence.Target.Webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook for geofence %v returned %v", geofence.Id, resp.Status)
+	}
+	return nil
+}
+
+// MatchChanges returns the changes to crimes inside the geofence. A
+// correction matches if the crime was inside the geofence before or after
+// it. The count of added crimes is left out, since they're alerted as they
+// are ingested.
+func (geofence *Geofence) MatchChanges(changes radar.Changes) radar.Changes {
+	matched := radar.Changes{From: changes.From, To: changes.To, BaseURL: changes.BaseURL}
+	for _, removed := range changes.Removed {
+		if geofence.Contains(*removed.Point) {
+			matched.Removed = append(matched.Removed, removed)
+		}
+	}
+	for _, correction := range changes.Corrected {
+		if geofence.Contains(*correction.Before.Point) || geofence.Contains(*correction.After.Point) {
+			matched.Corrected = append(matched.Corrected, correction)
+		}
+	}
+	return matched
+}
+
+// AlertChanges tells the webhooks of geofences that crimes inside them were
+// removed or corrected. Only webhooks hear about changes; people reading
+// email, chat and SMS alerts are only told about new crimes. It returns the
+// errors from notifiers, like Alert.
+func (alerter *Alerter) AlertChanges(changes radar.Changes) []error {
+	errs := make([]error, 0)
+	notifier, ok := alerter.Webhook.(ChangeNotifier)
+	if !ok {
+		return errs
+	}
+	for _, geofence := range alerter.Store.List() {
+		if geofence.Target.Webhook == "" {
+			continue
+		}
+		matched := geofence.MatchChanges(changes)
+		switch {
+		case matched.IsEmpty():
+			continue
+		case alerter.Limiter != nil && !alerter.Limiter.Allow(geofence.Target.Webhook):
+			errs = append(errs, fmt.Errorf("webhook alert for geofence %v was rate limited", geofence.Id))
+		default:
+			if err := notifier.NotifyChanges(geofence, matched); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
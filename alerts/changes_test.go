@@ -0,0 +1,87 @@
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+func newChanges() radar.Changes {
+	location := newResult().Locations[0]
+	before := radar.ChangedCrime{Crime: location.Crimes[0], Point: location.Point}
+	corrected := *before.Crime
+	corrected.Type = "Disorderly Conduct"
+	outside := radar.ChangedCrime{Crime: &radar.Crime{Id: 1}, Point: &radar.Point{Lat: 45.4, Lng: -122.6}}
+	return radar.Changes{
+		From:      "a",
+		To:        "b",
+		Added:     3,
+		Removed:   []radar.ChangedCrime{outside},
+		Corrected: []radar.Correction{{Before: before, After: radar.ChangedCrime{Crime: &corrected, Point: before.Point}}},
+	}
+}
+
+func TestMatchChanges(t *testing.T) {
+	geofence := Geofence{Polygon: lloyd}
+	matched := geofence.MatchChanges(newChanges())
+	if len(matched.Removed) != 0 || len(matched.Corrected) != 1 || matched.Added != 0 || matched.To != "b" {
+		t.Error("Geofence matched the wrong changes: ", matched)
+	}
+}
+
+func TestWebhookNotifyChanges(t *testing.T) {
+	var body struct {
+		Geofence Geofence
+		Changes  struct {
+			Corrected []struct {
+				After struct{ Type string }
+			}
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer server.Close()
+	geofence := Geofence{Id: "abc", Polygon: lloyd, Target: Target{Webhook: server.URL}}
+	if err := NewWebhookNotifier(time.Second).NotifyChanges(geofence, newChanges()); err != nil {
+		t.Fatal("NotifyChanges returned an error: ", err)
+	}
+	if body.Geofence.Id != "abc" || len(body.Changes.Corrected) != 1 || body.Changes.Corrected[0].After.Type != "Disorderly Conduct" {
+		t.Error("Webhook received the wrong body: ", body)
+	}
+}
+
+// changeNotifier records the geofences it's told about changes in.
+type changeNotifier struct {
+	recordingNotifier
+}
+
+func (notifier *changeNotifier) NotifyChanges(geofence Geofence, changes radar.Changes) error {
+	notifier.notified = append(notifier.notified, geofence.Id)
+	return nil
+}
+
+func TestAlertChanges(t *testing.T) {
+	store, _ := NewStore("")
+	inside, _ := store.Create(Geofence{Polygon: lloyd, Target: webhook})
+	store.Create(Geofence{Center: &Point{45.6, -122.6}, RadiusMiles: 0.5, Target: webhook})
+	store.Create(Geofence{Polygon: lloyd, Target: Target{Email: "someone@example.com"}})
+	notifier := &changeNotifier{}
+	alerter := &Alerter{Store: store, Webhook: notifier}
+
+	if errs := alerter.AlertChanges(newChanges()); len(errs) != 0 {
+		t.Error("AlertChanges returned errors: ", errs)
+	}
+	if len(notifier.notified) != 1 || notifier.notified[0] != inside.Id {
+		t.Error("Alerter notified the wrong geofences: ", notifier.notified)
+	}
+
+	alerter.Webhook = &recordingNotifier{}
+	if errs := alerter.AlertChanges(newChanges()); len(errs) != 0 {
+		t.Error("Notifiers that can't send changes should be skipped: ", errs)
+	}
+}
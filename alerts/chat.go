@@ -0,0 +1,146 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// The text of a notification, unless a notifier has its own template.
+const DEFAULT_TEMPLATE = `New crimes in {{.Name}}:
+{{range .Crimes}}
+{{.Date}} {{.Time}} {{.Type}} at {{.Lat}},{{.Lng}}{{if .URL}} {{.URL}}{{end}}{{end}}
+`
+
+// DefaultTemplate is DEFAULT_TEMPLATE, parsed.
+var DefaultTemplate = template.Must(template.New("alert").Parse(DEFAULT_TEMPLATE))
+
+// A Message is what a notification template is executed with.
+type Message struct {
+	Geofence Geofence
+	// Name is the geofence's name, or its id if it doesn't have one.
+	Name   string
+	Count  int
+	Crimes []MessageCrime
+}
+
+// A MessageCrime is a crime in a Message.
+type MessageCrime struct {
+	Id         int64
+	Date, Time string
+	Type       string
+	// Category is the category of the crime's type, e.g. "property".
+	Category string
+	Lat, Lng float64
+	// URL is the crime's permalink, or empty if the crimes don't have a
+	// base URL to link to.
+	URL string
+}
+
+// render executes tmpl, or DefaultTemplate if it's nil, for crimes inside
+// geofence.
+func render(tmpl *template.Template, geofence Geofence, crimes radar.SearchResult) (string, error) {
+	if tmpl == nil {
+		tmpl = DefaultTemplate
+	}
+	message := Message{Geofence: geofence, Name: geofence.Name, Crimes: make([]MessageCrime, 0)}
+	if message.Name == "" {
+		message.Name = geofence.Id
+	}
+	for _, location := range crimes.Locations {
+		for _, crime := range location.Crimes {
+			permalink := ""
+			if crimes.BaseURL != "" {
+				permalink = radar.Permalink(crimes.BaseURL, crime.Id)
+			}
+			message.Crimes = append(message.Crimes, MessageCrime{
+				Id:       crime.Id,
+				Date:     crime.Date,
+				Time:     crime.Time,
+				Type:     crime.Type,
+				Category: radar.Classify(crime.Type).Category,
+				Lat:      location.Point.Lat,
+				Lng:      location.Point.Lng,
+				URL:      permalink,
+			})
+		}
+	}
+	message.Count = len(message.Crimes)
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, message); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// A ChatNotifier posts a message about crimes to a chat service's incoming
+// webhook, like Slack's or Discord's.
+type ChatNotifier struct {
+	Client *http.Client
+	// Template is the text of messages. If it's nil, DefaultTemplate is used.
+	Template *template.Template
+	// url returns the webhook URL of a target.
+	url func(target Target) string
+	// field is the name of the message in the webhook's JSON payload.
+	field string
+	// maxLength is the longest message the service accepts.
+	maxLength int
+}
+
+// NewSlackNotifier creates a ChatNotifier for Slack incoming webhooks whose
+// requests time out after timeout.
+func NewSlackNotifier(timeout time.Duration, tmpl *template.Template) *ChatNotifier {
+	return &ChatNotifier{
+		Client:    &http.Client{Timeout: timeout},
+		Template:  tmpl,
+		url:       func(target Target) string { return target.Slack },
+		field:     "text",
+		maxLength: 40000,
+	}
+}
+
+// NewDiscordNotifier creates a ChatNotifier for Discord webhooks whose
+// requests time out after timeout.
+func NewDiscordNotifier(timeout time.Duration, tmpl *template.Template) *ChatNotifier {
+	return &ChatNotifier{
+		Client:    &http.Client{Timeout: timeout},
+		Template:  tmpl,
+		url:       func(target Target) string { return target.Discord },
+		field:     "content",
+		maxLength: 2000,
+	}
+}
+
+func (notifier *ChatNotifier) Notify(geofence Geofence, crimes radar.SearchResult) error {
+	text, err := render(notifier.Template, geofence, crimes)
+	if err != nil {
+		return err
+	}
+	if len(text) > notifier.maxLength {
+		end := notifier.maxLength - 3
+		for end > 0 && !utf8.RuneStart(text[end]) {
+			end -= 1
+		}
+		text = text[:end] + "..."
+	}
+	body, err := json.Marshal(map[string]string{notifier.field: text})
+	if err != nil {
+		return err
+	}
+	resp, err := notifier.Client.Post(notifier.url(geofence.Target), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("chat webhook for geofence %v returned %v", geofence.Id, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+// chatServer records the JSON payloads posted to it.
+func chatServer(payloads *[]map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		*payloads = append(*payloads, payload)
+		w.WriteHeader(204)
+	}))
+}
+
+func TestSlackNotifier(t *testing.T) {
+	var payloads []map[string]string
+	server := chatServer(&payloads)
+	defer server.Close()
+	geofence := Geofence{Name: "home", Polygon: lloyd, Target: Target{Slack: server.URL}}
+
+	if err := NewSlackNotifier(time.Second, nil).Notify(geofence, newResult()); err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) != 1 || !strings.Contains(payloads[0]["text"], "Liquor Laws") {
+		t.Error("Wrong Slack payload: ", payloads)
+	}
+}
+
+func TestDiscordNotifier(t *testing.T) {
+	var payloads []map[string]string
+	server := chatServer(&payloads)
+	defer server.Close()
+	geofence := Geofence{Name: "home", Polygon: lloyd, Target: Target{Discord: server.URL}}
+	tmpl := template.Must(template.New("long").Parse(strings.Repeat("x", 3000)))
+
+	if err := NewDiscordNotifier(time.Second, tmpl).Notify(geofence, newResult()); err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) != 1 || len(payloads[0]["content"]) != 2000 || !strings.HasSuffix(payloads[0]["content"], "...") {
+		t.Error("Discord messages should be truncated to 2000 characters")
+	}
+}
+
+func TestChatNotifierError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+	}))
+	defer server.Close()
+	geofence := Geofence{Id: "abc", Polygon: lloyd, Target: Target{Slack: server.URL}}
+	if err := NewSlackNotifier(time.Second, nil).Notify(geofence, newResult()); err == nil {
+		t.Error("Notify should return an error when the webhook fails")
+	}
+}
@@ -0,0 +1,139 @@
+// Package alerts notifies people when crimes occur inside areas they're
+// watching.
+package alerts
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// A Point is a coordinate in a Geofence.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// A Target is where the notifications of a Geofence go: any of a webhook
+// URL, an email address, the incoming webhook URLs of a Slack or Discord
+// channel, and a phone number for SMS alerts about severe crimes.
+type Target struct {
+	Webhook    string      `json:"webhook,omitempty"`
+	Email      string      `json:"email,omitempty"`
+	Slack      string      `json:"slack,omitempty"`
+	Discord    string      `json:"discord,omitempty"`
+	SMS        string      `json:"sms,omitempty"`
+	SMSOptions *SMSOptions `json:"sms_options,omitempty"`
+}
+
+// urls returns the target's URLs.
+func (target Target) urls() []string {
+	urls := make([]string, 0)
+	for _, u := range []string{target.Webhook, target.Slack, target.Discord} {
+		if u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// A Geofence is an area that someone wants to hear about new crimes in:
+// either a circle, given by Center and RadiusMiles, or a Polygon.
+type Geofence struct {
+	Id          string  `json:"id"`
+	Name        string  `json:"name,omitempty"`
+	Center      *Point  `json:"center,omitempty"`
+	RadiusMiles float64 `json:"radius_miles,omitempty"`
+	// Polygon is an outer ring of points followed by any holes in it.
+	Polygon [][]Point `json:"polygon,omitempty"`
+	Target  Target    `json:"target"`
+	// Owner is the id of the API key the geofence was created with, if it
+	// was created with one.
+	Owner string `json:"owner,omitempty"`
+}
+
+var (
+	errNoArea        = errors.New("geofence needs a center and radius or a polygon")
+	errTwoAreas      = errors.New("geofence can't have both a circle and a polygon")
+	errBadRadius     = errors.New("geofence radius must be greater than zero")
+	errShortPolygon  = errors.New("geofence polygon needs at least three points")
+	errBadCoordinate = errors.New("geofence coordinate is out of range")
+	errNoTarget      = errors.New("geofence needs a target")
+	errBadTargetURL  = errors.New("geofence target URL must be http or https")
+)
+
+func (p Point) valid() bool {
+	_, err := radar.NewPoint(radar.Latitude(p.Lat), radar.Longitude(p.Lng))
+	return err == nil
+}
+
+// Validate returns an error if the geofence doesn't describe an area or
+// has nowhere to send notifications.
+func (geofence *Geofence) Validate() error {
+	switch {
+	case geofence.Center == nil && len(geofence.Polygon) == 0:
+		return errNoArea
+	case geofence.Center != nil && len(geofence.Polygon) > 0:
+		return errTwoAreas
+	case geofence.Center != nil:
+		if !(geofence.RadiusMiles > 0) {
+			return errBadRadius
+		}
+		if !geofence.Center.valid() {
+			return errBadCoordinate
+		}
+	default:
+		for _, ring := range geofence.Polygon {
+			if len(ring) < 3 {
+				return errShortPolygon
+			}
+			for _, p := range ring {
+				if !p.valid() {
+					return errBadCoordinate
+				}
+			}
+		}
+	}
+	if geofence.Target.Email == "" && geofence.Target.SMS == "" && len(geofence.Target.urls()) == 0 {
+		return errNoTarget
+	}
+	if err := validateSMS(geofence.Target); err != nil {
+		return err
+	}
+	for _, targetURL := range geofence.Target.urls() {
+		u, err := url.Parse(targetURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errBadTargetURL
+		}
+	}
+	return nil
+}
+
+// Contains reports whether point is inside the geofence.
+func (geofence *Geofence) Contains(point radar.Point) bool {
+	if geofence.Center != nil {
+		center := radar.Point{Lat: geofence.Center.Lat, Lng: geofence.Center.Lng}
+		return center.GreatCircleDistance(&point) <= geofence.RadiusMiles
+	}
+	polygon := make(radar.Polygon, 0, len(geofence.Polygon))
+	for _, ring := range geofence.Polygon {
+		r := make(radar.Ring, 0, len(ring))
+		for _, p := range ring {
+			r = append(r, radar.Point{Lat: p.Lat, Lng: p.Lng})
+		}
+		polygon = append(polygon, r)
+	}
+	return polygon.Contains(point)
+}
+
+// Match returns the crimes in result that are inside the geofence.
+func (geofence *Geofence) Match(result radar.SearchResult) radar.SearchResult {
+	matched := radar.SearchResult{Query: result.Query, Locations: make([]*radar.CrimeLocation, 0), BaseURL: result.BaseURL}
+	for _, location := range result.Locations {
+		if geofence.Contains(*location.Point) {
+			matched.Locations = append(matched.Locations, location)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,75 @@
+package alerts
+
+import (
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+var webhook = Target{Webhook: "http://localhost/hook"}
+
+// A square around the Lloyd district, with a hole in its northeast corner.
+var lloyd = [][]Point{
+	{{45.52, -122.67}, {45.54, -122.67}, {45.54, -122.65}, {45.52, -122.65}},
+	{{45.535, -122.655}, {45.54, -122.655}, {45.54, -122.65}, {45.535, -122.65}},
+}
+
+func TestGeofenceValidate(t *testing.T) {
+	valid := []Geofence{
+		{Center: &Point{45.5, -122.6}, RadiusMiles: 0.5, Target: webhook},
+		{Polygon: lloyd, Target: Target{Email: "someone@example.com"}},
+		{Polygon: lloyd, Target: Target{Slack: "https://hooks.slack.com/services/x"}},
+	}
+	for _, geofence := range valid {
+		if err := geofence.Validate(); err != nil {
+			t.Error("Geofence should be valid: ", err)
+		}
+	}
+	invalid := map[error]Geofence{
+		errNoArea:        {Target: webhook},
+		errTwoAreas:      {Center: &Point{45.5, -122.6}, RadiusMiles: 0.5, Polygon: lloyd, Target: webhook},
+		errBadRadius:     {Center: &Point{45.5, -122.6}, Target: webhook},
+		errShortPolygon:  {Polygon: [][]Point{{{45.5, -122.6}, {45.6, -122.6}}}, Target: webhook},
+		errBadCoordinate: {Center: &Point{95.5, -122.6}, RadiusMiles: 0.5, Target: webhook},
+		errNoTarget:      {Center: &Point{45.5, -122.6}, RadiusMiles: 0.5},
+		errBadTargetURL:  {Center: &Point{45.5, -122.6}, RadiusMiles: 0.5, Target: Target{Discord: "ftp://example.com"}},
+	}
+	for expected, geofence := range invalid {
+		if err := geofence.Validate(); err != expected {
+			t.Error("Wrong error: ", err, " instead of ", expected)
+		}
+	}
+}
+
+func TestGeofenceContainsCircle(t *testing.T) {
+	geofence := Geofence{Center: &Point{45.53435699129174, -122.66469510763777}, RadiusMiles: 0.5}
+	if !geofence.Contains(radar.Point{Lat: 45.53579735412487, Lng: -122.66468312170824}) {
+		t.Error("Geofence should contain a point 0.1 miles from its center")
+	}
+	if geofence.Contains(radar.Point{Lat: 45.5184, Lng: -122.6554}) {
+		t.Error("Geofence should not contain a point over a mile from its center")
+	}
+}
+
+func TestGeofenceContainsPolygon(t *testing.T) {
+	geofence := Geofence{Polygon: lloyd}
+	if !geofence.Contains(radar.Point{Lat: 45.53, Lng: -122.66}) {
+		t.Error("Geofence should contain a point inside its polygon")
+	}
+	if geofence.Contains(radar.Point{Lat: 45.538, Lng: -122.652}) {
+		t.Error("Geofence should not contain a point in a hole")
+	}
+	if geofence.Contains(radar.Point{Lat: 45.5, Lng: -122.66}) {
+		t.Error("Geofence should not contain a point outside its polygon")
+	}
+}
+
+func TestGeofenceMatch(t *testing.T) {
+	inside := &radar.CrimeLocation{Point: &radar.Point{Lat: 45.53, Lng: -122.66}, Crimes: []*radar.Crime{{Id: 1}}}
+	outside := &radar.CrimeLocation{Point: &radar.Point{Lat: 45.5, Lng: -122.66}, Crimes: []*radar.Crime{{Id: 2}}}
+	geofence := Geofence{Polygon: lloyd}
+	matched := geofence.Match(radar.SearchResult{Locations: []*radar.CrimeLocation{inside, outside}})
+	if len(matched.Locations) != 1 || matched.Locations[0] != inside {
+		t.Error("Match returned the wrong locations: ", matched.Locations)
+	}
+}
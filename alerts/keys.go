@@ -0,0 +1,124 @@
+package alerts
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+// An APIKey lets a client manage geofences. Only a hash of the key is
+// stored; the key itself is returned once, when it's created.
+type APIKey struct {
+	Id      string    `json:"id"`
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	Hash    string    `json:"hash"`
+	// Key is only set on a newly created APIKey.
+	Key string `json:"key,omitempty"`
+}
+
+// hashKey returns the hash of key that's stored.
+func hashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// listKeys returns the API keys sorted by id. The caller must hold the
+// lock.
+func (store *Store) listKeys() []*APIKey {
+	keys := make([]*APIKey, 0, len(store.keys))
+	for _, key := range store.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Id < keys[j].Id
+	})
+	return keys
+}
+
+// Keys returns every API key, sorted by id, without their hashes.
+func (store *Store) Keys() []APIKey {
+	store.refresh()
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	keys := make([]APIKey, 0, len(store.keys))
+	for _, key := range store.listKeys() {
+		listed := *key
+		listed.Hash = ""
+		keys = append(keys, listed)
+	}
+	return keys
+}
+
+// CreateKey creates a new API key called name. The returned APIKey's Key
+// is the only copy of the key.
+func (store *Store) CreateKey(name string) (APIKey, error) {
+	id, err := newId()
+	if err != nil {
+		return APIKey{}, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return APIKey{}, err
+	}
+	key := &APIKey{Id: id, Name: name, Created: time.Now().UTC(), Hash: hashKey(secret)}
+	err = store.change(func() error {
+		store.keys[id] = key
+		return nil
+	})
+	if err != nil {
+		return APIKey{}, err
+	}
+	created := *key
+	created.Hash = ""
+	created.Key = secret
+	return created, nil
+}
+
+// DeleteKey removes the API key with id.
+func (store *Store) DeleteKey(id string) error {
+	return store.change(func() error {
+		if _, ok := store.keys[id]; !ok {
+			return ErrNotFound
+		}
+		delete(store.keys, id)
+		return nil
+	})
+}
+
+// HasKeys returns true if the store has any API keys.
+func (store *Store) HasKeys() bool {
+	store.refresh()
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return len(store.keys) > 0
+}
+
+// CheckKey returns true if key is one of the store's API keys.
+func (store *Store) CheckKey(key string) bool {
+	_, found := store.FindKey(key)
+	return found
+}
+
+// FindKey returns the API key that key is, without its hash, and false if
+// it isn't one of the store's.
+func (store *Store) FindKey(key string) (APIKey, bool) {
+	hash := []byte(hashKey(key))
+	store.refresh()
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	var found *APIKey
+	for _, stored := range store.listKeys() {
+		if subtle.ConstantTimeCompare(hash, []byte(stored.Hash)) == 1 {
+			found = stored
+		}
+	}
+	if found == nil {
+		return APIKey{}, false
+	}
+	matched := *found
+	matched.Hash = ""
+	return matched, true
+}
@@ -0,0 +1,45 @@
+package alerts
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestKeys(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "geofences.json")
+	store, _ := NewStore(filename)
+	if store.HasKeys() {
+		t.Error("A new store should have no keys")
+	}
+	created, err := store.CreateKey("alice")
+	if err != nil || created.Key == "" || created.Hash != "" {
+		t.Fatal("Error creating a key: ", created, err)
+	}
+	if !store.CheckKey(created.Key) || store.CheckKey("wrong") {
+		t.Error("CheckKey should only accept the created key")
+	}
+	if found, ok := store.FindKey(created.Key); !ok || found.Id != created.Id || found.Name != "alice" || found.Hash != "" {
+		t.Error("FindKey should find the created key: ", found, ok)
+	}
+
+	reopened, err := NewStore(filename)
+	if err != nil {
+		t.Fatal("Error reopening Store: ", err)
+	}
+	keys := reopened.Keys()
+	if len(keys) != 1 || keys[0].Name != "alice" || keys[0].Key != "" || keys[0].Hash != "" {
+		t.Error("Reopened store has the wrong keys: ", keys)
+	}
+	if !reopened.CheckKey(created.Key) {
+		t.Error("Keys should survive reopening the store")
+	}
+	if err := reopened.DeleteKey(created.Id); err != nil {
+		t.Error("Error deleting a key: ", err)
+	}
+	if reopened.CheckKey(created.Key) || reopened.HasKeys() {
+		t.Error("Deleted key should not be accepted")
+	}
+	if err := reopened.DeleteKey(created.Id); err != ErrNotFound {
+		t.Error("Store should not delete a missing key: ", err)
+	}
+}
@@ -0,0 +1,152 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// A Notifier tells a geofence's target about crimes inside the geofence.
+type Notifier interface {
+	Notify(geofence Geofence, crimes radar.SearchResult) error
+}
+
+// A WebhookNotifier POSTs crimes as JSON to a geofence's webhook URL:
+//
+//	{"geofence": {...}, "crimes": {"query": ..., "locations": [...]}}
+type WebhookNotifier struct {
+	Client *http.Client
+}
+
+// NewWebhookNotifier creates a WebhookNotifier whose requests time out
+// after timeout.
+func NewWebhookNotifier(timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{Client: &http.Client{Timeout: timeout}}
+}
+
+func (notifier *WebhookNotifier) Notify(geofence Geofence, crimes radar.SearchResult) error {
+	if crimes.Query == nil {
+		crimes.Query = crimes.Locations[0].Point
+	}
+	crimesJson, err := crimes.ToJson()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(struct {
+		Geofence Geofence        `json:"geofence"`
+		Crimes   json.RawMessage `json:"crimes"`
+	}{geofence, crimesJson})
+	if err != nil {
+		return err
+	}
+	resp, err := notifier.Client.Post(geofence.Target.Webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook for geofence %v returned %v", geofence.Id, resp.Status)
+	}
+	return nil
+}
+
+// An EmailNotifier emails a list of crimes to a geofence's email address
+// through an SMTP server.
+type EmailNotifier struct {
+	// Addr is the host:port of the SMTP server.
+	Addr string
+	From string
+	Auth smtp.Auth
+	// Template is the text of emails. If it's nil, DefaultTemplate is used.
+	Template *template.Template
+}
+
+func (notifier *EmailNotifier) Notify(geofence Geofence, crimes radar.SearchResult) error {
+	to := geofence.Target.Email
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("invalid email address for geofence %v", geofence.Id)
+	}
+	text, err := render(notifier.Template, geofence, crimes)
+	if err != nil {
+		return err
+	}
+	message := fmt.Sprintf("From: %v\nTo: %v\nSubject: New crimes near you\n\n%v", notifier.From, to, text)
+	// SMTP needs lines that end in CRLF.
+	message = strings.ReplaceAll(strings.ReplaceAll(message, "\r\n", "\n"), "\n", "\r\n")
+	return smtp.SendMail(notifier.Addr, notifier.Auth, notifier.From, []string{to}, []byte(message))
+}
+
+// An Alerter sends notifications for geofences in a Store.
+type Alerter struct {
+	Store *Store
+	// The notifiers for each kind of target. A notifier is nil if the
+	// server can't send that kind of notification, e.g. email without an
+	// SMTP server.
+	Webhook Notifier
+	Email   Notifier
+	Slack   Notifier
+	Discord Notifier
+	SMS     Notifier
+	// Limiter, if set, limits how often each target is notified.
+	Limiter *RateLimiter
+	// now returns the current time, for SMS quiet hours. It's replaced in
+	// tests.
+	now func() time.Time
+}
+
+// Alert notifies the targets of every geofence that new crimes are inside.
+// It returns the errors from notifiers, which don't stop other
+// notifications. A target that has been notified too often recently is
+// skipped, with an error. SMS targets only hear about crimes at their
+// severity threshold, outside their quiet hours.
+func (alerter *Alerter) Alert(crimes radar.SearchResult) []error {
+	errs := make([]error, 0)
+	for _, geofence := range alerter.Store.List() {
+		matched := geofence.Match(crimes)
+		if len(matched.Locations) == 0 {
+			continue
+		}
+		target := geofence.Target
+		sinks := []struct {
+			kind     string
+			address  string
+			notifier Notifier
+			crimes   radar.SearchResult
+		}{
+			{"webhook", target.Webhook, alerter.Webhook, matched},
+			{"email", target.Email, alerter.Email, matched},
+			{"slack", target.Slack, alerter.Slack, matched},
+			{"discord", target.Discord, alerter.Discord, matched},
+			{"sms", target.SMS, alerter.SMS, matched},
+		}
+		if target.SMS != "" {
+			now := time.Now()
+			if alerter.now != nil {
+				now = alerter.now()
+			}
+			sinks[4].crimes = smsCrimes(target, matched, now)
+		}
+		for _, sink := range sinks {
+			switch {
+			case sink.address == "" || len(sink.crimes.Locations) == 0:
+				continue
+			case sink.notifier == nil:
+				errs = append(errs, fmt.Errorf("can't send %v alerts for geofence %v", sink.kind, geofence.Id))
+			case alerter.Limiter != nil && !alerter.Limiter.Allow(sink.address):
+				errs = append(errs, fmt.Errorf("%v alert for geofence %v was rate limited", sink.kind, geofence.Id))
+			default:
+				if err := sink.notifier.Notify(geofence, sink.crimes); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,126 @@
+package alerts
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+func newResult() radar.SearchResult {
+	crime := &radar.Crime{Id: 13807517, Date: "12/01/2011", Time: "01:00:00", Type: "Liquor Laws"}
+	location := &radar.CrimeLocation{Point: &radar.Point{Lat: 45.53, Lng: -122.66}, Crimes: []*radar.Crime{crime}}
+	return radar.SearchResult{Locations: []*radar.CrimeLocation{location}}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var body struct {
+		Geofence Geofence
+		Crimes   struct {
+			Locations []json.RawMessage
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer server.Close()
+	geofence := Geofence{Id: "abc", Polygon: lloyd, Target: Target{Webhook: server.URL}}
+	if err := NewWebhookNotifier(time.Second).Notify(geofence, newResult()); err != nil {
+		t.Fatal("Notify returned an error: ", err)
+	}
+	if body.Geofence.Id != "abc" || len(body.Crimes.Locations) != 1 {
+		t.Error("Webhook received the wrong body: ", body)
+	}
+}
+
+func TestWebhookNotifierError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	}))
+	defer server.Close()
+	geofence := Geofence{Id: "abc", Polygon: lloyd, Target: Target{Webhook: server.URL}}
+	if err := NewWebhookNotifier(time.Second).Notify(geofence, newResult()); err == nil {
+		t.Error("Notify should return an error when the webhook fails")
+	}
+}
+
+func TestRender(t *testing.T) {
+	expected := "New crimes in home:\n\n12/01/2011 01:00:00 Liquor Laws at 45.53,-122.66\n"
+	actual, err := render(nil, Geofence{Name: "home"}, newResult())
+	if err != nil || actual != expected {
+		t.Errorf("Wrong message: %q, %v", actual, err)
+	}
+}
+
+func TestRenderPermalinks(t *testing.T) {
+	result := newResult()
+	result.BaseURL = "https://radar.example.com"
+	expected := "New crimes in home:\n\n12/01/2011 01:00:00 Liquor Laws at 45.53,-122.66 https://radar.example.com/crimes/13807517\n"
+	actual, err := render(nil, Geofence{Name: "home"}, result)
+	if err != nil || actual != expected {
+		t.Errorf("Wrong message: %q, %v", actual, err)
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("test").Parse("{{.Count}} near {{.Name}}: {{range .Crimes}}{{.Category}}{{end}}"))
+	actual, err := render(tmpl, Geofence{Id: "abc"}, newResult())
+	if err != nil || actual != "1 near abc: society" {
+		t.Errorf("Wrong message: %q, %v", actual, err)
+	}
+}
+
+// recordingNotifier records the geofences it's asked to notify.
+type recordingNotifier struct {
+	notified []string
+	err      error
+}
+
+func (notifier *recordingNotifier) Notify(geofence Geofence, crimes radar.SearchResult) error {
+	notifier.notified = append(notifier.notified, geofence.Id)
+	return notifier.err
+}
+
+func TestAlerter(t *testing.T) {
+	store, _ := NewStore("")
+	inside, _ := store.Create(Geofence{Polygon: lloyd, Target: webhook})
+	store.Create(Geofence{Center: &Point{45.4, -122.6}, RadiusMiles: 0.5, Target: webhook})
+	store.Create(Geofence{Polygon: lloyd, Target: Target{Email: "someone@example.com"}})
+	notifier := &recordingNotifier{}
+	alerter := &Alerter{Store: store, Webhook: notifier}
+
+	errs := alerter.Alert(newResult())
+	if len(notifier.notified) != 1 || notifier.notified[0] != inside.Id {
+		t.Error("Alerter notified the wrong geofences: ", notifier.notified)
+	}
+	if len(errs) != 1 {
+		t.Error("Alerter should return an error for an email target without SMTP: ", errs)
+	}
+
+	notifier.err = errors.New("unavailable")
+	alerter.Email = &recordingNotifier{}
+	if errs := alerter.Alert(newResult()); len(errs) != 1 || errs[0] != notifier.err {
+		t.Error("Alerter returned the wrong errors: ", errs)
+	}
+}
+
+func TestAlerterRateLimit(t *testing.T) {
+	store, _ := NewStore("")
+	store.Create(Geofence{Polygon: lloyd, Target: webhook})
+	notifier := &recordingNotifier{}
+	alerter := &Alerter{Store: store, Webhook: notifier, Limiter: NewRateLimiter(1)}
+
+	alerter.Alert(newResult())
+	errs := alerter.Alert(newResult())
+	if len(notifier.notified) != 1 {
+		t.Error("Alerter should skip rate limited targets: ", notifier.notified)
+	}
+	if len(errs) != 1 {
+		t.Error("Alerter should return an error for a rate limited target: ", errs)
+	}
+}
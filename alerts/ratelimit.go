@@ -0,0 +1,93 @@
+package alerts
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// A RateLimiter limits how often each target is notified, so that a burst
+// of crimes doesn't flood a channel. Each target may be notified Burst
+// times at once, and after that once per Interval. A RateLimiter is safe to
+// use from several goroutines.
+type RateLimiter struct {
+	Burst    int
+	Interval time.Duration
+	// now returns the current time. It's replaced in tests.
+	now     func() time.Time
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// A bucket holds the notifications a target has left.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a RateLimiter that allows perHour notifications
+// an hour to each target, all at once if need be.
+func NewRateLimiter(perHour int) *RateLimiter {
+	return &RateLimiter{Burst: perHour, Interval: time.Hour / time.Duration(perHour)}
+}
+
+// time returns the current time.
+func (limiter *RateLimiter) time() time.Time {
+	if limiter.now != nil {
+		return limiter.now()
+	}
+	return time.Now()
+}
+
+// Allow returns true if target may be notified now, and counts the
+// notification against its limit.
+func (limiter *RateLimiter) Allow(target string) bool {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	now := limiter.time()
+	if limiter.buckets == nil {
+		limiter.buckets = make(map[string]*bucket)
+	}
+	b, ok := limiter.buckets[target]
+	if !ok {
+		b = &bucket{tokens: float64(limiter.Burst), last: now}
+		limiter.buckets[target] = b
+	}
+	b.tokens += float64(now.Sub(b.last)) / float64(limiter.Interval)
+	if b.tokens > float64(limiter.Burst) {
+		b.tokens = float64(limiter.Burst)
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens -= 1
+	return true
+}
+
+// Remaining returns how many more times target may be notified now,
+// without counting a notification against its limit.
+func (limiter *RateLimiter) Remaining(target string) int {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	b, ok := limiter.buckets[target]
+	if !ok {
+		return limiter.Burst
+	}
+	tokens := b.tokens + float64(limiter.time().Sub(b.last))/float64(limiter.Interval)
+	return int(math.Min(tokens, float64(limiter.Burst)))
+}
+
+// Prune forgets the targets that have their whole burst back, which is the
+// same as never having been seen, so that a limiter of many targets, like
+// the clients of a server, doesn't grow without end.
+func (limiter *RateLimiter) Prune() {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	now := limiter.time()
+	for target, b := range limiter.buckets {
+		if b.tokens+float64(now.Sub(b.last))/float64(limiter.Interval) >= float64(limiter.Burst) {
+			delete(limiter.buckets, target)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(2)
+	limiter.now = func() time.Time { return now }
+
+	if !limiter.Allow("a") || !limiter.Allow("a") {
+		t.Error("RateLimiter should allow a burst")
+	}
+	if limiter.Allow("a") {
+		t.Error("RateLimiter should limit a target after a burst")
+	}
+	if !limiter.Allow("b") {
+		t.Error("RateLimiter should limit targets separately")
+	}
+	now = now.Add(30 * time.Minute)
+	if !limiter.Allow("a") {
+		t.Error("RateLimiter should allow a target again after an interval")
+	}
+	if limiter.Allow("a") {
+		t.Error("RateLimiter should refill one notification per interval")
+	}
+	if remaining := limiter.Remaining("a"); remaining != 0 {
+		t.Error("Wrong remaining notifications: ", remaining)
+	}
+	now = now.Add(45 * time.Minute)
+	if remaining := limiter.Remaining("a"); remaining != 1 || limiter.Remaining("c") != 2 {
+		t.Error("Wrong remaining notifications after a while: ", remaining)
+	}
+	if limiter.Remaining("a") != 1 {
+		t.Error("Remaining shouldn't count against the limit")
+	}
+}
+
+func TestRateLimiterPrune(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(2)
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("a")
+	limiter.Allow("a")
+	limiter.Allow("b")
+	now = now.Add(30 * time.Minute)
+	limiter.Prune()
+	if _, ok := limiter.buckets["b"]; ok {
+		t.Error("Prune should forget a target with its whole burst back")
+	}
+	if _, ok := limiter.buckets["a"]; !ok {
+		t.Error("Prune shouldn't forget a target that's still limited")
+	}
+	if !limiter.Allow("a") || limiter.Allow("a") {
+		t.Error("Prune shouldn't change a target's limit")
+	}
+}
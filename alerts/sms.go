@@ -0,0 +1,210 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// Severities of crimes, from least to most severe. SMS alerts are only
+// sent for crimes at or above a target's MinSeverity.
+const (
+	LowSeverity    = "low"
+	MediumSeverity = "medium"
+	HighSeverity   = "high"
+)
+
+// severities ranks each severity.
+var severities = map[string]int{LowSeverity: 1, MediumSeverity: 2, HighSeverity: 3}
+
+// Severity returns the severity of a crime. Crimes against persons,
+// robberies and crimes with a weapon are high, other crimes against
+// property are medium, and the rest are low.
+func Severity(crime *radar.Crime) string {
+	classification := radar.Classify(crime.Type)
+	switch {
+	case classification.Category == radar.PersonCategory, classification.Group == "Robbery", crime.Weapon != "":
+		return HighSeverity
+	case classification.Category == radar.PropertyCategory:
+		return MediumSeverity
+	}
+	return LowSeverity
+}
+
+// The longest SMS message Twilio accepts.
+const SMS_MAX_LENGTH = 1600
+
+// The text of SMS alerts, unless an SMSNotifier has its own template.
+const DEFAULT_SMS_TEMPLATE = `{{.Count}} new crime{{if ne .Count 1}}s{{end}} in {{.Name}}:{{range .Crimes}}
+{{.Type}} {{.Date}} {{.Time}}{{if .URL}} {{.URL}}{{end}}{{end}}`
+
+// DefaultSMSTemplate is DEFAULT_SMS_TEMPLATE, parsed.
+var DefaultSMSTemplate = template.Must(template.New("sms").Parse(DEFAULT_SMS_TEMPLATE))
+
+// QuietHours is a time of day when a target doesn't want SMS alerts, like
+// 22:00 to 07:00. Start and End are hh:mm in Timezone, or UTC if Timezone
+// is empty.
+type QuietHours struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// minutes parses an hh:mm time of day into minutes after midnight.
+func minutes(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains reports whether now is during the quiet hours. Quiet hours that
+// can't be parsed never contain a time; Validate catches them.
+func (quiet QuietHours) Contains(now time.Time) bool {
+	start, err := minutes(quiet.Start)
+	if err != nil {
+		return false
+	}
+	end, err := minutes(quiet.End)
+	if err != nil {
+		return false
+	}
+	location, err := time.LoadLocation(quiet.Timezone)
+	if err != nil {
+		return false
+	}
+	now = now.In(location)
+	minute := now.Hour()*60 + now.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	// The quiet hours span midnight.
+	return minute >= start || minute < end
+}
+
+// SMSOptions are a target's settings for SMS alerts.
+type SMSOptions struct {
+	// MinSeverity is the least severe crime to send an SMS about. If it's
+	// empty, only HighSeverity crimes are sent.
+	MinSeverity string      `json:"min_severity,omitempty"`
+	QuietHours  *QuietHours `json:"quiet_hours,omitempty"`
+}
+
+var (
+	errBadPhone      = errors.New("geofence SMS target must be a phone number like +15035550100")
+	errBadSeverity   = errors.New("geofence SMS severity must be low, medium or high")
+	errBadQuietHours = errors.New("geofence quiet hours must be hh:mm times in a known timezone")
+)
+
+// phonePattern matches E.164 phone numbers.
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// validateSMS returns an error if target's SMS settings are invalid.
+func validateSMS(target Target) error {
+	if target.SMS != "" && !phonePattern.MatchString(target.SMS) {
+		return errBadPhone
+	}
+	if target.SMSOptions == nil {
+		return nil
+	}
+	if _, ok := severities[target.SMSOptions.MinSeverity]; !ok && target.SMSOptions.MinSeverity != "" {
+		return errBadSeverity
+	}
+	if quiet := target.SMSOptions.QuietHours; quiet != nil {
+		_, startErr := minutes(quiet.Start)
+		_, endErr := minutes(quiet.End)
+		_, zoneErr := time.LoadLocation(quiet.Timezone)
+		if startErr != nil || endErr != nil || zoneErr != nil {
+			return errBadQuietHours
+		}
+	}
+	return nil
+}
+
+// smsCrimes returns the crimes that target should get an SMS about at now:
+// none during its quiet hours, and otherwise those at or above its
+// MinSeverity.
+func smsCrimes(target Target, crimes radar.SearchResult, now time.Time) radar.SearchResult {
+	options := SMSOptions{}
+	if target.SMSOptions != nil {
+		options = *target.SMSOptions
+	}
+	if options.QuietHours != nil && options.QuietHours.Contains(now) {
+		return crimes.Filter(func(crime *radar.Crime) bool { return false })
+	}
+	minimum := severities[HighSeverity]
+	if rank, ok := severities[options.MinSeverity]; ok {
+		minimum = rank
+	}
+	return crimes.Filter(func(crime *radar.Crime) bool {
+		return severities[Severity(crime)] >= minimum
+	})
+}
+
+// An SMSNotifier texts a list of crimes to a geofence's phone number
+// through Twilio's Messages API, or another API compatible with it.
+type SMSNotifier struct {
+	Client *http.Client
+	// BaseURL is the URL of the API, https://api.twilio.com for Twilio.
+	BaseURL    string
+	AccountSid string
+	AuthToken  string
+	// From is the phone number messages are sent from.
+	From string
+	// Template is the text of messages. If it's nil, DefaultSMSTemplate is
+	// used.
+	Template *template.Template
+}
+
+// NewSMSNotifier creates an SMSNotifier for a Twilio account whose
+// requests time out after timeout.
+func NewSMSNotifier(timeout time.Duration, accountSid string, authToken string, from string) *SMSNotifier {
+	return &SMSNotifier{
+		Client:     &http.Client{Timeout: timeout},
+		BaseURL:    "https://api.twilio.com",
+		AccountSid: accountSid,
+		AuthToken:  authToken,
+		From:       from,
+	}
+}
+
+func (notifier *SMSNotifier) Notify(geofence Geofence, crimes radar.SearchResult) error {
+	tmpl := notifier.Template
+	if tmpl == nil {
+		tmpl = DefaultSMSTemplate
+	}
+	text, err := render(tmpl, geofence, crimes)
+	if err != nil {
+		return err
+	}
+	if utf8.RuneCountInString(text) > SMS_MAX_LENGTH {
+		text = string([]rune(text)[:SMS_MAX_LENGTH-3]) + "..."
+	}
+	form := url.Values{"From": {notifier.From}, "To": {geofence.Target.SMS}, "Body": {text}}
+	endpoint := fmt.Sprintf("%v/2010-04-01/Accounts/%v/Messages.json",
+		strings.TrimSuffix(notifier.BaseURL, "/"), url.PathEscape(notifier.AccountSid))
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(notifier.AccountSid, notifier.AuthToken)
+	resp, err := notifier.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("SMS for geofence %v returned %v", geofence.Id, resp.Status)
+	}
+	return nil
+}
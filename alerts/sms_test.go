@@ -0,0 +1,114 @@
+package alerts
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+func TestSeverity(t *testing.T) {
+	crimes := map[string]*radar.Crime{
+		HighSeverity:   {Type: "Aggravated Assault"},
+		MediumSeverity: {Type: "Burglary"},
+		LowSeverity:    {Type: "Liquor Laws"},
+	}
+	for expected, crime := range crimes {
+		if actual := Severity(crime); actual != expected {
+			t.Error("Wrong severity for ", crime.Type, ": ", actual)
+		}
+	}
+	if actual := Severity(&radar.Crime{Type: "Trespass", Weapon: "Knife"}); actual != HighSeverity {
+		t.Error("Crimes with a weapon should be high severity: ", actual)
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	quiet := QuietHours{Start: "22:00", End: "07:00", Timezone: "America/Los_Angeles"}
+	// 06:30 UTC is 22:30 in Portland in winter.
+	if !quiet.Contains(time.Date(2024, 1, 1, 6, 30, 0, 0, time.UTC)) {
+		t.Error("Quiet hours should span midnight")
+	}
+	if quiet.Contains(time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC)) {
+		t.Error("Noon should not be in quiet hours")
+	}
+	daytime := QuietHours{Start: "09:00", End: "17:00"}
+	if !daytime.Contains(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)) || daytime.Contains(time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC)) {
+		t.Error("Wrong quiet hours within a day")
+	}
+}
+
+func TestValidateSMS(t *testing.T) {
+	invalid := map[error]Target{
+		errBadPhone:      {SMS: "555-0100"},
+		errBadSeverity:   {SMS: "+15035550100", SMSOptions: &SMSOptions{MinSeverity: "urgent"}},
+		errBadQuietHours: {SMS: "+15035550100", SMSOptions: &SMSOptions{QuietHours: &QuietHours{Start: "10pm", End: "07:00"}}},
+	}
+	for expected, target := range invalid {
+		if err := validateSMS(target); err != expected {
+			t.Error("Wrong error: ", err, " instead of ", expected)
+		}
+	}
+	if err := validateSMS(Target{SMS: "+15035550100", SMSOptions: &SMSOptions{MinSeverity: LowSeverity}}); err != nil {
+		t.Error("SMS target should be valid: ", err)
+	}
+}
+
+func TestSMSCrimes(t *testing.T) {
+	target := Target{SMS: "+15035550100"}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if crimes := smsCrimes(target, newResult(), now); len(crimes.Locations) != 0 {
+		t.Error("SMS targets should only get high severity crimes by default")
+	}
+	target.SMSOptions = &SMSOptions{MinSeverity: LowSeverity}
+	if crimes := smsCrimes(target, newResult(), now); len(crimes.Locations) != 1 {
+		t.Error("SMS target should get crimes at its threshold")
+	}
+	target.SMSOptions.QuietHours = &QuietHours{Start: "11:00", End: "13:00"}
+	if crimes := smsCrimes(target, newResult(), now); len(crimes.Locations) != 0 {
+		t.Error("SMS target should get no crimes during quiet hours")
+	}
+}
+
+func TestSMSNotifier(t *testing.T) {
+	var body, user string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2010-04-01/Accounts/AC123/Messages.json" {
+			w.WriteHeader(404)
+			return
+		}
+		user, _, _ = r.BasicAuth()
+		r.ParseForm()
+		body = r.PostForm.Get("To") + " " + r.PostForm.Get("Body")
+		w.WriteHeader(201)
+	}))
+	defer server.Close()
+	notifier := NewSMSNotifier(time.Second, "AC123", "token", "+15035550199")
+	notifier.BaseURL = server.URL
+	geofence := Geofence{Name: "home", Polygon: lloyd, Target: Target{SMS: "+15035550100"}}
+
+	if err := notifier.Notify(geofence, newResult()); err != nil {
+		t.Fatal(err)
+	}
+	if user != "AC123" || !strings.HasPrefix(body, "+15035550100 1 new crime in home:") {
+		t.Error("Wrong SMS request: ", user, body)
+	}
+}
+
+func TestAlerterSMS(t *testing.T) {
+	store, _ := NewStore("")
+	store.Create(Geofence{Polygon: lloyd, Target: Target{SMS: "+15035550100"}})
+	low, _ := store.Create(Geofence{Polygon: lloyd, Target: Target{SMS: "+15035550101", SMSOptions: &SMSOptions{MinSeverity: LowSeverity}}})
+	notifier := &recordingNotifier{}
+	alerter := &Alerter{Store: store, SMS: notifier}
+
+	if errs := alerter.Alert(newResult()); len(errs) != 0 {
+		t.Error("Alerter returned errors: ", errs)
+	}
+	if len(notifier.notified) != 1 || notifier.notified[0] != low.Id {
+		t.Error("Alerter sent SMS to the wrong geofences: ", notifier.notified)
+	}
+}
@@ -0,0 +1,356 @@
+package alerts
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ErrNotFound is returned for a geofence or API key id that isn't in a
+// Store.
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned by a SharedBackend's Save when someone else has
+// saved since it last loaded or saved, so saving would lose their changes.
+var ErrConflict = errors.New("store changed since it was loaded")
+
+// STORE_SAVE_ATTEMPTS is how many times a Store reloads a shared backend
+// and makes a change again when its save conflicts with someone else's.
+const STORE_SAVE_ATTEMPTS = 5
+
+// The version of the store's file format. Files written by older versions
+// are migrated when they're loaded:
+//
+//  1. A JSON array of geofences.
+//  2. An object with the version, geofences and API keys.
+const STORE_VERSION = 2
+
+// storeFile is the contents of a store's file.
+type storeFile struct {
+	Version   int         `json:"version"`
+	Geofences []*Geofence `json:"geofences"`
+	Keys      []*APIKey   `json:"api_keys"`
+}
+
+// migrations[i] upgrades the data of a version i+1 file to version i+2.
+var migrations = []func(data []byte) ([]byte, error){
+	func(data []byte) ([]byte, error) {
+		return json.Marshal(map[string]interface{}{"version": 2, "geofences": json.RawMessage(data)})
+	},
+}
+
+// fileVersion returns the format version of a store's file.
+func fileVersion(data []byte) (int, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		return 1, nil
+	}
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, err
+	}
+	if header.Version < 2 {
+		return 0, errors.New("store file has no version")
+	}
+	return header.Version, nil
+}
+
+// A Backend is where a Store saves its geofences and keys.
+type Backend interface {
+	// Load returns the saved data, or nil if nothing has been saved.
+	Load() ([]byte, error)
+	Save(data []byte) error
+}
+
+// A SharedBackend is a Backend that other processes save to as well, like
+// the other instances of a server behind a load balancer. A Store reloads
+// a SharedBackend's data whenever it has changed.
+type SharedBackend interface {
+	Backend
+	// Changed returns true if the data has been saved by anyone else since
+	// this backend last loaded or saved it. Save must return ErrConflict
+	// instead of saving in that case.
+	Changed() (bool, error)
+}
+
+// A fileBackend saves data to a file.
+type fileBackend struct {
+	filename string
+}
+
+func (backend fileBackend) Load() ([]byte, error) {
+	data, err := os.ReadFile(backend.filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Save writes data to the file, replacing it only once the new file is
+// complete and synced to disk.
+func (backend fileBackend) Save(data []byte) error {
+	temp := backend.filename + ".tmp"
+	f, err := os.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(temp, backend.filename)
+}
+
+// A Store holds geofences and the API keys that may manage them. If it has
+// a Backend, it saves every change there, so they survive restarts. A
+// Store is safe to use from several goroutines.
+type Store struct {
+	backend   Backend
+	mu        sync.RWMutex
+	geofences map[string]*Geofence
+	keys      map[string]*APIKey
+}
+
+// NewStore creates a Store that saves to filename and loads what's already
+// saved there, migrating it from older versions of the file format. If
+// filename is empty, everything is only kept in memory.
+func NewStore(filename string) (*Store, error) {
+	if filename == "" {
+		return NewStoreWithBackend(nil)
+	}
+	return NewStoreWithBackend(fileBackend{filename})
+}
+
+// NewStoreWithBackend creates a Store that saves to backend and loads
+// what's already saved there. If backend is nil, everything is only kept
+// in memory.
+func NewStoreWithBackend(backend Backend) (*Store, error) {
+	store := &Store{backend: backend, geofences: make(map[string]*Geofence), keys: make(map[string]*APIKey)}
+	if backend == nil {
+		return store, nil
+	}
+	data, err := backend.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := store.load(data); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// load replaces the store's contents with data, migrating it from older
+// versions of the file format. The caller must hold the lock, or be
+// creating the store.
+func (store *Store) load(data []byte) error {
+	geofences, keys := make(map[string]*Geofence), make(map[string]*APIKey)
+	if data != nil {
+		version, err := fileVersion(data)
+		if err != nil {
+			return err
+		}
+		if version > STORE_VERSION {
+			return fmt.Errorf("store file is version %v, newer than %v", version, STORE_VERSION)
+		}
+		for ; version < STORE_VERSION; version++ {
+			if data, err = migrations[version-1](data); err != nil {
+				return err
+			}
+		}
+		var file storeFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return err
+		}
+		for _, geofence := range file.Geofences {
+			geofences[geofence.Id] = geofence
+		}
+		for _, key := range file.Keys {
+			keys[key.Id] = key
+		}
+	}
+	store.geofences, store.keys = geofences, keys
+	return nil
+}
+
+// refresh reloads the store if its backend is shared and has changed.
+func (store *Store) refresh() error {
+	shared, ok := store.backend.(SharedBackend)
+	if !ok {
+		return nil
+	}
+	changed, err := shared.Changed()
+	if err != nil || !changed {
+		return err
+	}
+	data, err := shared.Load()
+	if err != nil {
+		return err
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.load(data)
+}
+
+// newId returns a random id for a geofence or API key.
+func newId() (string, error) {
+	return randomHex(8)
+}
+
+// randomHex returns size random bytes, hex encoded.
+func randomHex(size int) (string, error) {
+	id := make([]byte, size)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// list returns the geofences sorted by id. The caller must hold the lock.
+func (store *Store) list() []Geofence {
+	geofences := make([]Geofence, 0, len(store.geofences))
+	for _, geofence := range store.geofences {
+		geofences = append(geofences, *geofence)
+	}
+	sort.Slice(geofences, func(i, j int) bool {
+		return geofences[i].Id < geofences[j].Id
+	})
+	return geofences
+}
+
+// save writes the store to its backend. The caller must hold the lock.
+func (store *Store) save() error {
+	if store.backend == nil {
+		return nil
+	}
+	file := storeFile{Version: STORE_VERSION, Geofences: make([]*Geofence, 0), Keys: store.listKeys()}
+	for _, geofence := range store.list() {
+		geofence := geofence
+		file.Geofences = append(file.Geofences, &geofence)
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.backend.Save(data)
+}
+
+// List returns every geofence, sorted by id. If a shared backend can't be
+// reached, it returns the geofences the store last loaded.
+func (store *Store) List() []Geofence {
+	store.refresh()
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.list()
+}
+
+// Get returns the geofence with id.
+func (store *Store) Get(id string) (Geofence, error) {
+	if err := store.refresh(); err != nil {
+		return Geofence{}, err
+	}
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	geofence, ok := store.geofences[id]
+	if !ok {
+		return Geofence{}, ErrNotFound
+	}
+	return *geofence, nil
+}
+
+// change makes a change to the store with apply, which the caller runs
+// with the lock held, and saves it. If the save conflicts with another
+// process's, change reloads the store and applies the change again, so
+// neither process's change is lost.
+func (store *Store) change(apply func() error) error {
+	var err error
+	for attempt := 0; attempt < STORE_SAVE_ATTEMPTS; attempt++ {
+		if err = store.refresh(); err != nil {
+			return err
+		}
+		if err = store.tryChange(apply); err != ErrConflict {
+			return err
+		}
+	}
+	return err
+}
+
+// tryChange applies a change and saves it, undoing the change if either
+// fails.
+func (store *Store) tryChange(apply func() error) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	geofences := make(map[string]*Geofence, len(store.geofences))
+	for id, geofence := range store.geofences {
+		geofences[id] = geofence
+	}
+	keys := make(map[string]*APIKey, len(store.keys))
+	for id, key := range store.keys {
+		keys[id] = key
+	}
+	err := apply()
+	if err == nil {
+		err = store.save()
+	}
+	if err != nil {
+		store.geofences, store.keys = geofences, keys
+	}
+	return err
+}
+
+// Create validates geofence, gives it a new id and adds it to the store.
+func (store *Store) Create(geofence Geofence) (Geofence, error) {
+	if err := geofence.Validate(); err != nil {
+		return geofence, err
+	}
+	id, err := newId()
+	if err != nil {
+		return geofence, err
+	}
+	geofence.Id = id
+	err = store.change(func() error {
+		created := geofence
+		store.geofences[id] = &created
+		return nil
+	})
+	return geofence, err
+}
+
+// Update validates geofence and replaces the geofence with its id.
+func (store *Store) Update(geofence Geofence) error {
+	if err := geofence.Validate(); err != nil {
+		return err
+	}
+	return store.change(func() error {
+		if _, ok := store.geofences[geofence.Id]; !ok {
+			return ErrNotFound
+		}
+		updated := geofence
+		store.geofences[geofence.Id] = &updated
+		return nil
+	})
+}
+
+// Delete removes the geofence with id.
+func (store *Store) Delete(id string) error {
+	return store.change(func() error {
+		if _, ok := store.geofences[id]; !ok {
+			return ErrNotFound
+		}
+		delete(store.geofences, id)
+		return nil
+	})
+}
@@ -0,0 +1,191 @@
+package alerts
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	store, err := NewStore("")
+	if err != nil {
+		t.Fatal("Error creating Store: ", err)
+	}
+	geofence, err := store.Create(Geofence{Name: "home", Center: &Point{45.5, -122.6}, RadiusMiles: 0.5, Target: webhook})
+	if err != nil || geofence.Id == "" {
+		t.Fatal("Error creating a geofence: ", err)
+	}
+	if _, err := store.Create(Geofence{Target: webhook}); err != errNoArea {
+		t.Error("Store should not create an invalid geofence: ", err)
+	}
+	geofence.Name = "work"
+	if err := store.Update(geofence); err != nil {
+		t.Error("Error updating a geofence: ", err)
+	}
+	if found, err := store.Get(geofence.Id); err != nil || found.Name != "work" {
+		t.Error("Get returned the wrong geofence: ", found, err)
+	}
+	if len(store.List()) != 1 {
+		t.Error("Wrong number of geofences: ", store.List())
+	}
+	if err := store.Delete(geofence.Id); err != nil {
+		t.Error("Error deleting a geofence: ", err)
+	}
+	if _, err := store.Get(geofence.Id); err != ErrNotFound {
+		t.Error("Deleted geofence was found: ", err)
+	}
+	if err := store.Update(geofence); err != ErrNotFound {
+		t.Error("Store should not update a missing geofence: ", err)
+	}
+	if err := store.Delete(geofence.Id); err != ErrNotFound {
+		t.Error("Store should not delete a missing geofence: ", err)
+	}
+}
+
+func TestStorePersists(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "geofences.json")
+	store, err := NewStore(filename)
+	if err != nil {
+		t.Fatal("Error creating Store: ", err)
+	}
+	created, _ := store.Create(Geofence{Polygon: lloyd, Target: webhook})
+	store.Create(Geofence{Center: &Point{45.5, -122.6}, RadiusMiles: 0.5, Target: webhook})
+
+	reopened, err := NewStore(filename)
+	if err != nil {
+		t.Fatal("Error reopening Store: ", err)
+	}
+	if len(reopened.List()) != 2 {
+		t.Error("Reopened store has the wrong geofences: ", reopened.List())
+	}
+	found, err := reopened.Get(created.Id)
+	if err != nil || len(found.Polygon) != 2 || found.Target != webhook {
+		t.Error("Reopened store has the wrong geofence: ", found, err)
+	}
+}
+
+func TestStoreMigrates(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "geofences.json")
+	version1 := `[{"id": "abc", "polygon": [[{"lat": 45.53, "lng": -122.67}, {"lat": 45.53, "lng": -122.65}, {"lat": 45.54, "lng": -122.65}]], "target": {"webhook": "http://localhost/hook"}}]`
+	if err := os.WriteFile(filename, []byte(version1), 0600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewStore(filename)
+	if err != nil {
+		t.Fatal("Error migrating a version 1 store: ", err)
+	}
+	if found, err := store.Get("abc"); err != nil || found.Target != webhook {
+		t.Error("Migrated store has the wrong geofence: ", found, err)
+	}
+	if _, err := store.CreateKey("test"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filename)
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != STORE_VERSION || len(file.Geofences) != 1 {
+		t.Error("Store should save the current version: ", string(data))
+	}
+}
+
+func TestStoreNewerVersion(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "geofences.json")
+	os.WriteFile(filename, []byte(`{"version": 99}`), 0600)
+	if _, err := NewStore(filename); err == nil {
+		t.Error("NewStore should not load a file from a newer version")
+	}
+}
+
+// sharedBackend is a SharedBackend in memory, which several Stores can
+// share through their own views of it.
+type sharedBackend struct {
+	data     *[]byte
+	revision *int
+	seen     int
+}
+
+func (backend *sharedBackend) Load() ([]byte, error) {
+	backend.seen = *backend.revision
+	return *backend.data, nil
+}
+
+func (backend *sharedBackend) Save(data []byte) error {
+	if *backend.revision != backend.seen {
+		return ErrConflict
+	}
+	*backend.data = data
+	*backend.revision += 1
+	backend.seen = *backend.revision
+	return nil
+}
+
+func (backend *sharedBackend) Changed() (bool, error) {
+	return *backend.revision != backend.seen, nil
+}
+
+func TestStoreSharedBackend(t *testing.T) {
+	var data []byte
+	revision := 0
+	first, _ := NewStoreWithBackend(&sharedBackend{data: &data, revision: &revision})
+	second, _ := NewStoreWithBackend(&sharedBackend{data: &data, revision: &revision})
+
+	created, err := first.Create(Geofence{Polygon: lloyd, Target: webhook})
+	if err != nil {
+		t.Fatal("Error creating a geofence: ", err)
+	}
+	if found, err := second.Get(created.Id); err != nil || found.Id != created.Id {
+		t.Error("Store should see geofences saved by another store: ", err)
+	}
+	if err := second.Delete(created.Id); err != nil {
+		t.Error("Error deleting a geofence: ", err)
+	}
+	if len(first.List()) != 0 {
+		t.Error("Store should see geofences deleted by another store: ", first.List())
+	}
+}
+
+// racingBackend is a sharedBackend that lets another store save first the
+// next time it's saved to, as if both stores changed at the same moment.
+type racingBackend struct {
+	sharedBackend
+	race func()
+}
+
+func (backend *racingBackend) Save(data []byte) error {
+	if race := backend.race; race != nil {
+		backend.race = nil
+		race()
+	}
+	return backend.sharedBackend.Save(data)
+}
+
+func TestStoreSharedBackendConflict(t *testing.T) {
+	var data []byte
+	revision := 0
+	racing := &racingBackend{sharedBackend: sharedBackend{data: &data, revision: &revision}}
+	first, _ := NewStoreWithBackend(racing)
+	second, _ := NewStoreWithBackend(&sharedBackend{data: &data, revision: &revision})
+
+	var other Geofence
+	racing.race = func() {
+		var err error
+		if other, err = second.Create(Geofence{Polygon: lloyd, Target: webhook}); err != nil {
+			t.Fatal("Error creating a geofence: ", err)
+		}
+	}
+	created, err := first.Create(Geofence{Center: &Point{45.5, -122.6}, RadiusMiles: 0.5, Target: webhook})
+	if err != nil {
+		t.Fatal("Store should retry a conflicting save: ", err)
+	}
+	for _, store := range []*Store{first, second} {
+		if len(store.List()) != 2 {
+			t.Error("Store lost a change saved at the same moment: ", store.List())
+		}
+		if _, err := store.Get(created.Id); err != nil {
+			t.Error("Missing the geofence saved second: ", err)
+		}
+		if _, err := store.Get(other.Id); err != nil {
+			t.Error("Missing the geofence saved first: ", err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"runtime"
+	"sync"
+)
+
+// DEFAULT_ANALYTICS_QUEUE is how many analytics requests may wait for a
+// worker unless -analytics-queue says otherwise.
+const DEFAULT_ANALYTICS_QUEUE = 32
+
+// ANALYTICS_RETRY_SECONDS is how long a client refused by a full queue is
+// asked to wait before trying again.
+const ANALYTICS_RETRY_SECONDS = "5"
+
+var analyticsWorkers = flag.Int("analytics-workers", runtime.NumCPU(), "most expensive analytics requests, like /clusters and /stats/forecast, to run at once")
+var analyticsQueue = flag.Int("analytics-queue", DEFAULT_ANALYTICS_QUEUE, "most analytics requests to wait for a worker; more get 503 Service Unavailable")
+
+// A workerPool bounds how many requests run at once, with a queue of a
+// bounded number of requests waiting their turn. It's safe to use from
+// several goroutines.
+type workerPool struct {
+	slots    chan struct{}
+	maxQueue int
+	mu       sync.Mutex
+	queued   int
+}
+
+// newWorkerPool creates a pool of workers that queues up to queue requests.
+func newWorkerPool(workers int, queue int) *workerPool {
+	return &workerPool{slots: make(chan struct{}, workers), maxQueue: queue}
+}
+
+// analytics is the pool that the analytics endpoints run on.
+var analytics = newWorkerPool(runtime.NumCPU(), DEFAULT_ANALYTICS_QUEUE)
+
+// newAnalyticsPool returns the pool the flags ask for.
+func newAnalyticsPool() *workerPool {
+	if *analyticsWorkers < 1 || *analyticsQueue < 0 {
+		log.Fatal("Analytics need at least 1 worker, and a queue of 0 or more")
+	}
+	return newWorkerPool(*analyticsWorkers, *analyticsQueue)
+}
+
+// acquire takes a worker, waiting in the queue if they're all busy. It
+// returns false without one if the queue is full or ctx is done first.
+// A request that gets a worker must release it.
+func (pool *workerPool) acquire(ctx context.Context) bool {
+	select {
+	case pool.slots <- struct{}{}:
+		return true
+	default:
+	}
+	pool.mu.Lock()
+	if pool.queued >= pool.maxQueue {
+		pool.mu.Unlock()
+		return false
+	}
+	pool.queued += 1
+	pool.mu.Unlock()
+	defer func() {
+		pool.mu.Lock()
+		pool.queued -= 1
+		pool.mu.Unlock()
+	}()
+	select {
+	case pool.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release returns a worker to the pool.
+func (pool *workerPool) release() {
+	<-pool.slots
+}
+
+// pooled wraps an expensive handler so that it runs on the analytics pool,
+// and can't take every CPU from cheap searches. A request that finds the
+// queue full gets 503 Service Unavailable. The handler should take the
+// finder's lock itself, so that a queued request doesn't hold it.
+func pooled(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pool := analytics
+		if !pool.acquire(r.Context()) {
+			w.Header().Set("Retry-After", ANALYTICS_RETRY_SECONDS)
+			http.Error(w, http.StatusText(503), 503)
+			return
+		}
+		defer pool.release()
+		handler(w, r)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool(t *testing.T) {
+	pool := newWorkerPool(1, 1)
+	ctx := context.Background()
+	if !pool.acquire(ctx) {
+		t.Fatal("A free worker should be acquired")
+	}
+	acquired := make(chan bool)
+	go func() { acquired <- pool.acquire(ctx) }()
+	for {
+		pool.mu.Lock()
+		queued := pool.queued
+		pool.mu.Unlock()
+		if queued == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if pool.acquire(ctx) {
+		t.Error("A request shouldn't be queued when the queue is full")
+	}
+	pool.release()
+	if !<-acquired {
+		t.Error("A queued request should get the released worker")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if !newWorkerPool(1, 1).acquire(cancelled) {
+		t.Error("A free worker should be acquired even without waiting")
+	}
+	if pool.acquire(cancelled) {
+		t.Error("A request should stop waiting when its context is done")
+	}
+}
+
+func TestPooledHandlers(t *testing.T) {
+	defer func(saved *workerPool) { analytics = saved }(analytics)
+	analytics = newWorkerPool(1, 0)
+	analytics.acquire(context.Background())
+
+	resp := get(t, "/clusters?bbox=45.5,-122.7,45.52,-122.68&zoom=10")
+	if resp.Code != 503 || resp.Header().Get("Retry-After") == "" {
+		t.Error("An analytics request should be refused when the pool is full: ", resp.Code)
+	}
+	if resp := get(t, "/crimes/near/45.53435699129174/-122.66469510763777"); resp.Code != 200 {
+		t.Error("Searches shouldn't wait for the analytics pool: ", resp.Code)
+	}
+	analytics.release()
+	if resp := get(t, "/clusters?bbox=45.5,-122.7,45.52,-122.68&zoom=10"); resp.Code != 200 {
+		t.Error("An analytics request should run on a free worker: ", resp.Code)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+var anomalyInterval = flag.Duration("anomaly-interval", time.Hour, "how often to look for grid cells with unusually many recent crimes, or 0 not to look in the background")
+var anomalyWindow = flag.Duration("anomaly-window", radar.DEFAULT_ANOMALY_WINDOW, "length of the recent period that anomalies are found in")
+var anomalyPeriods = flag.Int("anomaly-periods", radar.DEFAULT_ANOMALY_PERIODS, "most periods of history that the recent period is compared with")
+var anomalySeasons = flag.Int("anomaly-seasons", radar.DEFAULT_ANOMALY_SEASONS, "most years of history whose same period the recent period is also compared with, so that yearly patterns aren't anomalies")
+var anomalyThreshold = flag.Float64("anomaly-threshold", radar.DEFAULT_ANOMALY_THRESHOLD, "standard deviations above its mean that a cell's recent count must be to be an anomaly")
+
+// anomaliesLock guards anomalies.
+var anomaliesLock sync.Mutex
+
+// anomalies is the report of the last background analysis, or nil if there
+// hasn't been one.
+var anomalies *radar.AnomalyReport
+
+// anomalyOptions returns the options for finding anomalies that the flags
+// ask for.
+func anomalyOptions() radar.AnomalyOptions {
+	return radar.AnomalyOptions{Window: *anomalyWindow, Periods: *anomalyPeriods, Seasons: *anomalySeasons, Threshold: *anomalyThreshold}
+}
+
+// analyzeAnomalies finds the anomalies in the data being served and keeps
+// them for /stats/anomalies. If alert is true, the webhooks of geofences
+// hear about the anomalies that weren't in the last analysis. The first
+// analysis has nothing to compare with, so it never alerts.
+func analyzeAnomalies(alert bool) {
+	finderLock.RLock()
+	report := finder.FindAnomalies(anomalyOptions())
+	finderLock.RUnlock()
+	anomaliesLock.Lock()
+	previous := anomalies
+	anomalies = &report
+	anomaliesLock.Unlock()
+	if previous == nil || !alert {
+		return
+	}
+	fresh := newAnomalies(*previous, report)
+	if len(fresh.Anomalies) == 0 {
+		return
+	}
+	log.Printf("Found %v new anomalies in the crimes from %v to %v", len(fresh.Anomalies), fresh.From.Format("2006-01-02"), fresh.To.Format("2006-01-02"))
+	for _, err := range alerter.AlertAnomalies(fresh) {
+		log.Println("Could not send an alert:", err)
+	}
+}
+
+// newAnomalies returns the anomalies in report whose cells weren't
+// anomalies in previous.
+func newAnomalies(previous radar.AnomalyReport, report radar.AnomalyReport) radar.AnomalyReport {
+	seen := make(map[radar.GridCell]bool, len(previous.Anomalies))
+	for _, anomaly := range previous.Anomalies {
+		seen[anomaly.Cell] = true
+	}
+	fresh := report
+	fresh.Anomalies = make([]radar.Anomaly, 0)
+	for _, anomaly := range report.Anomalies {
+		if !seen[anomaly.Cell] {
+			fresh.Anomalies = append(fresh.Anomalies, anomaly)
+		}
+	}
+	return fresh
+}
+
+// reanalyzeAnomalies looks for anomalies every interval, forever.
+func reanalyzeAnomalies(interval time.Duration, alert bool) {
+	for range time.Tick(interval) {
+		analyzeAnomalies(alert)
+	}
+}
+
+// anomaliesHandler returns the grid cells with unusually many recent
+// crimes, from the last background analysis. Searches of past data, and
+// servers that don't analyze in the background, find them for the request.
+func anomaliesHandler(w http.ResponseWriter, r *http.Request) {
+	anomaliesLock.Lock()
+	report := anomalies
+	anomaliesLock.Unlock()
+	if searched := finderFor(r); searched != &finder || report == nil {
+		found := searched.FindAnomalies(anomalyOptions())
+		report = &found
+	}
+	resp, err := report.ToJson()
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	count := len(report.Anomalies)
+	writeJson(w, r, resp, responseMeta{Count: &count})
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abrookins/radar/alerts"
+	"github.com/abrookins/radar/crimes"
+)
+
+func TestAnomaliesHandler(t *testing.T) {
+	defer func() { anomalies = nil }()
+	resp := get(t, "/stats/anomalies")
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	var report struct {
+		From      string
+		To        string
+		Periods   int
+		Anomalies []struct{ Cell string }
+	}
+	if err := json.Unmarshal(data(t, resp), &report); err != nil {
+		t.Fatal("Response was not an anomaly report: ", resp.Body.String())
+	}
+	if report.From != "2011-12-02" || report.To != "2011-12-31" || report.Periods != 11 || report.Anomalies == nil {
+		t.Error("Wrong anomaly report: ", report)
+	}
+
+	cell := radar.GridCell{Row: 1, Col: 2}
+	anomalies = &radar.AnomalyReport{Anomalies: []radar.Anomaly{{Cell: cell}}}
+	json.Unmarshal(data(t, get(t, "/stats/anomalies")), &report)
+	if len(report.Anomalies) != 1 || report.Anomalies[0].Cell != "1:2" {
+		t.Error("Handler should serve the last analysis: ", report)
+	}
+}
+
+func TestAnalyzeAnomalies(t *testing.T) {
+	defer func(store *alerts.Store, threshold float64) {
+		geofences, alerter, anomalies, *anomalyThreshold = store, newAlerter(store), nil, threshold
+	}(geofences, *anomalyThreshold)
+	notified := make(chan int, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Anomalies struct{ Anomalies []struct{ Cell string } }
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		notified <- len(body.Anomalies.Anomalies)
+	}))
+	defer server.Close()
+	geofences, _ = alerts.NewStore("")
+	alerter = newAlerter(geofences)
+	geofences.Create(alerts.Geofence{Center: &alerts.Point{Lat: 45.514, Lng: -122.664}, RadiusMiles: 1, Target: alerts.Target{Webhook: server.URL}})
+	*anomalyThreshold = 1
+
+	// The first analysis has nothing to compare with.
+	analyzeAnomalies(true)
+	if len(anomalies.Anomalies) == 0 {
+		t.Fatal("There should be anomalies at a low threshold")
+	}
+	select {
+	case <-notified:
+		t.Error("The first analysis should not alert")
+	default:
+	}
+
+	anomalies = &radar.AnomalyReport{}
+	analyzeAnomalies(true)
+	select {
+	case count := <-notified:
+		if count != 1 {
+			t.Error("Webhook was told about the wrong anomalies: ", count)
+		}
+	default:
+		t.Error("New anomalies should be alerted")
+	}
+	analyzeAnomalies(true)
+	select {
+	case <-notified:
+		t.Error("Anomalies should only be alerted when they're new")
+	default:
+	}
+}
+
+func TestNewAnomalies(t *testing.T) {
+	a, b := radar.Anomaly{Cell: radar.GridCell{Row: 1, Col: 1}}, radar.Anomaly{Cell: radar.GridCell{Row: 2, Col: 2}}
+	fresh := newAnomalies(radar.AnomalyReport{Anomalies: []radar.Anomaly{a}}, radar.AnomalyReport{Anomalies: []radar.Anomaly{a, b}})
+	if len(fresh.Anomalies) != 1 || fresh.Anomalies[0] != b {
+		t.Error("Wrong new anomalies: ", fresh.Anomalies)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abrookins/radar/alerts"
+)
+
+// The number of entries /admin/audit lists unless it's given a limit.
+const DEFAULT_AUDIT_LIMIT = 100
+
+// The actions the audit log records.
+const (
+	AuditReload          = "reload"
+	AuditIngest          = "ingest"
+	AuditGeofenceCreated = "geofence.created"
+	AuditGeofenceUpdated = "geofence.updated"
+	AuditGeofenceDeleted = "geofence.deleted"
+	AuditExportCreated   = "export.created"
+	AuditKeyCreated      = "key.created"
+	AuditKeyUsed         = "key.used"
+	AuditKeyRejected     = "key.rejected"
+)
+
+var auditLogFilename = flag.String("audit-log", "", "file to append a record of reloads, ingests, geofence changes and API key use to, listed at /admin/audit")
+
+// apiKeyKey is the key of the API key that a request was allowed with.
+const apiKeyKey contextKey = 2
+
+// An auditEntry is one action in the audit log: what was done, when, by
+// which API key from which address, and the action's details.
+type auditEntry struct {
+	Time    time.Time              `json:"time"`
+	Action  string                 `json:"action"`
+	Key     string                 `json:"key,omitempty"`
+	KeyName string                 `json:"key_name,omitempty"`
+	Address string                 `json:"address,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// An auditLog appends actions to a file as lines of JSON, and is never
+// rewritten. It's safe to use from several goroutines.
+type auditLog struct {
+	filename string
+	mu       sync.Mutex
+	file     *os.File
+}
+
+// audits records actions if the flags ask for it, and is nil if they don't.
+var audits *auditLog
+
+// openAuditLog opens filename to append actions to, creating it if it
+// doesn't exist.
+func openAuditLog(filename string) (*auditLog, error) {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLog{filename: filename, file: file}, nil
+}
+
+// loadAuditLog opens the -audit-log file, or returns nil if there isn't one.
+func loadAuditLog() *auditLog {
+	if *auditLogFilename == "" {
+		return nil
+	}
+	opened, err := openAuditLog(*auditLogFilename)
+	if err != nil {
+		log.Fatal("Could not open the audit log. ", err)
+	}
+	return opened
+}
+
+// Record logs action, done by the request r, or by the server itself if r
+// is nil, with details. The entry is written before Record returns, so
+// that it survives a crash. It does nothing if the log is nil.
+func (l *auditLog) Record(r *http.Request, action string, details map[string]interface{}) {
+	if l == nil {
+		return
+	}
+	entry := auditEntry{Time: time.Now().UTC(), Action: action, Details: details}
+	if r != nil {
+		entry.Address = clientAddress(r)
+		if key, ok := r.Context().Value(apiKeyKey).(alerts.APIKey); ok {
+			entry.Key, entry.KeyName = key.Id, key.Name
+		}
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Println("Could not audit an action. ", err)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		log.Println("Could not audit an action. ", err)
+	}
+}
+
+// Query returns up to limit entries, newest first, whose action is action
+// or starts with action and a dot, and that are from since on. An empty
+// action matches every entry.
+func (l *auditLog) Query(action string, since time.Time, limit int) ([]auditEntry, error) {
+	file, err := os.Open(l.filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	matched := make([]auditEntry, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A line cut off by a crash is skipped.
+			continue
+		}
+		if entry.Time.Before(since) {
+			continue
+		}
+		if action != "" && entry.Action != action && !strings.HasPrefix(entry.Action, action+".") {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	newest := make([]auditEntry, 0, min(len(matched), limit))
+	for i := len(matched) - 1; i >= 0 && len(newest) < limit; i-- {
+		newest = append(newest, matched[i])
+	}
+	return newest, nil
+}
+
+// auditReload records a reload of the data by r, or by a signal if r is
+// nil.
+func auditReload(r *http.Request, result stageResult) {
+	details := map[string]interface{}{"version": result.Version, "crimes": result.Crimes, "promoted": result.Promoted}
+	if result.Reason != "" {
+		details["reason"] = result.Reason
+	}
+	if r == nil {
+		details["signal"] = "SIGHUP"
+	}
+	audits.Record(r, AuditReload, details)
+}
+
+// withAPIKey returns r with the API key it was allowed with.
+func withAPIKey(r *http.Request, key alerts.APIKey) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), apiKeyKey, key))
+}
+
+// auditHandler lists the entries of the audit log, newest first. The
+// "action" parameter narrows them to an action, or a kind of action like
+// "geofence", "since" to those from an RFC 3339 time on, and "limit" to a
+// number of them.
+func auditHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := map[string]interface{}{}
+	limit := DEFAULT_AUDIT_LIMIT
+	if value := params.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			http.Error(w, http.StatusText(400), 400)
+			return
+		}
+	}
+	query["limit"] = limit
+	var since time.Time
+	if value := params.Get("since"); value != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, value); err != nil {
+			http.Error(w, http.StatusText(400), 400)
+			return
+		}
+		query["since"] = value
+	}
+	action := params.Get("action")
+	if action != "" {
+		query["action"] = action
+	}
+	entries, err := audits.Query(action, since, limit)
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println("Could not read the audit log. ", err)
+		return
+	}
+	count := len(entries)
+	writeValue(w, r, 200, entries, responseMeta{Query: query, Count: &count})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abrookins/radar/alerts"
+)
+
+func TestAuditLog(t *testing.T) {
+	audited, err := openAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal("openAuditLog returned an error: ", err)
+	}
+	audited.Record(nil, AuditReload, map[string]interface{}{"crimes": 10})
+	audited.Record(nil, AuditGeofenceCreated, map[string]interface{}{"id": "a"})
+	audited.Record(nil, AuditGeofenceDeleted, map[string]interface{}{"id": "a"})
+
+	entries, err := audited.Query("", time.Time{}, 10)
+	if err != nil || len(entries) != 3 || entries[0].Action != AuditGeofenceDeleted || entries[2].Details["crimes"] != 10.0 {
+		t.Error("Wrong entries: ", entries, err)
+	}
+	if entries, _ := audited.Query("geofence", time.Time{}, 10); len(entries) != 2 {
+		t.Error("An action should match the actions of its kind: ", entries)
+	}
+	if entries, _ := audited.Query("geo", time.Time{}, 10); len(entries) != 0 {
+		t.Error("An action shouldn't match the start of a word: ", entries)
+	}
+	if entries, _ := audited.Query("", time.Time{}, 1); len(entries) != 1 || entries[0].Action != AuditGeofenceDeleted {
+		t.Error("A limit should keep the newest entries: ", entries)
+	}
+	if entries, _ := audited.Query("", time.Now().Add(time.Hour), 10); len(entries) != 0 {
+		t.Error("Entries before since should be left out: ", entries)
+	}
+	var nothing *auditLog
+	nothing.Record(nil, AuditReload, nil)
+}
+
+func TestAuditHandler(t *testing.T) {
+	defer func() {
+		geofences, _ = alerts.NewStore("")
+		audits = nil
+	}()
+	geofences, _ = alerts.NewStore("")
+	key, _ := geofences.CreateKey("operator")
+	audits, _ = openAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+
+	if resp := get(t, "/geofences"); resp.Code != 401 {
+		t.Error("Wrong status code without a key: ", resp.Code)
+	}
+	body := `{"name": "Home", "center": {"lat": 45.5184, "lng": -122.6554}, "radius_miles": 0.5, "target": {"webhook": "http://example.com/hook"}}`
+	req := httptest.NewRequest("POST", "/geofences", strings.NewReader(body))
+	req.Header.Set("X-API-Key", key.Key)
+	resp := httptest.NewRecorder()
+	newRouter().ServeHTTP(resp, req)
+	if resp.Code != 201 {
+		t.Fatal("Wrong status code creating a geofence: ", resp.Code, resp.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/admin/audit?action=geofence", nil)
+	req.Header.Set("X-API-Key", key.Key)
+	resp = httptest.NewRecorder()
+	newRouter().ServeHTTP(resp, req)
+	var entries []auditEntry
+	if err := json.Unmarshal(data(t, resp), &entries); err != nil || len(entries) != 1 {
+		t.Fatal("Wrong entries: ", resp.Body.String())
+	}
+	if entries[0].Action != AuditGeofenceCreated || entries[0].Key != key.Id || entries[0].KeyName != "operator" {
+		t.Error("Wrong entry: ", entries[0])
+	}
+	all, _ := audits.Query("key", time.Time{}, 10)
+	if len(all) != 3 || all[2].Action != AuditKeyRejected || all[0].Details["path"] != "/admin/audit" {
+		t.Error("Requests with and without a key should be audited: ", all)
+	}
+	for _, params := range []string{"limit=0", "since=yesterday"} {
+		req = httptest.NewRequest("GET", "/admin/audit?"+params, nil)
+		req.Header.Set("X-API-Key", key.Key)
+		resp = httptest.NewRecorder()
+		newRouter().ServeHTTP(resp, req)
+		if resp.Code != 400 {
+			t.Error("Wrong status code for invalid parameters: ", params, resp.Code)
+		}
+	}
+}
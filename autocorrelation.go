@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// autocorrelationHandler returns global and local Moran's I of the crimes
+// on the grid, optionally of only those of a type, month or neighborhood.
+// The significance parameter sets the largest p-value of the clusters and
+// outliers that are returned.
+func autocorrelationHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseSearchFilter(r)
+	if err != nil {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	options := radar.AutocorrelationOptions{Filter: filter}
+	if value := r.URL.Query().Get("significance"); value != "" {
+		options.Significance, err = strconv.ParseFloat(value, 64)
+		if err != nil || options.Significance <= 0 || options.Significance >= 1 {
+			http.Error(w, http.StatusText(400), 400)
+			return
+		}
+	}
+	report := finderFor(r).Autocorrelation(options)
+	resp, err := report.ToJson()
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	count := len(report.Clusters)
+	writeJson(w, r, resp, responseMeta{Count: &count})
+}
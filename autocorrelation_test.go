@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAutocorrelationHandler(t *testing.T) {
+	resp := get(t, "/stats/autocorrelation")
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	var report struct {
+		Cells    int
+		Global   struct{ I, P_Value float64 }
+		Clusters []struct{ Quadrant string }
+	}
+	if err := json.Unmarshal(data(t, resp), &report); err != nil {
+		t.Fatal("Response was not an autocorrelation report: ", resp.Body.String())
+	}
+	if report.Cells == 0 || report.Global.I <= 0 || len(report.Clusters) == 0 {
+		t.Error("Wrong autocorrelation report: ", report)
+	}
+
+	all := len(report.Clusters)
+	json.Unmarshal(data(t, get(t, "/stats/autocorrelation?significance=0.001")), &report)
+	if len(report.Clusters) >= all {
+		t.Error("A lower significance should return fewer clusters: ", len(report.Clusters))
+	}
+	for _, path := range []string{"?significance=0", "?significance=1", "?significance=x", "?month=July"} {
+		if resp := get(t, "/stats/autocorrelation"+path); resp.Code != 400 {
+			t.Error("Bad parameters should be a bad request: ", path, resp.Code)
+		}
+	}
+}
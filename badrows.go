@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+var badRows = flag.String("bad-rows", radar.SkipBadRows, "what to do with rows of the data file that can't be loaded: skip, fail or quarantine")
+var quarantine = flag.String("quarantine", "", "file to quarantine bad rows of the data file in (default: next to the data file, ending in .quarantine.csv)")
+var ingestBadRows = flag.String("ingest-bad-rows", radar.SkipBadRows, "what to do with ingested rows that can't be loaded: skip, fail or quarantine")
+var ingestQuarantine = flag.String("ingest-quarantine", "", "file to append bad ingested rows to, for -ingest-bad-rows quarantine")
+
+// badRowPolicy returns the bad row policy with action and quarantine file,
+// or exits if action is unknown.
+func badRowPolicy(action string, file string) radar.BadRowPolicy {
+	for _, known := range radar.BadRowActions {
+		if action == known {
+			return radar.BadRowPolicy{Action: action, Quarantine: file}
+		}
+	}
+	log.Fatal("Unknown bad row action: ", action)
+	return radar.BadRowPolicy{}
+}
+
+// ingestRows decides what happens to ingested rows that can't be loaded.
+var ingestRows radar.BadRowPolicy
+
+// newIngestPolicy returns the bad row policy for ingested crimes that the
+// flags describe, or exits if they don't describe one.
+func newIngestPolicy() radar.BadRowPolicy {
+	policy := badRowPolicy(*ingestBadRows, *ingestQuarantine)
+	if policy.Action == radar.QuarantineBadRows && policy.Quarantine == "" {
+		log.Fatal("-ingest-bad-rows quarantine needs an -ingest-quarantine file.")
+	}
+	return policy
+}
+
+// logBadRows logs what happened to the bad rows of the file named filename.
+func logBadRows(loaded *radar.CrimeFinder, policy radar.BadRowPolicy, filename string) {
+	if len(loaded.Report.Errors) == 0 {
+		return
+	}
+	if policy.Action == radar.QuarantineBadRows {
+		log.Printf("Quarantined %v rows that could not be loaded in %v", len(loaded.Report.Errors), policy.QuarantineFile(localSource(filename)))
+		return
+	}
+	log.Printf("Skipped %v rows that could not be loaded", len(loaded.Report.Errors))
+}
+
+// isBadRows returns true if err failed a load because of bad rows.
+func isBadRows(err error) bool {
+	var badRowsError *radar.BadRowsError
+	return errors.As(err, &badRowsError)
+}
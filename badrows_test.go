@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+	"github.com/abrookins/radar/crimes/sample"
+)
+
+func TestIngestBadRows(t *testing.T) {
+	defer func() {
+		*ingest = false
+		ingestRows = radar.BadRowPolicy{}
+		finder = sample.NewFinder()
+		updateDatasetVersion()
+	}()
+	*ingest = true
+	ingestRows = badRowPolicy(radar.FailOnBadRows, "")
+	body := "99000001,12/31/2011,23:00:00,Burglary,,,,,45.531,-122.661\n99000002,12/31/2011,23:00:00,Burglary,,,,,,\n"
+	if resp := request(t, "POST", "/crimes", body); resp.Code != 422 {
+		t.Error("A bad row should fail the ingest: ", resp.Code)
+	}
+	if crime, _ := finder.FindByID(99000001); crime != nil {
+		t.Error("No crime should be ingested from a failed ingest")
+	}
+
+	ingestRows = badRowPolicy(radar.SkipBadRows, "")
+	if resp := request(t, "POST", "/crimes", body); resp.Code != 201 {
+		t.Error("Bad rows should be skipped: ", resp.Code)
+	}
+	if crime, _ := finder.FindByID(99000001); crime == nil {
+		t.Error("Good rows should be ingested")
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The ways a search response is cut down to fit the size budget, in the
+// order they're tried. Each keeps the cuts of those before it.
+const (
+	// DEGRADED_COUNTS marks a response whose locations have counts of their
+	// crimes instead of the crimes.
+	DEGRADED_COUNTS = "counts"
+	// DEGRADED_TRUNCATED marks a response that has only as many of its
+	// locations, in order, as fit.
+	DEGRADED_TRUNCATED = "truncated"
+)
+
+var maxResponseBytes = flag.Int("max-response-bytes", 0, "largest search response body, in bytes, with its envelope, before its crimes are replaced with counts at each location and then its locations are cut; 0 for no limit")
+
+var errNotObject = errors.New("expected a JSON object")
+
+// A jsonField is a field of a JSON object, with its value as it was
+// encoded.
+type jsonField struct {
+	Key   string
+	Value json.RawMessage
+}
+
+// An orderedObject is a JSON object whose fields are kept in the order
+// they were in, so that cutting a response down doesn't change its shape.
+type orderedObject []jsonField
+
+func (object *orderedObject) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil {
+		return err
+	} else if token != json.Delim('{') {
+		return errNotObject
+	}
+	*object = (*object)[:0]
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		*object = append(*object, jsonField{token.(string), value})
+	}
+	_, err := decoder.Token()
+	return err
+}
+
+func (object orderedObject) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for i, field := range object {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(field.Value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// get returns the value of the field called key, or nil if there isn't one.
+func (object orderedObject) get(key string) json.RawMessage {
+	for _, field := range object {
+		if field.Key == key {
+			return field.Value
+		}
+	}
+	return nil
+}
+
+// set sets the value of the field called key, adding it to the end if
+// there isn't one.
+func (object *orderedObject) set(key string, value json.RawMessage) {
+	for i := range *object {
+		if (*object)[i].Key == key {
+			(*object)[i].Value = value
+			return
+		}
+	}
+	*object = append(*object, jsonField{key, value})
+}
+
+// overBudget returns true if resp is larger than -max-response-bytes.
+func overBudget(resp []byte) bool {
+	return *maxResponseBytes > 0 && len(resp) > *maxResponseBytes
+}
+
+// countLocations cuts resp, the JSON of a SearchResult, down to the number
+// of crimes at each location, in place of its crimes, keeping the rest of
+// the result as it is.
+func countLocations(resp []byte) ([]byte, error) {
+	var result orderedObject
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, err
+	}
+	var locations []orderedObject
+	if err := json.Unmarshal(result.get("locations"), &locations); err != nil {
+		return nil, err
+	}
+	for i, location := range locations {
+		var crimes []json.RawMessage
+		if err := json.Unmarshal(location.get("crimes"), &crimes); err != nil {
+			return nil, err
+		}
+		counted := make(orderedObject, 0, len(location))
+		for _, field := range location {
+			if field.Key == "crimes" {
+				field = jsonField{"count", json.RawMessage(strconv.Itoa(len(crimes)))}
+			}
+			counted = append(counted, field)
+		}
+		locations[i] = counted
+	}
+	encoded, err := json.Marshal(locations)
+	if err != nil {
+		return nil, err
+	}
+	result.set("locations", encoded)
+	return json.Marshal(result)
+}
+
+// truncateLocations cuts resp, the JSON of a SearchResult, down to as many
+// of its first locations as fit, and says how many were left out in its
+// "omitted_locations". If not even the result without locations fits,
+// that's returned.
+func truncateLocations(resp []byte, fits func(cut []byte) (bool, error)) ([]byte, error) {
+	var result orderedObject
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, err
+	}
+	var locations []json.RawMessage
+	if err := json.Unmarshal(result.get("locations"), &locations); err != nil {
+		return nil, err
+	}
+	keeping := func(n int) ([]byte, error) {
+		kept, err := json.Marshal(locations[:n])
+		if err != nil {
+			return nil, err
+		}
+		result.set("locations", kept)
+		result.set("omitted_locations", json.RawMessage(strconv.Itoa(len(locations)-n)))
+		return json.Marshal(result)
+	}
+	// The size only grows with the number of locations kept, so the most
+	// that fit are searched for.
+	var err error
+	n := sort.Search(len(locations)+1, func(n int) bool {
+		if err != nil {
+			return true
+		}
+		var truncated []byte
+		if truncated, err = keeping(n); err != nil {
+			return true
+		}
+		var ok bool
+		ok, err = fits(truncated)
+		return !ok
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keeping(max(n-1, 0))
+}
+
+// degradations are the cuts that are made, in order, to a search response
+// that's over the size budget, by the name a response is marked with. A cut
+// is given a test of whether what it's cut fits.
+var degradations = []struct {
+	name string
+	cut  func(resp []byte, fits func(cut []byte) (bool, error)) ([]byte, error)
+}{
+	{DEGRADED_COUNTS, func(resp []byte, fits func(cut []byte) (bool, error)) ([]byte, error) {
+		return countLocations(resp)
+	}},
+	{DEGRADED_TRUNCATED, truncateLocations},
+}
+
+// budgetSearch cuts resp, the JSON of a SearchResult, down until the body
+// that encode makes of it fits the size budget or it can't be cut any
+// further. It returns the body, and the last way it was degraded, if it
+// was, which encode is told so that it can mark the body.
+func budgetSearch(resp []byte, encode func(resp []byte, degraded string) ([]byte, error)) ([]byte, string, error) {
+	body, err := encode(resp, "")
+	if err != nil {
+		return nil, "", err
+	}
+	degraded := ""
+	for _, degradation := range degradations {
+		if !overBudget(body) {
+			break
+		}
+		name := degradation.name
+		fits := func(cut []byte) (bool, error) {
+			body, err := encode(cut, name)
+			return err == nil && !overBudget(body), err
+		}
+		if resp, err = degradation.cut(resp, fits); err != nil {
+			return nil, "", err
+		}
+		degraded = name
+		if body, err = encode(resp, degraded); err != nil {
+			return nil, "", err
+		}
+	}
+	return body, degraded, nil
+}
+
+// writeSearchJson works like writeJson for resp, the JSON of a
+// SearchResult, cutting it down if the body, with its envelope, is over
+// the size budget. A response that was cut down says how in its meta's
+// "degraded" and its X-Radar-Degraded header.
+func writeSearchJson(w http.ResponseWriter, r *http.Request, resp []byte, meta responseMeta) {
+	// The time is measured once, so that every encoding of the response is
+	// the same size.
+	if start, ok := r.Context().Value(startKey).(time.Time); ok {
+		meta.TookMs = float64(time.Since(start)) / float64(time.Millisecond)
+	}
+	errStatus := 500
+	body, degraded, err := budgetSearch(resp, func(resp []byte, degraded string) ([]byte, error) {
+		marked := meta
+		marked.Degraded = degraded
+		body, status, err := encodeJson(r, resp, marked)
+		errStatus = status
+		return body, err
+	})
+	if err != nil {
+		writeEncodeError(w, errStatus, err)
+		return
+	}
+	if degraded != "" {
+		w.Header().Set("X-Radar-Degraded", degraded)
+	}
+	writeBody(w, r, 200, body)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCountLocations(t *testing.T) {
+	resp := `{"query":{"lat":1,"lng":2},"locations":[{"point":{"lat":1,"lng":2},"distance_miles":0,"crimes":[{"id":1},{"id":2}]}]}`
+	counted, err := countLocations([]byte(resp))
+	expected := `{"query":{"lat":1,"lng":2},"locations":[{"point":{"lat":1,"lng":2},"distance_miles":0,"count":2}]}`
+	if err != nil || string(counted) != expected {
+		t.Error("Wrong counts: ", string(counted), err)
+	}
+}
+
+// fitsBudget tells truncateLocations whether what it's cut fits the size
+// budget as it is.
+func fitsBudget(cut []byte) (bool, error) {
+	return !overBudget(cut), nil
+}
+
+// unencoded is an encoder for budgetSearch that leaves a response as it is.
+func unencoded(resp []byte, degraded string) ([]byte, error) {
+	return resp, nil
+}
+
+func TestTruncateLocations(t *testing.T) {
+	defer func(saved int) { *maxResponseBytes = saved }(*maxResponseBytes)
+	resp := `{"query":{},"locations":[{"count":1},{"count":2},{"count":3}]}`
+
+	*maxResponseBytes = len(`{"query":{},"locations":[{"count":1},{"count":2}],"omitted_locations":1}`)
+	truncated, err := truncateLocations([]byte(resp), fitsBudget)
+	expected := `{"query":{},"locations":[{"count":1},{"count":2}],"omitted_locations":1}`
+	if err != nil || string(truncated) != expected {
+		t.Error("Wrong truncated locations: ", string(truncated), err)
+	}
+
+	*maxResponseBytes = 1
+	truncated, err = truncateLocations([]byte(resp), fitsBudget)
+	expected = `{"query":{},"locations":[],"omitted_locations":3}`
+	if err != nil || string(truncated) != expected {
+		t.Error("A response that can't fit should have no locations: ", string(truncated), err)
+	}
+}
+
+func TestBudgetSearchCutsUntilItFits(t *testing.T) {
+	defer func(saved int) { *maxResponseBytes = saved }(*maxResponseBytes)
+	location := `{"crimes":[{"id":1}]}`
+	resp := `{"query":{},"locations":[` + strings.Repeat(location+",", 5) + location + `]}`
+
+	// Counting the crimes isn't enough to fit this budget.
+	expected := `{"query":{},"locations":[{"count":1},{"count":1},{"count":1},{"count":1}],"omitted_locations":2}`
+	*maxResponseBytes = len(expected)
+	cut, degraded, err := budgetSearch([]byte(resp), unencoded)
+	if err != nil || degraded != DEGRADED_TRUNCATED || string(cut) != expected {
+		t.Error("Wrong response cut down to the budget: ", string(cut), degraded, err)
+	}
+
+	*maxResponseBytes = len(`{"query":{},"locations":[` + strings.Repeat(`{"count":1},`, 5) + `{"count":1}]}`)
+	cut, degraded, err = budgetSearch([]byte(resp), unencoded)
+	if err != nil || degraded != DEGRADED_COUNTS || len(cut) > *maxResponseBytes {
+		t.Error("Counts alone should fit the budget: ", string(cut), degraded, err)
+	}
+
+	*maxResponseBytes = 1
+	if cut, degraded, err = budgetSearch([]byte(resp), unencoded); err != nil || degraded != DEGRADED_TRUNCATED {
+		t.Error("A response that can't fit should be cut as far as it can be: ", string(cut), degraded, err)
+	}
+}
+
+func TestResponseBudget(t *testing.T) {
+	defer func(saved int) { *maxResponseBytes = saved }(*maxResponseBytes)
+	url := "/crimes/near/45.53435699129174/-122.66469510763777"
+
+	full := get(t, url)
+	if full.Header().Get("X-Radar-Degraded") != "" {
+		t.Error("A response without a budget shouldn't be degraded")
+	}
+	*maxResponseBytes = len(data(t, full)) - 1
+	resp := get(t, url)
+	var body struct {
+		Meta struct {
+			Count    int
+			Degraded string
+		}
+		Data struct {
+			Locations []map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal("Response was not valid JSON: ", err)
+	}
+	if body.Meta.Degraded != DEGRADED_COUNTS || resp.Header().Get("X-Radar-Degraded") != DEGRADED_COUNTS {
+		t.Error("A response over the budget should be degraded: ", body.Meta, resp.Header())
+	}
+	total := 0
+	for _, location := range body.Data.Locations {
+		if _, ok := location["crimes"]; ok {
+			t.Fatal("A degraded location shouldn't have crimes: ", location)
+		}
+		total += int(location["count"].(float64))
+	}
+	if total != body.Meta.Count || len(resp.Body.Bytes()) >= len(full.Body.Bytes()) {
+		t.Error("Wrong degraded response: ", total, body.Meta.Count, len(resp.Body.Bytes()))
+	}
+
+	// The margin is for the time the response took, which can be longer.
+	*maxResponseBytes = len(full.Body.Bytes()) + 100
+	if resp := get(t, url); resp.Header().Get("X-Radar-Degraded") != "" {
+		t.Error("A response within the budget shouldn't be degraded")
+	}
+}
+
+func TestResponseBudgetCountsEnvelope(t *testing.T) {
+	defer func(saved int) { *maxResponseBytes = saved }(*maxResponseBytes)
+	url := "/crimes/near/45.53435699129174/-122.66469510763777"
+	full := get(t, url)
+
+	// The search result alone fits, but not with its envelope.
+	*maxResponseBytes = len(data(t, full)) + 10
+	for _, url := range []string{url, url + "?case=camel"} {
+		resp := get(t, url)
+		if resp.Code != 200 || resp.Header().Get("X-Radar-Degraded") == "" {
+			t.Error("A response over the budget with its envelope should be degraded: ", url, resp.Code)
+		}
+		if len(resp.Body.Bytes()) > *maxResponseBytes {
+			t.Error("A response should fit the budget with its envelope: ", url, len(resp.Body.Bytes()), *maxResponseBytes)
+		}
+	}
+
+	// Small enough budgets cut locations, and still count the envelope.
+	counted := get(t, url)
+	*maxResponseBytes = len(counted.Body.Bytes()) - 1
+	resp := get(t, url)
+	if resp.Header().Get("X-Radar-Degraded") != DEGRADED_TRUNCATED || len(resp.Body.Bytes()) > *maxResponseBytes {
+		t.Error("Wrong truncated response: ", resp.Header(), len(resp.Body.Bytes()), *maxResponseBytes)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+var cachePolicyFilename = flag.String("cache-policy", "", "JSON file of how long caches may keep responses, by path prefix, replacing the default policy")
+
+// A cacheRule is how long caches, like a CDN in front of the server, may
+// keep the responses to GET requests whose paths start with Prefix. A MaxAge
+// of 0 means they mustn't keep them at all.
+type cacheRule struct {
+	Prefix string
+	MaxAge time.Duration
+}
+
+// UnmarshalJSON reads a rule like {"prefix": "/crimes/near", "max_age": "5m"}.
+func (rule *cacheRule) UnmarshalJSON(data []byte) error {
+	var value struct {
+		Prefix string `json:"prefix"`
+		MaxAge string `json:"max_age"`
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	maxAge, err := time.ParseDuration(value.MaxAge)
+	if err != nil || maxAge < 0 {
+		return fmt.Errorf("invalid max_age for %v: %q", value.Prefix, value.MaxAge)
+	}
+	*rule = cacheRule{value.Prefix, maxAge}
+	return nil
+}
+
+// header returns the Cache-Control header of a response with status.
+// Only successful responses are kept, so that a cache doesn't serve an
+// error after the server recovers.
+func (rule cacheRule) header(status int) string {
+	if rule.MaxAge <= 0 || status < 200 || status > 299 {
+		return "no-store"
+	}
+	return fmt.Sprintf("public, max-age=%v", int(rule.MaxAge.Seconds()))
+}
+
+// A cachePolicy is a list of cache rules. The rule with the longest prefix
+// that matches a request's path applies to it, and responses to requests
+// that no rule matches get no Cache-Control header.
+type cachePolicy []cacheRule
+
+// defaultCachePolicy lets caches keep searches for a few minutes, since
+// ingested crimes should show up soon, and descriptions of the data for a
+// day. Admin endpoints and those that need an API key are never kept.
+var defaultCachePolicy = cachePolicy{
+	{"/crimes/", 5 * time.Minute},
+	{"/locations/", 5 * time.Minute},
+	{"/clusters", 5 * time.Minute},
+	{"/score/", 5 * time.Minute},
+	{"/neighborhoods", 5 * time.Minute},
+	{"/suggest", 5 * time.Minute},
+	{"/widget", WIDGET_MAX_AGE},
+	{"/stats/", time.Hour},
+	{"/meta/", 24 * time.Hour},
+	{"/admin/", 0},
+	{"/exports", 0},
+	{"/geofences", 0},
+	{"/snapshot", 0},
+}
+
+// cacheRules is the cache policy of the server's responses.
+var cacheRules = defaultCachePolicy
+
+// loadCachePolicy returns the policy in the -cache-policy file, or the
+// default policy if there isn't one.
+func loadCachePolicy() cachePolicy {
+	if *cachePolicyFilename == "" {
+		return defaultCachePolicy
+	}
+	policy, err := readCachePolicy(*cachePolicyFilename)
+	if err != nil {
+		log.Fatal("Could not load cache policy. ", err)
+	}
+	return policy
+}
+
+// readCachePolicy reads a cache policy from a JSON file listing its rules.
+func readCachePolicy(filename string) (cachePolicy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	policy := make(cachePolicy, 0)
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, err
+	}
+	for _, rule := range policy {
+		if !strings.HasPrefix(rule.Prefix, "/") {
+			return nil, errors.New("cache rule prefixes must start with /")
+		}
+	}
+	return policy, nil
+}
+
+// rule returns the rule that applies to path, and false if none does.
+func (policy cachePolicy) rule(path string) (cacheRule, bool) {
+	var matched cacheRule
+	found := false
+	for _, rule := range policy {
+		if strings.HasPrefix(path, rule.Prefix) && (!found || len(rule.Prefix) > len(matched.Prefix)) {
+			matched, found = rule, true
+		}
+	}
+	return matched, found
+}
+
+// cacheHeaderWriter sets the Cache-Control header of a response from its
+// rule when its status is written, unless the handler set one.
+type cacheHeaderWriter struct {
+	http.ResponseWriter
+	rule        cacheRule
+	wroteHeader bool
+}
+
+func (w *cacheHeaderWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if w.Header().Get("Cache-Control") == "" {
+			w.Header().Set("Cache-Control", w.rule.header(status))
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *cacheHeaderWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// cacheHeaders is middleware that gives the responses to GET and HEAD
+// requests the Cache-Control header of cacheRules. Other methods change
+// things, so caches don't keep their responses anyway.
+func cacheHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rule, ok := cacheRules.rule(r.URL.Path)
+		if !ok || (r.Method != "GET" && r.Method != "HEAD") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(&cacheHeaderWriter{ResponseWriter: w, rule: rule}, r)
+	})
+}
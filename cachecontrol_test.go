@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheHeaders(t *testing.T) {
+	headers := map[string]string{
+		"/crimes/near/45.5184/-122.6554":        "public, max-age=300",
+		"/crimes/near/45.5184/-122.6554?month=": "public, max-age=300",
+		"/meta/bounds":                          "public, max-age=86400",
+		"/stats/anomalies":                      "public, max-age=3600",
+		"/widget.js":                            "public, max-age=300",
+		"/crimes/near/45.5184/-122.6554?sort=a": "no-store",
+		"/admin/usage":                          "no-store",
+		"/nowhere":                              "",
+	}
+	for url, expected := range headers {
+		if header := get(t, url).Header().Get("Cache-Control"); header != expected {
+			t.Error("Wrong Cache-Control for ", url, ": ", header)
+		}
+	}
+	route := `{"points":[{"lat":45.5231,"lng":-122.6765},{"lat":45.5262,"lng":-122.668}]}`
+	if header := request(t, "POST", "/score/route", route).Header().Get("Cache-Control"); header != "" {
+		t.Error("POST responses should have no Cache-Control: ", header)
+	}
+}
+
+func TestCachePolicyRule(t *testing.T) {
+	policy := cachePolicy{{"/crimes/", time.Minute}, {"/crimes/near", time.Hour}}
+	if rule, ok := policy.rule("/crimes/near/45.5/-122.6"); !ok || rule.MaxAge != time.Hour {
+		t.Error("The longest matching prefix should apply: ", rule)
+	}
+	if rule, ok := policy.rule("/crimes/1"); !ok || rule.MaxAge != time.Minute {
+		t.Error("Wrong rule: ", rule)
+	}
+	if _, ok := policy.rule("/meta/bounds"); ok {
+		t.Error("No rule should apply")
+	}
+}
+
+func TestReadCachePolicy(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cache.json")
+	os.WriteFile(filename, []byte(`[{"prefix": "/crimes/near", "max_age": "10m"}, {"prefix": "/meta/", "max_age": "0s"}]`), 0644)
+	policy, err := readCachePolicy(filename)
+	if err != nil || len(policy) != 2 || policy[0] != (cacheRule{"/crimes/near", 10 * time.Minute}) {
+		t.Fatal("Wrong policy: ", policy, err)
+	}
+	if header := policy[1].header(200); header != "no-store" {
+		t.Error("A max age of 0 should not be kept: ", header)
+	}
+	for _, bad := range []string{`[{"prefix": "/meta/", "max_age": "a day"}]`, `[{"prefix": "meta", "max_age": "1h"}]`, `{}`} {
+		os.WriteFile(filename, []byte(bad), 0644)
+		if _, err := readCachePolicy(filename); err == nil {
+			t.Error("Invalid policy should be an error: ", bad)
+		}
+	}
+}
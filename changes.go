@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// changesLock guards lastChanges and removed.
+var changesLock sync.Mutex
+
+// lastChanges is how the data changed the last time it was refreshed, or
+// nil if it hasn't been.
+var lastChanges *radar.Changes
+
+// removed holds the crimes that refreshes have removed, by id, so that
+// looking one up says it's gone rather than that it never existed.
+var removed = make(map[int64]radar.ChangedCrime)
+
+// recordChanges keeps changes for /meta/changes and remembers the crimes
+// they removed. If alert is true, it tells the webhooks of geofences
+// about removed and corrected crimes inside them.
+func recordChanges(changes radar.Changes, alert bool) {
+	if changes.IsEmpty() {
+		return
+	}
+	changes.BaseURL = *baseURL
+	changesLock.Lock()
+	lastChanges = &changes
+	for _, crime := range changes.Removed {
+		removed[crime.Crime.Id] = crime
+	}
+	changesLock.Unlock()
+	log.Printf("The data changed from version %v: %v crimes added, %v removed and %v corrected",
+		changes.From, changes.Added, len(changes.Removed), len(changes.Corrected))
+	if alert && (len(changes.Removed) > 0 || len(changes.Corrected) > 0) {
+		go func() {
+			for _, err := range alerter.AlertChanges(changes) {
+				log.Println("Could not send an alert:", err)
+			}
+		}()
+	}
+}
+
+// removedCrime returns the crime with id if a refresh removed it.
+func removedCrime(id int64) (radar.ChangedCrime, bool) {
+	changesLock.Lock()
+	defer changesLock.Unlock()
+	crime, ok := removed[id]
+	return crime, ok
+}
+
+// changesHandler describes how the data changed the last time it was
+// refreshed. If it hasn't been, there are no changes.
+func changesHandler(w http.ResponseWriter, r *http.Request) {
+	changesLock.Lock()
+	changes := radar.Changes{From: currentDatasetVersion(), To: currentDatasetVersion(), BaseURL: *baseURL}
+	if lastChanges != nil {
+		changes = *lastChanges
+	}
+	changesLock.Unlock()
+	resp, err := changes.ToJson()
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	count := len(changes.Removed) + len(changes.Corrected)
+	writeJson(w, r, resp, responseMeta{Count: &count})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+	"github.com/abrookins/radar/crimes/sample"
+)
+
+func TestChanges(t *testing.T) {
+	defer func() {
+		finder = sample.NewFinder()
+		updateDatasetVersion()
+		lastChanges = nil
+		removed = make(map[int64]radar.ChangedCrime)
+	}()
+	resp := get(t, "/meta/changes")
+	if resp.Code != 200 || !strings.Contains(string(data(t, resp)), `"removed":[]`) {
+		t.Error("Data that was never refreshed should have no changes: ", resp.Body.String())
+	}
+
+	// The city removes the first crime and corrects the second.
+	contents := sample.CSV
+	lines := strings.Split(string(contents), "\n")
+	lines[2] = strings.Replace(lines[2], "Liquor Laws", "Disorderly Conduct", 1)
+	lines = append(lines[:1], lines[2:]...)
+	filename := filepath.Join(t.TempDir(), "corrected.csv")
+	os.WriteFile(filename, []byte(strings.Join(lines, "\n")), 0644)
+	corrected, err := radar.NewCrimeFinder(filename)
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	swapFinder(corrected, false)
+
+	resp = get(t, "/meta/changes")
+	var body struct {
+		Removed   []struct{ Id int64 }
+		Corrected []struct {
+			Before struct{ Type string }
+			After  struct{ Type string }
+		}
+	}
+	if err := json.Unmarshal(data(t, resp), &body); err != nil {
+		t.Fatal("Response was not valid JSON: ", err)
+	}
+	if len(body.Removed) != 1 || body.Removed[0].Id != 13807517 {
+		t.Error("Changes have the wrong removals: ", resp.Body.String())
+	}
+	if len(body.Corrected) != 1 || body.Corrected[0].After.Type != "Disorderly Conduct" {
+		t.Error("Changes have the wrong corrections: ", resp.Body.String())
+	}
+
+	resp = get(t, "/crimes/13807517")
+	if resp.Code != 410 || !strings.Contains(resp.Body.String(), `"id":13807517`) {
+		t.Error("A removed crime should be gone: ", resp.Code, resp.Body.String())
+	}
+	if resp := get(t, "/crimes/99999999999"); resp.Code != 404 {
+		t.Error("Wrong status code for a crime that never existed: ", resp.Code)
+	}
+}
@@ -0,0 +1,411 @@
+// Package client is a Go client for a radar server's HTTP API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abrookins/radar/alerts"
+)
+
+// The time a request has to complete, unless a Client has its own
+// http.Client.
+const DEFAULT_TIMEOUT = 30 * time.Second
+
+// The number of times a Client retries a request, unless told otherwise.
+const DEFAULT_RETRIES = 2
+
+// The wait before a Client's first retry. It doubles with each retry.
+const DEFAULT_BACKOFF = 200 * time.Millisecond
+
+// An Error is a response from the server with a status that isn't a
+// success, like 404 for a missing crime or 409 for an old if_version.
+type Error struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *Error) Error() string {
+	return "radar: " + e.Status
+}
+
+// IsStatus returns true if err is an Error with status code.
+func IsStatus(err error, code int) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == code
+}
+
+// A Client makes requests to a radar server. It's safe to use from several
+// goroutines.
+type Client struct {
+	// BaseURL is the server's URL, like "https://radar.example.com".
+	BaseURL string
+	// APIKey, if set, is sent in the X-API-Key header, for the endpoints
+	// that need one.
+	APIKey     string
+	HTTPClient *http.Client
+	// Retries is the number of times a request that may be repeated safely
+	// is retried after a network error or a 429, 502, 503 or 504 response.
+	// POSTs aren't retried.
+	Retries int
+	// Backoff is the wait before the first retry, doubled for each one
+	// after.
+	Backoff time.Duration
+}
+
+// NewClient creates a Client for the server at baseURL.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: DEFAULT_TIMEOUT},
+		Retries:    DEFAULT_RETRIES,
+		Backoff:    DEFAULT_BACKOFF,
+	}
+}
+
+// retryable returns true if a response with status may succeed if the
+// request is made again.
+func retryable(status int) bool {
+	return status == 429 || status == 502 || status == 503 || status == 504
+}
+
+// do makes a request and returns the response if it succeeded. The caller
+// must close its body.
+func (c *Client) do(ctx context.Context, method string, path string, body interface{}) (*http.Response, error) {
+	var encoded []byte
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	retries := c.Retries
+	if method == "POST" {
+		retries = 0
+	}
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, bytes.NewReader(encoded))
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.APIKey != "" {
+			req.Header.Set("X-API-Key", c.APIKey)
+		}
+		resp, err := c.HTTPClient.Do(req)
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		if err == nil {
+			resp.Body.Close()
+			err = &Error{resp.StatusCode, resp.Status}
+			if !retryable(resp.StatusCode) {
+				return nil, err
+			}
+		}
+		if attempt >= retries || ctx.Err() != nil {
+			return nil, err
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// call makes a request and decodes the data in the response's envelope
+// into value, returning its meta.
+func (c *Client) call(ctx context.Context, method string, path string, body interface{}, value interface{}) (Meta, error) {
+	resp, err := c.do(ctx, method, path, body)
+	if err != nil {
+		return Meta{}, err
+	}
+	defer resp.Body.Close()
+	var envelope struct {
+		Meta Meta            `json:"meta"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return Meta{}, err
+	}
+	if value != nil {
+		if err := json.Unmarshal(envelope.Data, value); err != nil {
+			return envelope.Meta, err
+		}
+	}
+	return envelope.Meta, nil
+}
+
+// NearOptions narrow a search. Empty fields don't.
+type NearOptions struct {
+	// Category is a category of the taxonomy, like "property".
+	Category string
+	Weapon   string
+	Domestic *bool
+	Arrest   *bool
+	// Type, Month and Neighborhood narrow the search to crimes of a type, in
+	// a month, like "2011-07", and in a neighborhood.
+	Type         string
+	Month        string
+	Neighborhood string
+	// Radius is the most distance, in miles, of a location from the point,
+	// up to half a mile.
+	Radius float64
+	// Limit is the most locations to return, after they're sorted by Sort:
+	// "distance", nearest first, or "recent", newest crimes first.
+	Limit int
+	Sort  string
+	// IncludeDistance asks for each location's DistanceMiles.
+	IncludeDistance bool
+	// MaxPerLocation is the most crimes to return at each location, which
+	// keeps its newest.
+	MaxPerLocation int
+	// ExcludeTypes, if it isn't nil, leaves out crimes of these types
+	// instead of the types the server leaves out by default. An empty
+	// list leaves out none.
+	ExcludeTypes []string
+	// Explain asks the server to describe how the search ran.
+	Explain bool
+	// AsOf searches the data as it was at a date or time, on a server
+	// that keeps a history.
+	AsOf string
+	// IfVersion makes the search fail with a 409 unless the data is that
+	// version.
+	IfVersion string
+}
+
+// values returns the query parameters of the options.
+func (options *NearOptions) values() url.Values {
+	values := url.Values{}
+	if options == nil {
+		return values
+	}
+	set := func(name string, value string) {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	set("category", options.Category)
+	set("weapon", options.Weapon)
+	if options.Domestic != nil {
+		set("domestic", strconv.FormatBool(*options.Domestic))
+	}
+	if options.Arrest != nil {
+		set("arrest", strconv.FormatBool(*options.Arrest))
+	}
+	set("type", options.Type)
+	set("month", options.Month)
+	set("neighborhood", options.Neighborhood)
+	if options.Radius > 0 {
+		set("radius", strconv.FormatFloat(options.Radius, 'f', -1, 64))
+	}
+	if options.Limit > 0 {
+		set("limit", strconv.Itoa(options.Limit))
+	}
+	set("sort", options.Sort)
+	if options.IncludeDistance {
+		set("include_distance", "true")
+	}
+	if options.MaxPerLocation > 0 {
+		set("max_per_location", strconv.Itoa(options.MaxPerLocation))
+	}
+	if options.ExcludeTypes != nil {
+		values.Set("exclude_types", strings.Join(options.ExcludeTypes, ","))
+	}
+	if options.Explain {
+		set("explain", "true")
+	}
+	set("as_of", options.AsOf)
+	set("if_version", options.IfVersion)
+	return values
+}
+
+// withQuery adds values to path as its query string.
+func withQuery(path string, values url.Values) string {
+	if len(values) == 0 {
+		return path
+	}
+	return path + "?" + values.Encode()
+}
+
+// FindNear searches for crimes within half a mile of lat and lng.
+func (c *Client) FindNear(ctx context.Context, lat float64, lng float64, options *NearOptions) (*SearchResult, error) {
+	path := fmt.Sprintf("/crimes/near/%v/%v", strconv.FormatFloat(lat, 'f', -1, 64), strconv.FormatFloat(lng, 'f', -1, 64))
+	var result SearchResult
+	meta, err := c.call(ctx, "GET", withQuery(path, options.values()), nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	result.Meta = meta
+	return &result, nil
+}
+
+// Crime looks up the crime with id, returning it at its location. A crime
+// that the data no longer has is an Error with status 404, or 410 if a
+// refresh removed it.
+func (c *Client) Crime(ctx context.Context, id int64) (*Location, error) {
+	var result SearchResult
+	if _, err := c.call(ctx, "GET", fmt.Sprintf("/crimes/%v", id), nil, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Locations) != 1 || len(result.Locations[0].Crimes) != 1 {
+		return nil, fmt.Errorf("radar: response for crime %v has %v locations", id, len(result.Locations))
+	}
+	return &result.Locations[0], nil
+}
+
+// Stats describes the coverage of the data, from /meta/bounds.
+func (c *Client) Stats(ctx context.Context) (*Stats, error) {
+	var stats Stats
+	meta, err := c.call(ctx, "GET", "/meta/bounds", nil, &stats)
+	if err != nil {
+		return nil, err
+	}
+	stats.Meta = meta
+	return &stats, nil
+}
+
+// NearestNeighbors returns a histogram of the distances between locations
+// and their nearest neighbors, in buckets bucketMiles wide, or the
+// server's default if it's 0.
+func (c *Client) NearestNeighbors(ctx context.Context, bucketMiles float64) (*Histogram, error) {
+	values := url.Values{}
+	if bucketMiles > 0 {
+		values.Set("bucket_miles", strconv.FormatFloat(bucketMiles, 'f', -1, 64))
+	}
+	var histogram Histogram
+	meta, err := c.call(ctx, "GET", withQuery("/meta/nearest-neighbors", values), nil, &histogram)
+	if err != nil {
+		return nil, err
+	}
+	histogram.Meta = meta
+	return &histogram, nil
+}
+
+// Clusters returns the clusters of locations inside bounds as a web map
+// shows them at zoom.
+func (c *Client) Clusters(ctx context.Context, bounds Bounds, zoom int) (*Clusters, error) {
+	values := url.Values{}
+	values.Set("bbox", fmt.Sprintf("%v,%v,%v,%v", bounds.Min.Lat, bounds.Min.Lng, bounds.Max.Lat, bounds.Max.Lng))
+	values.Set("zoom", strconv.Itoa(zoom))
+	var clusters Clusters
+	meta, err := c.call(ctx, "GET", withQuery("/clusters", values), nil, &clusters)
+	if err != nil {
+		return nil, err
+	}
+	clusters.Meta = meta
+	return &clusters, nil
+}
+
+// Changes describes how the data changed the last time it was refreshed.
+func (c *Client) Changes(ctx context.Context) (*Changes, error) {
+	var changes Changes
+	meta, err := c.call(ctx, "GET", "/meta/changes", nil, &changes)
+	if err != nil {
+		return nil, err
+	}
+	changes.Meta = meta
+	return &changes, nil
+}
+
+// Geofences lists the server's geofences.
+func (c *Client) Geofences(ctx context.Context) ([]alerts.Geofence, error) {
+	geofences := make([]alerts.Geofence, 0)
+	_, err := c.call(ctx, "GET", "/geofences", nil, &geofences)
+	return geofences, err
+}
+
+// CreateGeofence creates a geofence and returns it with its id.
+func (c *Client) CreateGeofence(ctx context.Context, geofence alerts.Geofence) (*alerts.Geofence, error) {
+	var created alerts.Geofence
+	if _, err := c.call(ctx, "POST", "/geofences", geofence, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Geofence gets the geofence with id.
+func (c *Client) Geofence(ctx context.Context, id string) (*alerts.Geofence, error) {
+	var geofence alerts.Geofence
+	if _, err := c.call(ctx, "GET", "/geofences/"+url.PathEscape(id), nil, &geofence); err != nil {
+		return nil, err
+	}
+	return &geofence, nil
+}
+
+// UpdateGeofence replaces the geofence with geofence.Id.
+func (c *Client) UpdateGeofence(ctx context.Context, geofence alerts.Geofence) error {
+	_, err := c.call(ctx, "PUT", "/geofences/"+url.PathEscape(geofence.Id), geofence, nil)
+	return err
+}
+
+// DeleteGeofence deletes the geofence with id.
+func (c *Client) DeleteGeofence(ctx context.Context, id string) error {
+	resp, err := c.do(ctx, "DELETE", "/geofences/"+url.PathEscape(id), nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// CreateExport starts an export. The job it returns can be followed with
+// Export until it's done.
+func (c *Client) CreateExport(ctx context.Context, request ExportRequest) (*ExportJob, error) {
+	var job ExportJob
+	if _, err := c.call(ctx, "POST", "/exports", request, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Export gets the export job with id.
+func (c *Client) Export(ctx context.Context, id string) (*ExportJob, error) {
+	var job ExportJob
+	if _, err := c.call(ctx, "GET", "/exports/"+url.PathEscape(id), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// WaitForExport polls the export job with id every interval until it's
+// done or failed, or ctx is done.
+func (c *Client) WaitForExport(ctx context.Context, id string, interval time.Duration) (*ExportJob, error) {
+	for {
+		job, err := c.Export(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status == ExportDone || job.Status == ExportFailed {
+			return job, nil
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// DownloadExport copies the finished export with id to w.
+func (c *Client) DownloadExport(ctx context.Context, id string, w io.Writer) error {
+	resp, err := c.do(ctx, "GET", "/exports/"+url.PathEscape(id)+"/download", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
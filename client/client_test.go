@@ -0,0 +1,160 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abrookins/radar/alerts"
+)
+
+const nearBody = `{"meta":{"query":{"lat":45.5,"lng":-122.6,"category":"property"},"took_ms":0.3,"count":1,"dataset_version":"abc-1"},
+"data":{"query":{"lat":45.5,"lng":-122.6},"locations":[{"point":{"lat":45.5,"lng":-122.6},"crimes":[{"id":1,"date":"01/01/2011","time":"01:00:00","type":"Burglary","url":"/crimes/1"}]}]}}`
+
+func newTestClient(handler http.HandlerFunc) (*Client, func()) {
+	server := httptest.NewServer(handler)
+	client := NewClient(server.URL)
+	client.Backoff = time.Millisecond
+	return client, server.Close
+}
+
+func TestFindNear(t *testing.T) {
+	var query string
+	client, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RequestURI()
+		w.Write([]byte(nearBody))
+	})
+	defer done()
+	arrest := true
+	result, err := client.FindNear(context.Background(), 45.5, -122.6, &NearOptions{Category: "property", Arrest: &arrest, Month: "2011-07"})
+	if err != nil {
+		t.Fatal("FindNear returned an error: ", err)
+	}
+	if query != "/crimes/near/45.5/-122.6?arrest=true&category=property&month=2011-07" {
+		t.Error("Wrong request: ", query)
+	}
+	crimes := result.Crimes()
+	if len(crimes) != 1 || crimes[0].Type != "Burglary" || crimes[0].URL != "/crimes/1" {
+		t.Error("Wrong crimes: ", crimes)
+	}
+	if result.Meta.DatasetVersion != "abc-1" || result.Meta.Count == nil || *result.Meta.Count != 1 {
+		t.Error("Wrong meta: ", result.Meta)
+	}
+}
+
+func TestFindNearOptions(t *testing.T) {
+	var query string
+	client, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RequestURI()
+		w.Write([]byte(nearBody))
+	})
+	defer done()
+	options := &NearOptions{Radius: 0.25, Limit: 5, Sort: "distance", IncludeDistance: true, MaxPerLocation: 2}
+	if _, err := client.FindNear(context.Background(), 45.5, -122.6, options); err != nil {
+		t.Fatal("FindNear returned an error: ", err)
+	}
+	if query != "/crimes/near/45.5/-122.6?include_distance=true&limit=5&max_per_location=2&radius=0.25&sort=distance" {
+		t.Error("Wrong request: ", query)
+	}
+	if _, err := client.FindNear(context.Background(), 45.5, -122.6, &NearOptions{ExcludeTypes: []string{}}); err != nil || query != "/crimes/near/45.5/-122.6?exclude_types=" {
+		t.Error("An empty list of excluded types should be sent: ", query, err)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	attempts := 0
+	client, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		attempts += 1
+		if attempts < 3 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte(nearBody))
+	})
+	defer done()
+	if _, err := client.FindNear(context.Background(), 45.5, -122.6, nil); err != nil || attempts != 3 {
+		t.Error("FindNear should retry until it succeeds: ", err, attempts)
+	}
+
+	attempts = 0
+	if _, err := client.CreateGeofence(context.Background(), alerts.Geofence{}); !IsStatus(err, 503) || attempts != 1 {
+		t.Error("POSTs should not be retried: ", err, attempts)
+	}
+
+	attempts = -10
+	if _, err := client.Stats(context.Background()); !IsStatus(err, 503) || attempts != -7 {
+		t.Error("Client should give up after its retries: ", err, attempts)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	client, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" {
+			w.WriteHeader(401)
+			return
+		}
+		w.WriteHeader(410)
+	})
+	defer done()
+	if _, err := client.Crime(context.Background(), 1); !IsStatus(err, 401) {
+		t.Error("Wrong error without an API key: ", err)
+	}
+	client.APIKey = "secret"
+	if _, err := client.Crime(context.Background(), 1); !IsStatus(err, 410) {
+		t.Error("Wrong error for a removed crime: ", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.Stats(ctx); err == nil {
+		t.Error("A cancelled request should fail")
+	}
+}
+
+func TestExports(t *testing.T) {
+	polls := 0
+	client, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/exports/abc":
+			polls += 1
+			status := ExportRunning
+			if polls > 1 {
+				status = ExportDone
+			}
+			w.Write([]byte(`{"meta":{"query":{}},"data":{"id":"abc","status":"` + status + `","count":2}}`))
+		case "/exports/abc/download":
+			w.Write([]byte("id,date\n"))
+		}
+	})
+	defer done()
+	job, err := client.WaitForExport(context.Background(), "abc", time.Millisecond)
+	if err != nil || job.Status != ExportDone || job.Count != 2 {
+		t.Fatal("WaitForExport returned the wrong job: ", job, err)
+	}
+	var buf bytes.Buffer
+	if err := client.DownloadExport(context.Background(), "abc", &buf); err != nil || buf.String() != "id,date\n" {
+		t.Error("Wrong download: ", buf.String(), err)
+	}
+}
+
+func TestClusters(t *testing.T) {
+	var query string
+	client, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RequestURI()
+		w.Write([]byte(`{"meta":{"count":2},"data":{"clusters":[{"point":{"lat":45.5,"lng":-122.6},"count":12,"expansion_zoom":11},{"point":{"lat":45.4,"lng":-122.7},"count":1,"crimes":[7]}]}}`))
+	})
+	defer done()
+	clusters, err := client.Clusters(context.Background(), Bounds{Point{45.4, -122.8}, Point{45.6, -122.5}}, 10)
+	if err != nil {
+		t.Fatal("Clusters returned an error: ", err)
+	}
+	if query != "/clusters?bbox=45.4%2C-122.8%2C45.6%2C-122.5&zoom=10" {
+		t.Error("Wrong request: ", query)
+	}
+	if len(clusters.Clusters) != 2 || clusters.Clusters[0].ExpansionZoom != 11 || clusters.Clusters[1].Crimes[0] != 7 {
+		t.Error("Wrong clusters: ", clusters.Clusters)
+	}
+}
@@ -0,0 +1,237 @@
+package client
+
+import "time"
+
+// Meta describes a response in its envelope: the query parameters as the
+// server applied them, how long the request took, how many crimes or other
+// items the response has and the version of the data it came from. The
+// server writes envelopes with it.
+type Meta struct {
+	Query  map[string]interface{} `json:"query"`
+	TookMs float64                `json:"took_ms"`
+	Count  *int                   `json:"count,omitempty"`
+	// DatasetVersion identifies the data the response came from.
+	DatasetVersion string `json:"dataset_version,omitempty"`
+	// Notice says that the response came from a demo server, and isn't
+	// real data to rely on.
+	Notice string `json:"notice,omitempty"`
+	// Degraded says how a response that was over the server's size budget
+	// was cut down, like "counts" for locations with counts of their
+	// crimes rather than the crimes.
+	Degraded string `json:"degraded,omitempty"`
+}
+
+// A Point is a latitude and longitude.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Bounds is a box from its southwest corner, Min, to its northeast corner,
+// Max.
+type Bounds struct {
+	Min Point `json:"min"`
+	Max Point `json:"max"`
+}
+
+// An Offense is one offense of an incident whose crimes were grouped by
+// case number.
+type Offense struct {
+	Id   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+// A Crime is a crime in a response. Weapon, Domestic, Arrest, Case and
+// Offenses are only set when the city's data has them.
+type Crime struct {
+	Id          int64                  `json:"id"`
+	Date        string                 `json:"date"`
+	Time        string                 `json:"time"`
+	Type        string                 `json:"type"`
+	Weapon      string                 `json:"weapon,omitempty"`
+	Domestic    *bool                  `json:"domestic,omitempty"`
+	Arrest      *bool                  `json:"arrest,omitempty"`
+	Case        string                 `json:"case,omitempty"`
+	Offenses    []Offense              `json:"offenses,omitempty"`
+	Enrichments map[string]interface{} `json:"enrichments,omitempty"`
+	// Geocoded is set if the crime's coordinates were found from its
+	// address.
+	Geocoded bool `json:"geocoded,omitempty"`
+	// URL is the crime's permalink.
+	URL string `json:"url"`
+}
+
+// A Location is a point and the crimes that occurred there.
+type Location struct {
+	Point Point `json:"point"`
+	// DistanceMiles is the distance from the searched point, if the search
+	// asked for it with IncludeDistance.
+	DistanceMiles *float64 `json:"distance_miles,omitempty"`
+	Crimes        []Crime  `json:"crimes"`
+	// Count is the number of crimes at the location when the response was
+	// too large and has counts instead of Crimes. See Meta.Degraded.
+	Count int `json:"count,omitempty"`
+}
+
+// Diagnostics help explain why a search found no locations.
+type Diagnostics struct {
+	Nearest              *Point   `json:"nearest,omitempty"`
+	NearestDistanceMiles *float64 `json:"nearest_distance_miles,omitempty"`
+	Bounds               *Bounds  `json:"bounds,omitempty"`
+	OutsideCoverage      bool     `json:"outside_coverage"`
+}
+
+// A Timing is how long a phase of a search took.
+type Timing struct {
+	Phase string  `json:"phase"`
+	Ms    float64 `json:"ms"`
+}
+
+// An Explanation describes how a search ran.
+type Explanation struct {
+	Index        string   `json:"index"`
+	NodesVisited int      `json:"nodes_visited"`
+	Candidates   int      `json:"candidates"`
+	Results      int      `json:"results"`
+	Cache        string   `json:"cache"`
+	Timings      []Timing `json:"timings"`
+}
+
+// A SearchResult is the response to a search for crimes near a point.
+type SearchResult struct {
+	Query       Point        `json:"query"`
+	Locations   []Location   `json:"locations"`
+	Diagnostics *Diagnostics `json:"diagnostics,omitempty"`
+	Explain     *Explanation `json:"explain,omitempty"`
+	Meta        Meta         `json:"-"`
+}
+
+// Crimes returns all of the crimes in a SearchResult.
+func (result SearchResult) Crimes() []Crime {
+	crimes := make([]Crime, 0)
+	for _, location := range result.Locations {
+		crimes = append(crimes, location.Crimes...)
+	}
+	return crimes
+}
+
+// A DateRange is the first and last dates of the data's crimes.
+type DateRange struct {
+	First string `json:"first"`
+	Last  string `json:"last"`
+}
+
+// Density describes how crimes are spread over the data's area and
+// locations.
+type Density struct {
+	CrimesPerSquareMile   float64 `json:"crimes_per_square_mile"`
+	MeanCrimesPerLocation float64 `json:"mean_crimes_per_location"`
+	MaxCrimesPerLocation  int     `json:"max_crimes_per_location"`
+}
+
+// Stats describes the coverage of the data.
+type Stats struct {
+	Bounds     *Bounds        `json:"bounds"`
+	Centroid   *Point         `json:"centroid"`
+	Dates      *DateRange     `json:"dates"`
+	Crimes     int            `json:"crimes"`
+	Locations  int            `json:"locations"`
+	Density    Density        `json:"density"`
+	Categories map[string]int `json:"categories"`
+	Meta       Meta           `json:"-"`
+}
+
+// A Bucket is a range of distances in a Histogram and how many locations'
+// nearest neighbors are in it.
+type Bucket struct {
+	Min   float64 `json:"min_miles"`
+	Max   float64 `json:"max_miles"`
+	Count int     `json:"count"`
+}
+
+// A Histogram describes the distances between locations and their nearest
+// neighbors.
+type Histogram struct {
+	Count int `json:"count"`
+	// Percentiles holds distances in miles by percentile, like "p50".
+	Percentiles map[string]float64 `json:"percentiles_miles"`
+	Buckets     []Bucket           `json:"buckets"`
+	Meta        Meta               `json:"-"`
+}
+
+// A Cluster is a group of nearby locations as a map shows them at one zoom
+// level, or a single location.
+type Cluster struct {
+	Point Point `json:"point"`
+	Count int   `json:"count"`
+	// ExpansionZoom is the zoom level at which the cluster splits apart, or
+	// zero if it's a single location.
+	ExpansionZoom int `json:"expansion_zoom"`
+	// Crimes holds the ids of a single location's crimes.
+	Crimes []int64 `json:"crimes"`
+}
+
+// Clusters are the clusters of locations inside a map's bounds.
+type Clusters struct {
+	Clusters []Cluster `json:"clusters"`
+	Meta     Meta      `json:"-"`
+}
+
+// A ChangedCrime is a crime in Changes and the point it occurred at.
+type ChangedCrime struct {
+	Crime
+	Point Point `json:"point"`
+}
+
+// A Correction is a crime whose record changed.
+type Correction struct {
+	Before ChangedCrime `json:"before"`
+	After  ChangedCrime `json:"after"`
+}
+
+// Changes describes how the data changed the last time it was refreshed.
+type Changes struct {
+	From      string         `json:"from"`
+	To        string         `json:"to"`
+	Added     int            `json:"added"`
+	Removed   []ChangedCrime `json:"removed"`
+	Corrected []Correction   `json:"corrected"`
+	Meta      Meta           `json:"-"`
+}
+
+// The statuses of an export job.
+const (
+	ExportPending = "pending"
+	ExportRunning = "running"
+	ExportDone    = "done"
+	ExportFailed  = "failed"
+)
+
+// An ExportRequest asks for an export of the crimes that match its filters
+// in a format, "csv" or "geojson". Empty filters match every crime.
+type ExportRequest struct {
+	Format string `json:"format"`
+	// Bounds is "minLat,minLng,maxLat,maxLng".
+	Bounds   string `json:"bounds"`
+	Category string `json:"category"`
+	// From and To are dates, "2011-01-01".
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Weapon   string `json:"weapon"`
+	Domestic *bool  `json:"domestic"`
+	Arrest   *bool  `json:"arrest"`
+}
+
+// An ExportJob is an export that the server writes in the background.
+type ExportJob struct {
+	Id       string        `json:"id"`
+	Status   string        `json:"status"`
+	Request  ExportRequest `json:"request"`
+	Created  time.Time     `json:"created"`
+	Finished *time.Time    `json:"finished,omitempty"`
+	// Count is the number of crimes exported, once the export is done.
+	Count int    `json:"count"`
+	Error string `json:"error,omitempty"`
+	// Download is the URL of the export, once it's done.
+	Download string `json:"download,omitempty"`
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// clustersHandler returns the clusters of locations inside the "bbox"
+// parameter, "minLat,minLng,maxLat,maxLng", as a web map shows them at the
+// "zoom" parameter's zoom level.
+func clustersHandler(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	bounds, err := radar.ParseBounds(values.Get("bbox"))
+	if err != nil {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	if err := limits.checkBounds(bounds); err != nil {
+		writeLimitError(w, err)
+		return
+	}
+	zoom, err := strconv.Atoi(values.Get("zoom"))
+	if err != nil || zoom < 0 || zoom > radar.MAX_ZOOM {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	clusters := finderFor(r).Clusters(bounds, zoom)
+	resp, err := radar.ClustersToJson(clusters)
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	count := len(clusters)
+	query := map[string]interface{}{"bbox": values.Get("bbox"), "zoom": zoom}
+	writeJson(w, r, resp, responseMeta{Query: query, Count: &count})
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestClusters(t *testing.T) {
+	resp := get(t, "/clusters?bbox=45.4,-122.8,45.6,-122.5&zoom=10&envelope=false")
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	var body struct {
+		Clusters []struct {
+			Count         int `json:"count"`
+			ExpansionZoom int `json:"expansion_zoom"`
+		} `json:"clusters"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for _, cluster := range body.Clusters {
+		count += cluster.Count
+	}
+	if len(body.Clusters) == 0 || count != finder.Report.Crimes {
+		t.Error("Clusters should cover every crime: ", len(body.Clusters), count)
+	}
+
+	for _, url := range []string{"/clusters?zoom=10", "/clusters?bbox=45.4,-122.8,45.6,-122.5", "/clusters?bbox=45.4,-122.8,45.6,-122.5&zoom=30"} {
+		if resp := get(t, url); resp.Code != 400 {
+			t.Error("Wrong status code for ", url, ": ", resp.Code)
+		}
+	}
+}
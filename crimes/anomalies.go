@@ -0,0 +1,304 @@
+package radar
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+)
+
+// Defaults for finding anomalies.
+const (
+	DEFAULT_ANOMALY_WINDOW     = 30 * 24 * time.Hour
+	DEFAULT_ANOMALY_PERIODS    = 12
+	DEFAULT_ANOMALY_SEASONS    = 3
+	DEFAULT_ANOMALY_THRESHOLD  = 3.0
+	DEFAULT_ANOMALY_MIN_CRIMES = 5
+)
+
+// AnomalyOptions are the settings for finding anomalies. Fields left at
+// their zero values get the defaults.
+type AnomalyOptions struct {
+	// Window is the length of the recent period, and of each period of
+	// history that it's compared with.
+	Window time.Duration
+	// Periods is the most windows of history a cell is compared with. A
+	// dataset with less history uses all it has.
+	Periods int
+	// Seasons is the most seasons of history, a FORECAST_SEASON of months
+	// apart, whose windows at the same time of year a cell is compared
+	// with. A dataset with less history uses all it has.
+	Seasons int
+	// Threshold is how many standard deviations a cell's recent count
+	// must be above its mean to be an anomaly.
+	Threshold float64
+	// MinCrimes is the fewest recent crimes an anomaly can have, so that a
+	// quiet cell isn't flagged for a couple more crimes than usual.
+	MinCrimes int
+	// At is the end of the recent window. The zero time means the end of
+	// the day of the latest crime.
+	At time.Time
+}
+
+// withDefaults returns the options with defaults for the fields that
+// aren't set.
+func (options AnomalyOptions) withDefaults() AnomalyOptions {
+	if options.Window <= 0 {
+		options.Window = DEFAULT_ANOMALY_WINDOW
+	}
+	if options.Periods <= 0 {
+		options.Periods = DEFAULT_ANOMALY_PERIODS
+	}
+	if options.Seasons <= 0 {
+		options.Seasons = DEFAULT_ANOMALY_SEASONS
+	}
+	if options.Threshold <= 0 {
+		options.Threshold = DEFAULT_ANOMALY_THRESHOLD
+	}
+	if options.MinCrimes <= 0 {
+		options.MinCrimes = DEFAULT_ANOMALY_MIN_CRIMES
+	}
+	return options
+}
+
+// An Anomaly is a cell of the grid with more crimes in the recent window
+// than its history makes likely.
+type Anomaly struct {
+	Cell GridCell
+	// Recent is the number of crimes in the recent window.
+	Recent int
+	// Mean and StdDev describe the cell's counts in the windows before.
+	Mean   float64
+	StdDev float64
+	// Score is how many standard deviations Recent is above Mean.
+	Score float64
+	// SeasonalMean, SeasonalStdDev and SeasonalScore are the same for the
+	// cell's counts in the windows at the same time of year in the seasons
+	// before. They're zero if the report has no seasons.
+	SeasonalMean   float64
+	SeasonalStdDev float64
+	SeasonalScore  float64
+}
+
+// An AnomalyReport lists the anomalies in a dataset's recent window, most
+// anomalous first.
+type AnomalyReport struct {
+	// From and To are the start and end of the recent window. They're
+	// zero if the data has no dated crimes and no end was asked for.
+	From time.Time
+	To   time.Time
+	// Periods is the number of windows of history that were used, and
+	// Seasons the number of windows at the same time of year.
+	Periods   int
+	Seasons   int
+	Anomalies []Anomaly
+}
+
+// baseline returns the mean of a cell's counts in windows of history and
+// their standard deviation. Counts of crimes are roughly Poisson, so the
+// standard deviation is at least the square root of the mean, and at least
+// one, which keeps cells with little history from being flagged for small
+// changes.
+func baseline(history []int) (float64, float64) {
+	mean, variance := 0.0, 0.0
+	for _, count := range history {
+		mean += float64(count)
+	}
+	mean /= float64(len(history))
+	for _, count := range history {
+		variance += (float64(count) - mean) * (float64(count) - mean)
+	}
+	variance /= float64(len(history))
+	return mean, math.Sqrt(math.Max(variance, math.Max(mean, 1)))
+}
+
+// FindAnomalies finds the cells of the grid whose count of crimes in the
+// recent window is unusually high compared with their counts in the
+// windows before it. If the data goes back a season or more, a cell must
+// also be unusually high compared with the same window in the seasons
+// before, so that a rise that comes at the same time every year isn't
+// flagged. Crimes whose dates can't be parsed are left out.
+func (finder *CrimeFinder) FindAnomalies(options AnomalyOptions) AnomalyReport {
+	options = options.withDefaults()
+	type datedCrime struct {
+		cell     GridCell
+		occurred time.Time
+	}
+	dated := make([]datedCrime, 0, finder.Report.Crimes)
+	var earliest, latest time.Time
+	for _, location := range finder.LocationLookup {
+		cell := GridCellOf(*location.Point)
+		for _, crime := range location.Crimes {
+			occurred, err := time.Parse(DATE_LAYOUT, crime.Date)
+			if err != nil {
+				continue
+			}
+			dated = append(dated, datedCrime{cell, occurred})
+			if earliest.IsZero() || occurred.Before(earliest) {
+				earliest = occurred
+			}
+			if occurred.After(latest) {
+				latest = occurred
+			}
+		}
+	}
+	report := AnomalyReport{To: options.At, Anomalies: make([]Anomaly, 0)}
+	if len(dated) == 0 && report.To.IsZero() {
+		// Without dated crimes there's no recent window to look at.
+		return report
+	}
+	if report.To.IsZero() {
+		report.To = latest.AddDate(0, 0, 1)
+	}
+	report.From = report.To.Add(-options.Window)
+	// Only whole windows of history count, so that the days before the
+	// data starts aren't taken for days without crimes.
+	report.Periods = min(options.Periods, int(report.From.Sub(earliest)/options.Window))
+	if len(dated) == 0 || report.Periods < 1 {
+		report.Periods = 0
+		return report
+	}
+	// season returns the start and end of the window k seasons before the
+	// recent one.
+	season := func(k int) (time.Time, time.Time) {
+		return report.From.AddDate(0, -FORECAST_SEASON*k, 0), report.To.AddDate(0, -FORECAST_SEASON*k, 0)
+	}
+	for report.Seasons < options.Seasons {
+		if from, _ := season(report.Seasons + 1); from.Before(earliest) {
+			break
+		}
+		report.Seasons++
+	}
+
+	// counts holds each cell's count in the recent window, then in each
+	// window of history, most recent first.
+	counts := make(map[GridCell][]int)
+	for _, crime := range dated {
+		if !crime.occurred.Before(report.To) {
+			continue
+		}
+		window := int(report.To.Sub(crime.occurred) / options.Window)
+		if window > report.Periods {
+			continue
+		}
+		if _, ok := counts[crime.cell]; !ok {
+			counts[crime.cell] = make([]int, report.Periods+1)
+		}
+		counts[crime.cell][window]++
+	}
+	// seasons holds each cell's counts in the same window of each season
+	// before, most recent first.
+	seasons := make(map[GridCell][]int)
+	for _, crime := range dated {
+		for k := 1; k <= report.Seasons; k++ {
+			from, to := season(k)
+			if crime.occurred.Before(from) || !crime.occurred.Before(to) {
+				continue
+			}
+			if _, ok := seasons[crime.cell]; !ok {
+				seasons[crime.cell] = make([]int, report.Seasons)
+			}
+			seasons[crime.cell][k-1]++
+		}
+	}
+	for cell, series := range counts {
+		recent, history := series[0], series[1:]
+		if recent < options.MinCrimes {
+			continue
+		}
+		anomaly := Anomaly{Cell: cell, Recent: recent}
+		anomaly.Mean, anomaly.StdDev = baseline(history)
+		anomaly.Score = (float64(recent) - anomaly.Mean) / anomaly.StdDev
+		if anomaly.Score < options.Threshold {
+			continue
+		}
+		if report.Seasons > 0 {
+			sameSeason, ok := seasons[cell]
+			if !ok {
+				sameSeason = make([]int, report.Seasons)
+			}
+			anomaly.SeasonalMean, anomaly.SeasonalStdDev = baseline(sameSeason)
+			anomaly.SeasonalScore = (float64(recent) - anomaly.SeasonalMean) / anomaly.SeasonalStdDev
+			if anomaly.SeasonalScore < options.Threshold {
+				continue
+			}
+		}
+		report.Anomalies = append(report.Anomalies, anomaly)
+	}
+	sort.Slice(report.Anomalies, func(i, j int) bool {
+		a, b := report.Anomalies[i], report.Anomalies[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Cell.Row != b.Cell.Row {
+			return a.Cell.Row < b.Cell.Row
+		}
+		return a.Cell.Col < b.Cell.Col
+	})
+	return report
+}
+
+// The JSON form of an Anomaly.
+type anomalyJson struct {
+	Cell   string    `json:"cell"`
+	Center pointJson `json:"center"`
+	Min    pointJson `json:"min"`
+	Max    pointJson `json:"max"`
+	Recent int       `json:"recent"`
+	Mean   float64   `json:"mean"`
+	StdDev float64   `json:"stddev"`
+	Score  float64   `json:"score"`
+	// The seasonal baseline is left out of reports without seasons.
+	SeasonalMean   *float64 `json:"seasonal_mean,omitempty"`
+	SeasonalStdDev *float64 `json:"seasonal_stddev,omitempty"`
+	SeasonalScore  *float64 `json:"seasonal_score,omitempty"`
+}
+
+// roundTo rounds value to places decimal places.
+func roundTo(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
+
+// toJson returns the JSON form of the anomaly, with its cell's id, center
+// and bounds, and its seasonal baseline if seasonal is true.
+func (anomaly Anomaly) toJson(seasonal bool) anomalyJson {
+	center, bounds := anomaly.Cell.Center(), anomaly.Cell.Bounds()
+	encoded := anomalyJson{
+		Cell:   anomaly.Cell.String(),
+		Center: pointJson{center.Lat, center.Lng},
+		Min:    pointJson{bounds.Min.Lat, bounds.Min.Lng},
+		Max:    pointJson{bounds.Max.Lat, bounds.Max.Lng},
+		Recent: anomaly.Recent,
+		Mean:   roundTo(anomaly.Mean, 2),
+		StdDev: roundTo(anomaly.StdDev, 2),
+		Score:  roundTo(anomaly.Score, 2),
+	}
+	if seasonal {
+		mean, stdDev, score := roundTo(anomaly.SeasonalMean, 2), roundTo(anomaly.SeasonalStdDev, 2), roundTo(anomaly.SeasonalScore, 2)
+		encoded.SeasonalMean, encoded.SeasonalStdDev, encoded.SeasonalScore = &mean, &stdDev, &score
+	}
+	return encoded
+}
+
+// ToJson returns the report marshalled to JSON bytes. The recent window is
+// given by its first and last days.
+func (report AnomalyReport) ToJson() ([]byte, error) {
+	anomalies := make([]anomalyJson, 0, len(report.Anomalies))
+	for _, anomaly := range report.Anomalies {
+		anomalies = append(anomalies, anomaly.toJson(report.Seasons > 0))
+	}
+	// A report of data without dated crimes has no window.
+	var from, to *string
+	if !report.To.IsZero() {
+		first, last := report.From.Format("2006-01-02"), report.To.AddDate(0, 0, -1).Format("2006-01-02")
+		from, to = &first, &last
+	}
+	return json.Marshal(struct {
+		From      *string       `json:"from"`
+		To        *string       `json:"to"`
+		Periods   int           `json:"periods"`
+		Seasons   int           `json:"seasons"`
+		Anomalies []anomalyJson `json:"anomalies"`
+	}{from, to, report.Periods, report.Seasons, anomalies})
+}
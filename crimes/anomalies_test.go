@@ -0,0 +1,107 @@
+package radar
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindAnomaliesWithoutCrimes(t *testing.T) {
+	finder := NewEmptyCrimeFinder(LoadOptions{})
+	report := finder.FindAnomalies(AnomalyOptions{})
+	if !report.From.IsZero() || !report.To.IsZero() || report.Periods != 0 || len(report.Anomalies) != 0 {
+		t.Error("Data without crimes should have no recent window: ", report)
+	}
+	data, err := report.ToJson()
+	if err != nil || string(data) != `{"from":null,"to":null,"periods":0,"seasons":0,"anomalies":[]}` {
+		t.Error("Wrong JSON: ", string(data), err)
+	}
+}
+
+func TestFindAnomalies(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	report := finder.FindAnomalies(AnomalyOptions{})
+	if report.Periods != 11 || report.Seasons != 0 || len(report.Anomalies) != 0 {
+		t.Error("Test data should have no anomalies by default: ", report)
+	}
+	if report.From.Format("2006-01-02") != "2011-12-02" {
+		t.Error("Wrong recent window: ", report.From)
+	}
+
+	report = finder.FindAnomalies(AnomalyOptions{Threshold: 0.5})
+	if len(report.Anomalies) != 2 {
+		t.Fatal("Wrong number of anomalies: ", report.Anomalies)
+	}
+	for i, anomaly := range report.Anomalies {
+		if anomaly.Recent < DEFAULT_ANOMALY_MIN_CRIMES || anomaly.Score < 0.5 {
+			t.Error("Anomaly should not have been found: ", anomaly)
+		}
+		if i > 0 && anomaly.Score > report.Anomalies[i-1].Score {
+			t.Error("Anomalies should be most anomalous first: ", report.Anomalies)
+		}
+	}
+	data, err := report.ToJson()
+	if err != nil {
+		t.Fatal("Error marshalling report: ", err)
+	}
+	var decoded struct {
+		To        string
+		Anomalies []struct{ Cell string }
+	}
+	json.Unmarshal(data, &decoded)
+	if decoded.To != "2011-12-31" || decoded.Anomalies[0].Cell != report.Anomalies[0].Cell.String() {
+		t.Error("Wrong JSON: ", string(data))
+	}
+
+	early := finder.FindAnomalies(AnomalyOptions{At: time.Date(2011, 3, 10, 0, 0, 0, 0, time.UTC)})
+	if early.Periods != 1 {
+		t.Error("History should be limited to whole windows before At: ", early.Periods)
+	}
+	tooEarly := finder.FindAnomalies(AnomalyOptions{At: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if tooEarly.Periods != 0 || tooEarly.Anomalies == nil || len(tooEarly.Anomalies) != 0 {
+		t.Error("There should be no history before the data: ", tooEarly)
+	}
+}
+
+func TestFindAnomaliesSeasonal(t *testing.T) {
+	// Two years of a cell with a crime a week, and with ten more each
+	// July.
+	var data strings.Builder
+	id := 1
+	for day := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC); day.Year() < 2012; day = day.AddDate(0, 0, 7) {
+		fmt.Fprintf(&data, "%v,%v,12:00:00,Burglary,,,,,45.5184,-122.6554\n", id, day.Format("01/02/2006"))
+		id++
+	}
+	for _, year := range []int{2010, 2011} {
+		for day := 1; day <= 10; day++ {
+			fmt.Fprintf(&data, "%v,07/%02d/%v,12:00:00,Burglary,,,,,45.5184,-122.6554\n", id, day*2, year)
+			id++
+		}
+	}
+	finder, err := NewCrimeFinderFromReader(strings.NewReader(data.String()), LoadOptions{})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	july := time.Date(2011, 8, 1, 0, 0, 0, 0, time.UTC)
+	report := finder.FindAnomalies(AnomalyOptions{At: july})
+	if report.Seasons != 1 || len(report.Anomalies) != 0 {
+		t.Error("A rise that comes every July should not be an anomaly: ", report)
+	}
+	if data, _ := report.ToJson(); !strings.Contains(string(data), `"seasons":1`) {
+		t.Error("Wrong JSON: ", string(data))
+	}
+
+	// Without a July before, the rise is an anomaly.
+	unseasonal := finder.FindAnomalies(AnomalyOptions{At: time.Date(2010, 8, 1, 0, 0, 0, 0, time.UTC)})
+	if unseasonal.Seasons != 0 || len(unseasonal.Anomalies) != 1 {
+		t.Fatal("A rise without a season before should be an anomaly: ", unseasonal)
+	}
+	if anomaly := unseasonal.Anomalies[0]; anomaly.Recent != 13 || anomaly.SeasonalScore != 0 {
+		t.Error("Wrong anomaly: ", anomaly)
+	}
+}
@@ -0,0 +1,46 @@
+package radar
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strconv"
+)
+
+// The column of the City's CSV data that holds the address of a crime.
+const ADDRESS_COLUMN = 4
+
+// jitterPoint moves a point up to miles away in a random direction. The
+// move is seeded by the point itself, so the same point always moves to the
+// same place and output stays the same across runs.
+func jitterPoint(lat float64, lng float64, miles float64) (float64, float64) {
+	h := fnv.New64a()
+	h.Write([]byte(GetCoordinateKey(lat, lng).String()))
+	r := rand.New(rand.NewSource(int64(h.Sum64())))
+	// Taking the square root spreads points evenly over the circle.
+	distance := miles * math.Sqrt(r.Float64())
+	angle := r.Float64() * 2 * math.Pi
+	lat += distance * math.Sin(angle) * HALF_MILE_LAT * 2
+	lng += distance * math.Cos(angle) * HALF_MILE_LNG * 2
+	return lat, lng
+}
+
+// anonymizeRows jitters the coordinates of rows by up to miles and removes
+// their addresses, so that no output can pinpoint a crime to a residence.
+// Crimes at the same location stay together after they move.
+func anonymizeRows(rows CsvRows, miles float64) {
+	for _, row := range rows {
+		row[ADDRESS_COLUMN] = ""
+		lat, err := strconv.ParseFloat(row[LAT_COLUMN], 64)
+		if err != nil {
+			continue
+		}
+		lng, err := strconv.ParseFloat(row[LNG_COLUMN], 64)
+		if err != nil {
+			continue
+		}
+		lat, lng = jitterPoint(lat, lng, miles)
+		row[LAT_COLUMN] = strconv.FormatFloat(lat, 'f', -1, 64)
+		row[LNG_COLUMN] = strconv.FormatFloat(lng, 'f', -1, 64)
+	}
+}
@@ -0,0 +1,57 @@
+package radar
+
+import (
+	"testing"
+)
+
+func TestJitterPoint(t *testing.T) {
+	point := Point{45.53435699129174, -122.66469510763777}
+	lat, lng := jitterPoint(point.Lat, point.Lng, 0.25)
+	if lat == point.Lat && lng == point.Lng {
+		t.Error("jitterPoint did not move the point")
+	}
+	moved := Point{lat, lng}
+	// Allow some slack for the difference between the half-mile constants
+	// and great-circle distance.
+	if moved.GreatCircleDistance(&point) > 0.26 {
+		t.Error("jitterPoint moved the point too far: ", moved.GreatCircleDistance(&point))
+	}
+	lat2, lng2 := jitterPoint(point.Lat, point.Lng, 0.25)
+	if lat != lat2 || lng != lng2 {
+		t.Error("jitterPoint should always move a point to the same place")
+	}
+}
+
+func TestAnonymizeRows(t *testing.T) {
+	row := CsvRow{"13690824", "05/27/2011", "08:35:00", "Liquor Laws", "NE SCHUYLER ST and NE 1ST AVE, PORTLAND, OR 97212", "ELIOT", "PORTLAND PREC NO", "590", "45.53579735412487", "-122.66468312170824"}
+	same := append(CsvRow{}, row...)
+	rows := CsvRows{row, same}
+	anonymizeRows(rows, 0.1)
+	if row[ADDRESS_COLUMN] != "" {
+		t.Error("anonymizeRows did not remove the address")
+	}
+	if row[8] == "45.53579735412487" || row[9] == "-122.66468312170824" {
+		t.Error("anonymizeRows did not move the coordinates")
+	}
+	if row[8] != same[8] || row[9] != same[9] {
+		t.Error("Crimes at the same location should move together")
+	}
+}
+
+func TestCrimeFinderJitter(t *testing.T) {
+	finder, err := NewCrimeFinderWithOptions("sample/crimes.csv", LoadOptions{JitterMiles: 0.1})
+	if err != nil {
+		t.Error("Error creating CrimeFinder: ", err)
+	}
+	original, _ := NewCrimeFinder("sample/crimes.csv")
+	if finder.Report.Crimes != original.Report.Crimes {
+		t.Error("Jitter should not drop crimes: ", finder.Report.Crimes)
+	}
+	for _, location := range finder.Locations() {
+		key := GetCoordinateKey(location.Point.Lat, location.Point.Lng)
+		if _, exists := original.LocationLookup[key]; exists {
+			t.Error("Location was not moved: ", key)
+			break
+		}
+	}
+}
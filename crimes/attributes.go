@@ -0,0 +1,73 @@
+package radar
+
+import "strings"
+
+// ParseFlag parses the yes/no values that cities use for flags. It returns
+// nil for an empty or unknown value.
+func ParseFlag(value string) *bool {
+	var flag bool
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "t", "yes", "y", "1":
+		flag = true
+	case "false", "f", "no", "n", "0":
+		flag = false
+	default:
+		return nil
+	}
+	return &flag
+}
+
+// NEIGHBORHOOD_COLUMN holds the neighborhood a crime occurred in.
+const NEIGHBORHOOD_COLUMN = 5
+
+// setAttributesFromRow sets the neighborhood and optional attributes of
+// crime from row, if it has them.
+func setAttributesFromRow(crime *Crime, row CsvRow) {
+	if len(row) > NEIGHBORHOOD_COLUMN {
+		crime.Neighborhood = row[NEIGHBORHOOD_COLUMN]
+	}
+	if len(row) > WEAPON_COLUMN {
+		crime.Weapon = row[WEAPON_COLUMN]
+	}
+	if len(row) > DOMESTIC_COLUMN {
+		crime.Domestic = ParseFlag(row[DOMESTIC_COLUMN])
+	}
+	if len(row) > ARREST_COLUMN {
+		crime.Arrest = ParseFlag(row[ARREST_COLUMN])
+	}
+	if len(row) > CASE_COLUMN {
+		crime.CaseNumber = row[CASE_COLUMN]
+	}
+	if len(row) > GEOCODED_COLUMN {
+		geocoded := ParseFlag(row[GEOCODED_COLUMN])
+		crime.Geocoded = geocoded != nil && *geocoded
+	}
+}
+
+// An AttributeFilter matches crimes by their optional attributes. Empty
+// fields match every crime.
+type AttributeFilter struct {
+	Weapon   string `json:"weapon,omitempty"`
+	Domestic *bool  `json:"domestic,omitempty"`
+	Arrest   *bool  `json:"arrest,omitempty"`
+}
+
+// Matches returns true if crime has every attribute the filter asks for. A
+// crime whose data lacks an attribute doesn't match a filter on it.
+func (filter AttributeFilter) Matches(crime *Crime) bool {
+	if filter.Weapon != "" && !strings.EqualFold(filter.Weapon, crime.Weapon) {
+		return false
+	}
+	if filter.Domestic != nil && (crime.Domestic == nil || *crime.Domestic != *filter.Domestic) {
+		return false
+	}
+	if filter.Arrest != nil && (crime.Arrest == nil || *crime.Arrest != *filter.Arrest) {
+		return false
+	}
+	return true
+}
+
+// IsEmpty returns true if the filter matches every crime.
+func (filter AttributeFilter) IsEmpty() bool {
+	return filter.Weapon == "" && filter.Domestic == nil && filter.Arrest == nil
+}
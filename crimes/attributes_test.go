@@ -0,0 +1,91 @@
+package radar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFlag(t *testing.T) {
+	for _, value := range []string{"true", "Y", "yes", "1"} {
+		if flag := ParseFlag(value); flag == nil || !*flag {
+			t.Error("ParseFlag did not parse ", value, " as true")
+		}
+	}
+	for _, value := range []string{"false", "N", "No", "0"} {
+		if flag := ParseFlag(value); flag == nil || *flag {
+			t.Error("ParseFlag did not parse ", value, " as false")
+		}
+	}
+	for _, value := range []string{"", "maybe"} {
+		if ParseFlag(value) != nil {
+			t.Error("ParseFlag should not parse ", value)
+		}
+	}
+}
+
+func TestAttributeFilterMatches(t *testing.T) {
+	yes, no := true, false
+	crime := &Crime{Id: 1, Type: "Assault, Simple", Weapon: "Knife", Domestic: &yes}
+	if !(AttributeFilter{}).Matches(crime) {
+		t.Error("An empty filter should match every crime")
+	}
+	if !(AttributeFilter{Weapon: "knife", Domestic: &yes}).Matches(crime) {
+		t.Error("Filter should have matched ", crime)
+	}
+	if (AttributeFilter{Domestic: &no}).Matches(crime) {
+		t.Error("Filter should not match a crime with a different flag")
+	}
+	if (AttributeFilter{Arrest: &no}).Matches(crime) {
+		t.Error("Filter should not match a crime that lacks the attribute")
+	}
+}
+
+func TestSearchResultToJsonAttributes(t *testing.T) {
+	arrest := true
+	crime := &Crime{Id: 1, Date: "1/1/2013", Time: "04:30", Type: "Robbery", Weapon: `Firearm "handgun"`, Arrest: &arrest}
+	point := Point{45.1, -122.3}
+	result := SearchResult{Query: &point, Locations: []*CrimeLocation{{&point, []*Crime{crime}}}}
+	actual, err := result.ToJson()
+	if err != nil {
+		t.Error("ToJson returned an error: ", err)
+	}
+	expected := `{"id":1,"date":"1/1/2013","time":"04:30","type":"Robbery","weapon":"Firearm \"handgun\"","arrest":true,"url":"/crimes/1"}`
+	if !strings.Contains(string(actual), expected) {
+		t.Error("ToJson did not include attributes: ", string(actual))
+	}
+	if strings.Contains(string(actual), "domestic") {
+		t.Error("ToJson should omit absent attributes: ", string(actual))
+	}
+}
+
+func TestCrimeFinderChicagoAttributes(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "chicago.csv")
+	os.WriteFile(filename, []byte(chicagoData), 0644)
+	finder, err := NewCrimeFinder(filename)
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	crimes := finder.All().Crimes()
+	if len(crimes) != 1 {
+		t.Fatal("Wrong number of crimes: ", len(crimes))
+	}
+	if crimes[0].Arrest == nil || *crimes[0].Arrest || crimes[0].Domestic == nil || *crimes[0].Domestic {
+		t.Error("Crime did not have the arrest and domestic flags: ", crimes[0])
+	}
+	if crimes[0].Weapon != "" {
+		t.Error("Crime should not have a weapon: ", crimes[0].Weapon)
+	}
+}
+
+func TestReadCrimesFromIgnoresExtraLegacyColumns(t *testing.T) {
+	data := "13807517,12/01/2011,01:00:00,Liquor Laws,,,,,45.5,-122.6,Knife,Y,N\n"
+	rows, _, err := readCrimesFrom(strings.NewReader(data))
+	if err != nil || len(rows) != 1 {
+		t.Fatal("readCrimesFrom could not read the row: ", err)
+	}
+	if len(rows[0]) != NUM_COLUMNS {
+		t.Error("Legacy rows should not have attribute columns: ", rows[0])
+	}
+}
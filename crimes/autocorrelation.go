@@ -0,0 +1,315 @@
+package radar
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Defaults for measuring spatial autocorrelation.
+const (
+	DEFAULT_PERMUTATIONS = 999
+	DEFAULT_SIGNIFICANCE = 0.05
+)
+
+// The quadrants of a Moran scatterplot that a cell can fall in: whether
+// its count is above or below the mean, then whether its neighbors' are.
+const (
+	HIGH_HIGH = "high-high"
+	LOW_LOW   = "low-low"
+	HIGH_LOW  = "high-low"
+	LOW_HIGH  = "low-high"
+)
+
+// AutocorrelationOptions are the settings for measuring spatial
+// autocorrelation. Fields left at their zero values get the defaults.
+type AutocorrelationOptions struct {
+	// Filter limits the crimes that are counted.
+	Filter SearchFilter
+	// Permutations is the number of random rearrangements of the counts
+	// that significance is judged against.
+	Permutations int
+	// Significance is the largest pseudo p-value of a cell that's reported
+	// as a cluster or an outlier.
+	Significance float64
+}
+
+// withDefaults returns the options with defaults for the fields that
+// aren't set.
+func (options AutocorrelationOptions) withDefaults() AutocorrelationOptions {
+	if options.Permutations <= 0 {
+		options.Permutations = DEFAULT_PERMUTATIONS
+	}
+	if options.Significance <= 0 {
+		options.Significance = DEFAULT_SIGNIFICANCE
+	}
+	return options
+}
+
+// GlobalMoran is Moran's I over the whole grid: near 1 when cells with many
+// crimes are next to each other, near Expected when counts are placed at
+// random, and below it when busy cells are next to quiet ones.
+type GlobalMoran struct {
+	I        float64
+	Expected float64
+	// ZScore and PValue compare I with its values for random
+	// rearrangements of the counts.
+	ZScore float64
+	PValue float64
+}
+
+// LocalMoran is the local Moran's I of a cell: positive when the cell is
+// part of a cluster of cells like it, and negative when it's an outlier
+// among its neighbors.
+type LocalMoran struct {
+	Cell  GridCell
+	Count int
+	I     float64
+	// PValue compares I with its values for random neighbors.
+	PValue float64
+	// Quadrant is HIGH_HIGH or LOW_LOW for a cluster, and HIGH_LOW or
+	// LOW_HIGH for an outlier.
+	Quadrant string
+}
+
+// An AutocorrelationReport describes how crimes cluster on the grid.
+type AutocorrelationReport struct {
+	// Cells is the number of cells that were compared.
+	Cells  int
+	Global GlobalMoran
+	// Clusters are the cells whose local Moran's I is significant, most
+	// significant first.
+	Clusters []LocalMoran
+}
+
+// Autocorrelation computes global and local Moran's I over the crimes
+// counted in each cell of the grid. The cells compared are those with
+// crimes and the cells next to them, so that a stray location doesn't
+// stretch the grid over miles of empty cells, and each cell's neighbors are
+// the up to eight cells around it, weighted equally. Significance is judged
+// by permutation, as in PySAL: the counts are rearranged at random, the same
+// way every time so that reports are repeatable, and a pseudo p-value is
+// the share of rearrangements at least as extreme as the data.
+func (finder *CrimeFinder) Autocorrelation(options AutocorrelationOptions) AutocorrelationReport {
+	options = options.withDefaults()
+	report := AutocorrelationReport{Clusters: make([]LocalMoran, 0), Global: GlobalMoran{PValue: 1}}
+
+	// The area is the same for every filter, so that filters are compared
+	// over the same cells.
+	counts := make(map[GridCell]int)
+	for _, location := range finder.LocationLookup {
+		cell := GridCellOf(*location.Point)
+		for row := cell.Row - 1; row <= cell.Row+1; row++ {
+			for col := cell.Col - 1; col <= cell.Col+1; col++ {
+				counts[GridCell{row, col}] += 0
+			}
+		}
+		for _, crime := range location.Crimes {
+			if options.Filter.Matches(crime) {
+				counts[cell]++
+			}
+		}
+	}
+	cells := make([]GridCell, 0, len(counts))
+	for cell := range counts {
+		cells = append(cells, cell)
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].Row != cells[j].Row {
+			return cells[i].Row < cells[j].Row
+		}
+		return cells[i].Col < cells[j].Col
+	})
+	n := len(cells)
+	report.Cells = n
+	if n < 2 {
+		return report
+	}
+	report.Global.Expected = -1 / float64(n-1)
+
+	positions := make(map[GridCell]int, n)
+	for i, cell := range cells {
+		positions[cell] = i
+	}
+	neighbors := make([][]int, n)
+	for i, cell := range cells {
+		for row := cell.Row - 1; row <= cell.Row+1; row++ {
+			for col := cell.Col - 1; col <= cell.Col+1; col++ {
+				if j, ok := positions[GridCell{row, col}]; ok && j != i {
+					neighbors[i] = append(neighbors[i], j)
+				}
+			}
+		}
+	}
+
+	// z holds the deviations of the counts from their mean.
+	mean := 0.0
+	for _, cell := range cells {
+		mean += float64(counts[cell])
+	}
+	mean /= float64(n)
+	z := make([]float64, n)
+	m2 := 0.0
+	for i, cell := range cells {
+		z[i] = float64(counts[cell]) - mean
+		m2 += z[i] * z[i]
+	}
+	if m2 == 0 {
+		return report
+	}
+	m2 /= float64(n)
+
+	lag := func(values []float64, i int) float64 {
+		sum := 0.0
+		for _, j := range neighbors[i] {
+			sum += values[j]
+		}
+		return sum / float64(len(neighbors[i]))
+	}
+	moran := func(values []float64) float64 {
+		sum := 0.0
+		for i := range values {
+			if len(neighbors[i]) > 0 {
+				sum += values[i] * lag(values, i)
+			}
+		}
+		return sum / (m2 * float64(n))
+	}
+	random := rand.New(rand.NewSource(1))
+	permutations := options.Permutations
+
+	report.Global.I = moran(z)
+	shuffled := append([]float64(nil), z...)
+	simulated := make([]float64, permutations)
+	for p := range simulated {
+		random.Shuffle(n, func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		simulated[p] = moran(shuffled)
+	}
+	simulatedMean, simulatedVariance := 0.0, 0.0
+	for _, value := range simulated {
+		simulatedMean += value
+	}
+	simulatedMean /= float64(permutations)
+	for _, value := range simulated {
+		simulatedVariance += (value - simulatedMean) * (value - simulatedMean)
+	}
+	simulatedVariance /= float64(permutations)
+	if simulatedVariance > 0 {
+		report.Global.ZScore = (report.Global.I - simulatedMean) / math.Sqrt(simulatedVariance)
+	}
+	report.Global.PValue = pseudoPValue(report.Global.I, simulated)
+
+	// Each cell's I is compared with its values for neighbors drawn at
+	// random from the other cells.
+	chosen := make([]int, 0, 8)
+	for i, cell := range cells {
+		k := len(neighbors[i])
+		if k == 0 || k >= n {
+			continue
+		}
+		local := z[i] / m2 * lag(z, i)
+		for p := range simulated {
+			chosen = chosen[:0]
+			sum := 0.0
+			for len(chosen) < k {
+				j := random.Intn(n)
+				if j == i || containsInt(chosen, j) {
+					continue
+				}
+				chosen = append(chosen, j)
+				sum += z[j]
+			}
+			simulated[p] = z[i] / m2 * sum / float64(k)
+		}
+		pValue := pseudoPValue(local, simulated)
+		if pValue > options.Significance {
+			continue
+		}
+		report.Clusters = append(report.Clusters, LocalMoran{cell, counts[cell], local, pValue, quadrant(z[i], lag(z, i))})
+	}
+	sort.SliceStable(report.Clusters, func(i, j int) bool {
+		return report.Clusters[i].PValue < report.Clusters[j].PValue
+	})
+	return report
+}
+
+// pseudoPValue returns the share of simulated values at least as far as
+// observed into the tail it's in, counting observed itself.
+func pseudoPValue(observed float64, simulated []float64) float64 {
+	larger := 0
+	for _, value := range simulated {
+		if value >= observed {
+			larger++
+		}
+	}
+	if len(simulated)-larger < larger {
+		larger = len(simulated) - larger
+	}
+	return float64(larger+1) / float64(len(simulated)+1)
+}
+
+// containsInt returns true if values contains value.
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// quadrant returns the quadrant of the Moran scatterplot for a cell whose
+// deviation from the mean is z and whose neighbors' is lag.
+func quadrant(z float64, lag float64) string {
+	switch {
+	case z > 0 && lag >= 0:
+		return HIGH_HIGH
+	case z > 0:
+		return HIGH_LOW
+	case lag > 0:
+		return LOW_HIGH
+	}
+	return LOW_LOW
+}
+
+// The JSON form of a LocalMoran.
+type localMoranJson struct {
+	Cell     string    `json:"cell"`
+	Center   pointJson `json:"center"`
+	Min      pointJson `json:"min"`
+	Max      pointJson `json:"max"`
+	Count    int       `json:"count"`
+	I        float64   `json:"i"`
+	PValue   float64   `json:"p_value"`
+	Quadrant string    `json:"quadrant"`
+}
+
+// ToJson returns the report marshalled to JSON bytes.
+func (report AutocorrelationReport) ToJson() ([]byte, error) {
+	clusters := make([]localMoranJson, 0, len(report.Clusters))
+	for _, cluster := range report.Clusters {
+		center, bounds := cluster.Cell.Center(), cluster.Cell.Bounds()
+		clusters = append(clusters, localMoranJson{
+			Cell:     cluster.Cell.String(),
+			Center:   pointJson{center.Lat, center.Lng},
+			Min:      pointJson{bounds.Min.Lat, bounds.Min.Lng},
+			Max:      pointJson{bounds.Max.Lat, bounds.Max.Lng},
+			Count:    cluster.Count,
+			I:        roundTo(cluster.I, 4),
+			PValue:   roundTo(cluster.PValue, 4),
+			Quadrant: cluster.Quadrant,
+		})
+	}
+	global := map[string]float64{
+		"i":        roundTo(report.Global.I, 4),
+		"expected": roundTo(report.Global.Expected, 4),
+		"z_score":  roundTo(report.Global.ZScore, 2),
+		"p_value":  roundTo(report.Global.PValue, 4),
+	}
+	return json.Marshal(struct {
+		Cells    int                `json:"cells"`
+		Global   map[string]float64 `json:"global"`
+		Clusters []localMoranJson   `json:"clusters"`
+	}{report.Cells, global, clusters})
+}
@@ -0,0 +1,85 @@
+package radar
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAutocorrelation(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	report := finder.Autocorrelation(AutocorrelationOptions{})
+	if report.Cells != 92 {
+		t.Error("Wrong number of cells: ", report.Cells)
+	}
+	if report.Global.I <= report.Global.Expected || report.Global.PValue > DEFAULT_SIGNIFICANCE || report.Global.ZScore <= 0 {
+		t.Error("Crimes in the test data should cluster: ", report.Global)
+	}
+	if len(report.Clusters) == 0 {
+		t.Fatal("There should be significant clusters")
+	}
+	highest := report.Clusters[0]
+	for i, cluster := range report.Clusters {
+		if cluster.PValue > DEFAULT_SIGNIFICANCE {
+			t.Error("Cluster is not significant: ", cluster)
+		}
+		if i > 0 && cluster.PValue < report.Clusters[i-1].PValue {
+			t.Error("Clusters should be most significant first: ", report.Clusters)
+		}
+		if (cluster.Quadrant == HIGH_HIGH || cluster.Quadrant == LOW_LOW) != (cluster.I > 0) {
+			t.Error("Clusters should have a positive I and outliers a negative one: ", cluster)
+		}
+		if cluster.Count > highest.Count {
+			highest = cluster
+		}
+	}
+	if highest.Quadrant != HIGH_HIGH || highest.Cell != (GridCell{6375, -16945}) {
+		t.Error("Downtown should be a high-high cluster: ", highest)
+	}
+	again := finder.Autocorrelation(AutocorrelationOptions{})
+	if again.Global != report.Global || len(again.Clusters) != len(report.Clusters) {
+		t.Error("Reports should be repeatable: ", again.Global, report.Global)
+	}
+
+	strict := finder.Autocorrelation(AutocorrelationOptions{Significance: 0.001})
+	if len(strict.Clusters) >= len(report.Clusters) {
+		t.Error("A lower significance should find fewer clusters: ", len(strict.Clusters))
+	}
+	filtered := finder.Autocorrelation(AutocorrelationOptions{Filter: SearchFilter{Type: "Burglary"}})
+	if filtered.Cells != report.Cells || filtered.Global.I == report.Global.I {
+		t.Error("A filter should count fewer crimes over the same cells: ", filtered.Cells, filtered.Global)
+	}
+	none := finder.Autocorrelation(AutocorrelationOptions{Filter: SearchFilter{Type: "Nothing"}})
+	if none.Global.I != 0 || none.Global.PValue != 1 || len(none.Clusters) != 0 {
+		t.Error("No crimes should have no autocorrelation: ", none)
+	}
+
+	data, err := report.ToJson()
+	if err != nil {
+		t.Fatal("Error marshalling report: ", err)
+	}
+	var decoded struct {
+		Cells    int
+		Global   map[string]float64
+		Clusters []struct{ Cell, Quadrant string }
+	}
+	json.Unmarshal(data, &decoded)
+	if decoded.Cells != 92 || decoded.Global["p_value"] == 0 || decoded.Clusters[0].Cell != report.Clusters[0].Cell.String() {
+		t.Error("Wrong JSON: ", string(data))
+	}
+}
+
+func TestPseudoPValue(t *testing.T) {
+	simulated := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	if p := pseudoPValue(10, simulated); p != 0.1 {
+		t.Error("Wrong p-value above every simulation: ", p)
+	}
+	if p := pseudoPValue(0, simulated); p != 0.1 {
+		t.Error("Wrong p-value below every simulation: ", p)
+	}
+	if p := pseudoPValue(8, simulated); p != 0.3 {
+		t.Error("Wrong p-value: ", p)
+	}
+}
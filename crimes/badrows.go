@@ -0,0 +1,162 @@
+package radar
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Actions a BadRowPolicy can take on rows that can't be loaded.
+const (
+	// SkipBadRows leaves bad rows out and lists them in the LoadReport. This
+	// is the default.
+	SkipBadRows = "skip"
+	// FailOnBadRows fails the load if any row is bad, before any crime is
+	// loaded.
+	FailOnBadRows = "fail"
+	// QuarantineBadRows leaves bad rows out and writes them to a quarantine
+	// file, so that they can be repaired and loaded later.
+	QuarantineBadRows = "quarantine"
+)
+
+// BadRowActions lists the actions a BadRowPolicy accepts.
+var BadRowActions = []string{SkipBadRows, FailOnBadRows, QuarantineBadRows}
+
+var errNoQuarantineFile = errors.New("quarantining rows that aren't from a file needs a quarantine file")
+
+// A BadRowPolicy decides what happens to the rows of a source of crimes that
+// can't be loaded. The zero value skips them.
+type BadRowPolicy struct {
+	// Action is one of BadRowActions.
+	Action string
+	// Quarantine is the file that QuarantineBadRows writes rows to. If it
+	// is empty, rows from a file go next to it, in a file with the same
+	// name ending in .quarantine.csv.
+	Quarantine string
+}
+
+// QuarantineFile returns the file the policy quarantines the bad rows of
+// source in, or "" if it has none.
+func (policy BadRowPolicy) QuarantineFile(source string) string {
+	if policy.Quarantine != "" || source == "" {
+		return policy.Quarantine
+	}
+	return strings.TrimSuffix(source, filepath.Ext(source)) + ".quarantine.csv"
+}
+
+// A BadRowsError fails a load with a FailOnBadRows policy.
+type BadRowsError struct {
+	Errors []RowError
+}
+
+func (e *BadRowsError) Error() string {
+	if len(e.Errors) == 1 {
+		return fmt.Sprintf("1 row could not be loaded: %v", e.Errors[0])
+	}
+	return fmt.Sprintf("%v rows could not be loaded, the first: %v", len(e.Errors), e.Errors[0])
+}
+
+// apply handles the rowErrors of source as the policy says. Loading a file
+// replaces its quarantine file, so that it holds the rows of the last load,
+// while ingested rows are appended to theirs.
+func (policy BadRowPolicy) apply(rowErrors []RowError, source string, appendRows bool) error {
+	switch policy.Action {
+	case "", SkipBadRows:
+		return nil
+	case FailOnBadRows:
+		if len(rowErrors) > 0 {
+			return &BadRowsError{rowErrors}
+		}
+		return nil
+	case QuarantineBadRows:
+		filename := policy.QuarantineFile(source)
+		if filename == "" {
+			return errNoQuarantineFile
+		}
+		if appendRows && len(rowErrors) == 0 {
+			return nil
+		}
+		return writeQuarantine(filename, rowErrors, appendRows)
+	}
+	return fmt.Errorf("unknown bad row action: %q", policy.Action)
+}
+
+// writeQuarantine writes the rows of rowErrors to filename as CSV in the
+// layout of RadarSchema, so that the file loads once they're repaired, with
+// each row's record number and error in columns after the schema's.
+func writeQuarantine(filename string, rowErrors []RowError, appendRows bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendRows {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(filename, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(f)
+	if info.Size() == 0 {
+		header := make([]string, 0, NUM_SCHEMA_COLUMNS+2)
+		for _, names := range RadarSchema.Columns {
+			header = append(header, names[0])
+		}
+		if err := writer.Write(append(header, "record", "error")); err != nil {
+			return err
+		}
+	}
+	for _, rowError := range rowErrors {
+		row := make([]string, NUM_SCHEMA_COLUMNS, NUM_SCHEMA_COLUMNS+2)
+		copy(row, rowError.row)
+		row = append(row, strconv.Itoa(rowError.Record), rowError.Err.Error())
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// checkRows splits rows into those that addRows can load and RowErrors for
+// the rest, so that a policy sees every bad row before any crime is loaded.
+// The rows are checked on up to workers goroutines.
+func checkRows(rows CsvRows, workers int) (CsvRows, []RowError) {
+	errs := make([]error, len(rows))
+	parallelChunks(len(rows), workers, func(start int, end int) {
+		for i := start; i < end; i++ {
+			errs[i] = checkRow(rows[i])
+		}
+	})
+	good := make(CsvRows, 0, len(rows))
+	rowErrors := make([]RowError, 0)
+	for i, row := range rows {
+		if errs[i] != nil {
+			rowErrors = append(rowErrors, newRowError(0, row, errs[i]))
+			continue
+		}
+		good = append(good, row)
+	}
+	return good, rowErrors
+}
+
+// checkRow returns the error addRows would skip row for, if any.
+func checkRow(row CsvRow) error {
+	if len(row) < NUM_COLUMNS {
+		return errShortRow
+	}
+	if _, err := pointFromRow(row); err != nil {
+		return err
+	}
+	_, err := strconv.ParseInt(row[0], 0, 64)
+	return err
+}
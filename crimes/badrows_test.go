@@ -0,0 +1,99 @@
+package radar
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const badRowsData = `Record ID,Report Date,Report Time,Major Offense Type,Address,Neighborhood,Police Precinct,Police District,X Coordinate,Y Coordinate
+1,12/01/2011,01:00:00,Liquor Laws,NE WEIDLER ST,LLOYD,PORTLAND PREC NO,690,45.53435699129174,-122.66469510763777
+2,07/07/2011,18:30:00,Liquor Laws,NE SCHUYLER ST,ELIOT,PORTLAND PREC NO,590,,
+three,07/08/2011,18:30:00,Burglary,NE SCHUYLER ST,ELIOT,PORTLAND PREC NO,590,45.53579735412487,-122.66468312170824
+`
+
+func TestBadRowPolicies(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "bad.csv")
+	os.WriteFile(filename, []byte(badRowsData), 0644)
+
+	finder, err := NewCrimeFinderWithOptions(filename, LoadOptions{BadRows: BadRowPolicy{Action: SkipBadRows}})
+	if err != nil || finder.Report.Crimes != 1 || len(finder.Report.Errors) != 2 {
+		t.Error("Bad rows should be skipped: ", err, finder.Report.Crimes, finder.Report.Errors)
+	}
+
+	_, err = NewCrimeFinderWithOptions(filename, LoadOptions{BadRows: BadRowPolicy{Action: FailOnBadRows}})
+	var badRows *BadRowsError
+	if !errors.As(err, &badRows) || len(badRows.Errors) != 2 {
+		t.Error("Bad rows should fail the load: ", err)
+	}
+
+	if _, err := NewCrimeFinderWithOptions(filename, LoadOptions{BadRows: BadRowPolicy{Action: "ignore"}}); err == nil {
+		t.Error("Unknown actions should fail the load")
+	}
+}
+
+func TestQuarantineBadRows(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "bad.csv")
+	os.WriteFile(filename, []byte(badRowsData), 0644)
+
+	policy := BadRowPolicy{Action: QuarantineBadRows}
+	finder, err := NewCrimeFinderWithOptions(filename, LoadOptions{BadRows: policy})
+	if err != nil || finder.Report.Crimes != 1 {
+		t.Fatal("Bad rows should be quarantined: ", err, finder.Report.Crimes)
+	}
+	quarantine := filepath.Join(dir, "bad.quarantine.csv")
+	if policy.QuarantineFile(filename) != quarantine {
+		t.Error("Wrong quarantine file: ", policy.QuarantineFile(filename))
+	}
+	contents, err := os.ReadFile(quarantine)
+	if err != nil {
+		t.Fatal("Error reading quarantine file: ", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], ",record,error") || !strings.Contains(lines[1], "missing coordinates") {
+		t.Error("Wrong quarantined rows: ", lines)
+	}
+
+	// Once repaired, the quarantined rows load.
+	repaired := strings.Replace(string(contents), "NE SCHUYLER ST,ELIOT,PORTLAND PREC NO,590,,", "NE SCHUYLER ST,ELIOT,PORTLAND PREC NO,590,45.5357,-122.6646", 1)
+	repaired = strings.Replace(repaired, "three,", "3,", 1)
+	os.WriteFile(quarantine, []byte(repaired), 0644)
+	loaded, err := NewCrimeFinder(quarantine)
+	if err != nil || loaded.Report.Crimes != 2 || len(loaded.Report.Errors) != 0 {
+		t.Error("Repaired rows should load: ", err, loaded.Report.Crimes, loaded.Report.Errors)
+	}
+}
+
+func TestIngestWithPolicy(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	crimes := finder.Report.Crimes
+	data := "99000001,12/31/2011,23:00:00,Burglary,,,,,45.5185,-122.6555\n99000002,12/31/2011,23:00:00,Burglary,,,,,,\n"
+
+	_, rowErrors, err := finder.IngestWithPolicy(strings.NewReader(data), nil, BadRowPolicy{Action: FailOnBadRows})
+	if err == nil || len(rowErrors) != 1 || finder.Report.Crimes != crimes {
+		t.Error("A bad row should stop every crime being ingested: ", err, rowErrors, finder.Report.Crimes)
+	}
+
+	if _, _, err := finder.IngestWithPolicy(strings.NewReader(data), nil, BadRowPolicy{Action: QuarantineBadRows}); err != errNoQuarantineFile {
+		t.Error("Ingested rows need a quarantine file: ", err)
+	}
+
+	quarantine := filepath.Join(t.TempDir(), "ingest.csv")
+	policy := BadRowPolicy{Action: QuarantineBadRows, Quarantine: quarantine}
+	for i := 0; i < 2; i++ {
+		if _, _, err := finder.IngestWithPolicy(strings.NewReader(data), nil, policy); err != nil {
+			t.Fatal("Error ingesting: ", err)
+		}
+	}
+	contents, _ := os.ReadFile(quarantine)
+	if lines := strings.Split(strings.TrimSpace(string(contents)), "\n"); len(lines) != 3 {
+		t.Error("Ingested rows should be appended to the quarantine file: ", lines)
+	}
+}
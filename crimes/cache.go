@@ -0,0 +1,143 @@
+package radar
+
+import (
+	"hash/fnv"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/abrookins/radar/internal/kdtree"
+)
+
+// A cacheCell is a square of the map, a nearCache's cellSize degrees on a
+// side, numbered from 0,0 at the equator and prime meridian.
+type cacheCell struct {
+	row, col int64
+}
+
+// A cachedLocation is a location a search from a cell could return, with
+// the coordinates the tree has for it.
+type cachedLocation struct {
+	lat, lng float64
+	location *CrimeLocation
+}
+
+// A nearCache holds, for some cells of the map, every location that a
+// search from anywhere in the cell could return, so that searches from
+// those cells skip the tree. It's safe to use from several goroutines.
+type nearCache struct {
+	cellSize float64
+	mu       sync.RWMutex
+	cells    map[cacheCell][]cachedLocation
+}
+
+func (cache *nearCache) cellOf(p Point) cacheCell {
+	return cacheCell{int64(math.Floor(p.Lat / cache.cellSize)), int64(math.Floor(p.Lng / cache.cellSize))}
+}
+
+// lookup returns the locations cached for the cell that query is in. It's
+// safe to call on a nil cache, which has no cells.
+func (cache *nearCache) lookup(query Point) ([]cachedLocation, bool) {
+	if cache == nil {
+		return nil, false
+	}
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	locations, ok := cache.cells[cache.cellOf(query)]
+	return locations, ok
+}
+
+// clear empties the cache. It's safe to call on a nil cache.
+func (cache *nearCache) clear() {
+	if cache == nil {
+		return
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.cells = make(map[cacheCell][]cachedLocation)
+}
+
+// EnableCache gives the finder a cache of searches for cells of the map,
+// cellSize degrees on a side. The cache starts empty; Warm fills it.
+func (finder *CrimeFinder) EnableCache(cellSize float64) {
+	finder.cache = &nearCache{cellSize: cellSize, cells: make(map[cacheCell][]cachedLocation)}
+}
+
+// Warm replaces the cells in the finder's cache with the cells that points
+// are in, finding the locations that searches from each cell could return.
+// It returns the number of cells cached. Loading new data empties the
+// cache, so call Warm again afterwards. Warm does nothing if the finder has
+// no cache.
+func (finder *CrimeFinder) Warm(points []Point) int {
+	cache := finder.cache
+	tree := finder.tree()
+	if cache == nil || tree == nil {
+		return 0
+	}
+	cells := make(map[cacheCell][]cachedLocation)
+	for _, p := range points {
+		cell := cache.cellOf(p)
+		if _, done := cells[cell]; done {
+			continue
+		}
+		// Searches from the edges of the cell reach half a mile beyond it.
+		minLat, minLng := float64(cell.row)*cache.cellSize, float64(cell.col)*cache.cellSize
+		ranges := map[int]kdtree.Range{
+			LAT_AXIS: {Min: minLat - HALF_MILE_LAT, Max: minLat + cache.cellSize + HALF_MILE_LAT},
+			LNG_AXIS: {Min: minLng - HALF_MILE_LNG, Max: minLng + cache.cellSize + HALF_MILE_LNG}}
+		nodes, err := tree.FindRange(ranges)
+		if err != nil {
+			continue
+		}
+		locations := make([]cachedLocation, 0, len(nodes))
+		for _, node := range nodes {
+			point := nodePoint(node)
+			key := GetCoordinateKey(point.Lat, point.Lng)
+			if location, exists := finder.LocationLookup[key]; exists {
+				locations = append(locations, cachedLocation{point.Lat, point.Lng, location})
+			}
+		}
+		cells[cell] = locations
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.cells = cells
+	return len(cells)
+}
+
+// Fingerprint identifies the finder's data: finders with the same crimes at
+// the same locations have the same fingerprint, so it can key caches that
+// are shared between processes.
+func (finder *CrimeFinder) Fingerprint() string {
+	return finder.fingerprint
+}
+
+// LoadedAt returns when the finder's data was loaded, or last changed by
+// Ingest.
+func (finder *CrimeFinder) LoadedAt() time.Time {
+	return finder.loadedAt
+}
+
+// Version identifies a load of the finder's data: its fingerprint and the
+// time it was loaded, in Unix seconds. Unlike the fingerprint, it changes
+// when the same data is loaded again.
+func (finder *CrimeFinder) Version() string {
+	if finder.fingerprint == "" {
+		return ""
+	}
+	return finder.fingerprint + "-" + strconv.FormatInt(finder.loadedAt.Unix(), 10)
+}
+
+// buildFingerprint computes the finder's fingerprint from its locations.
+func (finder *CrimeFinder) buildFingerprint() {
+	hash := fnv.New64a()
+	for _, location := range finder.Locations() {
+		hash.Write([]byte(GetCoordinateKey(location.Point.Lat, location.Point.Lng).String()))
+		for _, crime := range location.Crimes {
+			hash.Write([]byte("," + strconv.FormatInt(crime.Id, 10)))
+		}
+		hash.Write([]byte(";"))
+	}
+	finder.fingerprint = strconv.FormatUint(hash.Sum64(), 16)
+}
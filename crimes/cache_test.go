@@ -0,0 +1,71 @@
+package radar
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCrimeFinderCache(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	queries := []Point{{Lat: 45.5184, Lng: -122.6554}, {Lat: 45.5199, Lng: -122.6501}, {Lat: 45.5250, Lng: -122.6801}}
+	expected := make([]SearchResult, len(queries))
+	for i, query := range queries {
+		expected[i], _ = finder.FindNear(query)
+	}
+	if len(expected[0].Locations) == 0 {
+		t.Fatal("No crimes near the first query")
+	}
+
+	finder.EnableCache(0.01)
+	if warmed := finder.Warm(queries); warmed != 2 {
+		t.Error("Wrong number of cells warmed: ", warmed)
+	}
+	for i, query := range queries {
+		result, _ := finder.FindNearExplained(query)
+		if result.Explanation.Cache != "hit" {
+			t.Error("Search from a warm cell missed the cache")
+		}
+		if len(result.Locations) != len(expected[i].Locations) {
+			t.Fatal("Cached search found ", len(result.Locations), " locations instead of ", len(expected[i].Locations))
+		}
+		for j, location := range result.Locations {
+			if location != expected[i].Locations[j] {
+				t.Error("Cached search returned the wrong locations")
+				break
+			}
+		}
+	}
+	if result, _ := finder.FindNearExplained(Point{Lat: 45.4, Lng: -122.6}); result.Explanation.Cache != "miss" {
+		t.Error("Search from a cold cell should miss the cache: ", result.Explanation.Cache)
+	}
+
+	finder.Ingest(strings.NewReader("99000001,12/31/2011,23:00:00,Burglary,,,,,45.5185,-122.6555\n"), nil)
+	if result, _ := finder.FindNearExplained(queries[0]); result.Explanation.Cache != "miss" {
+		t.Error("Ingesting crimes should empty the cache")
+	}
+}
+
+func TestCrimeFinderFingerprint(t *testing.T) {
+	first, _ := NewCrimeFinder("sample/crimes.csv")
+	second, _ := NewCrimeFinder("sample/crimes.csv")
+	if first.Fingerprint() == "" || first.Fingerprint() != second.Fingerprint() {
+		t.Error("Finders with the same data should have the same fingerprint: ", first.Fingerprint(), second.Fingerprint())
+	}
+	second.Ingest(strings.NewReader("99000001,12/31/2011,23:00:00,Burglary,,,,,45.5185,-122.6555\n"), nil)
+	if first.Fingerprint() == second.Fingerprint() {
+		t.Error("Ingesting crimes should change the fingerprint")
+	}
+}
+
+func TestCrimeFinderVersion(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	if !strings.HasPrefix(finder.Version(), finder.Fingerprint()+"-") || finder.LoadedAt().IsZero() {
+		t.Error("Wrong version: ", finder.Version())
+	}
+	if empty := (CrimeFinder{}); empty.Version() != "" {
+		t.Error("A finder without data should have no version: ", empty.Version())
+	}
+}
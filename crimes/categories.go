@@ -0,0 +1,131 @@
+package radar
+
+import (
+	"strings"
+)
+
+// The top level of the crime taxonomy, following NIBRS's "crime against"
+// categories.
+const (
+	PersonCategory   = "person"
+	PropertyCategory = "property"
+	SocietyCategory  = "society"
+	// OtherCategory holds crime types that aren't in the taxonomy.
+	OtherCategory = "other"
+)
+
+// Categories lists every category a crime can be in.
+var Categories = []string{PersonCategory, PropertyCategory, SocietyCategory, OtherCategory}
+
+// A Classification places a crime type in the taxonomy: a NIBRS-style
+// offense group within a category.
+type Classification struct {
+	Category string
+	Group    string
+}
+
+// taxonomy maps lowercase crime types from the cities' data to their
+// Classification.
+var taxonomy = map[string]Classification{
+	// Crimes against persons
+	"homicide":                   {PersonCategory, "Homicide Offenses"},
+	"aggravated assault":         {PersonCategory, "Assault Offenses"},
+	"assault, simple":            {PersonCategory, "Assault Offenses"},
+	"simple assault":             {PersonCategory, "Assault Offenses"},
+	"intimidation":               {PersonCategory, "Assault Offenses"},
+	"battery":                    {PersonCategory, "Assault Offenses"},
+	"assault":                    {PersonCategory, "Assault Offenses"},
+	"kidnap":                     {PersonCategory, "Kidnapping/Abduction"},
+	"kidnapping/abduction":       {PersonCategory, "Kidnapping/Abduction"},
+	"kidnapping":                 {PersonCategory, "Kidnapping/Abduction"},
+	"rape":                       {PersonCategory, "Sex Offenses"},
+	"sex offenses":               {PersonCategory, "Sex Offenses"},
+	"sex offense":                {PersonCategory, "Sex Offenses"},
+	"criminal sexual assault":    {PersonCategory, "Sex Offenses"},
+	"offenses against family":    {PersonCategory, "Family Offenses"},
+	"offense involving children": {PersonCategory, "Family Offenses"},
+	// Crimes against property
+	"arson":                        {PropertyCategory, "Arson"},
+	"burglary":                     {PropertyCategory, "Burglary/Breaking & Entering"},
+	"burglary/breaking & entering": {PropertyCategory, "Burglary/Breaking & Entering"},
+	"embezzlement":                 {PropertyCategory, "Embezzlement"},
+	"forgery":                      {PropertyCategory, "Counterfeiting/Forgery"},
+	"counterfeiting/forgery":       {PropertyCategory, "Counterfeiting/Forgery"},
+	"fraud":                        {PropertyCategory, "Fraud Offenses"},
+	"deceptive practice":           {PropertyCategory, "Fraud Offenses"},
+	"identity theft":               {PropertyCategory, "Fraud Offenses"},
+	"larceny":                      {PropertyCategory, "Larceny/Theft Offenses"},
+	"theft":                        {PropertyCategory, "Larceny/Theft Offenses"},
+	"shoplifting":                  {PropertyCategory, "Larceny/Theft Offenses"},
+	"theft from motor vehicle":     {PropertyCategory, "Larceny/Theft Offenses"},
+	"motor vehicle theft":          {PropertyCategory, "Motor Vehicle Theft"},
+	"robbery":                      {PropertyCategory, "Robbery"},
+	"stolen property":              {PropertyCategory, "Stolen Property Offenses"},
+	"stolen property offenses":     {PropertyCategory, "Stolen Property Offenses"},
+	"vandalism":                    {PropertyCategory, "Destruction/Damage/Vandalism of Property"},
+	"criminal damage":              {PropertyCategory, "Destruction/Damage/Vandalism of Property"},
+	"destruction/damage/vandalism of property": {PropertyCategory, "Destruction/Damage/Vandalism of Property"},
+	// Crimes against society
+	"curfew":                   {SocietyCategory, "Curfew/Loitering/Vagrancy Violations"},
+	"disorderly conduct":       {SocietyCategory, "Disorderly Conduct"},
+	"drugs":                    {SocietyCategory, "Drug/Narcotic Offenses"},
+	"narcotics":                {SocietyCategory, "Drug/Narcotic Offenses"},
+	"drug/narcotic violations": {SocietyCategory, "Drug/Narcotic Offenses"},
+	"duii":                     {SocietyCategory, "Driving Under the Influence"},
+	"gambling":                 {SocietyCategory, "Gambling Offenses"},
+	"liquor laws":              {SocietyCategory, "Liquor Law Violations"},
+	"liquor law violation":     {SocietyCategory, "Liquor Law Violations"},
+	"prostitution":             {SocietyCategory, "Prostitution Offenses"},
+	"runaway":                  {SocietyCategory, "Runaway"},
+	"trespass":                 {SocietyCategory, "Trespass of Real Property"},
+	"criminal trespass":        {SocietyCategory, "Trespass of Real Property"},
+	"weapons":                  {SocietyCategory, "Weapon Law Violations"},
+	"weapons violation":        {SocietyCategory, "Weapon Law Violations"},
+	"weapon law violations":    {SocietyCategory, "Weapon Law Violations"},
+}
+
+// Classify returns the Classification of a crime type. A type that isn't in
+// the taxonomy is in OtherCategory, in a group of its own.
+func Classify(crimeType string) Classification {
+	if classification, ok := taxonomy[strings.ToLower(strings.TrimSpace(crimeType))]; ok {
+		return classification
+	}
+	return Classification{OtherCategory, crimeType}
+}
+
+// IsCategory reports whether name is one of the Categories.
+func IsCategory(name string) bool {
+	for _, category := range Categories {
+		if name == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns a SearchResult with only the crimes for which keep returns
+// true. Locations left without crimes are dropped. The CrimeLocations in the
+// original result are not changed.
+func (r SearchResult) Filter(keep func(crime *Crime) bool) SearchResult {
+	filtered := r
+	filtered.Locations = make([]*CrimeLocation, 0, len(r.Locations))
+	for _, location := range r.Locations {
+		crimes := make([]*Crime, 0, len(location.Crimes))
+		for _, crime := range location.Crimes {
+			if keep(crime) {
+				crimes = append(crimes, crime)
+			}
+		}
+		if len(crimes) > 0 {
+			filtered.Locations = append(filtered.Locations, &CrimeLocation{location.Point, crimes})
+		}
+	}
+	return filtered
+}
+
+// FilterCategory returns a SearchResult with only the crimes in category.
+func (r SearchResult) FilterCategory(category string) SearchResult {
+	return r.Filter(func(crime *Crime) bool {
+		return Classify(crime.Type).Category == category
+	})
+}
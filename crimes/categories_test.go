@@ -0,0 +1,63 @@
+package radar
+
+import (
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	expected := map[string]Classification{
+		"Larceny":         {PropertyCategory, "Larceny/Theft Offenses"},
+		"Assault, Simple": {PersonCategory, "Assault Offenses"},
+		"BATTERY":         {PersonCategory, "Assault Offenses"},
+		"Liquor Laws":     {SocietyCategory, "Liquor Law Violations"},
+		"Jaywalking":      {OtherCategory, "Jaywalking"},
+	}
+	for crimeType, classification := range expected {
+		if Classify(crimeType) != classification {
+			t.Error("Classify(", crimeType, ") returned ", Classify(crimeType))
+		}
+	}
+}
+
+// Every type in the City's data should be in the taxonomy.
+func TestClassifyCityTypes(t *testing.T) {
+	finder, _ := NewCrimeFinder("../data/crime_incident_data_wgs84.csv")
+	for _, crimeType := range finder.CrimeTypes {
+		if Classify(crimeType).Category == OtherCategory {
+			t.Error("Crime type is not in the taxonomy: ", crimeType)
+		}
+	}
+}
+
+func TestIsCategory(t *testing.T) {
+	if !IsCategory(PropertyCategory) || IsCategory("Property") || IsCategory("") {
+		t.Error("IsCategory returned the wrong answer")
+	}
+}
+
+func TestSearchResultFilterCategory(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	result, _ := finder.FindNear(Point{45.53435699129174, -122.66469510763777})
+	total := len(result.Crimes())
+	counted := 0
+	for _, category := range Categories {
+		filtered := result.FilterCategory(category)
+		for _, location := range filtered.Locations {
+			if len(location.Crimes) == 0 {
+				t.Error("FilterCategory should drop locations without crimes")
+			}
+		}
+		for _, crime := range filtered.Crimes() {
+			if Classify(crime.Type).Category != category {
+				t.Error("FilterCategory kept a crime in another category: ", crime)
+			}
+		}
+		counted += len(filtered.Crimes())
+	}
+	if counted != total {
+		t.Error("Every crime should be in exactly one category: ", counted, total)
+	}
+	if len(result.Crimes()) != total {
+		t.Error("FilterCategory should not change the original result")
+	}
+}
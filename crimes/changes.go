@@ -0,0 +1,166 @@
+package radar
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// A ChangedCrime is a crime in Changes and the point it occurred at.
+type ChangedCrime struct {
+	Crime *Crime
+	Point *Point
+}
+
+// A Correction is a crime whose record changed between two versions of the
+// data.
+type Correction struct {
+	Before ChangedCrime
+	After  ChangedCrime
+}
+
+// Changes describes how a finder's data differs from an earlier version,
+// such as when the city corrects old records: the crimes that were removed
+// or corrected, and how many were added. Crimes are matched by id.
+type Changes struct {
+	// From and To are the versions of the earlier and later data.
+	From      string
+	To        string
+	Added     int
+	Removed   []ChangedCrime
+	Corrected []Correction
+	// BaseURL is the URL of the server that the permalinks of crimes point
+	// to, as in SearchResult.
+	BaseURL string
+}
+
+// Shift returns how far, in miles, the correction moved the crime.
+func (correction Correction) Shift() float64 {
+	return correction.Before.Point.GreatCircleDistance(correction.After.Point)
+}
+
+// IsEmpty returns true if the data didn't change.
+func (changes Changes) IsEmpty() bool {
+	return changes.Added == 0 && len(changes.Removed) == 0 && len(changes.Corrected) == 0
+}
+
+// sameRecord returns true if two crimes have the same record. Enrichments
+// are left out, since they aren't part of the city's data.
+func sameRecord(a ChangedCrime, b ChangedCrime) bool {
+	return a.Crime.Date == b.Crime.Date && a.Crime.Time == b.Crime.Time &&
+		a.Crime.Type == b.Crime.Type && a.Crime.Weapon == b.Crime.Weapon &&
+		a.Crime.CaseNumber == b.Crime.CaseNumber &&
+		reflect.DeepEqual(a.Crime.Domestic, b.Crime.Domestic) &&
+		reflect.DeepEqual(a.Crime.Arrest, b.Crime.Arrest) &&
+		reflect.DeepEqual(a.Crime.Offenses, b.Crime.Offenses) &&
+		*a.Point == *b.Point
+}
+
+// eachIndexed calls visit with each crime of the finder that FindByID
+// returns, skipping the later crimes of an id that several crimes share.
+func (finder *CrimeFinder) eachIndexed(visit func(crime ChangedCrime)) {
+	for _, location := range finder.Locations() {
+		for _, crime := range location.Crimes {
+			if indexed, _ := finder.FindByID(crime.Id); indexed == crime {
+				visit(ChangedCrime{crime, location.Point})
+			}
+		}
+	}
+}
+
+// Diff returns the changes from the data of old to the data of finder.
+func (finder *CrimeFinder) Diff(old *CrimeFinder) Changes {
+	changes := Changes{
+		From:      old.Version(),
+		To:        finder.Version(),
+		Removed:   make([]ChangedCrime, 0),
+		Corrected: make([]Correction, 0),
+	}
+	old.eachIndexed(func(before ChangedCrime) {
+		crime, location := finder.FindByID(before.Crime.Id)
+		if crime == nil {
+			changes.Removed = append(changes.Removed, before)
+			return
+		}
+		after := ChangedCrime{crime, location.Point}
+		if !sameRecord(before, after) {
+			changes.Corrected = append(changes.Corrected, Correction{before, after})
+		}
+	})
+	finder.eachIndexed(func(after ChangedCrime) {
+		if crime, _ := old.FindByID(after.Crime.Id); crime == nil {
+			changes.Added += 1
+		}
+	})
+	return changes
+}
+
+// CountByType returns the number of crimes of each type in the finder.
+func (finder *CrimeFinder) CountByType() map[string]int {
+	counts := make(map[string]int)
+	for _, location := range finder.Locations() {
+		for _, crime := range location.Crimes {
+			counts[crime.Type] += 1
+		}
+	}
+	return counts
+}
+
+// The JSON form of a ChangedCrime.
+type changedCrimeJson struct {
+	Id       int64     `json:"id"`
+	Date     string    `json:"date"`
+	Time     string    `json:"time"`
+	Type     string    `json:"type"`
+	Weapon   string    `json:"weapon,omitempty"`
+	Domestic *bool     `json:"domestic,omitempty"`
+	Arrest   *bool     `json:"arrest,omitempty"`
+	Case     string    `json:"case,omitempty"`
+	Offenses []Offense `json:"offenses,omitempty"`
+	Point    pointJson `json:"point"`
+	URL      string    `json:"url"`
+}
+
+func (changed ChangedCrime) toJson(baseURL string) changedCrimeJson {
+	crime := changed.Crime
+	return changedCrimeJson{
+		Id:       crime.Id,
+		Date:     crime.Date,
+		Time:     crime.Time,
+		Type:     crime.Type,
+		Weapon:   crime.Weapon,
+		Domestic: crime.Domestic,
+		Arrest:   crime.Arrest,
+		Case:     crime.CaseNumber,
+		Offenses: crime.Offenses,
+		Point:    pointJson{changed.Point.Lat, changed.Point.Lng},
+		URL:      Permalink(baseURL, crime.Id),
+	}
+}
+
+// ToJson returns Changes marshalled to JSON bytes.
+func (changes Changes) ToJson() ([]byte, error) {
+	type correctionJson struct {
+		Before changedCrimeJson `json:"before"`
+		After  changedCrimeJson `json:"after"`
+	}
+	out := struct {
+		From      string             `json:"from"`
+		To        string             `json:"to"`
+		Added     int                `json:"added"`
+		Removed   []changedCrimeJson `json:"removed"`
+		Corrected []correctionJson   `json:"corrected"`
+	}{
+		From:      changes.From,
+		To:        changes.To,
+		Added:     changes.Added,
+		Removed:   make([]changedCrimeJson, 0, len(changes.Removed)),
+		Corrected: make([]correctionJson, 0, len(changes.Corrected)),
+	}
+	for _, removed := range changes.Removed {
+		out.Removed = append(out.Removed, removed.toJson(changes.BaseURL))
+	}
+	for _, correction := range changes.Corrected {
+		out.Corrected = append(out.Corrected, correctionJson{correction.Before.toJson(changes.BaseURL), correction.After.toJson(changes.BaseURL)})
+	}
+	return json.Marshal(out)
+}
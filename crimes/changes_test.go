@@ -0,0 +1,58 @@
+package radar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	data, err := os.ReadFile("sample/crimes.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The city removes the first crime, moves the second to another type
+	// and reports a new one.
+	lines := strings.Split(string(data), "\n")
+	lines[2] = strings.Replace(lines[2], "Liquor Laws", "Disorderly Conduct", 1)
+	lines = append(lines[:1], lines[2:]...)
+	lines = append(lines, "99000001,12/31/2011,23:00:00,Burglary,,,,,45.531,-122.661")
+	filename := filepath.Join(t.TempDir(), "corrected.csv")
+	os.WriteFile(filename, []byte(strings.Join(lines, "\n")), 0644)
+	finder, err := NewCrimeFinder(filename)
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+
+	changes := finder.Diff(&old)
+	if changes.Added != 1 || changes.From != old.Version() || changes.To != finder.Version() {
+		t.Error("Diff has the wrong versions or additions: ", changes.Added)
+	}
+	if len(changes.Removed) != 1 || changes.Removed[0].Crime.Id != 13807517 {
+		t.Error("Diff has the wrong removals: ", changes.Removed)
+	}
+	if len(changes.Corrected) != 1 || changes.Corrected[0].Before.Crime.Type != "Liquor Laws" ||
+		changes.Corrected[0].After.Crime.Type != "Disorderly Conduct" {
+		t.Error("Diff has the wrong corrections: ", changes.Corrected)
+	}
+	if shift := changes.Corrected[0].Shift(); shift != 0 {
+		t.Error("Correction should not have moved the crime: ", shift)
+	}
+	oldCounts, counts := old.CountByType(), finder.CountByType()
+	if counts["Burglary"] != oldCounts["Burglary"]+1 || counts["Liquor Laws"] != oldCounts["Liquor Laws"]-2 {
+		t.Error("Wrong counts by type: ", counts["Burglary"], counts["Liquor Laws"])
+	}
+	if same := old.Diff(&old); !same.IsEmpty() {
+		t.Error("Data should not differ from itself: ", same)
+	}
+
+	json, err := changes.ToJson()
+	if err != nil || !strings.Contains(string(json), `"removed":[{"id":13807517,`) || !strings.Contains(string(json), `"added":1`) {
+		t.Error("Changes have the wrong JSON: ", string(json), err)
+	}
+}
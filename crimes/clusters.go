@@ -0,0 +1,201 @@
+package radar
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+)
+
+// Settings for clustering locations on a web map, in the manner of
+// Mapbox's supercluster.
+const (
+	// CLUSTER_RADIUS is how close, in pixels, locations must be on the map
+	// to join a cluster.
+	CLUSTER_RADIUS = 60
+	// CLUSTER_EXTENT is the width and height of a map tile in pixels.
+	CLUSTER_EXTENT = 512
+	// CLUSTER_MAX_ZOOM is the highest zoom level with clusters. Locations
+	// are shown on their own when the map is zoomed in further.
+	CLUSTER_MAX_ZOOM = 16
+	// MAX_ZOOM is the highest zoom level of web maps.
+	MAX_ZOOM = 22
+)
+
+// A Cluster is a group of nearby locations as shown at one zoom level of a
+// map, or a single location.
+type Cluster struct {
+	// Point is the center of the cluster's crimes.
+	Point Point
+	// Count is the number of crimes in the cluster.
+	Count int
+	// ExpansionZoom is the zoom level at which the cluster splits apart. It
+	// is zero if the cluster is a single location.
+	ExpansionZoom int
+	// Location is set if the cluster is a single location.
+	Location *CrimeLocation
+}
+
+// A clusterNode is a cluster in the index: a location or a group of
+// clusters from the zoom level above.
+type clusterNode struct {
+	// x and y are the node's position in web mercator, from 0 to 1.
+	x, y  float64
+	count int
+	// zoom is the level the node was formed at.
+	zoom     int
+	location *CrimeLocation
+}
+
+// A clusterIndex holds the clusters of every zoom level.
+type clusterIndex struct {
+	// levels holds the clusters of each zoom level up to CLUSTER_MAX_ZOOM,
+	// and then the locations on their own.
+	levels [CLUSTER_MAX_ZOOM + 2][]*clusterNode
+}
+
+// clusters builds a finder's clusterIndex the first time it's needed.
+// buildIndexes replaces it when the data changes.
+type clusters struct {
+	once  sync.Once
+	index *clusterIndex
+}
+
+// project converts p to web mercator, where the map is a square from 0 to 1.
+func project(p Point) (float64, float64) {
+	sin := math.Sin(p.Lat * math.Pi / 180)
+	y := 0.5 - 0.25*math.Log((1+sin)/(1-sin))/math.Pi
+	return p.Lng/360 + 0.5, math.Min(math.Max(y, 0), 1)
+}
+
+// unproject converts a web mercator position back to latitude and longitude.
+func unproject(x, y float64) Point {
+	lat := 360*math.Atan(math.Exp((180-y*360)*math.Pi/180))/math.Pi - 90
+	return Point{lat, (x - 0.5) * 360}
+}
+
+// newClusterIndex clusters the finder's locations at every zoom level.
+// Each level is built from the one above it: a node absorbs the nodes within
+// CLUSTER_RADIUS pixels of it that haven't joined a cluster yet.
+func newClusterIndex(locations []*CrimeLocation) *clusterIndex {
+	index := &clusterIndex{}
+	leaves := make([]*clusterNode, 0, len(locations))
+	for _, location := range locations {
+		x, y := project(*location.Point)
+		leaves = append(leaves, &clusterNode{x: x, y: y, count: len(location.Crimes), location: location})
+	}
+	index.levels[CLUSTER_MAX_ZOOM+1] = leaves
+	for zoom := CLUSTER_MAX_ZOOM; zoom >= 0; zoom-- {
+		index.levels[zoom] = clusterLevel(index.levels[zoom+1], zoom)
+	}
+	return index
+}
+
+// clusterLevel clusters nodes, the clusters of the zoom level above, for
+// zoom.
+func clusterLevel(nodes []*clusterNode, zoom int) []*clusterNode {
+	radius := CLUSTER_RADIUS / (CLUSTER_EXTENT * math.Pow(2, float64(zoom)))
+	type cell struct{ x, y int }
+	cellOf := func(node *clusterNode) cell {
+		return cell{int(math.Floor(node.x / radius)), int(math.Floor(node.y / radius))}
+	}
+	grid := make(map[cell][]int)
+	for i, node := range nodes {
+		c := cellOf(node)
+		grid[c] = append(grid[c], i)
+	}
+	clustered := make([]bool, len(nodes))
+	level := make([]*clusterNode, 0)
+	for i, node := range nodes {
+		if clustered[i] {
+			continue
+		}
+		clustered[i] = true
+		count := node.count
+		x, y := node.x*float64(node.count), node.y*float64(node.count)
+		c := cellOf(node)
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for _, j := range grid[cell{c.x + dx, c.y + dy}] {
+					neighbor := nodes[j]
+					if clustered[j] || math.Hypot(neighbor.x-node.x, neighbor.y-node.y) > radius {
+						continue
+					}
+					clustered[j] = true
+					count += neighbor.count
+					x += neighbor.x * float64(neighbor.count)
+					y += neighbor.y * float64(neighbor.count)
+				}
+			}
+		}
+		if count == node.count {
+			// Nothing joined the node, so it's the same at this level.
+			level = append(level, node)
+			continue
+		}
+		level = append(level, &clusterNode{x: x / float64(count), y: y / float64(count), count: count, zoom: zoom})
+	}
+	return level
+}
+
+// Clusters returns the clusters of the finder's locations inside bounds as
+// a map shows them at zoom, from 0 for the whole world to MAX_ZOOM.
+func (finder *CrimeFinder) Clusters(bounds Bounds, zoom int) []Cluster {
+	var index *clusterIndex
+	if finder.clusters == nil {
+		index = newClusterIndex(finder.Locations())
+	} else {
+		finder.clusters.once.Do(func() {
+			finder.clusters.index = newClusterIndex(finder.Locations())
+		})
+		index = finder.clusters.index
+	}
+	if zoom > CLUSTER_MAX_ZOOM {
+		zoom = CLUSTER_MAX_ZOOM + 1
+	}
+	if zoom < 0 {
+		zoom = 0
+	}
+	result := make([]Cluster, 0)
+	for _, node := range index.levels[zoom] {
+		cluster := Cluster{Count: node.count}
+		if node.location != nil {
+			cluster.Point = *node.location.Point
+			cluster.Location = node.location
+		} else {
+			cluster.Point = unproject(node.x, node.y)
+			cluster.ExpansionZoom = node.zoom + 1
+		}
+		if bounds.Contains(cluster.Point) {
+			result = append(result, cluster)
+		}
+	}
+	return result
+}
+
+// ClustersToJson returns clusters marshalled to JSON bytes. A cluster that
+// is a single location lists the ids of its crimes.
+func ClustersToJson(clusters []Cluster) ([]byte, error) {
+	type clusterJson struct {
+		Point         pointJson `json:"point"`
+		Count         int       `json:"count"`
+		ExpansionZoom int       `json:"expansion_zoom,omitempty"`
+		Crimes        []int64   `json:"crimes,omitempty"`
+	}
+	encoded := make([]clusterJson, 0, len(clusters))
+	for _, cluster := range clusters {
+		c := clusterJson{
+			Point:         pointJson{cluster.Point.Lat, cluster.Point.Lng},
+			Count:         cluster.Count,
+			ExpansionZoom: cluster.ExpansionZoom,
+		}
+		if cluster.Location != nil {
+			for _, crime := range cluster.Location.Crimes {
+				c.Crimes = append(c.Crimes, crime.Id)
+			}
+		}
+		encoded = append(encoded, c)
+	}
+	return json.Marshal(struct {
+		Clusters []clusterJson `json:"clusters"`
+	}{encoded})
+}
@@ -0,0 +1,61 @@
+package radar
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestProject(t *testing.T) {
+	point := Point{45.5184, -122.6554}
+	x, y := project(point)
+	if x < 0 || x > 1 || y < 0 || y > 1 {
+		t.Fatal("Projection is off the map: ", x, y)
+	}
+	if back := unproject(x, y); math.Abs(back.Lat-point.Lat) > 1e-9 || math.Abs(back.Lng-point.Lng) > 1e-9 {
+		t.Error("Unprojection should return the same point: ", back)
+	}
+}
+
+func TestClusters(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	world := Bounds{Point{-90, -180}, Point{90, 180}}
+	clusters := finder.Clusters(world, 0)
+	if len(clusters) != 1 || clusters[0].Count != finder.Report.Crimes || clusters[0].ExpansionZoom < 1 {
+		t.Fatal("The whole city should be one cluster at zoom 0: ", clusters)
+	}
+	previous := 1
+	for zoom := 1; zoom <= MAX_ZOOM; zoom++ {
+		clusters := finder.Clusters(world, zoom)
+		count := 0
+		for _, cluster := range clusters {
+			count += cluster.Count
+			if cluster.Location == nil && cluster.ExpansionZoom <= zoom {
+				t.Error("A cluster should expand at a higher zoom: ", zoom, cluster.ExpansionZoom)
+			}
+		}
+		if count != finder.Report.Crimes || len(clusters) < previous {
+			t.Error("Clusters at zoom ", zoom, " have the wrong number of crimes or split less: ", count, len(clusters))
+		}
+		previous = len(clusters)
+	}
+	if clusters := finder.Clusters(world, MAX_ZOOM); len(clusters) != finder.Report.Locations {
+		t.Error("Every location should be on its own at the highest zoom: ", len(clusters))
+	}
+
+	// Only clusters inside the bounds are returned.
+	bounds := Bounds{Point{45.52, -122.67}, Point{45.53, -122.66}}
+	for _, cluster := range finder.Clusters(bounds, 15) {
+		if !bounds.Contains(cluster.Point) {
+			t.Error("Cluster outside the bounds: ", cluster.Point)
+		}
+	}
+
+	resp, err := ClustersToJson(finder.Clusters(world, MAX_ZOOM)[:1])
+	if err != nil || !strings.Contains(string(resp), `"crimes":[`) || strings.Contains(string(resp), "expansion_zoom") {
+		t.Error("Wrong JSON for a location: ", string(resp), err)
+	}
+}
@@ -0,0 +1,478 @@
+package radar
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// A compressed snapshot holds the same data as a snapshot in a fraction of
+// the space, but must be decompressed rather than mapped into memory. Its
+// header is laid out like a snapshot's, with its own magic bytes and the
+// number of columns in place of the number of strings. Each column follows
+// as its length and then its DEFLATE-compressed bytes:
+//
+//   - The latitude and longitude of each location, with the bits of each
+//     XORed with the previous location's, as uvarints.
+//   - The number of crimes at each location.
+//   - Crime ids, each as a varint delta from the previous crime's.
+//   - When each crime occurred: for a crime whose date and time are in the
+//     usual layouts, a zero byte and the varint delta in seconds from the
+//     previous such crime, and otherwise a one byte and both strings.
+//   - Types, weapons, case numbers and neighborhoods, each as a dictionary
+//     of distinct values followed by each crime's index into it.
+//   - The domestic, arrest and geocoded flags of each crime.
+//   - The number of offenses of each crime, each offense's id as a varint
+//     delta from the previous offense's, and its type as an index into a
+//     dictionary at the start of the column.
+//
+// Integers are varints as written by encoding/binary, and strings are a
+// uvarint length followed by their bytes. Each column is compressed on its
+// own, so they're decompressed and decoded in parallel.
+//
+// Columns are compressed with DEFLATE rather than zstd. The package keeps
+// to the standard library, which has no zstd, and writing a zstd codec
+// would dwarf the rest of the format. Most of the savings come from the
+// column encodings anyway, which DEFLATE then squeezes well. A format with
+// zstd columns would be a new COMPRESSED_SNAPSHOT_VERSION.
+const (
+	COMPRESSED_SNAPSHOT_MAGIC   = "RADARSNZ"
+	COMPRESSED_SNAPSHOT_VERSION = 1
+)
+
+// The columns of a compressed snapshot, in order.
+const (
+	compressedLocations = iota
+	compressedCounts
+	compressedIds
+	compressedTimes
+	compressedTypes
+	compressedWeapons
+	compressedCases
+	compressedNeighborhoods
+	compressedFlags
+	compressedOffenses
+	numCompressedColumns
+)
+
+// compressedStrings returns the field of a crime that each column of
+// strings holds.
+var compressedStrings = map[int]func(crime *Crime) *string{
+	compressedTypes:         func(crime *Crime) *string { return &crime.Type },
+	compressedWeapons:       func(crime *Crime) *string { return &crime.Weapon },
+	compressedCases:         func(crime *Crime) *string { return &crime.CaseNumber },
+	compressedNeighborhoods: func(crime *Crime) *string { return &crime.Neighborhood },
+}
+
+// The layout of the dates and times that compressed snapshots store as
+// timestamps.
+const compressedTimeLayout = DATE_LAYOUT + " 15:04:05"
+
+// Kinds of time in a compressed snapshot.
+const (
+	compressedTimestamp = iota
+	compressedTimeStrings
+)
+
+// columnWriter encodes the values of a compressed snapshot column.
+type columnWriter struct {
+	buf []byte
+}
+
+func (cw *columnWriter) uvarint(value uint64) {
+	cw.buf = binary.AppendUvarint(cw.buf, value)
+}
+
+func (cw *columnWriter) varint(value int64) {
+	cw.buf = binary.AppendVarint(cw.buf, value)
+}
+
+func (cw *columnWriter) byte(value byte) {
+	cw.buf = append(cw.buf, value)
+}
+
+func (cw *columnWriter) string(value string) {
+	cw.uvarint(uint64(len(value)))
+	cw.buf = append(cw.buf, value...)
+}
+
+// dictionaryColumn writes values as a dictionary of the distinct values
+// followed by the index of each value into it.
+func dictionaryColumn(values []string) []byte {
+	table := &snapshotStrings{indexes: make(map[string]uint32)}
+	indexes := &columnWriter{}
+	for _, value := range values {
+		indexes.uvarint(uint64(table.add(value)))
+	}
+	column := &columnWriter{}
+	column.uvarint(uint64(len(table.values)))
+	for _, value := range table.values {
+		column.string(value)
+	}
+	column.buf = append(column.buf, indexes.buf...)
+	return column.buf
+}
+
+// crimeTimestamp returns the time crime occurred, and false if its date and
+// time wouldn't be written back the same way from a timestamp.
+func crimeTimestamp(crime *Crime) (int64, bool) {
+	occurred, err := time.Parse(compressedTimeLayout, crime.Date+" "+crime.Time)
+	if err != nil || occurred.Format(compressedTimeLayout) != crime.Date+" "+crime.Time {
+		return 0, false
+	}
+	return occurred.Unix(), true
+}
+
+// compressedColumns encodes the finder's data as the columns of a
+// compressed snapshot.
+func (finder *CrimeFinder) compressedColumns() (int, int, int, [][]byte) {
+	locations := finder.Locations()
+	crimes := finder.All().Crimes()
+	columns := make([]*columnWriter, numCompressedColumns)
+	for i := range columns {
+		columns[i] = &columnWriter{}
+	}
+
+	var lastLat, lastLng uint64
+	for _, location := range locations {
+		lat, lng := math.Float64bits(location.Point.Lat), math.Float64bits(location.Point.Lng)
+		columns[compressedLocations].uvarint(lat ^ lastLat)
+		columns[compressedLocations].uvarint(lng ^ lastLng)
+		lastLat, lastLng = lat, lng
+		columns[compressedCounts].uvarint(uint64(len(location.Crimes)))
+	}
+
+	var lastId, lastOffenseId, lastTimestamp int64
+	values := make(map[int][]string, len(compressedStrings))
+	offenseTypes := &snapshotStrings{indexes: make(map[string]uint32)}
+	offenses := &columnWriter{}
+	numOffenses := 0
+	for _, crime := range crimes {
+		columns[compressedIds].varint(crime.Id - lastId)
+		lastId = crime.Id
+		if timestamp, ok := crimeTimestamp(crime); ok {
+			columns[compressedTimes].byte(compressedTimestamp)
+			columns[compressedTimes].varint(timestamp - lastTimestamp)
+			lastTimestamp = timestamp
+		} else {
+			columns[compressedTimes].byte(compressedTimeStrings)
+			columns[compressedTimes].string(crime.Date)
+			columns[compressedTimes].string(crime.Time)
+		}
+		for column, field := range compressedStrings {
+			values[column] = append(values[column], *field(crime))
+		}
+		geocoded := uint8(0)
+		if crime.Geocoded {
+			geocoded = 1
+		}
+		columns[compressedFlags].buf = append(columns[compressedFlags].buf, snapshotFlag(crime.Domestic), snapshotFlag(crime.Arrest), geocoded)
+		offenses.uvarint(uint64(len(crime.Offenses)))
+		for _, offense := range crime.Offenses {
+			offenses.varint(offense.Id - lastOffenseId)
+			lastOffenseId = offense.Id
+			offenses.uvarint(uint64(offenseTypes.add(offense.Type)))
+		}
+		numOffenses += len(crime.Offenses)
+	}
+	for column := range compressedStrings {
+		columns[column].buf = dictionaryColumn(values[column])
+	}
+	columns[compressedOffenses].uvarint(uint64(len(offenseTypes.values)))
+	for _, value := range offenseTypes.values {
+		columns[compressedOffenses].string(value)
+	}
+	columns[compressedOffenses].buf = append(columns[compressedOffenses].buf, offenses.buf...)
+
+	encoded := make([][]byte, len(columns))
+	for i, column := range columns {
+		encoded[i] = column.buf
+	}
+	return len(locations), len(crimes), numOffenses, encoded
+}
+
+// WriteCompressedSnapshot writes the finder's locations and crimes to w as
+// a compressed snapshot, which is smaller than one written by
+// WriteSnapshot but can't be used in place. Enrichments aren't written.
+func (finder *CrimeFinder) WriteCompressedSnapshot(w io.Writer) error {
+	numLocations, numCrimes, numOffenses, columns := finder.compressedColumns()
+	compressed := make([][]byte, len(columns))
+	errs := make([]error, len(columns))
+	var wg sync.WaitGroup
+	for i, column := range columns {
+		wg.Add(1)
+		go func(i int, column []byte) {
+			defer wg.Done()
+			var buf bytes.Buffer
+			fw, _ := flate.NewWriter(&buf, flate.BestCompression)
+			if _, errs[i] = fw.Write(column); errs[i] == nil {
+				errs[i] = fw.Close()
+			}
+			compressed[i] = buf.Bytes()
+		}(i, column)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	sw := &snapshotWriter{w: bufio.NewWriter(w)}
+	sw.write([]byte(COMPRESSED_SNAPSHOT_MAGIC))
+	sw.write(uint64(COMPRESSED_SNAPSHOT_VERSION))
+	sw.write([]uint64{uint64(numLocations), uint64(numCrimes), uint64(numOffenses), uint64(len(compressed))})
+	for _, column := range compressed {
+		sw.write(uint64(len(column)))
+		sw.write(column)
+	}
+	if sw.err != nil {
+		return sw.err
+	}
+	return sw.w.Flush()
+}
+
+// SaveCompressedSnapshot writes the finder's data to a compressed snapshot
+// file at filename.
+func (finder *CrimeFinder) SaveCompressedSnapshot(filename string) error {
+	return saveSnapshotFile(filename, finder.WriteCompressedSnapshot)
+}
+
+// columnReader decodes the values of a compressed snapshot column,
+// remembering the first error.
+type columnReader struct {
+	data []byte
+	err  error
+}
+
+func (cr *columnReader) uvarint() uint64 {
+	if cr.err != nil {
+		return 0
+	}
+	value, n := binary.Uvarint(cr.data)
+	if n <= 0 {
+		cr.err = errSnapshotCorrupt
+		return 0
+	}
+	cr.data = cr.data[n:]
+	return value
+}
+
+func (cr *columnReader) varint() int64 {
+	if cr.err != nil {
+		return 0
+	}
+	value, n := binary.Varint(cr.data)
+	if n <= 0 {
+		cr.err = errSnapshotCorrupt
+		return 0
+	}
+	cr.data = cr.data[n:]
+	return value
+}
+
+func (cr *columnReader) byte() byte {
+	if cr.err != nil || len(cr.data) == 0 {
+		cr.err = errSnapshotCorrupt
+		return 0
+	}
+	value := cr.data[0]
+	cr.data = cr.data[1:]
+	return value
+}
+
+func (cr *columnReader) string() string {
+	size := cr.uvarint()
+	if cr.err != nil || size > uint64(len(cr.data)) {
+		cr.err = errSnapshotCorrupt
+		return ""
+	}
+	value := string(cr.data[:size])
+	cr.data = cr.data[size:]
+	return value
+}
+
+// count reads a number of values that each take at least one byte, so a
+// corrupt count can't be larger than what's left of the column.
+func (cr *columnReader) count() int {
+	count := cr.uvarint()
+	if count > uint64(len(cr.data)) {
+		cr.err = errSnapshotCorrupt
+		return 0
+	}
+	return int(count)
+}
+
+// dictionary reads a dictionary of strings.
+func (cr *columnReader) dictionary() []string {
+	values := make([]string, cr.count())
+	for i := range values {
+		values[i] = cr.string()
+	}
+	return values
+}
+
+// lookup reads an index into values.
+func (cr *columnReader) lookup(values []string) string {
+	index := cr.uvarint()
+	if cr.err != nil || index >= uint64(len(values)) {
+		cr.err = errSnapshotCorrupt
+		return ""
+	}
+	return values[index]
+}
+
+// decodeCompressedColumn fills in the fields of crimes that column holds.
+// Columns hold different fields, so they're decoded at the same time.
+func decodeCompressedColumn(column int, cr *columnReader, crimes []Crime) {
+	switch column {
+	case compressedIds:
+		var id int64
+		for c := range crimes {
+			id += cr.varint()
+			crimes[c].Id = id
+		}
+	case compressedTimes:
+		var timestamp int64
+		for c := range crimes {
+			if cr.byte() == compressedTimestamp {
+				timestamp += cr.varint()
+				occurred := time.Unix(timestamp, 0).UTC()
+				crimes[c].Date, crimes[c].Time = occurred.Format(DATE_LAYOUT), occurred.Format("15:04:05")
+			} else {
+				crimes[c].Date, crimes[c].Time = cr.string(), cr.string()
+			}
+		}
+	case compressedTypes, compressedWeapons, compressedCases, compressedNeighborhoods:
+		values, field := cr.dictionary(), compressedStrings[column]
+		for c := range crimes {
+			*field(&crimes[c]) = cr.lookup(values)
+		}
+	case compressedFlags:
+		for c := range crimes {
+			crimes[c].Domestic = snapshotFlagValue(cr.byte())
+			crimes[c].Arrest = snapshotFlagValue(cr.byte())
+			crimes[c].Geocoded = cr.byte() != 0
+		}
+	case compressedOffenses:
+		types := cr.dictionary()
+		var id int64
+		for c := range crimes {
+			if count := cr.count(); count > 0 {
+				crimes[c].Offenses = make([]Offense, count)
+			}
+			for o := range crimes[c].Offenses {
+				id += cr.varint()
+				crimes[c].Offenses[o] = Offense{Id: id, Type: cr.lookup(types)}
+			}
+		}
+	}
+}
+
+// readCompressedSnapshot creates a CrimeFinder from compressed snapshot
+// data, building its indexes in the background if lazy is true. Unlike
+// readSnapshot, the finder doesn't refer to data once it's created.
+func readCompressedSnapshot(data []byte, lazy bool) (CrimeFinder, error) {
+	finder := CrimeFinder{}
+	finder.options.LazyIndexes = lazy
+	le := binary.LittleEndian
+	if len(data) < SNAPSHOT_HEADER_SIZE || string(data[:len(COMPRESSED_SNAPSHOT_MAGIC)]) != COMPRESSED_SNAPSHOT_MAGIC {
+		return finder, errSnapshotMagic
+	}
+	if le.Uint64(data[8:]) != COMPRESSED_SNAPSHOT_VERSION {
+		return finder, errSnapshotVersion
+	}
+	numLocations := le.Uint64(data[16:])
+	numCrimes := le.Uint64(data[24:])
+	if le.Uint64(data[40:]) != numCompressedColumns {
+		return finder, errSnapshotCorrupt
+	}
+
+	sr := &snapshotReader{data: data, offset: SNAPSHOT_HEADER_SIZE}
+	compressed := make([][]byte, numCompressedColumns)
+	for i := range compressed {
+		size := sr.column(1, 8)
+		if sr.err != nil {
+			return finder, sr.err
+		}
+		compressed[i] = sr.column(int(min(le.Uint64(size), math.MaxInt32)), 1)
+	}
+	if sr.err != nil {
+		return finder, sr.err
+	}
+
+	columns := make([]*columnReader, numCompressedColumns)
+	var wg sync.WaitGroup
+	for i := range compressed {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			decompressed, err := io.ReadAll(flate.NewReader(bytes.NewReader(compressed[i])))
+			if err != nil {
+				err = errSnapshotCorrupt
+			}
+			columns[i] = &columnReader{data: decompressed, err: err}
+		}(i)
+	}
+	wg.Wait()
+	// Every location and crime takes at least a byte of these columns.
+	if numLocations > uint64(len(columns[compressedCounts].data)) || numCrimes > uint64(len(columns[compressedIds].data)) {
+		return finder, errSnapshotCorrupt
+	}
+
+	crimes := make([]Crime, numCrimes)
+	for i := compressedIds; i < numCompressedColumns; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			decodeCompressedColumn(i, columns[i], crimes)
+		}(i)
+	}
+	wg.Wait()
+	for _, column := range columns {
+		if column.err != nil {
+			return finder, column.err
+		}
+	}
+
+	points, counts := columns[compressedLocations], columns[compressedCounts]
+	locations := make(LocationLookup, numLocations)
+	keys := make([]CoordinateKey, 0, numLocations)
+	var lat, lng uint64
+	next := uint64(0)
+	for i := uint64(0); i < numLocations; i++ {
+		lat ^= points.uvarint()
+		lng ^= points.uvarint()
+		count := counts.uvarint()
+		if points.err != nil || counts.err != nil || count > numCrimes-next {
+			return finder, errSnapshotCorrupt
+		}
+		point := Point{Lat: math.Float64frombits(lat), Lng: math.Float64frombits(lng)}
+		location := &CrimeLocation{Point: &point, Crimes: make([]*Crime, 0, count)}
+		for c := next; c < next+count; c++ {
+			crime := &crimes[c]
+			if !finder.CrimeTypes.Contains(crime.Type) {
+				finder.CrimeTypes = append(finder.CrimeTypes, crime.Type)
+			}
+			location.Crimes = append(location.Crimes, crime)
+		}
+		next += count
+		key := GetCoordinateKey(point.Lat, point.Lng)
+		locations[key] = location
+		keys = append(keys, key)
+	}
+	if next != numCrimes {
+		return finder, errSnapshotCorrupt
+	}
+	finder.LocationLookup = locations
+	finder.keys = keys
+	finder.Report.Crimes = int(numCrimes)
+	finder.Report.Locations = int(numLocations)
+	finder.buildIndexes()
+	return finder, nil
+}
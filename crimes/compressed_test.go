@@ -0,0 +1,103 @@
+package radar
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCompressedSnapshotRoundTrip(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	filename := filepath.Join(t.TempDir(), "test.snapshot")
+	if err := finder.SaveCompressedSnapshot(filename); err != nil {
+		t.Fatal("Error saving compressed snapshot: ", err)
+	}
+	loaded, err := LoadSnapshot(filename)
+	if err != nil {
+		t.Fatal("Error loading compressed snapshot: ", err)
+	}
+	if !reflect.DeepEqual(loaded.All().Crimes(), finder.All().Crimes()) {
+		t.Error("Compressed snapshot crimes differ")
+	}
+	if len(loaded.CrimeTypes) != len(finder.CrimeTypes) {
+		t.Error("Wrong crime types: ", loaded.CrimeTypes)
+	}
+	for _, crimeType := range finder.CrimeTypes {
+		if !loaded.CrimeTypes.Contains(crimeType) {
+			t.Error("Missing crime type: ", crimeType)
+		}
+	}
+	query := Point{45.53435699129174, -122.66469510763777}
+	expected, _ := finder.FindNear(query)
+	actual, _ := loaded.FindNear(query)
+	expectedJson, _ := expected.ToJson()
+	actualJson, _ := actual.ToJson()
+	if !bytes.Equal(actualJson, expectedJson) {
+		t.Error("Compressed snapshot search differs from CSV search: ", string(actualJson))
+	}
+
+	var plain bytes.Buffer
+	finder.WriteSnapshot(&plain)
+	info, _ := os.Stat(filename)
+	csv, _ := os.Stat("sample/crimes.csv")
+	if info.Size() >= int64(plain.Len())/2 || info.Size() >= csv.Size()/2 {
+		t.Error("Compressed snapshot is too large: ", info.Size(), plain.Len(), csv.Size())
+	}
+}
+
+func TestCompressedSnapshotAttributes(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "seattle.csv")
+	os.WriteFile(filename, []byte(seattleIncidentData), 0644)
+	finder, err := NewCrimeFinderWithOptions(filename, LoadOptions{GroupByCase: true})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	crimes := finder.All().Crimes()
+	yes := true
+	crimes[0].Arrest = &yes
+	crimes[0].Geocoded = true
+	// A time that can't be stored as a timestamp is kept as it is.
+	crimes[1].Time = "late"
+	var buf bytes.Buffer
+	if err := finder.WriteCompressedSnapshot(&buf); err != nil {
+		t.Fatal("Error writing compressed snapshot: ", err)
+	}
+	loaded, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatal("Error reading compressed snapshot: ", err)
+	}
+	if !reflect.DeepEqual(loaded.All().Crimes(), crimes) {
+		t.Error("Compressed snapshot crimes differ: ", loaded.All().Crimes())
+	}
+}
+
+func TestReadCompressedSnapshotErrors(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	var buf bytes.Buffer
+	finder.WriteCompressedSnapshot(&buf)
+	data := buf.Bytes()
+
+	version := append([]byte{}, data...)
+	version[8] = 99
+	if _, err := readSnapshot(version, false); err != errSnapshotVersion {
+		t.Error("Wrong error for an unsupported version: ", err)
+	}
+	for _, size := range []int{SNAPSHOT_HEADER_SIZE, len(data) / 2, len(data) - 1} {
+		if _, err := readSnapshot(data[:size], false); err != errSnapshotCorrupt {
+			t.Error("Wrong error for a compressed snapshot cut to ", size, " bytes: ", err)
+		}
+	}
+	crimes := append([]byte{}, data...)
+	crimes[24]++
+	if _, err := readSnapshot(crimes, false); err != errSnapshotCorrupt {
+		t.Error("Wrong error for the wrong number of crimes: ", err)
+	}
+}
@@ -0,0 +1,44 @@
+package radar
+
+import "github.com/abrookins/radar/internal/kdtree"
+
+// A Latitude is degrees north of the equator, and a Longitude degrees east
+// of the prime meridian. Code that parses coordinates hands them to
+// NewPoint as these types, so passing a longitude where a latitude belongs
+// doesn't compile.
+type (
+	Latitude  float64
+	Longitude float64
+)
+
+// The columns of the coordinates in a row of the City's CSV data, once
+// applyCoordinateOrder has put them in order.
+const (
+	LAT_COLUMN = 8
+	LNG_COLUMN = 9
+)
+
+// The axes of the coordinates of nodes in a finder's tree.
+const (
+	LAT_AXIS = 0
+	LNG_AXIS = 1
+)
+
+// nodePoint returns the point of a node in a finder's tree.
+func nodePoint(node *kdtree.Node) Point {
+	return Point{node.Coordinates[LAT_AXIS], node.Coordinates[LNG_AXIS]}
+}
+
+// pointFromRow returns the point at which the crime in a row of CSV data
+// occurred.
+func pointFromRow(row CsvRow) (Point, error) {
+	lat, err := floatForCol(LAT_COLUMN, row)
+	if err != nil {
+		return Point{}, err
+	}
+	lng, err := floatForCol(LNG_COLUMN, row)
+	if err != nil {
+		return Point{}, err
+	}
+	return NewPoint(Latitude(lat), Longitude(lng))
+}
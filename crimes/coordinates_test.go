@@ -0,0 +1,30 @@
+package radar
+
+import (
+	"testing"
+
+	"github.com/abrookins/radar/internal/kdtree"
+)
+
+func TestPointFromRow(t *testing.T) {
+	row := CsvRow{"1", "01/01/2011", "01:00:00", "Burglary", "", "", "", "", "45.5", "-122.6"}
+	point, err := pointFromRow(row)
+	if err != nil || point != (Point{45.5, -122.6}) {
+		t.Error("Wrong point from row: ", point, err)
+	}
+	row[LAT_COLUMN] = "122.6"
+	if _, err := pointFromRow(row); err == nil {
+		t.Error("A latitude out of range should be an error")
+	}
+	row[LAT_COLUMN] = ""
+	if _, err := pointFromRow(row); err == nil {
+		t.Error("A missing latitude should be an error")
+	}
+}
+
+func TestNodePoint(t *testing.T) {
+	node := &kdtree.Node{Coordinates: []float64{45.5, -122.6}}
+	if point := nodePoint(node); point != (Point{45.5, -122.6}) {
+		t.Error("Wrong point for node: ", point)
+	}
+}
@@ -6,12 +6,18 @@ package radar
 import (
 	"bytes"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"os"
+	"sort"
 	"strconv"
+	"time"
 
-	"github.com/unit3/kdtree"
+	"github.com/abrookins/radar/internal/kdtree"
 )
 
 // One half mile of latitude in the WGS84 coordinate system in Oregon.
@@ -20,12 +26,48 @@ const HALF_MILE_LAT = 0.00714
 // One half mile of longitude in the WGS84 coordinate system in Oregon.
 const HALF_MILE_LNG = 0.00724
 
-// A Point represents a latitude and longitude coordinate pair.
+// A Point represents a latitude and longitude coordinate pair. Points made
+// from data or requests should be created with NewPoint, which checks them
+// and takes its coordinates as a Latitude and a Longitude.
 type Point struct {
 	Lat float64
 	Lng float64
 }
 
+// NewPoint returns the Point at lat and lng, or an error if they aren't a
+// valid coordinate. A negative zero is made positive, so that the point has
+// the same coordinate key as a positive zero.
+func NewPoint(lat Latitude, lng Longitude) (Point, error) {
+	if math.IsNaN(float64(lat)) || math.IsNaN(float64(lng)) || math.Abs(float64(lat)) > 90 || math.Abs(float64(lng)) > 180 {
+		return Point{}, fmt.Errorf("coordinate out of range: %v,%v", lat, lng)
+	}
+	// Adding zero turns -0 into 0 and leaves other values alone.
+	return Point{float64(lat) + 0, float64(lng) + 0}, nil
+}
+
+// Radius of the earth (Miles)
+const EARTH_RADIUS = 3959.0
+
+// GreatCircleDistance calculates the Haversine distance in miles between two
+// points.
+// https://github.com/kellydunn/golang-geo/blob/master/point.go
+func (p *Point) GreatCircleDistance(p2 *Point) float64 {
+	dLat := (p2.Lat - p.Lat) * (math.Pi / 180.0)
+	dLon := (p2.Lng - p.Lng) * (math.Pi / 180.0)
+
+	lat1 := p.Lat * (math.Pi / 180.0)
+	lat2 := p2.Lat * (math.Pi / 180.0)
+
+	a1 := math.Sin(dLat/2) * math.Sin(dLat/2)
+	a2 := math.Sin(dLon/2) * math.Sin(dLon/2) * math.Cos(lat1) * math.Cos(lat2)
+
+	a := a1 + a2
+
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+
+	return EARTH_RADIUS * c
+}
+
 type Points []*Point
 
 type CsvRow []string
@@ -53,6 +95,26 @@ type Crime struct {
 	Date string
 	Time string
 	Type string
+	// Weapon, Domestic and Arrest are optional attributes that only some
+	// cities' data has. They are empty or nil when the data lacks them.
+	Weapon   string
+	Domestic *bool
+	Arrest   *bool
+	// CaseNumber identifies the incident the crime was reported in, if the
+	// data has it.
+	CaseNumber string
+	// Offenses lists every offense of an incident when crimes are grouped
+	// by case number.
+	Offenses []Offense
+	// Enrichments holds information that Enrichers added to the crime after
+	// it was loaded, keyed by name.
+	Enrichments map[string]interface{}
+	// Geocoded is set if the data didn't have the crime's coordinates, and
+	// they were found from its address.
+	Geocoded bool
+	// Neighborhood is the neighborhood the crime occurred in, if the data
+	// has it.
+	Neighborhood string
 }
 
 // String formats a string version of a Crime.
@@ -69,7 +131,7 @@ type CrimeLocation struct {
 }
 
 // This will help us find the CrimeLocation that a kd-tree node refers to.
-type LocationLookup map[string]*CrimeLocation
+type LocationLookup map[CoordinateKey]*CrimeLocation
 
 // getOrCreateFromCsvRow gets an existing CrimeLocation for the coordinate
 // stored in "row", or creates a CrimeLocation for that coordinate if one does
@@ -77,24 +139,94 @@ type LocationLookup map[string]*CrimeLocation
 func (locs LocationLookup) getOrCreateFromCsvRow(row CsvRow) (*CrimeLocation, error) {
 	var location *CrimeLocation
 	var pointExists bool
-	coords, err := floatCoordsFromRow(row)
+	point, err := pointFromRow(row)
 	if err != nil {
 		return nil, err
 	}
-	key := GetCoordinateKey(coords[0], coords[1])
+	key := GetCoordinateKey(point.Lat, point.Lng)
 	location, pointExists = locs[key]
 	if !pointExists {
-		point := Point{coords[0], coords[1]}
 		location = &CrimeLocation{&point, make([]*Crime, 0)}
 		locs[key] = location
 	}
 	return location, nil
 }
 
+// The number of columns in a row of the City's CSV data.
+const NUM_COLUMNS = 10
+
+// Columns for optional crime attributes that follow the City's columns in
+// rows converted by a Schema.
+const (
+	WEAPON_COLUMN = iota + NUM_COLUMNS
+	DOMESTIC_COLUMN
+	ARREST_COLUMN
+	CASE_COLUMN
+	// NUM_SCHEMA_COLUMNS is the number of columns in a converted row.
+	NUM_SCHEMA_COLUMNS
+)
+
+// GEOCODED_COLUMN is set on rows whose coordinates were geocoded. It follows
+// the columns a Schema converts to.
+const GEOCODED_COLUMN = NUM_SCHEMA_COLUMNS
+
+// A RowError records a row of CSV data that could not be loaded.
+type RowError struct {
+	// Record is the 1-based number of the row in the file, or 0 if unknown.
+	Record int
+	// Id is the value of the row's "id" column, if it had one.
+	Id  string
+	Err error
+	// row is the row, kept for a geocoder to find the coordinates of and
+	// for quarantining.
+	row CsvRow
+}
+
+// Error formats a string version of a RowError.
+func (e RowError) Error() string {
+	return fmt.Sprintf("record %v (id %q): %v", e.Record, e.Id, e.Err)
+}
+
+// A LoadReport summarizes the data a CrimeFinder loaded and the rows it
+// had to skip.
+type LoadReport struct {
+	Crimes    int
+	Locations int
+	Errors    []RowError
+	// CoordinateOrder is the order the coordinate columns were in.
+	CoordinateOrder int
+	// Dropped is the number of crimes the retention policy dropped.
+	Dropped int
+	// Excluded is the number of crimes removed or aggregated because they
+	// were inside an exclusion zone.
+	Excluded int
+	// Grouped is the number of crimes merged into another crime of the same
+	// incident.
+	Grouped int
+	// OutsideShard is the number of crimes that weren't loaded because they
+	// were outside the finder's shard.
+	OutsideShard int
+	// Geocoded is the number of crimes whose coordinates were found from
+	// their addresses.
+	Geocoded int
+	// EnrichmentErrors holds errors returned by Enrichers.
+	EnrichmentErrors []error
+}
+
 // The result of a search for crimes near a location.
 type SearchResult struct {
 	Query     *Point
 	Locations []*CrimeLocation
+	// Explanation describes how the search ran, if it was requested.
+	Explanation *Explanation
+	// Diagnostics help explain why a search found no locations.
+	Diagnostics *Diagnostics
+	// BaseURL is the URL of the server that the permalinks of crimes point
+	// to. If it's empty, permalinks are paths.
+	BaseURL string
+	// IncludeDistance adds the distance in miles of each location from
+	// Query to the JSON.
+	IncludeDistance bool
 }
 
 // Points returns all of the coordinates of a SearchResult's LocationLookup.
@@ -127,11 +259,69 @@ func (r SearchResult) ToJson() ([]byte, error) {
 	for x, location := range r.Locations {
 		total := len(location.Crimes)
 		buf.WriteString(fmt.Sprintf(`{"point":{"lat":%v,"lng":%v},`, location.Point.Lat, location.Point.Lng))
+		if r.IncludeDistance {
+			buf.WriteString(fmt.Sprintf(`"distance_miles":%v,`, roundTo(r.Query.GreatCircleDistance(location.Point), 3)))
+		}
 		buf.WriteString(`"crimes":[`)
-		line := `{"id":%v,"date":"%v","time":"%v","type":"%v"}`
 		for i, crime := range location.Crimes {
 			isLast := i == total-1
-			buf.WriteString(fmt.Sprintf(line, crime.Id, crime.Date, crime.Time, crime.Type))
+			buf.WriteString(fmt.Sprintf(`{"id":%v`, crime.Id))
+			for _, field := range [...][2]string{{"date", crime.Date}, {"time", crime.Time}, {"type", crime.Type}} {
+				value, err := json.Marshal(field[1])
+				if err != nil {
+					return nil, err
+				}
+				buf.WriteString(`,"` + field[0] + `":`)
+				buf.Write(value)
+			}
+			if crime.Weapon != "" {
+				weapon, err := json.Marshal(crime.Weapon)
+				if err != nil {
+					return nil, err
+				}
+				buf.WriteString(`,"weapon":`)
+				buf.Write(weapon)
+			}
+			if crime.Domestic != nil {
+				buf.WriteString(fmt.Sprintf(`,"domestic":%v`, *crime.Domestic))
+			}
+			if crime.Arrest != nil {
+				buf.WriteString(fmt.Sprintf(`,"arrest":%v`, *crime.Arrest))
+			}
+			if crime.CaseNumber != "" {
+				caseNumber, err := json.Marshal(crime.CaseNumber)
+				if err != nil {
+					return nil, err
+				}
+				buf.WriteString(`,"case":`)
+				buf.Write(caseNumber)
+			}
+			if len(crime.Offenses) > 0 {
+				offenses, err := json.Marshal(crime.Offenses)
+				if err != nil {
+					return nil, err
+				}
+				buf.WriteString(`,"offenses":`)
+				buf.Write(offenses)
+			}
+			if crime.Geocoded {
+				buf.WriteString(`,"geocoded":true`)
+			}
+			if len(crime.Enrichments) > 0 {
+				enrichments, err := json.Marshal(crime.Enrichments)
+				if err != nil {
+					return nil, err
+				}
+				buf.WriteString(`,"enrichments":`)
+				buf.Write(enrichments)
+			}
+			permalink, err := json.Marshal(Permalink(r.BaseURL, crime.Id))
+			if err != nil {
+				return nil, err
+			}
+			buf.WriteString(`,"url":`)
+			buf.Write(permalink)
+			buf.WriteString("}")
 			if (total > 1) && !isLast {
 				buf.WriteString(",")
 			}
@@ -140,49 +330,173 @@ func (r SearchResult) ToJson() ([]byte, error) {
 		isLast :=  x == totalLocations-1
 		if (totalLocations > 1) && !isLast {
 			buf.WriteString(",")
-		}	
+		}
 	}
-	buf.WriteString("]}")
+	buf.WriteString("]")
+	if r.Diagnostics != nil {
+		diagnostics, err := r.Diagnostics.ToJson()
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(`,"diagnostics":`)
+		buf.Write(diagnostics)
+	}
+	if r.Explanation != nil {
+		explanation, err := r.Explanation.ToJson()
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(`,"explain":`)
+		buf.Write(explanation)
+	}
+	buf.WriteString("}")
 	return buf.Bytes(), nil
 }
 
+// Orderings that a CrimeFinder can use when returning its CrimeLocations.
+const (
+	// InsertionOrder returns locations in the order they appeared in the data.
+	InsertionOrder = iota
+	// KeyOrder returns locations sorted by their coordinate key: by
+	// latitude, then longitude.
+	KeyOrder
+)
+
 // An object that can find crimes near a WGS84 coordinate.
 type CrimeFinder struct {
 	LocationLookup LocationLookup
 	CrimeTypes     CrimeTypes
 	Tree           *kdtree.Tree
+	// Order is the ordering used by Locations() and All(). Either way, the
+	// output is the same across runs for the same data.
+	Order int
+	// Report describes the last load of data into the CrimeFinder.
+	Report LoadReport
+	// keys holds the coordinate keys of LocationLookup in insertion order.
+	keys []CoordinateKey
+	// ids indexes crimes by id, and idFilter lets FindByID skip ids that
+	// aren't in the index.
+	ids      map[int64]idEntry
+	idFilter *bloomFilter
+	// options are the options the finder loaded its data with.
+	options LoadOptions
+	// sequence is the next id that assignIDs numbers a crime with.
+	sequence int64
+	// cache holds searches for popular cells, if EnableCache was called.
+	cache *nearCache
+	// fingerprint identifies the finder's data, and loadedAt is when the
+	// data was last loaded or changed.
+	fingerprint string
+	loadedAt    time.Time
+	// clusters holds the clusters of the finder's locations at each zoom
+	// level, once they're needed.
+	clusters *clusters
+	// risk holds the finder's RiskModel under DefaultScorer, once it's
+	// needed.
+	risk *riskModel
+	// secondary indexes crimes by the attributes searches filter by.
+	secondary *secondaryIndexes
+	// pending is the build of the indexes above, if they're being built
+	// in the background.
+	pending *backgroundBuild
+}
+
+// orderedKeys returns the coordinate keys of the CrimeFinder's LocationLookup
+// in the order requested by finder.Order. If the finder's LocationLookup was
+// set directly, we don't know the insertion order, so keys are sorted.
+func (finder *CrimeFinder) orderedKeys() []CoordinateKey {
+	if finder.Order == InsertionOrder && len(finder.keys) == len(finder.LocationLookup) {
+		return finder.keys
+	}
+	keys := make([]CoordinateKey, 0, len(finder.LocationLookup))
+	for key := range finder.LocationLookup {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].less(keys[j]) })
+	return keys
 }
 
 // Locations returned a slice of all the CrimeLocations in this CrimeFinder
 func (finder *CrimeFinder) Locations() []*CrimeLocation {
 	locations := make([]*CrimeLocation, 0)
-	for _, location := range finder.LocationLookup {
-		locations = append(locations, location)
+	for _, key := range finder.orderedKeys() {
+		locations = append(locations, finder.LocationLookup[key])
 	}
 	return locations
 }
 
-// FindNear returns a SearchResult containing LocationLookup within a half-mile of ``query``
+// FindNear returns a SearchResult containing LocationLookup within a half-mile of “query“
 func (finder *CrimeFinder) FindNear(query Point) (SearchResult, error) {
+	return finder.findNear(query, nil)
+}
+
+// FindNearExplained works like FindNear and also sets the result's
+// Explanation to describe how the search ran.
+func (finder *CrimeFinder) FindNearExplained(query Point) (SearchResult, error) {
+	return finder.findNear(query, newExplanation())
+}
+
+// findNear finds locations near query, filling in explanation if it isn't nil.
+func (finder *CrimeFinder) findNear(query Point, explanation *Explanation) (SearchResult, error) {
 	nearby := SearchResult{}
 	nearby.Query = &query
 	nearby.Locations = make([]*CrimeLocation, 0)
+	nearby.Explanation = explanation
 	ranges := map[int]kdtree.Range{
-		0: {query.Lat - HALF_MILE_LAT, query.Lat + HALF_MILE_LAT},
-		1: {query.Lng - HALF_MILE_LNG, query.Lng + HALF_MILE_LNG}}
-	results, err := finder.Tree.FindRange(ranges)
+		LAT_AXIS: {Min: query.Lat - HALF_MILE_LAT, Max: query.Lat + HALF_MILE_LAT},
+		LNG_AXIS: {Min: query.Lng - HALF_MILE_LNG, Max: query.Lng + HALF_MILE_LNG}}
+	tree := finder.tree()
+	if tree == nil {
+		return finder.scanNear(query, explanation), nil
+	}
+	if cached, hit := finder.cache.lookup(query); hit {
+		start := time.Now()
+		for _, candidate := range cached {
+			if candidate.lat >= ranges[LAT_AXIS].Min && candidate.lat <= ranges[LAT_AXIS].Max &&
+				candidate.lng >= ranges[LNG_AXIS].Min && candidate.lng <= ranges[LNG_AXIS].Max {
+				nearby.Locations = append(nearby.Locations, candidate.location)
+			}
+		}
+		explanation.record("cache", start)
+		if len(nearby.Locations) == 0 {
+			nearby.Diagnostics = finder.diagnose(query)
+		}
+		if explanation != nil {
+			explanation.Cache = "hit"
+			explanation.Candidates = len(cached)
+			explanation.Results = len(nearby.Locations)
+		}
+		return nearby, nil
+	}
+	start := time.Now()
+	results, visited, err := tree.FindRangeVisited(ranges)
 	if err != nil {
 		return nearby, err
 	}
+	explanation.record("search", start)
+	start = time.Now()
 	for i := 0; i < len(results); i++ {
 		node := results[i]
 		// If we have a record for this coordinate, add it to ``nearby``.
-		key := GetCoordinateKey(node.Coordinates[0], node.Coordinates[1])
+		point := nodePoint(node)
+		key := GetCoordinateKey(point.Lat, point.Lng)
 		location, exists := finder.LocationLookup[key]
 		if exists {
 			nearby.Locations = append(nearby.Locations, location)
 		}
 	}
+	explanation.record("lookup", start)
+	if len(nearby.Locations) == 0 {
+		nearby.Diagnostics = finder.diagnose(query)
+	}
+	if explanation != nil {
+		explanation.NodesVisited = visited
+		explanation.Candidates = len(results)
+		explanation.Results = len(nearby.Locations)
+		if finder.cache != nil {
+			explanation.Cache = "miss"
+		}
+	}
 	return nearby, nil
 }
 
@@ -193,57 +507,346 @@ func (finder *CrimeFinder) All() SearchResult {
 	return all
 }
 
-// loadFromCsv hydrates a CrimeFinder from CSV data.
-func (finder *CrimeFinder) loadFromCsv(rows CsvRows) error {
-	locations := make(LocationLookup)
-	numCrimes := 0
-	for _, row := range rows {
-		location, err := locations.getOrCreateFromCsvRow(row)
-		if err != nil {
-			continue
+// addRows adds the crimes in rows to the finder's locations, without
+// rebuilding its indexes. It returns the crimes it added, by location.
+//
+// Rows are parsed on several goroutines. The locations are then split into
+// shards by their keys, and each shard's crimes are added by a goroutine of
+// its own, so that no two goroutines add to one location. Locations, their
+// crimes and the crime types still end up in the order of rows.
+func (finder *CrimeFinder) addRows(rows CsvRows) SearchResult {
+	if finder.LocationLookup == nil {
+		finder.LocationLookup = make(LocationLookup)
+	}
+	locations := finder.LocationLookup
+	workers := finder.options.workers()
+	parsed := make([]parsedRow, len(rows))
+	parallelChunks(len(rows), workers, func(start int, end int) {
+		for i := start; i < end; i++ {
+			parsed[i] = parseRow(rows[i], workers)
 		}
-		// Parse the "id" column as an int64
-		id, err := strconv.ParseInt(row[0], 0, 64)
-		if err != nil {
+	})
+
+	// A touchedLocation is a location that rows add crimes to, the crimes
+	// they add, and the first row that does.
+	type touchedLocation struct {
+		key      CoordinateKey
+		location *CrimeLocation
+		added    *CrimeLocation
+		first    int
+		created  bool
+	}
+	// The rows are split into their shards in one pass, in order, so that
+	// each shard's goroutine only goes through its own rows.
+	shardRows := make([][]int, workers)
+	for i := range parsed {
+		if parsed[i].err == nil {
+			shardRows[parsed[i].shard] = append(shardRows[parsed[i].shard], i)
+		}
+	}
+	shards := make([][]*touchedLocation, workers)
+	parallelChunks(workers, workers, func(start int, end int) {
+		for shard := start; shard < end; shard++ {
+			touched := make(map[CoordinateKey]*touchedLocation)
+			for _, i := range shardRows[shard] {
+				row := &parsed[i]
+				t, ok := touched[row.key]
+				if !ok {
+					// Nothing adds to the finder's locations until every
+					// shard is done, so they can be read at once.
+					location, exists := locations[row.key]
+					if !exists {
+						point := row.point
+						location = &CrimeLocation{&point, make([]*Crime, 0)}
+					}
+					t = &touchedLocation{row.key, location, &CrimeLocation{Point: location.Point}, i, !exists}
+					touched[row.key] = t
+					shards[shard] = append(shards[shard], t)
+				}
+				t.location.Crimes = append(t.location.Crimes, row.crime)
+				t.added.Crimes = append(t.added.Crimes, row.crime)
+			}
+		}
+	})
+
+	all := make([]*touchedLocation, 0)
+	for _, touched := range shards {
+		all = append(all, touched...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].first < all[j].first })
+	added := SearchResult{Locations: make([]*CrimeLocation, 0, len(all))}
+	for _, t := range all {
+		if t.created {
+			locations[t.key] = t.location
+			finder.keys = append(finder.keys, t.key)
+		}
+		added.Locations = append(added.Locations, t.added)
+	}
+	types := make(map[string]bool, len(finder.CrimeTypes))
+	for _, crimeType := range finder.CrimeTypes {
+		types[crimeType] = true
+	}
+	numCrimes := 0
+	for i, row := range parsed {
+		if row.err != nil {
+			finder.Report.Errors = append(finder.Report.Errors, newRowError(0, rows[i], row.err))
 			continue
 		}
-		crimeType := string(row[3])
-		if !finder.CrimeTypes.Contains(crimeType) {
-			finder.CrimeTypes = append(finder.CrimeTypes, crimeType)
+		if !types[row.crime.Type] {
+			types[row.crime.Type] = true
+			finder.CrimeTypes = append(finder.CrimeTypes, row.crime.Type)
 		}
-		location.Crimes = append(location.Crimes, &Crime{id, row[1], row[2], crimeType})
 		numCrimes += 1
 	}
-	log.Printf("Loaded %v crimes and %v locations", numCrimes, len(locations))
-	finder.LocationLookup = locations
-	return nil
+	finder.Report.Crimes += numCrimes
+	finder.Report.Locations = len(locations)
+	return added
+}
+
+// prepareRows puts rows in coordinate order, geocodes the rows in rowErrors
+// that are missing coordinates if geocoder is set, gives rows ids, applies
+// the finder's retention policy, exclusion zones, jitter and shard to them
+// and checks that they can be loaded. It returns the rows and the row
+// errors that are left.
+func (finder *CrimeFinder) prepareRows(rows CsvRows, rowErrors []RowError, coordinateOrder int, geocoder Geocoder) (CsvRows, []RowError) {
+	options := finder.options
+	finder.Report.CoordinateOrder = applyCoordinateOrder(rows, coordinateOrder)
+	if geocoder != nil {
+		var geocoded CsvRows
+		geocoded, rowErrors = geocodeRows(rowErrors, geocoder)
+		rows = append(rows, geocoded...)
+		finder.Report.Geocoded += len(geocoded)
+	}
+	// Ids are made from the data as it was published, before jitter moves
+	// it.
+	finder.assignIDs(rows, options.IDs)
+	rows, dropped := applyRetention(rows, options.Retention)
+	rows, excluded := applyExclusionZones(rows, options.ExclusionZones)
+	finder.Report.Dropped += dropped
+	finder.Report.Excluded += excluded
+	if options.JitterMiles > 0 {
+		anonymizeRows(rows, options.JitterMiles)
+	}
+	// Jitter can move a crime across a shard's edge, so the shard is
+	// applied last, to the coordinates that will be served.
+	rows, outside := applyShard(rows, options.Shard)
+	finder.Report.OutsideShard += outside
+	rows, badRows := checkRows(rows, options.workers())
+	return rows, append(rowErrors, badRows...)
+}
+
+// Ingest adds the crimes in r, which is CSV data in schema, to a finder that
+// has already loaded data, and rebuilds its indexes. The finder's load
+// options apply to the new crimes, except that crimes aren't grouped by
+// case, or geocoded, since searches wait for Ingest. It returns the crimes
+// that were added, by location, and the rows that couldn't be added. Ingest
+// must not run while the finder is searched.
+func (finder *CrimeFinder) Ingest(r io.Reader, schema *Schema) (SearchResult, []RowError, error) {
+	return finder.IngestWithPolicy(r, schema, BadRowPolicy{})
+}
+
+// IngestWithPolicy is Ingest with policy deciding what happens to the rows
+// that can't be added. With a FailOnBadRows policy, no crime is added if a
+// row is bad, and the rows are returned with a *BadRowsError.
+func (finder *CrimeFinder) IngestWithPolicy(r io.Reader, schema *Schema, policy BadRowPolicy) (SearchResult, []RowError, error) {
+	rows, rowErrors, err := readCrimesWithSchema(r, schema)
+	if err != nil {
+		return SearchResult{}, nil, err
+	}
+	// The indexes are rebuilt, but a build in the background must finish
+	// with the locations before they change.
+	finder.WaitForIndexes()
+	numErrors := len(finder.Report.Errors)
+	report := finder.Report
+	rows, rowErrors = finder.prepareRows(rows, rowErrors, finder.Report.CoordinateOrder, nil)
+	if err := policy.apply(rowErrors, "", true); err != nil {
+		finder.Report = report
+		return SearchResult{}, rowErrors, err
+	}
+	added := finder.addRows(rows)
+	rowErrors = append(rowErrors, finder.Report.Errors[numErrors:]...)
+	finder.Report.Errors = append(finder.Report.Errors[:numErrors], rowErrors...)
+	finder.enrich(added.Locations, finder.options.Enrichers)
+	finder.buildIndexes()
+	return added, rowErrors, nil
 }
 
 // NewCrimeFinder creates a new CrimeFinder loaded from CSV data.
 func NewCrimeFinder(filename string) (CrimeFinder, error) {
-	var err error
-	finder := CrimeFinder{}
-	rows, err := readCrimes(filename)
-	if err != nil {
-		return finder, err
+	return NewCrimeFinderWithOptions(filename, LoadOptions{})
+}
+
+// NewCrimeFinderWithOptions creates a new CrimeFinder loaded from CSV data
+// using options. A file named *.geojson or *.json is read as GeoJSON, like a
+// GeoJSON export, and its schema and coordinate order options are ignored.
+// Its bad rows are handled by options.BadRows, which returns a
+// *BadRowsError if it fails the load.
+func NewCrimeFinderWithOptions(filename string, options LoadOptions) (CrimeFinder, error) {
+	if isGeoJSONFile(filename) {
+		options.CoordinateOrder = LatLngOrder
 	}
-	err = finder.loadFromCsv(rows)
+	return loadCrimeFinder(filename, options, func(add func(CsvRows, []RowError)) error {
+		return readCrimeBatches(filename, options.Schema, options.Progress, add)
+	})
+}
+
+// NewCrimeFinderFromReader creates a new CrimeFinder loaded from the CSV
+// data in r using options, like data embedded in a program. Progress is
+// only reported for parsing and indexing, since the size of the data isn't
+// known, and bad rows can't be quarantined next to a file.
+func NewCrimeFinderFromReader(r io.Reader, options LoadOptions) (CrimeFinder, error) {
+	return loadCrimeFinder("", options, func(add func(CsvRows, []RowError)) error {
+		return readCrimesInBatches(r, options.Schema, add)
+	})
+}
+
+// NewEmptyCrimeFinder creates a CrimeFinder with no crimes, for a server
+// that starts empty and adds its crimes with Ingest. It can be searched
+// like any other, and options apply to the crimes Ingest adds.
+func NewEmptyCrimeFinder(options LoadOptions) CrimeFinder {
+	// There are no rows, so there are no bad rows to fail the load.
+	finder, _ := newCrimeFinderFromRows(make(CsvRows, 0), nil, "", options)
+	return finder
+}
+
+// newCrimeFinderFromRows creates a new CrimeFinder from rows read from
+// source, whose rowErrors are handled by options.BadRows.
+func newCrimeFinderFromRows(rows CsvRows, rowErrors []RowError, source string, options LoadOptions) (CrimeFinder, error) {
+	return loadCrimeFinder(source, options, func(add func(CsvRows, []RowError)) error {
+		add(rows, rowErrors)
+		return nil
+	})
+}
+
+// loadCrimeFinder creates a new CrimeFinder from the data of source, which
+// read passes to add a batch at a time. Each batch is added as soon as it's
+// read, so that a load holds no more than a batch of rows besides the
+// crimes it has added. The bad rows are handled by options.BadRows once
+// they've all been read.
+func loadCrimeFinder(source string, options LoadOptions, read func(add func(CsvRows, []RowError)) error) (CrimeFinder, error) {
+	finder := CrimeFinder{options: options}
+	finder.LocationLookup = make(LocationLookup)
+	finder.keys = make([]CoordinateKey, 0)
+	finder.Report.Errors = make([]RowError, 0)
+	finder.Report.CoordinateOrder = options.CoordinateOrder
+	start := time.Now()
+	numRows := 0
+	err := read(func(rows CsvRows, rowErrors []RowError) {
+		numRows += len(rows)
+		finder.addBatch(rows, rowErrors)
+	})
 	if err != nil {
+		return CrimeFinder{}, err
+	}
+	// Data without coordinates to detect their order from is taken to be
+	// in latitude, longitude order.
+	if finder.Report.CoordinateOrder == DetectCoordinateOrder {
+		finder.Report.CoordinateOrder = LatLngOrder
+	}
+	if err := options.BadRows.apply(finder.Report.Errors, source, false); err != nil {
 		return finder, err
 	}
+	log.Printf("Loaded %v crimes and %v locations", finder.Report.Crimes, len(finder.LocationLookup))
+	if options.GroupByCase {
+		finder.Report.Grouped = finder.groupByCase()
+	}
+	finder.Enrich(options.Enrichers...)
+	reportPhase(options.Progress, ParsePhase, numRows, start)
+	start = time.Now()
+	finder.buildIndexes()
+	reportPhase(options.Progress, IndexPhase, 0, start)
+	return finder, nil
+}
+
+// addBatch prepares a batch of rows read by a load, and the rows of the
+// batch that couldn't be read, and adds its crimes to the finder. The order
+// of the coordinates is detected from the first batch that has any. Once a
+// row is bad under a FailOnBadRows policy, the load is going to fail, so no
+// more crimes are added.
+func (finder *CrimeFinder) addBatch(rows CsvRows, rowErrors []RowError) {
+	order := finder.Report.CoordinateOrder
+	rows, rowErrors = finder.prepareRows(rows, rowErrors, order, finder.options.Geocoder)
+	if order == DetectCoordinateOrder && len(rows) == 0 {
+		finder.Report.CoordinateOrder = DetectCoordinateOrder
+	}
+	finder.Report.Errors = append(finder.Report.Errors, rowErrors...)
+	if finder.options.BadRows.Action == FailOnBadRows && len(finder.Report.Errors) > 0 {
+		return
+	}
+	finder.addRows(rows)
+}
+
+// buildIndexes builds the indexes that searches use from the finder's
+// locations, in the background if its options ask for it, and empties the
+// search cache, which they make stale.
+func (finder *CrimeFinder) buildIndexes() {
+	finder.pending = nil
+	if finder.options.LazyIndexes {
+		finder.buildIndexesInBackground()
+	} else {
+		finder.buildTree()
+		finder.buildIdIndex()
+		finder.buildSecondaryIndexes()
+	}
+	finder.buildFingerprint()
+	finder.loadedAt = time.Now()
+	finder.cache.clear()
+	finder.clusters = &clusters{}
+	finder.risk = &riskModel{}
+}
+
+// buildTree builds the finder's tree from its locations. The tree is built
+// from ordered locations so that range searches return nodes in the same
+// order across runs.
+func (finder *CrimeFinder) buildTree() {
 	nodes := make([]*kdtree.Node, 0)
-	for _, location := range finder.LocationLookup {
+	for _, location := range finder.Locations() {
 		node := kdtree.Node{}
 		node.Coordinates = Coordinates{location.Point.Lat, location.Point.Lng}
 		nodes = append(nodes, &node)
 	}
 	finder.Tree = kdtree.BuildTree(nodes)
-	return finder, nil
 }
 
-// GetCoordinateKey returns a pair of float64 coordinates as strings.
-func GetCoordinateKey(x float64, y float64) string {
-	return fmt.Sprintf("%v,%v", x, y)
+// COORDINATE_KEY_SCALE is the number of steps a degree is divided into for
+// the keys of locations. A step is about half an inch, far finer than the
+// data is accurate to.
+const COORDINATE_KEY_SCALE = 1e7
+
+// A CoordinateKey identifies a location by its coordinates, rounded to
+// steps of 1/COORDINATE_KEY_SCALE degrees. Keys are integers, rather than
+// formatted floats, so that a coordinate parsed from data and the same
+// coordinate read back from a kd-tree node always have the same key, even
+// if they differ in their last bits or one of them is -0.
+type CoordinateKey struct {
+	Lat int64
+	Lng int64
+}
+
+// GetCoordinateKey returns the key of the coordinates x and y.
+func GetCoordinateKey(x float64, y float64) CoordinateKey {
+	return CoordinateKey{quantize(x), quantize(y)}
+}
+
+// quantize returns the number of steps of a coordinate key in degrees.
+func quantize(degrees float64) int64 {
+	return int64(math.Round(degrees * COORDINATE_KEY_SCALE))
+}
+
+// String returns the key's coordinates separated by a comma, like
+// "45.5231,-122.6765".
+func (key CoordinateKey) String() string {
+	return strconv.FormatFloat(float64(key.Lat)/COORDINATE_KEY_SCALE, 'f', -1, 64) + "," +
+		strconv.FormatFloat(float64(key.Lng)/COORDINATE_KEY_SCALE, 'f', -1, 64)
+}
+
+// less returns true if key sorts before other, by latitude and then
+// longitude.
+func (key CoordinateKey) less(other CoordinateKey) bool {
+	if key.Lat != other.Lat {
+		return key.Lat < other.Lat
+	}
+	return key.Lng < other.Lng
 }
 
 // isFloat checks if a string is coercible to a float.
@@ -255,36 +858,186 @@ func isFloat(s string) bool {
 	return true
 }
 
-// readCrimes reads CSV data from a file identified by filename.
-func readCrimes(filename string) (CsvRows, error) {
+// Errors recorded for rows that readCrimesInBatches skips.
+var (
+	errShortRow           = errors.New("row is too short")
+	errMissingCoordinates = errors.New("row is missing coordinates")
+	errBadCoordinates     = errors.New("row has coordinates that are not numbers")
+)
+
+// newRowError creates a RowError for row, which may be too short to have an id.
+func newRowError(record int, row CsvRow, err error) RowError {
+	id := ""
+	if len(row) > 0 {
+		id = row[0]
+	}
+	return RowError{Record: record, Id: id, Err: err, row: row}
+}
+
+// readCrimeBatches reads CSV data from a file identified by filename, or
+// GeoJSON data if its extension is .geojson or .json, and calls add with
+// each batch of rows and row errors, reporting progress through report if
+// it is set. GeoJSON is read in one batch.
+func readCrimeBatches(filename string, schema *Schema, report func(LoadProgress), add func(rows CsvRows, rowErrors []RowError)) error {
 	f, err := os.Open(filename)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer f.Close()
+	numRows := 0
+	counted := func(rows CsvRows, rowErrors []RowError) {
+		numRows += len(rows) + len(rowErrors)
+		add(rows, rowErrors)
+	}
+	read := func(r io.Reader) error {
+		if isGeoJSONFile(filename) {
+			rows, rowErrors, err := readGeoJSONCrimes(r)
+			if err != nil {
+				return err
+			}
+			counted(rows, rowErrors)
+			return nil
+		}
+		return readCrimesInBatches(r, schema, counted)
+	}
+	if report == nil {
+		return read(f)
 	}
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	reader := newProgressReader(f, info.Size(), report)
+	if err := read(reader); err != nil {
+		return err
+	}
+	reader.progress.Rows = numRows
+	reader.progress.Elapsed = time.Since(reader.start)
+	reader.progress.Done = true
+	report(reader.progress)
+	return nil
+}
 
-	reader := csv.NewReader(f)
-	reader.TrailingComma = true
-	rows, err := reader.ReadAll()
+// readCrimesWithSchema reads CSV data in schema from r, as
+// readCrimesInBatches does, and returns all of its rows at once.
+func readCrimesWithSchema(r io.Reader, schema *Schema) (CsvRows, []RowError, error) {
+	rows := make(CsvRows, 0)
+	rowErrors := make([]RowError, 0)
+	err := readCrimesInBatches(r, schema, func(batch CsvRows, batchErrors []RowError) {
+		rows = append(rows, batch...)
+		rowErrors = append(rowErrors, batchErrors...)
+	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	return rows, rowErrors, nil
+}
 
-	filteredRows := make(CsvRows, 0)
-	for _, row := range rows {
-		if row[8] == "" || row[9] == "" {
-			continue
+// LOAD_BATCH_ROWS is the number of rows that are read before they're
+// handed on to be loaded.
+const LOAD_BATCH_ROWS = 10000
+
+// readCrimesInBatches reads CSV data in schema from r, dropping rows without
+// usable coordinates, and calls add with each batch of up to
+// LOAD_BATCH_ROWS rows it keeps and the RowErrors of the rows it dropped. A
+// header row, if the data has one, is dropped silently. If schema is nil,
+// it is detected from the header, falling back to LegacySchema for data
+// without a header we recognize. Every schema but LegacySchema needs a
+// header. The data may be UTF-8, with or without a BOM, or Windows-1252.
+// Rows are read, converted and checked one at a time, so only a batch of
+// rows is in memory at once, unless add keeps them.
+func readCrimesInBatches(r io.Reader, schema *Schema, add func(rows CsvRows, rowErrors []RowError)) error {
+	reader := csv.NewReader(newDecodingReader(r))
+	reader.TrailingComma = true
+	// Check the length of each row ourselves instead of failing the whole
+	// file because of one bad row.
+	reader.FieldsPerRecord = -1
+
+	filteredRows := make(CsvRows, 0, LOAD_BATCH_ROWS)
+	rowErrors := make([]RowError, 0)
+	var indexes [NUM_SCHEMA_COLUMNS]int
+	converting := false
+	for record := 1; ; record++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if record == 1 {
+			if schema == nil {
+				schema = DetectSchema(row)
+				if schema != nil {
+					log.Printf("Detected the %v schema", schema.Name)
+				}
+			}
+			if schema != nil && schema != LegacySchema {
+				if indexes, err = schema.columnIndexes(row); err != nil {
+					return err
+				}
+				converting = true
+				// Converted rows are copies, so the reader can reuse
+				// the memory of the rows it reads.
+				reader.ReuseRecord = true
+				if schema.Positions == nil {
+					continue
+				}
+			}
+		}
+		if converting {
+			row = convertRow(indexes, row)
+			if schema.Normalize != nil {
+				if err := schema.Normalize(row); err != nil {
+					rowErrors = append(rowErrors, newRowError(record, row, err))
+					continue
+				}
+			}
+		} else if len(row) > NUM_COLUMNS {
+			// The legacy layout has no attribute columns, so ignore
+			// anything past its own columns.
+			row = row[:NUM_COLUMNS]
+		}
+		// Some exports omit trailing empty columns, so pad those rows out.
+		// A row that is still missing coordinates is skipped below.
+		if len(row) < NUM_COLUMNS {
+			row = append(row, make(CsvRow, NUM_COLUMNS-len(row))...)
 		}
-		if !isFloat(row[8]) || !isFloat(row[9]) {
+		if err := checkCoordinates(row); err != nil {
+			if err == errBadCoordinates && record == 1 {
+				continue
+			}
+			rowErrors = append(rowErrors, newRowError(record, row, err))
 			continue
 		}
 		filteredRows = append(filteredRows, row)
+		if len(filteredRows) == LOAD_BATCH_ROWS {
+			add(filteredRows, rowErrors)
+			filteredRows = make(CsvRows, 0, LOAD_BATCH_ROWS)
+			rowErrors = make([]RowError, 0)
+		}
 	}
+	add(filteredRows, rowErrors)
+	return nil
+}
 
-	return filteredRows, nil
+// checkCoordinates returns an error if row, in the legacy layout, is
+// missing its coordinates or they aren't numbers.
+func checkCoordinates(row CsvRow) error {
+	if row[LAT_COLUMN] == "" || row[LNG_COLUMN] == "" {
+		return errMissingCoordinates
+	}
+	if !isFloat(row[LAT_COLUMN]) || !isFloat(row[LNG_COLUMN]) {
+		return errBadCoordinates
+	}
+	return nil
 }
 
 // floatForCol tries to coerce a specific column of a CSV file into float64.
 func floatForCol(col int, row CsvRow) (float64, error) {
+	if col >= len(row) {
+		return 0, errShortRow
+	}
 	val := row[col]
 	id := row[0]
 	f, err := strconv.ParseFloat(val, 64)
@@ -294,21 +1047,3 @@ func floatForCol(col int, row CsvRow) (float64, error) {
 	}
 	return f, nil
 }
-
-// floatCoordsFromRow tries to return a coordinate pair for a row of CSV data.
-func floatCoordsFromRow(row CsvRow) (Coordinates, error) {
-	coords := make(Coordinates, 2)
-	latitudeColumn := 8
-	longitudeColumn := 9
-	lat, err := floatForCol(latitudeColumn, row)
-	if err != nil {
-		return nil, err
-	}
-	coords[0] = lat
-	lng, err := floatForCol(longitudeColumn, row)
-	if err != nil {
-		return nil, err
-	}
-	coords[1] = lng
-	return coords, nil
-}
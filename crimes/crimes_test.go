@@ -1,36 +1,20 @@
 package radar
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"testing/quick"
 
-	"github.com/unit3/kdtree"
+	"github.com/abrookins/radar/internal/kdtree"
 )
 
-// Radius of the earth (Miles)
-var EARTH_RADIUS = 3959.0
-
-// GreatCircleDistance calculates the Haversine distance between two points.
-// Used to verify distance-based search.
-// https://github.com/kellydunn/golang-geo/blob/master/point.go
-func (p *Point) GreatCircleDistance(p2 *Point) float64 {
-	dLat := (p2.Lat - p.Lat) * (math.Pi / 180.0)
-	dLon := (p2.Lng - p.Lng) * (math.Pi / 180.0)
-
-	lat1 := p.Lat * (math.Pi / 180.0)
-	lat2 := p2.Lat * (math.Pi / 180.0)
-
-	a1 := math.Sin(dLat/2) * math.Sin(dLat/2)
-	a2 := math.Sin(dLon/2) * math.Sin(dLon/2) * math.Cos(lat1) * math.Cos(lat2)
-
-	a := a1 + a2
-
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-
-	return EARTH_RADIUS * c
-}
-
 // CrimeType tests
 
 func TestCrimeTypeContainsDoesNotExist(t *testing.T) {
@@ -56,7 +40,7 @@ func TestCrimeHasFields(t *testing.T) {
 	expectedDate := "1/1/2013"
 	expectedTime := "04:30"
 	expectedType := "Burglary"
-	c := &Crime{expectedId, expectedDate, expectedTime, expectedType}
+	c := &Crime{Id: expectedId, Date: expectedDate, Time: expectedTime, Type: expectedType}
 
 	if expectedId != c.Id {
 		t.Error("It should have an ID")
@@ -77,7 +61,7 @@ func TestCrimesString(t *testing.T) {
 	expectedDate := "1/1/2013"
 	expectedTime := "04:30"
 	expectedType := "Burglary"
-	c := &Crime{expectedId, expectedDate, expectedTime, expectedType}
+	c := &Crime{Id: expectedId, Date: expectedDate, Time: expectedTime, Type: expectedType}
 
 	expectedString := "(1, 1/1/2013, 04:30, Burglary)"
 	actual := fmt.Sprintf("%v", c)
@@ -89,8 +73,8 @@ func TestCrimesString(t *testing.T) {
 
 func TestSearchResultToJson(t *testing.T) {
 	crimes := Crimes{
-		{int64(1), "1/1/2013", "04:30", "Burglary"},
-		{int64(2), "1/2/2013", "04:45", "Robbery"},
+		{Id: int64(1), Date: "1/1/2013", Time: "04:30", Type: "Burglary"},
+		{Id: int64(2), Date: "1/2/2013", Time: "04:45", Type: "Robbery"},
 	}
 	crimePoint := Point{45.1, -122.3}
 	location := CrimeLocation{
@@ -101,10 +85,10 @@ func TestSearchResultToJson(t *testing.T) {
 	node := kdtree.Node{}
 	node.Coordinates = Coordinates{crimePoint.Lat, crimePoint.Lng}
 	searchResult := SearchResult{
-		&queryPoint,
-		[]*CrimeLocation{&location},
+		Query:     &queryPoint,
+		Locations: []*CrimeLocation{&location},
 	}
-	expectedJson := `{"query":{"lat":45.1,"lng":-122.3},"locations":[{"point":{"lat":45.1,"lng":-122.3},"crimes":[{"id":1,"date":"1/1/2013","time":"04:30","type":"Burglary"},{"id":2,"date":"1/2/2013","time":"04:45","type":"Robbery"}]}]}`
+	expectedJson := `{"query":{"lat":45.1,"lng":-122.3},"locations":[{"point":{"lat":45.1,"lng":-122.3},"crimes":[{"id":1,"date":"1/1/2013","time":"04:30","type":"Burglary","url":"/crimes/1"},{"id":2,"date":"1/2/2013","time":"04:45","type":"Robbery","url":"/crimes/2"}]}]}`
 	actualJson, err := searchResult.ToJson()
 	jsonString := string(actualJson[:])
 	if err != nil {
@@ -115,6 +99,29 @@ func TestSearchResultToJson(t *testing.T) {
 	}
 }
 
+func TestSearchResultToJsonEscapesStrings(t *testing.T) {
+	crimePoint := Point{45.1, -122.3}
+	location := CrimeLocation{&crimePoint, Crimes{{Id: 1, Date: `1/1/2013`, Time: "04:30", Type: `Liquor "Laws" \ Other`}}}
+	searchResult := SearchResult{Query: &crimePoint, Locations: []*CrimeLocation{&location}}
+	actualJson, err := searchResult.ToJson()
+	if err != nil {
+		t.Fatal("ToJson returned an error: ", err)
+	}
+	var decoded struct {
+		Locations []struct {
+			Crimes []struct {
+				Type string `json:"type"`
+			} `json:"crimes"`
+		} `json:"locations"`
+	}
+	if err := json.Unmarshal(actualJson, &decoded); err != nil {
+		t.Fatal("ToJson returned invalid JSON: ", err, string(actualJson))
+	}
+	if decoded.Locations[0].Crimes[0].Type != `Liquor "Laws" \ Other` {
+		t.Error("The crime's type was not escaped: ", decoded.Locations[0].Crimes[0].Type)
+	}
+}
+
 // CrimeLocation tests
 
 func TestCrimeLocationHasFields(t *testing.T) {
@@ -214,7 +221,7 @@ func TestCrimeFinderFields(t *testing.T) {
 }
 
 func TestCrimeFinderNewCrimeFinder(t *testing.T) {
-	finder, err := NewCrimeFinder("../data/test.csv")
+	finder, err := NewCrimeFinder("sample/crimes.csv")
 	if err != nil {
 		t.Error("Error creating CrimeFinder: ", err)
 	}
@@ -224,7 +231,7 @@ func TestCrimeFinderNewCrimeFinder(t *testing.T) {
 }
 
 func TestCrimeFinderAll(t *testing.T) {
-	finder, err := NewCrimeFinder("../data/test.csv")
+	finder, err := NewCrimeFinder("sample/crimes.csv")
 	if err != nil {
 		t.Error("Error creating CrimeFinder: ", err)
 	}
@@ -239,7 +246,7 @@ func TestCrimeFinderAll(t *testing.T) {
 }
 
 func TestCrimeFinderLocations(t *testing.T) {
-	finder, err := NewCrimeFinder("../data/test.csv")
+	finder, err := NewCrimeFinder("sample/crimes.csv")
 	if err != nil {
 		t.Error("Error creating CrimeFinder: ", err)
 	}
@@ -253,8 +260,47 @@ func TestCrimeFinderLocations(t *testing.T) {
 	}
 }
 
+func TestCrimeFinderLocationsInsertionOrder(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Error("Error creating CrimeFinder: ", err)
+	}
+	locations := finder.Locations()
+
+	// The first row of the test data is the first location.
+	first := locations[0].Point
+	if first.Lat != 45.53435699129174 || first.Lng != -122.66469510763777 {
+		t.Error("First location was not the first location in the data: ", first)
+	}
+	again := finder.Locations()
+	for i := range locations {
+		if locations[i] != again[i] {
+			t.Error("Locations() returned locations in a different order")
+			break
+		}
+	}
+}
+
+func TestCrimeFinderLocationsKeyOrder(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Error("Error creating CrimeFinder: ", err)
+	}
+	finder.Order = KeyOrder
+	locations := finder.All().Locations
+
+	for i := 1; i < len(locations); i++ {
+		prev := GetCoordinateKey(locations[i-1].Point.Lat, locations[i-1].Point.Lng)
+		key := GetCoordinateKey(locations[i].Point.Lat, locations[i].Point.Lng)
+		if key.less(prev) {
+			t.Error("Locations were not sorted by key: ", prev, key)
+			break
+		}
+	}
+}
+
 func TestCrimeFinderFindNear(t *testing.T) {
-	finder, _ := NewCrimeFinder("../data/test.csv")
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
 	point := Point{45.53435699129174, -122.66469510763777}
 	result, _ := finder.FindNear(point)
 
@@ -292,11 +338,375 @@ func TestCrimeFinderFindNearRegression(t *testing.T) {
 	}
 }
 
+func TestNewCrimeFinderFromReader(t *testing.T) {
+	data := "13690824,05/27/2011,08:35:00,Liquor Laws\n" +
+		"13690825,05/27/2011,08:35:00,Liquor Laws,,,,,45.5,-122.6\n"
+	finder, err := NewCrimeFinderFromReader(strings.NewReader(data), LoadOptions{})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	if finder.Report.Crimes != 1 || len(finder.Report.Errors) != 1 {
+		t.Error("Wrong crimes or errors: ", finder.Report.Crimes, finder.Report.Errors)
+	}
+	if _, err := NewCrimeFinderFromReader(strings.NewReader(data), LoadOptions{BadRows: BadRowPolicy{Action: FailOnBadRows}}); err == nil {
+		t.Error("Bad rows should fail the load when the policy says to")
+	}
+}
+
+// readCrimesFrom reads CSV data in the legacy layout from r.
+func readCrimesFrom(r io.Reader) (CsvRows, []RowError, error) {
+	return readCrimesWithSchema(r, LegacySchema)
+}
+
+func TestReadCrimesFromShortRows(t *testing.T) {
+	data := "13690824,05/27/2011,08:35:00,Liquor Laws\n" +
+		"13690825,05/27/2011,08:35:00,Liquor Laws,,,,,45.5,-122.6\n"
+	rows, rowErrors, err := readCrimesFrom(strings.NewReader(data))
+	if err != nil {
+		t.Error("readCrimesFrom returned an error: ", err)
+	}
+	if len(rows) != 1 {
+		t.Error("readCrimesFrom should have skipped the short row: ", len(rows))
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Record != 1 || rowErrors[0].Id != "13690824" {
+		t.Error("readCrimesFrom should have recorded an error for the short row: ", rowErrors)
+	}
+}
+
+func TestReadCrimesFromOmittedTrailingColumns(t *testing.T) {
+	// The last row has no trailing columns after its coordinates, and the
+	// row before it omits the empty coordinate columns entirely.
+	data := "13690824,05/27/2011,08:35:00,Liquor Laws,,,,,45.5,-122.6,extra\n" +
+		"13690825,05/27/2011,08:35:00,Liquor Laws,,,,\n" +
+		"13690826,05/27/2011,08:35:00,Liquor Laws,,,,,45.6,-122.7\n"
+	rows, rowErrors, err := readCrimesFrom(strings.NewReader(data))
+	if err != nil {
+		t.Error("readCrimesFrom returned an error: ", err)
+	}
+	if len(rows) != 2 {
+		t.Error("readCrimesFrom returned the wrong number of rows: ", len(rows))
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Err != errMissingCoordinates {
+		t.Error("readCrimesFrom should have recorded missing coordinates: ", rowErrors)
+	}
+}
+
+func TestReadCrimesFromSkipsHeader(t *testing.T) {
+	data := "Record ID,Report Date,Report Time,Major Offense Type,Address,Neighborhood,Police Precinct,Police District,X Coordinate,Y Coordinate\n" +
+		"13690825,05/27/2011,08:35:00,Liquor Laws,,,,,45.5,-122.6\n"
+	rows, rowErrors, _ := readCrimesFrom(strings.NewReader(data))
+	if len(rows) != 1 || len(rowErrors) != 0 {
+		t.Error("readCrimesFrom should have skipped the header without an error: ", rowErrors)
+	}
+}
+
+func TestCrimeFinderReport(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Error("Error creating CrimeFinder: ", err)
+	}
+	if finder.Report.Locations != 224 {
+		t.Error("Report has the wrong number of locations: ", finder.Report.Locations)
+	}
+	if finder.Report.Crimes == 0 {
+		t.Error("Report should count the crimes loaded")
+	}
+}
+
+// FuzzReadCrimes feeds malformed CSV data through the loader, which should
+// return an error or skip bad rows rather than panic.
+func FuzzReadCrimes(f *testing.F) {
+	f.Add("Record ID,Report Date,Report Time,Major Offense Type,Address,Neighborhood,Police Precinct,Police District,X Coordinate,Y Coordinate\n" +
+		"13807517,12/01/2011,01:00:00,Liquor Laws,\"NE WEIDLER ST, PORTLAND\",LLOYD,PORTLAND PREC NO,690,45.53435699129174,-122.66469510763777\n")
+	f.Add("\ufeff13807517,12/01/2011,01:00:00,Liquor Laws,,,,,45.5,-122.6\n")
+	f.Add("13807517,12/01/2011\n")
+	f.Add("1,\"a\"\"b\",c,d,e,f,g,h,45,-122\n")
+	f.Fuzz(func(t *testing.T, data string) {
+		rows, _, err := readCrimesFrom(strings.NewReader(data))
+		if err != nil {
+			return
+		}
+		finder := CrimeFinder{}
+		finder.addRows(rows)
+		for _, location := range finder.Locations() {
+			if location == nil {
+				t.Error("addRows created a nil location")
+			}
+		}
+	})
+}
+
+// Searches share the CrimeFinder's data, so they must be safe to run from
+// many goroutines at once. Run with -race.
+func TestCrimeFinderConcurrentSearches(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Error("Error creating CrimeFinder: ", err)
+	}
+	point := Point{45.53435699129174, -122.66469510763777}
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				result, err := finder.FindNear(point)
+				if err != nil || len(result.Locations) != 14 {
+					t.Error("FindNear returned the wrong result from a goroutine")
+					return
+				}
+				if _, err := result.ToJson(); err != nil {
+					t.Error("ToJson returned an error from a goroutine: ", err)
+					return
+				}
+				finder.All()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// findNearLinear finds locations within the FindNear box by checking every
+// location, as a reference for the kd-tree search.
+func findNearLinear(finder *CrimeFinder, query Point) map[*CrimeLocation]bool {
+	found := make(map[*CrimeLocation]bool)
+	for _, location := range finder.Locations() {
+		p := location.Point
+		if p.Lat >= query.Lat-HALF_MILE_LAT && p.Lat <= query.Lat+HALF_MILE_LAT &&
+			p.Lng >= query.Lng-HALF_MILE_LNG && p.Lng <= query.Lng+HALF_MILE_LNG {
+			found[location] = true
+		}
+	}
+	return found
+}
+
+// Property test: for random points around the test data, the kd-tree search
+// returns exactly the locations that a linear scan does.
+func TestCrimeFinderFindNearMatchesLinearScan(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Error("Error creating CrimeFinder: ", err)
+	}
+	// Points within a few miles of the test data.
+	randomPoint := func(values []reflect.Value, r *rand.Rand) {
+		values[0] = reflect.ValueOf(45.53 + (r.Float64()-0.5)*0.1)
+		values[1] = reflect.ValueOf(-122.66 + (r.Float64()-0.5)*0.1)
+	}
+	property := func(lat float64, lng float64) bool {
+		query := Point{lat, lng}
+		result, err := finder.FindNear(query)
+		if err != nil {
+			return false
+		}
+		found := make(map[*CrimeLocation]bool)
+		for _, location := range result.Locations {
+			found[location] = true
+		}
+		return len(found) == len(result.Locations) && reflect.DeepEqual(found, findNearLinear(&finder, query))
+	}
+	config := &quick.Config{MaxCount: 500, Values: randomPoint}
+	if err := quick.Check(property, config); err != nil {
+		t.Error("FindNear disagreed with a linear scan: ", err)
+	}
+}
+
 func TestGetCoordinateKey(t *testing.T) {
 	x := 45.1
 	y := -122.1
 	key := GetCoordinateKey(x, y)
-	if key != "45.1,-122.1" {
+	if key != (CoordinateKey{451000000, -1221000000}) || key.String() != "45.1,-122.1" {
 		t.Error("Coordinate key is wrong: ", key)
 	}
 }
+
+func TestGetCoordinateKeyDrift(t *testing.T) {
+	// A coordinate that's off in its last bits, as one recomputed from a
+	// kd-tree node can be, or is -0, has the same key.
+	lat, lng := 45.51864993557909, -122.6597206882416
+	key := GetCoordinateKey(lat, lng)
+	if drifted := GetCoordinateKey(math.Nextafter(lat, 90), math.Nextafter(lng, 0)); drifted != key {
+		t.Error("Coordinates that differ in their last bits should have the same key: ", key, drifted)
+	}
+	if GetCoordinateKey(math.Copysign(0, -1), 0) != GetCoordinateKey(0, 0) {
+		t.Error("-0 should have the same key as 0")
+	}
+	if key.String() != "45.5186499,-122.6597207" {
+		t.Error("Wrong string for a key: ", key.String())
+	}
+}
+
+func TestCrimeFinderIngest(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	crimes := finder.Report.Crimes
+	data := "99000001,12/31/2011,23:00:00,Burglary,,,,,45.53435699129174,-122.66469510763777\n" +
+		"99000002,12/31/2011,23:30:00,Arson,,,,,45.6,-122.7\n" +
+		"99000003,12/31/2011,23:45:00,Arson,,,,,,\n"
+	added, rowErrors, err := finder.Ingest(strings.NewReader(data), nil)
+	if err != nil {
+		t.Fatal("Ingest returned an error: ", err)
+	}
+	if len(added.Locations) != 2 || len(added.Crimes()) != 2 || len(rowErrors) != 1 {
+		t.Error("Ingest added the wrong crimes: ", added.Crimes(), rowErrors)
+	}
+	if finder.Report.Crimes != crimes+2 {
+		t.Error("Wrong number of crimes after ingest: ", finder.Report.Crimes)
+	}
+	if crime, _ := finder.FindByID(99000002); crime == nil {
+		t.Error("FindByID did not find an ingested crime")
+	}
+	result, _ := finder.FindNear(Point{45.53435699129174, -122.66469510763777})
+	found := false
+	for _, crime := range result.Crimes() {
+		found = found || crime.Id == 99000001
+	}
+	if !found {
+		t.Error("FindNear did not find an ingested crime")
+	}
+}
+
+func TestCrimeFinderIngestAppliesOptions(t *testing.T) {
+	finder, err := NewCrimeFinderWithOptions("sample/crimes.csv", LoadOptions{
+		Retention: RetentionPolicy{ExcludedTypes: CrimeTypes{"Arson"}},
+	})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	dropped := finder.Report.Dropped
+	data := "99000002,12/31/2011,23:30:00,Arson,,,,,45.6,-122.7\n"
+	added, _, err := finder.Ingest(strings.NewReader(data), nil)
+	if err != nil {
+		t.Fatal("Ingest returned an error: ", err)
+	}
+	if len(added.Locations) != 0 || finder.Report.Dropped != dropped+1 {
+		t.Error("Ingest should apply the retention policy: ", added.Crimes())
+	}
+}
+
+func TestNewEmptyCrimeFinder(t *testing.T) {
+	finder := NewEmptyCrimeFinder(LoadOptions{})
+	query := Point{45.53435699129174, -122.66469510763777}
+	result, err := finder.FindNear(query)
+	if err != nil || len(result.Locations) != 0 {
+		t.Error("An empty finder should find nothing: ", result.Locations, err)
+	}
+	if data, err := result.ToJson(); err != nil || !strings.Contains(string(data), `"locations":[]`) {
+		t.Error("Wrong JSON for an empty result: ", string(data), err)
+	}
+	if result, err := finder.FindNearFiltered(query, SearchFilter{Type: "Arson"}); err != nil || len(result.Locations) != 0 {
+		t.Error("An empty finder should find nothing: ", result.Locations, err)
+	}
+	if _, ok := finder.Bounds(); ok {
+		t.Error("An empty finder should have no bounds")
+	}
+
+	data := "99000001,12/31/2011,23:00:00,Burglary,,,,,45.53435699129174,-122.66469510763777\n"
+	if _, _, err := finder.Ingest(strings.NewReader(data), nil); err != nil {
+		t.Fatal("Ingest returned an error: ", err)
+	}
+	if result, _ := finder.FindNear(query); len(result.Crimes()) != 1 {
+		t.Error("FindNear did not find the ingested crime: ", result.Crimes())
+	}
+}
+
+func TestNewCrimeFinderFromHeaderOnly(t *testing.T) {
+	for _, data := range []string{"", "Record ID,Report Date,Report Time,Major Offense Type,Address,Neighborhood,Police Precinct,Police District,X Coordinate,Y Coordinate\n"} {
+		finder, err := NewCrimeFinderFromReader(strings.NewReader(data), LoadOptions{})
+		if err != nil || finder.Report.Crimes != 0 {
+			t.Error("Data without rows should load with no crimes: ", err, finder.Report.Crimes)
+		}
+		if result, err := finder.FindNear(Point{45.5, -122.6}); err != nil || len(result.Locations) != 0 {
+			t.Error("A finder without crimes should find nothing: ", result.Locations, err)
+		}
+	}
+}
+
+func TestNewPoint(t *testing.T) {
+	point, err := NewPoint(45.5184, -122.6554)
+	if err != nil || point != (Point{45.5184, -122.6554}) {
+		t.Error("Wrong point: ", point, err)
+	}
+	point, err = NewPoint(Latitude(math.Copysign(0, -1)), Longitude(math.Copysign(0, -1)))
+	if err != nil || GetCoordinateKey(point.Lat, point.Lng).String() != "0,0" {
+		t.Error("Negative zero should be made positive: ", GetCoordinateKey(point.Lat, point.Lng), err)
+	}
+	for _, bad := range [][2]float64{{91, 0}, {0, -181}, {math.NaN(), 0}, {0, math.Inf(1)}} {
+		if _, err := NewPoint(Latitude(bad[0]), Longitude(bad[1])); err == nil {
+			t.Error("NewPoint should have returned an error for: ", bad)
+		}
+	}
+}
+
+func TestReadCrimesWithSchemaStreams(t *testing.T) {
+	header := strings.SplitN(pdx2015Data, "\n", 2)[0]
+	reader, writer := io.Pipe()
+	go func() {
+		fmt.Fprintln(writer, header)
+		for i := 0; i < 20000; i++ {
+			fmt.Fprintf(writer, "ADDRESS,15-X%v,Property,Lloyd,5/27/2015,835,Larceny Offenses,Larceny,45.5,-122.6,,,5/28/2015,1\n", i)
+			if i%1000 == 0 {
+				fmt.Fprintln(writer, "UNKNOWN,15-X,Property,,1/2/2015,0,Fraud Offenses,Identity Theft,,,,,1/2/2015,1")
+			}
+		}
+		writer.Close()
+	}()
+	rows, rowErrors, err := readCrimesWithSchema(reader, nil)
+	if err != nil {
+		t.Fatal("readCrimesWithSchema returned an error: ", err)
+	}
+	if len(rows) != 20000 || len(rowErrors) != 20 {
+		t.Fatal("Wrong rows: ", len(rows), len(rowErrors))
+	}
+	ids := make(map[string]bool)
+	for _, row := range rows {
+		ids[row[0]] = true
+	}
+	if len(ids) != len(rows) {
+		t.Error("Rows read later shouldn't change the rows kept: ", len(ids))
+	}
+	for i := 1; i < len(rowErrors); i++ {
+		if rowErrors[i].Record <= rowErrors[i-1].Record {
+			t.Error("Row errors should be in record order: ", rowErrors[i-1].Record, rowErrors[i].Record)
+		}
+	}
+
+	if _, _, err := readCrimesWithSchema(strings.NewReader(header+"\n\"unterminated,1\n"), nil); err == nil {
+		t.Error("Malformed CSV should be an error")
+	}
+}
+
+// A load adds rows in batches as they're read, and the batches load the same
+// crimes as one batch would.
+func TestNewCrimeFinderFromReaderLoadsInBatches(t *testing.T) {
+	numRows := 2*LOAD_BATCH_ROWS + 500
+	data := new(strings.Builder)
+	for i := 0; i < numRows; i++ {
+		// The coordinates are in longitude, latitude order, which the first
+		// batch has to detect for the rest.
+		fmt.Fprintf(data, "%v,05/27/2011,08:35:00,Larceny,,,,,-122.%v,45.%v\n", i, 6000+i%97, 5000+i%89)
+	}
+	batches := make([]int, 0)
+	err := readCrimesInBatches(strings.NewReader(data.String()), LegacySchema, func(rows CsvRows, rowErrors []RowError) {
+		batches = append(batches, len(rows))
+	})
+	if err != nil || !reflect.DeepEqual(batches, []int{LOAD_BATCH_ROWS, LOAD_BATCH_ROWS, 500}) {
+		t.Fatal("Wrong batches: ", batches, err)
+	}
+
+	finder, err := NewCrimeFinderFromReader(strings.NewReader(data.String()), LoadOptions{IDs: SequentialIDs})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	if finder.Report.Crimes != numRows || finder.Report.CoordinateOrder != LngLatOrder || len(finder.Report.Errors) != 0 {
+		t.Fatal("Wrong report: ", finder.Report.Crimes, finder.Report.CoordinateOrder, len(finder.Report.Errors))
+	}
+	ids := make(map[int64]bool)
+	for _, crime := range finder.All().Crimes() {
+		ids[crime.Id] = true
+	}
+	if len(ids) != numRows || !ids[1] || !ids[int64(numRows)] {
+		t.Error("Sequential ids should carry on from one batch to the next: ", len(ids))
+	}
+}
@@ -0,0 +1,301 @@
+package radar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DAY_LAYOUT is the layout of the days that date ranges start and end on.
+const DAY_LAYOUT = "2006-01-02"
+
+// A cell is a hotspot in a period when it has at least HOTSPOT_MIN_CRIMES
+// crimes, and at least HOTSPOT_FACTOR times the average count of the area's
+// cells that had crimes then.
+const (
+	HOTSPOT_MIN_CRIMES = 5
+	HOTSPOT_FACTOR     = 2.0
+)
+
+var errDateRange = errors.New("period must be a month, like 2011-07, or a range of days, like 2011-07-01/2011-07-07")
+
+// A DateRange is the days from From up to, but not including, To.
+type DateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// ParseDateRange parses a month in MONTH_LAYOUT, like "2011-07", or a range
+// of days in DAY_LAYOUT, like "2011-07-01/2011-07-07", which includes both
+// days.
+func ParseDateRange(s string) (DateRange, error) {
+	if month, err := time.Parse(MONTH_LAYOUT, s); err == nil {
+		return DateRange{month, month.AddDate(0, 1, 0)}, nil
+	}
+	from, to, ok := strings.Cut(s, "/")
+	if !ok {
+		return DateRange{}, errDateRange
+	}
+	first, err := time.Parse(DAY_LAYOUT, from)
+	if err != nil {
+		return DateRange{}, errDateRange
+	}
+	last, err := time.Parse(DAY_LAYOUT, to)
+	if err != nil || last.Before(first) {
+		return DateRange{}, errDateRange
+	}
+	return DateRange{first, last.AddDate(0, 0, 1)}, nil
+}
+
+// Contains returns true if date is in the range.
+func (r DateRange) Contains(date time.Time) bool {
+	return !date.Before(r.From) && date.Before(r.To)
+}
+
+// String returns the range in the form ParseDateRange parses, with its
+// first and last days.
+func (r DateRange) String() string {
+	return r.From.Format(DAY_LAYOUT) + "/" + r.To.AddDate(0, 0, -1).Format(DAY_LAYOUT)
+}
+
+// An Area is a part of the data: the crimes inside Bounds, or in
+// Neighborhood, or every crime if neither is set.
+type Area struct {
+	Bounds       *Bounds
+	Neighborhood string
+}
+
+// ParseArea parses a bounding box in the form ParseBounds parses, or else
+// the name of a neighborhood. An empty area is all of the data.
+func ParseArea(s string) Area {
+	if bounds, err := ParseBounds(s); err == nil {
+		return Area{Bounds: &bounds}
+	}
+	return Area{Neighborhood: strings.TrimSpace(s)}
+}
+
+// Contains returns true if crime, which occurred at location, is in the
+// area.
+func (area Area) Contains(location *CrimeLocation, crime *Crime) bool {
+	if area.Bounds != nil && !area.Bounds.Contains(*location.Point) {
+		return false
+	}
+	return area.Neighborhood == "" || indexKey(area.Neighborhood) == indexKey(crime.Neighborhood)
+}
+
+// String returns the area in the form ParseArea parses.
+func (area Area) String() string {
+	if area.Bounds != nil {
+		b := area.Bounds
+		return fmt.Sprintf("%v,%v,%v,%v", b.Min.Lat, b.Min.Lng, b.Max.Lat, b.Max.Lng)
+	}
+	return area.Neighborhood
+}
+
+// A TypeChange is the number of crimes of a type in each of two periods.
+type TypeChange struct {
+	Type string
+	A    int
+	B    int
+}
+
+// A Hotspot is a cell of the grid that was a hotspot in one period, and
+// its counts in each.
+type Hotspot struct {
+	Cell GridCell
+	A    int
+	B    int
+}
+
+// A Delta describes what changed in an area between two periods.
+type Delta struct {
+	Area   Area
+	A      DateRange
+	B      DateRange
+	TotalA int
+	TotalB int
+	// Types are the changes of each type of crime, biggest first.
+	Types []TypeChange
+	// NewHotspots were hotspots in B but not in A, and
+	// DisappearedHotspots in A but not in B, busiest first.
+	NewHotspots         []Hotspot
+	DisappearedHotspots []Hotspot
+	// Nearby are the points of interest near the center of each hotspot's
+	// cell, if the delta was given any.
+	Nearby map[GridCell][]NearbyPOI
+}
+
+// WithPOIs returns the delta with the points of interest in pois near each
+// of its hotspots, which explain what a hotspot grew up around.
+func (delta Delta) WithPOIs(pois POIs) Delta {
+	delta.Nearby = make(map[GridCell][]NearbyPOI)
+	for _, spot := range append(append([]Hotspot{}, delta.NewHotspots...), delta.DisappearedHotspots...) {
+		delta.Nearby[spot.Cell] = pois.Near(spot.Cell.Center())
+	}
+	return delta
+}
+
+// hotspots returns the cells of weights that are hotspots, where a cell's
+// weight is the number of its crimes, or their total weight if they're
+// weighed.
+func hotspots(weights map[GridCell]float64) map[GridCell]bool {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	spots := make(map[GridCell]bool)
+	if len(weights) == 0 {
+		return spots
+	}
+	average := total / float64(len(weights))
+	for cell, weight := range weights {
+		if weight >= HOTSPOT_MIN_CRIMES && weight >= HOTSPOT_FACTOR*average {
+			spots[cell] = true
+		}
+	}
+	return spots
+}
+
+// cellWeights returns counts of crimes in cells as weights for hotspots.
+func cellWeights(counts map[GridCell]int) map[GridCell]float64 {
+	weights := make(map[GridCell]float64, len(counts))
+	for cell, count := range counts {
+		weights[cell] = float64(count)
+	}
+	return weights
+}
+
+// Delta compares the crimes in area in period a with those in period b:
+// the change in each type's count, and the hotspots that appeared or
+// disappeared. Crimes whose dates can't be parsed are left out.
+func (finder *CrimeFinder) Delta(area Area, a DateRange, b DateRange) Delta {
+	delta := Delta{Area: area, A: a, B: b, Types: make([]TypeChange, 0), NewHotspots: make([]Hotspot, 0), DisappearedHotspots: make([]Hotspot, 0)}
+	types := make(map[string]*TypeChange)
+	cellsA, cellsB := make(map[GridCell]int), make(map[GridCell]int)
+	for _, location := range finder.LocationLookup {
+		cell := GridCellOf(*location.Point)
+		for _, crime := range location.Crimes {
+			if !area.Contains(location, crime) {
+				continue
+			}
+			occurred, err := time.Parse(DATE_LAYOUT, crime.Date)
+			if err != nil {
+				continue
+			}
+			inA, inB := a.Contains(occurred), b.Contains(occurred)
+			if !inA && !inB {
+				continue
+			}
+			change, ok := types[crime.Type]
+			if !ok {
+				change = &TypeChange{Type: crime.Type}
+				types[crime.Type] = change
+			}
+			if inA {
+				change.A++
+				delta.TotalA++
+				cellsA[cell]++
+			}
+			if inB {
+				change.B++
+				delta.TotalB++
+				cellsB[cell]++
+			}
+		}
+	}
+	for _, change := range types {
+		delta.Types = append(delta.Types, *change)
+	}
+	sort.Slice(delta.Types, func(i, j int) bool {
+		x, y := abs(delta.Types[i].B-delta.Types[i].A), abs(delta.Types[j].B-delta.Types[j].A)
+		if x != y {
+			return x > y
+		}
+		return delta.Types[i].Type < delta.Types[j].Type
+	})
+
+	spotsA, spotsB := hotspots(cellWeights(cellsA)), hotspots(cellWeights(cellsB))
+	for cell := range spotsB {
+		if !spotsA[cell] {
+			delta.NewHotspots = append(delta.NewHotspots, Hotspot{cell, cellsA[cell], cellsB[cell]})
+		}
+	}
+	for cell := range spotsA {
+		if !spotsB[cell] {
+			delta.DisappearedHotspots = append(delta.DisappearedHotspots, Hotspot{cell, cellsA[cell], cellsB[cell]})
+		}
+	}
+	sortHotspots(delta.NewHotspots, func(h Hotspot) int { return h.B })
+	sortHotspots(delta.DisappearedHotspots, func(h Hotspot) int { return h.A })
+	return delta
+}
+
+// sortHotspots sorts spots by the count that made them hotspots, most
+// first.
+func sortHotspots(spots []Hotspot, count func(Hotspot) int) {
+	sort.Slice(spots, func(i, j int) bool {
+		if count(spots[i]) != count(spots[j]) {
+			return count(spots[i]) > count(spots[j])
+		}
+		if spots[i].Cell.Row != spots[j].Cell.Row {
+			return spots[i].Cell.Row < spots[j].Cell.Row
+		}
+		return spots[i].Cell.Col < spots[j].Cell.Col
+	})
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// ToJson returns the delta marshalled to JSON bytes. A type's percent
+// change is null when it had no crimes in the first period.
+func (delta Delta) ToJson() ([]byte, error) {
+	type typeJson struct {
+		Type          string   `json:"type"`
+		A             int      `json:"a"`
+		B             int      `json:"b"`
+		Change        int      `json:"change"`
+		PercentChange *float64 `json:"percent_change"`
+	}
+	type hotspotJson struct {
+		Cell   string          `json:"cell"`
+		Center pointJson       `json:"center"`
+		A      int             `json:"a"`
+		B      int             `json:"b"`
+		Nearby []nearbyPOIJson `json:"nearby,omitempty"`
+	}
+	types := make([]typeJson, 0, len(delta.Types))
+	for _, change := range delta.Types {
+		entry := typeJson{change.Type, change.A, change.B, change.B - change.A, nil}
+		if change.A > 0 {
+			percent := roundTo(100*float64(change.B-change.A)/float64(change.A), 1)
+			entry.PercentChange = &percent
+		}
+		types = append(types, entry)
+	}
+	spots := func(hotspots []Hotspot) []hotspotJson {
+		result := make([]hotspotJson, 0, len(hotspots))
+		for _, spot := range hotspots {
+			center := spot.Cell.Center()
+			result = append(result, hotspotJson{spot.Cell.String(), pointJson{center.Lat, center.Lng}, spot.A, spot.B, nearbyToJson(delta.Nearby[spot.Cell])})
+		}
+		return result
+	}
+	return json.Marshal(struct {
+		Area                string        `json:"area"`
+		PeriodA             string        `json:"period_a"`
+		PeriodB             string        `json:"period_b"`
+		TotalA              int           `json:"total_a"`
+		TotalB              int           `json:"total_b"`
+		Types               []typeJson    `json:"types"`
+		NewHotspots         []hotspotJson `json:"new_hotspots"`
+		DisappearedHotspots []hotspotJson `json:"disappeared_hotspots"`
+	}{delta.Area.String(), delta.A.String(), delta.B.String(), delta.TotalA, delta.TotalB, types, spots(delta.NewHotspots), spots(delta.DisappearedHotspots)})
+}
@@ -0,0 +1,92 @@
+package radar
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseDateRange(t *testing.T) {
+	month, err := ParseDateRange("2011-07")
+	if err != nil || month.String() != "2011-07-01/2011-07-31" {
+		t.Error("Wrong month: ", month, err)
+	}
+	days, err := ParseDateRange("2011-07-01/2011-07-07")
+	if err != nil || days.String() != "2011-07-01/2011-07-07" || !days.Contains(days.To.AddDate(0, 0, -1)) || days.Contains(days.To) {
+		t.Error("Wrong range of days: ", days, err)
+	}
+	for _, bad := range []string{"", "July", "2011-07-07/2011-07-01", "2011-07-01/", "2011-07-01"} {
+		if _, err := ParseDateRange(bad); err == nil {
+			t.Error("Bad range was parsed: ", bad)
+		}
+	}
+}
+
+func TestParseArea(t *testing.T) {
+	if area := ParseArea("45.5,-122.7,45.54,-122.65"); area.Bounds == nil || area.String() != "45.5,-122.7,45.54,-122.65" {
+		t.Error("Wrong bounding box: ", area)
+	}
+	if area := ParseArea(" Downtown "); area.Bounds != nil || area.Neighborhood != "Downtown" {
+		t.Error("Wrong neighborhood: ", area)
+	}
+}
+
+func TestDelta(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	june, _ := ParseDateRange("2011-06")
+	december, _ := ParseDateRange("2011-12")
+	delta := finder.Delta(Area{}, june, december)
+	if delta.TotalA != 196 || delta.TotalB != 207 {
+		t.Error("Wrong totals: ", delta.TotalA, delta.TotalB)
+	}
+	sumA, sumB := 0, 0
+	for i, change := range delta.Types {
+		sumA += change.A
+		sumB += change.B
+		if i > 0 && abs(change.B-change.A) > abs(delta.Types[i-1].B-delta.Types[i-1].A) {
+			t.Error("Types should be biggest change first: ", delta.Types)
+		}
+	}
+	if sumA != delta.TotalA || sumB != delta.TotalB || delta.Types[0] != (TypeChange{"Larceny", 35, 50}) {
+		t.Error("Wrong type changes: ", delta.Types)
+	}
+	downtown := finder.Delta(ParseArea("downtown"), june, december)
+	if downtown.TotalA != 96 || downtown.TotalB != 98 {
+		t.Error("Wrong totals for a neighborhood: ", downtown.TotalA, downtown.TotalB)
+	}
+
+	september, _ := ParseDateRange("2011-09")
+	october, _ := ParseDateRange("2011-10")
+	delta = finder.Delta(Area{}, september, october)
+	spot := Hotspot{GridCell{6374, -16945}, 16, 52}
+	if len(delta.NewHotspots) != 1 || delta.NewHotspots[0] != spot || len(delta.DisappearedHotspots) != 0 {
+		t.Error("Wrong new hotspots: ", delta.NewHotspots, delta.DisappearedHotspots)
+	}
+	reversed := finder.Delta(Area{}, october, september)
+	if len(reversed.DisappearedHotspots) != 1 || reversed.DisappearedHotspots[0] != (Hotspot{spot.Cell, 52, 16}) {
+		t.Error("Wrong disappeared hotspots: ", reversed.DisappearedHotspots)
+	}
+
+	data, err := delta.ToJson()
+	if err != nil {
+		t.Fatal("Error marshalling delta: ", err)
+	}
+	var decoded struct {
+		PeriodA string `json:"period_a"`
+		Types   []struct {
+			PercentChange *float64 `json:"percent_change"`
+		}
+		NewHotspots []struct{ Cell string } `json:"new_hotspots"`
+	}
+	json.Unmarshal(data, &decoded)
+	if decoded.PeriodA != "2011-09-01/2011-09-30" || decoded.NewHotspots[0].Cell != "6374:-16945" {
+		t.Error("Wrong JSON: ", string(data))
+	}
+	for i, change := range delta.Types {
+		if (change.A == 0) != (decoded.Types[i].PercentChange == nil) {
+			t.Error("Percent change should be null only without crimes in the first period: ", change)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package radar
+
+import (
+	"encoding/json"
+)
+
+// A Bounds is the smallest box that covers a set of points.
+type Bounds struct {
+	Min Point
+	Max Point
+}
+
+// Contains reports whether point is inside the box.
+func (b Bounds) Contains(point Point) bool {
+	return point.Lat >= b.Min.Lat && point.Lat <= b.Max.Lat &&
+		point.Lng >= b.Min.Lng && point.Lng <= b.Max.Lng
+}
+
+// Diagnostics help a client tell "no crime here" apart from "no data here"
+// when a search finds no locations.
+type Diagnostics struct {
+	// Nearest is the location closest to the query, or nil if there is no
+	// data at all.
+	Nearest *Point
+	// NearestDistance is the distance in miles from the query to Nearest.
+	NearestDistance float64
+	// Bounds covers every location in the data, or is nil if there is none.
+	Bounds *Bounds
+	// OutsideCoverage is true if the query is outside Bounds.
+	OutsideCoverage bool
+}
+
+// Bounds returns the box that covers every location in the CrimeFinder. It
+// returns false if the finder has no locations.
+func (finder *CrimeFinder) Bounds() (Bounds, bool) {
+	tree := finder.tree()
+	if tree == nil {
+		return finder.scanBounds()
+	}
+	if tree.Root == nil {
+		return Bounds{}, false
+	}
+	min, max := tree.Min, tree.Max
+	return Bounds{Point{min[0], min[1]}, Point{max[0], max[1]}}, true
+}
+
+// diagnose creates Diagnostics for a search near query. The work is a single
+// nearest-neighbor search, so it's cheap enough for every empty result.
+func (finder *CrimeFinder) diagnose(query Point) *Diagnostics {
+	diagnostics := &Diagnostics{OutsideCoverage: true}
+	bounds, ok := finder.Bounds()
+	if !ok {
+		return diagnostics
+	}
+	diagnostics.Bounds = &bounds
+	diagnostics.OutsideCoverage = !bounds.Contains(query)
+	var nearest Point
+	if tree := finder.tree(); tree != nil {
+		nearest = nodePoint(tree.Nearest(Coordinates{query.Lat, query.Lng}))
+	} else {
+		nearest = *finder.scanNearest(query)
+	}
+	diagnostics.Nearest = &nearest
+	diagnostics.NearestDistance = query.GreatCircleDistance(&nearest)
+	return diagnostics
+}
+
+// The JSON form of a Point.
+type pointJson struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// ToJson returns Diagnostics marshalled to JSON bytes. Fields without a
+// value are left out.
+func (d *Diagnostics) ToJson() ([]byte, error) {
+	type boundsJson struct {
+		Min pointJson `json:"min"`
+		Max pointJson `json:"max"`
+	}
+	out := struct {
+		Nearest         *pointJson  `json:"nearest,omitempty"`
+		NearestDistance *float64    `json:"nearest_distance_miles,omitempty"`
+		Bounds          *boundsJson `json:"bounds,omitempty"`
+		OutsideCoverage bool        `json:"outside_coverage"`
+	}{OutsideCoverage: d.OutsideCoverage}
+	if d.Nearest != nil {
+		out.Nearest = &pointJson{d.Nearest.Lat, d.Nearest.Lng}
+		out.NearestDistance = &d.NearestDistance
+	}
+	if d.Bounds != nil {
+		out.Bounds = &boundsJson{
+			pointJson{d.Bounds.Min.Lat, d.Bounds.Min.Lng},
+			pointJson{d.Bounds.Max.Lat, d.Bounds.Max.Lng},
+		}
+	}
+	return json.Marshal(out)
+}
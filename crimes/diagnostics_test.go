@@ -0,0 +1,75 @@
+package radar
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCrimeFinderBounds(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	bounds, ok := finder.Bounds()
+	if !ok {
+		t.Fatal("Bounds should exist for a finder with data")
+	}
+	for _, location := range finder.Locations() {
+		if !bounds.Contains(*location.Point) {
+			t.Error("Bounds does not contain a location: ", location.Point)
+			break
+		}
+	}
+	if _, ok := (&CrimeFinder{}).Bounds(); ok {
+		t.Error("Bounds should not exist for a finder without data")
+	}
+}
+
+func TestCrimeFinderFindNearDiagnosticsOutsideCoverage(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	// Seattle
+	result, _ := finder.FindNear(Point{47.6062, -122.3321})
+	d := result.Diagnostics
+	if d == nil {
+		t.Fatal("An empty result should have Diagnostics")
+	}
+	if !d.OutsideCoverage || d.Bounds == nil || d.Nearest == nil {
+		t.Error("Diagnostics should show that the query is outside coverage")
+	}
+	if d.NearestDistance < 100 || d.NearestDistance > 200 {
+		t.Error("Diagnostics has the wrong nearest distance: ", d.NearestDistance)
+	}
+}
+
+func TestCrimeFinderFindNearDiagnosticsInsideCoverage(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	bounds, _ := finder.Bounds()
+	// Search every corner of the data's bounds until one comes up empty.
+	corners := []Point{bounds.Min, bounds.Max, {bounds.Min.Lat, bounds.Max.Lng}, {bounds.Max.Lat, bounds.Min.Lng}}
+	for _, corner := range corners {
+		result, _ := finder.FindNear(corner)
+		if len(result.Locations) > 0 {
+			if result.Diagnostics != nil {
+				t.Error("A result with locations should not have Diagnostics")
+			}
+			continue
+		}
+		if result.Diagnostics.OutsideCoverage {
+			t.Error("A query inside the bounds should be inside coverage")
+		}
+		if result.Diagnostics.NearestDistance <= 0.5 {
+			t.Error("The nearest location should be more than half a mile away")
+		}
+		return
+	}
+}
+
+func TestSearchResultToJsonDiagnostics(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	result, _ := finder.FindNear(Point{47.6062, -122.3321})
+	data, err := result.ToJson()
+	if err != nil {
+		t.Error("ToJson returned an error: ", err)
+	}
+	json := string(data)
+	if !strings.Contains(json, `"diagnostics":{"nearest":{"lat":`) || !strings.Contains(json, `"outside_coverage":true`) {
+		t.Error("ToJson did not include diagnostics: ", json)
+	}
+}
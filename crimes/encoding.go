@@ -0,0 +1,72 @@
+package radar
+
+import (
+	"bufio"
+	"io"
+	"unicode/utf8"
+)
+
+// The UTF-8 byte order mark that some exports begin with.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Characters for bytes 0x80 through 0x9F in Windows-1252. Bytes that are not
+// defined in Windows-1252 map to the same code point, like browsers do.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008D', 'Ž', '\u008F',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009D', 'ž', 'Ÿ',
+}
+
+// decodeWindows1252 returns the character for a byte of Windows-1252 text.
+func decodeWindows1252(b byte) rune {
+	if b >= 0x80 && b <= 0x9F {
+		return windows1252[b-0x80]
+	}
+	// The rest of Windows-1252 matches the first 256 Unicode code points.
+	return rune(b)
+}
+
+// A decodingReader reads text that may be UTF-8 or Windows-1252 and returns
+// it as UTF-8. Valid UTF-8 passes through unchanged and any other byte is
+// decoded as Windows-1252, so files that mix the two (e.g. concatenated
+// exports) come out right as well.
+type decodingReader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+// newDecodingReader returns a reader that strips a leading UTF-8 BOM from r
+// and converts its contents to UTF-8.
+func newDecodingReader(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(len(utf8BOM)); err == nil && string(bom) == string(utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+	return &decodingReader{r: br}
+}
+
+// Read reads decoded UTF-8 text into p.
+func (d *decodingReader) Read(p []byte) (int, error) {
+	var err error
+	for len(d.buf) < len(p) {
+		var c rune
+		var size int
+		c, size, err = d.r.ReadRune()
+		if err != nil {
+			break
+		}
+		if c == utf8.RuneError && size == 1 {
+			d.r.UnreadRune()
+			b, _ := d.r.ReadByte()
+			c = decodeWindows1252(b)
+		}
+		d.buf = utf8.AppendRune(d.buf, c)
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	if n > 0 {
+		return n, nil
+	}
+	return 0, err
+}
@@ -0,0 +1,48 @@
+package radar
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func decodeString(s string) string {
+	out, _ := io.ReadAll(newDecodingReader(strings.NewReader(s)))
+	return string(out)
+}
+
+func TestDecodingReaderStripsBOM(t *testing.T) {
+	actual := decodeString("\xEF\xBB\xBF13807517,12/01/2011")
+	if actual != "13807517,12/01/2011" {
+		t.Error("BOM was not stripped: ", actual)
+	}
+}
+
+func TestDecodingReaderUTF8(t *testing.T) {
+	expected := "SE CÉSAR E CHÁVEZ BLVD – PORTLAND"
+	actual := decodeString(expected)
+	if actual != expected {
+		t.Error("UTF-8 text was changed: ", actual)
+	}
+}
+
+func TestDecodingReaderWindows1252(t *testing.T) {
+	actual := decodeString("SE C\xC9SAR E CH\xC1VEZ BLVD \x96 PORTLAND")
+	expected := "SE CÉSAR E CHÁVEZ BLVD – PORTLAND"
+	if actual != expected {
+		t.Error("Windows-1252 text was not converted: ", actual)
+	}
+}
+
+func TestReadCrimesFromBOM(t *testing.T) {
+	data := "\xEF\xBB\xBF13690825,05/27/2011,08:35:00,Liquor Laws,,,,,45.5,-122.6\n"
+	rows, _, err := readCrimesFrom(strings.NewReader(data))
+	if err != nil {
+		t.Error("readCrimesFrom returned an error: ", err)
+	}
+	finder := CrimeFinder{}
+	finder.addRows(rows)
+	if finder.Report.Crimes != 1 {
+		t.Error("The first record's id should parse after the BOM is stripped: ", finder.Report.Errors)
+	}
+}
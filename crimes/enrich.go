@@ -0,0 +1,57 @@
+package radar
+
+import (
+	"fmt"
+)
+
+// An Enricher adds information to crimes after they load, e.g. the census
+// tract a crime occurred in or the weather on the day it happened.
+type Enricher interface {
+	// Enrich returns values to add to the Enrichments of a crime that
+	// occurred at location.
+	Enrich(crime *Crime, location *CrimeLocation) (map[string]interface{}, error)
+}
+
+// EnricherFunc lets an ordinary function act as an Enricher.
+type EnricherFunc func(crime *Crime, location *CrimeLocation) (map[string]interface{}, error)
+
+// Enrich calls f(crime, location).
+func (f EnricherFunc) Enrich(crime *Crime, location *CrimeLocation) (map[string]interface{}, error) {
+	return f(crime, location)
+}
+
+// Enrich runs enrichers on every crime in the CrimeFinder. Values from later
+// enrichers replace values with the same name from earlier ones. An error
+// from an enricher is recorded in the finder's Report and doesn't stop the
+// other enrichers.
+func (finder *CrimeFinder) Enrich(enrichers ...Enricher) {
+	finder.enrich(finder.Locations(), enrichers)
+}
+
+// enrich runs enrichers on the crimes at locations.
+func (finder *CrimeFinder) enrich(locations []*CrimeLocation, enrichers []Enricher) {
+	if len(enrichers) == 0 {
+		return
+	}
+	for _, location := range locations {
+		for _, crime := range location.Crimes {
+			for _, enricher := range enrichers {
+				values, err := enricher.Enrich(crime, location)
+				if err != nil {
+					err = fmt.Errorf("enriching crime %v: %v", crime.Id, err)
+					finder.Report.EnrichmentErrors = append(finder.Report.EnrichmentErrors, err)
+					continue
+				}
+				if len(values) == 0 {
+					continue
+				}
+				if crime.Enrichments == nil {
+					crime.Enrichments = make(map[string]interface{})
+				}
+				for name, value := range values {
+					crime.Enrichments[name] = value
+				}
+			}
+		}
+	}
+}
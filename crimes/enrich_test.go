@@ -0,0 +1,66 @@
+package radar
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCrimeFinderEnrich(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Error("Error creating CrimeFinder: ", err)
+	}
+	sector := EnricherFunc(func(crime *Crime, location *CrimeLocation) (map[string]interface{}, error) {
+		if location.Point.Lng < -122.66 {
+			return map[string]interface{}{"sector": "west"}, nil
+		}
+		return map[string]interface{}{"sector": "east"}, nil
+	})
+	finder.Enrich(sector)
+
+	for _, crime := range finder.All().Crimes() {
+		if crime.Enrichments["sector"] == nil {
+			t.Error("Crime was not enriched: ", crime)
+			break
+		}
+	}
+}
+
+func TestCrimeFinderEnrichErrors(t *testing.T) {
+	finder, err := NewCrimeFinderWithOptions("sample/crimes.csv", LoadOptions{
+		Enrichers: []Enricher{
+			EnricherFunc(func(crime *Crime, location *CrimeLocation) (map[string]interface{}, error) {
+				return nil, errors.New("service unavailable")
+			}),
+			EnricherFunc(func(crime *Crime, location *CrimeLocation) (map[string]interface{}, error) {
+				return map[string]interface{}{"checked": true}, nil
+			}),
+		},
+	})
+	if err != nil {
+		t.Error("Error creating CrimeFinder: ", err)
+	}
+	if len(finder.Report.EnrichmentErrors) != finder.Report.Crimes {
+		t.Error("Wrong number of enrichment errors: ", len(finder.Report.EnrichmentErrors))
+	}
+	crime := finder.All().Crimes()[0]
+	if crime.Enrichments["checked"] != true {
+		t.Error("An error from one enricher should not stop the others")
+	}
+}
+
+func TestSearchResultToJsonEnrichments(t *testing.T) {
+	crime := &Crime{Id: 1, Date: "1/1/2013", Time: "04:30", Type: "Burglary"}
+	crime.Enrichments = map[string]interface{}{"tract": "23.03", "walkscore": 88}
+	point := Point{45.1, -122.3}
+	result := SearchResult{Query: &point, Locations: []*CrimeLocation{{&point, []*Crime{crime}}}}
+	actual, err := result.ToJson()
+	if err != nil {
+		t.Error("ToJson returned an error: ", err)
+	}
+	expected := `{"id":1,"date":"1/1/2013","time":"04:30","type":"Burglary","enrichments":{"tract":"23.03","walkscore":88},"url":"/crimes/1"}`
+	if !strings.Contains(string(actual), expected) {
+		t.Error("ToJson did not include enrichments: ", string(actual))
+	}
+}
@@ -0,0 +1,77 @@
+package radar
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// The name of the index that searches use, as reported in an Explanation.
+const INDEX_NAME = "kdtree"
+
+// The name reported for searches that scan every location, because the
+// finder's indexes haven't been built yet.
+const SCAN_NAME = "scan"
+
+// An Explanation describes how a search ran, to help tune the index and
+// search radius.
+type Explanation struct {
+	// Index is the name of the index the search used.
+	Index string
+	// NodesVisited is the number of index nodes the search looked at.
+	NodesVisited int
+	// Candidates is the number of locations the index returned.
+	Candidates int
+	// Results is the number of locations in the result.
+	Results int
+	// Cache is "hit" or "miss" if the result came through a cache, or "none".
+	Cache string
+	// Plan is the plan a filtered search ran with, and Estimates are the
+	// estimates of how many crimes each plan would check that it was
+	// chosen by. Searches that aren't filtered don't have a plan.
+	Plan      string
+	Estimates map[string]int
+	// Timings holds how long each phase of the search took.
+	Timings map[string]time.Duration
+	// phases holds the names of the phases in Timings in the order they ran.
+	phases []string
+}
+
+// newExplanation creates an Explanation for a search that hasn't run yet.
+func newExplanation() *Explanation {
+	return &Explanation{Index: INDEX_NAME, Cache: "none", Timings: make(map[string]time.Duration)}
+}
+
+// record saves the time since start as the time a phase took. It's safe to
+// call on a nil Explanation, so searches don't have to check for one.
+func (e *Explanation) record(phase string, start time.Time) {
+	if e == nil {
+		return
+	}
+	if _, exists := e.Timings[phase]; !exists {
+		e.phases = append(e.phases, phase)
+	}
+	e.Timings[phase] += time.Since(start)
+}
+
+// ToJson returns an Explanation marshalled to JSON bytes, with timings in
+// milliseconds.
+func (e *Explanation) ToJson() ([]byte, error) {
+	type timing struct {
+		Phase string  `json:"phase"`
+		Ms    float64 `json:"ms"`
+	}
+	timings := make([]timing, 0, len(e.phases))
+	for _, phase := range e.phases {
+		timings = append(timings, timing{phase, float64(e.Timings[phase]) / float64(time.Millisecond)})
+	}
+	return json.Marshal(struct {
+		Index        string         `json:"index"`
+		NodesVisited int            `json:"nodes_visited"`
+		Candidates   int            `json:"candidates"`
+		Results      int            `json:"results"`
+		Cache        string         `json:"cache"`
+		Plan         string         `json:"plan,omitempty"`
+		Estimates    map[string]int `json:"estimates,omitempty"`
+		Timings      []timing       `json:"timings"`
+	}{e.Index, e.NodesVisited, e.Candidates, e.Results, e.Cache, e.Plan, e.Estimates, timings})
+}
@@ -0,0 +1,64 @@
+package radar
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCrimeFinderFindNearExplained(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	point := Point{45.53435699129174, -122.66469510763777}
+	result, err := finder.FindNearExplained(point)
+	if err != nil {
+		t.Error("FindNearExplained returned an error: ", err)
+	}
+	e := result.Explanation
+	if e == nil {
+		t.Fatal("FindNearExplained did not set an Explanation")
+	}
+	if e.Index != INDEX_NAME || e.Cache != "none" {
+		t.Error("Explanation has the wrong index or cache: ", e.Index, e.Cache)
+	}
+	if e.Results != 14 || e.Candidates < e.Results || e.NodesVisited < e.Candidates {
+		t.Error("Explanation has the wrong counts: ", e.NodesVisited, e.Candidates, e.Results)
+	}
+	if _, ok := e.Timings["search"]; !ok {
+		t.Error("Explanation is missing the search timing")
+	}
+}
+
+func TestCrimeFinderFindNearNotExplained(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	result, _ := finder.FindNear(Point{45.53435699129174, -122.66469510763777})
+	if result.Explanation != nil {
+		t.Error("FindNear should not set an Explanation")
+	}
+}
+
+func TestSearchResultToJsonExplained(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	result, _ := finder.FindNearExplained(Point{45.53435699129174, -122.66469510763777})
+	data, err := result.ToJson()
+	if err != nil {
+		t.Error("ToJson returned an error: ", err)
+	}
+	var body struct {
+		Explain struct {
+			Index   string
+			Results int
+			Timings []struct {
+				Phase string
+				Ms    float64
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatal("ToJson returned invalid JSON: ", err)
+	}
+	if body.Explain.Index != INDEX_NAME || body.Explain.Results != 14 {
+		t.Error("ToJson has the wrong explanation: ", string(data))
+	}
+	if len(body.Explain.Timings) != 2 || body.Explain.Timings[0].Phase != "search" {
+		t.Error("ToJson has the wrong timings: ", body.Explain.Timings)
+	}
+}
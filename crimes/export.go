@@ -0,0 +1,196 @@
+package radar
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Formats that crimes can be exported in.
+const (
+	// CsvFormat is CSV in the layout of RadarSchema, so that an export can
+	// be loaded again.
+	CsvFormat = "csv"
+	// GeoJSONFormat is a GeoJSON FeatureCollection with a Point feature for
+	// each crime.
+	GeoJSONFormat = "geojson"
+)
+
+// ExportFormats holds the formats crimes can be exported in.
+var ExportFormats = []string{CsvFormat, GeoJSONFormat}
+
+// IsExportFormat returns true if format is one that crimes can be exported
+// in.
+func IsExportFormat(format string) bool {
+	for _, known := range ExportFormats {
+		if format == known {
+			return true
+		}
+	}
+	return false
+}
+
+// An ExportFilter selects the crimes to export. Empty fields match every
+// crime.
+type ExportFilter struct {
+	// Bounds, if set, only matches crimes inside the box.
+	Bounds *Bounds
+	// Category only matches crimes in a category of the taxonomy.
+	Category string
+	// From and To only match crimes on or after From and on or before To.
+	// A crime whose date can't be parsed doesn't match either.
+	From time.Time
+	To   time.Time
+	// Attributes matches crimes by their optional attributes.
+	Attributes AttributeFilter
+}
+
+// Matches returns true if the crime, which occurred at point, matches the
+// filter.
+func (filter ExportFilter) Matches(crime *Crime, point *Point) bool {
+	if filter.Bounds != nil && !filter.Bounds.Contains(*point) {
+		return false
+	}
+	if filter.Category != "" && Classify(crime.Type).Category != filter.Category {
+		return false
+	}
+	if !filter.From.IsZero() || !filter.To.IsZero() {
+		date, err := time.Parse(DATE_LAYOUT, crime.Date)
+		if err != nil || !filter.From.IsZero() && date.Before(filter.From) || !filter.To.IsZero() && date.After(filter.To) {
+			return false
+		}
+	}
+	return filter.Attributes.Matches(crime)
+}
+
+// Select returns the crimes that match filter, as a SearchResult without a
+// query. It only holds pointers to the finder's crimes, so it's cheap to
+// make while the finder is locked and write out afterward.
+func (finder *CrimeFinder) Select(filter ExportFilter) SearchResult {
+	result := SearchResult{Locations: make([]*CrimeLocation, 0)}
+	for _, location := range finder.Locations() {
+		crimes := make([]*Crime, 0)
+		for _, crime := range location.Crimes {
+			if filter.Matches(crime, location.Point) {
+				crimes = append(crimes, crime)
+			}
+		}
+		if len(crimes) > 0 {
+			result.Locations = append(result.Locations, &CrimeLocation{location.Point, crimes})
+		}
+	}
+	return result
+}
+
+// formatFlag formats an optional flag for a CSV export.
+func formatFlag(flag *bool) string {
+	if flag == nil {
+		return ""
+	}
+	return strconv.FormatBool(*flag)
+}
+
+// WriteExport writes the crimes of r to w in format, with permalinks to
+// r.BaseURL where the format has room for them.
+func (r SearchResult) WriteExport(w io.Writer, format string) error {
+	switch format {
+	case CsvFormat:
+		return r.writeCsv(w)
+	case GeoJSONFormat:
+		return r.writeGeoJSON(w)
+	}
+	return fmt.Errorf("unknown export format: %q", format)
+}
+
+func (r SearchResult) writeCsv(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	header := make([]string, 0, NUM_SCHEMA_COLUMNS)
+	for _, names := range RadarSchema.Columns {
+		header = append(header, names[0])
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, location := range r.Locations {
+		lat := strconv.FormatFloat(location.Point.Lat, 'f', -1, 64)
+		lng := strconv.FormatFloat(location.Point.Lng, 'f', -1, 64)
+		for _, crime := range location.Crimes {
+			row := []string{
+				strconv.FormatInt(crime.Id, 10), crime.Date, crime.Time, crime.Type, "", crime.Neighborhood, "", "", lat, lng,
+				crime.Weapon, formatFlag(crime.Domestic), formatFlag(crime.Arrest), crime.CaseNumber,
+			}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (r SearchResult) writeGeoJSON(w io.Writer) error {
+	type properties struct {
+		Id           int64  `json:"id"`
+		Date         string `json:"date"`
+		Time         string `json:"time"`
+		Type         string `json:"type"`
+		Category     string `json:"category"`
+		Weapon       string `json:"weapon,omitempty"`
+		Domestic     *bool  `json:"domestic,omitempty"`
+		Arrest       *bool  `json:"arrest,omitempty"`
+		Case         string `json:"case,omitempty"`
+		Geocoded     bool   `json:"geocoded,omitempty"`
+		Neighborhood string `json:"neighborhood,omitempty"`
+		URL          string `json:"url"`
+	}
+	type geometry struct {
+		Type        string     `json:"type"`
+		Coordinates [2]float64 `json:"coordinates"`
+	}
+	type feature struct {
+		Type       string     `json:"type"`
+		Geometry   geometry   `json:"geometry"`
+		Properties properties `json:"properties"`
+	}
+	// Features are written one at a time, so that a large export doesn't
+	// have to fit in memory as JSON.
+	buf := bufio.NewWriter(w)
+	buf.WriteString(`{"type":"FeatureCollection","features":[`)
+	first := true
+	for _, location := range r.Locations {
+		for _, crime := range location.Crimes {
+			encoded, err := json.Marshal(feature{
+				Type:     "Feature",
+				Geometry: geometry{"Point", [2]float64{location.Point.Lng, location.Point.Lat}},
+				Properties: properties{
+					Id:           crime.Id,
+					Date:         crime.Date,
+					Time:         crime.Time,
+					Type:         crime.Type,
+					Category:     Classify(crime.Type).Category,
+					Weapon:       crime.Weapon,
+					Domestic:     crime.Domestic,
+					Arrest:       crime.Arrest,
+					Case:         crime.CaseNumber,
+					Geocoded:     crime.Geocoded,
+					Neighborhood: crime.Neighborhood,
+					URL:          Permalink(r.BaseURL, crime.Id),
+				},
+			})
+			if err != nil {
+				return err
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			buf.Write(encoded)
+		}
+	}
+	buf.WriteString("]}")
+	return buf.Flush()
+}
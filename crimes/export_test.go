@@ -0,0 +1,98 @@
+package radar
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSelect(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	if all := finder.Select(ExportFilter{}); len(all.Crimes()) != finder.Report.Crimes {
+		t.Error("An empty filter should select every crime: ", len(all.Crimes()))
+	}
+	filter := ExportFilter{
+		Bounds:   &Bounds{Min: Point{45.52, -122.67}, Max: Point{45.54, -122.65}},
+		Category: PropertyCategory,
+		From:     time.Date(2011, 6, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2011, 6, 30, 0, 0, 0, 0, time.UTC),
+	}
+	selected := finder.Select(filter)
+	if len(selected.Crimes()) == 0 {
+		t.Fatal("Filter should select some crimes")
+	}
+	for _, location := range selected.Locations {
+		for _, crime := range location.Crimes {
+			date, _ := time.Parse(DATE_LAYOUT, crime.Date)
+			if !filter.Bounds.Contains(*location.Point) || Classify(crime.Type).Category != PropertyCategory || date.Month() != time.June {
+				t.Error("Filter selected the wrong crime: ", crime)
+			}
+		}
+	}
+}
+
+func TestWriteExportCsv(t *testing.T) {
+	arrest := true
+	crime := &Crime{Id: 1, Date: "01/01/2013", Time: "04:30:00", Type: "Robbery", Weapon: "Knife", Arrest: &arrest}
+	point := Point{45.5, -122.6}
+	result := SearchResult{Locations: []*CrimeLocation{{&point, []*Crime{crime}}}}
+	var buf bytes.Buffer
+	if err := result.WriteExport(&buf, CsvFormat); err != nil {
+		t.Fatal("WriteExport returned an error: ", err)
+	}
+	// An export loads back as the same crimes.
+	filename := filepath.Join(t.TempDir(), "export.csv")
+	os.WriteFile(filename, buf.Bytes(), 0644)
+	finder, err := NewCrimeFinder(filename)
+	if err != nil {
+		t.Fatal("Error loading the export: ", err)
+	}
+	loaded, location := finder.FindByID(1)
+	if loaded == nil || loaded.Type != "Robbery" || loaded.Weapon != "Knife" || loaded.Arrest == nil || !*loaded.Arrest || *location.Point != point {
+		t.Error("Export loaded as the wrong crime: ", loaded)
+	}
+}
+
+func TestWriteExportGeoJSON(t *testing.T) {
+	crime := &Crime{Id: 1, Date: "01/01/2013", Time: "04:30:00", Type: "Robbery"}
+	point := Point{45.5, -122.6}
+	result := SearchResult{Locations: []*CrimeLocation{{&point, []*Crime{crime, crime}}}, BaseURL: "https://radar.example.com"}
+	var buf bytes.Buffer
+	if err := result.WriteExport(&buf, GeoJSONFormat); err != nil {
+		t.Fatal("WriteExport returned an error: ", err)
+	}
+	var collection struct {
+		Type     string
+		Features []struct {
+			Geometry struct {
+				Type        string
+				Coordinates []float64
+			}
+			Properties struct {
+				Id       int64
+				Category string
+				URL      string
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &collection); err != nil {
+		t.Fatal("Export was not valid JSON: ", err, buf.String())
+	}
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 2 {
+		t.Fatal("Export has the wrong features: ", buf.String())
+	}
+	feature := collection.Features[0]
+	if feature.Geometry.Coordinates[0] != -122.6 || feature.Properties.Category != PropertyCategory ||
+		feature.Properties.URL != "https://radar.example.com/crimes/1" {
+		t.Error("Export has the wrong feature: ", buf.String())
+	}
+	if err := result.WriteExport(&buf, "xml"); err == nil {
+		t.Error("WriteExport should refuse an unknown format")
+	}
+}
@@ -0,0 +1,195 @@
+package radar
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// The most months ahead a forecast can be for.
+const MAX_FORECAST_HORIZON = 24
+
+// FORECAST_SEASON is the number of months in a season of the seasonal
+// naive model.
+const FORECAST_SEASON = 12
+
+// FORECAST_LEVEL is the confidence level of a forecast's intervals, and
+// FORECAST_Z the number of standard deviations they're wide on each side.
+const (
+	FORECAST_LEVEL = 0.95
+	FORECAST_Z     = 1.96
+)
+
+// The methods a cell's counts can be forecast with.
+const (
+	// NAIVE_METHOD forecasts every month to have the last month's count.
+	NAIVE_METHOD = "naive"
+	// SEASONAL_NAIVE_METHOD forecasts every month to have the count of the
+	// same month a season before.
+	SEASONAL_NAIVE_METHOD = "seasonal-naive"
+)
+
+// A cellModel is the monthly series of a cell's counts and the standard
+// deviation of the errors of forecasting a month ahead with them.
+type cellModel struct {
+	series []int
+	sigma  float64
+}
+
+// A ForecastModel forecasts the monthly counts of crimes in each cell of
+// the grid. It uses the seasonal naive method once it has more than a
+// season of history, so that residuals can be measured, and the naive
+// method before then.
+type ForecastModel struct {
+	// Start is the first month of the history, and Months its length.
+	Start  time.Time
+	Months int
+	Method string
+	cells  map[GridCell]cellModel
+}
+
+// monthIndex returns the number of months from start to date.
+func monthIndex(start time.Time, date time.Time) int {
+	return (date.Year()-start.Year())*12 + int(date.Month()) - int(start.Month())
+}
+
+// TrainForecasts builds a ForecastModel from the monthly counts of crimes
+// in each cell, from the month of the earliest crime to the month of the
+// latest. Crimes whose dates can't be parsed are left out.
+func (finder *CrimeFinder) TrainForecasts() *ForecastModel {
+	model := &ForecastModel{Method: NAIVE_METHOD, cells: make(map[GridCell]cellModel)}
+	var latest time.Time
+	for _, location := range finder.LocationLookup {
+		for _, crime := range location.Crimes {
+			occurred, err := time.Parse(DATE_LAYOUT, crime.Date)
+			if err != nil {
+				continue
+			}
+			if model.Start.IsZero() || occurred.Before(model.Start) {
+				model.Start = occurred
+			}
+			if occurred.After(latest) {
+				latest = occurred
+			}
+		}
+	}
+	if model.Start.IsZero() {
+		return model
+	}
+	model.Start = time.Date(model.Start.Year(), model.Start.Month(), 1, 0, 0, 0, 0, time.UTC)
+	model.Months = monthIndex(model.Start, latest) + 1
+	if model.Months > FORECAST_SEASON {
+		model.Method = SEASONAL_NAIVE_METHOD
+	}
+
+	counts := make(map[GridCell][]int)
+	for _, location := range finder.LocationLookup {
+		cell := GridCellOf(*location.Point)
+		for _, crime := range location.Crimes {
+			occurred, err := time.Parse(DATE_LAYOUT, crime.Date)
+			if err != nil {
+				continue
+			}
+			if _, ok := counts[cell]; !ok {
+				counts[cell] = make([]int, model.Months)
+			}
+			counts[cell][monthIndex(model.Start, occurred)]++
+		}
+	}
+	for cell, series := range counts {
+		model.cells[cell] = cellModel{series, model.residualSigma(series)}
+	}
+	return model
+}
+
+// lag returns the number of months back the model's method looks.
+func (model *ForecastModel) lag() int {
+	if model.Method == SEASONAL_NAIVE_METHOD {
+		return FORECAST_SEASON
+	}
+	return 1
+}
+
+// residualSigma returns the standard deviation of the errors of the
+// model's forecasts of series a month ahead.
+func (model *ForecastModel) residualSigma(series []int) float64 {
+	lag := model.lag()
+	if len(series) <= lag {
+		return 0
+	}
+	sum := 0.0
+	for t := lag; t < len(series); t++ {
+		residual := float64(series[t] - series[t-lag])
+		sum += residual * residual
+	}
+	return math.Sqrt(sum / float64(len(series)-lag))
+}
+
+// A ForecastMonth is a month's forecast count and the interval it should
+// fall in at FORECAST_LEVEL confidence.
+type ForecastMonth struct {
+	Month time.Time
+	Count float64
+	Lower float64
+	Upper float64
+}
+
+// A Forecast is a cell's forecast for the months after the model's
+// history.
+type Forecast struct {
+	Cell   GridCell
+	Method string
+	Months []ForecastMonth
+}
+
+// Forecast forecasts the counts of cell for horizon months after the
+// model's history. A cell without crimes is forecast to have none. The
+// intervals widen with the number of seasons, or months, ahead, as the
+// errors of the naive methods add up.
+func (model *ForecastModel) Forecast(cell GridCell, horizon int) Forecast {
+	forecast := Forecast{Cell: cell, Method: model.Method, Months: make([]ForecastMonth, 0, horizon)}
+	if model.Months == 0 {
+		return forecast
+	}
+	trained, ok := model.cells[cell]
+	if !ok {
+		trained = cellModel{series: make([]int, model.Months)}
+	}
+	lag := min(model.lag(), model.Months)
+	for h := 1; h <= horizon; h++ {
+		// The month of the last season of history that's forecast to repeat,
+		// and the number of times it repeats to get to month h.
+		steps := (h-1)/lag + 1
+		count := float64(trained.series[model.Months-lag+(h-1)%lag])
+		width := FORECAST_Z * trained.sigma * math.Sqrt(float64(steps))
+		forecast.Months = append(forecast.Months, ForecastMonth{
+			Month: model.Start.AddDate(0, model.Months+h-1, 0),
+			Count: count,
+			Lower: math.Max(count-width, 0),
+			Upper: count + width,
+		})
+	}
+	return forecast
+}
+
+// ToJson returns the forecast marshalled to JSON bytes.
+func (forecast Forecast) ToJson() ([]byte, error) {
+	type monthJson struct {
+		Month string  `json:"month"`
+		Count float64 `json:"count"`
+		Lower float64 `json:"lower"`
+		Upper float64 `json:"upper"`
+	}
+	months := make([]monthJson, 0, len(forecast.Months))
+	for _, month := range forecast.Months {
+		months = append(months, monthJson{month.Month.Format(MONTH_LAYOUT), roundTo(month.Count, 2), roundTo(month.Lower, 2), roundTo(month.Upper, 2)})
+	}
+	center := forecast.Cell.Center()
+	return json.Marshal(struct {
+		Cell   string      `json:"cell"`
+		Center pointJson   `json:"center"`
+		Method string      `json:"method"`
+		Level  float64     `json:"level"`
+		Months []monthJson `json:"months"`
+	}{forecast.Cell.String(), pointJson{center.Lat, center.Lng}, forecast.Method, FORECAST_LEVEL, months})
+}
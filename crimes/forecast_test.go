@@ -0,0 +1,70 @@
+package radar
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestForecast(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	model := finder.TrainForecasts()
+	if model.Months != 12 || model.Method != NAIVE_METHOD || model.Start.Format(MONTH_LAYOUT) != "2011-01" {
+		t.Error("A year of history should use the naive method: ", model.Start, model.Months, model.Method)
+	}
+	downtown := GridCell{6375, -16945}
+	forecast := model.Forecast(downtown, 3)
+	if len(forecast.Months) != 3 || forecast.Months[0].Month.Format(MONTH_LAYOUT) != "2012-01" {
+		t.Fatal("Wrong months forecast: ", forecast.Months)
+	}
+	last := float64(model.cells[downtown].series[11])
+	for i, month := range forecast.Months {
+		if month.Count != last {
+			t.Error("The naive method should forecast the last month: ", month.Count, last)
+		}
+		if month.Lower > month.Count || month.Upper <= month.Count || month.Lower < 0 {
+			t.Error("Wrong interval: ", month)
+		}
+		if i > 0 && month.Upper-month.Count <= forecast.Months[i-1].Upper-forecast.Months[i-1].Count {
+			t.Error("Intervals should widen with the horizon: ", forecast.Months)
+		}
+	}
+	empty := model.Forecast(GridCell{0, 0}, 2)
+	if len(empty.Months) != 2 || empty.Months[1].Count != 0 || empty.Months[1].Upper != 0 {
+		t.Error("A cell without crimes should be forecast to have none: ", empty.Months)
+	}
+
+	// A thirteenth month makes a season of residuals.
+	finder.Ingest(strings.NewReader("99000001,01/15/2012,23:00:00,Burglary,,,,,45.5185,-122.6555\n"), nil)
+	model = finder.TrainForecasts()
+	if model.Months != 13 || model.Method != SEASONAL_NAIVE_METHOD {
+		t.Fatal("More than a year of history should use the seasonal naive method: ", model.Months, model.Method)
+	}
+	forecast = model.Forecast(downtown, 14)
+	series := model.cells[downtown].series
+	if forecast.Months[0].Count != float64(series[1]) || forecast.Months[12].Count != float64(series[1]) {
+		t.Error("The seasonal naive method should repeat the last season: ", forecast.Months[0], forecast.Months[12])
+	}
+	first, second := forecast.Months[0], forecast.Months[12]
+	if second.Upper-second.Count <= first.Upper-first.Count {
+		t.Error("Intervals should widen a season ahead: ", first, second)
+	}
+
+	data, err := forecast.ToJson()
+	if err != nil {
+		t.Fatal("Error marshalling forecast: ", err)
+	}
+	var decoded struct {
+		Cell   string
+		Method string
+		Level  float64
+		Months []struct{ Month string }
+	}
+	json.Unmarshal(data, &decoded)
+	if decoded.Cell != "6375:-16945" || decoded.Method != SEASONAL_NAIVE_METHOD || decoded.Level != FORECAST_LEVEL || decoded.Months[0].Month != "2012-02" {
+		t.Error("Wrong JSON: ", string(data))
+	}
+}
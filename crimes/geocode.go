@@ -0,0 +1,163 @@
+package radar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotGeocoded is returned by a Geocoder that can't find an address.
+var ErrNotGeocoded = errors.New("address could not be geocoded")
+
+// A Geocoder finds the point at an address, like a geocoding service.
+type Geocoder interface {
+	Geocode(address string) (Point, error)
+}
+
+// GeocoderFunc lets an ordinary function act as a Geocoder.
+type GeocoderFunc func(address string) (Point, error)
+
+// Geocode calls f(address).
+func (f GeocoderFunc) Geocode(address string) (Point, error) {
+	return f(address)
+}
+
+// A geocodeEntry is what a CachedGeocoder remembers about an address. An
+// address that couldn't be found is remembered too, so that it isn't
+// looked up again.
+type geocodeEntry struct {
+	Lat   float64 `json:"lat"`
+	Lng   float64 `json:"lng"`
+	Found bool    `json:"found"`
+}
+
+// A CachedGeocoder remembers the addresses a Geocoder has looked up, in a
+// file that's kept across loads, and waits between lookups so as not to go
+// over a geocoding service's rate limit. Geocoding services usually allow
+// about one request a second, so most addresses must come from the cache
+// for a large file to load in reasonable time.
+type CachedGeocoder struct {
+	geocoder Geocoder
+	filename string
+	interval time.Duration
+	mu       sync.Mutex
+	entries  map[string]geocodeEntry
+	last     time.Time
+	// sleep waits for d. It defaults to time.Sleep.
+	sleep func(d time.Duration)
+}
+
+// NewCachedGeocoder creates a CachedGeocoder that looks up addresses with
+// geocoder, at most once every interval, and keeps them in filename. An
+// empty filename keeps them only in memory.
+func NewCachedGeocoder(geocoder Geocoder, filename string, interval time.Duration) (*CachedGeocoder, error) {
+	cached := &CachedGeocoder{
+		geocoder: geocoder,
+		filename: filename,
+		interval: interval,
+		entries:  make(map[string]geocodeEntry),
+		sleep:    time.Sleep,
+	}
+	if filename == "" {
+		return cached, nil
+	}
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return cached, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &cached.entries); err != nil {
+		return nil, fmt.Errorf("reading geocoding cache %v: %v", filename, err)
+	}
+	return cached, nil
+}
+
+// normalizeAddress makes addresses that differ only in case and spacing
+// share a cache entry.
+func normalizeAddress(address string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(address), " "))
+}
+
+// Geocode returns the point at address from the cache, or looks it up.
+func (cached *CachedGeocoder) Geocode(address string) (Point, error) {
+	key := normalizeAddress(address)
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+	if entry, ok := cached.entries[key]; ok {
+		if !entry.Found {
+			return Point{}, ErrNotGeocoded
+		}
+		return Point{entry.Lat, entry.Lng}, nil
+	}
+	if wait := cached.interval - time.Since(cached.last); !cached.last.IsZero() && wait > 0 {
+		cached.sleep(wait)
+	}
+	cached.last = time.Now()
+	point, err := cached.geocoder.Geocode(address)
+	switch {
+	case err == nil:
+		cached.entries[key] = geocodeEntry{point.Lat, point.Lng, true}
+	case errors.Is(err, ErrNotGeocoded):
+		cached.entries[key] = geocodeEntry{}
+	}
+	return point, err
+}
+
+// Save writes the cache to its file.
+func (cached *CachedGeocoder) Save() error {
+	if cached.filename == "" {
+		return nil
+	}
+	cached.mu.Lock()
+	data, err := json.Marshal(cached.entries)
+	cached.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	temporary := cached.filename + ".tmp"
+	if err := os.WriteFile(temporary, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(temporary, cached.filename)
+}
+
+// geocodeRows geocodes the addresses of the rows in rowErrors that are
+// missing coordinates. It returns the rows it found coordinates for, in
+// latitude, longitude order and marked as geocoded, and the row errors
+// that are left. Rows that couldn't be geocoded keep their errors, with the
+// reason.
+func geocodeRows(rowErrors []RowError, geocoder Geocoder) (CsvRows, []RowError) {
+	rows := make(CsvRows, 0)
+	left := make([]RowError, 0, len(rowErrors))
+	for _, rowError := range rowErrors {
+		row := rowError.row
+		if rowError.Err != errMissingCoordinates || strings.TrimSpace(row[4]) == "" {
+			left = append(left, rowError)
+			continue
+		}
+		point, err := geocoder.Geocode(row[4])
+		if err == nil {
+			point, err = NewPoint(Latitude(point.Lat), Longitude(point.Lng))
+		}
+		if err != nil {
+			rowError.Err = fmt.Errorf("%v, and geocoding its address failed: %v", rowError.Err, err)
+			left = append(left, rowError)
+			continue
+		}
+		if len(row) <= GEOCODED_COLUMN {
+			row = append(row, make(CsvRow, GEOCODED_COLUMN+1-len(row))...)
+		}
+		row[LAT_COLUMN] = strconv.FormatFloat(point.Lat, 'f', -1, 64)
+		row[LNG_COLUMN] = strconv.FormatFloat(point.Lng, 'f', -1, 64)
+		row[GEOCODED_COLUMN] = "true"
+		rows = append(rows, row)
+	}
+	return rows, left
+}
@@ -0,0 +1,109 @@
+package radar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const ungeocodedData = `Record ID,Report Date,Report Time,Major Offense Type,Address,Neighborhood,Police Precinct,Police District,X Coordinate,Y Coordinate
+1,12/01/2011,01:00:00,Liquor Laws,NE WEIDLER ST,LLOYD,PORTLAND PREC NO,690,45.53435699129174,-122.66469510763777
+2,07/07/2011,18:30:00,Liquor Laws,NE SCHUYLER ST,ELIOT,PORTLAND PREC NO,590,,
+3,07/08/2011,18:30:00,Burglary,NOWHERE AVE,ELIOT,PORTLAND PREC NO,590,,
+4,07/09/2011,18:30:00,Burglary,,ELIOT,PORTLAND PREC NO,590,,
+`
+
+// countingGeocoder finds NE SCHUYLER ST and counts its lookups.
+func countingGeocoder(lookups *int) Geocoder {
+	return GeocoderFunc(func(address string) (Point, error) {
+		*lookups++
+		if normalizeAddress(address) == "NE SCHUYLER ST" {
+			return Point{45.53579735412487, -122.66468312170824}, nil
+		}
+		return Point{}, ErrNotGeocoded
+	})
+}
+
+func TestCachedGeocoder(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "geocode.json")
+	lookups := 0
+	cached, err := NewCachedGeocoder(countingGeocoder(&lookups), filename, time.Second)
+	if err != nil {
+		t.Fatal("Error creating CachedGeocoder: ", err)
+	}
+	slept := time.Duration(0)
+	cached.sleep = func(d time.Duration) { slept += d }
+
+	point, err := cached.Geocode("NE Schuyler St")
+	if err != nil || point.Lat != 45.53579735412487 {
+		t.Error("Wrong point for address: ", point, err)
+	}
+	if _, err := cached.Geocode("NOWHERE AVE"); err != ErrNotGeocoded {
+		t.Error("Unknown addresses should not be geocoded: ", err)
+	}
+	if slept == 0 {
+		t.Error("Lookups should wait for the interval")
+	}
+	cached.Geocode("  ne schuyler   st ")
+	cached.Geocode("NOWHERE AVE")
+	if lookups != 2 {
+		t.Error("Found and unknown addresses should both be cached: ", lookups)
+	}
+
+	if err := cached.Save(); err != nil {
+		t.Fatal("Error saving geocoding cache: ", err)
+	}
+	reloaded, err := NewCachedGeocoder(countingGeocoder(&lookups), filename, time.Second)
+	if err != nil {
+		t.Fatal("Error loading geocoding cache: ", err)
+	}
+	point, err = reloaded.Geocode("NE SCHUYLER ST")
+	if err != nil || point.Lng != -122.66468312170824 || lookups != 2 {
+		t.Error("Saved addresses should not be looked up again: ", point, err, lookups)
+	}
+}
+
+func TestLoadGeocodesMissingCoordinates(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "ungeocoded.csv")
+	os.WriteFile(filename, []byte(ungeocodedData), 0644)
+	lookups := 0
+	cached, _ := NewCachedGeocoder(countingGeocoder(&lookups), "", 0)
+
+	finder, err := NewCrimeFinderWithOptions(filename, LoadOptions{Geocoder: cached})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	if finder.Report.Crimes != 2 || finder.Report.Geocoded != 1 {
+		t.Error("Wrong number of crimes or geocoded crimes: ", finder.Report.Crimes, finder.Report.Geocoded)
+	}
+	if len(finder.Report.Errors) != 2 || !strings.Contains(finder.Report.Errors[0].Error(), "geocoding") {
+		t.Error("Rows that can't be geocoded should be reported: ", finder.Report.Errors)
+	}
+	crime, _ := finder.FindByID(2)
+	if crime == nil || !crime.Geocoded {
+		t.Fatal("Geocoded crime should be flagged: ", crime)
+	}
+	point := Point{45.53579735412487, -122.66468312170824}
+	result := SearchResult{Query: &point, Locations: []*CrimeLocation{{&point, []*Crime{crime}}}}
+	if actual, _ := result.ToJson(); !strings.Contains(string(actual), `"geocoded":true`) {
+		t.Error("Geocoded crime JSON should say so: ", string(actual))
+	}
+	if crime, _ := finder.FindByID(1); crime == nil || crime.Geocoded {
+		t.Error("Crimes with coordinates should not be flagged: ", crime)
+	}
+
+	snapshot := filepath.Join(dir, "ungeocoded.snapshot")
+	if err := finder.SaveSnapshot(snapshot); err != nil {
+		t.Fatal("Error saving snapshot: ", err)
+	}
+	loaded, err := LoadSnapshot(snapshot)
+	if err != nil {
+		t.Fatal("Error loading snapshot: ", err)
+	}
+	if crime, _ := loaded.FindByID(2); crime == nil || !crime.Geocoded {
+		t.Error("Snapshots should keep the geocoded flag: ", crime)
+	}
+}
@@ -0,0 +1,72 @@
+package radar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+var errNotPoint = errors.New("feature isn't a Point")
+
+// isGeoJSONFile reports whether filename names GeoJSON data rather than CSV.
+func isGeoJSONFile(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	return ext == ".geojson" || ext == ".json"
+}
+
+// The parts of a GeoJSON feature that we read crimes from. The properties
+// are those of GeoJSON exports.
+type crimeFeature struct {
+	Geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// geoJSONProperties are the properties read into each column of the legacy
+// layout, except the coordinates, which come from the geometry.
+var geoJSONProperties = [GEOCODED_COLUMN + 1]string{
+	"id", "date", "time", "type", "address", "neighborhood", "precinct", "district",
+	"", "", "weapon", "domestic", "arrest", "case", "geocoded",
+}
+
+// readGeoJSONCrimes reads a GeoJSON FeatureCollection of Point features,
+// like the ones GeoJSON exports have, into rows in the legacy layout, with
+// latitude before longitude. Features that aren't points are returned as
+// RowErrors.
+func readGeoJSONCrimes(r io.Reader) (CsvRows, []RowError, error) {
+	var collection struct {
+		Features []crimeFeature `json:"features"`
+	}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err := decoder.Decode(&collection); err != nil {
+		return nil, nil, err
+	}
+	rows := make(CsvRows, 0, len(collection.Features))
+	rowErrors := make([]RowError, 0)
+	for i, feature := range collection.Features {
+		row := make(CsvRow, len(geoJSONProperties))
+		for column, name := range geoJSONProperties {
+			if value, ok := feature.Properties[name]; ok && name != "" && value != nil {
+				row[column] = fmt.Sprint(value)
+			}
+		}
+		var coordinates []float64
+		if feature.Geometry.Type == "Point" {
+			json.Unmarshal(feature.Geometry.Coordinates, &coordinates)
+		}
+		if len(coordinates) < 2 {
+			rowErrors = append(rowErrors, newRowError(i+1, row, errNotPoint))
+			continue
+		}
+		row[LAT_COLUMN] = fmt.Sprint(coordinates[1])
+		row[LNG_COLUMN] = fmt.Sprint(coordinates[0])
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, nil
+}
@@ -0,0 +1,54 @@
+package radar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadGeoJSON(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	// A GeoJSON export loads back as the same crimes.
+	filename := filepath.Join(t.TempDir(), "export.geojson")
+	f, _ := os.Create(filename)
+	finder.All().WriteExport(f, GeoJSONFormat)
+	f.Close()
+	// Coordinates in GeoJSON are always longitude first, whatever the
+	// options say.
+	loaded, err := NewCrimeFinderWithOptions(filename, LoadOptions{CoordinateOrder: LngLatOrder})
+	if err != nil {
+		t.Fatal("Error loading GeoJSON: ", err)
+	}
+	if loaded.Report.Crimes != finder.Report.Crimes || len(loaded.Report.Errors) != 0 {
+		t.Fatal("Wrong number of crimes loaded from GeoJSON: ", loaded.Report.Crimes, loaded.Report.Errors)
+	}
+	if changes := loaded.Diff(&finder); !changes.IsEmpty() {
+		t.Error("GeoJSON loaded as different crimes: ", changes.Added, changes.Removed, changes.Corrected)
+	}
+}
+
+func TestReadGeoJSONCrimes(t *testing.T) {
+	data := `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-122.6, 45.5]},
+		 "properties": {"id": 7, "date": "01/01/2013", "time": "04:30:00", "type": "Robbery", "arrest": true}},
+		{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[-122.6, 45.5], [-122.7, 45.6]]},
+		 "properties": {"id": 8}}]}`
+	rows, rowErrors, err := readGeoJSONCrimes(strings.NewReader(data))
+	if err != nil {
+		t.Fatal("Error reading GeoJSON: ", err)
+	}
+	if len(rows) != 1 || len(rowErrors) != 1 || rowErrors[0].Id != "8" {
+		t.Fatal("Wrong rows or errors: ", rows, rowErrors)
+	}
+	row := rows[0]
+	if row[0] != "7" || row[3] != "Robbery" || row[LAT_COLUMN] != "45.5" || row[LNG_COLUMN] != "-122.6" || row[ARREST_COLUMN] != "true" {
+		t.Error("Wrong row: ", row)
+	}
+	if _, _, err := readGeoJSONCrimes(strings.NewReader("not json")); err == nil {
+		t.Error("Invalid GeoJSON should be an error")
+	}
+}
@@ -0,0 +1,184 @@
+package radar
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Run the tests with -update to rewrite the golden files with the current
+// output, after checking that a change to it is intended.
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of encoder tests")
+
+// goldenResult returns a SearchResult that uses every field the encoders
+// write: optional attributes, grouped offenses, enrichments, a geocoded
+// crime and values that need escaping, in its type and weapon.
+func goldenResult() SearchResult {
+	yes, no := true, false
+	return SearchResult{
+		Query: &Point{45.5184, -122.6554},
+		Locations: []*CrimeLocation{
+			{
+				Point: &Point{45.5231, -122.6765},
+				Crimes: []*Crime{
+					{
+						Id: 13807517, Date: "12/01/2011", Time: "01:00:00", Type: "Liquor Laws",
+						Neighborhood: "DOWNTOWN",
+					},
+					{
+						Id: 13716403, Date: "07/07/2011", Time: "18:30:00", Type: "Aggravated Assault",
+						Weapon: `Knife, "kitchen"`, Domestic: &yes, Arrest: &no, CaseNumber: "JA366925",
+						Offenses:     []Offense{{13716403, "Aggravated Assault"}, {13716404, "Vandalism"}},
+						Enrichments:  map[string]interface{}{"census_tract": "51", "walk_score": 98},
+						Neighborhood: "DOWNTOWN",
+					},
+				},
+			},
+			{
+				Point: &Point{45.5262, -122.668},
+				Crimes: []*Crime{
+					{Id: 13690825, Date: "05/27/2011", Time: "08:35:00", Type: `Larceny, "shoplifting" \ retail`, Geocoded: true},
+				},
+			},
+		},
+		BaseURL: "https://radar.example.com",
+	}
+}
+
+// goldenNeighborhoods returns neighborhoods whose names need escaping, one
+// of them with a hole.
+func goldenNeighborhoods() []Neighborhood {
+	return []Neighborhood{
+		{Name: "DOWNTOWN", Polygons: []Polygon{
+			{Ring{{45.51, -122.69}, {45.53, -122.69}, {45.53, -122.67}, {45.51, -122.67}}},
+		}},
+		{Name: `HOSFORD-ABERNETHY "HAND"`, Polygons: []Polygon{
+			{Ring{{45.5, -122.66}, {45.52, -122.66}, {45.52, -122.64}, {45.5, -122.64}}, Ring{{45.505, -122.655}, {45.51, -122.655}, {45.51, -122.65}}},
+		}},
+	}
+}
+
+// The encoders that golden files are kept for, by the name of their file.
+var goldenEncoders = map[string]func() ([]byte, error){
+	"search.json": goldenResult().ToJson,
+	"export.csv": func() ([]byte, error) {
+		buf := new(bytes.Buffer)
+		err := goldenResult().WriteExport(buf, CsvFormat)
+		return buf.Bytes(), err
+	},
+	"export.geojson": func() ([]byte, error) {
+		buf := new(bytes.Buffer)
+		err := goldenResult().WriteExport(buf, GeoJSONFormat)
+		return buf.Bytes(), err
+	},
+	"neighborhoods.geojson": func() ([]byte, error) {
+		neighborhoods := goldenNeighborhoods()
+		return NeighborhoodCounts{
+			{neighborhoods[0], 3, map[string]int{PersonCategory: 1, SocietyCategory: 2}, 0},
+			{neighborhoods[1], 0, map[string]int{}, 0},
+		}.ToJson()
+	},
+	"neighborhoods-per-capita.geojson": func() ([]byte, error) {
+		neighborhoods := goldenNeighborhoods()
+		return NeighborhoodCounts{
+			{neighborhoods[0], 3, map[string]int{PersonCategory: 1, SocietyCategory: 2}, 0},
+			{neighborhoods[1], 0, map[string]int{}, 0},
+		}.WithPopulations(Populations{"downtown": 12000, `hosford-abernethy "hand"`: 7500}).ToJson()
+	},
+	"clusters.json": func() ([]byte, error) {
+		result := goldenResult()
+		return ClustersToJson([]Cluster{
+			{Point: Point{45.52, -122.67}, Count: 12, ExpansionZoom: 14},
+			{Point: *result.Locations[0].Point, Count: 2, Location: result.Locations[0]},
+		})
+	},
+	"safe-area.geojson": func() ([]byte, error) {
+		return SafeArea{
+			Center: Point{45.5184, -122.6554}, Threshold: 40, Risk: 12.345,
+			Boundary: []Point{{45.5184, -122.6481}, {45.5255, -122.6554}, {45.5184, -122.6627}, {45.5113, -122.6554}},
+			MinMiles: 0.5, MaxMiles: 0.5,
+		}.ToGeoJson()
+	},
+	"neighborhood-months.csv": func() ([]byte, error) {
+		buf := new(bytes.Buffer)
+		err := MonthlyCounts{
+			{"2011-06", "DOWNTOWN", 4, 0},
+			{"2011-07", "DOWNTOWN", 2, 0},
+			{"2011-07", `HOSFORD-ABERNETHY "HAND"`, 1, 0},
+		}.WriteCsv(buf)
+		return buf.Bytes(), err
+	},
+	"neighborhood-months-per-capita.csv": func() ([]byte, error) {
+		buf := new(bytes.Buffer)
+		err := MonthlyCounts{
+			{"2011-06", "DOWNTOWN", 4, 0},
+			{"2011-07", "DOWNTOWN", 2, 0},
+			{"2011-07", `HOSFORD-ABERNETHY "HAND"`, 1, 0},
+		}.WithPopulations(Populations{"downtown": 12000}).WriteCsv(buf)
+		return buf.Bytes(), err
+	},
+	"hotspots.geojson": func() ([]byte, error) {
+		return CellCounts{{GridCellOf(Point{45.5184, -122.6554}), 12, 0}, {GridCellOf(Point{45.5231, -122.6765}), 9, 0}}.ToJson()
+	},
+	"location-history.json": func() ([]byte, error) {
+		location := goldenResult().Locations[0]
+		return LocationHistory{
+			Key:     GetCoordinateKey(location.Point.Lat, location.Point.Lng),
+			Point:   location.Point,
+			Crimes:  location.Crimes,
+			Years:   map[int]int{2011: 2},
+			BaseURL: "https://radar.example.com",
+		}.ToJson()
+	},
+	"anomalies.json": func() ([]byte, error) {
+		return AnomalyReport{
+			From:    time.Date(2011, 12, 1, 0, 0, 0, 0, time.UTC),
+			To:      time.Date(2011, 12, 29, 0, 0, 0, 0, time.UTC),
+			Periods: 8,
+			Seasons: 1,
+			Anomalies: []Anomaly{
+				{
+					Cell: GridCellOf(Point{45.5184, -122.6554}), Recent: 14, Mean: 4.125, StdDev: 1.6535, Score: 6.0061,
+					SeasonalMean: 5, SeasonalStdDev: 2.2361, SeasonalScore: 4.0249,
+				},
+			},
+		}.ToJson()
+	},
+}
+
+func TestEncodersMatchGoldenFiles(t *testing.T) {
+	for name, encode := range goldenEncoders {
+		actual, err := encode()
+		if err != nil {
+			t.Error("Error encoding ", name, ": ", err)
+			continue
+		}
+		filename := filepath.Join("testdata", "golden", name)
+		if *updateGolden {
+			if err := os.WriteFile(filename, actual, 0644); err != nil {
+				t.Fatal("Error updating golden file: ", err)
+			}
+			continue
+		}
+		expected, err := os.ReadFile(filename)
+		if err != nil {
+			t.Error("Error reading golden file: ", err)
+			continue
+		}
+		if !bytes.Equal(actual, expected) {
+			t.Error("Output of ", name, " doesn't match its golden file. Got:\n", string(actual))
+		}
+	}
+}
+
+// Every export format should have a golden file.
+func TestEveryExportFormatHasGoldenFile(t *testing.T) {
+	for _, format := range ExportFormats {
+		if _, ok := goldenEncoders["export."+format]; !ok {
+			t.Error("Export format has no golden file: ", format)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package radar
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var errGridCell = errors.New("grid cell must be a row and column, like 6377:-17163")
+
+// A GridCell is a cell of a grid of half-mile squares, a quarter the area
+// of a search, that crimes are counted by for planning searches and for
+// analytics. It's identified by its row and column: the latitude and
+// longitude of its south-west corner, in cells.
+type GridCell struct {
+	Row int
+	Col int
+}
+
+// GridCellOf returns the cell of the grid that point is in.
+func GridCellOf(point Point) GridCell {
+	return GridCell{int(math.Floor(point.Lat / HALF_MILE_LAT)), int(math.Floor(point.Lng / HALF_MILE_LNG))}
+}
+
+// ParseGridCell parses a cell in the form its String method returns.
+func ParseGridCell(s string) (GridCell, error) {
+	var cell GridCell
+	var rest string
+	if n, _ := fmt.Sscanf(s, "%d:%d%s", &cell.Row, &cell.Col, &rest); n != 2 {
+		return cell, errGridCell
+	}
+	return cell, nil
+}
+
+// String returns the cell's row and column, like "6377:-17163".
+func (cell GridCell) String() string {
+	return fmt.Sprintf("%d:%d", cell.Row, cell.Col)
+}
+
+// Bounds returns the box the cell covers.
+func (cell GridCell) Bounds() Bounds {
+	south, west := float64(cell.Row)*HALF_MILE_LAT, float64(cell.Col)*HALF_MILE_LNG
+	return Bounds{Point{south, west}, Point{south + HALF_MILE_LAT, west + HALF_MILE_LNG}}
+}
+
+// Center returns the point at the middle of the cell.
+func (cell GridCell) Center() Point {
+	bounds := cell.Bounds()
+	return Point{(bounds.Min.Lat + bounds.Max.Lat) / 2, (bounds.Min.Lng + bounds.Max.Lng) / 2}
+}
@@ -0,0 +1,24 @@
+package radar
+
+import "testing"
+
+func TestGridCell(t *testing.T) {
+	point := Point{45.53435699129174, -122.66469510763777}
+	cell := GridCellOf(point)
+	parsed, err := ParseGridCell(cell.String())
+	if err != nil || parsed != cell {
+		t.Error("Cell did not round trip: ", cell, parsed, err)
+	}
+	bounds := cell.Bounds()
+	if point.Lat < bounds.Min.Lat || point.Lat >= bounds.Max.Lat || point.Lng < bounds.Min.Lng || point.Lng >= bounds.Max.Lng {
+		t.Error("Cell should contain its point: ", bounds)
+	}
+	if GridCellOf(cell.Center()) != cell {
+		t.Error("Cell should contain its center: ", cell.Center())
+	}
+	for _, bad := range []string{"", "6377", "6377:", "a:b", "6377:-17163x", "1.5:2"} {
+		if _, err := ParseGridCell(bad); err == nil {
+			t.Error("Bad cell was parsed: ", bad)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package radar
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The format of the load time at the start of a history file's name. It
+// sorts in time order.
+const HISTORY_TIME_FORMAT = "20060102T150405.000000000Z"
+
+// The number of historical finders a History keeps loaded at once.
+const HISTORY_LOADED = 2
+
+// ErrNoHistory is returned for a time before the oldest snapshot in a
+// History.
+var ErrNoHistory = errors.New("no data was loaded by then")
+
+// A HistoryEntry is a snapshot in a History.
+type HistoryEntry struct {
+	LoadedAt    time.Time
+	Fingerprint string
+	filename    string
+}
+
+// A History keeps snapshots of the last few versions of a finder's data in
+// a directory, so that searches can be run against the data as it was
+// loaded at an earlier time. It's safe to use from several goroutines.
+type History struct {
+	dir     string
+	size    int
+	mu      sync.Mutex
+	entries []HistoryEntry
+	// loaded holds the most recently used historical finders, by filename,
+	// and used lists their filenames, most recent last.
+	loaded map[string]*CrimeFinder
+	used   []string
+}
+
+// NewHistory creates a History that keeps size snapshots in dir, finding
+// the snapshots already there.
+func NewHistory(dir string, size int) (*History, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	history := &History{dir: dir, size: size, loaded: make(map[string]*CrimeFinder)}
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasSuffix(name, ".snapshot") {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimSuffix(name, ".snapshot"), "-", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		loadedAt, err := time.Parse(HISTORY_TIME_FORMAT, parts[0])
+		if err != nil {
+			continue
+		}
+		history.entries = append(history.entries, HistoryEntry{loadedAt, parts[1], filepath.Join(dir, name)})
+	}
+	sort.Slice(history.entries, func(i, j int) bool {
+		return history.entries[i].LoadedAt.Before(history.entries[j].LoadedAt)
+	})
+	return history, history.prune()
+}
+
+// prune removes the oldest snapshots beyond the history's size. The caller
+// must hold the lock, or be creating the history.
+func (history *History) prune() error {
+	for len(history.entries) > history.size {
+		oldest := history.entries[0]
+		if err := os.Remove(oldest.filename); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		history.entries = history.entries[1:]
+		delete(history.loaded, oldest.filename)
+	}
+	return nil
+}
+
+// Entries returns the history's snapshots, oldest first.
+func (history *History) Entries() []HistoryEntry {
+	history.mu.Lock()
+	defer history.mu.Unlock()
+	return append([]HistoryEntry(nil), history.entries...)
+}
+
+// Record saves a snapshot of the finder's data, unless the newest snapshot
+// already has the same data, and removes the oldest snapshots beyond the
+// history's size.
+func (history *History) Record(finder *CrimeFinder) error {
+	history.mu.Lock()
+	defer history.mu.Unlock()
+	if n := len(history.entries); n > 0 && history.entries[n-1].Fingerprint == finder.Fingerprint() {
+		return nil
+	}
+	loadedAt := finder.LoadedAt().UTC()
+	name := loadedAt.Format(HISTORY_TIME_FORMAT) + "-" + finder.Fingerprint() + ".snapshot"
+	filename := filepath.Join(history.dir, name)
+	// The snapshot is written under another name first, so that a partly
+	// written one is never mistaken for history.
+	if err := finder.SaveSnapshot(filename + ".tmp"); err != nil {
+		os.Remove(filename + ".tmp")
+		return err
+	}
+	if err := os.Rename(filename+".tmp", filename); err != nil {
+		return err
+	}
+	history.entries = append(history.entries, HistoryEntry{loadedAt, finder.Fingerprint(), filename})
+	return history.prune()
+}
+
+// At returns a finder with the data as it was at t: the newest snapshot
+// loaded at or before t. It returns ErrNoHistory if there's no snapshot
+// that old.
+func (history *History) At(t time.Time) (*CrimeFinder, error) {
+	history.mu.Lock()
+	defer history.mu.Unlock()
+	i := sort.Search(len(history.entries), func(i int) bool {
+		return history.entries[i].LoadedAt.After(t)
+	})
+	if i == 0 {
+		return nil, ErrNoHistory
+	}
+	entry := history.entries[i-1]
+	finder, ok := history.loaded[entry.filename]
+	if !ok {
+		// Historical finders are read into memory rather than mapped, so
+		// that pruned snapshots' space is freed.
+		f, err := os.Open(entry.filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		read, err := ReadSnapshot(f)
+		if err != nil {
+			return nil, err
+		}
+		// A snapshot doesn't record when it was loaded.
+		read.loadedAt = entry.LoadedAt
+		finder = &read
+		history.loaded[entry.filename] = finder
+	}
+	history.use(entry.filename)
+	return finder, nil
+}
+
+// use marks the finder loaded from filename as the most recently used, and
+// unloads the least recently used finders beyond HISTORY_LOADED. The
+// caller must hold the lock.
+func (history *History) use(filename string) {
+	used := make([]string, 0, len(history.used)+1)
+	for _, name := range history.used {
+		if name != filename {
+			if _, ok := history.loaded[name]; ok {
+				used = append(used, name)
+			}
+		}
+	}
+	used = append(used, filename)
+	for len(used) > HISTORY_LOADED {
+		delete(history.loaded, used[0])
+		used = used[1:]
+	}
+	history.used = used
+}
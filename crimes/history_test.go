@@ -0,0 +1,66 @@
+package radar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	history, err := NewHistory(dir, 2)
+	if err != nil {
+		t.Fatal("Error creating a History: ", err)
+	}
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	before := finder.LoadedAt().Add(-time.Second)
+	if _, err := history.At(before); err != ErrNoHistory {
+		t.Error("An empty history should have no data: ", err)
+	}
+	if err := history.Record(&finder); err != nil {
+		t.Fatal("Error recording the history: ", err)
+	}
+	if err := history.Record(&finder); err != nil || len(history.Entries()) != 1 {
+		t.Error("Recording the same data twice should keep one snapshot: ", len(history.Entries()))
+	}
+	first := finder.LoadedAt()
+	original := finder.Report.Crimes
+
+	for i, row := range []string{
+		"99000001,12/31/2011,23:00:00,Burglary,,,,,45.531,-122.661\n",
+		"99000002,12/31/2011,23:30:00,Burglary,,,,,45.532,-122.662\n",
+	} {
+		time.Sleep(time.Millisecond)
+		if _, _, err := finder.Ingest(strings.NewReader(row), nil); err != nil {
+			t.Fatal("Error ingesting a crime: ", err)
+		}
+		if err := history.Record(&finder); err != nil {
+			t.Fatal("Error recording the history: ", err)
+		}
+		if i == 0 {
+			if old, err := history.At(finder.LoadedAt()); err != nil || old.Report.Crimes != original+1 {
+				t.Error("History has the wrong data: ", err)
+			}
+		}
+	}
+
+	entries := history.Entries()
+	if len(entries) != 2 || !entries[0].LoadedAt.After(first) {
+		t.Fatal("History should keep the two newest snapshots: ", entries)
+	}
+	if _, err := history.At(first); err != ErrNoHistory {
+		t.Error("Pruned data should be gone: ", err)
+	}
+	latest, err := history.At(time.Now())
+	if err != nil || latest.Fingerprint() != finder.Fingerprint() || latest.Version() != finder.Version() {
+		t.Error("History should have the latest data: ", err)
+	}
+
+	reopened, err := NewHistory(dir, 2)
+	if err != nil || len(reopened.Entries()) != 2 || !reopened.Entries()[1].LoadedAt.Equal(entries[1].LoadedAt) {
+		t.Error("Reopened history has the wrong snapshots: ", err)
+	}
+}
@@ -0,0 +1,115 @@
+package radar
+
+import "math"
+
+// The rate of false positives the id index's bloom filter is sized for.
+const BLOOM_FALSE_POSITIVE_RATE = 0.01
+
+// A bloomFilter answers whether an id might be in a set, using a few bits
+// per id. It never says an id that was added is missing, so a miss lets
+// FindByID return without looking at the much larger id index.
+type bloomFilter struct {
+	bits   []uint64
+	hashes int
+}
+
+// newBloomFilter creates a bloomFilter sized for count ids.
+func newBloomFilter(count int) *bloomFilter {
+	if count < 1 {
+		count = 1
+	}
+	size := math.Ceil(-float64(count) * math.Log(BLOOM_FALSE_POSITIVE_RATE) / (math.Ln2 * math.Ln2))
+	hashes := int(math.Round(size / float64(count) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &bloomFilter{bits: make([]uint64, int(size)/64+1), hashes: hashes}
+}
+
+// mix scrambles the bits of x, as in the SplitMix64 generator.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// positions calls visit with the position of each of id's bits, deriving
+// them from two hashes of the id.
+func (filter *bloomFilter) positions(id int64, visit func(bit uint64) bool) {
+	size := uint64(len(filter.bits)) * 64
+	first := mix(uint64(id))
+	second := mix(first^0x9e3779b97f4a7c15) | 1
+	for i := 0; i < filter.hashes; i++ {
+		if !visit((first + uint64(i)*second) % size) {
+			return
+		}
+	}
+}
+
+func (filter *bloomFilter) add(id int64) {
+	filter.positions(id, func(bit uint64) bool {
+		filter.bits[bit/64] |= 1 << (bit % 64)
+		return true
+	})
+}
+
+// mayContain returns false if id was never added, and true if it probably
+// was.
+func (filter *bloomFilter) mayContain(id int64) bool {
+	found := true
+	filter.positions(id, func(bit uint64) bool {
+		found = filter.bits[bit/64]&(1<<(bit%64)) != 0
+		return found
+	})
+	return found
+}
+
+// An idEntry is a crime in the id index and the location it occurred at.
+type idEntry struct {
+	crime    *Crime
+	location *CrimeLocation
+}
+
+// buildIdIndex indexes the finder's crimes by id. When several crimes share
+// an id, the first one is indexed.
+func (finder *CrimeFinder) buildIdIndex() {
+	finder.ids = make(map[int64]idEntry)
+	finder.idFilter = newBloomFilter(finder.Report.Crimes)
+	for _, location := range finder.Locations() {
+		for _, crime := range location.Crimes {
+			if _, exists := finder.ids[crime.Id]; !exists {
+				finder.ids[crime.Id] = idEntry{crime, location}
+				finder.idFilter.add(crime.Id)
+			}
+		}
+	}
+}
+
+// FindByID returns the crime with id and its location, or nil if there is
+// no such crime. Finders that weren't created by NewCrimeFinder or
+// LoadSnapshot, or whose indexes are still being built, don't have an id
+// index, so they search every crime.
+func (finder *CrimeFinder) FindByID(id int64) (*Crime, *CrimeLocation) {
+	ids, idFilter := finder.idIndex()
+	if ids == nil {
+		for _, location := range finder.Locations() {
+			for _, crime := range location.Crimes {
+				if crime.Id == id {
+					return crime, location
+				}
+			}
+		}
+		return nil, nil
+	}
+	if !idFilter.mayContain(id) {
+		return nil, nil
+	}
+	entry, ok := ids[id]
+	if !ok {
+		return nil, nil
+	}
+	return entry.crime, entry.location
+}
@@ -0,0 +1,50 @@
+package radar
+
+import "testing"
+
+func TestBloomFilter(t *testing.T) {
+	filter := newBloomFilter(1000)
+	for id := int64(0); id < 1000; id++ {
+		filter.add(id * 7)
+	}
+	for id := int64(0); id < 1000; id++ {
+		if !filter.mayContain(id * 7) {
+			t.Fatal("Bloom filter is missing an id it was given: ", id*7)
+		}
+	}
+	falsePositives := 0
+	for id := int64(10000); id < 20000; id++ {
+		if filter.mayContain(id * 7) {
+			falsePositives += 1
+		}
+	}
+	if falsePositives > 300 {
+		t.Error("Too many false positives: ", falsePositives)
+	}
+}
+
+func TestCrimeFinderFindByID(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	for _, location := range finder.Locations()[:10] {
+		expected := location.Crimes[0]
+		crime, crimeLocation := finder.FindByID(expected.Id)
+		if crime != expected || crimeLocation != location {
+			t.Error("FindByID returned the wrong crime for ", expected.Id, ": ", crime)
+		}
+	}
+	if crime, location := finder.FindByID(-1); crime != nil || location != nil {
+		t.Error("FindByID should not find a missing id: ", crime)
+	}
+}
+
+func TestCrimeFinderFindByIDWithoutIndex(t *testing.T) {
+	crime := &Crime{Id: 13807517, Date: "12/01/2011", Time: "01:00:00", Type: "Liquor Laws"}
+	location := &CrimeLocation{&Point{45.5, -122.6}, []*Crime{crime}}
+	finder := CrimeFinder{LocationLookup: LocationLookup{GetCoordinateKey(45.5, -122.6): location}}
+	if found, _ := finder.FindByID(13807517); found != crime {
+		t.Error("FindByID did not search a finder without an index: ", found)
+	}
+}
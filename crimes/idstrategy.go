@@ -0,0 +1,88 @@
+package radar
+
+import (
+	"hash/fnv"
+	"math"
+	"strconv"
+)
+
+// An IDStrategy gives each crime in a dataset its id. Dedupe, FindByID and
+// diffing refreshes all go by id, so a dataset without stable record ids
+// needs a strategy that makes them.
+type IDStrategy interface {
+	// ID returns the id of row, which is in the legacy layout. sequence
+	// counts the rows of a load up from one more than the largest id the
+	// finder already has.
+	ID(row CsvRow, sequence int64) (int64, error)
+}
+
+// IDStrategyFunc adapts a function to an IDStrategy.
+type IDStrategyFunc func(row CsvRow, sequence int64) (int64, error)
+
+func (f IDStrategyFunc) ID(row CsvRow, sequence int64) (int64, error) {
+	return f(row, sequence)
+}
+
+// SourceIDs uses the record id in the data, which is how the City's data is
+// loaded.
+var SourceIDs = IDStrategyFunc(func(row CsvRow, sequence int64) (int64, error) {
+	return strconv.ParseInt(row[0], 0, 64)
+})
+
+// HashIDs makes an id from a hash of a crime's date, time, type, address,
+// neighborhood, precinct, district and coordinates. The same record gets the
+// same id each time the data is loaded, so refreshes can be diffed, but a
+// correction to any of those fields looks like a removal and an addition,
+// and identical records share an id.
+var HashIDs = IDStrategyFunc(func(row CsvRow, sequence int64) (int64, error) {
+	hash := fnv.New64a()
+	for _, field := range row[1:NUM_COLUMNS] {
+		hash.Write([]byte(field))
+		hash.Write([]byte{0})
+	}
+	return int64(hash.Sum64() & math.MaxInt64), nil
+})
+
+// SequentialIDs numbers crimes in the order they're loaded. The ids are
+// unique but only stable while the data's rows keep their order, so they
+// suit datasets that are only ever appended to.
+var SequentialIDs = IDStrategyFunc(func(row CsvRow, sequence int64) (int64, error) {
+	return sequence, nil
+})
+
+// IDStrategies holds the built-in id strategies by name.
+var IDStrategies = map[string]IDStrategy{
+	"source":     SourceIDs,
+	"hash":       HashIDs,
+	"sequential": SequentialIDs,
+}
+
+// assignIDs replaces the id column of rows with the ids strategy gives them.
+// A nil strategy leaves the source ids alone. Rows that strategy can't give
+// an id keep the one they have, and are reported when the id is parsed.
+func (finder *CrimeFinder) assignIDs(rows CsvRows, strategy IDStrategy) {
+	if strategy == nil {
+		return
+	}
+	// A load assigns the ids of its batches before the finder's ids are
+	// indexed, so the sequence also carries on from the last batch.
+	sequence := max(finder.sequence, 1)
+	ids, _ := finder.idIndex()
+	for id := range ids {
+		if id >= sequence {
+			sequence = id + 1
+		}
+	}
+	for _, row := range rows {
+		if len(row) < NUM_COLUMNS {
+			continue
+		}
+		id, err := strategy.ID(row, sequence)
+		if err != nil {
+			continue
+		}
+		row[0] = strconv.FormatInt(id, 10)
+		sequence += 1
+	}
+	finder.sequence = sequence
+}
@@ -0,0 +1,62 @@
+package radar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const noIdsData = `Record ID,Report Date,Report Time,Major Offense Type,Address,Neighborhood,Police Precinct,Police District,X Coordinate,Y Coordinate
+,12/01/2011,01:00:00,Liquor Laws,NE WEIDLER ST,LLOYD,PORTLAND PREC NO,690,45.53435699129174,-122.66469510763777
+,07/07/2011,18:30:00,Liquor Laws,NE SCHUYLER ST,ELIOT,PORTLAND PREC NO,590,45.53579735412487,-122.66468312170824
+`
+
+func TestIDStrategies(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "noids.csv")
+	os.WriteFile(filename, []byte(noIdsData), 0644)
+
+	finder, err := NewCrimeFinder(filename)
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	if finder.Report.Crimes != 0 || len(finder.Report.Errors) != 2 {
+		t.Error("Rows without ids should be skipped with source ids: ", finder.Report.Crimes, finder.Report.Errors)
+	}
+
+	finder, err = NewCrimeFinderWithOptions(filename, LoadOptions{IDs: SequentialIDs})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	if finder.Report.Crimes != 2 {
+		t.Fatal("Wrong number of crimes with sequential ids: ", finder.Report.Crimes)
+	}
+	if crime, _ := finder.FindByID(2); crime == nil || crime.Date != "07/07/2011" {
+		t.Error("Wrong crime for sequential id 2: ", crime)
+	}
+	finder.Ingest(strings.NewReader(",12/31/2011,23:00:00,Burglary,,,,,45.5185,-122.6555\n"), nil)
+	if crime, _ := finder.FindByID(3); crime == nil || crime.Type != "Burglary" {
+		t.Error("Ingested crimes should continue the sequence: ", crime)
+	}
+
+	hashed, err := NewCrimeFinderWithOptions(filename, LoadOptions{IDs: HashIDs})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	// Jitter moves crimes after their ids are made, so it doesn't change them.
+	again, err := NewCrimeFinderWithOptions(filename, LoadOptions{IDs: HashIDs, JitterMiles: 0.1})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	changes := again.Diff(&hashed)
+	if hashed.Report.Crimes != 2 || changes.Added != 0 || len(changes.Removed) != 0 {
+		t.Error("Hashed ids should be the same each load: ", hashed.Report.Crimes, changes.Added, changes.Removed)
+	}
+	for _, location := range hashed.Locations() {
+		for _, crime := range location.Crimes {
+			if crime.Id <= 0 {
+				t.Error("Hashed ids should be positive: ", crime.Id)
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package radar
+
+import "log"
+
+// An Offense is one offense reported in an incident.
+type Offense struct {
+	Id   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+// groupByCase merges the crimes at each location that share a case number
+// into the first of them, which lists every offense in Offenses. Crimes
+// without a case number are left alone. It returns the number of crimes
+// that were merged away.
+func (finder *CrimeFinder) groupByCase() int {
+	grouped := 0
+	for _, location := range finder.Locations() {
+		incidents := make(map[string]*Crime)
+		crimes := make([]*Crime, 0, len(location.Crimes))
+		for _, crime := range location.Crimes {
+			if crime.CaseNumber == "" {
+				crimes = append(crimes, crime)
+				continue
+			}
+			offense := Offense{Id: crime.Id, Type: crime.Type}
+			if incident, ok := incidents[crime.CaseNumber]; ok {
+				incident.Offenses = append(incident.Offenses, offense)
+				grouped += 1
+				continue
+			}
+			crime.Offenses = []Offense{offense}
+			incidents[crime.CaseNumber] = crime
+			crimes = append(crimes, crime)
+		}
+		location.Crimes = crimes
+	}
+	if grouped > 0 {
+		log.Printf("Grouped %v crimes into incidents by case number", grouped)
+	}
+	finder.Report.Crimes -= grouped
+	return grouped
+}
@@ -0,0 +1,75 @@
+package radar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A Seattle incident with three offenses and one with a single offense.
+const seattleIncidentData = `Report Number,Offense ID,Offense Start DateTime,Offense End DateTime,Report DateTime,Group A B,Crime Against Category,Offense Parent Group,Offense,Offense Code,Precinct,Sector,Beat,MCPP,100 Block Address,Longitude,Latitude
+2020-044620,12605873663,02/05/2020 10:10:00 AM,,02/05/2020 11:24:31 AM,A,PERSON,ASSAULT OFFENSES,Aggravated Assault,13A,W,Q,Q1,MAGNOLIA,32XX BLOCK OF 23RD AVE W,-122.38591,47.64938
+2020-044620,12605873664,02/05/2020 10:10:00 AM,,02/05/2020 11:24:31 AM,A,PROPERTY,ROBBERY,Robbery,120,W,Q,Q1,MAGNOLIA,32XX BLOCK OF 23RD AVE W,-122.38591,47.64938
+2020-044620,12605873665,02/05/2020 10:10:00 AM,,02/05/2020 11:24:31 AM,A,SOCIETY,WEAPON LAW VIOLATIONS,Weapon Law Violations,520,W,Q,Q1,MAGNOLIA,32XX BLOCK OF 23RD AVE W,-122.38591,47.64938
+2020-044621,12605873666,02/05/2020 11:10:00 AM,,02/05/2020 11:24:31 AM,A,PROPERTY,LARCENY-THEFT,Theft From Motor Vehicle,23F,W,Q,Q1,MAGNOLIA,32XX BLOCK OF 23RD AVE W,-122.38591,47.64938
+`
+
+func TestCrimeFinderGroupByCase(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "seattle.csv")
+	os.WriteFile(filename, []byte(seattleIncidentData), 0644)
+	finder, err := NewCrimeFinderWithOptions(filename, LoadOptions{GroupByCase: true})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	if finder.Report.Crimes != 2 || finder.Report.Grouped != 2 {
+		t.Error("Wrong number of crimes or grouped crimes: ", finder.Report.Crimes, finder.Report.Grouped)
+	}
+	crimes := finder.All().Crimes()
+	if len(crimes) != 2 {
+		t.Fatal("Wrong number of incidents: ", len(crimes))
+	}
+	incident := crimes[0]
+	if incident.CaseNumber != "2020-044620" || len(incident.Offenses) != 3 {
+		t.Error("Incident did not have its offenses: ", incident)
+	}
+	if incident.Offenses[2].Id != 12605873665 || incident.Offenses[2].Type != "Weapon Law Violations" {
+		t.Error("Wrong offense: ", incident.Offenses[2])
+	}
+	if len(crimes[1].Offenses) != 1 {
+		t.Error("A single-offense incident should list its offense: ", crimes[1])
+	}
+}
+
+func TestCrimeFinderWithoutGroupByCase(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "seattle.csv")
+	os.WriteFile(filename, []byte(seattleIncidentData), 0644)
+	finder, err := NewCrimeFinder(filename)
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	crimes := finder.All().Crimes()
+	if len(crimes) != 4 || finder.Report.Grouped != 0 {
+		t.Error("Crimes should not be grouped unless asked: ", len(crimes))
+	}
+	for _, crime := range crimes {
+		if crime.Offenses != nil {
+			t.Error("Crime should not have offenses: ", crime)
+		}
+	}
+}
+
+func TestSearchResultToJsonOffenses(t *testing.T) {
+	crime := &Crime{Id: 1, Date: "02/05/2020", Time: "10:10:00", Type: "Robbery", CaseNumber: "2020-044620"}
+	crime.Offenses = []Offense{{Id: 1, Type: "Robbery"}, {Id: 2, Type: "Aggravated Assault"}}
+	point := Point{47.64938, -122.38591}
+	result := SearchResult{Query: &point, Locations: []*CrimeLocation{{&point, []*Crime{crime}}}}
+	actual, err := result.ToJson()
+	if err != nil {
+		t.Error("ToJson returned an error: ", err)
+	}
+	expected := `"type":"Robbery","case":"2020-044620","offenses":[{"id":1,"type":"Robbery"},{"id":2,"type":"Aggravated Assault"}],"url":"/crimes/1"}`
+	if !strings.Contains(string(actual), expected) {
+		t.Error("ToJson did not include offenses: ", string(actual))
+	}
+}
@@ -0,0 +1,95 @@
+package radar
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// walkJson rewrites the JSON in data a token at a time, and returns it
+// compacted. renameKey is called with each key of an object, and
+// replaceValue with each value that's a string, and what they return takes
+// its place. Either may be nil to leave those alone. Both are given the
+// path of the token: the keys of the fields it's inside, outermost first,
+// and then its own key if it's the value of a field. The elements of an
+// array have the array's path.
+func walkJson(data []byte, renameKey func(path []string, key string) string, replaceValue func(path []string, value string) string) ([]byte, error) {
+	// A frame is an object or array that we're in the middle of. Its count
+	// is the number of keys and values written to it so far, and its key is
+	// the last key written to it, if it's an object.
+	type frame struct {
+		object bool
+		count  int
+		path   []string
+		key    string
+	}
+	stack := []frame{{}}
+	buf := new(bytes.Buffer)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			// The decoder stops at the end of the data even if it's in the
+			// middle of an object or array.
+			if len(stack) > 1 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if delim, ok := token.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			buf.WriteRune(rune(delim))
+			continue
+		}
+		top := &stack[len(stack)-1]
+		isKey := top.object && top.count%2 == 0
+		if top.count > 0 && (isKey || !top.object) {
+			buf.WriteByte(',')
+		} else if top.object && !isKey {
+			buf.WriteByte(':')
+		}
+		top.count += 1
+		path := top.path
+		if top.object && !isKey {
+			// The path is copied so that appending to it never changes the
+			// path of a frame.
+			path = append(path[:len(path):len(path)], top.key)
+		}
+		switch value := token.(type) {
+		case json.Delim:
+			buf.WriteRune(rune(value))
+			stack = append(stack, frame{object: value == '{', path: path})
+			continue
+		case string:
+			if isKey {
+				top.key = value
+				if renameKey != nil {
+					token = renameKey(path, value)
+				}
+			} else if replaceValue != nil {
+				token = replaceValue(path, value)
+			}
+		}
+		encoded, err := json.Marshal(token)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encoded)
+	}
+	return buf.Bytes(), nil
+}
+
+// inJsonField returns true if path, as walkJson gives it, is inside a field
+// called name.
+func inJsonField(path []string, name string) bool {
+	for _, key := range path {
+		if key == name {
+			return true
+		}
+	}
+	return false
+}
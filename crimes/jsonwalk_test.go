@@ -0,0 +1,30 @@
+package radar
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWalkJson(t *testing.T) {
+	data := `{"a": {"b": ["x", {"c": "y"}], "n": 1.50}, "d": null}`
+	keys, values := []string{}, []string{}
+	walked, err := walkJson([]byte(data), func(path []string, key string) string {
+		keys = append(keys, strings.Join(append(path, key), "."))
+		return strings.ToUpper(key)
+	}, func(path []string, value string) string {
+		values = append(values, strings.Join(path, ".")+"="+value)
+		return value + "!"
+	})
+	if err != nil {
+		t.Fatal("Error walking JSON: ", err)
+	}
+	if string(walked) != `{"A":{"B":["x!",{"C":"y!"}],"N":1.50},"D":null}` {
+		t.Error("Wrong JSON: ", string(walked))
+	}
+	if strings.Join(keys, " ") != "a a.b a.b.c a.n d" || strings.Join(values, " ") != "a.b=x a.b.c=y" {
+		t.Error("Wrong paths: ", keys, values)
+	}
+	if _, err := walkJson([]byte(`{"a":`), nil, nil); err == nil {
+		t.Error("Invalid JSON should return an error")
+	}
+}
@@ -0,0 +1,152 @@
+package radar
+
+import (
+	"math"
+	"time"
+
+	"github.com/abrookins/radar/internal/kdtree"
+)
+
+// A backgroundBuild is a build of a finder's indexes that runs while the
+// finder answers searches by scanning its locations. Every copy of the
+// finder shares it, and its indexes are only read once done is closed.
+type backgroundBuild struct {
+	done      chan struct{}
+	took      time.Duration
+	tree      *kdtree.Tree
+	ids       map[int64]idEntry
+	idFilter  *bloomFilter
+	secondary *secondaryIndexes
+}
+
+// ready returns true once the build has finished.
+func (build *backgroundBuild) ready() bool {
+	select {
+	case <-build.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// buildIndexesInBackground starts building the finder's tree, id index and
+// secondary indexes, and returns without waiting for them.
+func (finder *CrimeFinder) buildIndexesInBackground() {
+	build := &backgroundBuild{done: make(chan struct{})}
+	// The build works on a finder of its own, so that it doesn't write to
+	// a finder that's being searched. Its locations are shared, which is
+	// safe because Ingest waits for the build before changing them.
+	shadow := &CrimeFinder{
+		LocationLookup: finder.LocationLookup,
+		Order:          finder.Order,
+		keys:           finder.keys,
+		Report:         LoadReport{Crimes: finder.Report.Crimes},
+	}
+	finder.Tree, finder.ids, finder.idFilter, finder.secondary = nil, nil, nil, nil
+	finder.pending = build
+	go func() {
+		start := time.Now()
+		shadow.buildTree()
+		shadow.buildIdIndex()
+		shadow.buildSecondaryIndexes()
+		build.tree, build.ids, build.idFilter, build.secondary = shadow.Tree, shadow.ids, shadow.idFilter, shadow.secondary
+		build.took = time.Since(start)
+		close(build.done)
+	}()
+}
+
+// IndexesReady returns true if the finder's indexes have been built.
+// Until they are, searches scan every location, which is correct but slow.
+func (finder *CrimeFinder) IndexesReady() bool {
+	return finder.pending == nil || finder.pending.ready()
+}
+
+// WaitForIndexes waits for the finder's indexes to be built and returns
+// how long a background build took, or 0 if they weren't built in the
+// background.
+func (finder *CrimeFinder) WaitForIndexes() time.Duration {
+	if finder.pending == nil {
+		return 0
+	}
+	<-finder.pending.done
+	return finder.pending.took
+}
+
+// tree returns the finder's tree, or nil if it hasn't been built.
+func (finder *CrimeFinder) tree() *kdtree.Tree {
+	if finder.pending != nil && finder.pending.ready() {
+		return finder.pending.tree
+	}
+	return finder.Tree
+}
+
+// idIndex returns the finder's id index and its filter, or nil if they
+// haven't been built.
+func (finder *CrimeFinder) idIndex() (map[int64]idEntry, *bloomFilter) {
+	if finder.pending != nil && finder.pending.ready() {
+		return finder.pending.ids, finder.pending.idFilter
+	}
+	return finder.ids, finder.idFilter
+}
+
+// secondaryIndex returns the finder's secondary indexes, or nil if they
+// haven't been built.
+func (finder *CrimeFinder) secondaryIndex() *secondaryIndexes {
+	if finder.pending != nil && finder.pending.ready() {
+		return finder.pending.secondary
+	}
+	return finder.secondary
+}
+
+// scanNear finds the locations near query by checking every location, for
+// finders without a tree.
+func (finder *CrimeFinder) scanNear(query Point, explanation *Explanation) SearchResult {
+	nearby := SearchResult{Query: &query, Locations: make([]*CrimeLocation, 0), Explanation: explanation}
+	start := time.Now()
+	covered := SearchBounds(query)
+	locations := finder.Locations()
+	for _, location := range locations {
+		if covered.Contains(*location.Point) {
+			nearby.Locations = append(nearby.Locations, location)
+		}
+	}
+	explanation.record("scan", start)
+	if len(nearby.Locations) == 0 {
+		nearby.Diagnostics = finder.diagnose(query)
+	}
+	if explanation != nil {
+		explanation.Index = SCAN_NAME
+		explanation.Candidates = len(locations)
+		explanation.Results = len(nearby.Locations)
+	}
+	return nearby
+}
+
+// scanNearest returns the location nearest query by checking every
+// location, for finders without a tree.
+func (finder *CrimeFinder) scanNearest(query Point) *Point {
+	var nearest *Point
+	best := math.Inf(1)
+	for _, location := range finder.LocationLookup {
+		if distance := query.GreatCircleDistance(location.Point); distance < best {
+			nearest, best = location.Point, distance
+		}
+	}
+	return nearest
+}
+
+// scanBounds returns the box that covers every location by checking each
+// one, for finders without a tree.
+func (finder *CrimeFinder) scanBounds() (Bounds, bool) {
+	bounds, ok := Bounds{}, false
+	for _, location := range finder.LocationLookup {
+		point := *location.Point
+		if !ok {
+			bounds, ok = Bounds{point, point}, true
+			continue
+		}
+		bounds.Min.Lat, bounds.Min.Lng = math.Min(bounds.Min.Lat, point.Lat), math.Min(bounds.Min.Lng, point.Lng)
+		bounds.Max.Lat, bounds.Max.Lng = math.Max(bounds.Max.Lat, point.Lat), math.Max(bounds.Max.Lng, point.Lng)
+	}
+	return bounds, ok
+}
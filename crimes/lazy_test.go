@@ -0,0 +1,73 @@
+package radar
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// nearKeys returns the coordinate keys of the locations a search found, in
+// order, since a scan and the tree find them in different orders.
+func nearKeys(result SearchResult) []string {
+	keys := make([]string, 0, len(result.Locations))
+	for _, location := range result.Locations {
+		keys = append(keys, GetCoordinateKey(location.Point.Lat, location.Point.Lng).String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestLazyIndexes(t *testing.T) {
+	eager, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	lazy, err := NewCrimeFinderWithOptions("sample/crimes.csv", LoadOptions{LazyIndexes: true})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	// A build that never finishes, to search while the indexes are missing.
+	scanning := lazy
+	scanning.pending = &backgroundBuild{done: make(chan struct{})}
+	if scanning.IndexesReady() {
+		t.Error("Indexes should not be ready while they're being built")
+	}
+	query := Point{45.53435699129174, -122.66469510763777}
+	expected, _ := eager.FindNear(query)
+	scanned, err := scanning.FindNearExplained(query)
+	if err != nil {
+		t.Fatal("Scan returned an error: ", err)
+	}
+	if len(expected.Locations) == 0 || !reflect.DeepEqual(nearKeys(scanned), nearKeys(expected)) {
+		t.Error("Scan found different locations: ", len(scanned.Locations), len(expected.Locations))
+	}
+	if scanned.Explanation.Index != SCAN_NAME {
+		t.Error("Scan should be explained as a scan: ", scanned.Explanation.Index)
+	}
+	filter := SearchFilter{Type: "Larceny"}
+	expected, _ = eager.FindNearFiltered(query, filter)
+	filtered, _ := scanning.FindNearFiltered(query, filter)
+	if !reflect.DeepEqual(nearKeys(filtered), nearKeys(expected)) {
+		t.Error("Filtered scan found different locations: ", len(filtered.Locations), len(expected.Locations))
+	}
+	crime := expected.Locations[0].Crimes[0]
+	if found, _ := scanning.FindByID(crime.Id); found == nil || found.Id != crime.Id {
+		t.Error("Crime should be found by id while indexes are built: ", crime.Id)
+	}
+	expectedBounds, _ := eager.Bounds()
+	if bounds, ok := scanning.Bounds(); !ok || bounds != expectedBounds {
+		t.Error("Wrong bounds while indexes are built: ", bounds, expectedBounds)
+	}
+
+	lazy.WaitForIndexes()
+	if !lazy.IndexesReady() {
+		t.Error("Indexes should be ready once built")
+	}
+	indexed, _ := lazy.FindNearExplained(query)
+	if indexed.Explanation.Index != INDEX_NAME {
+		t.Error("Search should use the tree once it's built: ", indexed.Explanation.Index)
+	}
+	if !reflect.DeepEqual(nearKeys(indexed), nearKeys(scanned)) {
+		t.Error("Tree found different locations than the scan: ", len(indexed.Locations), len(scanned.Locations))
+	}
+}
@@ -0,0 +1,87 @@
+package radar
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A LocationHistory is every crime at a location, newest first, with how
+// many occurred each year.
+type LocationHistory struct {
+	Key    CoordinateKey
+	Point  *Point
+	Crimes []*Crime
+	// Years counts the location's crimes by the year they occurred in.
+	// Crimes whose dates can't be parsed aren't counted.
+	Years map[int]int
+	// BaseURL is the URL of the server that the permalinks of crimes point
+	// to. If it's empty, permalinks are paths.
+	BaseURL string
+}
+
+// ParseLocationKey parses the key of a location, its latitude and
+// longitude separated by a comma, like "45.5231,-122.6765", and returns it
+// in the form the finder's LocationLookup uses.
+func ParseLocationKey(key string) (CoordinateKey, error) {
+	latValue, lngValue, _ := strings.Cut(key, ",")
+	lat, err := strconv.ParseFloat(latValue, 64)
+	if err != nil {
+		return CoordinateKey{}, fmt.Errorf("invalid location key: %q", key)
+	}
+	lng, err := strconv.ParseFloat(lngValue, 64)
+	if err != nil {
+		return CoordinateKey{}, fmt.Errorf("invalid location key: %q", key)
+	}
+	point, err := NewPoint(Latitude(lat), Longitude(lng))
+	if err != nil {
+		return CoordinateKey{}, err
+	}
+	return GetCoordinateKey(point.Lat, point.Lng), nil
+}
+
+// LocationHistory returns the history of the location whose key is key, as
+// ParseLocationKey returns it. It returns false if there's no location with
+// the key.
+func (finder *CrimeFinder) LocationHistory(key CoordinateKey) (LocationHistory, bool) {
+	location, exists := finder.LocationLookup[key]
+	if !exists {
+		return LocationHistory{}, false
+	}
+	history := LocationHistory{Key: key, Point: location.Point, Years: make(map[int]int)}
+	history.Crimes = append(make([]*Crime, 0, len(location.Crimes)), location.Crimes...)
+	sort.SliceStable(history.Crimes, func(i, j int) bool { return newer(history.Crimes[i], history.Crimes[j]) })
+	for _, crime := range history.Crimes {
+		if occurred := crimeTime(crime); !occurred.IsZero() {
+			history.Years[occurred.Year()] += 1
+		}
+	}
+	return history, true
+}
+
+// ToJson returns the history marshalled to JSON bytes. Its years are in
+// order, oldest first.
+func (history LocationHistory) ToJson() ([]byte, error) {
+	type yearJson struct {
+		Year  int `json:"year"`
+		Count int `json:"count"`
+	}
+	years := make([]yearJson, 0, len(history.Years))
+	for year, count := range history.Years {
+		years = append(years, yearJson{year, count})
+	}
+	sort.Slice(years, func(i, j int) bool { return years[i].Year < years[j].Year })
+	crimes := make([]crimeJson, 0, len(history.Crimes))
+	for _, crime := range history.Crimes {
+		crimes = append(crimes, newCrimeJson(crime, history.BaseURL))
+	}
+	return json.Marshal(struct {
+		Key    string      `json:"key"`
+		Point  pointJson   `json:"point"`
+		Total  int         `json:"total"`
+		Years  []yearJson  `json:"years"`
+		Crimes []crimeJson `json:"crimes"`
+	}{history.Key.String(), pointJson{history.Point.Lat, history.Point.Lng}, len(history.Crimes), years, crimes})
+}
@@ -0,0 +1,58 @@
+package radar
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLocationKey(t *testing.T) {
+	keys := map[string]string{
+		"45.5231,-122.6765":   "45.5231,-122.6765",
+		"45.52310,-122.67650": "45.5231,-122.6765",
+	}
+	for key, expected := range keys {
+		if actual, err := ParseLocationKey(key); err != nil || actual.String() != expected {
+			t.Error("Wrong key for ", key, ": ", actual, err)
+		}
+	}
+	for _, key := range []string{"", "45.5231", "45.5231,", "a,b", "91,0", "45.5231,-122.6765,1"} {
+		if _, err := ParseLocationKey(key); err == nil {
+			t.Error("Invalid key should be an error: ", key)
+		}
+	}
+}
+
+func TestLocationHistory(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	busiest := finder.Locations()[0]
+	for _, location := range finder.Locations() {
+		if len(location.Crimes) > len(busiest.Crimes) {
+			busiest = location
+		}
+	}
+	first := busiest.Crimes[0]
+	key := GetCoordinateKey(busiest.Point.Lat, busiest.Point.Lng)
+	history, ok := finder.LocationHistory(key)
+	if !ok || len(history.Crimes) != len(busiest.Crimes) || history.Years[2011] != len(busiest.Crimes) {
+		t.Fatal("Wrong history: ", len(history.Crimes), history.Years)
+	}
+	for i := 1; i < len(history.Crimes); i++ {
+		if crimeTime(history.Crimes[i]).After(crimeTime(history.Crimes[i-1])) {
+			t.Error("Crimes should be newest first")
+		}
+	}
+	if busiest.Crimes[0] != first {
+		t.Error("The location's crimes shouldn't be reordered")
+	}
+	if _, ok := finder.LocationHistory(GetCoordinateKey(0, 0)); ok {
+		t.Error("There should be no history where there's no location")
+	}
+
+	data, err := history.ToJson()
+	if err != nil {
+		t.Fatal("ToJson returned an error: ", err)
+	}
+	if !strings.HasPrefix(string(data), `{"key":"`+key.String()+`","point":`) || !strings.Contains(string(data), `"years":[{"year":2011,"count":`) {
+		t.Error("Wrong JSON: ", string(data))
+	}
+}
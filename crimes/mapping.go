@@ -0,0 +1,152 @@
+package radar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// MappedColumns are the names that a ColumnMapping gives the columns of
+// the layout, in its order. They're the names in the header of CSV exports.
+var MappedColumns = [NUM_SCHEMA_COLUMNS]string{
+	"id", "date", "time", "type", "address", "neighborhood", "precinct", "district",
+	"lat", "lng", "weapon", "domestic", "arrest", "case",
+}
+
+// requiredColumns are the columns of the layout that the loader needs.
+var requiredColumns = []int{0, 1, 3, LAT_COLUMN, LNG_COLUMN}
+
+var errMappingColumns = errors.New("a column mapping needs either columns or positions")
+
+// A ColumnMapping describes the columns of a CSV export that none of the
+// built-in schemas do, like another city's, so that it can be loaded. Keys
+// are the MappedColumns, and at least id, date, type, lat and lng must be
+// mapped. If the date and time are in one column, map both to it.
+type ColumnMapping struct {
+	// Name names the mapping in logs. If it's empty, the mapping is
+	// called "custom".
+	Name string `json:"name"`
+	// Columns maps columns to their names in the data's header row, which
+	// are matched ignoring case, spaces and punctuation.
+	Columns map[string]string `json:"columns"`
+	// Positions maps columns to their positions in the rows of data that
+	// has no header row, starting at 0. Only one of Columns and Positions
+	// may be set.
+	Positions map[string]int `json:"positions"`
+}
+
+// mappedColumn returns the position of name in the layout, or -1 if it
+// isn't one of the MappedColumns.
+func mappedColumn(name string) int {
+	for column, mapped := range MappedColumns {
+		if name == mapped {
+			return column
+		}
+	}
+	return -1
+}
+
+// Schema returns a Schema that reads data with the mapping's columns. It
+// returns an error if the mapping is invalid.
+func (mapping ColumnMapping) Schema() (*Schema, error) {
+	if (len(mapping.Columns) == 0) == (len(mapping.Positions) == 0) {
+		return nil, errMappingColumns
+	}
+	schema := &Schema{Name: mapping.Name, Normalize: normalizeMappedRow}
+	if schema.Name == "" {
+		schema.Name = "custom"
+	}
+	if mapping.Positions != nil {
+		schema.Positions = make([]int, NUM_SCHEMA_COLUMNS)
+		for column := range schema.Positions {
+			schema.Positions[column] = -1
+		}
+	}
+	mapped := make(map[int]bool)
+	for name, header := range mapping.Columns {
+		column := mappedColumn(name)
+		if column < 0 {
+			return nil, fmt.Errorf("unknown column: %q", name)
+		}
+		if header == "" {
+			return nil, fmt.Errorf("no header name for the %v column", name)
+		}
+		schema.Columns[column] = []string{header}
+		mapped[column] = true
+	}
+	for name, position := range mapping.Positions {
+		column := mappedColumn(name)
+		if column < 0 {
+			return nil, fmt.Errorf("unknown column: %q", name)
+		}
+		if position < 0 {
+			return nil, fmt.Errorf("invalid position for the %v column: %v", name, position)
+		}
+		schema.Positions[column] = position
+		mapped[column] = true
+	}
+	for _, column := range requiredColumns {
+		if !mapped[column] {
+			return nil, fmt.Errorf("the %v column isn't mapped", MappedColumns[column])
+		}
+	}
+	if !mapped[2] || schema.sameColumn(1, 2) {
+		schema.Normalize = normalizeMappedDateTime
+	}
+	return schema, nil
+}
+
+// sameColumn returns true if the schema maps columns a and b to the same
+// column of the data.
+func (schema *Schema) sameColumn(a int, b int) bool {
+	if schema.Positions != nil {
+		return schema.Positions[a] == schema.Positions[b]
+	}
+	return len(schema.Columns[b]) > 0 && normalizeColumnName(schema.Columns[a][0]) == normalizeColumnName(schema.Columns[b][0])
+}
+
+// normalizeMappedRow converts the date and time of a row read with a
+// ColumnMapping, which may be in any of the layouts the City has used, to
+// the forms used by the legacy data.
+func normalizeMappedRow(row CsvRow) error {
+	date, err := normalizeDate(row[1])
+	if err != nil {
+		return err
+	}
+	row[1] = date
+	t, err := normalizeTime(row[2])
+	if err != nil {
+		return err
+	}
+	row[2] = t
+	return nil
+}
+
+// normalizeMappedDateTime converts the date of a row read with a
+// ColumnMapping that has the date and time in one column, or no time
+// column. A crime whose column only has a date gets no time.
+func normalizeMappedDateTime(row CsvRow) error {
+	if err := normalizeDateTimeColumns(row); err == nil {
+		return nil
+	}
+	row[2] = ""
+	return normalizeMappedRow(row)
+}
+
+// LoadColumnMapping reads a ColumnMapping from a JSON file, like
+//
+//	{"name": "denver", "columns": {"id": "incident_id", "date": "first_occurrence_date", ...}}
+//
+// and returns the Schema it describes.
+func LoadColumnMapping(filename string) (*Schema, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var mapping ColumnMapping
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return nil, err
+	}
+	return mapping.Schema()
+}
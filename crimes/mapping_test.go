@@ -0,0 +1,79 @@
+package radar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestColumnMappingHeader(t *testing.T) {
+	data := `incident_id,offense_type_id,first_occurrence_date,geo_lat,geo_lon,neighborhood_id
+20216000001,burglary-residence,2021-01-04T13:30:00,39.7392,-104.9903,capitol-hill
+20216000002,theft-items-from-vehicle,2021-01-05T08:15:00,,,five-points
+`
+	schema, err := ColumnMapping{Name: "denver", Columns: map[string]string{
+		"id": "incident_id", "type": "Offense Type ID", "date": "first_occurrence_date", "time": "first_occurrence_date",
+		"lat": "geo_lat", "lng": "geo_lon", "neighborhood": "neighborhood_id",
+	}}.Schema()
+	if err != nil {
+		t.Fatal("Schema returned an error: ", err)
+	}
+	rows, rowErrors, err := readCrimesWithSchema(strings.NewReader(data), schema)
+	if err != nil {
+		t.Fatal("readCrimesWithSchema returned an error: ", err)
+	}
+	if len(rows) != 1 || len(rowErrors) != 1 {
+		t.Fatal("Wrong rows: ", rows, rowErrors)
+	}
+	row := rows[0]
+	if row[0] != "20216000001" || row[1] != "01/04/2021" || row[2] != "13:30:00" || row[3] != "burglary-residence" || row[5] != "capitol-hill" || row[LAT_COLUMN] != "39.7392" {
+		t.Error("Row was mapped wrong: ", row)
+	}
+}
+
+func TestColumnMappingPositions(t *testing.T) {
+	data := "Burglary,7,45.5231,-122.6765,2011-12-01,0130\nLarceny,8,45.5343,-122.6646,2011-12-02,\n"
+	schema, err := ColumnMapping{Positions: map[string]int{"type": 0, "id": 1, "lat": 2, "lng": 3, "date": 4, "time": 5}}.Schema()
+	if err != nil {
+		t.Fatal("Schema returned an error: ", err)
+	}
+	finder, err := NewCrimeFinderFromReader(strings.NewReader(data), LoadOptions{Schema: schema})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	if finder.Report.Crimes != 2 {
+		t.Fatal("Data without a header should load every row: ", finder.Report.Crimes)
+	}
+	crime, _ := finder.FindByID(7)
+	if crime == nil || crime.Type != "Burglary" || crime.Date != "12/01/2011" || crime.Time != "01:30:00" {
+		t.Error("Crime was mapped wrong: ", crime)
+	}
+}
+
+func TestColumnMappingInvalid(t *testing.T) {
+	mappings := []ColumnMapping{
+		{},
+		{Columns: map[string]string{"id": "a"}, Positions: map[string]int{"id": 0}},
+		{Columns: map[string]string{"id": "a", "date": "b", "type": "c", "lat": "d"}},
+		{Columns: map[string]string{"id": "a", "date": "b", "type": "c", "lat": "d", "lng": "e", "color": "f"}},
+		{Positions: map[string]int{"id": 0, "date": 1, "type": 2, "lat": 3, "lng": -1}},
+	}
+	for _, mapping := range mappings {
+		if _, err := mapping.Schema(); err == nil {
+			t.Error("Invalid mapping should be an error: ", mapping)
+		}
+	}
+}
+
+func TestLoadColumnMapping(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "mapping.json")
+	os.WriteFile(filename, []byte(`{"columns": {"id": "ID", "date": "When", "type": "What", "lat": "Lat", "lng": "Lon"}}`), 0644)
+	schema, err := LoadColumnMapping(filename)
+	if err != nil {
+		t.Fatal("LoadColumnMapping returned an error: ", err)
+	}
+	if schema.Name != "custom" || schema.Columns[LNG_COLUMN][0] != "Lon" {
+		t.Error("Wrong schema: ", schema)
+	}
+}
@@ -0,0 +1,92 @@
+package radar
+
+import "encoding/json"
+
+// A DistanceMatrix compares every pair of a list of points by distance and
+// by the risk of crime between them, for ranking many destinations at
+// once.
+type DistanceMatrix struct {
+	Points []Point
+	// Risk holds the risk score at each point.
+	Risk []float64
+	// Miles holds the distance between each pair of points, and PathRisk
+	// the average risk score along the straight line between them. The
+	// diagonals are 0.
+	Miles    [][]float64
+	PathRisk [][]float64
+	// Weighted holds the distance between each pair of points weighted by
+	// the risk between them: Miles times 1 plus PathRisk over 100, so that
+	// a mile through the riskiest places counts as two.
+	Weighted [][]float64
+}
+
+// DistanceMatrix compares points under DefaultScorer.
+func (finder *CrimeFinder) DistanceMatrix(points []Point) (DistanceMatrix, error) {
+	return finder.RiskModel().DistanceMatrix(points)
+}
+
+// DistanceMatrix compares every pair of points. The risk between two
+// points is scored as ScoreRoute scores a segment of a route.
+func (model *RiskModel) DistanceMatrix(points []Point) (DistanceMatrix, error) {
+	n := len(points)
+	matrix := DistanceMatrix{Points: points, Risk: make([]float64, n)}
+	matrix.Miles, matrix.PathRisk, matrix.Weighted = squareMatrix(n), squareMatrix(n), squareMatrix(n)
+	for i, point := range points {
+		risk, err := model.RiskScore(point)
+		if err != nil {
+			return matrix, err
+		}
+		matrix.Risk[i] = risk
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			score, err := model.ScoreRoute([]Point{points[i], points[j]})
+			if err != nil {
+				return matrix, err
+			}
+			segment := score.Segments[0]
+			weighted := segment.Miles * (1 + segment.Risk/100)
+			matrix.Miles[i][j], matrix.Miles[j][i] = segment.Miles, segment.Miles
+			matrix.PathRisk[i][j], matrix.PathRisk[j][i] = segment.Risk, segment.Risk
+			matrix.Weighted[i][j], matrix.Weighted[j][i] = weighted, weighted
+		}
+	}
+	return matrix, nil
+}
+
+// squareMatrix returns an n by n matrix of zeroes.
+func squareMatrix(n int) [][]float64 {
+	matrix := make([][]float64, n)
+	for i := range matrix {
+		matrix[i] = make([]float64, n)
+	}
+	return matrix
+}
+
+// roundMatrix returns matrix with its values rounded to places.
+func roundMatrix(matrix [][]float64, places int) [][]float64 {
+	rounded := squareMatrix(len(matrix))
+	for i, row := range matrix {
+		for j, value := range row {
+			rounded[i][j] = roundTo(value, places)
+		}
+	}
+	return rounded
+}
+
+// ToJson returns the matrix marshalled to JSON bytes.
+func (matrix DistanceMatrix) ToJson() ([]byte, error) {
+	points := make([]pointJson, 0, len(matrix.Points))
+	risk := make([]float64, 0, len(matrix.Risk))
+	for i, point := range matrix.Points {
+		points = append(points, pointJson{point.Lat, point.Lng})
+		risk = append(risk, roundTo(matrix.Risk[i], 1))
+	}
+	return json.Marshal(struct {
+		Points   []pointJson `json:"points"`
+		Risk     []float64   `json:"risk"`
+		Miles    [][]float64 `json:"miles"`
+		PathRisk [][]float64 `json:"path_risk"`
+		Weighted [][]float64 `json:"weighted_miles"`
+	}{points, risk, roundMatrix(matrix.Miles, 3), roundMatrix(matrix.PathRisk, 1), roundMatrix(matrix.Weighted, 3)})
+}
@@ -0,0 +1,62 @@
+package radar
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestDistanceMatrix(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	points := []Point{{45.5231, -122.6765}, {45.5262, -122.668}, {45.6, -122.5}}
+	matrix, err := finder.DistanceMatrix(points)
+	if err != nil {
+		t.Fatal("DistanceMatrix returned an error: ", err)
+	}
+	for i := range points {
+		risk, _ := finder.RiskScore(points[i])
+		if matrix.Risk[i] != risk {
+			t.Error("Wrong risk at point ", i, ": ", matrix.Risk[i])
+		}
+		for j := range points {
+			if matrix.Miles[i][j] != matrix.Miles[j][i] || matrix.Weighted[i][j] != matrix.Weighted[j][i] {
+				t.Error("The matrix should be symmetric: ", i, j)
+			}
+			if i == j && (matrix.Miles[i][j] != 0 || matrix.Weighted[i][j] != 0) {
+				t.Error("A point should be no distance from itself")
+			}
+			if matrix.Weighted[i][j] < matrix.Miles[i][j] || matrix.Weighted[i][j] > 2*matrix.Miles[i][j] {
+				t.Error("Weighted miles should be between one and two times the miles: ", matrix.Weighted[i][j], matrix.Miles[i][j])
+			}
+		}
+	}
+	score, _ := finder.ScoreRoute(points[:2])
+	if math.Abs(matrix.Miles[0][1]-score.Miles) > 1e-9 || matrix.PathRisk[0][1] != score.Segments[0].Risk {
+		t.Error("A pair should be scored like a route between them: ", matrix.PathRisk[0][1], score.Segments[0].Risk)
+	}
+	// Downtown is riskier than the edge of the data.
+	if matrix.PathRisk[0][1] <= matrix.PathRisk[1][2] {
+		t.Error("Wrong risk between points: ", matrix.PathRisk)
+	}
+}
+
+func TestDistanceMatrixToJson(t *testing.T) {
+	matrix := DistanceMatrix{
+		Points:   []Point{{45.5, -122.6}, {45.51, -122.6}},
+		Risk:     []float64{10.04, 20},
+		Miles:    [][]float64{{0, 0.69123}, {0.69123, 0}},
+		PathRisk: [][]float64{{0, 50.06}, {50.06, 0}},
+		Weighted: [][]float64{{0, 1.0372}, {1.0372, 0}},
+	}
+	data, err := matrix.ToJson()
+	if err != nil {
+		t.Fatal("ToJson returned an error: ", err)
+	}
+	expected := `{"points":[{"lat":45.5,"lng":-122.6},{"lat":45.51,"lng":-122.6}],"risk":[10,20],"miles":[[0,0.691],[0.691,0]],"path_risk":[[0,50.1],[50.1,0]],"weighted_miles":[[0,1.037],[1.037,0]]}`
+	if string(data) != expected {
+		t.Error("Wrong JSON: ", string(data))
+	}
+	if !json.Valid(data) {
+		t.Error("JSON is not valid")
+	}
+}
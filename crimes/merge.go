@@ -0,0 +1,116 @@
+package radar
+
+import (
+	"fmt"
+	"time"
+)
+
+// Policies for crimes that two merged datasets both have, by id.
+const (
+	// PreferNewer keeps whichever crime has the later date and time. If
+	// they're the same, the second dataset's crime is kept, since sources
+	// are usually merged oldest first.
+	PreferNewer = "newer"
+	// PreferFirst keeps the first dataset's crime.
+	PreferFirst = "first"
+	// KeepBoth keeps both crimes. FindByID finds the first dataset's.
+	KeepBoth = "both"
+)
+
+// MergePolicies lists the policies Merge accepts.
+var MergePolicies = []string{PreferNewer, PreferFirst, KeepBoth}
+
+// A MergeReport describes how two datasets were merged.
+type MergeReport struct {
+	// Conflicts is the number of crimes in the second dataset that had the
+	// id of a crime in the first.
+	Conflicts int
+	// Replaced is the number of conflicts where the second dataset's crime
+	// was kept instead of the first's.
+	Replaced int
+}
+
+// crimeTime returns when crime occurred, or the zero time if its date can't
+// be parsed.
+func crimeTime(crime *Crime) time.Time {
+	occurred, err := time.Parse(DATE_LAYOUT+" 15:04:05", crime.Date+" "+crime.Time)
+	if err != nil {
+		occurred, _ = time.Parse(DATE_LAYOUT, crime.Date)
+	}
+	return occurred
+}
+
+// Merge creates a CrimeFinder with the crimes of first and second. Crimes
+// are matched by id, and policy decides what to do with a crime that both
+// have. The merged finder has copies of the crimes, so neither dataset
+// changes.
+func Merge(first *CrimeFinder, second *CrimeFinder, policy string) (CrimeFinder, MergeReport, error) {
+	report := MergeReport{}
+	switch policy {
+	case PreferNewer, PreferFirst, KeepBoth:
+	default:
+		return CrimeFinder{}, report, fmt.Errorf("unknown merge policy: %q", policy)
+	}
+
+	merged := make([]ChangedCrime, 0, first.Report.Crimes+second.Report.Crimes)
+	positions := make(map[int64]int)
+	for _, location := range first.Locations() {
+		for _, crime := range location.Crimes {
+			if _, exists := positions[crime.Id]; !exists {
+				positions[crime.Id] = len(merged)
+			}
+			merged = append(merged, ChangedCrime{crime, location.Point})
+		}
+	}
+	for _, location := range second.Locations() {
+		for _, crime := range location.Crimes {
+			position, exists := positions[crime.Id]
+			if !exists {
+				positions[crime.Id] = len(merged)
+				merged = append(merged, ChangedCrime{crime, location.Point})
+				continue
+			}
+			report.Conflicts += 1
+			switch policy {
+			case PreferNewer:
+				if !crimeTime(crime).Before(crimeTime(merged[position].Crime)) {
+					merged[position] = ChangedCrime{crime, location.Point}
+					report.Replaced += 1
+				}
+			case KeepBoth:
+				merged = append(merged, ChangedCrime{crime, location.Point})
+			}
+		}
+	}
+
+	for i, entry := range merged {
+		crime := *entry.Crime
+		merged[i].Crime = &crime
+	}
+	return newFinderFromCrimes(merged), report, nil
+}
+
+// newFinderFromCrimes creates a CrimeFinder with the crimes of entries, at
+// their points, and builds its indexes. The finder shares the crimes with
+// whatever else has them.
+func newFinderFromCrimes(entries []ChangedCrime) CrimeFinder {
+	finder := CrimeFinder{LocationLookup: make(LocationLookup), keys: make([]CoordinateKey, 0)}
+	for _, entry := range entries {
+		key := GetCoordinateKey(entry.Point.Lat, entry.Point.Lng)
+		location, exists := finder.LocationLookup[key]
+		if !exists {
+			point := *entry.Point
+			location = &CrimeLocation{&point, make([]*Crime, 0)}
+			finder.LocationLookup[key] = location
+			finder.keys = append(finder.keys, key)
+		}
+		location.Crimes = append(location.Crimes, entry.Crime)
+		if !finder.CrimeTypes.Contains(entry.Crime.Type) {
+			finder.CrimeTypes = append(finder.CrimeTypes, entry.Crime.Type)
+		}
+	}
+	finder.Report.Crimes = len(entries)
+	finder.Report.Locations = len(finder.LocationLookup)
+	finder.buildIndexes()
+	return finder
+}
@@ -0,0 +1,61 @@
+package radar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const mergeData = `13807517,12/02/2011,01:00:00,Disorderly Conduct,,,,,45.53435699129174,-122.66469510763777
+13716403,07/06/2011,18:30:00,Vandalism,,,,,45.53579735412487,-122.66468312170824
+99000001,12/31/2011,23:00:00,Burglary,,,,,45.531,-122.661
+`
+
+func TestMerge(t *testing.T) {
+	first, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	filename := filepath.Join(t.TempDir(), "second.csv")
+	os.WriteFile(filename, []byte(mergeData), 0644)
+	second, err := NewCrimeFinder(filename)
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+
+	// 13807517 is later in the second dataset and 13716403 earlier.
+	merged, report, err := Merge(&first, &second, PreferNewer)
+	if err != nil {
+		t.Fatal("Merge returned an error: ", err)
+	}
+	if merged.Report.Crimes != first.Report.Crimes+1 || report.Conflicts != 2 || report.Replaced != 1 {
+		t.Error("Wrong merge with PreferNewer: ", merged.Report.Crimes, report)
+	}
+	if crime, _ := merged.FindByID(13807517); crime == nil || crime.Type != "Disorderly Conduct" {
+		t.Error("The newer crime should be kept: ", crime)
+	}
+	if crime, _ := merged.FindByID(13716403); crime == nil || crime.Type != "Liquor Laws" {
+		t.Error("The older crime should be dropped: ", crime)
+	}
+	if crime, _ := merged.FindByID(99000001); crime == nil {
+		t.Error("A crime only in the second dataset should be kept")
+	}
+	if crime, _ := first.FindByID(13807517); crime.Type != "Liquor Laws" {
+		t.Error("Merge shouldn't change the datasets: ", crime)
+	}
+
+	merged, report, _ = Merge(&first, &second, PreferFirst)
+	if crime, _ := merged.FindByID(13807517); crime.Type != "Liquor Laws" || report.Replaced != 0 {
+		t.Error("The first dataset's crime should be kept: ", crime, report)
+	}
+
+	merged, _, _ = Merge(&first, &second, KeepBoth)
+	if merged.Report.Crimes != first.Report.Crimes+3 {
+		t.Error("Both crimes should be kept: ", merged.Report.Crimes)
+	}
+
+	if _, _, err := Merge(&first, &second, "last"); err == nil || !strings.Contains(err.Error(), "unknown merge policy") {
+		t.Error("Wrong error for an unknown policy: ", err)
+	}
+}
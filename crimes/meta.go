@@ -0,0 +1,130 @@
+package radar
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// A Summary describes the coverage of a CrimeFinder's data, so clients can
+// validate input and pick a sensible initial map view.
+type Summary struct {
+	// Bounds covers every location, and is nil if there are none.
+	Bounds *Bounds
+	// Centroid is the average of every location, and is nil if there are none.
+	Centroid *Point
+	// FirstDate and LastDate are the earliest and latest crime dates, or
+	// empty if no dates could be parsed.
+	FirstDate string
+	LastDate  string
+	Crimes    int
+	Locations int
+	// CrimesPerSquareMile is the number of crimes per square mile of Bounds.
+	CrimesPerSquareMile float64
+	// MeanCrimesPerLocation and MaxCrimesPerLocation describe how crimes are
+	// spread among locations.
+	MeanCrimesPerLocation float64
+	MaxCrimesPerLocation  int
+	// Categories counts the crimes in each category of the taxonomy.
+	Categories map[string]int
+}
+
+// SquareMiles returns the approximate area of the box in square miles.
+func (b Bounds) SquareMiles() float64 {
+	height := (b.Max.Lat - b.Min.Lat) / (HALF_MILE_LAT * 2)
+	width := (b.Max.Lng - b.Min.Lng) / (HALF_MILE_LNG * 2)
+	return height * width
+}
+
+// Summary returns a Summary of the CrimeFinder's data.
+func (finder *CrimeFinder) Summary() Summary {
+	summary := Summary{Categories: make(map[string]int)}
+	for _, category := range Categories {
+		summary.Categories[category] = 0
+	}
+	var first, last time.Time
+	centroid := Point{}
+	for _, location := range finder.Locations() {
+		summary.Locations += 1
+		summary.Crimes += len(location.Crimes)
+		if len(location.Crimes) > summary.MaxCrimesPerLocation {
+			summary.MaxCrimesPerLocation = len(location.Crimes)
+		}
+		centroid.Lat += location.Point.Lat
+		centroid.Lng += location.Point.Lng
+		for _, crime := range location.Crimes {
+			summary.Categories[Classify(crime.Type).Category] += 1
+			date, err := time.Parse(DATE_LAYOUT, crime.Date)
+			if err != nil {
+				continue
+			}
+			if first.IsZero() || date.Before(first) {
+				first = date
+			}
+			if last.IsZero() || date.After(last) {
+				last = date
+			}
+		}
+	}
+	if summary.Locations == 0 {
+		return summary
+	}
+	centroid.Lat /= float64(summary.Locations)
+	centroid.Lng /= float64(summary.Locations)
+	summary.Centroid = &centroid
+	summary.MeanCrimesPerLocation = float64(summary.Crimes) / float64(summary.Locations)
+	if bounds, ok := finder.Bounds(); ok {
+		summary.Bounds = &bounds
+		if area := bounds.SquareMiles(); area > 0 {
+			summary.CrimesPerSquareMile = float64(summary.Crimes) / area
+		}
+	}
+	if !first.IsZero() {
+		summary.FirstDate = first.Format(DATE_LAYOUT)
+		summary.LastDate = last.Format(DATE_LAYOUT)
+	}
+	return summary
+}
+
+// ToJson returns a Summary marshalled to JSON bytes.
+func (s Summary) ToJson() ([]byte, error) {
+	type boundsJson struct {
+		Min pointJson `json:"min"`
+		Max pointJson `json:"max"`
+	}
+	type dateRangeJson struct {
+		First string `json:"first"`
+		Last  string `json:"last"`
+	}
+	type densityJson struct {
+		CrimesPerSquareMile   float64 `json:"crimes_per_square_mile"`
+		MeanCrimesPerLocation float64 `json:"mean_crimes_per_location"`
+		MaxCrimesPerLocation  int     `json:"max_crimes_per_location"`
+	}
+	out := struct {
+		Bounds     *boundsJson    `json:"bounds"`
+		Centroid   *pointJson     `json:"centroid"`
+		Dates      *dateRangeJson `json:"dates"`
+		Crimes     int            `json:"crimes"`
+		Locations  int            `json:"locations"`
+		Density    densityJson    `json:"density"`
+		Categories map[string]int `json:"categories"`
+	}{
+		Crimes:     s.Crimes,
+		Locations:  s.Locations,
+		Density:    densityJson{s.CrimesPerSquareMile, s.MeanCrimesPerLocation, s.MaxCrimesPerLocation},
+		Categories: s.Categories,
+	}
+	if s.Bounds != nil {
+		out.Bounds = &boundsJson{
+			pointJson{s.Bounds.Min.Lat, s.Bounds.Min.Lng},
+			pointJson{s.Bounds.Max.Lat, s.Bounds.Max.Lng},
+		}
+	}
+	if s.Centroid != nil {
+		out.Centroid = &pointJson{s.Centroid.Lat, s.Centroid.Lng}
+	}
+	if s.FirstDate != "" {
+		out.Dates = &dateRangeJson{s.FirstDate, s.LastDate}
+	}
+	return json.Marshal(out)
+}
@@ -0,0 +1,45 @@
+package radar
+
+import (
+	"testing"
+)
+
+func TestCrimeFinderSummary(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	summary := finder.Summary()
+	if summary.Locations != 224 || summary.Crimes != finder.Report.Crimes {
+		t.Error("Summary has the wrong counts: ", summary.Locations, summary.Crimes)
+	}
+	if summary.Bounds == nil || summary.Centroid == nil || !summary.Bounds.Contains(*summary.Centroid) {
+		t.Error("Summary's centroid should be inside its bounds")
+	}
+	if summary.FirstDate != "01/01/2011" || summary.LastDate != "12/31/2011" {
+		t.Error("Summary has the wrong dates: ", summary.FirstDate, summary.LastDate)
+	}
+	categorized := 0
+	for _, count := range summary.Categories {
+		categorized += count
+	}
+	if categorized != summary.Crimes || summary.Categories[PropertyCategory] == 0 {
+		t.Error("Summary has the wrong category counts: ", summary.Categories)
+	}
+	if summary.CrimesPerSquareMile <= 0 || summary.MeanCrimesPerLocation < 1 || summary.MaxCrimesPerLocation < 1 {
+		t.Error("Summary has the wrong density: ", summary)
+	}
+}
+
+func TestCrimeFinderSummaryEmpty(t *testing.T) {
+	finder := CrimeFinder{}
+	summary := finder.Summary()
+	if summary.Bounds != nil || summary.Centroid != nil || summary.Crimes != 0 {
+		t.Error("Summary of an empty finder should be empty: ", summary)
+	}
+	data, err := summary.ToJson()
+	if err != nil {
+		t.Error("ToJson returned an error: ", err)
+	}
+	expected := `{"bounds":null,"centroid":null,"dates":null,"crimes":0,"locations":0,"density":{"crimes_per_square_mile":0,"mean_crimes_per_location":0,"max_crimes_per_location":0},"categories":{"other":0,"person":0,"property":0,"society":0}}`
+	if string(data) != expected {
+		t.Error("ToJson returned the wrong JSON: ", string(data))
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package radar
+
+import "os"
+
+// mapFile reads the file at filename into memory, on platforms where we
+// don't map files.
+func mapFile(filename string) ([]byte, error) {
+	return os.ReadFile(filename)
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package radar
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the file at filename into memory read-only. The mapping is
+// shared, so processes that map the same file share its pages.
+func mapFile(filename string) ([]byte, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return []byte{}, nil
+	}
+	return syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+}
@@ -0,0 +1,47 @@
+package radar
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ways of naming the fields of JSON output.
+const (
+	// SnakeCase names fields like "nearest_distance_miles". ToJson methods
+	// name fields this way.
+	SnakeCase = "snake"
+	// CamelCase names fields like "nearestDistanceMiles".
+	CamelCase = "camel"
+)
+
+// toCamelCase converts a snake_case name to camelCase.
+func toCamelCase(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// RenameKeys renames the fields of the JSON in data, which is output of a
+// ToJson method, to follow naming: SnakeCase or CamelCase. An empty naming
+// means SnakeCase. The names of enrichments are left as the enrichers gave
+// them.
+func RenameKeys(data []byte, naming string) ([]byte, error) {
+	switch naming {
+	case "", SnakeCase:
+		return data, nil
+	case CamelCase:
+	default:
+		return nil, fmt.Errorf("unknown field naming: %q", naming)
+	}
+
+	return walkJson(data, func(path []string, key string) string {
+		if inJsonField(path, "enrichments") {
+			return key
+		}
+		return toCamelCase(key)
+	}, nil)
+}
@@ -0,0 +1,59 @@
+package radar
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToCamelCase(t *testing.T) {
+	names := map[string]string{
+		"id":                     "id",
+		"nearest_distance_miles": "nearestDistanceMiles",
+		"p50":                    "p50",
+	}
+	for name, expected := range names {
+		if actual := toCamelCase(name); actual != expected {
+			t.Error("Wrong camelCase name for ", name, ": ", actual)
+		}
+	}
+}
+
+func TestRenameKeys(t *testing.T) {
+	data := `{"query":{"lat":45.1,"lng":-122.3},"locations":[],"diagnostics":{"nearest_distance_miles":1.5,"outside_coverage":false,"bounds":null},"list":[1,"a_b",{"max_miles":2}]}`
+	actual, err := RenameKeys([]byte(data), CamelCase)
+	if err != nil {
+		t.Fatal("RenameKeys returned an error: ", err)
+	}
+	expected := `{"query":{"lat":45.1,"lng":-122.3},"locations":[],"diagnostics":{"nearestDistanceMiles":1.5,"outsideCoverage":false,"bounds":null},"list":[1,"a_b",{"maxMiles":2}]}`
+	if string(actual) != expected {
+		t.Error("Wrong renamed JSON: ", string(actual))
+	}
+	if !json.Valid(actual) {
+		t.Error("Renamed JSON is not valid")
+	}
+}
+
+func TestRenameKeysKeepsEnrichmentNames(t *testing.T) {
+	data := `{"id":1,"enrichments":{"census_tract":"23.03","nested":{"walk_score":88}},"nodes_visited":3}`
+	actual, err := RenameKeys([]byte(data), CamelCase)
+	if err != nil {
+		t.Fatal("RenameKeys returned an error: ", err)
+	}
+	expected := `{"id":1,"enrichments":{"census_tract":"23.03","nested":{"walk_score":88}},"nodesVisited":3}`
+	if string(actual) != expected {
+		t.Error("Wrong renamed JSON: ", string(actual))
+	}
+}
+
+func TestRenameKeysSnakeCase(t *testing.T) {
+	data := []byte(`{"nodes_visited":3}`)
+	for _, naming := range []string{"", SnakeCase} {
+		actual, err := RenameKeys(data, naming)
+		if err != nil || string(actual) != string(data) {
+			t.Error("Snake case naming should not change the JSON: ", string(actual), err)
+		}
+	}
+	if _, err := RenameKeys(data, "kebab"); err == nil {
+		t.Error("RenameKeys should return an error for an unknown naming")
+	}
+}
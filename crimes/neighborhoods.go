@@ -0,0 +1,202 @@
+package radar
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// A Neighborhood is a named area that crimes are counted in, like one of the
+// City's neighborhoods.
+type Neighborhood struct {
+	Name     string
+	Polygons []Polygon
+	// bounds covers the neighborhood's outer rings, so that points outside
+	// it are skipped without checking its polygons.
+	bounds Bounds
+}
+
+// Contains reports whether point is inside any of the neighborhood's
+// polygons.
+func (neighborhood Neighborhood) Contains(point Point) bool {
+	if !neighborhood.bounds.Contains(point) {
+		return false
+	}
+	for _, polygon := range neighborhood.Polygons {
+		if polygon.Contains(point) {
+			return true
+		}
+	}
+	return false
+}
+
+// polygonBounds returns the box that covers the outer rings of polygons.
+func polygonBounds(polygons []Polygon) Bounds {
+	bounds := Bounds{Point{math.Inf(1), math.Inf(1)}, Point{math.Inf(-1), math.Inf(-1)}}
+	for _, polygon := range polygons {
+		if len(polygon) == 0 {
+			continue
+		}
+		for _, p := range polygon[0] {
+			bounds.Min.Lat, bounds.Min.Lng = math.Min(bounds.Min.Lat, p.Lat), math.Min(bounds.Min.Lng, p.Lng)
+			bounds.Max.Lat, bounds.Max.Lng = math.Max(bounds.Max.Lat, p.Lat), math.Max(bounds.Max.Lng, p.Lng)
+		}
+	}
+	return bounds
+}
+
+// LoadNeighborhoods reads neighborhoods from a GeoJSON FeatureCollection of
+// Polygon and MultiPolygon features. Each feature's "name" property names
+// its neighborhood, and must be there and different from the others'.
+func LoadNeighborhoods(filename string) ([]Neighborhood, error) {
+	areas, err := readGeoJSONAreas(filename)
+	if err != nil {
+		return nil, err
+	}
+	neighborhoods := make([]Neighborhood, 0, len(areas))
+	names := make(map[string]bool)
+	for i, area := range areas {
+		name, _ := area.Properties["name"].(string)
+		if name == "" {
+			return nil, fmt.Errorf("feature %v has no name", i)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("feature %v: duplicate neighborhood %q", i, name)
+		}
+		names[name] = true
+		neighborhoods = append(neighborhoods, Neighborhood{name, area.Polygons, polygonBounds(area.Polygons)})
+	}
+	return neighborhoods, nil
+}
+
+// A NeighborhoodCount is a neighborhood and the crimes inside it.
+type NeighborhoodCount struct {
+	Neighborhood Neighborhood
+	Crimes       int
+	// Categories counts the crimes in each category of the taxonomy.
+	Categories map[string]int
+	// Population is the number of the neighborhood's residents, or 0 if it
+	// isn't known.
+	Population int
+}
+
+// NeighborhoodCounts is a count of the crimes in each of a list of
+// neighborhoods, in the same order.
+type NeighborhoodCounts []NeighborhoodCount
+
+// WithPopulations returns the counts with the population of each
+// neighborhood in populations, so that they have per-capita rates.
+func (counts NeighborhoodCounts) WithPopulations(populations Populations) NeighborhoodCounts {
+	counted := make(NeighborhoodCounts, len(counts))
+	for i, count := range counts {
+		count.Population = populations.Of(count.Neighborhood.Name)
+		counted[i] = count
+	}
+	return counted
+}
+
+// CountNeighborhoods counts the finder's crimes in each of neighborhoods. A
+// crime inside neighborhoods that overlap is counted in each of them.
+func (finder *CrimeFinder) CountNeighborhoods(neighborhoods []Neighborhood) NeighborhoodCounts {
+	counts := make(NeighborhoodCounts, len(neighborhoods))
+	for i, neighborhood := range neighborhoods {
+		counts[i] = NeighborhoodCount{Neighborhood: neighborhood, Categories: make(map[string]int)}
+		for _, category := range Categories {
+			counts[i].Categories[category] = 0
+		}
+	}
+	for _, location := range finder.Locations() {
+		for i, neighborhood := range neighborhoods {
+			if !neighborhood.Contains(*location.Point) {
+				continue
+			}
+			counts[i].Crimes += len(location.Crimes)
+			for _, crime := range location.Crimes {
+				counts[i].Categories[Classify(crime.Type).Category] += 1
+			}
+		}
+	}
+	return counts
+}
+
+// geometryJson is a GeoJSON Polygon or MultiPolygon, with each point in
+// longitude, latitude order.
+type geometryJson struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// neighborhoodGeometry returns the GeoJSON geometry of the neighborhood: a
+// Polygon if it has one, and a MultiPolygon if it has more.
+func neighborhoodGeometry(neighborhood Neighborhood) geometryJson {
+	polygons := make([][][][2]float64, 0, len(neighborhood.Polygons))
+	for _, polygon := range neighborhood.Polygons {
+		rings := make([][][2]float64, 0, len(polygon))
+		for _, ring := range polygon {
+			positions := make([][2]float64, 0, len(ring))
+			for _, p := range ring {
+				positions = append(positions, [2]float64{p.Lng, p.Lat})
+			}
+			rings = append(rings, positions)
+		}
+		polygons = append(polygons, rings)
+	}
+	if len(polygons) == 1 {
+		return geometryJson{"Polygon", polygons[0]}
+	}
+	return geometryJson{"MultiPolygon", polygons}
+}
+
+// neighborhoodFeatureJson is a neighborhood as a GeoJSON Feature.
+type neighborhoodFeatureJson struct {
+	Type       string       `json:"type"`
+	Geometry   geometryJson `json:"geometry"`
+	Properties struct {
+		Name       string         `json:"name"`
+		Crimes     int            `json:"crimes"`
+		Categories map[string]int `json:"categories"`
+		// The population and rates are left out when the population isn't
+		// known.
+		Population        int                `json:"population,omitempty"`
+		CrimesPer1000     *float64           `json:"crimes_per_1000,omitempty"`
+		CategoriesPer1000 map[string]float64 `json:"categories_per_1000,omitempty"`
+	} `json:"properties"`
+}
+
+// featureJson returns the count as a GeoJSON Feature of its neighborhood's
+// boundary, with its name and counts as properties, and its population and
+// the rates of its counts per PER_CAPITA_RESIDENTS if it's known.
+func (count NeighborhoodCount) featureJson() neighborhoodFeatureJson {
+	feature := neighborhoodFeatureJson{Type: "Feature", Geometry: neighborhoodGeometry(count.Neighborhood)}
+	feature.Properties.Name = count.Neighborhood.Name
+	feature.Properties.Crimes = count.Crimes
+	feature.Properties.Categories = count.Categories
+	if count.Population > 0 {
+		rate := perCapita(count.Crimes, count.Population)
+		feature.Properties.Population = count.Population
+		feature.Properties.CrimesPer1000 = &rate
+		feature.Properties.CategoriesPer1000 = make(map[string]float64, len(count.Categories))
+		for category, crimes := range count.Categories {
+			feature.Properties.CategoriesPer1000[category] = perCapita(crimes, count.Population)
+		}
+	}
+	return feature
+}
+
+// ToJson returns the count marshalled to JSON bytes, as a GeoJSON Feature.
+func (count NeighborhoodCount) ToJson() ([]byte, error) {
+	return json.Marshal(count.featureJson())
+}
+
+// ToJson returns the counts marshalled to JSON bytes, as a GeoJSON
+// FeatureCollection that can be drawn as a choropleth.
+func (counts NeighborhoodCounts) ToJson() ([]byte, error) {
+	features := make([]neighborhoodFeatureJson, 0, len(counts))
+	for _, count := range counts {
+		features = append(features, count.featureJson())
+	}
+	return json.Marshal(struct {
+		Type     string                    `json:"type"`
+		Features []neighborhoodFeatureJson `json:"features"`
+	}{"FeatureCollection", features})
+}
@@ -0,0 +1,106 @@
+package radar
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// neighborhoodsGeoJSON has a square neighborhood around the Lloyd District
+// and one of two squares far from any crimes.
+const neighborhoodsGeoJSON = `{"type":"FeatureCollection","features":[
+	{"type":"Feature","properties":{"name":"Lloyd"},
+	 "geometry":{"type":"Polygon","coordinates":[[[-122.67,45.53],[-122.67,45.54],[-122.66,45.54],[-122.66,45.53],[-122.67,45.53]]]}},
+	{"type":"Feature","properties":{"name":"Nowhere"},
+	 "geometry":{"type":"MultiPolygon","coordinates":[[[[-122.1,45.1],[-122.1,45.11],[-122.09,45.11],[-122.1,45.1]]],[[[-122.0,45.1],[-122.0,45.11],[-121.99,45.11],[-122.0,45.1]]]]}}
+]}`
+
+func loadTestNeighborhoods(t *testing.T) []Neighborhood {
+	filename := filepath.Join(t.TempDir(), "neighborhoods.geojson")
+	os.WriteFile(filename, []byte(neighborhoodsGeoJSON), 0644)
+	neighborhoods, err := LoadNeighborhoods(filename)
+	if err != nil {
+		t.Fatal("LoadNeighborhoods returned an error: ", err)
+	}
+	return neighborhoods
+}
+
+func TestCountNeighborhoods(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	neighborhoods := loadTestNeighborhoods(t)
+	counts := finder.CountNeighborhoods(neighborhoods)
+	if len(counts) != 2 || counts[0].Neighborhood.Name != "Lloyd" {
+		t.Fatal("Wrong counts: ", counts)
+	}
+	box := Bounds{Point{45.53, -122.67}, Point{45.54, -122.66}}
+	expected := len(finder.Select(ExportFilter{Bounds: &box}).Crimes())
+	if expected == 0 || counts[0].Crimes != expected {
+		t.Error("Wrong number of crimes in the neighborhood: ", counts[0].Crimes, " != ", expected)
+	}
+	total := 0
+	for _, count := range counts[0].Categories {
+		total += count
+	}
+	if total != counts[0].Crimes {
+		t.Error("Categories should add up to the crimes: ", counts[0].Categories)
+	}
+	if counts[1].Crimes != 0 || counts[1].Categories[PropertyCategory] != 0 {
+		t.Error("A neighborhood without crimes should have none: ", counts[1])
+	}
+}
+
+func TestNeighborhoodCountsToJson(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	resp, err := finder.CountNeighborhoods(loadTestNeighborhoods(t)).ToJson()
+	if err != nil {
+		t.Fatal("ToJson returned an error: ", err)
+	}
+	var collection struct {
+		Type     string
+		Features []struct {
+			Type     string
+			Geometry struct {
+				Type        string
+				Coordinates json.RawMessage
+			}
+			Properties struct {
+				Name   string
+				Crimes int
+			}
+		}
+	}
+	json.Unmarshal(resp, &collection)
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 2 {
+		t.Fatal("Wrong FeatureCollection: ", string(resp))
+	}
+	lloyd, nowhere := collection.Features[0], collection.Features[1]
+	if lloyd.Type != "Feature" || lloyd.Geometry.Type != "Polygon" || lloyd.Properties.Name != "Lloyd" || lloyd.Properties.Crimes == 0 {
+		t.Error("Wrong feature: ", lloyd)
+	}
+	if string(lloyd.Geometry.Coordinates) != "[[[-122.67,45.53],[-122.67,45.54],[-122.66,45.54],[-122.66,45.53],[-122.67,45.53]]]" {
+		t.Error("Coordinates should be in longitude, latitude order: ", string(lloyd.Geometry.Coordinates))
+	}
+	if nowhere.Geometry.Type != "MultiPolygon" {
+		t.Error("A neighborhood of several polygons should be a MultiPolygon: ", nowhere.Geometry.Type)
+	}
+}
+
+func TestLoadNeighborhoodsInvalid(t *testing.T) {
+	square := `{"type":"Feature","properties":%v,"geometry":{"type":"Polygon","coordinates":[[[-122.67,45.53],[-122.67,45.54],[-122.66,45.54],[-122.67,45.53]]]}}`
+	collections := []string{
+		`{"type":"FeatureCollection","features":[` + fmt.Sprintf(square, `{}`) + `]}`,
+		`{"type":"FeatureCollection","features":[` + fmt.Sprintf(square, `{"name":"A"}`) + `,` + fmt.Sprintf(square, `{"name":"A"}`) + `]}`,
+	}
+	filename := filepath.Join(t.TempDir(), "neighborhoods.geojson")
+	for _, collection := range collections {
+		os.WriteFile(filename, []byte(collection), 0644)
+		if _, err := LoadNeighborhoods(filename); err == nil {
+			t.Error("Invalid neighborhoods should be an error: ", collection)
+		}
+	}
+}
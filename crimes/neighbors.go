@@ -0,0 +1,112 @@
+package radar
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// The most buckets a Histogram will have. Distances past the last bucket are
+// counted in it.
+const MAX_HISTOGRAM_BUCKETS = 200
+
+// A HistogramBucket counts the values in [Min, Max).
+type HistogramBucket struct {
+	Min   float64
+	Max   float64
+	Count int
+}
+
+// A Histogram describes the distribution of a set of distances in miles.
+type Histogram struct {
+	Buckets []HistogramBucket
+	// Percentiles maps a percentile (e.g. 50) to the distance at or below
+	// which that percent of the distances fall.
+	Percentiles map[int]float64
+	Count       int
+}
+
+// The percentiles included in a Histogram.
+var histogramPercentiles = []int{10, 25, 50, 75, 90, 95, 99}
+
+// NearestNeighborDistances returns, for every location, the distance in miles
+// to the nearest other location. Operators can use these to choose cell sizes
+// and search radii for a new city's data.
+func (finder *CrimeFinder) NearestNeighborDistances() []float64 {
+	distances := make([]float64, 0)
+	tree := finder.tree()
+	if tree == nil {
+		return distances
+	}
+	for _, node := range tree.Nodes() {
+		neighbor := tree.NearestOther(node)
+		if neighbor == nil {
+			continue
+		}
+		p := nodePoint(node)
+		q := nodePoint(neighbor)
+		distances = append(distances, p.GreatCircleDistance(&q))
+	}
+	return distances
+}
+
+// NewHistogram sorts distances into buckets that are bucketSize miles wide.
+func NewHistogram(distances []float64, bucketSize float64) Histogram {
+	histogram := Histogram{Buckets: make([]HistogramBucket, 0), Percentiles: make(map[int]float64)}
+	histogram.Count = len(distances)
+	if len(distances) == 0 || bucketSize <= 0 {
+		return histogram
+	}
+	sorted := append([]float64{}, distances...)
+	sort.Float64s(sorted)
+
+	numBuckets := int(sorted[len(sorted)-1]/bucketSize) + 1
+	if numBuckets > MAX_HISTOGRAM_BUCKETS {
+		numBuckets = MAX_HISTOGRAM_BUCKETS
+	}
+	for i := 0; i < numBuckets; i++ {
+		bucket := HistogramBucket{Min: float64(i) * bucketSize, Max: float64(i+1) * bucketSize}
+		histogram.Buckets = append(histogram.Buckets, bucket)
+	}
+	for _, distance := range sorted {
+		i := int(distance / bucketSize)
+		if i >= numBuckets {
+			i = numBuckets - 1
+		}
+		histogram.Buckets[i].Count += 1
+	}
+	// The last bucket holds everything past the others.
+	histogram.Buckets[numBuckets-1].Max = math.Max(histogram.Buckets[numBuckets-1].Max, sorted[len(sorted)-1])
+
+	for _, p := range histogramPercentiles {
+		i := int(math.Ceil(float64(p)/100*float64(len(sorted)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		histogram.Percentiles[p] = sorted[i]
+	}
+	return histogram
+}
+
+// ToJson returns a Histogram marshalled to JSON bytes.
+func (h Histogram) ToJson() ([]byte, error) {
+	type bucketJson struct {
+		Min   float64 `json:"min_miles"`
+		Max   float64 `json:"max_miles"`
+		Count int     `json:"count"`
+	}
+	buckets := make([]bucketJson, 0, len(h.Buckets))
+	for _, b := range h.Buckets {
+		buckets = append(buckets, bucketJson{b.Min, b.Max, b.Count})
+	}
+	percentiles := make(map[string]float64)
+	for p, distance := range h.Percentiles {
+		percentiles[fmt.Sprintf("p%v", p)] = distance
+	}
+	return json.Marshal(struct {
+		Count       int                `json:"count"`
+		Percentiles map[string]float64 `json:"percentiles_miles"`
+		Buckets     []bucketJson       `json:"buckets"`
+	}{h.Count, percentiles, buckets})
+}
@@ -0,0 +1,73 @@
+package radar
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCrimeFinderNearestNeighborDistances(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	distances := finder.NearestNeighborDistances()
+	if len(distances) != 224 {
+		t.Error("Wrong number of distances: ", len(distances))
+	}
+	for _, d := range distances {
+		// Locations are unique, so no neighbor is at distance 0.
+		if d <= 0 || d > 5 {
+			t.Error("Distance is out of range: ", d)
+			break
+		}
+	}
+	if len((&CrimeFinder{}).NearestNeighborDistances()) != 0 {
+		t.Error("A finder without data should have no distances")
+	}
+}
+
+func TestNewHistogram(t *testing.T) {
+	distances := []float64{0.01, 0.02, 0.06, 0.11, 0.12, 0.5, 3.0}
+	h := NewHistogram(distances, 0.05)
+	if h.Count != 7 {
+		t.Error("Histogram has the wrong count: ", h.Count)
+	}
+	if len(h.Buckets) != 61 {
+		t.Error("Histogram has the wrong number of buckets: ", len(h.Buckets))
+	}
+	if h.Buckets[0].Count != 2 || h.Buckets[1].Count != 1 || h.Buckets[2].Count != 2 {
+		t.Error("Histogram counted the wrong buckets: ", h.Buckets[:3])
+	}
+	if h.Percentiles[50] != 0.11 || h.Percentiles[99] != 3.0 {
+		t.Error("Histogram has the wrong percentiles: ", h.Percentiles)
+	}
+}
+
+func TestNewHistogramMaxBuckets(t *testing.T) {
+	h := NewHistogram([]float64{0.001, 100}, 0.001)
+	if len(h.Buckets) != MAX_HISTOGRAM_BUCKETS {
+		t.Error("Histogram has too many buckets: ", len(h.Buckets))
+	}
+	last := h.Buckets[len(h.Buckets)-1]
+	if last.Count != 1 || last.Max != 100 {
+		t.Error("The last bucket should hold values past the others: ", last)
+	}
+}
+
+func TestHistogramToJson(t *testing.T) {
+	data, err := NewHistogram([]float64{0.01, 0.06}, 0.05).ToJson()
+	if err != nil {
+		t.Error("ToJson returned an error: ", err)
+	}
+	var body struct {
+		Count       int
+		Percentiles map[string]float64 `json:"percentiles_miles"`
+		Buckets     []struct {
+			Min   float64 `json:"min_miles"`
+			Count int
+		}
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatal("ToJson returned invalid JSON: ", err)
+	}
+	if body.Count != 2 || body.Percentiles["p50"] != 0.01 || len(body.Buckets) != 2 || body.Buckets[1].Min != 0.05 {
+		t.Error("ToJson returned the wrong JSON: ", string(data))
+	}
+}
@@ -0,0 +1,219 @@
+package radar
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// A MonthlyCount is the number of crimes in a neighborhood in a month.
+type MonthlyCount struct {
+	// Month is a month in MONTH_LAYOUT, like "2011-07".
+	Month        string
+	Neighborhood string
+	Crimes       int
+	// Population is the number of the neighborhood's residents, or 0 if it
+	// isn't known.
+	Population int
+}
+
+// MonthlyCounts are the counts of crimes in each neighborhood in each
+// month, by month and then by neighborhood.
+type MonthlyCounts []MonthlyCount
+
+// WithPopulations returns the counts with the population of each
+// neighborhood in populations, so that they have per-capita rates.
+func (counts MonthlyCounts) WithPopulations(populations Populations) MonthlyCounts {
+	counted := make(MonthlyCounts, len(counts))
+	for i, count := range counts {
+		count.Population = populations.Of(count.Neighborhood)
+		counted[i] = count
+	}
+	return counted
+}
+
+// CountNeighborhoodMonths counts the finder's crimes in each of
+// neighborhoods in each month. Without neighborhoods, crimes are counted in
+// the neighborhood their row names, and those without one are left out.
+// Crimes whose dates can't be parsed are left out too, and a neighborhood
+// has no count in a month it had no crimes in.
+func (finder *CrimeFinder) CountNeighborhoodMonths(neighborhoods []Neighborhood) MonthlyCounts {
+	type key struct{ month, neighborhood string }
+	counted := make(map[key]int)
+	for _, location := range finder.Locations() {
+		var inside []string
+		for _, neighborhood := range neighborhoods {
+			if neighborhood.Contains(*location.Point) {
+				inside = append(inside, neighborhood.Name)
+			}
+		}
+		for _, crime := range location.Crimes {
+			month := crimeMonth(crime)
+			if month == "" {
+				continue
+			}
+			if len(neighborhoods) == 0 {
+				if crime.Neighborhood != "" {
+					counted[key{month, crime.Neighborhood}] += 1
+				}
+				continue
+			}
+			for _, name := range inside {
+				counted[key{month, name}] += 1
+			}
+		}
+	}
+	counts := make(MonthlyCounts, 0, len(counted))
+	for k, crimes := range counted {
+		counts = append(counts, MonthlyCount{Month: k.month, Neighborhood: k.neighborhood, Crimes: crimes})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Month != counts[j].Month {
+			return counts[i].Month < counts[j].Month
+		}
+		return counts[i].Neighborhood < counts[j].Neighborhood
+	})
+	return counts
+}
+
+// WriteCsv writes the counts to w as CSV, with a header of month,
+// neighborhood and crimes. If any count's population is known, a
+// crimes_per_1000 column follows with the rate of crimes per
+// PER_CAPITA_RESIDENTS, which is empty for the neighborhoods whose
+// population isn't.
+func (counts MonthlyCounts) WriteCsv(w io.Writer) error {
+	perCapitaColumn := false
+	for _, count := range counts {
+		perCapitaColumn = perCapitaColumn || count.Population > 0
+	}
+	writer := csv.NewWriter(w)
+	header := []string{"month", "neighborhood", "crimes"}
+	if perCapitaColumn {
+		header = append(header, "crimes_per_1000")
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, count := range counts {
+		record := []string{count.Month, count.Neighborhood, strconv.Itoa(count.Crimes)}
+		if perCapitaColumn {
+			rate := ""
+			if count.Population > 0 {
+				rate = strconv.FormatFloat(perCapita(count.Crimes, count.Population), 'f', -1, 64)
+			}
+			record = append(record, rate)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// A CellCount is a cell of the grid and the crimes inside it.
+type CellCount struct {
+	Cell   GridCell
+	Crimes int
+	// Weight is the total weight of the crimes, if they were weighed, and
+	// otherwise 0.
+	Weight float64
+}
+
+// CellCounts are the counts of crimes in a list of cells of the grid.
+type CellCounts []CellCount
+
+// FindHotspots returns the cells of the grid that are hotspots across all
+// of the finder's crimes, as Delta finds them in a period, busiest first.
+func (finder *CrimeFinder) FindHotspots() CellCounts {
+	return finder.findHotspots(nil)
+}
+
+// FindRecentHotspots is FindHotspots with each crime weighed by how recent
+// it is: a crime counts for half as much for every halfLife between it and
+// the finder's latest crime, so that a recent wave of crimes makes a
+// hotspot where as many crimes years ago wouldn't. Ages are measured from
+// the latest crime rather than now so that data that's no longer updated
+// still has hotspots. Cells are hotspots, and ordered, by the weight of
+// their crimes. Crimes whose dates can't be parsed count fully.
+func (finder *CrimeFinder) FindRecentHotspots(halfLife time.Duration) CellCounts {
+	var latest time.Time
+	for _, location := range finder.Locations() {
+		for _, crime := range location.Crimes {
+			if occurred, err := time.Parse(DATE_LAYOUT, crime.Date); err == nil && occurred.After(latest) {
+				latest = occurred
+			}
+		}
+	}
+	decay := TimeDecay{HalfLife: halfLife, At: latest}
+	return finder.findHotspots(func(location *CrimeLocation, crime *Crime) float64 {
+		return decay.Weight(*location.Point, location, crime)
+	})
+}
+
+// findHotspots returns the cells that are hotspots when each crime weighs
+// what weigh says, or 1 if weigh is nil, busiest first.
+func (finder *CrimeFinder) findHotspots(weigh func(location *CrimeLocation, crime *Crime) float64) CellCounts {
+	counts := make(map[GridCell]int)
+	weights := make(map[GridCell]float64)
+	for _, location := range finder.Locations() {
+		cell := GridCellOf(*location.Point)
+		counts[cell] += len(location.Crimes)
+		for _, crime := range location.Crimes {
+			if weigh == nil {
+				weights[cell] += 1
+			} else {
+				weights[cell] += weigh(location, crime)
+			}
+		}
+	}
+	spots := make(CellCounts, 0)
+	for cell := range hotspots(weights) {
+		spot := CellCount{Cell: cell, Crimes: counts[cell]}
+		if weigh != nil {
+			spot.Weight = weights[cell]
+		}
+		spots = append(spots, spot)
+	}
+	sort.Slice(spots, func(i, j int) bool {
+		if x, y := weights[spots[i].Cell], weights[spots[j].Cell]; x != y {
+			return x > y
+		}
+		if spots[i].Cell.Row != spots[j].Cell.Row {
+			return spots[i].Cell.Row < spots[j].Cell.Row
+		}
+		return spots[i].Cell.Col < spots[j].Cell.Col
+	})
+	return spots
+}
+
+// ToJson returns the counts marshalled to JSON bytes, as a GeoJSON
+// FeatureCollection of the cells' squares, with each cell's name, crimes
+// and the weight of its crimes, if they were weighed, as properties.
+func (counts CellCounts) ToJson() ([]byte, error) {
+	type properties struct {
+		Cell   string  `json:"cell"`
+		Crimes int     `json:"crimes"`
+		Weight float64 `json:"weight,omitempty"`
+	}
+	type feature struct {
+		Type       string       `json:"type"`
+		Geometry   geometryJson `json:"geometry"`
+		Properties properties   `json:"properties"`
+	}
+	features := make([]feature, 0, len(counts))
+	for _, count := range counts {
+		b := count.Cell.Bounds()
+		ring := [][2]float64{
+			{b.Min.Lng, b.Min.Lat}, {b.Max.Lng, b.Min.Lat}, {b.Max.Lng, b.Max.Lat}, {b.Min.Lng, b.Max.Lat}, {b.Min.Lng, b.Min.Lat},
+		}
+		features = append(features, feature{"Feature", geometryJson{"Polygon", [][][2]float64{ring}}, properties{count.Cell.String(), count.Crimes, count.Weight}})
+	}
+	return json.Marshal(struct {
+		Type     string    `json:"type"`
+		Features []feature `json:"features"`
+	}{"FeatureCollection", features})
+}
@@ -0,0 +1,134 @@
+package radar
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCountNeighborhoodMonths(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	counts := finder.CountNeighborhoodMonths(nil)
+	june, _ := ParseDateRange("2011-06")
+	december, _ := ParseDateRange("2011-12")
+	delta := finder.Delta(ParseArea("downtown"), june, december)
+	found := map[string]int{}
+	for i, count := range counts {
+		if i > 0 && (counts[i-1].Month > count.Month || counts[i-1].Month == count.Month && counts[i-1].Neighborhood >= count.Neighborhood) {
+			t.Error("Counts should be by month, then neighborhood: ", counts[i-1], count)
+		}
+		if strings.EqualFold(count.Neighborhood, "downtown") {
+			found[count.Month] += count.Crimes
+		}
+	}
+	if found["2011-06"] != delta.TotalA || found["2011-12"] != delta.TotalB {
+		t.Error("Wrong counts for downtown: ", found["2011-06"], found["2011-12"])
+	}
+
+	bounded := finder.CountNeighborhoodMonths(loadTestNeighborhoods(t))
+	total := 0
+	for _, count := range bounded {
+		if count.Neighborhood != "Lloyd" {
+			t.Error("Only neighborhoods with crimes should be counted: ", count)
+		}
+		total += count.Crimes
+	}
+	if total == 0 || total != finder.CountNeighborhoods(loadTestNeighborhoods(t))[0].Crimes {
+		t.Error("Wrong total for a neighborhood's boundary: ", total)
+	}
+
+	var csv strings.Builder
+	if err := bounded.WriteCsv(&csv); err != nil {
+		t.Fatal("WriteCsv returned an error: ", err)
+	}
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	if lines[0] != "month,neighborhood,crimes" || len(lines) != len(bounded)+1 || !strings.HasPrefix(lines[1], bounded[0].Month+",Lloyd,") {
+		t.Error("Wrong CSV: ", csv.String())
+	}
+}
+
+func TestFindHotspots(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	spots := finder.FindHotspots()
+	if len(spots) == 0 {
+		t.Fatal("There should be hotspots")
+	}
+	for i, spot := range spots {
+		if spot.Crimes < HOTSPOT_MIN_CRIMES || i > 0 && spot.Crimes > spots[i-1].Crimes {
+			t.Error("Hotspots should be busiest first: ", spots)
+			break
+		}
+	}
+	resp, err := spots.ToJson()
+	if err != nil {
+		t.Fatal("ToJson returned an error: ", err)
+	}
+	var collection struct {
+		Type     string
+		Features []struct {
+			Geometry struct {
+				Type        string
+				Coordinates [][][2]float64
+			}
+			Properties struct {
+				Cell   string
+				Crimes int
+			}
+		}
+	}
+	json.Unmarshal(resp, &collection)
+	first := collection.Features[0]
+	center := spots[0].Cell.Center()
+	ring := first.Geometry.Coordinates[0]
+	if collection.Type != "FeatureCollection" || len(collection.Features) != len(spots) || first.Properties.Cell != spots[0].Cell.String() || first.Properties.Crimes != spots[0].Crimes {
+		t.Error("Wrong GeoJSON: ", string(resp))
+	}
+	if first.Geometry.Type != "Polygon" || len(ring) != 5 || ring[0] != ring[4] || !(ring[0][0] < center.Lng && ring[2][0] > center.Lng && ring[0][1] < center.Lat && ring[2][1] > center.Lat) {
+		t.Error("Wrong cell polygon: ", first.Geometry)
+	}
+}
+
+func TestFindRecentHotspots(t *testing.T) {
+	// A cell with many crimes years ago, one with fewer crimes recently,
+	// and quiet cells around them.
+	csv := "Record ID,Report Date,Report Time,Major Offense Type,Address,Neighborhood,Police Precinct,Police District,X Coordinate,Y Coordinate\n"
+	id := 0
+	row := func(date string, lat float64) {
+		id += 1
+		csv += fmt.Sprintf("%d,%v,01:00:00,Burglary,,LLOYD,PORTLAND PREC NO,690,%v,-122.6646\n", id, date, lat)
+	}
+	for i := 0; i < 10; i++ {
+		row("03/01/2005", 45.40)
+	}
+	for i := 0; i < 6; i++ {
+		row("12/01/2011", 45.50)
+	}
+	for i := 1; i <= 8; i++ {
+		row("12/01/2011", 45.50+float64(i)*0.02)
+	}
+	finder, err := NewCrimeFinderFromReader(strings.NewReader(csv), LoadOptions{})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	old, recent := GridCellOf(Point{45.40, -122.6646}), GridCellOf(Point{45.50, -122.6646})
+
+	if spots := finder.FindHotspots(); len(spots) != 2 || spots[0].Cell != old || spots[1].Cell != recent || spots[0].Weight != 0 {
+		t.Error("Without weights, both busy cells should be hotspots: ", spots)
+	}
+	spots := finder.FindRecentHotspots(90 * 24 * time.Hour)
+	if len(spots) != 1 || spots[0].Cell != recent || spots[0].Crimes != 6 || spots[0].Weight != 6 {
+		t.Error("Only the recently busy cell should be a hotspot: ", spots)
+	}
+	resp, _ := spots.ToJson()
+	if !strings.Contains(string(resp), `"weight":6`) {
+		t.Error("Weighed hotspots should have their weight: ", string(resp))
+	}
+}
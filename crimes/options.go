@@ -0,0 +1,101 @@
+package radar
+
+import (
+	"math"
+	"strconv"
+)
+
+// Orders in which the coordinate columns of CSV data may appear.
+const (
+	// DetectCoordinateOrder guesses the order from the values in the data.
+	DetectCoordinateOrder = iota
+	// LatLngOrder means latitude comes before longitude, like the City's data.
+	LatLngOrder
+	// LngLatOrder means longitude comes before latitude.
+	LngLatOrder
+)
+
+// LoadOptions control how a CrimeFinder loads CSV data. The zero value
+// loads the City's data.
+type LoadOptions struct {
+	// Schema describes the columns of the data. If it is nil, the schema is
+	// detected from the data's header.
+	Schema *Schema
+	// CoordinateOrder is the order of the coordinate columns.
+	CoordinateOrder int
+	// Enrichers run, in order, on every crime after the data loads.
+	Enrichers []Enricher
+	// JitterMiles, if set, moves every location up to this many miles in a
+	// random direction and drops addresses, for publishing data that must
+	// not pinpoint a crime to a residence.
+	JitterMiles float64
+	// Retention decides which crimes to keep.
+	Retention RetentionPolicy
+	// ExclusionZones are areas whose crimes are removed or aggregated.
+	ExclusionZones []ExclusionZone
+	// GroupByCase merges crimes at a location that share a case number
+	// into one incident with an entry in Offenses for each crime.
+	GroupByCase bool
+	// Progress, if set, is called as the data loads, for reporting
+	// progress on large files.
+	Progress func(LoadProgress)
+	// Geocoder, if set, finds the coordinates of crimes that the data has
+	// an address but no coordinates for.
+	Geocoder Geocoder
+	// IDs gives crimes their ids. If it is nil, the record id in the data
+	// is used.
+	IDs IDStrategy
+	// Shard, if set, is the area this finder serves in a deployment that
+	// splits the data between servers. Crimes outside it aren't loaded.
+	Shard *Bounds
+	// BadRows decides what happens to rows that can't be loaded.
+	BadRows BadRowPolicy
+	// LazyIndexes builds the finder's indexes in the background, so that
+	// it can be searched as soon as its data loads. Until they're built,
+	// searches scan every location.
+	LazyIndexes bool
+	// Workers is the number of goroutines that rows are checked and parsed
+	// into crimes on. If it is 0, there's one for each CPU.
+	Workers int
+}
+
+// detectCoordinateOrder guesses the order of the coordinate columns in rows.
+// A latitude is never more than 90 degrees from the equator, so a column with
+// values beyond that must be longitude. If the values don't tell us, we
+// assume the data is in latitude, longitude order.
+func detectCoordinateOrder(rows CsvRows) int {
+	latLng, lngLat := 0, 0
+	for _, row := range rows {
+		first, err := strconv.ParseFloat(row[LAT_COLUMN], 64)
+		if err != nil {
+			continue
+		}
+		second, err := strconv.ParseFloat(row[LNG_COLUMN], 64)
+		if err != nil {
+			continue
+		}
+		if math.Abs(first) > 90 && math.Abs(second) <= 90 {
+			lngLat += 1
+		} else if math.Abs(second) > 90 && math.Abs(first) <= 90 {
+			latLng += 1
+		}
+	}
+	if lngLat > latLng {
+		return LngLatOrder
+	}
+	return LatLngOrder
+}
+
+// applyCoordinateOrder puts the coordinate columns of rows in latitude,
+// longitude order and returns the order the data was in.
+func applyCoordinateOrder(rows CsvRows, order int) int {
+	if order == DetectCoordinateOrder {
+		order = detectCoordinateOrder(rows)
+	}
+	if order == LngLatOrder {
+		for _, row := range rows {
+			row[LAT_COLUMN], row[LNG_COLUMN] = row[LNG_COLUMN], row[LAT_COLUMN]
+		}
+	}
+	return order
+}
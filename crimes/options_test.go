@@ -0,0 +1,56 @@
+package radar
+
+import (
+	"testing"
+)
+
+func lngLatRows() CsvRows {
+	return CsvRows{
+		{"1", "05/27/2011", "08:35:00", "Liquor Laws", "", "", "", "", "-122.66468312170824", "45.53579735412487"},
+		{"2", "05/27/2011", "08:35:00", "Liquor Laws", "", "", "", "", "-122.66469510763777", "45.53435699129174"},
+	}
+}
+
+func TestDetectCoordinateOrderLatLng(t *testing.T) {
+	rows := CsvRows{
+		{"1", "05/27/2011", "08:35:00", "Liquor Laws", "", "", "", "", "45.53579735412487", "-122.66468312170824"},
+	}
+	if detectCoordinateOrder(rows) != LatLngOrder {
+		t.Error("Should have detected latitude, longitude order")
+	}
+}
+
+func TestDetectCoordinateOrderLngLat(t *testing.T) {
+	if detectCoordinateOrder(lngLatRows()) != LngLatOrder {
+		t.Error("Should have detected longitude, latitude order")
+	}
+}
+
+func TestDetectCoordinateOrderAmbiguous(t *testing.T) {
+	// Both values could be a latitude.
+	rows := CsvRows{
+		{"1", "05/27/2011", "08:35:00", "Liquor Laws", "", "", "", "", "-73.9", "40.7"},
+	}
+	if detectCoordinateOrder(rows) != LatLngOrder {
+		t.Error("Should have fallen back to latitude, longitude order")
+	}
+}
+
+func TestApplyCoordinateOrderSwapsColumns(t *testing.T) {
+	rows := lngLatRows()
+	order := applyCoordinateOrder(rows, DetectCoordinateOrder)
+	if order != LngLatOrder {
+		t.Error("Wrong coordinate order: ", order)
+	}
+	if rows[0][8] != "45.53579735412487" || rows[0][9] != "-122.66468312170824" {
+		t.Error("Coordinate columns were not swapped: ", rows[0])
+	}
+}
+
+func TestApplyCoordinateOrderOverride(t *testing.T) {
+	rows := lngLatRows()
+	order := applyCoordinateOrder(rows, LatLngOrder)
+	if order != LatLngOrder || rows[0][8] != "-122.66468312170824" {
+		t.Error("The coordinate order option should override detection")
+	}
+}
@@ -0,0 +1,73 @@
+package radar
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// workers returns the number of goroutines that rows are parsed on.
+func (options LoadOptions) workers() int {
+	if options.Workers > 0 {
+		return options.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// parallelChunks splits n items into contiguous chunks, one for each of up
+// to workers goroutines, calls process with the start and end of each, and
+// waits for them all.
+func parallelChunks(n int, workers int, process func(start int, end int)) {
+	if n == 0 {
+		return
+	}
+	size := (n + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < n; start += size {
+		wg.Add(1)
+		go func(start int, end int) {
+			defer wg.Done()
+			process(start, end)
+		}(start, min(start+size, n))
+	}
+	wg.Wait()
+}
+
+// A parsedRow is a row turned into a crime at a point, or the error that
+// kept it from being one.
+type parsedRow struct {
+	key   CoordinateKey
+	point Point
+	crime *Crime
+	// shard is the shard of key that adds the crime to its location.
+	shard int
+	err   error
+}
+
+// parseRow parses row, in the legacy layout, into a crime at a point, and
+// finds which of shards its location is in.
+func parseRow(row CsvRow, shards int) parsedRow {
+	if len(row) < NUM_COLUMNS {
+		return parsedRow{err: errShortRow}
+	}
+	point, err := pointFromRow(row)
+	if err != nil {
+		return parsedRow{err: err}
+	}
+	id, err := strconv.ParseInt(row[0], 0, 64)
+	if err != nil {
+		return parsedRow{err: err}
+	}
+	crime := &Crime{Id: id, Date: row[1], Time: row[2], Type: row[3]}
+	setAttributesFromRow(crime, row)
+	key := GetCoordinateKey(point.Lat, point.Lng)
+	return parsedRow{key, point, crime, key.shard(shards), nil}
+}
+
+// shard returns which of shards the key is in, so that the locations of
+// keys can be split between goroutines that never share one.
+func (key CoordinateKey) shard(shards int) int {
+	hash := uint64(key.Lat)*0x9e3779b97f4a7c15 ^ uint64(key.Lng)
+	hash ^= hash >> 29
+	return int(hash % uint64(shards))
+}
@@ -0,0 +1,89 @@
+package radar
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParallelChunks(t *testing.T) {
+	for _, n := range []int{0, 1, 7, 100} {
+		for _, workers := range []int{1, 3, 8, 200} {
+			seen := make([]int, n)
+			parallelChunks(n, workers, func(start int, end int) {
+				for i := start; i < end; i++ {
+					seen[i] += 1
+				}
+			})
+			for i, count := range seen {
+				if count != 1 {
+					t.Error("Item ", i, " of ", n, " was processed ", count, " times by ", workers, " workers")
+				}
+			}
+		}
+	}
+}
+
+func TestLoadWithWorkers(t *testing.T) {
+	serial, err := NewCrimeFinderWithOptions("sample/crimes.csv", LoadOptions{Workers: 1})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	ids := func(finder CrimeFinder) [][]int64 {
+		locations := make([][]int64, 0)
+		for _, location := range finder.Locations() {
+			crimes := make([]int64, 0, len(location.Crimes))
+			for _, crime := range location.Crimes {
+				crimes = append(crimes, crime.Id)
+			}
+			locations = append(locations, crimes)
+		}
+		return locations
+	}
+	for _, workers := range []int{2, 7, 16} {
+		parallel, err := NewCrimeFinderWithOptions("sample/crimes.csv", LoadOptions{Workers: workers})
+		if err != nil {
+			t.Fatal("Error creating CrimeFinder: ", err)
+		}
+		if parallel.Fingerprint() != serial.Fingerprint() || parallel.Report.Crimes != serial.Report.Crimes || parallel.Report.Locations != serial.Report.Locations {
+			t.Error("Workers loaded different data: ", workers, parallel.Report.Crimes)
+		}
+		if !reflect.DeepEqual(parallel.CrimeTypes, serial.CrimeTypes) {
+			t.Error("Crime types should be in the order of the rows: ", parallel.CrimeTypes)
+		}
+		if !reflect.DeepEqual(ids(parallel), ids(serial)) {
+			t.Error("Locations and their crimes should be in the order of the rows with ", workers, " workers")
+		}
+	}
+}
+
+func TestIngestWithWorkers(t *testing.T) {
+	finder, err := NewCrimeFinderWithOptions("sample/crimes.csv", LoadOptions{Workers: 4})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	location := finder.Locations()[0]
+	existing := len(location.Crimes)
+	lat, lng := location.Point.Lat, location.Point.Lng
+	types := len(finder.CrimeTypes)
+	row := func(id string, crimeType string, lat float64, lng float64) string {
+		return strings.Join([]string{id, "12/31/2011", "23:00:00", crimeType, "", "", "", "", fmt.Sprint(lat), fmt.Sprint(lng)}, ",") + "\n"
+	}
+	data := row("99000001", "Burglary", lat, lng) + row("99000002", "Piracy", 45.6, -122.7) +
+		row("99000003", "Arson", lat, lng) + row("nope", "Arson", lat, lng)
+	added, rowErrors, err := finder.Ingest(strings.NewReader(data), nil)
+	if err != nil {
+		t.Fatal("Ingest returned an error: ", err)
+	}
+	if len(rowErrors) != 1 || len(added.Locations) != 2 || len(added.Locations[0].Crimes) != 2 || added.Locations[1].Crimes[0].Id != 99000002 {
+		t.Error("Ingest added the wrong crimes: ", added.Locations, rowErrors)
+	}
+	crimes := location.Crimes
+	if len(crimes) != existing+2 || crimes[existing].Id != 99000001 || crimes[existing+1].Id != 99000003 {
+		t.Error("Crimes should be added to an existing location in order: ", crimes[existing:])
+	}
+	if len(finder.CrimeTypes) != types+1 || finder.CrimeTypes[types] != "Piracy" {
+		t.Error("A new type should be added once: ", finder.CrimeTypes)
+	}
+}
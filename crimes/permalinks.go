@@ -0,0 +1,16 @@
+package radar
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The path of a crime's permalink on a server, given the crime's id.
+const PERMALINK_PATH = "/crimes/%v"
+
+// Permalink returns the stable URL of the crime with id on the server at
+// baseURL, like "https://radar.example.com/crimes/13807517". If baseURL is
+// empty, it returns the path, "/crimes/13807517".
+func Permalink(baseURL string, id int64) string {
+	return strings.TrimSuffix(baseURL, "/") + fmt.Sprintf(PERMALINK_PATH, id)
+}
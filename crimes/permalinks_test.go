@@ -0,0 +1,28 @@
+package radar
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPermalink(t *testing.T) {
+	if link := Permalink("", 13807517); link != "/crimes/13807517" {
+		t.Error("Wrong permalink without a base URL: ", link)
+	}
+	if link := Permalink("https://radar.example.com/", 13807517); link != "https://radar.example.com/crimes/13807517" {
+		t.Error("Wrong permalink: ", link)
+	}
+}
+
+func TestSearchResultToJsonPermalinks(t *testing.T) {
+	crime := &Crime{Id: 1, Date: "1/1/2013", Time: "04:30", Type: "Burglary"}
+	point := Point{45.1, -122.3}
+	result := SearchResult{Query: &point, Locations: []*CrimeLocation{{&point, []*Crime{crime}}}, BaseURL: "https://radar.example.com"}
+	actual, err := result.ToJson()
+	if err != nil {
+		t.Error("ToJson returned an error: ", err)
+	}
+	if !strings.Contains(string(actual), `"url":"https://radar.example.com/crimes/1"`) {
+		t.Error("ToJson did not include the permalink: ", string(actual))
+	}
+}
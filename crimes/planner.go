@@ -0,0 +1,119 @@
+package radar
+
+import (
+	"math"
+	"time"
+
+	"github.com/abrookins/radar/internal/roaring"
+)
+
+// Plans that a filtered search can run with.
+const (
+	// SpatialPlan finds the crimes in the search's area with the kd-tree,
+	// then intersects them with the crimes that match the filter. It's the
+	// better plan when few crimes are nearby.
+	SpatialPlan = "spatial"
+	// AttributePlan intersects the crimes that match each part of the
+	// filter, then keeps those inside the search's area. It's the better
+	// plan when few crimes match the filter.
+	AttributePlan = "attributes"
+)
+
+// A queryPlan is the plan chosen for a filtered search and the estimates
+// it was chosen by: how many crimes are in the search's area, and at most
+// how many match the filter.
+type queryPlan struct {
+	driver     string
+	spatial    int
+	attributes int
+}
+
+// plan chooses how to run a search from query for the crimes in every one
+// of bitmaps, by whichever of the kd-tree or the secondary indexes should
+// give fewer crimes to check.
+func (finder *CrimeFinder) plan(query Point, bitmaps []*roaring.Bitmap) queryPlan {
+	plan := queryPlan{driver: SpatialPlan, spatial: finder.secondaryIndex().estimateNear(query), attributes: math.MaxInt}
+	for _, bitmap := range bitmaps {
+		plan.attributes = min(plan.attributes, bitmap.Cardinality())
+	}
+	if plan.attributes < plan.spatial {
+		plan.driver = AttributePlan
+	}
+	return plan
+}
+
+// estimateNear estimates how many crimes a search from query covers from
+// the density grid, assuming the crimes of each cell are spread evenly
+// across it.
+func (indexes *secondaryIndexes) estimateNear(query Point) int {
+	covered := SearchBounds(query)
+	low, high := GridCellOf(covered.Min), GridCellOf(covered.Max)
+	estimate := 0.0
+	for row := low.Row; row <= high.Row; row++ {
+		for col := low.Col; col <= high.Col; col++ {
+			cell := GridCell{row, col}
+			count, ok := indexes.density[cell]
+			if !ok {
+				continue
+			}
+			bounds := cell.Bounds()
+			height := math.Min(covered.Max.Lat, bounds.Max.Lat) - math.Max(covered.Min.Lat, bounds.Min.Lat)
+			width := math.Min(covered.Max.Lng, bounds.Max.Lng) - math.Max(covered.Min.Lng, bounds.Min.Lng)
+			if height > 0 && width > 0 {
+				estimate += float64(count) * height / HALF_MILE_LAT * width / HALF_MILE_LNG
+			}
+		}
+	}
+	return int(math.Ceil(estimate))
+}
+
+// findByAttributes runs an AttributePlan: the crimes that match every
+// bitmap and are inside the area of a search from query, in position
+// order.
+func (finder *CrimeFinder) findByAttributes(query Point, bitmaps []*roaring.Bitmap, explanation *Explanation) *roaring.Bitmap {
+	start := time.Now()
+	matched := intersectAll(bitmaps)
+	explanation.record("intersect", start)
+	start = time.Now()
+	covered := SearchBounds(query)
+	found := roaring.New()
+	matched.Each(func(position uint32) bool {
+		if covered.Contains(*finder.secondaryIndex().crimes[position].location.Point) {
+			found.Add(position)
+		}
+		return true
+	})
+	explanation.record("filter", start)
+	if explanation != nil {
+		explanation.Candidates = matched.Cardinality()
+	}
+	return found
+}
+
+// findBySpatial runs a SpatialPlan: the crimes in the area of a search from
+// query that match every bitmap.
+func (finder *CrimeFinder) findBySpatial(query Point, bitmaps []*roaring.Bitmap, explanation *Explanation) (*roaring.Bitmap, error) {
+	start := time.Now()
+	near, visited, err := finder.near(query)
+	if err != nil {
+		return nil, err
+	}
+	explanation.record("search", start)
+	start = time.Now()
+	found := intersectAll(append([]*roaring.Bitmap{near}, bitmaps...))
+	explanation.record("intersect", start)
+	if explanation != nil {
+		explanation.NodesVisited = visited
+		explanation.Candidates = near.Cardinality()
+	}
+	return found, nil
+}
+
+// intersectAll returns the values in every one of bitmaps.
+func intersectAll(bitmaps []*roaring.Bitmap) *roaring.Bitmap {
+	matched := bitmaps[0]
+	for _, bitmap := range bitmaps[1:] {
+		matched = matched.And(bitmap)
+	}
+	return matched
+}
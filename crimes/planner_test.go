@@ -0,0 +1,43 @@
+package radar
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEstimateNear(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	query := Point{45.53435699129174, -122.66469510763777}
+	near, _, _ := finder.near(query)
+	estimate := finder.secondary.estimateNear(query)
+	if estimate == 0 || estimate > finder.Report.Crimes || estimate > near.Cardinality()*4 {
+		t.Error("Wrong estimate of the crimes near query: ", estimate, near.Cardinality())
+	}
+	if estimate := finder.secondary.estimateNear(Point{0, 0}); estimate != 0 {
+		t.Error("There should be no crimes far from the data: ", estimate)
+	}
+}
+
+func TestPlansAgree(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	for _, location := range finder.Locations()[:20] {
+		query := *location.Point
+		for _, crimeType := range []string{"Liquor Laws", "Larceny", "Homicide"} {
+			bitmaps := finder.secondary.match(SearchFilter{Type: crimeType})
+			spatial, err := finder.findBySpatial(query, bitmaps, nil)
+			if err != nil {
+				t.Fatal("Spatial plan returned an error: ", err)
+			}
+			attributes := finder.findByAttributes(query, bitmaps, nil)
+			if !reflect.DeepEqual(spatial.ToArray(), attributes.ToArray()) {
+				t.Error("Plans found different crimes: ", query, crimeType)
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package radar
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// POI_NEARBY_MILES is the farthest a point of interest can be from a place
+// to be given as context for it.
+const POI_NEARBY_MILES = 0.5
+
+// A POI is a point of interest, like a school, a park or a transit stop,
+// that explains what's near a place that's scored or reported on.
+type POI struct {
+	Name  string
+	Kind  string
+	Point Point
+}
+
+// POIs are the points of interest loaded from a file.
+type POIs []POI
+
+// LoadPOIs reads points of interest from a CSV file with a header, whose
+// "name", "kind", "lat" and "lng" columns are each POI's name, its kind,
+// like "school" or "transit", and its coordinates. Other columns are
+// ignored.
+func LoadPOIs(filename string) (POIs, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid points of interest %v: %v", filename, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("invalid points of interest %v: no header", filename)
+	}
+	columns := map[string]int{"name": -1, "kind": -1, "lat": -1, "lng": -1}
+	for i, column := range rows[0] {
+		if _, ok := columns[strings.ToLower(strings.TrimSpace(column))]; ok {
+			columns[strings.ToLower(strings.TrimSpace(column))] = i
+		}
+	}
+	for _, i := range columns {
+		if i < 0 {
+			return nil, fmt.Errorf("invalid points of interest %v: the header needs name, kind, lat and lng columns", filename)
+		}
+	}
+	pois := make(POIs, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(row[columns["lat"]]), 64)
+		lng, lngErr := strconv.ParseFloat(strings.TrimSpace(row[columns["lng"]]), 64)
+		if latErr != nil || lngErr != nil {
+			return nil, fmt.Errorf("invalid points of interest %v: line %v: invalid coordinates", filename, i+2)
+		}
+		point, err := NewPoint(Latitude(lat), Longitude(lng))
+		if err != nil {
+			return nil, fmt.Errorf("invalid points of interest %v: line %v: %v", filename, i+2, err)
+		}
+		kind := strings.ToLower(strings.TrimSpace(row[columns["kind"]]))
+		if kind == "" {
+			return nil, fmt.Errorf("invalid points of interest %v: line %v: no kind", filename, i+2)
+		}
+		pois = append(pois, POI{strings.TrimSpace(row[columns["name"]]), kind, point})
+	}
+	return pois, nil
+}
+
+// A NearbyPOI is a point of interest and its distance from a place.
+type NearbyPOI struct {
+	POI
+	Miles float64
+}
+
+// closestOnSegment returns the point of the segment from start to end
+// that's closest to p. The segment is short enough to treat as flat,
+// with the grid's scale of miles to degrees.
+func closestOnSegment(p Point, start Point, end Point) Point {
+	dx, dy := (end.Lng-start.Lng)/HALF_MILE_LNG, (end.Lat-start.Lat)/HALF_MILE_LAT
+	length := dx*dx + dy*dy
+	if length == 0 {
+		return start
+	}
+	t := ((p.Lng-start.Lng)/HALF_MILE_LNG*dx + (p.Lat-start.Lat)/HALF_MILE_LAT*dy) / length
+	t = math.Max(0, math.Min(1, t))
+	return Point{start.Lat + (end.Lat-start.Lat)*t, start.Lng + (end.Lng-start.Lng)*t}
+}
+
+// NearSegment returns the nearest point of interest of each kind within
+// POI_NEARBY_MILES of the segment from start to end, nearest first. Files
+// of points of interest are small, so every one is checked.
+func (pois POIs) NearSegment(start Point, end Point) []NearbyPOI {
+	nearest := make(map[string]NearbyPOI)
+	for _, poi := range pois {
+		closest := closestOnSegment(poi.Point, start, end)
+		miles := closest.GreatCircleDistance(&poi.Point)
+		if miles > POI_NEARBY_MILES {
+			continue
+		}
+		if found, ok := nearest[poi.Kind]; !ok || miles < found.Miles {
+			nearest[poi.Kind] = NearbyPOI{poi, miles}
+		}
+	}
+	nearby := make([]NearbyPOI, 0, len(nearest))
+	for _, poi := range nearest {
+		nearby = append(nearby, poi)
+	}
+	sort.Slice(nearby, func(i, j int) bool {
+		if nearby[i].Miles != nearby[j].Miles {
+			return nearby[i].Miles < nearby[j].Miles
+		}
+		return nearby[i].Kind < nearby[j].Kind
+	})
+	return nearby
+}
+
+// Near returns the nearest point of interest of each kind within
+// POI_NEARBY_MILES of point, nearest first.
+func (pois POIs) Near(point Point) []NearbyPOI {
+	return pois.NearSegment(point, point)
+}
+
+// The JSON form of a NearbyPOI.
+type nearbyPOIJson struct {
+	Name   string    `json:"name"`
+	Kind   string    `json:"kind"`
+	Center pointJson `json:"center"`
+	Miles  float64   `json:"miles"`
+}
+
+// nearbyToJson returns the JSON form of nearby points of interest.
+func nearbyToJson(nearby []NearbyPOI) []nearbyPOIJson {
+	if len(nearby) == 0 {
+		return nil
+	}
+	encoded := make([]nearbyPOIJson, 0, len(nearby))
+	for _, poi := range nearby {
+		encoded = append(encoded, nearbyPOIJson{poi.Name, poi.Kind, pointJson{poi.Point.Lat, poi.Point.Lng}, roundTo(poi.Miles, 2)})
+	}
+	return encoded
+}
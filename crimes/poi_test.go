@@ -0,0 +1,99 @@
+package radar
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// lloydPOIs are points of interest around the Lloyd district.
+var lloydPOIs = POIs{
+	{"Lloyd Center Transit Center", "transit", Point{45.5313, -122.6556}},
+	{"Convention Center Station", "transit", Point{45.5287, -122.6627}},
+	{"Holladay Park", "park", Point{45.5302, -122.6541}},
+	{"Grant High School", "school", Point{45.5411, -122.6353}},
+}
+
+func TestLoadPOIs(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "pois.csv")
+	os.WriteFile(filename, []byte("Name,Kind,Lat,Lng,Address\nHolladay Park, Park ,45.5302,-122.6541,NE 11th Ave\n"), 0644)
+	pois, err := LoadPOIs(filename)
+	if err != nil {
+		t.Fatal("Error loading points of interest: ", err)
+	}
+	if len(pois) != 1 || pois[0] != (POI{"Holladay Park", "park", Point{45.5302, -122.6541}}) {
+		t.Error("Wrong points of interest: ", pois)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"name,kind,lat\nHolladay Park,park,45.5302\n",
+		"name,kind,lat,lng\nHolladay Park,park,north,-122.6541\n",
+		"name,kind,lat,lng\nHolladay Park,park,95,-122.6541\n",
+		"name,kind,lat,lng\nHolladay Park,,45.5302,-122.6541\n",
+	} {
+		os.WriteFile(filename, []byte(invalid), 0644)
+		if _, err := LoadPOIs(filename); err == nil {
+			t.Error("Invalid points of interest should be an error: ", invalid)
+		}
+	}
+}
+
+func TestPOIsNear(t *testing.T) {
+	nearby := lloydPOIs.Near(Point{45.5310, -122.6550})
+	if len(nearby) != 2 || nearby[0].Name != "Lloyd Center Transit Center" || nearby[1].Name != "Holladay Park" {
+		t.Fatal("Should find the nearest point of interest of each kind, nearest first: ", nearby)
+	}
+	if nearby[0].Miles > nearby[1].Miles || nearby[1].Miles > POI_NEARBY_MILES {
+		t.Error("Wrong distances: ", nearby)
+	}
+	if far := lloydPOIs.Near(Point{45.4, -122.5}); len(far) != 0 {
+		t.Error("Points of interest should be near: ", far)
+	}
+	if none := POIs(nil).Near(Point{45.5310, -122.6550}); len(none) != 0 {
+		t.Error("No points of interest should find none: ", none)
+	}
+}
+
+func TestPOIsNearSegment(t *testing.T) {
+	// A segment along NE Broadway passes the school in the middle, though
+	// both of its ends are more than half a mile from it.
+	start, end := Point{45.5411, -122.6600}, Point{45.5411, -122.6100}
+	if len(lloydPOIs.Near(start)) != 0 || len(lloydPOIs.Near(end)) != 0 {
+		t.Fatal("The ends of the segment should be far from every point of interest")
+	}
+	nearby := lloydPOIs.NearSegment(start, end)
+	if len(nearby) != 1 || nearby[0].Kind != "school" || nearby[0].Miles > 0.01 {
+		t.Error("Should find points of interest along a segment: ", nearby)
+	}
+}
+
+func TestWithPOIs(t *testing.T) {
+	area := SafeArea{Center: Point{45.5310, -122.6550}}.WithPOIs(lloydPOIs)
+	data, _ := area.ToGeoJson()
+	var feature struct {
+		Properties struct {
+			Nearby []struct {
+				Name  string
+				Kind  string
+				Miles float64
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &feature); err != nil || len(feature.Properties.Nearby) != 2 || feature.Properties.Nearby[0].Kind != "transit" {
+		t.Error("Safe area should have the points of interest near its center: ", string(data), err)
+	}
+
+	score := RouteScore{Segments: []RouteSegment{{Start: Point{45.5411, -122.6600}, End: Point{45.5411, -122.6100}}}}
+	if scored := score.WithPOIs(lloydPOIs); len(scored.Segments[0].Nearby) != 1 || score.Segments[0].Nearby != nil {
+		t.Error("Route should have the points of interest near each segment: ", scored.Segments)
+	}
+
+	cell := GridCellOf(Point{45.5310, -122.6550})
+	delta := Delta{NewHotspots: []Hotspot{{cell, 1, 9}}}.WithPOIs(lloydPOIs)
+	if data, _ := delta.ToJson(); len(delta.Nearby[cell]) == 0 || !strings.Contains(string(data), `"nearby":[{"name":"Holladay Park"`) {
+		t.Error("Delta should have the points of interest near its hotspots: ", string(data))
+	}
+}
@@ -0,0 +1,74 @@
+package radar
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PER_CAPITA_RESIDENTS is the number of residents that per-capita rates
+// are counted per: a rate is the crimes per 1,000 residents.
+const PER_CAPITA_RESIDENTS = 1000
+
+// Populations are the numbers of residents of neighborhoods or census
+// tracts, by name. Names are looked up regardless of case.
+type Populations map[string]int
+
+// LoadPopulations reads populations from a CSV file with a header, like a
+// census table, whose "name" column names a neighborhood or tract and whose
+// "population" column is the number of its residents. Other columns are
+// ignored.
+func LoadPopulations(filename string) (Populations, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid populations %v: %v", filename, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("invalid populations %v: no header", filename)
+	}
+	nameColumn, populationColumn := -1, -1
+	for i, column := range rows[0] {
+		switch strings.ToLower(strings.TrimSpace(column)) {
+		case "name":
+			nameColumn = i
+		case "population":
+			populationColumn = i
+		}
+	}
+	if nameColumn < 0 || populationColumn < 0 {
+		return nil, fmt.Errorf("invalid populations %v: the header needs name and population columns", filename)
+	}
+	populations := make(Populations, len(rows)-1)
+	for i, row := range rows[1:] {
+		name := indexKey(row[nameColumn])
+		population, err := strconv.Atoi(strings.TrimSpace(row[populationColumn]))
+		if err != nil || population < 0 {
+			return nil, fmt.Errorf("invalid populations %v: line %v: invalid population %q", filename, i+2, row[populationColumn])
+		}
+		if _, exists := populations[name]; exists {
+			return nil, fmt.Errorf("invalid populations %v: line %v: duplicate name %q", filename, i+2, row[nameColumn])
+		}
+		populations[name] = population
+	}
+	return populations, nil
+}
+
+// Of returns the population of the neighborhood or tract called name, or 0
+// if it isn't known.
+func (populations Populations) Of(name string) int {
+	return populations[indexKey(name)]
+}
+
+// perCapita returns the rate of crimes among population residents, per
+// PER_CAPITA_RESIDENTS, rounded to hundredths.
+func perCapita(crimes int, population int) float64 {
+	return math.Round(float64(crimes)*PER_CAPITA_RESIDENTS/float64(population)*100) / 100
+}
@@ -0,0 +1,50 @@
+package radar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPopulations(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "populations.csv")
+	os.WriteFile(filename, []byte("GEOID,Name,Population\n1,Lloyd,1200\n2, Downtown ,13000\n"), 0644)
+	populations, err := LoadPopulations(filename)
+	if err != nil {
+		t.Fatal("Error loading populations: ", err)
+	}
+	if populations.Of("LLOYD") != 1200 || populations.Of("downtown") != 13000 || populations.Of("Pearl") != 0 {
+		t.Error("Wrong populations: ", populations)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"name,residents\nLloyd,1200\n",
+		"name,population\nLloyd,many\n",
+		"name,population\nLloyd,-1\n",
+		"name,population\nLloyd,1200\nlloyd,1300\n",
+	} {
+		os.WriteFile(filename, []byte(invalid), 0644)
+		if _, err := LoadPopulations(filename); err == nil {
+			t.Error("Invalid populations should be an error: ", invalid)
+		}
+	}
+}
+
+func TestNeighborhoodCountsWithPopulations(t *testing.T) {
+	counts := NeighborhoodCounts{
+		{Neighborhood: Neighborhood{Name: "Lloyd"}, Crimes: 3, Categories: map[string]int{PersonCategory: 3}},
+		{Neighborhood: Neighborhood{Name: "Pearl"}, Crimes: 5, Categories: map[string]int{}},
+	}
+	populated := counts.WithPopulations(Populations{"lloyd": 1500})
+	if populated[0].Population != 1500 || populated[1].Population != 0 || counts[0].Population != 0 {
+		t.Error("Wrong populations: ", populated)
+	}
+	feature := populated[0].featureJson()
+	if *feature.Properties.CrimesPer1000 != 2 || feature.Properties.CategoriesPer1000[PersonCategory] != 2 {
+		t.Error("Wrong per-capita rates: ", feature.Properties)
+	}
+	if populated[1].featureJson().Properties.CrimesPer1000 != nil {
+		t.Error("A neighborhood without a population should have no rate")
+	}
+}
@@ -0,0 +1,95 @@
+package radar
+
+import (
+	"bytes"
+	"io"
+	"time"
+)
+
+// Phases of loading data, as reported in LoadProgress.
+const (
+	// ReadPhase reads and splits the CSV data into rows.
+	ReadPhase = "read"
+	// ParsePhase filters the rows and turns them into crimes and locations.
+	ParsePhase = "parse"
+	// IndexPhase builds the index that searches use.
+	IndexPhase = "index"
+)
+
+// The number of bytes read between reports of progress in the read phase.
+const PROGRESS_INTERVAL_BYTES = 1 << 20
+
+// LoadProgress describes how far along loading data is. A phase reports
+// progress as it runs, then once more with Done set when it finishes.
+type LoadProgress struct {
+	Phase string
+	// Bytes is the number of bytes of the file read so far, out of
+	// TotalBytes.
+	Bytes      int64
+	TotalBytes int64
+	// Rows is the number of rows the read or parse phase has handled. While
+	// the file is being read, it counts lines rather than rows.
+	Rows int
+	// Elapsed is the time since the phase started.
+	Elapsed time.Duration
+	Done    bool
+}
+
+// Percent returns how much of the file has been read, from 0 to 100.
+func (progress LoadProgress) Percent() float64 {
+	if progress.TotalBytes <= 0 {
+		return 0
+	}
+	return float64(progress.Bytes) / float64(progress.TotalBytes) * 100
+}
+
+// RowsPerSecond returns the rate at which the phase has handled rows.
+func (progress LoadProgress) RowsPerSecond() float64 {
+	if progress.Elapsed <= 0 {
+		return 0
+	}
+	return float64(progress.Rows) / progress.Elapsed.Seconds()
+}
+
+// progressReader reports progress as the data under it is read.
+type progressReader struct {
+	r        io.Reader
+	progress LoadProgress
+	start    time.Time
+	report   func(LoadProgress)
+	// unreported is the number of bytes read since the last report.
+	unreported int64
+}
+
+// newProgressReader returns a reader that reports progress through report
+// while reading totalBytes bytes from r.
+func newProgressReader(r io.Reader, totalBytes int64, report func(LoadProgress)) *progressReader {
+	return &progressReader{
+		r:        r,
+		progress: LoadProgress{Phase: ReadPhase, TotalBytes: totalBytes},
+		start:    time.Now(),
+		report:   report,
+	}
+}
+
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.r.Read(buf)
+	p.progress.Bytes += int64(n)
+	p.progress.Rows += bytes.Count(buf[:n], []byte("\n"))
+	p.unreported += int64(n)
+	if p.unreported >= PROGRESS_INTERVAL_BYTES {
+		p.unreported = 0
+		p.progress.Elapsed = time.Since(p.start)
+		p.report(p.progress)
+	}
+	return n, err
+}
+
+// reportPhase reports that phase finished after handling rows, if there is
+// a report function.
+func reportPhase(report func(LoadProgress), phase string, rows int, start time.Time) {
+	if report == nil {
+		return
+	}
+	report(LoadProgress{Phase: phase, Rows: rows, Elapsed: time.Since(start), Done: true})
+}
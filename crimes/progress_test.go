@@ -0,0 +1,76 @@
+package radar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadProgressPercent(t *testing.T) {
+	progress := LoadProgress{Bytes: 25, TotalBytes: 200}
+	if progress.Percent() != 12.5 {
+		t.Error("Wrong percent: ", progress.Percent())
+	}
+	if (LoadProgress{Bytes: 25}).Percent() != 0 {
+		t.Error("Percent should be 0 when the size is unknown")
+	}
+}
+
+func TestLoadProgressRowsPerSecond(t *testing.T) {
+	progress := LoadProgress{Rows: 500, Elapsed: 2 * time.Second}
+	if progress.RowsPerSecond() != 250 {
+		t.Error("Wrong rows per second: ", progress.RowsPerSecond())
+	}
+}
+
+func TestProgressReader(t *testing.T) {
+	line := strings.Repeat("x", 1023) + "\n"
+	data := strings.Repeat(line, 3*1024)
+	reports := make([]LoadProgress, 0)
+	reader := newProgressReader(strings.NewReader(data), int64(len(data)), func(progress LoadProgress) {
+		reports = append(reports, progress)
+	})
+	buf := make([]byte, 4096)
+	for {
+		if _, err := reader.Read(buf); err != nil {
+			break
+		}
+	}
+	if len(reports) != 3 {
+		t.Fatal("Wrong number of reports: ", len(reports))
+	}
+	if reports[0].Bytes != PROGRESS_INTERVAL_BYTES || reports[0].Rows != 1024 || reports[0].Phase != ReadPhase {
+		t.Error("Wrong progress: ", reports[0])
+	}
+	if reports[2].Percent() != 100 {
+		t.Error("Wrong percent at the end: ", reports[2].Percent())
+	}
+}
+
+func TestCrimeFinderProgress(t *testing.T) {
+	phases := make([]string, 0)
+	var read, parse LoadProgress
+	_, err := NewCrimeFinderWithOptions("sample/crimes.csv", LoadOptions{
+		Progress: func(progress LoadProgress) {
+			if !progress.Done {
+				return
+			}
+			phases = append(phases, progress.Phase)
+			switch progress.Phase {
+			case ReadPhase:
+				read = progress
+			case ParsePhase:
+				parse = progress
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	if strings.Join(phases, ",") != "read,parse,index" {
+		t.Error("Wrong phases: ", phases)
+	}
+	if read.Percent() != 100 || read.Rows == 0 || parse.Rows == 0 {
+		t.Error("Wrong progress: ", read, parse)
+	}
+}
@@ -0,0 +1,100 @@
+package radar
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/abrookins/radar/internal/kdtree"
+)
+
+// MILES_PER_DEGREE_LAT is the length of a degree of latitude, which is
+// about the same everywhere.
+const MILES_PER_DEGREE_LAT = EARTH_RADIUS * math.Pi / 180
+
+// RadiusBounds returns the smallest box that holds every point within miles
+// of query. A degree of longitude gets shorter away from the equator, so
+// the box is wider, in degrees, the further north or south query is. Near
+// the poles, or across the antimeridian, it covers every longitude.
+func RadiusBounds(query Point, miles float64) Bounds {
+	lat := miles / MILES_PER_DEGREE_LAT
+	bounds := Bounds{
+		Min: Point{math.Max(query.Lat-lat, -90), -180},
+		Max: Point{math.Min(query.Lat+lat, 90), 180},
+	}
+	// The widest part of the circle is at the latitude of the box nearest
+	// a pole.
+	widest := math.Max(math.Abs(bounds.Min.Lat), math.Abs(bounds.Max.Lat))
+	if widest < 90 {
+		lng := lat / math.Cos(widest*math.Pi/180)
+		if query.Lng-lng >= -180 && query.Lng+lng <= 180 {
+			bounds.Min.Lng, bounds.Max.Lng = query.Lng-lng, query.Lng+lng
+		}
+	}
+	return bounds
+}
+
+// FindWithinRadius returns the locations within miles of query, by
+// great-circle distance. Unlike FindNear, whose half-mile box is measured
+// in degrees at Portland's latitude, it's accurate anywhere. It returns an
+// error if miles isn't more than 0 and at most MAX_SEARCH_RADIUS.
+func (finder *CrimeFinder) FindWithinRadius(query Point, miles float64) (SearchResult, error) {
+	return finder.findWithinRadius(query, miles, nil)
+}
+
+// findWithinRadius finds the locations within miles of query, filling in
+// explanation if it isn't nil. The kd-tree finds the locations in the box
+// around the circle, and those outside the circle are dropped.
+func (finder *CrimeFinder) findWithinRadius(query Point, miles float64, explanation *Explanation) (SearchResult, error) {
+	nearby := SearchResult{Query: &query, Locations: make([]*CrimeLocation, 0), Explanation: explanation}
+	if !(miles > 0) || miles > MAX_SEARCH_RADIUS {
+		return nearby, fmt.Errorf("invalid radius: %v is not between 0 and %v miles", miles, MAX_SEARCH_RADIUS)
+	}
+	covered := RadiusBounds(query, miles)
+	var candidates []*CrimeLocation
+	start := time.Now()
+	if tree := finder.tree(); tree != nil {
+		ranges := map[int]kdtree.Range{
+			LAT_AXIS: {Min: covered.Min.Lat, Max: covered.Max.Lat},
+			LNG_AXIS: {Min: covered.Min.Lng, Max: covered.Max.Lng}}
+		results, visited, err := tree.FindRangeVisited(ranges)
+		if err != nil {
+			return nearby, err
+		}
+		for _, node := range results {
+			point := nodePoint(node)
+			if location, exists := finder.LocationLookup[GetCoordinateKey(point.Lat, point.Lng)]; exists {
+				candidates = append(candidates, location)
+			}
+		}
+		explanation.record("search", start)
+		if explanation != nil {
+			explanation.NodesVisited = visited
+		}
+	} else {
+		for _, location := range finder.Locations() {
+			if covered.Contains(*location.Point) {
+				candidates = append(candidates, location)
+			}
+		}
+		explanation.record("scan", start)
+		if explanation != nil {
+			explanation.Index = SCAN_NAME
+		}
+	}
+	start = time.Now()
+	for _, location := range candidates {
+		if query.GreatCircleDistance(location.Point) <= miles {
+			nearby.Locations = append(nearby.Locations, location)
+		}
+	}
+	explanation.record("distance", start)
+	if len(nearby.Locations) == 0 {
+		nearby.Diagnostics = finder.diagnose(query)
+	}
+	if explanation != nil {
+		explanation.Candidates = len(candidates)
+		explanation.Results = len(nearby.Locations)
+	}
+	return nearby, nil
+}
@@ -0,0 +1,60 @@
+package radar
+
+import (
+	"testing"
+)
+
+func TestRadiusBounds(t *testing.T) {
+	portland := RadiusBounds(Point{45.5, -122.6}, 1)
+	equator := RadiusBounds(Point{0, 0}, 1)
+	if height := portland.Max.Lat - portland.Min.Lat; height < 0.0289 || height > 0.029 {
+		t.Error("Wrong height of a box: ", height)
+	}
+	if portland.Max.Lng-portland.Min.Lng <= equator.Max.Lng-equator.Min.Lng {
+		t.Error("A box should be wider in degrees away from the equator: ", portland, equator)
+	}
+	corner := Point{45.5, portland.Max.Lng}
+	if miles := (&Point{45.5, -122.6}).GreatCircleDistance(&corner); miles < 1 {
+		t.Error("A box should hold the whole circle: ", miles)
+	}
+	if pole := RadiusBounds(Point{89.99, 10}, 5); pole.Min.Lng != -180 || pole.Max.Lng != 180 || pole.Max.Lat != 90 {
+		t.Error("A box near a pole should cover every longitude: ", pole)
+	}
+}
+
+func TestFindWithinRadius(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	query := Point{45.5184, -122.6554}
+	within, err := finder.FindWithinRadius(query, 0.5)
+	if err != nil {
+		t.Fatal("FindWithinRadius returned an error: ", err)
+	}
+	found := make(map[*CrimeLocation]bool)
+	for _, location := range within.Locations {
+		found[location] = true
+	}
+	for _, location := range finder.Locations() {
+		if inside := query.GreatCircleDistance(location.Point) <= 0.5; inside != found[location] {
+			t.Error("Wrong location in the result: ", location.Point, inside)
+		}
+	}
+	near, _ := finder.FindNear(query)
+	if len(within.Locations) <= len(near.Locations) {
+		t.Error("A half-mile radius should find more than FindNear's box at this latitude: ", len(within.Locations), len(near.Locations))
+	}
+
+	wide, _ := finder.FindWithinRadius(query, 2)
+	if len(wide.Locations) <= len(within.Locations) {
+		t.Error("A larger radius should find more locations: ", len(wide.Locations))
+	}
+	lazy, _ := NewCrimeFinderWithOptions("sample/crimes.csv", LoadOptions{LazyIndexes: true})
+	lazy.pending = &backgroundBuild{done: make(chan struct{})}
+	if scanned, _ := lazy.FindWithinRadius(query, 2); len(scanned.Locations) != len(wide.Locations) {
+		t.Error("A scan should find the same locations: ", len(scanned.Locations))
+	}
+	for _, miles := range []float64{0, -1, MAX_SEARCH_RADIUS + 1} {
+		if _, err := finder.FindWithinRadius(query, miles); err == nil {
+			t.Error("An invalid radius should be an error: ", miles)
+		}
+	}
+}
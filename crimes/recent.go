@@ -0,0 +1,121 @@
+package radar
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// A RecentCrime is a crime found by a search, with where it occurred.
+type RecentCrime struct {
+	Crime *Crime
+	Point *Point
+	// Miles is the distance from the search's query to the crime.
+	Miles float64
+}
+
+// RecentCrimes lists the crimes a search found, newest first, rather than
+// grouped by location: a feed of what happened around the query lately.
+type RecentCrimes struct {
+	Query  *Point
+	Crimes []RecentCrime
+	// Total is the number of crimes the search found, of which Crimes holds
+	// the newest.
+	Total int
+	// BaseURL is the URL of the server that the permalinks of crimes point
+	// to. If it's empty, permalinks are paths.
+	BaseURL string
+}
+
+// Recent returns the newest limit crimes of the result, newest first.
+// Crimes on the same date and time are in order of id, highest first, and
+// crimes whose dates can't be parsed come last.
+func (r SearchResult) Recent(limit int) RecentCrimes {
+	recent := RecentCrimes{Query: r.Query, Crimes: make([]RecentCrime, 0), BaseURL: r.BaseURL}
+	for _, location := range r.Locations {
+		miles := 0.0
+		if r.Query != nil {
+			miles = r.Query.GreatCircleDistance(location.Point)
+		}
+		for _, crime := range location.Crimes {
+			recent.Crimes = append(recent.Crimes, RecentCrime{crime, location.Point, miles})
+		}
+	}
+	sort.SliceStable(recent.Crimes, func(i, j int) bool {
+		return newer(recent.Crimes[i].Crime, recent.Crimes[j].Crime)
+	})
+	recent.Total = len(recent.Crimes)
+	if limit >= 0 && len(recent.Crimes) > limit {
+		recent.Crimes = recent.Crimes[:limit]
+	}
+	return recent
+}
+
+// newer returns true if a occurred after b, or at the same time with a
+// higher id. Crimes whose dates can't be parsed are older than any other.
+func newer(a *Crime, b *Crime) bool {
+	at, bt := crimeTime(a), crimeTime(b)
+	if !at.Equal(bt) {
+		return at.After(bt)
+	}
+	return a.Id > b.Id
+}
+
+// The JSON form of a crime, with its category and permalink.
+type crimeJson struct {
+	Id       int64  `json:"id"`
+	Date     string `json:"date"`
+	Time     string `json:"time"`
+	Type     string `json:"type"`
+	Category string `json:"category"`
+	Weapon   string `json:"weapon,omitempty"`
+	Domestic *bool  `json:"domestic,omitempty"`
+	Arrest   *bool  `json:"arrest,omitempty"`
+	Case     string `json:"case,omitempty"`
+	Geocoded bool   `json:"geocoded,omitempty"`
+	URL      string `json:"url"`
+}
+
+// newCrimeJson returns the JSON form of crime, with a permalink to baseURL.
+func newCrimeJson(crime *Crime, baseURL string) crimeJson {
+	return crimeJson{
+		Id:       crime.Id,
+		Date:     crime.Date,
+		Time:     crime.Time,
+		Type:     crime.Type,
+		Category: Classify(crime.Type).Category,
+		Weapon:   crime.Weapon,
+		Domestic: crime.Domestic,
+		Arrest:   crime.Arrest,
+		Case:     crime.CaseNumber,
+		Geocoded: crime.Geocoded,
+		URL:      Permalink(baseURL, crime.Id),
+	}
+}
+
+// The JSON form of a RecentCrime.
+type recentCrimeJson struct {
+	crimeJson
+	Point pointJson `json:"point"`
+	Miles float64   `json:"distance_miles"`
+}
+
+// ToJson returns the crimes marshalled to JSON bytes.
+func (recent RecentCrimes) ToJson() ([]byte, error) {
+	crimes := make([]recentCrimeJson, 0, len(recent.Crimes))
+	for _, found := range recent.Crimes {
+		crimes = append(crimes, recentCrimeJson{
+			crimeJson: newCrimeJson(found.Crime, recent.BaseURL),
+			Point:     pointJson{found.Point.Lat, found.Point.Lng},
+			Miles:     roundTo(found.Miles, 3),
+		})
+	}
+	out := struct {
+		Query  *pointJson        `json:"query"`
+		Total  int               `json:"total"`
+		Crimes []recentCrimeJson `json:"crimes"`
+	}{Total: recent.Total, Crimes: crimes}
+	if recent.Query != nil {
+		out.Query = &pointJson{recent.Query.Lat, recent.Query.Lng}
+	}
+	return json.Marshal(out)
+}
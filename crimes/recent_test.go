@@ -0,0 +1,65 @@
+package radar
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSearchResultRecent(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	result, _ := finder.FindNear(Point{45.5184, -122.6554})
+	recent := result.Recent(10)
+	if len(recent.Crimes) != 10 || recent.Total != len(result.Crimes()) {
+		t.Fatal("Wrong number of recent crimes: ", len(recent.Crimes), recent.Total)
+	}
+	for i := 1; i < len(recent.Crimes); i++ {
+		if crimeTime(recent.Crimes[i].Crime).After(crimeTime(recent.Crimes[i-1].Crime)) {
+			t.Error("Recent crimes should be newest first: ", recent.Crimes[i-1].Crime, recent.Crimes[i].Crime)
+		}
+	}
+	if all := result.Recent(-1); len(all.Crimes) != all.Total {
+		t.Error("A negative limit should list every crime: ", len(all.Crimes))
+	}
+}
+
+func TestRecentOrdersTiesById(t *testing.T) {
+	result := SearchResult{
+		Query: &Point{45.5, -122.6},
+		Locations: []*CrimeLocation{
+			{&Point{45.5, -122.6}, []*Crime{{Id: 1, Date: "05/27/2011", Time: "08:35:00"}, {Id: 3, Date: "not a date"}}},
+			{&Point{45.51, -122.6}, []*Crime{{Id: 2, Date: "05/27/2011", Time: "08:35:00"}, {Id: 4, Date: "05/26/2011", Time: "23:00:00"}}},
+		},
+	}
+	recent := result.Recent(10)
+	ids := make([]int64, 0)
+	for _, found := range recent.Crimes {
+		ids = append(ids, found.Crime.Id)
+	}
+	if len(ids) != 4 || ids[0] != 2 || ids[1] != 1 || ids[2] != 4 || ids[3] != 3 {
+		t.Error("Wrong order of recent crimes: ", ids)
+	}
+	if recent.Crimes[2].Miles == 0 {
+		t.Error("Crimes away from the query should have a distance")
+	}
+}
+
+func TestRecentCrimesToJson(t *testing.T) {
+	yes := true
+	recent := RecentCrimes{
+		Query:   &Point{45.5, -122.6},
+		Crimes:  []RecentCrime{{&Crime{Id: 1, Date: "05/27/2011", Time: "08:35:00", Type: "Larceny", Arrest: &yes}, &Point{45.51, -122.6}, 0.69}},
+		Total:   3,
+		BaseURL: "https://radar.example.com",
+	}
+	data, err := recent.ToJson()
+	if err != nil {
+		t.Fatal("ToJson returned an error: ", err)
+	}
+	expected := `{"query":{"lat":45.5,"lng":-122.6},"total":3,"crimes":[{"id":1,"date":"05/27/2011","time":"08:35:00","type":"Larceny","category":"property","arrest":true,"url":"https://radar.example.com/crimes/1","point":{"lat":45.51,"lng":-122.6},"distance_miles":0.69}]}`
+	if string(data) != expected {
+		t.Error("Wrong JSON: ", string(data))
+	}
+	if !json.Valid(data) {
+		t.Error("JSON is not valid")
+	}
+}
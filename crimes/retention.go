@@ -0,0 +1,60 @@
+package radar
+
+import (
+	"time"
+)
+
+// The layout of dates in the City's CSV data.
+const DATE_LAYOUT = "01/02/2006"
+
+// A RetentionPolicy decides which crimes a CrimeFinder keeps. Every source of
+// crimes goes through the same policy, so a category that must not be
+// published can't slip in another way. The zero value keeps everything.
+type RetentionPolicy struct {
+	// MaxAgeYears drops crimes more than this many years old. Zero keeps
+	// crimes of any age.
+	MaxAgeYears int
+	// MaxAgeYearsByType overrides MaxAgeYears for specific crime types.
+	MaxAgeYearsByType map[string]int
+	// ExcludedTypes are never kept, e.g. expunged categories.
+	ExcludedTypes CrimeTypes
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+}
+
+// Keep reports whether the policy keeps crime. A crime whose date can't be
+// parsed is kept, since we can't tell how old it is.
+func (p RetentionPolicy) Keep(crime *Crime) bool {
+	if p.ExcludedTypes.Contains(crime.Type) {
+		return false
+	}
+	years := p.MaxAgeYears
+	if typeYears, ok := p.MaxAgeYearsByType[crime.Type]; ok {
+		years = typeYears
+	}
+	if years <= 0 {
+		return true
+	}
+	date, err := time.Parse(DATE_LAYOUT, crime.Date)
+	if err != nil {
+		return true
+	}
+	now := time.Now
+	if p.Now != nil {
+		now = p.Now
+	}
+	return !date.Before(now().AddDate(-years, 0, 0))
+}
+
+// applyRetention returns the rows that policy keeps and the number it
+// dropped.
+func applyRetention(rows CsvRows, policy RetentionPolicy) (CsvRows, int) {
+	kept := make(CsvRows, 0, len(rows))
+	for _, row := range rows {
+		crime := Crime{Date: row[1], Time: row[2], Type: row[3]}
+		if policy.Keep(&crime) {
+			kept = append(kept, row)
+		}
+	}
+	return kept, len(rows) - len(kept)
+}
@@ -0,0 +1,65 @@
+package radar
+
+import (
+	"testing"
+	"time"
+)
+
+func retentionNow() time.Time {
+	return time.Date(2013, 6, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestRetentionPolicyZeroValueKeepsEverything(t *testing.T) {
+	policy := RetentionPolicy{}
+	if !policy.Keep(&Crime{Date: "01/01/1990", Type: "Burglary"}) {
+		t.Error("The zero RetentionPolicy should keep every crime")
+	}
+}
+
+func TestRetentionPolicyExcludedTypes(t *testing.T) {
+	policy := RetentionPolicy{ExcludedTypes: CrimeTypes{"Liquor Laws"}}
+	if policy.Keep(&Crime{Date: "01/01/2013", Type: "Liquor Laws"}) {
+		t.Error("RetentionPolicy kept an excluded type")
+	}
+	if !policy.Keep(&Crime{Date: "01/01/2013", Type: "Burglary"}) {
+		t.Error("RetentionPolicy dropped a type that is not excluded")
+	}
+}
+
+func TestRetentionPolicyMaxAge(t *testing.T) {
+	policy := RetentionPolicy{
+		MaxAgeYears:       2,
+		MaxAgeYearsByType: map[string]int{"DUII": 1},
+		Now:               retentionNow,
+	}
+	if policy.Keep(&Crime{Date: "05/31/2011", Type: "Burglary"}) {
+		t.Error("RetentionPolicy kept a crime older than MaxAgeYears")
+	}
+	if !policy.Keep(&Crime{Date: "06/01/2011", Type: "Burglary"}) {
+		t.Error("RetentionPolicy dropped a crime within MaxAgeYears")
+	}
+	if policy.Keep(&Crime{Date: "01/01/2012", Type: "DUII"}) {
+		t.Error("RetentionPolicy should use the max age for the crime's type")
+	}
+	if !policy.Keep(&Crime{Date: "not a date", Type: "Burglary"}) {
+		t.Error("RetentionPolicy should keep crimes with dates it can't parse")
+	}
+}
+
+func TestCrimeFinderRetention(t *testing.T) {
+	all, _ := NewCrimeFinder("sample/crimes.csv")
+	policy := RetentionPolicy{ExcludedTypes: CrimeTypes{"Liquor Laws"}}
+	finder, err := NewCrimeFinderWithOptions("sample/crimes.csv", LoadOptions{Retention: policy})
+	if err != nil {
+		t.Error("Error creating CrimeFinder: ", err)
+	}
+	for _, crime := range finder.All().Crimes() {
+		if crime.Type == "Liquor Laws" {
+			t.Error("CrimeFinder loaded an excluded type")
+			break
+		}
+	}
+	if finder.Report.Dropped == 0 || finder.Report.Crimes+finder.Report.Dropped != all.Report.Crimes {
+		t.Error("Report has the wrong number of dropped crimes: ", finder.Report.Dropped)
+	}
+}
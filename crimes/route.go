@@ -0,0 +1,170 @@
+package radar
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+)
+
+// ROUTE_SAMPLE_MILES is the most distance between the points of a segment
+// of a route whose risk scores are averaged to score it.
+const ROUTE_SAMPLE_MILES = 0.05
+
+var errPolyline = errors.New("polyline is not a valid encoded polyline")
+
+// DecodePolyline decodes a route in the encoded polyline format of Google
+// Maps, with precision decimal places: 5 for Google's polylines and 6 for
+// those of OSRM and Mapbox's polyline6.
+func DecodePolyline(encoded string, precision int) ([]Point, error) {
+	scale := math.Pow(10, float64(precision))
+	points := make([]Point, 0)
+	var lat, lng int64
+	for i := 0; i < len(encoded); {
+		var deltas [2]int64
+		for d := range deltas {
+			var result int64
+			for shift := uint(0); ; shift += 5 {
+				if i >= len(encoded) || shift > 30 {
+					return nil, errPolyline
+				}
+				b := int64(encoded[i]) - 63
+				i++
+				if b < 0 || b > 63 {
+					return nil, errPolyline
+				}
+				result |= (b & 0x1f) << shift
+				if b < 0x20 {
+					break
+				}
+			}
+			if result&1 != 0 {
+				deltas[d] = ^(result >> 1)
+			} else {
+				deltas[d] = result >> 1
+			}
+		}
+		lat += deltas[0]
+		lng += deltas[1]
+		point, err := NewPoint(Latitude(float64(lat)/scale), Longitude(float64(lng)/scale))
+		if err != nil {
+			return nil, errPolyline
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+// RouteMiles returns the length of the route through points.
+func RouteMiles(points []Point) float64 {
+	miles := 0.0
+	for i := 1; i < len(points); i++ {
+		miles += points[i-1].GreatCircleDistance(&points[i])
+	}
+	return miles
+}
+
+// A RouteSegment is the stretch of a route between two of its points.
+type RouteSegment struct {
+	Start Point
+	End   Point
+	Miles float64
+	// Risk is the average risk score along the segment, and Safety is 100
+	// less Risk.
+	Risk   float64
+	Safety float64
+	// Nearby are the points of interest near the segment, if the score
+	// was given any.
+	Nearby []NearbyPOI
+}
+
+// A RouteScore scores each segment of a route, and the whole route by the
+// average safety of its segments, weighted by their lengths.
+type RouteScore struct {
+	Segments []RouteSegment
+	Miles    float64
+	Safety   float64
+}
+
+// ScoreRoute scores the route through points under DefaultScorer.
+func (finder *CrimeFinder) ScoreRoute(points []Point) (RouteScore, error) {
+	return finder.RiskModel().ScoreRoute(points)
+}
+
+// ScoreRoute scores the route through points, which must number at least
+// two. Each segment is scored by the average of the risk scores of points
+// along it, no more than ROUTE_SAMPLE_MILES apart.
+func (model *RiskModel) ScoreRoute(points []Point) (RouteScore, error) {
+	score := RouteScore{Segments: make([]RouteSegment, 0, len(points))}
+	weighted := 0.0
+	for i := 1; i < len(points); i++ {
+		start, end := points[i-1], points[i]
+		segment := RouteSegment{Start: start, End: end, Miles: start.GreatCircleDistance(&end)}
+		samples := int(math.Ceil(segment.Miles/ROUTE_SAMPLE_MILES)) + 1
+		risk := 0.0
+		for s := 0; s < samples; s++ {
+			fraction := 0.0
+			if samples > 1 {
+				fraction = float64(s) / float64(samples-1)
+			}
+			sample := Point{start.Lat + (end.Lat-start.Lat)*fraction, start.Lng + (end.Lng-start.Lng)*fraction}
+			sampleRisk, err := model.RiskScore(sample)
+			if err != nil {
+				return score, err
+			}
+			risk += sampleRisk
+		}
+		segment.Risk = risk / float64(samples)
+		segment.Safety = 100 - segment.Risk
+		score.Segments = append(score.Segments, segment)
+		score.Miles += segment.Miles
+		weighted += segment.Safety * segment.Miles
+	}
+	if score.Miles > 0 {
+		score.Safety = weighted / score.Miles
+	} else if len(score.Segments) > 0 {
+		score.Safety = score.Segments[0].Safety
+	}
+	return score, nil
+}
+
+// WithPOIs returns the score with the points of interest in pois near
+// each of its segments, which explain what a risky stretch passes.
+func (score RouteScore) WithPOIs(pois POIs) RouteScore {
+	segments := make([]RouteSegment, len(score.Segments))
+	for i, segment := range score.Segments {
+		segment.Nearby = pois.NearSegment(segment.Start, segment.End)
+		segments[i] = segment
+	}
+	score.Segments = segments
+	return score
+}
+
+// The JSON form of a RouteSegment.
+type routeSegmentJson struct {
+	Start  pointJson       `json:"start"`
+	End    pointJson       `json:"end"`
+	Miles  float64         `json:"miles"`
+	Risk   float64         `json:"risk"`
+	Safety float64         `json:"safety"`
+	Nearby []nearbyPOIJson `json:"nearby,omitempty"`
+}
+
+// ToJson returns the score marshalled to JSON bytes.
+func (score RouteScore) ToJson() ([]byte, error) {
+	segments := make([]routeSegmentJson, 0, len(score.Segments))
+	for _, segment := range score.Segments {
+		segments = append(segments, routeSegmentJson{
+			Start:  pointJson{segment.Start.Lat, segment.Start.Lng},
+			End:    pointJson{segment.End.Lat, segment.End.Lng},
+			Miles:  roundTo(segment.Miles, 3),
+			Risk:   roundTo(segment.Risk, 1),
+			Safety: roundTo(segment.Safety, 1),
+			Nearby: nearbyToJson(segment.Nearby),
+		})
+	}
+	return json.Marshal(struct {
+		Miles    float64            `json:"miles"`
+		Safety   float64            `json:"safety"`
+		Segments []routeSegmentJson `json:"segments"`
+	}{roundTo(score.Miles, 3), roundTo(score.Safety, 1), segments})
+}
@@ -0,0 +1,71 @@
+package radar
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestDecodePolyline(t *testing.T) {
+	// Google's example.
+	points, err := DecodePolyline("_p~iF~ps|U_ulLnnqC_mqNvxq`@", 5)
+	expected := []Point{{38.5, -120.2}, {40.7, -120.95}, {43.252, -126.453}}
+	if err != nil || len(points) != len(expected) {
+		t.Fatal("Wrong points: ", points, err)
+	}
+	for i, point := range points {
+		if math.Abs(point.Lat-expected[i].Lat) > 1e-9 || math.Abs(point.Lng-expected[i].Lng) > 1e-9 {
+			t.Error("Wrong point: ", point, expected[i])
+		}
+	}
+	six, err := DecodePolyline("whoyuAfpq~hFw`EgrO_ePozD", 6)
+	if err != nil || len(six) != 3 || math.Abs(six[2].Lat-45.535) > 1e-9 {
+		t.Error("Wrong points at precision 6: ", six, err)
+	}
+	for _, bad := range []string{"_p~iF~ps|U_", "_p~iF", "~~~~~~~~~~~~~~", "_p~iF ps|U"} {
+		if _, err := DecodePolyline(bad, 5); err == nil {
+			t.Error("Bad polyline was decoded: ", bad)
+		}
+	}
+}
+
+func TestScoreRoute(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	points := []Point{{45.5231, -122.6765}, {45.5262, -122.6680}, {45.5350, -122.6650}}
+	score, err := finder.ScoreRoute(points)
+	if err != nil {
+		t.Fatal("Error scoring route: ", err)
+	}
+	if len(score.Segments) != 2 || math.Abs(score.Miles-RouteMiles(points)) > 1e-9 {
+		t.Fatal("Wrong segments: ", score)
+	}
+	first, second := score.Segments[0], score.Segments[1]
+	if first.Safety >= second.Safety || first.Safety+first.Risk != 100 {
+		t.Error("Downtown should be less safe: ", first, second)
+	}
+	expected := (first.Safety*first.Miles + second.Safety*second.Miles) / score.Miles
+	if math.Abs(score.Safety-expected) > 1e-9 {
+		t.Error("Overall safety should be weighted by length: ", score.Safety, expected)
+	}
+
+	quiet, _ := finder.ScoreRoute([]Point{{45.6, -122.5}, {45.6, -122.5}})
+	if quiet.Safety != 100 || quiet.Miles != 0 {
+		t.Error("A route with no crimes should be safe: ", quiet)
+	}
+
+	data, err := score.ToJson()
+	if err != nil {
+		t.Fatal("Error marshalling score: ", err)
+	}
+	var decoded struct {
+		Safety   float64
+		Segments []struct{ Safety float64 }
+	}
+	json.Unmarshal(data, &decoded)
+	if len(decoded.Segments) != 2 || decoded.Safety != roundTo(score.Safety, 1) {
+		t.Error("Wrong JSON: ", string(data))
+	}
+}
@@ -0,0 +1,119 @@
+package radar
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// The settings of the search for a safe area. Rays are cast from the
+// center along SAFE_AREA_BEARINGS evenly spaced bearings, and checked every
+// SAFE_AREA_STEP_MILES.
+const (
+	SAFE_AREA_BEARINGS   = 36
+	SAFE_AREA_STEP_MILES = 0.05
+)
+
+// A SafeArea is the area around a point whose risk scores stay below a
+// threshold, like an isochrone of risk instead of travel time.
+type SafeArea struct {
+	Center    Point
+	Threshold float64
+	// Risk is the risk score at the center. If it isn't below Threshold,
+	// there's no safe area, and Boundary is empty.
+	Risk float64
+	// Boundary is the ring of points where the area ends along each
+	// bearing, counterclockwise from east.
+	Boundary []Point
+	// MinMiles and MaxMiles are the least and most distance from the center
+	// to the boundary. A MaxMiles of the search's limit means the area may
+	// go further.
+	MinMiles float64
+	MaxMiles float64
+	// Nearby are the points of interest near the center, if the area was
+	// given any.
+	Nearby []NearbyPOI
+}
+
+// WithPOIs returns the area with the points of interest in pois near its
+// center.
+func (area SafeArea) WithPOIs(pois POIs) SafeArea {
+	area.Nearby = pois.Near(area.Center)
+	return area
+}
+
+// offset returns the point miles from p at angle radians counterclockwise
+// from east.
+func offset(p Point, miles float64, angle float64) Point {
+	return Point{p.Lat + miles*math.Sin(angle)*HALF_MILE_LAT*2, p.Lng + miles*math.Cos(angle)*HALF_MILE_LNG*2}
+}
+
+// SafeArea finds the safe area around center under DefaultScorer.
+func (finder *CrimeFinder) SafeArea(center Point, threshold float64, maxMiles float64) (SafeArea, error) {
+	return finder.RiskModel().SafeArea(center, threshold, maxMiles)
+}
+
+// SafeArea finds the contiguous area around center whose risk scores stay
+// below threshold, out to maxMiles. Along each bearing, the area ends at
+// the last point checked before one whose risk reaches threshold, so that
+// every point of the boundary can be reached from the center without
+// passing somewhere riskier.
+func (model *RiskModel) SafeArea(center Point, threshold float64, maxMiles float64) (SafeArea, error) {
+	area := SafeArea{Center: center, Threshold: threshold, Boundary: make([]Point, 0, SAFE_AREA_BEARINGS)}
+	var err error
+	if area.Risk, err = model.RiskScore(center); err != nil || area.Risk >= threshold {
+		return area, err
+	}
+	steps := int(maxMiles / SAFE_AREA_STEP_MILES)
+	area.MinMiles = maxMiles
+	for bearing := 0; bearing < SAFE_AREA_BEARINGS; bearing++ {
+		angle := 2 * math.Pi * float64(bearing) / SAFE_AREA_BEARINGS
+		reach := 0.0
+		for step := 1; step <= steps; step++ {
+			miles := float64(step) * SAFE_AREA_STEP_MILES
+			score, err := model.RiskScore(offset(center, miles, angle))
+			if err != nil {
+				return area, err
+			}
+			if score >= threshold {
+				break
+			}
+			reach = miles
+		}
+		area.Boundary = append(area.Boundary, offset(center, reach, angle))
+		area.MinMiles = math.Min(area.MinMiles, reach)
+		area.MaxMiles = math.Max(area.MaxMiles, reach)
+	}
+	return area, nil
+}
+
+// ToGeoJson returns the area marshalled to a GeoJSON Feature, whose
+// geometry is a Polygon of its boundary, or null if there's no safe area.
+func (area SafeArea) ToGeoJson() ([]byte, error) {
+	type polygon struct {
+		Type        string        `json:"type"`
+		Coordinates [][][]float64 `json:"coordinates"`
+	}
+	var geometry *polygon
+	if len(area.Boundary) > 0 {
+		ring := make([][]float64, 0, len(area.Boundary)+1)
+		for _, point := range append(area.Boundary, area.Boundary[0]) {
+			ring = append(ring, []float64{roundTo(point.Lng, 6), roundTo(point.Lat, 6)})
+		}
+		geometry = &polygon{"Polygon", [][][]float64{ring}}
+	}
+	properties := map[string]interface{}{
+		"center":    pointJson{area.Center.Lat, area.Center.Lng},
+		"threshold": area.Threshold,
+		"risk":      roundTo(area.Risk, 1),
+		"min_miles": roundTo(area.MinMiles, 2),
+		"max_miles": roundTo(area.MaxMiles, 2),
+	}
+	if nearby := nearbyToJson(area.Nearby); nearby != nil {
+		properties["nearby"] = nearby
+	}
+	return json.Marshal(struct {
+		Type       string                 `json:"type"`
+		Geometry   *polygon               `json:"geometry"`
+		Properties map[string]interface{} `json:"properties"`
+	}{"Feature", geometry, properties})
+}
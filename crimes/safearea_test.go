@@ -0,0 +1,60 @@
+package radar
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSafeArea(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	center := Point{45.535, -122.665}
+	area, err := finder.SafeArea(center, 50, 1)
+	if err != nil {
+		t.Fatal("Error finding safe area: ", err)
+	}
+	if len(area.Boundary) != SAFE_AREA_BEARINGS || area.Risk >= 50 || area.MinMiles >= area.MaxMiles || area.MaxMiles != 1 {
+		t.Fatal("Wrong safe area: ", area)
+	}
+	for _, point := range area.Boundary {
+		if risk, _ := finder.RiskScore(point); risk >= 50 {
+			t.Error("The boundary should be below the threshold: ", point, risk)
+		}
+		if miles := center.GreatCircleDistance(&point); miles > 1.01 {
+			t.Error("The boundary should be within the limit: ", point, miles)
+		}
+	}
+	wider, _ := finder.SafeArea(center, 90, 1)
+	if wider.MinMiles < area.MinMiles {
+		t.Error("A higher threshold should give a larger area: ", wider.MinMiles, area.MinMiles)
+	}
+	downtown, _ := finder.SafeArea(Point{45.5231, -122.6765}, 50, 1)
+	if len(downtown.Boundary) != 0 || downtown.Risk < 50 {
+		t.Error("A risky center should have no safe area: ", downtown)
+	}
+
+	data, err := area.ToGeoJson()
+	if err != nil {
+		t.Fatal("Error marshalling area: ", err)
+	}
+	var feature struct {
+		Type     string
+		Geometry struct {
+			Type        string
+			Coordinates [][][]float64
+		}
+	}
+	json.Unmarshal(data, &feature)
+	ring := feature.Geometry.Coordinates[0]
+	if feature.Type != "Feature" || feature.Geometry.Type != "Polygon" || len(ring) != SAFE_AREA_BEARINGS+1 || ring[0][0] != ring[len(ring)-1][0] || ring[0][0] > -122 {
+		t.Error("Wrong GeoJSON: ", string(data))
+	}
+	data, _ = downtown.ToGeoJson()
+	var empty map[string]interface{}
+	json.Unmarshal(data, &empty)
+	if empty["geometry"] != nil {
+		t.Error("No safe area should have no geometry: ", string(data))
+	}
+}
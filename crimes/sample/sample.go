@@ -0,0 +1,33 @@
+// Package sample embeds a small dataset: 2,321 crimes reported in Portland
+// in 2011, in the City's legacy layout. The server serves it as a demo, and
+// tests of radar and of programs that use it can load it without the City's
+// full data files.
+package sample
+
+import (
+	"bytes"
+	_ "embed"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// CSV is the dataset, as CSV.
+//
+//go:embed crimes.csv
+var CSV []byte
+
+// NewFinder returns a CrimeFinder loaded from the dataset.
+func NewFinder() radar.CrimeFinder {
+	return NewFinderWithOptions(radar.LoadOptions{})
+}
+
+// NewFinderWithOptions returns a CrimeFinder loaded from the dataset using
+// options. It panics if options fail the load, since the dataset itself
+// always loads.
+func NewFinderWithOptions(options radar.LoadOptions) radar.CrimeFinder {
+	finder, err := radar.NewCrimeFinderFromReader(bytes.NewReader(CSV), options)
+	if err != nil {
+		panic(err)
+	}
+	return finder
+}
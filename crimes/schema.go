@@ -0,0 +1,271 @@
+package radar
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// A Schema describes the columns of a city's CSV data. The loader reads data
+// in the layout of the City of Portland's legacy WGS84 export, so a Schema
+// converts its rows into that layout:
+//
+//	0: id, 1: date, 2: time, 3: type, 4: address, 5: neighborhood,
+//	6: precinct, 7: district, 8: latitude, 9: longitude
+//
+// followed by optional attributes that the legacy data doesn't have:
+//
+//	10: weapon, 11: domestic, 12: arrest, 13: case number
+type Schema struct {
+	Name string
+	// Columns lists, for each column of the layout, the names that column
+	// may have in the schema's header row. An empty entry means the schema
+	// doesn't have the column.
+	Columns [NUM_SCHEMA_COLUMNS][]string
+	// Positions, if set, gives the position of each column of the layout
+	// in data that has no header row, or -1 if the data doesn't have the
+	// column. Columns is then ignored.
+	Positions []int
+	// Normalize, if set, rewrites the values of a row once it is in the
+	// legacy layout, e.g. to reformat dates.
+	Normalize func(row CsvRow) error
+}
+
+// LegacySchema is the City of Portland's original 10-column WGS84 export,
+// which the loader reads without conversion.
+var LegacySchema = &Schema{
+	Name: "legacy",
+	Columns: [NUM_SCHEMA_COLUMNS][]string{
+		{"Record ID"}, {"Report Date"}, {"Report Time"}, {"Major Offense Type"},
+		{"Address"}, {"Neighborhood"}, {"Police Precinct"}, {"Police District"},
+		{"X Coordinate"}, {"Y Coordinate"},
+	},
+}
+
+// Pdx2015Schema is the City of Portland's open data export from 2015 on.
+var Pdx2015Schema = &Schema{
+	Name: "pdx2015",
+	Columns: [NUM_SCHEMA_COLUMNS][]string{
+		{"Case Number"}, {"Occur Date"}, {"Occur Time"}, {"Offense Type"},
+		{"Address"}, {"Neighborhood"}, nil, nil,
+		{"OpenDataLat", "Open Data Lat"}, {"OpenDataLon", "Open Data Lon"},
+		nil, nil, nil, {"Case Number"},
+	},
+	Normalize: normalizePdx2015,
+}
+
+// SeattleSchema is the Seattle Police Department's crime data export.
+var SeattleSchema = &Schema{
+	Name: "seattle",
+	Columns: [NUM_SCHEMA_COLUMNS][]string{
+		{"Offense ID"}, {"Offense Start DateTime"}, {"Offense Start DateTime"}, {"Offense"},
+		{"100 Block Address"}, {"MCPP"}, {"Precinct"}, {"Beat"},
+		{"Latitude"}, {"Longitude"},
+		nil, nil, nil, {"Report Number"},
+	},
+	Normalize: normalizeDateTimeColumns,
+}
+
+// ChicagoSchema is the City of Chicago's "Crimes - 2001 to Present" export.
+var ChicagoSchema = &Schema{
+	Name: "chicago",
+	Columns: [NUM_SCHEMA_COLUMNS][]string{
+		{"ID"}, {"Date"}, {"Date"}, {"Primary Type"},
+		{"Block"}, {"Community Area"}, {"District"}, {"Beat"},
+		{"Latitude"}, {"Longitude"},
+		nil, {"Domestic"}, {"Arrest"}, {"Case Number"},
+	},
+	Normalize: normalizeDateTimeColumns,
+}
+
+// RadarSchema is the layout of CSV exports, so that they can be loaded
+// again with their attributes.
+var RadarSchema = &Schema{
+	Name: "radar",
+	Columns: [NUM_SCHEMA_COLUMNS][]string{
+		{"id"}, {"date"}, {"time"}, {"type"},
+		{"address"}, {"neighborhood"}, {"precinct"}, {"district"},
+		{"lat"}, {"lng"},
+		{"weapon"}, {"domestic"}, {"arrest"}, {"case"},
+	},
+}
+
+// KnownSchemas holds the built-in schemas in the order DetectSchema tries
+// them.
+var KnownSchemas = []*Schema{LegacySchema, Pdx2015Schema, SeattleSchema, ChicagoSchema, RadarSchema}
+
+// Schemas holds the built-in schemas by name.
+var Schemas = map[string]*Schema{
+	LegacySchema.Name:  LegacySchema,
+	Pdx2015Schema.Name: Pdx2015Schema,
+	SeattleSchema.Name: SeattleSchema,
+	ChicagoSchema.Name: ChicagoSchema,
+	RadarSchema.Name:   RadarSchema,
+}
+
+// DetectSchema returns the known schema that best matches header, or nil if
+// none has the columns the loader needs. When several match, the one with
+// the most matching columns wins.
+func DetectSchema(header CsvRow) *Schema {
+	var best *Schema
+	bestMatches := 0
+	for _, schema := range KnownSchemas {
+		indexes, err := schema.columnIndexes(header)
+		if err != nil {
+			continue
+		}
+		matches := 0
+		for _, index := range indexes {
+			if index >= 0 {
+				matches += 1
+			}
+		}
+		if matches > bestMatches {
+			best = schema
+			bestMatches = matches
+		}
+	}
+	return best
+}
+
+// normalizeColumnName makes column names comparable by dropping case,
+// spaces and punctuation.
+func normalizeColumnName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// columnIndexes finds the position in header of each legacy column. A column
+// the header doesn't have gets -1. It returns an error if the header lacks a
+// column the loader needs.
+func (schema *Schema) columnIndexes(header CsvRow) ([NUM_SCHEMA_COLUMNS]int, error) {
+	var indexes [NUM_SCHEMA_COLUMNS]int
+	if schema.Positions != nil {
+		for column := range indexes {
+			indexes[column] = -1
+			if column < len(schema.Positions) {
+				indexes[column] = schema.Positions[column]
+			}
+		}
+		return indexes, nil
+	}
+	positions := make(map[string]int)
+	for i, name := range header {
+		positions[normalizeColumnName(name)] = i
+	}
+	for column, names := range schema.Columns {
+		indexes[column] = -1
+		for _, name := range names {
+			if i, ok := positions[normalizeColumnName(name)]; ok {
+				indexes[column] = i
+				break
+			}
+		}
+	}
+	for _, column := range []int{0, 1, 3, 8, 9} {
+		if indexes[column] == -1 {
+			return indexes, fmt.Errorf("header is missing the %v column for schema %v", schema.Columns[column][0], schema.Name)
+		}
+	}
+	return indexes, nil
+}
+
+// convertRow converts row to the legacy layout, taking each column from its
+// position in indexes.
+func convertRow(indexes [NUM_SCHEMA_COLUMNS]int, row CsvRow) CsvRow {
+	legacy := make(CsvRow, NUM_SCHEMA_COLUMNS)
+	for column, index := range indexes {
+		if index >= 0 && index < len(row) {
+			legacy[column] = strings.TrimSpace(row[index])
+		}
+	}
+	return legacy
+}
+
+// Layouts of dates that the City has used in its exports.
+var dateLayouts = []string{"1/2/2006", "2006-01-02", "2006-01-02T15:04:05", "2006-01-02T15:04:05.000"}
+
+// normalizeDate converts a date in any of dateLayouts to DATE_LAYOUT.
+func normalizeDate(value string) (string, error) {
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.Format(DATE_LAYOUT), nil
+		}
+	}
+	return "", fmt.Errorf("unknown date format: %q", value)
+}
+
+// normalizeTime converts a time like "1530", "930" or "15:30" to the
+// "15:30:00" form of the legacy data. Empty times stay empty.
+func normalizeTime(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	for _, layout := range []string{"15:04:05", "15:04", "1504"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format("15:04:05"), nil
+		}
+	}
+	if len(value) < 4 {
+		if t, err := time.Parse("1504", strings.Repeat("0", 4-len(value))+value); err == nil {
+			return t.Format("15:04:05"), nil
+		}
+	}
+	return "", fmt.Errorf("unknown time format: %q", value)
+}
+
+// Layouts of timestamps in exports that keep the date and time together.
+var dateTimeLayouts = []string{
+	"01/02/2006 03:04:05 PM", "01/02/2006 15:04:05", "01/02/2006 15:04",
+	"2006-01-02T15:04:05.000", "2006-01-02T15:04:05", "2006-01-02 15:04:05",
+	"2006 Jan 02 03:04:05 PM",
+}
+
+// normalizeDateTimeColumns splits the timestamp that a schema maps to both
+// the date and time columns into the legacy date and time formats.
+func normalizeDateTimeColumns(row CsvRow) error {
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.Parse(layout, row[1]); err == nil {
+			row[1] = t.Format(DATE_LAYOUT)
+			row[2] = t.Format("15:04:05")
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown timestamp format: %q", row[1])
+}
+
+// errNoCaseNumber is returned for rows whose case number has no digits.
+var errNoCaseNumber = errors.New("case number has no digits")
+
+// normalizePdx2015 converts the values of a pdx2015 row to the forms used by
+// the legacy data. Case numbers like "15-X4762502" aren't integers, so the id
+// is the case number's digits.
+func normalizePdx2015(row CsvRow) error {
+	id := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, row[0])
+	if id == "" {
+		return errNoCaseNumber
+	}
+	row[0] = id
+	date, err := normalizeDate(row[1])
+	if err != nil {
+		return err
+	}
+	row[1] = date
+	t, err := normalizeTime(row[2])
+	if err != nil {
+		return err
+	}
+	row[2] = t
+	return nil
+}
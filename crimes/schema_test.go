@@ -0,0 +1,135 @@
+package radar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A few rows in the layout of the City's open data export from 2015 on.
+const pdx2015Data = `Address,Case Number,Crime Against,Neighborhood,Occur Date,Occur Time,Offense Category,Offense Type,OpenDataLat,OpenDataLon,OpenDataX,OpenDataY,Report Date,Offense Count
+"NE WEIDLER ST and NE 1ST AVE, PORTLAND, OR 97232",15-X4762502,Property,Lloyd,5/27/2015,835,Larceny Offenses,Theft From Motor Vehicle,45.53435699129174,-122.66469510763777,7649850,686040,5/28/2015,1
+"NE SCHUYLER ST and NE 1ST AVE, PORTLAND, OR 97212",15-X4762503,Society,Eliot,12/1/2015,1530,Drug/Narcotic Offenses,Drug/Narcotic Violations,45.53579735412487,-122.66468312170824,7649852,686565,12/1/2015,1
+"UNKNOWN",15-X4762504,Property,,1/2/2015,0,Fraud Offenses,Identity Theft,,,,,1/2/2015,1
+"BAD DATE",15-X4762505,Property,Lloyd,yesterday,0,Fraud Offenses,Identity Theft,45.5,-122.6,,,1/2/2015,1
+`
+
+func TestNormalizeDate(t *testing.T) {
+	for value, expected := range map[string]string{"5/27/2015": "05/27/2015", "2015-05-27": "05/27/2015", "05/27/2015": "05/27/2015"} {
+		actual, err := normalizeDate(value)
+		if err != nil || actual != expected {
+			t.Error("normalizeDate(", value, ") returned ", actual, err)
+		}
+	}
+	if _, err := normalizeDate("yesterday"); err == nil {
+		t.Error("normalizeDate should return an error for an unknown format")
+	}
+}
+
+func TestNormalizeTime(t *testing.T) {
+	for value, expected := range map[string]string{"1530": "15:30:00", "835": "08:35:00", "0": "00:00:00", "15:30": "15:30:00", "": ""} {
+		actual, err := normalizeTime(value)
+		if err != nil || actual != expected {
+			t.Error("normalizeTime(", value, ") returned ", actual, err)
+		}
+	}
+}
+
+func TestReadCrimesWithSchemaPdx2015(t *testing.T) {
+	rows, rowErrors, err := readCrimesWithSchema(strings.NewReader(pdx2015Data), Pdx2015Schema)
+	if err != nil {
+		t.Fatal("readCrimesWithSchema returned an error: ", err)
+	}
+	if len(rows) != 2 {
+		t.Fatal("Wrong number of rows: ", len(rows))
+	}
+	expected := CsvRow{"154762502", "05/27/2015", "08:35:00", "Theft From Motor Vehicle", "NE WEIDLER ST and NE 1ST AVE, PORTLAND, OR 97232", "Lloyd", "", "", "45.53435699129174", "-122.66469510763777"}
+	for i := range expected {
+		if rows[0][i] != expected[i] {
+			t.Error("Row was converted wrong: ", rows[0])
+			break
+		}
+	}
+	if len(rowErrors) != 2 || rowErrors[0].Record != 4 || rowErrors[1].Record != 5 {
+		t.Error("Wrong row errors: ", rowErrors)
+	}
+}
+
+func TestReadCrimesWithSchemaMissingColumn(t *testing.T) {
+	data := "Case Number,Occur Date,Offense Type\n15-X1,5/27/2015,Theft\n"
+	_, _, err := readCrimesWithSchema(strings.NewReader(data), Pdx2015Schema)
+	if err == nil {
+		t.Error("readCrimesWithSchema should return an error when the header lacks coordinates")
+	}
+}
+
+func TestCrimeFinderPdx2015(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "pdx2015.csv")
+	os.WriteFile(filename, []byte(pdx2015Data), 0644)
+	finder, err := NewCrimeFinderWithOptions(filename, LoadOptions{Schema: Schemas["pdx2015"]})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	if finder.Report.Crimes != 2 || finder.Report.Locations != 2 {
+		t.Error("Wrong number of crimes or locations: ", finder.Report.Crimes, finder.Report.Locations)
+	}
+	result, _ := finder.FindNear(Point{45.53435699129174, -122.66469510763777})
+	if len(result.Crimes()) != 2 || result.Crimes()[0].Id != 154762502 {
+		t.Error("FindNear returned the wrong crimes: ", result.Crimes())
+	}
+}
+
+const seattleData = `Report Number,Offense ID,Offense Start DateTime,Offense End DateTime,Report DateTime,Group A B,Crime Against Category,Offense Parent Group,Offense,Offense Code,Precinct,Sector,Beat,MCPP,100 Block Address,Longitude,Latitude
+2020-044620,12605873663,02/05/2020 10:10:00 AM,,02/05/2020 11:24:31 AM,A,SOCIETY,DRUG/NARCOTIC OFFENSES,Drug/Narcotic Violations,35A,W,Q,Q1,MAGNOLIA,32XX BLOCK OF 23RD AVE W,-122.38591,47.64938
+`
+
+const chicagoData = `ID,Case Number,Date,Block,IUCR,Primary Type,Description,Location Description,Arrest,Domestic,Beat,District,Ward,Community Area,FBI Code,X Coordinate,Y Coordinate,Year,Updated On,Latitude,Longitude,Location
+11034701,JA366925,01/01/2001 11:00:00 AM,016XX E 86TH PL,1153,DECEPTIVE PRACTICE,FINANCIAL IDENTITY THEFT OVER $ 300,RESIDENCE,false,false,0412,004,8,45,11,,,2001,08/05/2017 03:50:08 PM,41.738,-87.584,
+`
+
+func TestDetectSchema(t *testing.T) {
+	headers := map[string]string{
+		"legacy":  "Record ID,Report Date,Report Time,Major Offense Type,Address,Neighborhood,Police Precinct,Police District,X Coordinate,Y Coordinate",
+		"pdx2015": strings.Split(pdx2015Data, "\n")[0],
+		"seattle": strings.Split(seattleData, "\n")[0],
+		"chicago": strings.Split(chicagoData, "\n")[0],
+	}
+	for name, header := range headers {
+		schema := DetectSchema(strings.Split(header, ","))
+		if schema == nil || schema.Name != name {
+			t.Error("Did not detect the ", name, " schema: ", schema)
+		}
+	}
+	if DetectSchema(CsvRow{"13807517", "12/01/2011", "01:00:00", "Liquor Laws"}) != nil {
+		t.Error("DetectSchema should not match a row of data")
+	}
+}
+
+func TestReadCrimesWithDetectedSchema(t *testing.T) {
+	expected := map[string]CsvRow{
+		seattleData: {"12605873663", "02/05/2020", "10:10:00", "Drug/Narcotic Violations", "32XX BLOCK OF 23RD AVE W", "MAGNOLIA", "W", "Q1", "47.64938", "-122.38591"},
+		chicagoData: {"11034701", "01/01/2001", "11:00:00", "DECEPTIVE PRACTICE", "016XX E 86TH PL", "45", "004", "0412", "41.738", "-87.584"},
+	}
+	for data, row := range expected {
+		rows, rowErrors, err := readCrimesWithSchema(strings.NewReader(data), nil)
+		if err != nil || len(rows) != 1 || len(rowErrors) != 0 {
+			t.Error("readCrimesWithSchema could not read detected data: ", err, rowErrors)
+			continue
+		}
+		for i := range row {
+			if rows[0][i] != row[i] {
+				t.Error("Row was converted wrong: ", rows[0])
+				break
+			}
+		}
+	}
+}
+
+func TestReadCrimesWithoutHeaderFallsBackToLegacy(t *testing.T) {
+	data := "13690825,05/27/2011,08:35:00,Liquor Laws,,,,,45.5,-122.6\n"
+	rows, _, err := readCrimesWithSchema(strings.NewReader(data), nil)
+	if err != nil || len(rows) != 1 || rows[0][0] != "13690825" {
+		t.Error("Data without a header should be read as legacy data: ", rows, err)
+	}
+}
@@ -0,0 +1,103 @@
+package radar
+
+import (
+	"sort"
+	"sync"
+)
+
+// Density returns the density of crimes at point under DefaultScorer.
+func (finder *CrimeFinder) Density(point Point) (float64, error) {
+	return densityAt(finder, point, DefaultScorer)
+}
+
+// densityAt returns the density of crimes at point: the sum of the weights
+// scorer gives the crimes a search from point finds.
+func densityAt(finder *CrimeFinder, point Point, scorer Scorer) (float64, error) {
+	nearby, err := finder.FindNear(point)
+	if err != nil {
+		return 0, err
+	}
+	density := 0.0
+	for _, location := range nearby.Locations {
+		for _, crime := range location.Crimes {
+			density += scorer.Weight(point, location, crime)
+		}
+	}
+	return density, nil
+}
+
+// A RiskModel scores the risk of crime at points of a finder's data under a
+// Scorer, by ranking the density at a point against the densities at the
+// data's locations.
+type RiskModel struct {
+	finder *CrimeFinder
+	scorer Scorer
+	// sorted holds the densities at the finder's locations, in order.
+	sorted []float64
+}
+
+// NewRiskModel builds a RiskModel of the finder's data under scorer. It
+// finds the density at every location, so it's worth keeping until the
+// data changes.
+func (finder *CrimeFinder) NewRiskModel(scorer Scorer) *RiskModel {
+	model := &RiskModel{finder: finder, scorer: scorer}
+	model.sorted = make([]float64, 0, len(finder.LocationLookup))
+	for _, location := range finder.Locations() {
+		if density, err := densityAt(finder, *location.Point, scorer); err == nil {
+			model.sorted = append(model.sorted, density)
+		}
+	}
+	sort.Float64s(model.sorted)
+	return model
+}
+
+// riskModel holds a finder's RiskModel under DefaultScorer. It's built the
+// first time it's needed and buildIndexes replaces it when the data
+// changes.
+type riskModel struct {
+	once  sync.Once
+	model *RiskModel
+}
+
+// RiskModel returns the finder's RiskModel under DefaultScorer.
+func (finder *CrimeFinder) RiskModel() *RiskModel {
+	if finder.risk == nil {
+		return finder.NewRiskModel(DefaultScorer)
+	}
+	finder.risk.once.Do(func() {
+		finder.risk.model = finder.NewRiskModel(DefaultScorer)
+	})
+	return finder.risk.model
+}
+
+// RiskScore returns the risk of crime at point under DefaultScorer.
+func (finder *CrimeFinder) RiskScore(point Point) (float64, error) {
+	return finder.RiskModel().RiskScore(point)
+}
+
+// Density returns the density of crimes at point under the model's
+// scorer.
+func (model *RiskModel) Density(point Point) (float64, error) {
+	return densityAt(model.finder, point, model.scorer)
+}
+
+// RiskScore returns the risk of crime at point, from 0 to 100: the
+// percentage of the data's locations that have a lower density of crimes
+// than point. A point with no crimes nearby scores 0, and one as dense as
+// the busiest place in the data scores close to 100.
+func (model *RiskModel) RiskScore(point Point) (float64, error) {
+	density, err := model.Density(point)
+	if err != nil {
+		return 0, err
+	}
+	return riskOf(density, model.sorted), nil
+}
+
+// riskOf returns the percentage of sorted that's below density.
+func riskOf(density float64, sorted []float64) float64 {
+	if len(sorted) == 0 || density <= 0 {
+		return 0
+	}
+	below := sort.SearchFloat64s(sorted, density)
+	return 100 * float64(below) / float64(len(sorted))
+}
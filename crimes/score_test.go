@@ -0,0 +1,29 @@
+package radar
+
+import "testing"
+
+func TestRiskScore(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	downtown, err := finder.RiskScore(Point{45.5231, -122.6765})
+	if err != nil {
+		t.Fatal("Error scoring a point: ", err)
+	}
+	quiet, _ := finder.RiskScore(Point{45.46, -122.65})
+	nowhere, _ := finder.RiskScore(Point{45.6, -122.5})
+	if downtown < 90 || downtown > 100 || quiet >= downtown || nowhere != 0 {
+		t.Error("Wrong risk scores: ", downtown, quiet, nowhere)
+	}
+
+	near, _ := finder.Density(Point{45.5231, -122.6765})
+	far, _ := finder.Density(Point{45.5231 + HALF_MILE_LAT/2, -122.6765})
+	if near <= far || far <= 0 {
+		t.Error("Density should decay with distance: ", near, far)
+	}
+	sorted := finder.RiskModel().sorted
+	if len(sorted) != len(finder.LocationLookup) || riskOf(sorted[len(sorted)-1]+1, sorted) != 100 {
+		t.Error("Risk should be ranked against every location: ", len(sorted))
+	}
+}
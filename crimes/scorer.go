@@ -0,0 +1,173 @@
+package radar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// DECAY_MILES is the distance at which a crime counts half as much toward
+// the density at a point as a crime at the point itself.
+const DECAY_MILES = 0.1
+
+// DEFAULT_HALF_LIFE is the age at which a crime counts half as much as one
+// that just happened, for the built-in scorers that weigh recent crimes
+// more.
+const DEFAULT_HALF_LIFE = 90 * 24 * time.Hour
+
+// A Scorer is a methodology for scoring the risk of crime: it weighs how
+// much each crime a search from a point finds counts toward the density of
+// crime there. Risk scores rank densities against each other, so only the
+// relative sizes of weights matter.
+type Scorer interface {
+	Weight(point Point, location *CrimeLocation, crime *Crime) float64
+}
+
+// ScorerFunc adapts a function to a Scorer.
+type ScorerFunc func(point Point, location *CrimeLocation, crime *Crime) float64
+
+func (f ScorerFunc) Weight(point Point, location *CrimeLocation, crime *Crime) float64 {
+	return f(point, location, crime)
+}
+
+// DistanceDecay weighs crimes by their distance from the point: a crime
+// counts for half as much every HalfMiles further away it is.
+type DistanceDecay struct {
+	HalfMiles float64
+}
+
+func (decay DistanceDecay) Weight(point Point, location *CrimeLocation, crime *Crime) float64 {
+	return math.Pow(0.5, point.GreatCircleDistance(location.Point)/decay.HalfMiles)
+}
+
+// SeverityWeights weighs crimes by how serious they are: by the weight of
+// their type, matched regardless of case, or else of their category in the
+// taxonomy. Crimes with neither weigh 1.
+type SeverityWeights struct {
+	Types      map[string]float64
+	Categories map[string]float64
+}
+
+func (weights SeverityWeights) Weight(point Point, location *CrimeLocation, crime *Crime) float64 {
+	if weight, ok := weights.Types[indexKey(crime.Type)]; ok {
+		return weight
+	}
+	if weight, ok := weights.Categories[Classify(crime.Type).Category]; ok {
+		return weight
+	}
+	return 1
+}
+
+// TimeDecay weighs crimes by their age at At, or now if At is zero: a
+// crime counts for half as much for every HalfLife that's passed since it
+// occurred. Crimes whose dates can't be parsed weigh 1.
+type TimeDecay struct {
+	HalfLife time.Duration
+	At       time.Time
+}
+
+func (decay TimeDecay) Weight(point Point, location *CrimeLocation, crime *Crime) float64 {
+	occurred, err := time.Parse(DATE_LAYOUT, crime.Date)
+	if err != nil {
+		return 1
+	}
+	at := decay.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return math.Pow(0.5, float64(at.Sub(occurred))/float64(decay.HalfLife))
+}
+
+// Product weighs crimes by the product of the weights its scorers give
+// them, to combine methodologies.
+type Product []Scorer
+
+func (scorers Product) Weight(point Point, location *CrimeLocation, crime *Crime) float64 {
+	weight := 1.0
+	for _, scorer := range scorers {
+		weight *= scorer.Weight(point, location, crime)
+	}
+	return weight
+}
+
+// DefaultSeverity weighs crimes against persons most and crimes against
+// society least.
+var DefaultSeverity = SeverityWeights{Categories: map[string]float64{
+	PersonCategory:   3,
+	PropertyCategory: 2,
+	SocietyCategory:  1,
+}}
+
+// DefaultScorer weighs crimes by distance alone.
+var DefaultScorer Scorer = DistanceDecay{DECAY_MILES}
+
+// Scorers holds scorers by name. The built-in ones all decay with
+// distance; programs that embed the package can add their own.
+var Scorers = map[string]Scorer{
+	"distance":        DefaultScorer,
+	"severity":        Product{DefaultScorer, DefaultSeverity},
+	"recent":          Product{DefaultScorer, TimeDecay{HalfLife: DEFAULT_HALF_LIFE}},
+	"severity-recent": Product{DefaultScorer, DefaultSeverity, TimeDecay{HalfLife: DEFAULT_HALF_LIFE}},
+}
+
+// A ScorerConfig describes a scorer built from the built-in methodologies,
+// so that one can be defined in a file instead of code.
+type ScorerConfig struct {
+	// DecayMiles is the HalfMiles of the distance decay, or DECAY_MILES if
+	// it's 0.
+	DecayMiles float64 `json:"decay_miles"`
+	// Types and Categories are severity weights. If both are empty,
+	// crimes aren't weighed by severity.
+	Types      map[string]float64 `json:"types"`
+	Categories map[string]float64 `json:"categories"`
+	// HalfLifeDays is the half life of the time decay, in days. If it's 0,
+	// crimes aren't weighed by age.
+	HalfLifeDays float64 `json:"half_life_days"`
+}
+
+// Scorer returns the scorer the config describes.
+func (config ScorerConfig) Scorer() (Scorer, error) {
+	if config.DecayMiles < 0 || config.HalfLifeDays < 0 {
+		return nil, fmt.Errorf("decay_miles and half_life_days can't be negative")
+	}
+	for category := range config.Categories {
+		if !IsCategory(category) {
+			return nil, fmt.Errorf("unknown category: %q", category)
+		}
+	}
+	decay := DistanceDecay{DECAY_MILES}
+	if config.DecayMiles > 0 {
+		decay.HalfMiles = config.DecayMiles
+	}
+	scorer := Product{decay}
+	if len(config.Types) > 0 || len(config.Categories) > 0 {
+		types := make(map[string]float64, len(config.Types))
+		for crimeType, weight := range config.Types {
+			types[indexKey(crimeType)] = weight
+		}
+		scorer = append(scorer, SeverityWeights{types, config.Categories})
+	}
+	if config.HalfLifeDays > 0 {
+		scorer = append(scorer, TimeDecay{HalfLife: time.Duration(config.HalfLifeDays * float64(24*time.Hour))})
+	}
+	return scorer, nil
+}
+
+// LoadScorer reads a ScorerConfig from a JSON file and returns the scorer
+// it describes.
+func LoadScorer(filename string) (Scorer, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var config ScorerConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("invalid scorer config %v: %v", filename, err)
+	}
+	return config.Scorer()
+}
@@ -0,0 +1,98 @@
+package radar
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScorers(t *testing.T) {
+	point := Point{45.5, -122.6}
+	location := &CrimeLocation{Point: &Point{45.5 + HALF_MILE_LAT/5, -122.6}}
+	homicide := &Crime{Type: "Homicide", Date: "06/01/2011"}
+	drugs := &Crime{Type: "Drugs", Date: "12/01/2011"}
+
+	if weight := (DistanceDecay{DECAY_MILES}).Weight(point, location, homicide); math.Abs(weight-0.5) > 0.01 {
+		t.Error("A crime a tenth of a mile away should count about half: ", weight)
+	}
+	if weight := DefaultSeverity.Weight(point, location, homicide); weight != 3 {
+		t.Error("Wrong weight for a crime against a person: ", weight)
+	}
+	custom := SeverityWeights{Types: map[string]float64{"drugs": 0.5}, Categories: map[string]float64{SocietyCategory: 2}}
+	if weight := custom.Weight(point, location, drugs); weight != 0.5 {
+		t.Error("A type's weight should win over its category's: ", weight)
+	}
+	if weight := custom.Weight(point, location, &Crime{Type: "Unknown"}); weight != 1 {
+		t.Error("Crimes without a weight should weigh 1: ", weight)
+	}
+	decay := TimeDecay{HalfLife: 30 * 24 * time.Hour, At: time.Date(2011, 12, 31, 0, 0, 0, 0, time.UTC)}
+	if weight := decay.Weight(point, location, drugs); math.Abs(weight-0.5) > 0.01 {
+		t.Error("A crime a half life old should count about half: ", weight)
+	}
+	if weight := decay.Weight(point, location, &Crime{Date: "not a date"}); weight != 1 {
+		t.Error("Undated crimes should weigh 1: ", weight)
+	}
+	product := Product{DefaultSeverity, ScorerFunc(func(Point, *CrimeLocation, *Crime) float64 { return 0.5 })}
+	if weight := product.Weight(point, location, homicide); weight != 1.5 {
+		t.Error("A product should multiply its weights: ", weight)
+	}
+	for _, name := range []string{"distance", "severity", "recent", "severity-recent"} {
+		if _, ok := Scorers[name]; !ok {
+			t.Error("Missing built-in scorer: ", name)
+		}
+	}
+}
+
+func TestRiskModel(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	point := Point{45.5231, -122.6765}
+	byDistance := finder.NewRiskModel(DefaultScorer)
+	expected, _ := finder.RiskScore(point)
+	if risk, _ := byDistance.RiskScore(point); risk != expected {
+		t.Error("The default model should score like the finder: ", risk, expected)
+	}
+	// Only homicides count, and there are few of them.
+	homicides := finder.NewRiskModel(Product{DefaultScorer, SeverityWeights{Categories: map[string]float64{PersonCategory: 0, PropertyCategory: 0, SocietyCategory: 0, OtherCategory: 0}, Types: map[string]float64{"homicide": 1}}})
+	density, _ := homicides.Density(point)
+	all, _ := byDistance.Density(point)
+	if density >= all {
+		t.Error("A scorer should change the density: ", density, all)
+	}
+	if score, _ := homicides.ScoreRoute([]Point{point, {45.5262, -122.6680}}); len(score.Segments) != 1 {
+		t.Error("A model should score routes: ", score)
+	}
+}
+
+func TestLoadScorer(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "scorer.json")
+	os.WriteFile(filename, []byte(`{"decay_miles": 0.2, "types": {"Homicide": 10}, "categories": {"person": 4}, "half_life_days": 30}`), 0644)
+	scorer, err := LoadScorer(filename)
+	if err != nil {
+		t.Fatal("Error loading scorer: ", err)
+	}
+	product, ok := scorer.(Product)
+	if !ok || len(product) != 3 || product[0] != (DistanceDecay{0.2}) {
+		t.Fatal("Wrong scorer: ", scorer)
+	}
+	if weight := product[1].Weight(Point{}, nil, &Crime{Type: "homicide"}); weight != 10 {
+		t.Error("Types should match regardless of case: ", weight)
+	}
+	if product[2].(TimeDecay).HalfLife != 30*24*time.Hour {
+		t.Error("Wrong half life: ", product[2])
+	}
+	for _, bad := range []string{`{"categories": {"people": 2}}`, `{"decay_miles": -1}`, `{"decay": 1}`, `not json`} {
+		os.WriteFile(filename, []byte(bad), 0644)
+		if _, err := LoadScorer(filename); err == nil {
+			t.Error("Bad scorer config was loaded: ", bad)
+		}
+	}
+	if _, err := LoadScorer(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("A missing file should be an error")
+	}
+}
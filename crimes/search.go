@@ -0,0 +1,188 @@
+package radar
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MAX_SEARCH_RADIUS is the largest radius, in miles, of a search near a
+// point. Searches without a radius cover FindNear's half-mile box.
+const MAX_SEARCH_RADIUS = 5
+
+// A SearchSort is an order for the locations of a search.
+type SearchSort string
+
+// The orders of locations that a search can ask for.
+const (
+	// LocationSort leaves locations in the order of Locations().
+	LocationSort SearchSort = ""
+	// DistanceSort puts the nearest locations first.
+	DistanceSort SearchSort = "distance"
+	// RecentSort puts the locations with the newest crimes first.
+	RecentSort SearchSort = "recent"
+)
+
+// SearchFilters are the filters of a search near a point. Crimes must
+// match every one that's set.
+type SearchFilters struct {
+	// Category is one of the taxonomy's categories, like PersonCategory.
+	Category   string          `json:"category,omitempty"`
+	Attributes AttributeFilter `json:"attributes"`
+	Filter     SearchFilter    `json:"filter"`
+	// ExcludedTypes are crime types to leave out, ignoring case.
+	ExcludedTypes []string `json:"exclude_types,omitempty"`
+}
+
+// IsEmpty returns true if the filters match every crime.
+func (filters SearchFilters) IsEmpty() bool {
+	return filters.Category == "" && filters.Attributes.IsEmpty() && filters.Filter.IsEmpty() && len(filters.ExcludedTypes) == 0
+}
+
+// excludes returns true if crime is of one of the filters' ExcludedTypes.
+func (filters SearchFilters) excludes(crime *Crime) bool {
+	for _, excluded := range filters.ExcludedTypes {
+		if indexKey(excluded) == indexKey(crime.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchOptions are the settings for a search near a point. Fields left at
+// their zero values search the way FindNear does.
+type SearchOptions struct {
+	// Radius is the most distance, in miles, of a location from the query,
+	// searched the way FindWithinRadius does. 0 means the area FindNear
+	// covers.
+	Radius float64 `json:"radius,omitempty"`
+	// Limit is the most locations to return, after sorting. 0 means all.
+	Limit int        `json:"limit,omitempty"`
+	Sort  SearchSort `json:"sort,omitempty"`
+	// Filters are the crimes to find.
+	Filters SearchFilters `json:"filters"`
+	// IncludeDistance adds the distance of each location from the query
+	// to the result's JSON.
+	IncludeDistance bool `json:"include_distance,omitempty"`
+	// MaxPerLocation is the most crimes to return at each location, which
+	// keeps its newest. 0 means all.
+	MaxPerLocation int `json:"max_per_location,omitempty"`
+}
+
+// Validate returns an error describing the first option that isn't valid.
+func (options SearchOptions) Validate() error {
+	if options.Radius < 0 || options.Radius > MAX_SEARCH_RADIUS {
+		return fmt.Errorf("invalid radius: %v is not between 0 and %v miles", options.Radius, MAX_SEARCH_RADIUS)
+	}
+	if options.Limit < 0 {
+		return fmt.Errorf("invalid limit: %v", options.Limit)
+	}
+	switch options.Sort {
+	case LocationSort, DistanceSort, RecentSort:
+	default:
+		return fmt.Errorf("unknown sort: %q", options.Sort)
+	}
+	if options.MaxPerLocation < 0 {
+		return fmt.Errorf("invalid max per location: %v", options.MaxPerLocation)
+	}
+	if category := options.Filters.Category; category != "" && !IsCategory(category) {
+		return fmt.Errorf("unknown category: %q", category)
+	}
+	if month := options.Filters.Filter.Month; month != "" {
+		if _, err := time.Parse(MONTH_LAYOUT, month); err != nil {
+			return fmt.Errorf("invalid month: %q", month)
+		}
+	}
+	return nil
+}
+
+// FindNearWithOptions works like FindNear, but searches the way options
+// ask. It returns an error if they aren't valid.
+func (finder *CrimeFinder) FindNearWithOptions(query Point, options SearchOptions) (SearchResult, error) {
+	return finder.findNearWithOptions(query, options, nil)
+}
+
+// FindNearWithOptionsExplained works like FindNearWithOptions and also
+// sets the result's Explanation to describe how the search ran.
+func (finder *CrimeFinder) FindNearWithOptionsExplained(query Point, options SearchOptions) (SearchResult, error) {
+	return finder.findNearWithOptions(query, options, newExplanation())
+}
+
+// findNearWithOptions searches near query the way options ask, filling in
+// explanation if it isn't nil. Filters the secondary indexes don't cover
+// are applied to the result of the indexed search.
+func (finder *CrimeFinder) findNearWithOptions(query Point, options SearchOptions, explanation *Explanation) (SearchResult, error) {
+	if err := options.Validate(); err != nil {
+		return SearchResult{Query: &query, Locations: make([]*CrimeLocation, 0)}, err
+	}
+	var nearby SearchResult
+	var err error
+	if options.Radius > 0 {
+		// The secondary indexes cover FindNear's box, so a search with a
+		// radius filters the locations within it.
+		if nearby, err = finder.findWithinRadius(query, options.Radius, explanation); err != nil {
+			return nearby, err
+		}
+		if !options.Filters.Filter.IsEmpty() {
+			nearby = nearby.Filter(options.Filters.Filter.Matches)
+		}
+	} else if nearby, err = finder.findNearFiltered(query, options.Filters.Filter, explanation); err != nil {
+		return nearby, err
+	}
+	if options.Filters.Category != "" {
+		nearby = nearby.FilterCategory(options.Filters.Category)
+	}
+	if !options.Filters.Attributes.IsEmpty() {
+		nearby = nearby.Filter(options.Filters.Attributes.Matches)
+	}
+	if len(options.Filters.ExcludedTypes) > 0 {
+		nearby = nearby.Filter(func(crime *Crime) bool { return !options.Filters.excludes(crime) })
+	}
+	return options.Apply(nearby), nil
+}
+
+// Apply narrows, sorts and limits the locations of nearby as the options
+// ask, without filtering their crimes. Locations whose crimes are cut are
+// copies, so the finder's aren't changed.
+func (options SearchOptions) Apply(nearby SearchResult) SearchResult {
+	nearby.IncludeDistance = options.IncludeDistance
+	if options.MaxPerLocation > 0 {
+		kept := make([]*CrimeLocation, 0, len(nearby.Locations))
+		for _, location := range nearby.Locations {
+			if len(location.Crimes) > options.MaxPerLocation {
+				crimes := append(make([]*Crime, 0, len(location.Crimes)), location.Crimes...)
+				sort.SliceStable(crimes, func(i, j int) bool { return newer(crimes[i], crimes[j]) })
+				location = &CrimeLocation{location.Point, crimes[:options.MaxPerLocation]}
+			}
+			kept = append(kept, location)
+		}
+		nearby.Locations = kept
+	}
+	switch options.Sort {
+	case DistanceSort:
+		distances := make(map[*CrimeLocation]float64, len(nearby.Locations))
+		for _, location := range nearby.Locations {
+			distances[location] = nearby.Query.GreatCircleDistance(location.Point)
+		}
+		sort.SliceStable(nearby.Locations, func(i, j int) bool {
+			return distances[nearby.Locations[i]] < distances[nearby.Locations[j]]
+		})
+	case RecentSort:
+		newest := make(map[*CrimeLocation]*Crime, len(nearby.Locations))
+		for _, location := range nearby.Locations {
+			for _, crime := range location.Crimes {
+				if newest[location] == nil || newer(crime, newest[location]) {
+					newest[location] = crime
+				}
+			}
+		}
+		sort.SliceStable(nearby.Locations, func(i, j int) bool {
+			a, b := newest[nearby.Locations[i]], newest[nearby.Locations[j]]
+			return a != nil && (b == nil || newer(a, b))
+		})
+	}
+	if options.Limit > 0 && len(nearby.Locations) > options.Limit {
+		nearby.Locations = nearby.Locations[:options.Limit]
+	}
+	return nearby
+}
@@ -0,0 +1,110 @@
+package radar
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSearchOptionsValidate(t *testing.T) {
+	if err := (SearchOptions{}).Validate(); err != nil {
+		t.Error("The zero options should be valid: ", err)
+	}
+	valid := SearchOptions{Radius: 0.25, Limit: 5, Sort: DistanceSort, MaxPerLocation: 2, Filters: SearchFilters{Category: PropertyCategory}}
+	if err := valid.Validate(); err != nil {
+		t.Error("Options should be valid: ", err)
+	}
+	for _, options := range []SearchOptions{
+		{Radius: -1},
+		{Radius: MAX_SEARCH_RADIUS + 0.1},
+		{Limit: -1},
+		{Sort: "alphabetical"},
+		{MaxPerLocation: -1},
+		{Filters: SearchFilters{Category: "crimes"}},
+		{Filters: SearchFilters{Filter: SearchFilter{Month: "May"}}},
+	} {
+		if err := options.Validate(); err == nil {
+			t.Error("Options should not be valid: ", options)
+		}
+	}
+}
+
+func TestFindNearWithOptions(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	query := Point{45.5184, -122.6554}
+	all, _ := finder.FindNear(query)
+	result, err := finder.FindNearWithOptions(query, SearchOptions{})
+	if err != nil || len(result.Locations) != len(all.Locations) || len(result.Crimes()) != len(all.Crimes()) {
+		t.Error("The zero options should search like FindNear: ", err, len(result.Locations), len(all.Locations))
+	}
+
+	result, _ = finder.FindNearWithOptions(query, SearchOptions{Radius: 0.25, Sort: DistanceSort, Limit: 3})
+	if len(result.Locations) != 3 {
+		t.Fatal("Wrong number of locations: ", len(result.Locations))
+	}
+	last := 0.0
+	for _, location := range result.Locations {
+		miles := query.GreatCircleDistance(location.Point)
+		if miles > 0.25 || miles < last {
+			t.Error("Locations should be within the radius, nearest first: ", miles, last)
+		}
+		last = miles
+	}
+
+	result, _ = finder.FindNearWithOptions(query, SearchOptions{MaxPerLocation: 1, Sort: RecentSort})
+	if len(result.Locations) != len(all.Locations) {
+		t.Error("Wrong number of locations: ", len(result.Locations))
+	}
+	for i, location := range result.Locations {
+		if len(location.Crimes) != 1 {
+			t.Error("Locations should have one crime: ", len(location.Crimes))
+		}
+		if i > 0 && newer(location.Crimes[0], result.Locations[i-1].Crimes[0]) {
+			t.Error("Locations should be newest first: ", result.Locations[i-1].Crimes[0], location.Crimes[0])
+		}
+	}
+	for _, location := range finder.Locations() {
+		if len(location.Crimes) == 0 {
+			t.Fatal("MaxPerLocation should not change the finder's locations")
+		}
+	}
+	if again, _ := finder.FindNear(query); len(again.Crimes()) != len(all.Crimes()) {
+		t.Error("MaxPerLocation should not change the finder's locations: ", len(again.Crimes()))
+	}
+
+	result, _ = finder.FindNearWithOptions(query, SearchOptions{Filters: SearchFilters{Category: PropertyCategory}})
+	for _, crime := range result.Crimes() {
+		if Classify(crime.Type).Category != PropertyCategory {
+			t.Error("Result has a crime outside the category: ", crime.Type)
+		}
+	}
+
+	result, _ = finder.FindNearWithOptions(query, SearchOptions{Filters: SearchFilters{ExcludedTypes: []string{"larceny", "Liquor Laws"}}})
+	if len(result.Crimes()) == 0 || len(result.Crimes()) >= len(all.Crimes()) {
+		t.Error("Wrong number of crimes with types excluded: ", len(result.Crimes()))
+	}
+	for _, crime := range result.Crimes() {
+		if crime.Type == "Larceny" || crime.Type == "Liquor Laws" {
+			t.Error("Result has an excluded type: ", crime.Type)
+		}
+	}
+
+	if _, err := finder.FindNearWithOptions(query, SearchOptions{Sort: "alphabetical"}); err == nil {
+		t.Error("Invalid options should be an error")
+	}
+}
+
+func TestSearchResultIncludeDistance(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	result, _ := finder.FindNearWithOptions(Point{45.5184, -122.6554}, SearchOptions{IncludeDistance: true, Limit: 1, Sort: DistanceSort})
+	encoded, err := result.ToJson()
+	if err != nil {
+		t.Fatal("ToJson returned an error: ", err)
+	}
+	if !bytes.Contains(encoded, []byte(`},"distance_miles":`)) {
+		t.Error("JSON should have the location's distance: ", string(encoded))
+	}
+	result.IncludeDistance = false
+	if encoded, _ = result.ToJson(); bytes.Contains(encoded, []byte("distance_miles")) {
+		t.Error("JSON should not have distances unless asked: ", string(encoded))
+	}
+}
@@ -0,0 +1,246 @@
+package radar
+
+import (
+	"strings"
+	"time"
+
+	"github.com/abrookins/radar/internal/kdtree"
+	"github.com/abrookins/radar/internal/roaring"
+)
+
+// The name of the secondary indexes, as reported in an Explanation.
+const SECONDARY_INDEX_NAME = "attributes"
+
+// MONTH_LAYOUT is the layout of the months that searches filter by.
+const MONTH_LAYOUT = "2006-01"
+
+// A SearchFilter narrows a search to crimes of a type, in a month and in a
+// neighborhood. Empty fields match every crime. Types and neighborhoods
+// match regardless of case.
+type SearchFilter struct {
+	Type string `json:"type,omitempty"`
+	// Month is a month in MONTH_LAYOUT, like "2011-07".
+	Month        string `json:"month,omitempty"`
+	Neighborhood string `json:"neighborhood,omitempty"`
+}
+
+// IsEmpty returns true if the filter matches every crime.
+func (filter SearchFilter) IsEmpty() bool {
+	return filter.Type == "" && filter.Month == "" && filter.Neighborhood == ""
+}
+
+// Matches returns true if crime has every value the filter asks for.
+func (filter SearchFilter) Matches(crime *Crime) bool {
+	if filter.Type != "" && indexKey(filter.Type) != indexKey(crime.Type) {
+		return false
+	}
+	if filter.Month != "" && filter.Month != crimeMonth(crime) {
+		return false
+	}
+	if filter.Neighborhood != "" && indexKey(filter.Neighborhood) != indexKey(crime.Neighborhood) {
+		return false
+	}
+	return true
+}
+
+// indexKey is the key a value is indexed under, so that values that differ
+// only in case and spacing share one.
+func indexKey(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// crimeMonth returns the month of crime in MONTH_LAYOUT, or "" if its date
+// can't be parsed.
+func crimeMonth(crime *Crime) string {
+	date, err := time.Parse(DATE_LAYOUT, crime.Date)
+	if err != nil {
+		return ""
+	}
+	return date.Format(MONTH_LAYOUT)
+}
+
+// An indexedCrime is a crime in the secondary indexes and the location it
+// occurred at.
+type indexedCrime struct {
+	crime    *Crime
+	location *CrimeLocation
+}
+
+// secondaryIndexes map the types, months and neighborhoods of crimes to
+// bitmaps of the crimes that have them. A crime is identified by its
+// position in crimes, which are in the order of the finder's locations, so
+// the crimes of a location have consecutive positions, starting at the
+// location's entry in firsts. Filters and the area of a search are then
+// combined by intersecting bitmaps. density counts the crimes in each cell
+// of the grid, for planning searches, and suggestions complete the types,
+// categories and neighborhoods of crimes for search boxes.
+type secondaryIndexes struct {
+	crimes        []indexedCrime
+	firsts        map[*CrimeLocation]uint32
+	types         map[string]*roaring.Bitmap
+	months        map[string]*roaring.Bitmap
+	neighborhoods map[string]*roaring.Bitmap
+	density       map[GridCell]int
+	suggestions   *suggestionIndex
+}
+
+// addTo adds position to the bitmap for key in index.
+func addTo(index map[string]*roaring.Bitmap, key string, position uint32) {
+	bitmap, ok := index[key]
+	if !ok {
+		bitmap = roaring.New()
+		index[key] = bitmap
+	}
+	bitmap.Add(position)
+}
+
+// buildSecondaryIndexes builds the finder's secondary indexes from its
+// locations.
+func (finder *CrimeFinder) buildSecondaryIndexes() {
+	indexes := &secondaryIndexes{
+		crimes:        make([]indexedCrime, 0, finder.Report.Crimes),
+		firsts:        make(map[*CrimeLocation]uint32, len(finder.LocationLookup)),
+		types:         make(map[string]*roaring.Bitmap),
+		months:        make(map[string]*roaring.Bitmap),
+		neighborhoods: make(map[string]*roaring.Bitmap),
+		density:       make(map[GridCell]int),
+	}
+	for _, location := range finder.Locations() {
+		indexes.firsts[location] = uint32(len(indexes.crimes))
+		indexes.density[GridCellOf(*location.Point)] += len(location.Crimes)
+		for _, crime := range location.Crimes {
+			position := uint32(len(indexes.crimes))
+			indexes.crimes = append(indexes.crimes, indexedCrime{crime, location})
+			addTo(indexes.types, indexKey(crime.Type), position)
+			if month := crimeMonth(crime); month != "" {
+				addTo(indexes.months, month, position)
+			}
+			if key := indexKey(crime.Neighborhood); key != "" {
+				addTo(indexes.neighborhoods, key, position)
+			}
+		}
+	}
+	indexes.suggestions = buildSuggestionIndex(finder.Locations())
+	finder.secondary = indexes
+}
+
+// match returns the bitmaps of the crimes that match each part of filter,
+// which must not be empty. A value no crime has matches an empty bitmap.
+func (indexes *secondaryIndexes) match(filter SearchFilter) []*roaring.Bitmap {
+	bitmaps := make([]*roaring.Bitmap, 0, 3)
+	lookups := []struct {
+		index map[string]*roaring.Bitmap
+		key   string
+	}{
+		{indexes.types, indexKey(filter.Type)},
+		{indexes.months, filter.Month},
+		{indexes.neighborhoods, indexKey(filter.Neighborhood)},
+	}
+	for _, lookup := range lookups {
+		if lookup.key == "" {
+			continue
+		}
+		bitmap, ok := lookup.index[lookup.key]
+		if !ok {
+			bitmap = roaring.New()
+		}
+		bitmaps = append(bitmaps, bitmap)
+	}
+	return bitmaps
+}
+
+// near returns a bitmap of the crimes at the locations a search from query
+// covers, and the number of tree nodes the search visited.
+func (finder *CrimeFinder) near(query Point) (*roaring.Bitmap, int, error) {
+	covered := SearchBounds(query)
+	ranges := map[int]kdtree.Range{
+		LAT_AXIS: {Min: covered.Min.Lat, Max: covered.Max.Lat},
+		LNG_AXIS: {Min: covered.Min.Lng, Max: covered.Max.Lng}}
+	results, visited, err := finder.tree().FindRangeVisited(ranges)
+	if err != nil {
+		return nil, visited, err
+	}
+	near := roaring.New()
+	for _, node := range results {
+		point := nodePoint(node)
+		location, exists := finder.LocationLookup[GetCoordinateKey(point.Lat, point.Lng)]
+		if !exists {
+			continue
+		}
+		first := finder.secondaryIndex().firsts[location]
+		near.AddRange(first, first+uint32(len(location.Crimes)))
+	}
+	return near, visited, nil
+}
+
+// FindNearFiltered works like FindNear, but only returns the crimes that
+// match filter. The crimes near query and the crimes that match each part
+// of filter are bitmaps, so combining them is a few intersections. The
+// search is planned to start from the kd-tree or from the secondary
+// indexes, whichever should give fewer crimes to check. Locations are in
+// the order of Locations().
+func (finder *CrimeFinder) FindNearFiltered(query Point, filter SearchFilter) (SearchResult, error) {
+	return finder.findNearFiltered(query, filter, nil)
+}
+
+// FindNearFilteredExplained works like FindNearFiltered and also sets the
+// result's Explanation to describe how the search ran.
+func (finder *CrimeFinder) FindNearFilteredExplained(query Point, filter SearchFilter) (SearchResult, error) {
+	return finder.findNearFiltered(query, filter, newExplanation())
+}
+
+// findNearFiltered finds the crimes near query that match filter, filling
+// in explanation if it isn't nil. Finders without secondary indexes, or
+// whose indexes are still being built, filter a search instead.
+func (finder *CrimeFinder) findNearFiltered(query Point, filter SearchFilter, explanation *Explanation) (SearchResult, error) {
+	if filter.IsEmpty() {
+		return finder.findNear(query, explanation)
+	}
+	secondary := finder.secondaryIndex()
+	if secondary == nil {
+		nearby, err := finder.findNear(query, explanation)
+		if err != nil {
+			return nearby, err
+		}
+		return nearby.Filter(filter.Matches), nil
+	}
+	nearby := SearchResult{Query: &query, Locations: make([]*CrimeLocation, 0), Explanation: explanation}
+	start := time.Now()
+	bitmaps := secondary.match(filter)
+	plan := finder.plan(query, bitmaps)
+	explanation.record("plan", start)
+	var found *roaring.Bitmap
+	if plan.driver == AttributePlan {
+		found = finder.findByAttributes(query, bitmaps, explanation)
+	} else {
+		var err error
+		if found, err = finder.findBySpatial(query, bitmaps, explanation); err != nil {
+			return nearby, err
+		}
+	}
+	start = time.Now()
+	var current *CrimeLocation
+	found.Each(func(position uint32) bool {
+		entry := secondary.crimes[position]
+		if current == nil || current.Point != entry.location.Point {
+			current = &CrimeLocation{Point: entry.location.Point}
+			nearby.Locations = append(nearby.Locations, current)
+		}
+		current.Crimes = append(current.Crimes, entry.crime)
+		return true
+	})
+	explanation.record("lookup", start)
+	if len(nearby.Locations) == 0 {
+		nearby.Diagnostics = finder.diagnose(query)
+	}
+	if explanation != nil {
+		explanation.Index = INDEX_NAME
+		if plan.driver == AttributePlan {
+			explanation.Index = SECONDARY_INDEX_NAME
+		}
+		explanation.Plan = plan.driver
+		explanation.Estimates = map[string]int{SpatialPlan: plan.spatial, AttributePlan: plan.attributes}
+		explanation.Results = len(nearby.Locations)
+	}
+	return nearby, nil
+}
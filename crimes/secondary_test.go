@@ -0,0 +1,62 @@
+package radar
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindNearFiltered(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	query := Point{45.53435699129174, -122.66469510763777}
+	filters := []SearchFilter{
+		{Type: "liquor laws"},
+		{Month: "2011-07"},
+		{Neighborhood: "Eliot"},
+		{Type: "Liquor Laws", Month: "2011-05", Neighborhood: "ELIOT"},
+		{Type: "Arson", Month: "1999-01"},
+	}
+	for _, filter := range filters {
+		nearby, err := finder.FindNear(query)
+		if err != nil {
+			t.Fatal("FindNear returned an error: ", err)
+		}
+		expected := make(map[int64]bool)
+		for _, crime := range nearby.Filter(filter.Matches).Crimes() {
+			expected[crime.Id] = true
+		}
+		result, err := finder.FindNearFiltered(query, filter)
+		if err != nil {
+			t.Fatal("FindNearFiltered returned an error: ", err)
+		}
+		actual := make(map[int64]bool)
+		for _, crime := range result.Crimes() {
+			actual[crime.Id] = true
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Error("Wrong crimes for filter: ", filter, len(actual), len(expected))
+		}
+		for i, location := range result.Locations {
+			for _, other := range result.Locations[i+1:] {
+				if location.Point == other.Point {
+					t.Error("A location should appear once: ", location.Point)
+				}
+			}
+		}
+	}
+
+	// A common type near query is found from the kd-tree, and a rare one
+	// from the secondary indexes.
+	result, _ := finder.FindNearFilteredExplained(query, SearchFilter{Type: "Liquor Laws"})
+	e := result.Explanation
+	if e == nil || e.Plan != SpatialPlan || e.Index != INDEX_NAME || e.Estimates[SpatialPlan] >= e.Estimates[AttributePlan] {
+		t.Error("A common type should be planned from the kd-tree: ", e)
+	}
+	result, _ = finder.FindNearFilteredExplained(query, SearchFilter{Type: "Homicide"})
+	e = result.Explanation
+	if e == nil || e.Plan != AttributePlan || e.Index != SECONDARY_INDEX_NAME || e.Candidates != 2 {
+		t.Error("A rare type should be planned from the secondary indexes: ", e)
+	}
+}
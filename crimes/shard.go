@@ -0,0 +1,76 @@
+package radar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// InShard reports whether point belongs to the shard that b bounds. Unlike
+// Contains, a shard doesn't include its maximum edges, so shards that share
+// an edge never both hold a point.
+func (b Bounds) InShard(point Point) bool {
+	return point.Lat >= b.Min.Lat && point.Lat < b.Max.Lat &&
+		point.Lng >= b.Min.Lng && point.Lng < b.Max.Lng
+}
+
+// Intersects reports whether two boxes overlap.
+func (b Bounds) Intersects(other Bounds) bool {
+	return b.Min.Lat <= other.Max.Lat && other.Min.Lat <= b.Max.Lat &&
+		b.Min.Lng <= other.Max.Lng && other.Min.Lng <= b.Max.Lng
+}
+
+// SearchBounds returns the box that a search from query covers.
+func SearchBounds(query Point) Bounds {
+	return Bounds{
+		Min: Point{query.Lat - HALF_MILE_LAT, query.Lng - HALF_MILE_LNG},
+		Max: Point{query.Lat + HALF_MILE_LAT, query.Lng + HALF_MILE_LNG},
+	}
+}
+
+// ParseBounds parses a box written as "minLat,minLng,maxLat,maxLng".
+func ParseBounds(value string) (Bounds, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return Bounds{}, fmt.Errorf("bounds need four coordinates: %q", value)
+	}
+	coords := make([]float64, 4)
+	for i, part := range parts {
+		coord, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return Bounds{}, fmt.Errorf("invalid coordinate in bounds: %q", part)
+		}
+		coords[i] = coord
+	}
+	min, err := NewPoint(Latitude(coords[0]), Longitude(coords[1]))
+	if err != nil {
+		return Bounds{}, err
+	}
+	max, err := NewPoint(Latitude(coords[2]), Longitude(coords[3]))
+	if err != nil {
+		return Bounds{}, err
+	}
+	bounds := Bounds{min, max}
+	if bounds.Min.Lat >= bounds.Max.Lat || bounds.Min.Lng >= bounds.Max.Lng {
+		return Bounds{}, fmt.Errorf("bounds minimum must be below their maximum: %q", value)
+	}
+	return bounds, nil
+}
+
+// applyShard removes the rows that aren't in shard. It returns the rows it
+// kept and the number it removed.
+func applyShard(rows CsvRows, shard *Bounds) (CsvRows, int) {
+	if shard == nil {
+		return rows, 0
+	}
+	kept := make(CsvRows, 0, len(rows))
+	for _, row := range rows {
+		point, err := pointFromRow(row)
+		// Rows without valid coordinates are kept, so that they're reported
+		// as errors like they would be without a shard.
+		if err != nil || shard.InShard(point) {
+			kept = append(kept, row)
+		}
+	}
+	return kept, len(rows) - len(kept)
+}
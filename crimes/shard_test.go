@@ -0,0 +1,55 @@
+package radar
+
+import "testing"
+
+func TestParseBounds(t *testing.T) {
+	bounds, err := ParseBounds("45.4, -122.8,45.6,-122.5")
+	if err != nil || bounds.Min != (Point{45.4, -122.8}) || bounds.Max != (Point{45.6, -122.5}) {
+		t.Error("Wrong bounds: ", bounds, err)
+	}
+	for _, value := range []string{"45.4,-122.8,45.6", "45.4,west,45.6,-122.5", "45.6,-122.8,45.4,-122.5"} {
+		if _, err := ParseBounds(value); err == nil {
+			t.Error("ParseBounds should not parse ", value)
+		}
+	}
+}
+
+func TestBoundsInShard(t *testing.T) {
+	west := Bounds{Point{45, -123}, Point{46, -122.6}}
+	east := Bounds{Point{45, -122.6}, Point{46, -122}}
+	edge := Point{45.5, -122.6}
+	if west.InShard(edge) || !east.InShard(edge) {
+		t.Error("A point on a shared edge should belong to exactly one shard")
+	}
+	if !west.Intersects(SearchBounds(edge)) || !east.Intersects(SearchBounds(edge)) {
+		t.Error("A search on a shared edge should cover both shards")
+	}
+	if west.Intersects(SearchBounds(Point{45.5, -122.2})) {
+		t.Error("A search far from a shard should not cover it")
+	}
+}
+
+func TestCrimeFinderShard(t *testing.T) {
+	all, _ := NewCrimeFinder("sample/crimes.csv")
+	west := Bounds{Point{-90, -180}, Point{90, -122.66}}
+	east := Bounds{Point{-90, -122.66}, Point{90, 180}}
+	westFinder, err := NewCrimeFinderWithOptions("sample/crimes.csv", LoadOptions{Shard: &west})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	eastFinder, _ := NewCrimeFinderWithOptions("sample/crimes.csv", LoadOptions{Shard: &east})
+	if westFinder.Report.Crimes == 0 || eastFinder.Report.Crimes == 0 {
+		t.Fatal("Both shards should have crimes")
+	}
+	if westFinder.Report.Crimes+eastFinder.Report.Crimes != all.Report.Crimes {
+		t.Error("Shards should split the crimes: ", westFinder.Report.Crimes, eastFinder.Report.Crimes, all.Report.Crimes)
+	}
+	if westFinder.Report.OutsideShard != eastFinder.Report.Crimes {
+		t.Error("Wrong number of crimes outside the shard: ", westFinder.Report.OutsideShard)
+	}
+	for _, location := range westFinder.Locations() {
+		if !west.InShard(*location.Point) {
+			t.Fatal("Shard has a location outside it: ", location.Point)
+		}
+	}
+}
@@ -0,0 +1,425 @@
+package radar
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"os"
+	"unsafe"
+)
+
+// A snapshot is a binary copy of a CrimeFinder's data that loads much faster
+// than CSV. It is laid out so that the file can be mapped into memory
+// read-only and used where it is, rather than parsed:
+//
+//   - A header of SNAPSHOT_HEADER_SIZE bytes: the magic bytes, the format
+//     version, then the number of locations, crimes, offenses and strings.
+//   - Columns of fixed-width little-endian values, each starting on an
+//     8-byte boundary: latitudes and longitudes of locations, the offset of
+//     each location's first crime, crime ids, string references for the
+//     dates, times, types, weapons and case numbers of crimes, the
+//     domestic and arrest flags, the offset of each crime's first offense,
+//     the ids and types of offenses, from version 2, whether each crime
+//     was geocoded, and, from version 3, a string reference for the
+//     neighborhood of each crime.
+//   - A string table: the offset of each string, then the string bytes.
+//
+// Every string a crime has points into the string table, so processes that
+// load the same snapshot share those pages through the page cache instead
+// of each holding a copy.
+const (
+	SNAPSHOT_MAGIC       = "RADARSNP"
+	SNAPSHOT_VERSION     = 3
+	SNAPSHOT_HEADER_SIZE = 48
+)
+
+// Values of the flag columns in a snapshot.
+const (
+	snapshotFlagUnset = iota
+	snapshotFlagFalse
+	snapshotFlagTrue
+)
+
+var (
+	errSnapshotMagic   = errors.New("file is not a radar snapshot")
+	errSnapshotVersion = errors.New("snapshot has an unsupported version")
+	errSnapshotCorrupt = errors.New("snapshot is corrupt")
+)
+
+// snapshotStrings builds the string table of a snapshot, storing each
+// distinct string once.
+type snapshotStrings struct {
+	indexes map[string]uint32
+	values  []string
+}
+
+func (table *snapshotStrings) add(value string) uint32 {
+	if index, ok := table.indexes[value]; ok {
+		return index
+	}
+	index := uint32(len(table.values))
+	table.indexes[value] = index
+	table.values = append(table.values, value)
+	return index
+}
+
+// snapshotWriter writes the columns of a snapshot, keeping each one aligned.
+type snapshotWriter struct {
+	w       *bufio.Writer
+	written int
+	err     error
+}
+
+func (sw *snapshotWriter) write(data interface{}) {
+	if sw.err != nil {
+		return
+	}
+	sw.err = binary.Write(sw.w, binary.LittleEndian, data)
+	sw.written += binary.Size(data)
+}
+
+// align pads the snapshot to the next 8-byte boundary.
+func (sw *snapshotWriter) align() {
+	if padding := (8 - sw.written%8) % 8; padding > 0 {
+		sw.write(make([]byte, padding))
+	}
+}
+
+func snapshotFlag(flag *bool) uint8 {
+	switch {
+	case flag == nil:
+		return snapshotFlagUnset
+	case *flag:
+		return snapshotFlagTrue
+	}
+	return snapshotFlagFalse
+}
+
+// WriteSnapshot writes the finder's locations and crimes to w as a
+// snapshot. Enrichments aren't written.
+func (finder *CrimeFinder) WriteSnapshot(w io.Writer) error {
+	locations := finder.Locations()
+	crimes := finder.All().Crimes()
+	table := &snapshotStrings{indexes: make(map[string]uint32)}
+
+	lats := make([]float64, len(locations))
+	lngs := make([]float64, len(locations))
+	crimeOffsets := make([]uint64, 0, len(locations)+1)
+	numCrimes := 0
+	for i, location := range locations {
+		lats[i], lngs[i] = location.Point.Lat, location.Point.Lng
+		crimeOffsets = append(crimeOffsets, uint64(numCrimes))
+		numCrimes += len(location.Crimes)
+	}
+	crimeOffsets = append(crimeOffsets, uint64(numCrimes))
+
+	ids := make([]int64, len(crimes))
+	var stringColumns [5][]uint32
+	for i := range stringColumns {
+		stringColumns[i] = make([]uint32, len(crimes))
+	}
+	domestic := make([]uint8, len(crimes))
+	arrest := make([]uint8, len(crimes))
+	geocoded := make([]uint8, len(crimes))
+	neighborhoods := make([]uint32, len(crimes))
+	offenseOffsets := make([]uint64, 0, len(crimes)+1)
+	offenseIds := make([]int64, 0)
+	offenseTypes := make([]uint32, 0)
+	for i, crime := range crimes {
+		ids[i] = crime.Id
+		for column, value := range []string{crime.Date, crime.Time, crime.Type, crime.Weapon, crime.CaseNumber} {
+			stringColumns[column][i] = table.add(value)
+		}
+		domestic[i] = snapshotFlag(crime.Domestic)
+		arrest[i] = snapshotFlag(crime.Arrest)
+		if crime.Geocoded {
+			geocoded[i] = 1
+		}
+		neighborhoods[i] = table.add(crime.Neighborhood)
+		offenseOffsets = append(offenseOffsets, uint64(len(offenseIds)))
+		for _, offense := range crime.Offenses {
+			offenseIds = append(offenseIds, offense.Id)
+			offenseTypes = append(offenseTypes, table.add(offense.Type))
+		}
+	}
+	offenseOffsets = append(offenseOffsets, uint64(len(offenseIds)))
+
+	stringOffsets := make([]uint64, 0, len(table.values)+1)
+	size := 0
+	for _, value := range table.values {
+		stringOffsets = append(stringOffsets, uint64(size))
+		size += len(value)
+	}
+	stringOffsets = append(stringOffsets, uint64(size))
+
+	sw := &snapshotWriter{w: bufio.NewWriter(w)}
+	sw.write([]byte(SNAPSHOT_MAGIC))
+	sw.write(uint64(SNAPSHOT_VERSION))
+	sw.write([]uint64{uint64(len(locations)), uint64(len(crimes)), uint64(len(offenseIds)), uint64(len(table.values))})
+	for _, column := range []interface{}{lats, lngs, crimeOffsets, ids} {
+		sw.write(column)
+	}
+	for _, column := range stringColumns {
+		sw.write(column)
+		sw.align()
+	}
+	for _, column := range []interface{}{domestic, arrest, offenseOffsets, offenseIds, offenseTypes, geocoded, neighborhoods} {
+		sw.align()
+		sw.write(column)
+	}
+	sw.align()
+	sw.write(stringOffsets)
+	for _, value := range table.values {
+		sw.write([]byte(value))
+	}
+	if sw.err != nil {
+		return sw.err
+	}
+	return sw.w.Flush()
+}
+
+// SaveSnapshot writes the finder's data to a snapshot file at filename.
+func (finder *CrimeFinder) SaveSnapshot(filename string) error {
+	return saveSnapshotFile(filename, finder.WriteSnapshot)
+}
+
+// saveSnapshotFile creates a file at filename and writes a snapshot to it
+// with write.
+func saveSnapshotFile(filename string, write func(w io.Writer) error) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// snapshotReader reads the columns of a snapshot in place.
+type snapshotReader struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+// column returns the next count values of size bytes each and moves past
+// them, or nil if the snapshot is too short.
+func (sr *snapshotReader) column(count int, size int) []byte {
+	if sr.err != nil || count < 0 || count > (len(sr.data)-sr.offset)/size {
+		sr.err = errSnapshotCorrupt
+		return nil
+	}
+	end := sr.offset + count*size
+	column := sr.data[sr.offset:end]
+	sr.offset = end
+	return column
+}
+
+// align moves to the next 8-byte boundary.
+func (sr *snapshotReader) align() {
+	sr.offset += (8 - sr.offset%8) % 8
+}
+
+// snapshotTable looks up strings in the string table of a snapshot without
+// copying them.
+type snapshotTable struct {
+	offsets []byte
+	strings []byte
+}
+
+func (table snapshotTable) get(index uint32) (string, error) {
+	i := int(index) * 8
+	if i+16 > len(table.offsets) {
+		return "", errSnapshotCorrupt
+	}
+	start := binary.LittleEndian.Uint64(table.offsets[i:])
+	end := binary.LittleEndian.Uint64(table.offsets[i+8:])
+	if start > end || end > uint64(len(table.strings)) {
+		return "", errSnapshotCorrupt
+	}
+	if start == end {
+		return "", nil
+	}
+	return unsafe.String(&table.strings[start], int(end-start)), nil
+}
+
+func snapshotFlagValue(flag uint8) *bool {
+	switch flag {
+	case snapshotFlagFalse:
+		value := false
+		return &value
+	case snapshotFlagTrue:
+		value := true
+		return &value
+	}
+	return nil
+}
+
+// readSnapshot creates a CrimeFinder from snapshot data, building its
+// indexes in the background if lazy is true. The finder's strings refer to
+// data, so data must not change while the finder is used. Compressed
+// snapshots are read by readCompressedSnapshot.
+func readSnapshot(data []byte, lazy bool) (CrimeFinder, error) {
+	if len(data) >= len(COMPRESSED_SNAPSHOT_MAGIC) && string(data[:len(COMPRESSED_SNAPSHOT_MAGIC)]) == COMPRESSED_SNAPSHOT_MAGIC {
+		return readCompressedSnapshot(data, lazy)
+	}
+	finder := CrimeFinder{}
+	finder.options.LazyIndexes = lazy
+	if len(data) < SNAPSHOT_HEADER_SIZE || string(data[:len(SNAPSHOT_MAGIC)]) != SNAPSHOT_MAGIC {
+		return finder, errSnapshotMagic
+	}
+	le := binary.LittleEndian
+	// Version 1 snapshots are read as if no crime was geocoded, and
+	// snapshots before version 3 as if no crime had a neighborhood.
+	version := le.Uint64(data[8:])
+	if version < 1 || version > SNAPSHOT_VERSION {
+		return finder, errSnapshotVersion
+	}
+	numLocations := int(le.Uint64(data[16:]))
+	numCrimes := int(le.Uint64(data[24:]))
+	numOffenses := int(le.Uint64(data[32:]))
+	numStrings := int(le.Uint64(data[40:]))
+
+	sr := &snapshotReader{data: data, offset: SNAPSHOT_HEADER_SIZE}
+	lats := sr.column(numLocations, 8)
+	lngs := sr.column(numLocations, 8)
+	crimeOffsets := sr.column(numLocations+1, 8)
+	ids := sr.column(numCrimes, 8)
+	var stringColumns [5][]byte
+	for i := range stringColumns {
+		stringColumns[i] = sr.column(numCrimes, 4)
+		sr.align()
+	}
+	domestic := sr.column(numCrimes, 1)
+	sr.align()
+	arrest := sr.column(numCrimes, 1)
+	sr.align()
+	offenseOffsets := sr.column(numCrimes+1, 8)
+	sr.align()
+	offenseIds := sr.column(numOffenses, 8)
+	sr.align()
+	offenseTypes := sr.column(numOffenses, 4)
+	sr.align()
+	geocoded := make([]byte, numCrimes)
+	if version >= 2 {
+		geocoded = sr.column(numCrimes, 1)
+		sr.align()
+	}
+	var neighborhoods []byte
+	if version >= 3 {
+		neighborhoods = sr.column(numCrimes, 4)
+		sr.align()
+	}
+	table := snapshotTable{offsets: sr.column(numStrings+1, 8)}
+	if sr.err != nil {
+		return finder, sr.err
+	}
+	table.strings = sr.data[sr.offset:]
+
+	locations := make(LocationLookup, numLocations)
+	keys := make([]CoordinateKey, 0, numLocations)
+	crimes := make([]Crime, numCrimes)
+	for i := 0; i < numLocations; i++ {
+		point := Point{
+			Lat: math.Float64frombits(le.Uint64(lats[i*8:])),
+			Lng: math.Float64frombits(le.Uint64(lngs[i*8:])),
+		}
+		first, last := le.Uint64(crimeOffsets[i*8:]), le.Uint64(crimeOffsets[i*8+8:])
+		if first > last || last > uint64(numCrimes) {
+			return finder, errSnapshotCorrupt
+		}
+		location := &CrimeLocation{Point: &point, Crimes: make([]*Crime, 0, last-first)}
+		for c := first; c < last; c++ {
+			crime := &crimes[c]
+			crime.Id = int64(le.Uint64(ids[c*8:]))
+			fields := []*string{&crime.Date, &crime.Time, &crime.Type, &crime.Weapon, &crime.CaseNumber}
+			for column, field := range fields {
+				value, err := table.get(le.Uint32(stringColumns[column][c*4:]))
+				if err != nil {
+					return finder, err
+				}
+				*field = value
+			}
+			crime.Domestic = snapshotFlagValue(domestic[c])
+			crime.Arrest = snapshotFlagValue(arrest[c])
+			crime.Geocoded = geocoded[c] != 0
+			if neighborhoods != nil {
+				neighborhood, err := table.get(le.Uint32(neighborhoods[c*4:]))
+				if err != nil {
+					return finder, err
+				}
+				crime.Neighborhood = neighborhood
+			}
+			firstOffense, lastOffense := le.Uint64(offenseOffsets[c*8:]), le.Uint64(offenseOffsets[c*8+8:])
+			if firstOffense > lastOffense || lastOffense > uint64(numOffenses) {
+				return finder, errSnapshotCorrupt
+			}
+			for o := firstOffense; o < lastOffense; o++ {
+				offenseType, err := table.get(le.Uint32(offenseTypes[o*4:]))
+				if err != nil {
+					return finder, err
+				}
+				crime.Offenses = append(crime.Offenses, Offense{Id: int64(le.Uint64(offenseIds[o*8:])), Type: offenseType})
+			}
+			if !finder.CrimeTypes.Contains(crime.Type) {
+				finder.CrimeTypes = append(finder.CrimeTypes, crime.Type)
+			}
+			location.Crimes = append(location.Crimes, crime)
+		}
+		key := GetCoordinateKey(point.Lat, point.Lng)
+		locations[key] = location
+		keys = append(keys, key)
+	}
+	finder.LocationLookup = locations
+	finder.keys = keys
+	finder.Report.Crimes = numCrimes
+	finder.Report.Locations = numLocations
+	finder.buildIndexes()
+	return finder, nil
+}
+
+// LoadSnapshot creates a CrimeFinder from a snapshot file written by
+// SaveSnapshot or SaveCompressedSnapshot. Where the platform allows, the
+// file is mapped into memory rather than read, and stays mapped for as long
+// as the process runs.
+func LoadSnapshot(filename string) (CrimeFinder, error) {
+	return LoadSnapshotWithOptions(filename, LoadOptions{})
+}
+
+// LoadSnapshotWithOptions works like LoadSnapshot, using options. A
+// snapshot's crimes have already been loaded, so of the options only
+// LazyIndexes applies.
+func LoadSnapshotWithOptions(filename string, options LoadOptions) (CrimeFinder, error) {
+	data, err := mapFile(filename)
+	if err != nil {
+		return CrimeFinder{}, err
+	}
+	finder, err := readSnapshot(data, options.LazyIndexes)
+	if err != nil {
+		return finder, err
+	}
+	log.Printf("Loaded %v crimes and %v locations from a snapshot", finder.Report.Crimes, finder.Report.Locations)
+	return finder, nil
+}
+
+// ReadSnapshot creates a CrimeFinder from a snapshot read from r, such as
+// one downloaded from another server. Unlike LoadSnapshot, it reads the
+// snapshot into memory, which is freed once the finder is no longer used.
+func ReadSnapshot(r io.Reader) (CrimeFinder, error) {
+	return ReadSnapshotWithOptions(r, LoadOptions{})
+}
+
+// ReadSnapshotWithOptions works like ReadSnapshot, using options. As with
+// LoadSnapshotWithOptions, only LazyIndexes applies.
+func ReadSnapshotWithOptions(r io.Reader, options LoadOptions) (CrimeFinder, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return CrimeFinder{}, err
+	}
+	return readSnapshot(data, options.LazyIndexes)
+}
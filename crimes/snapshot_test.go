@@ -0,0 +1,107 @@
+package radar
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	filename := filepath.Join(t.TempDir(), "test.snapshot")
+	if err := finder.SaveSnapshot(filename); err != nil {
+		t.Fatal("Error saving snapshot: ", err)
+	}
+	loaded, err := LoadSnapshot(filename)
+	if err != nil {
+		t.Fatal("Error loading snapshot: ", err)
+	}
+	if loaded.Report.Crimes != finder.Report.Crimes || loaded.Report.Locations != finder.Report.Locations {
+		t.Error("Wrong number of crimes or locations: ", loaded.Report.Crimes, loaded.Report.Locations)
+	}
+	if len(loaded.CrimeTypes) != len(finder.CrimeTypes) {
+		t.Error("Wrong crime types: ", loaded.CrimeTypes)
+	}
+	for _, crimeType := range finder.CrimeTypes {
+		if !loaded.CrimeTypes.Contains(crimeType) {
+			t.Error("Missing crime type: ", crimeType)
+		}
+	}
+	query := Point{45.53435699129174, -122.66469510763777}
+	expected, _ := finder.FindNear(query)
+	actual, err := loaded.FindNear(query)
+	if err != nil {
+		t.Fatal("FindNear returned an error: ", err)
+	}
+	expectedJson, _ := expected.ToJson()
+	actualJson, _ := actual.ToJson()
+	if !bytes.Equal(actualJson, expectedJson) {
+		t.Error("Snapshot search differs from CSV search: ", string(actualJson))
+	}
+}
+
+func TestSnapshotRoundTripAttributes(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "seattle.csv")
+	os.WriteFile(filename, []byte(seattleIncidentData), 0644)
+	finder, err := NewCrimeFinderWithOptions(filename, LoadOptions{GroupByCase: true})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	yes := true
+	finder.All().Crimes()[0].Arrest = &yes
+	var buf bytes.Buffer
+	if err := finder.WriteSnapshot(&buf); err != nil {
+		t.Fatal("Error writing snapshot: ", err)
+	}
+	loaded, err := readSnapshot(buf.Bytes(), false)
+	if err != nil {
+		t.Fatal("Error reading snapshot: ", err)
+	}
+	if !reflect.DeepEqual(loaded.All().Crimes(), finder.All().Crimes()) {
+		t.Error("Snapshot crimes differ: ", loaded.All().Crimes())
+	}
+}
+
+func TestReadSnapshotErrors(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	var buf bytes.Buffer
+	finder.WriteSnapshot(&buf)
+	data := buf.Bytes()
+
+	if _, err := readSnapshot([]byte("13807517,12/01/2011,01:00:00"), false); err != errSnapshotMagic {
+		t.Error("Wrong error for a file that isn't a snapshot: ", err)
+	}
+	version := append([]byte{}, data...)
+	version[8] = 99
+	if _, err := readSnapshot(version, false); err != errSnapshotVersion {
+		t.Error("Wrong error for an unsupported version: ", err)
+	}
+	for _, size := range []int{SNAPSHOT_HEADER_SIZE, len(data) / 2, len(data) - 1} {
+		if _, err := readSnapshot(data[:size], false); err != errSnapshotCorrupt {
+			t.Error("Wrong error for a snapshot cut to ", size, " bytes: ", err)
+		}
+	}
+}
+
+func TestReadSnapshot(t *testing.T) {
+	finder, _ := NewCrimeFinder("sample/crimes.csv")
+	var buf bytes.Buffer
+	if err := finder.WriteSnapshot(&buf); err != nil {
+		t.Fatal("Error writing snapshot: ", err)
+	}
+	read, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatal("Error reading snapshot: ", err)
+	}
+	if read.Report.Crimes != finder.Report.Crimes || read.Fingerprint() != finder.Fingerprint() {
+		t.Error("Read snapshot has different data: ", read.Report.Crimes, read.Fingerprint())
+	}
+}
@@ -0,0 +1,190 @@
+package radar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SOCRATA_PAGE_SIZE is the number of records asked for in each request to
+// a Socrata dataset. Socrata allows up to 50,000 a request.
+const SOCRATA_PAGE_SIZE = 50000
+
+// SOCRATA_TIMEOUT is the time each request for a page has to finish.
+const SOCRATA_TIMEOUT = 2 * time.Minute
+
+var errSocrataSchema = errors.New("the dataset's fields don't match a known schema; give one with a column mapping")
+
+// socrataPageSize is the number of records asked for in each request. It's
+// only changed by tests, to page through small datasets.
+var socrataPageSize = SOCRATA_PAGE_SIZE
+
+// socrataClient requests the pages of Socrata datasets.
+var socrataClient = &http.Client{Timeout: SOCRATA_TIMEOUT}
+
+// NewCrimeFinderFromSocrata creates a new CrimeFinder loaded from a dataset
+// published with Socrata, like the open data of many cities. The dataset is
+// identified by the domain of its portal, like data.cityofchicago.org, and
+// its id, like ijzp-q8t2. appToken may be empty, but Socrata limits the
+// requests it answers without one.
+func NewCrimeFinderFromSocrata(domain string, datasetID string, appToken string) (CrimeFinder, error) {
+	return NewCrimeFinderFromSocrataWithOptions(domain, datasetID, appToken, LoadOptions{})
+}
+
+// NewCrimeFinderFromSocrataWithOptions creates a new CrimeFinder loaded from
+// a Socrata dataset using options. The dataset's field names are matched to
+// options.Schema, or to a known schema if it's nil, as the columns of a CSV
+// header are. A schema of positions can't be used, since fields have no
+// order. Progress is reported after each page is read, and bad rows can't
+// be quarantined without a quarantine file.
+func NewCrimeFinderFromSocrataWithOptions(domain string, datasetID string, appToken string, options LoadOptions) (CrimeFinder, error) {
+	return loadCrimeFinder("", options, func(add func(CsvRows, []RowError)) error {
+		return readSocrataCrimes(socrataURL(domain, datasetID), appToken, options.Schema, options.Progress, add)
+	})
+}
+
+// socrataURL returns the URL of the JSON records of the dataset with id on
+// domain. A domain with a scheme, like http://localhost:8080, is used as it
+// is, and any other is reached over HTTPS.
+func socrataURL(domain string, id string) string {
+	if !strings.Contains(domain, "://") {
+		domain = "https://" + domain
+	}
+	return strings.TrimSuffix(domain, "/") + "/resource/" + url.PathEscape(id) + ".json"
+}
+
+// readSocrataCrimes reads the records of the dataset at resource into rows
+// in the legacy layout, a page at a time, in the order Socrata keeps them,
+// so that pages don't overlap or skip records, and calls add with the rows
+// and row errors of each page. The rows are converted, normalized and
+// checked like those of CSV data in schema, and the record number of a
+// RowError is the record's position in the dataset.
+func readSocrataCrimes(resource string, appToken string, schema *Schema, report func(LoadProgress), add func(rows CsvRows, rowErrors []RowError)) error {
+	if schema != nil && schema.Positions != nil {
+		return errSocrataSchema
+	}
+	var header CsvRow
+	var indexes [NUM_SCHEMA_COLUMNS]int
+	start := time.Now()
+	for offset := 0; ; offset += socrataPageSize {
+		records, err := fetchSocrataPage(resource, appToken, offset)
+		if err != nil {
+			return err
+		}
+		if fields := addSocrataFields(header, records); len(fields) > len(header) {
+			header = fields
+			if schema == nil {
+				if schema = DetectSchema(header); schema == nil {
+					return errSocrataSchema
+				}
+			}
+			if indexes, err = schema.columnIndexes(header); err != nil {
+				return err
+			}
+		}
+		rows := make(CsvRows, 0, len(records))
+		rowErrors := make([]RowError, 0)
+		for i, record := range records {
+			values := make(CsvRow, len(header))
+			for j, field := range header {
+				values[j] = socrataValue(record[field])
+			}
+			row := convertRow(indexes, values)
+			if schema.Normalize != nil {
+				if err := schema.Normalize(row); err != nil {
+					rowErrors = append(rowErrors, newRowError(offset+i+1, row, err))
+					continue
+				}
+			}
+			if err := checkCoordinates(row); err != nil {
+				rowErrors = append(rowErrors, newRowError(offset+i+1, row, err))
+				continue
+			}
+			rows = append(rows, row)
+		}
+		add(rows, rowErrors)
+		if report != nil {
+			report(LoadProgress{Phase: ReadPhase, Rows: offset + len(records), Elapsed: time.Since(start), Done: len(records) < socrataPageSize})
+		}
+		if len(records) < socrataPageSize {
+			return nil
+		}
+	}
+}
+
+// fetchSocrataPage requests the page of records of the dataset at resource
+// that starts at offset.
+func fetchSocrataPage(resource string, appToken string, offset int) ([]map[string]interface{}, error) {
+	query := url.Values{}
+	query.Set("$limit", strconv.Itoa(socrataPageSize))
+	query.Set("$offset", strconv.Itoa(offset))
+	query.Set("$order", ":id")
+	req, err := http.NewRequest("GET", resource+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if appToken != "" {
+		req.Header.Set("X-App-Token", appToken)
+	}
+	resp, err := socrataClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("socrata returned %v for %v: %s", resp.Status, resource, strings.TrimSpace(string(message)))
+	}
+	var records []map[string]interface{}
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// addSocrataFields returns fields with the names of the fields of records
+// that it doesn't have added to the end. Socrata leaves out the fields of a
+// record that are null, so a field may first appear in any record.
+func addSocrataFields(fields CsvRow, records []map[string]interface{}) CsvRow {
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		seen[field] = true
+	}
+	var added CsvRow
+	for _, record := range records {
+		for field := range record {
+			if !seen[field] {
+				seen[field] = true
+				added = append(added, field)
+			}
+		}
+	}
+	// Fields are added in the same order whatever order records list them
+	// in.
+	sort.Strings(added)
+	return append(fields, added...)
+}
+
+// socrataValue returns a value of a record as a column of a row. Values
+// like locations, which are objects, have no column, and are empty.
+func socrataValue(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	}
+	return ""
+}
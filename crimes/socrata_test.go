@@ -0,0 +1,125 @@
+package radar
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// socrataServer serves records like those of Chicago's crimes dataset, a
+// page at a time, to requests with token. The fields of null values are
+// left out, as Socrata leaves them out.
+func socrataServer(t *testing.T, records []map[string]interface{}, token string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/resource/ijzp-q8t2.json" || query.Get("$order") != ":id" {
+			t.Error("Wrong request: ", r.URL)
+		}
+		if r.Header.Get("X-App-Token") != token {
+			http.Error(w, `{"message": "Invalid app_token specified"}`, 403)
+			return
+		}
+		limit, _ := strconv.Atoi(query.Get("$limit"))
+		offset, _ := strconv.Atoi(query.Get("$offset"))
+		page := records[min(offset, len(records)):min(offset+limit, len(records))]
+		json.NewEncoder(w).Encode(page)
+	}))
+}
+
+func chicagoRecords(n int) []map[string]interface{} {
+	records := make([]map[string]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		record := map[string]interface{}{
+			"id":           fmt.Sprint(11034701 + i),
+			"case_number":  fmt.Sprintf("JA%v", 366925+i),
+			"date":         "2001-01-01T11:00:00.000",
+			"block":        "016XX E 86TH PL",
+			"primary_type": "DECEPTIVE PRACTICE",
+			"arrest":       false,
+			"domestic":     i%2 == 0,
+			"district":     "004",
+			"latitude":     fmt.Sprint(41.738 + float64(i)*0.001),
+			"longitude":    "-87.584",
+			"location":     map[string]interface{}{"type": "Point", "coordinates": []float64{-87.584, 41.738}},
+		}
+		if i == 0 {
+			// Only some records have a community area.
+			record["community_area"] = "45"
+		}
+		records = append(records, record)
+	}
+	// Socrata leaves out the coordinates of crimes it doesn't map.
+	delete(records[n-1], "latitude")
+	delete(records[n-1], "longitude")
+	return records
+}
+
+func TestNewCrimeFinderFromSocrata(t *testing.T) {
+	defer func(size int) {
+		socrataPageSize = size
+	}(socrataPageSize)
+	socrataPageSize = 10
+	server := socrataServer(t, chicagoRecords(25), "token")
+	defer server.Close()
+
+	var progress []LoadProgress
+	finder, err := NewCrimeFinderFromSocrataWithOptions(server.URL, "ijzp-q8t2", "token", LoadOptions{Progress: func(p LoadProgress) {
+		if p.Phase == ReadPhase {
+			progress = append(progress, p)
+		}
+	}})
+	if err != nil {
+		t.Fatal("Error loading from Socrata: ", err)
+	}
+	if finder.Report.Crimes != 24 || len(finder.Report.Errors) != 1 || finder.Report.Errors[0].Record != 25 {
+		t.Error("Wrong crimes: ", finder.Report.Crimes, finder.Report.Errors)
+	}
+	if len(progress) != 3 || progress[2].Rows != 25 || !progress[2].Done || progress[1].Done {
+		t.Error("Wrong progress: ", progress)
+	}
+	crime, location := finder.FindByID(11034701)
+	if crime == nil || crime.Date != "01/01/2001" || crime.Time != "11:00:00" || crime.Type != "DECEPTIVE PRACTICE" || crime.CaseNumber != "JA366925" {
+		t.Fatal("Wrong crime: ", crime)
+	}
+	if crime.Neighborhood != "45" || crime.Arrest == nil || *crime.Arrest || crime.Domestic == nil || !*crime.Domestic {
+		t.Error("Wrong attributes: ", crime)
+	}
+	nearby, err := finder.FindNear(*location.Point)
+	if err != nil || len(nearby.Locations) == 0 {
+		t.Error("Crimes from Socrata should be found: ", err)
+	}
+	if other, _ := finder.FindByID(11034702); other == nil || other.Neighborhood != "" {
+		t.Error("A record without a field should have an empty column: ", other)
+	}
+
+	_, err = NewCrimeFinderFromSocrata(server.URL, "ijzp-q8t2", "")
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "Invalid app_token") {
+		t.Error("A refused request should be an error: ", err)
+	}
+}
+
+func TestNewCrimeFinderFromSocrataUnknownFields(t *testing.T) {
+	records := []map[string]interface{}{{"incident": "1", "lat": "45.5"}}
+	server := socrataServer(t, records, "")
+	defer server.Close()
+	if _, err := NewCrimeFinderFromSocrata(server.URL, "ijzp-q8t2", ""); err != errSocrataSchema {
+		t.Error("Fields that don't match a schema should be an error: ", err)
+	}
+	positions := &Schema{Name: "positions", Positions: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}}
+	if _, err := NewCrimeFinderFromSocrataWithOptions(server.URL, "ijzp-q8t2", "", LoadOptions{Schema: positions}); err != errSocrataSchema {
+		t.Error("A schema of positions should be an error: ", err)
+	}
+}
+
+func TestSocrataURL(t *testing.T) {
+	if url := socrataURL("data.cityofchicago.org", "ijzp-q8t2"); url != "https://data.cityofchicago.org/resource/ijzp-q8t2.json" {
+		t.Error("Wrong URL: ", url)
+	}
+	if url := socrataURL("http://localhost:8080/", "ijzp-q8t2"); url != "http://localhost:8080/resource/ijzp-q8t2.json" {
+		t.Error("Wrong URL for a domain with a scheme: ", url)
+	}
+}
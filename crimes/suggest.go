@@ -0,0 +1,140 @@
+package radar
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Kinds of suggestions.
+const (
+	TypeSuggestion         = "type"
+	CategorySuggestion     = "category"
+	NeighborhoodSuggestion = "neighborhood"
+)
+
+// MAX_SUGGESTIONS is the most suggestions Suggest returns.
+const MAX_SUGGESTIONS = 25
+
+// A Suggestion is a crime type, category or neighborhood that a search box
+// can complete to, and how many crimes it has.
+type Suggestion struct {
+	Text  string
+	Kind  string
+	Count int
+}
+
+// Suggestions are suggestions ranked by how many crimes they have.
+type Suggestions []Suggestion
+
+// A suggestionEntry is a prefix-searchable key of a suggestion: its text in
+// lower case from the start of one of its words, so that "theft" finds
+// "Motor Vehicle Theft".
+type suggestionEntry struct {
+	key        string
+	suggestion int
+}
+
+// A suggestionIndex finds suggestions by the prefix of one of their words.
+// Its entries are sorted by key, so the entries with a prefix are next to
+// each other.
+type suggestionIndex struct {
+	suggestions Suggestions
+	entries     []suggestionEntry
+}
+
+// buildSuggestionIndex indexes the crime types, categories and
+// neighborhoods of the crimes at locations. Values that differ only in case
+// or surrounding space are one suggestion, named the way the first crime
+// has it.
+func buildSuggestionIndex(locations []*CrimeLocation) *suggestionIndex {
+	index := &suggestionIndex{suggestions: make(Suggestions, 0), entries: make([]suggestionEntry, 0)}
+	positions := make(map[[2]string]int)
+	count := func(kind string, text string) {
+		text = strings.TrimSpace(text)
+		id := [2]string{kind, indexKey(text)}
+		if id[1] == "" {
+			return
+		}
+		position, exists := positions[id]
+		if !exists {
+			position = len(index.suggestions)
+			positions[id] = position
+			index.suggestions = append(index.suggestions, Suggestion{Text: text, Kind: kind})
+		}
+		index.suggestions[position].Count += 1
+	}
+	for _, location := range locations {
+		for _, crime := range location.Crimes {
+			count(TypeSuggestion, crime.Type)
+			count(CategorySuggestion, Classify(crime.Type).Category)
+			count(NeighborhoodSuggestion, crime.Neighborhood)
+		}
+	}
+	for position, suggestion := range index.suggestions {
+		words := strings.Fields(indexKey(suggestion.Text))
+		for i := range words {
+			index.entries = append(index.entries, suggestionEntry{strings.Join(words[i:], " "), position})
+		}
+	}
+	sort.Slice(index.entries, func(i, j int) bool { return index.entries[i].key < index.entries[j].key })
+	return index
+}
+
+// find returns up to limit suggestions with a word that starts with prefix,
+// most crimes first.
+func (index *suggestionIndex) find(prefix string, limit int) Suggestions {
+	prefix = strings.Join(strings.Fields(indexKey(prefix)), " ")
+	found := make(Suggestions, 0)
+	if prefix == "" {
+		return found
+	}
+	seen := make(map[int]bool)
+	start := sort.Search(len(index.entries), func(i int) bool { return index.entries[i].key >= prefix })
+	for _, entry := range index.entries[start:] {
+		if !strings.HasPrefix(entry.key, prefix) {
+			break
+		}
+		if !seen[entry.suggestion] {
+			seen[entry.suggestion] = true
+			found = append(found, index.suggestions[entry.suggestion])
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Count != found[j].Count {
+			return found[i].Count > found[j].Count
+		}
+		return found[i].Text < found[j].Text
+	})
+	return found[:min(len(found), limit)]
+}
+
+// Suggest returns up to limit crime types, categories and neighborhoods
+// with a word that starts with prefix, ignoring case, ranked by how many
+// crimes they have. A limit outside 1 to MAX_SUGGESTIONS is
+// MAX_SUGGESTIONS.
+func (finder *CrimeFinder) Suggest(prefix string, limit int) Suggestions {
+	if limit < 1 || limit > MAX_SUGGESTIONS {
+		limit = MAX_SUGGESTIONS
+	}
+	if secondary := finder.secondaryIndex(); secondary != nil {
+		return secondary.suggestions.find(prefix, limit)
+	}
+	// The indexes are still being built, so build the suggestions for
+	// this search.
+	return buildSuggestionIndex(finder.Locations()).find(prefix, limit)
+}
+
+// ToJson returns the suggestions marshalled to JSON bytes.
+func (suggestions Suggestions) ToJson() ([]byte, error) {
+	type suggestionJson struct {
+		Text  string `json:"text"`
+		Kind  string `json:"kind"`
+		Count int    `json:"count"`
+	}
+	out := make([]suggestionJson, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		out = append(out, suggestionJson{suggestion.Text, suggestion.Kind, suggestion.Count})
+	}
+	return json.Marshal(out)
+}
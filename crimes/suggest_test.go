@@ -0,0 +1,58 @@
+package radar
+
+import (
+	"reflect"
+	"testing"
+)
+
+// findSuggestion returns the suggestion with text and kind, and false if
+// there isn't one.
+func findSuggestion(suggestions Suggestions, text string, kind string) (Suggestion, bool) {
+	for _, suggestion := range suggestions {
+		if suggestion.Text == text && suggestion.Kind == kind {
+			return suggestion, true
+		}
+	}
+	return Suggestion{}, false
+}
+
+func TestSuggest(t *testing.T) {
+	finder, err := NewCrimeFinder("sample/crimes.csv")
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	suggestions := finder.Suggest("BUR", 0)
+	if suggestion, ok := findSuggestion(suggestions, "Burglary", TypeSuggestion); !ok || suggestion.Count != 20 {
+		t.Error("Wrong suggestions for a prefix: ", suggestions)
+	}
+	for i := 1; i < len(suggestions); i++ {
+		if suggestions[i].Count > suggestions[i-1].Count {
+			t.Error("Suggestions should be ranked by count: ", suggestions)
+		}
+	}
+	if _, ok := findSuggestion(finder.Suggest("theft", 0), "Motor Vehicle Theft", TypeSuggestion); !ok {
+		t.Error("Suggestions should match the start of any word")
+	}
+	if suggestion, ok := findSuggestion(finder.Suggest("prop", 0), PropertyCategory, CategorySuggestion); !ok || suggestion.Count == 0 {
+		t.Error("Categories should be suggested: ", suggestion)
+	}
+	if suggestions := finder.Suggest("l", 2); len(suggestions) != 2 || suggestions[0].Text != "Larceny" {
+		t.Error("Wrong limited suggestions: ", suggestions)
+	}
+	if suggestions := finder.Suggest(" ", 0); len(suggestions) != 0 {
+		t.Error("A blank prefix should suggest nothing: ", suggestions)
+	}
+
+	lazy, _ := NewCrimeFinderWithOptions("sample/crimes.csv", LoadOptions{LazyIndexes: true})
+	lazy.pending = &backgroundBuild{done: make(chan struct{})}
+	if expected, actual := finder.Suggest("d", 0), lazy.Suggest("d", 0); !reflect.DeepEqual(expected, actual) {
+		t.Error("Suggestions should be the same while indexes are built: ", actual)
+	}
+}
+
+func TestSuggestionsToJson(t *testing.T) {
+	resp, err := Suggestions{{"Burglary", TypeSuggestion, 20}}.ToJson()
+	if err != nil || string(resp) != `[{"text":"Burglary","kind":"type","count":20}]` {
+		t.Error("Wrong JSON: ", string(resp), err)
+	}
+}
@@ -0,0 +1,189 @@
+package radar
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SOURCE_LANGUAGE is the language of the names of crime types and
+// categories in the data.
+const SOURCE_LANGUAGE = "en"
+
+// Translations map languages, like "es", to labels for the names of crime
+// types and categories. Names are looked up regardless of case.
+type Translations map[string]map[string]string
+
+// BuiltInTranslations label the categories of the taxonomy and the crime
+// types it knows.
+var BuiltInTranslations = Translations{
+	"es": {
+		PersonCategory:   "contra las personas",
+		PropertyCategory: "contra la propiedad",
+		SocietyCategory:  "contra la sociedad",
+		OtherCategory:    "otros",
+
+		"homicide":                   "Homicidio",
+		"aggravated assault":         "Agresión agravada",
+		"assault, simple":            "Agresión simple",
+		"simple assault":             "Agresión simple",
+		"intimidation":               "Intimidación",
+		"battery":                    "Lesiones",
+		"assault":                    "Agresión",
+		"kidnap":                     "Secuestro",
+		"kidnapping/abduction":       "Secuestro/Rapto",
+		"kidnapping":                 "Secuestro",
+		"rape":                       "Violación",
+		"sex offenses":               "Delitos sexuales",
+		"sex offense":                "Delito sexual",
+		"criminal sexual assault":    "Agresión sexual",
+		"offenses against family":    "Delitos contra la familia",
+		"offense involving children": "Delito que involucra a menores",
+
+		"arson":                        "Incendio provocado",
+		"burglary":                     "Robo con allanamiento",
+		"burglary/breaking & entering": "Robo con allanamiento",
+		"embezzlement":                 "Malversación",
+		"forgery":                      "Falsificación",
+		"counterfeiting/forgery":       "Falsificación",
+		"fraud":                        "Fraude",
+		"deceptive practice":           "Práctica engañosa",
+		"identity theft":               "Robo de identidad",
+		"larceny":                      "Hurto",
+		"theft":                        "Robo",
+		"shoplifting":                  "Hurto en tiendas",
+		"theft from motor vehicle":     "Robo de vehículo",
+		"motor vehicle theft":          "Robo de vehículo motorizado",
+		"robbery":                      "Asalto",
+		"stolen property":              "Propiedad robada",
+		"stolen property offenses":     "Delitos de propiedad robada",
+		"vandalism":                    "Vandalismo",
+		"criminal damage":              "Daños",
+		"destruction/damage/vandalism of property": "Destrucción/Daño/Vandalismo de propiedad",
+
+		"curfew":                   "Toque de queda",
+		"disorderly conduct":       "Alteración del orden",
+		"drugs":                    "Drogas",
+		"narcotics":                "Narcóticos",
+		"drug/narcotic violations": "Infracciones de drogas/narcóticos",
+		"duii":                     "Conducir bajo los efectos",
+		"gambling":                 "Juegos de azar",
+		"liquor laws":              "Leyes de alcohol",
+		"liquor law violation":     "Infracción de leyes de alcohol",
+		"prostitution":             "Prostitución",
+		"runaway":                  "Fuga del hogar",
+		"trespass":                 "Allanamiento",
+		"criminal trespass":        "Allanamiento",
+		"weapons":                  "Armas",
+		"weapons violation":        "Infracción de armas",
+		"weapon law violations":    "Infracciones de leyes de armas",
+	},
+}
+
+// LoadTranslations reads translations from a JSON file of the same shape
+// as Translations, like {"es": {"Larceny": "Hurto"}}.
+func LoadTranslations(filename string) (Translations, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var loaded Translations
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return nil, fmt.Errorf("invalid translations %v: %v", filename, err)
+	}
+	return Translations{}.Merge(loaded), nil
+}
+
+// Merge returns the translations with those of other added, replacing any
+// labels they share.
+func (t Translations) Merge(other Translations) Translations {
+	merged := make(Translations, len(t)+len(other))
+	for _, translations := range []Translations{t, other} {
+		for language, labels := range translations {
+			language = strings.ToLower(language)
+			if merged[language] == nil {
+				merged[language] = make(map[string]string)
+			}
+			for name, label := range labels {
+				merged[language][indexKey(name)] = label
+			}
+		}
+	}
+	return merged
+}
+
+// Translate returns the label of name in language, or name if there isn't
+// one.
+func (t Translations) Translate(name string, language string) string {
+	if label, ok := t[language][indexKey(name)]; ok {
+		return label
+	}
+	return name
+}
+
+// Language returns the language to translate into for an Accept-Language
+// header, or "" if the reader prefers SOURCE_LANGUAGE or a language there
+// are no translations for. A tag like "es-MX" matches "es".
+func (t Translations) Language(acceptLanguage string) string {
+	type preference struct {
+		tag     string
+		quality float64
+	}
+	preferences := make([]preference, 0)
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if tag != "" && quality > 0 {
+			preferences = append(preferences, preference{strings.ToLower(tag), quality})
+		}
+	}
+	sort.SliceStable(preferences, func(i, j int) bool { return preferences[i].quality > preferences[j].quality })
+	for _, preference := range preferences {
+		primary, _, _ := strings.Cut(preference.tag, "-")
+		for _, tag := range []string{preference.tag, primary} {
+			if tag == SOURCE_LANGUAGE {
+				return ""
+			}
+			if _, ok := t[tag]; ok {
+				return tag
+			}
+		}
+	}
+	return ""
+}
+
+// translatedKeys are the fields of ToJson output whose values are names of
+// crime types or categories.
+var translatedKeys = map[string]bool{"type": true, "category": true}
+
+// TranslateValues translates the names of crime types and categories in the
+// JSON in data, which is output of a ToJson method, into language: the
+// values of "type" and "category" fields, and the keys of "categories"
+// objects. Names without a label are left as they are, which also leaves
+// GeoJSON's types alone. Enrichments aren't translated.
+func TranslateValues(data []byte, translations Translations, language string) ([]byte, error) {
+	if _, ok := translations[language]; !ok {
+		return data, nil
+	}
+	renameKey := func(path []string, key string) string {
+		if len(path) > 0 && path[len(path)-1] == "categories" && !inJsonField(path, "enrichments") {
+			return translations.Translate(key, language)
+		}
+		return key
+	}
+	replaceValue := func(path []string, value string) string {
+		if len(path) > 0 && translatedKeys[path[len(path)-1]] && !inJsonField(path, "enrichments") {
+			return translations.Translate(value, language)
+		}
+		return value
+	}
+	return walkJson(data, renameKey, replaceValue)
+}
@@ -0,0 +1,83 @@
+package radar
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	if label := BuiltInTranslations.Translate("Liquor Laws", "es"); label != "Leyes de alcohol" {
+		t.Error("Wrong label: ", label)
+	}
+	if label := BuiltInTranslations.Translate(PersonCategory, "es"); label != "contra las personas" {
+		t.Error("Wrong label for a category: ", label)
+	}
+	if label := BuiltInTranslations.Translate("Mystery", "es"); label != "Mystery" {
+		t.Error("Names without a label should be kept: ", label)
+	}
+	if label := BuiltInTranslations.Translate("Larceny", ""); label != "Larceny" {
+		t.Error("Names shouldn't be translated without a language: ", label)
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	headers := map[string]string{
+		"es":                      "es",
+		"es-MX,es;q=0.9":          "es",
+		"fr-CA, es;q=0.5":         "es",
+		"en-US,en;q=0.9,es;q=0.8": "",
+		"es;q=0.2, en;q=0.8":      "",
+		"es;q=0":                  "",
+		"fr":                      "",
+		"":                        "",
+	}
+	for header, expected := range headers {
+		if actual := BuiltInTranslations.Language(header); actual != expected {
+			t.Error("Wrong language for ", header, ": ", actual)
+		}
+	}
+}
+
+func TestLoadTranslations(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "translations.json")
+	os.WriteFile(filename, []byte(`{"ES": {"Larceny": "Latrocinio"}, "fr": {"Larceny": "Vol"}}`), 0644)
+	loaded, err := LoadTranslations(filename)
+	if err != nil {
+		t.Fatal("LoadTranslations returned an error: ", err)
+	}
+	merged := BuiltInTranslations.Merge(loaded)
+	if label := merged.Translate("LARCENY", "es"); label != "Latrocinio" {
+		t.Error("Loaded labels should replace built-in ones: ", label)
+	}
+	if label := merged.Translate("Arson", "es"); label != "Incendio provocado" {
+		t.Error("Built-in labels should be kept: ", label)
+	}
+	if label := merged.Translate("Larceny", "fr"); label != "Vol" {
+		t.Error("Loaded languages should be added: ", label)
+	}
+
+	os.WriteFile(filename, []byte(`{"es": ["Hurto"]}`), 0644)
+	if _, err := LoadTranslations(filename); err == nil {
+		t.Error("Invalid translations should be an error")
+	}
+}
+
+func TestTranslateValues(t *testing.T) {
+	data := `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"type":"Larceny","category":"property"}}],"categories":{"person":2,"other":1},"types":[{"type":"Arson","count":1},"Larceny"],"enrichments":{"type":"Larceny"}}`
+	actual, err := TranslateValues([]byte(data), BuiltInTranslations, "es")
+	if err != nil {
+		t.Fatal("TranslateValues returned an error: ", err)
+	}
+	expected := `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"type":"Hurto","category":"contra la propiedad"}}],"categories":{"contra las personas":2,"otros":1},"types":[{"type":"Incendio provocado","count":1},"Larceny"],"enrichments":{"type":"Larceny"}}`
+	if string(actual) != expected {
+		t.Error("Wrong translated JSON: ", string(actual))
+	}
+	if !json.Valid(actual) {
+		t.Error("Translated JSON is not valid")
+	}
+	if actual, _ := TranslateValues([]byte(data), BuiltInTranslations, ""); string(actual) != data {
+		t.Error("JSON shouldn't change without a language: ", string(actual))
+	}
+}
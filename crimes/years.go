@@ -0,0 +1,325 @@
+package radar
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UNDATED_YEAR is the year of the partition that holds crimes whose dates
+// can't be parsed.
+const UNDATED_YEAR = 0
+
+// YEAR_PARTITION_EXTENSION ends the names of the snapshot files that
+// SaveYearPartitions writes, one for each year, like "2011.snapshot".
+const YEAR_PARTITION_EXTENSION = ".snapshot"
+
+// UNDATED_PARTITION_NAME is the name of the file of undated crimes.
+const UNDATED_PARTITION_NAME = "undated" + YEAR_PARTITION_EXTENSION
+
+var errNoYearPartitions = errors.New("no year partitions")
+
+// yearPartitionFilename returns the name of the file in dir that holds the
+// crimes of year.
+func yearPartitionFilename(dir string, year int) string {
+	if year == UNDATED_YEAR {
+		return filepath.Join(dir, UNDATED_PARTITION_NAME)
+	}
+	return filepath.Join(dir, fmt.Sprintf("%04d%v", year, YEAR_PARTITION_EXTENSION))
+}
+
+// crimeYear returns the year crime occurred in, or UNDATED_YEAR if its
+// date can't be parsed.
+func crimeYear(crime *Crime) int {
+	if occurred := crimeTime(crime); !occurred.IsZero() {
+		return occurred.Year()
+	}
+	return UNDATED_YEAR
+}
+
+// SaveYearPartitions splits the finder's crimes by the year they occurred
+// in and saves a snapshot of each year to dir, creating it if it doesn't
+// exist, for OpenYearPartitions. Crimes whose dates can't be parsed are
+// saved together, apart from the years. It returns the years it saved, in
+// order.
+func (finder *CrimeFinder) SaveYearPartitions(dir string) ([]int, error) {
+	byYear := make(map[int][]ChangedCrime)
+	for _, location := range finder.Locations() {
+		for _, crime := range location.Crimes {
+			year := crimeYear(crime)
+			byYear[year] = append(byYear[year], ChangedCrime{crime, location.Point})
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	years := make([]int, 0, len(byYear))
+	for year, crimes := range byYear {
+		partition := newFinderFromCrimes(crimes)
+		if err := partition.SaveSnapshot(yearPartitionFilename(dir, year)); err != nil {
+			return nil, err
+		}
+		if year != UNDATED_YEAR {
+			years = append(years, year)
+		}
+	}
+	sort.Ints(years)
+	return years, nil
+}
+
+// YearPartitions is a dataset that SaveYearPartitions split by year, of
+// which only some years are loaded at once. The most recent years are
+// always loaded, since most searches are for recent crimes, along with any
+// undated crimes, and they're served by one finder that never changes.
+// Other years each get a finder of their own when a search asks for them,
+// which is unloaded once no search has asked for it for a while. Loading
+// or unloading a year never changes the finder of another. Each year's
+// file is mapped into memory the first time it's loaded, so loading it
+// again is cheap. It's safe to use from several goroutines.
+type YearPartitions struct {
+	dir string
+	// years are the years there are partitions for, oldest first, and
+	// undated is true if there's a partition of undated crimes.
+	years   []int
+	undated bool
+	// resident is the number of the most recent years that stay loaded,
+	// and residentFinder has their crimes.
+	resident       int
+	residentFinder CrimeFinder
+
+	mu sync.Mutex
+	// loaded holds the crimes of the loaded years, finders holds the
+	// finders of the loaded years that aren't resident, touched is when a
+	// search last asked for each of them, and mapped holds the mapped
+	// files of the years that have been loaded.
+	loaded  map[int][]ChangedCrime
+	finders map[int]*CrimeFinder
+	touched map[int]time.Time
+	mapped  map[int][]byte
+}
+
+// OpenYearPartitions opens the partitions that SaveYearPartitions saved to
+// dir and loads the resident most recent years. It returns an error if
+// there are no partitions in dir.
+func OpenYearPartitions(dir string, resident int) (*YearPartitions, error) {
+	if resident < 0 {
+		return nil, fmt.Errorf("invalid number of resident years: %v", resident)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	partitions := &YearPartitions{
+		dir:      dir,
+		resident: resident,
+		loaded:   make(map[int][]ChangedCrime),
+		finders:  make(map[int]*CrimeFinder),
+		touched:  make(map[int]time.Time),
+		mapped:   make(map[int][]byte),
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == UNDATED_PARTITION_NAME {
+			partitions.undated = true
+			continue
+		}
+		year, err := strconv.Atoi(strings.TrimSuffix(name, YEAR_PARTITION_EXTENSION))
+		if err != nil || !strings.HasSuffix(name, YEAR_PARTITION_EXTENSION) || year <= UNDATED_YEAR {
+			continue
+		}
+		partitions.years = append(partitions.years, year)
+	}
+	if len(partitions.years) == 0 && !partitions.undated {
+		return nil, errNoYearPartitions
+	}
+	sort.Ints(partitions.years)
+	partitions.mu.Lock()
+	defer partitions.mu.Unlock()
+	residentYears := partitions.residentYears()
+	for _, year := range residentYears {
+		if err := partitions.load(year); err != nil {
+			return nil, err
+		}
+	}
+	partitions.residentFinder = partitions.combine(residentYears)
+	return partitions, nil
+}
+
+// residentYears returns the years that are always loaded.
+func (partitions *YearPartitions) residentYears() []int {
+	years := partitions.years[max(0, len(partitions.years)-partitions.resident):]
+	if partitions.undated {
+		years = append([]int{UNDATED_YEAR}, years...)
+	}
+	return years
+}
+
+// isResident returns true if year is always loaded.
+func (partitions *YearPartitions) isResident(year int) bool {
+	for _, resident := range partitions.residentYears() {
+		if year == resident {
+			return true
+		}
+	}
+	return false
+}
+
+// has returns true if there's a partition for year.
+func (partitions *YearPartitions) has(year int) bool {
+	i := sort.SearchInts(partitions.years, year)
+	return i < len(partitions.years) && partitions.years[i] == year
+}
+
+// load loads the partition of year, mapping its file if it hasn't been.
+// It must be called with mu held.
+func (partitions *YearPartitions) load(year int) error {
+	data, exists := partitions.mapped[year]
+	if !exists {
+		var err error
+		if data, err = mapFile(yearPartitionFilename(partitions.dir, year)); err != nil {
+			return err
+		}
+		partitions.mapped[year] = data
+	}
+	finder, err := readSnapshot(data, false)
+	if err != nil {
+		return err
+	}
+	// Only the crimes are kept, since Finder indexes them all together.
+	crimes := make([]ChangedCrime, 0, finder.Report.Crimes)
+	for _, location := range finder.Locations() {
+		for _, crime := range location.Crimes {
+			crimes = append(crimes, ChangedCrime{crime, location.Point})
+		}
+	}
+	partitions.loaded[year] = crimes
+	return nil
+}
+
+// Years returns the years there are partitions for, oldest first. Undated
+// crimes aren't in any of them.
+func (partitions *YearPartitions) Years() []int {
+	return append([]int{}, partitions.years...)
+}
+
+// Loaded returns the years that are loaded, oldest first.
+func (partitions *YearPartitions) Loaded() []int {
+	partitions.mu.Lock()
+	defer partitions.mu.Unlock()
+	years := make([]int, 0, len(partitions.loaded))
+	for year := range partitions.loaded {
+		if year != UNDATED_YEAR {
+			years = append(years, year)
+		}
+	}
+	sort.Ints(years)
+	return years
+}
+
+// Between returns the years there are partitions for that overlap from
+// through to, oldest first. A zero from or to leaves that end open.
+func (partitions *YearPartitions) Between(from time.Time, to time.Time) []int {
+	years := make([]int, 0)
+	for _, year := range partitions.years {
+		if (from.IsZero() || year >= from.Year()) && (to.IsZero() || year <= to.Year()) {
+			years = append(years, year)
+		}
+	}
+	return years
+}
+
+// Finder returns the CrimeFinder of the resident years and the undated
+// crimes. It's built once, so it doesn't change as other years are loaded
+// and unloaded.
+func (partitions *YearPartitions) Finder() CrimeFinder {
+	return partitions.residentFinder
+}
+
+// Year records that a search asked for year and returns the CrimeFinder of
+// its crimes, loading it if it isn't loaded. It returns nil if year is
+// resident, since the finder from Finder has its crimes, or if there's no
+// partition for it.
+func (partitions *YearPartitions) Year(year int) (*CrimeFinder, error) {
+	partitions.mu.Lock()
+	defer partitions.mu.Unlock()
+	if !partitions.has(year) || partitions.isResident(year) {
+		return nil, nil
+	}
+	partitions.touched[year] = time.Now()
+	if finder, exists := partitions.finders[year]; exists {
+		return finder, nil
+	}
+	if err := partitions.load(year); err != nil {
+		delete(partitions.touched, year)
+		return nil, err
+	}
+	finder := newFinderFromCrimes(partitions.loaded[year])
+	partitions.finders[year] = &finder
+	log.Printf("Loaded the crimes of %v", year)
+	return &finder, nil
+}
+
+// Including returns a new CrimeFinder with the crimes of the resident
+// years, the undated crimes and the crimes of years, loading the years that
+// aren't loaded, for work like an export that spans several years. Years
+// there are no partitions for are ignored.
+func (partitions *YearPartitions) Including(years ...int) (CrimeFinder, error) {
+	for _, year := range years {
+		if _, err := partitions.Year(year); err != nil {
+			return CrimeFinder{}, err
+		}
+	}
+	partitions.mu.Lock()
+	defer partitions.mu.Unlock()
+	included := partitions.residentYears()
+	for _, year := range years {
+		// A year may have been evicted since it was loaded, in which case
+		// its crimes are left out.
+		if _, exists := partitions.finders[year]; exists {
+			included = append(included, year)
+		}
+	}
+	return partitions.combine(included), nil
+}
+
+// combine returns a CrimeFinder with the crimes of years, which must be
+// loaded, sharing their crimes rather than copying them. It must be called
+// with mu held, or before partitions is shared.
+func (partitions *YearPartitions) combine(years []int) CrimeFinder {
+	years = append([]int{}, years...)
+	sort.Ints(years)
+	crimes := make([]ChangedCrime, 0)
+	for i, year := range years {
+		if i > 0 && year == years[i-1] {
+			continue
+		}
+		crimes = append(crimes, partitions.loaded[year]...)
+	}
+	return newFinderFromCrimes(crimes)
+}
+
+// Evict unloads the years that aren't resident and that no search has
+// asked for within idle. Searches that already have the finder of such a
+// year from Year can go on using it. It returns true if it unloaded any
+// years.
+func (partitions *YearPartitions) Evict(idle time.Duration) bool {
+	partitions.mu.Lock()
+	defer partitions.mu.Unlock()
+	changed := false
+	for year, touched := range partitions.touched {
+		if time.Since(touched) >= idle {
+			delete(partitions.loaded, year)
+			delete(partitions.finders, year)
+			delete(partitions.touched, year)
+			changed = true
+		}
+	}
+	return changed
+}
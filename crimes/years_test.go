@@ -0,0 +1,138 @@
+package radar
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const yearsData = `Record ID,Report Date,Report Time,Major Offense Type,Address,Neighborhood,Police Precinct,Police District,X Coordinate,Y Coordinate
+1,03/01/2009,01:00:00,Burglary,,LLOYD,PORTLAND PREC NO,690,45.5343,-122.6646
+2,07/01/2010,01:00:00,Burglary,,LLOYD,PORTLAND PREC NO,690,45.5343,-122.6646
+3,11/01/2011,01:00:00,Vandalism,,LLOYD,PORTLAND PREC NO,690,45.5231,-122.6765
+4,12/01/2011,01:00:00,Burglary,,LLOYD,PORTLAND PREC NO,690,45.5231,-122.6765
+5,01/01/2012,01:00:00,Burglary,,LLOYD,PORTLAND PREC NO,690,45.5343,-122.6646
+`
+
+// savedYears saves yearsData split by year to a temporary directory and
+// returns it.
+func savedYears(t *testing.T) string {
+	finder, err := NewCrimeFinderFromReader(strings.NewReader(yearsData), LoadOptions{})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	dir := filepath.Join(t.TempDir(), "years")
+	years, err := finder.SaveYearPartitions(dir)
+	if err != nil {
+		t.Fatal("Error saving year partitions: ", err)
+	}
+	if !reflect.DeepEqual(years, []int{2009, 2010, 2011, 2012}) {
+		t.Error("Wrong years saved: ", years)
+	}
+	return dir
+}
+
+func TestYearPartitions(t *testing.T) {
+	partitions, err := OpenYearPartitions(savedYears(t), 2)
+	if err != nil {
+		t.Fatal("Error opening year partitions: ", err)
+	}
+	if loaded := partitions.Loaded(); !reflect.DeepEqual(loaded, []int{2011, 2012}) {
+		t.Error("The most recent years should be loaded: ", loaded)
+	}
+	finder := partitions.Finder()
+	if finder.Report.Crimes != 3 {
+		t.Error("Wrong number of crimes loaded: ", finder.Report.Crimes)
+	}
+	if crime, _ := finder.FindByID(4); crime == nil {
+		t.Error("Crime from a resident year was not found")
+	}
+
+	year, err := partitions.Year(2009)
+	if err != nil || year == nil {
+		t.Fatal("Asking for a year that isn't loaded should load it: ", err)
+	}
+	if crime, _ := year.FindByID(1); crime == nil || year.Report.Crimes != 1 {
+		t.Error("Wrong crimes in a loaded year: ", year.Report.Crimes)
+	}
+	if loaded := partitions.Loaded(); !reflect.DeepEqual(loaded, []int{2009, 2011, 2012}) {
+		t.Error("Wrong years loaded: ", loaded)
+	}
+	if again, _ := partitions.Year(2009); again != year {
+		t.Error("Asking for a loaded year should return the same finder")
+	}
+	if resident, _ := partitions.Year(2011); resident != nil {
+		t.Error("A resident year should be searched with the resident finder")
+	}
+	if missing, err := partitions.Year(1999); missing != nil || err != nil {
+		t.Error("A year without a partition should be ignored: ", err)
+	}
+	// Loading a year must not change what's served for the others.
+	if after := partitions.Finder(); after.Version() != finder.Version() || after.Report.Crimes != 3 {
+		t.Error("Loading a year changed the resident finder: ", after.Report.Crimes)
+	}
+
+	if partitions.Evict(time.Hour) {
+		t.Error("A year that was just touched should not be evicted")
+	}
+	if !partitions.Evict(0) {
+		t.Error("An idle year should be evicted")
+	}
+	if loaded := partitions.Loaded(); !reflect.DeepEqual(loaded, []int{2011, 2012}) {
+		t.Error("Resident years should never be evicted: ", loaded)
+	}
+	if crime, _ := year.FindByID(1); crime == nil {
+		t.Error("An evicted year should still be searchable by whoever has it")
+	}
+}
+
+func TestYearPartitionsIncluding(t *testing.T) {
+	partitions, err := OpenYearPartitions(savedYears(t), 1)
+	if err != nil {
+		t.Fatal("Error opening year partitions: ", err)
+	}
+	including, err := partitions.Including(2010, 2011, 1999)
+	if err != nil || including.Report.Crimes != 4 {
+		t.Fatal("Wrong crimes including years: ", including.Report.Crimes, err)
+	}
+	for _, id := range []int64{2, 3, 4, 5} {
+		if crime, _ := including.FindByID(id); crime == nil {
+			t.Error("Crime missing from the included years: ", id)
+		}
+	}
+	if resident := partitions.Finder(); resident.Report.Crimes != 1 {
+		t.Error("Including years should not change the resident finder: ", resident.Report.Crimes)
+	}
+}
+
+func TestYearPartitionsBetween(t *testing.T) {
+	partitions, err := OpenYearPartitions(savedYears(t), 1)
+	if err != nil {
+		t.Fatal("Error opening year partitions: ", err)
+	}
+	from := time.Date(2010, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2011, 2, 1, 0, 0, 0, 0, time.UTC)
+	if years := partitions.Between(from, to); !reflect.DeepEqual(years, []int{2010, 2011}) {
+		t.Error("Wrong years between: ", years)
+	}
+	if years := partitions.Between(time.Time{}, to); !reflect.DeepEqual(years, []int{2009, 2010, 2011}) {
+		t.Error("Wrong years with no start: ", years)
+	}
+}
+
+func TestYearPartitionsInvalid(t *testing.T) {
+	if _, err := OpenYearPartitions(t.TempDir(), 2); err == nil {
+		t.Error("A directory with no partitions should be an error")
+	}
+	if _, err := OpenYearPartitions(savedYears(t), -1); err == nil {
+		t.Error("A negative number of resident years should be an error")
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "2011"+YEAR_PARTITION_EXTENSION), []byte("not a snapshot"), 0644)
+	if _, err := OpenYearPartitions(dir, 2); err == nil {
+		t.Error("A partition that isn't a snapshot should be an error")
+	}
+}
@@ -0,0 +1,228 @@
+package radar
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// A Ring is a closed line of points. The last point may repeat the first.
+type Ring []Point
+
+// A Polygon is an outer Ring followed by any holes cut out of it.
+type Polygon []Ring
+
+// What happens to crimes inside an ExclusionZone.
+const (
+	// RemoveZone drops the crimes inside a zone.
+	RemoveZone = iota
+	// AggregateZone moves the crimes inside a zone to a single location at
+	// the zone's center, so counts are available but not exact locations.
+	AggregateZone
+)
+
+// An ExclusionZone is an area, e.g. a park or school, whose crimes must not
+// be published at their exact locations.
+type ExclusionZone struct {
+	Name     string
+	Polygons []Polygon
+	Mode     int
+}
+
+// contains reports whether point is inside the ring, using ray casting.
+func (ring Ring) contains(point Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Lat > point.Lat) != (b.Lat > point.Lat) &&
+			point.Lng < (b.Lng-a.Lng)*(point.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lng {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Contains reports whether point is inside the polygon and not in a hole.
+func (polygon Polygon) Contains(point Point) bool {
+	if len(polygon) == 0 || !polygon[0].contains(point) {
+		return false
+	}
+	for _, hole := range polygon[1:] {
+		if hole.contains(point) {
+			return false
+		}
+	}
+	return true
+}
+
+// Contains reports whether point is inside any of the zone's polygons.
+func (zone ExclusionZone) Contains(point Point) bool {
+	for _, polygon := range zone.Polygons {
+		if polygon.Contains(point) {
+			return true
+		}
+	}
+	return false
+}
+
+// Center returns the average of the points of the zone's outer rings.
+func (zone ExclusionZone) Center() Point {
+	center := Point{}
+	n := 0.0
+	for _, polygon := range zone.Polygons {
+		if len(polygon) == 0 {
+			continue
+		}
+		for _, p := range polygon[0] {
+			center.Lat += p.Lat
+			center.Lng += p.Lng
+			n += 1
+		}
+	}
+	if n > 0 {
+		center.Lat /= n
+		center.Lng /= n
+	}
+	return center
+}
+
+// applyExclusionZones drops the rows inside RemoveZones and moves the rows
+// inside AggregateZones to the zone's center. It returns the rows that are
+// left and the number of rows that were dropped or moved.
+func applyExclusionZones(rows CsvRows, zones []ExclusionZone) (CsvRows, int) {
+	if len(zones) == 0 {
+		return rows, 0
+	}
+	kept := make(CsvRows, 0, len(rows))
+	excluded := 0
+	for _, row := range rows {
+		point, err := pointFromRow(row)
+		if err != nil {
+			kept = append(kept, row)
+			continue
+		}
+		removed := false
+		for _, zone := range zones {
+			if !zone.Contains(point) {
+				continue
+			}
+			excluded += 1
+			if zone.Mode == RemoveZone {
+				removed = true
+			} else {
+				center := zone.Center()
+				row[ADDRESS_COLUMN] = zone.Name
+				row[LAT_COLUMN] = strconv.FormatFloat(center.Lat, 'f', -1, 64)
+				row[LNG_COLUMN] = strconv.FormatFloat(center.Lng, 'f', -1, 64)
+			}
+			break
+		}
+		if !removed {
+			kept = append(kept, row)
+		}
+	}
+	return kept, excluded
+}
+
+// The parts of a GeoJSON FeatureCollection that we read exclusion zones from.
+type geoJSONFeatures struct {
+	Features []struct {
+		Properties map[string]interface{} `json:"properties"`
+		Geometry   struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"features"`
+}
+
+// polygonFromGeoJSON converts GeoJSON polygon coordinates, which are in
+// longitude, latitude order, to a Polygon.
+func polygonFromGeoJSON(coordinates [][][]float64) (Polygon, error) {
+	polygon := make(Polygon, 0, len(coordinates))
+	for _, positions := range coordinates {
+		ring := make(Ring, 0, len(positions))
+		for _, position := range positions {
+			if len(position) < 2 {
+				return nil, fmt.Errorf("position has %v coordinates", len(position))
+			}
+			point, err := NewPoint(Latitude(position[1]), Longitude(position[0]))
+			if err != nil {
+				return nil, err
+			}
+			ring = append(ring, point)
+		}
+		polygon = append(polygon, ring)
+	}
+	return polygon, nil
+}
+
+// A geoJSONArea is a Polygon or MultiPolygon feature of a GeoJSON
+// FeatureCollection.
+type geoJSONArea struct {
+	Properties map[string]interface{}
+	Polygons   []Polygon
+}
+
+// readGeoJSONAreas reads the features of a GeoJSON FeatureCollection of
+// Polygon and MultiPolygon features.
+func readGeoJSONAreas(filename string) ([]geoJSONArea, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var collection geoJSONFeatures
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, err
+	}
+	areas := make([]geoJSONArea, 0, len(collection.Features))
+	for i, feature := range collection.Features {
+		area := geoJSONArea{Properties: feature.Properties}
+		var polygons [][][][]float64
+		switch feature.Geometry.Type {
+		case "Polygon":
+			var coordinates [][][]float64
+			err = json.Unmarshal(feature.Geometry.Coordinates, &coordinates)
+			polygons = append(polygons, coordinates)
+		case "MultiPolygon":
+			err = json.Unmarshal(feature.Geometry.Coordinates, &polygons)
+		default:
+			err = fmt.Errorf("unsupported geometry type %q", feature.Geometry.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("feature %v: %v", i, err)
+		}
+		for _, coordinates := range polygons {
+			polygon, err := polygonFromGeoJSON(coordinates)
+			if err != nil {
+				return nil, fmt.Errorf("feature %v: %v", i, err)
+			}
+			area.Polygons = append(area.Polygons, polygon)
+		}
+		areas = append(areas, area)
+	}
+	return areas, nil
+}
+
+// LoadExclusionZones reads exclusion zones from a GeoJSON FeatureCollection
+// of Polygon and MultiPolygon features. A feature's "name" property names the
+// zone, and a "mode" property of "aggregate" makes it an AggregateZone.
+// Otherwise, it is a RemoveZone.
+func LoadExclusionZones(filename string) ([]ExclusionZone, error) {
+	areas, err := readGeoJSONAreas(filename)
+	if err != nil {
+		return nil, err
+	}
+	zones := make([]ExclusionZone, 0, len(areas))
+	for _, area := range areas {
+		zone := ExclusionZone{Polygons: area.Polygons, Mode: RemoveZone}
+		if name, ok := area.Properties["name"].(string); ok {
+			zone.Name = name
+		}
+		if mode, ok := area.Properties["mode"].(string); ok && mode == "aggregate" {
+			zone.Mode = AggregateZone
+		}
+		zones = append(zones, zone)
+	}
+	return zones, nil
+}
@@ -0,0 +1,102 @@
+package radar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// A square around the first location in the test data with a hole in its
+// north-east corner.
+func testZone(mode int) ExclusionZone {
+	outer := Ring{{45.53, -122.67}, {45.54, -122.67}, {45.54, -122.66}, {45.53, -122.66}, {45.53, -122.67}}
+	hole := Ring{{45.538, -122.662}, {45.54, -122.662}, {45.54, -122.66}, {45.538, -122.66}}
+	return ExclusionZone{"Test Park", []Polygon{{outer, hole}}, mode}
+}
+
+func TestExclusionZoneContains(t *testing.T) {
+	zone := testZone(RemoveZone)
+	if !zone.Contains(Point{45.53435699129174, -122.66469510763777}) {
+		t.Error("Zone should contain a point inside it")
+	}
+	if zone.Contains(Point{45.55, -122.66469510763777}) {
+		t.Error("Zone should not contain a point outside it")
+	}
+	if zone.Contains(Point{45.539, -122.661}) {
+		t.Error("Zone should not contain a point inside a hole")
+	}
+}
+
+func TestApplyExclusionZonesRemove(t *testing.T) {
+	rows := CsvRows{
+		{"1", "05/27/2011", "08:35:00", "Liquor Laws", "", "", "", "", "45.53435699129174", "-122.66469510763777"},
+		{"2", "05/27/2011", "08:35:00", "Liquor Laws", "", "", "", "", "45.55", "-122.66469510763777"},
+	}
+	kept, excluded := applyExclusionZones(rows, []ExclusionZone{testZone(RemoveZone)})
+	if len(kept) != 1 || excluded != 1 || kept[0][0] != "2" {
+		t.Error("applyExclusionZones should have removed the row inside the zone")
+	}
+}
+
+func TestApplyExclusionZonesAggregate(t *testing.T) {
+	rows := CsvRows{
+		{"1", "05/27/2011", "08:35:00", "Liquor Laws", "1 PARK WAY", "", "", "", "45.53435699129174", "-122.66469510763777"},
+		{"2", "05/27/2011", "08:35:00", "Liquor Laws", "2 PARK WAY", "", "", "", "45.531", "-122.669"},
+	}
+	kept, excluded := applyExclusionZones(rows, []ExclusionZone{testZone(AggregateZone)})
+	if len(kept) != 2 || excluded != 2 {
+		t.Error("applyExclusionZones should have kept both rows")
+	}
+	if kept[0][8] != kept[1][8] || kept[0][9] != kept[1][9] {
+		t.Error("Rows in an aggregate zone should share a location")
+	}
+	if kept[0][ADDRESS_COLUMN] != "Test Park" {
+		t.Error("Rows in an aggregate zone should not keep their addresses")
+	}
+}
+
+func TestLoadExclusionZones(t *testing.T) {
+	geojson := `{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{"name":"Test Park","mode":"aggregate"},
+		 "geometry":{"type":"Polygon","coordinates":[[[-122.67,45.53],[-122.67,45.54],[-122.66,45.54],[-122.66,45.53],[-122.67,45.53]]]}},
+		{"type":"Feature","properties":{"name":"Schools"},
+		 "geometry":{"type":"MultiPolygon","coordinates":[[[[-122.6,45.5],[-122.6,45.51],[-122.59,45.51],[-122.6,45.5]]],[[[-122.5,45.5],[-122.5,45.51],[-122.49,45.51],[-122.5,45.5]]]]}}
+	]}`
+	filename := filepath.Join(t.TempDir(), "zones.geojson")
+	os.WriteFile(filename, []byte(geojson), 0644)
+	zones, err := LoadExclusionZones(filename)
+	if err != nil {
+		t.Fatal("LoadExclusionZones returned an error: ", err)
+	}
+	if len(zones) != 2 {
+		t.Fatal("Wrong number of zones: ", len(zones))
+	}
+	if zones[0].Name != "Test Park" || zones[0].Mode != AggregateZone {
+		t.Error("First zone was loaded wrong: ", zones[0])
+	}
+	if !zones[0].Contains(Point{45.535, -122.665}) {
+		t.Error("GeoJSON coordinates should be read as longitude, latitude")
+	}
+	if zones[1].Mode != RemoveZone || len(zones[1].Polygons) != 2 {
+		t.Error("Second zone was loaded wrong: ", zones[1])
+	}
+}
+
+func TestCrimeFinderExclusionZones(t *testing.T) {
+	finder, err := NewCrimeFinderWithOptions("sample/crimes.csv", LoadOptions{
+		ExclusionZones: []ExclusionZone{testZone(RemoveZone)},
+	})
+	if err != nil {
+		t.Error("Error creating CrimeFinder: ", err)
+	}
+	if finder.Report.Excluded == 0 {
+		t.Error("Report should count the excluded crimes")
+	}
+	zone := testZone(RemoveZone)
+	for _, location := range finder.Locations() {
+		if zone.Contains(*location.Point) {
+			t.Error("CrimeFinder loaded a location in a removed zone")
+			break
+		}
+	}
+}
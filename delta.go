@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// deltaHandler compares the crimes in the "area" parameter, a bounding box
+// or a neighborhood, in "period_a" with those in "period_b": how each type's
+// count changed, and which hotspots appeared or disappeared.
+func deltaHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	a, err := radar.ParseDateRange(params.Get("period_a"))
+	if err != nil {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	b, err := radar.ParseDateRange(params.Get("period_b"))
+	if err != nil {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	area := radar.ParseArea(params.Get("area"))
+	if area.Bounds != nil {
+		if err := limits.checkBounds(*area.Bounds); err != nil {
+			writeLimitError(w, err)
+			return
+		}
+	}
+	delta := finderFor(r).Delta(area, a, b).WithPOIs(pois)
+	resp, err := delta.ToJson()
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	query := map[string]interface{}{"area": area.String(), "period_a": a.String(), "period_b": b.String()}
+	writeJson(w, r, resp, responseMeta{Query: query})
+}
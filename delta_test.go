@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDeltaHandler(t *testing.T) {
+	resp := get(t, "/stats/delta?area=downtown&period_a=2011-06&period_b=2011-12-01/2011-12-31")
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	var delta struct {
+		Area    string
+		PeriodA string `json:"period_a"`
+		TotalA  int    `json:"total_a"`
+		TotalB  int    `json:"total_b"`
+		Types   []struct{ Type string }
+	}
+	json.Unmarshal(data(t, resp), &delta)
+	if delta.Area != "downtown" || delta.PeriodA != "2011-06-01/2011-06-30" || delta.TotalA != 96 || delta.TotalB != 98 || len(delta.Types) == 0 {
+		t.Error("Wrong delta: ", delta)
+	}
+	json.Unmarshal(data(t, get(t, "/stats/delta?period_a=2011-06&period_b=2011-12")), &delta)
+	if delta.Area != "" || delta.TotalA != 196 {
+		t.Error("A missing area should be all of the data: ", delta)
+	}
+	for _, path := range []string{"?period_a=2011-06", "?period_a=June&period_b=2011-12", "?period_a=2011-06&period_b=2011-12-31/2011-12-01"} {
+		if resp := get(t, "/stats/delta"+path); resp.Code != 400 {
+			t.Error("Bad parameters should be a bad request: ", path, resp.Code)
+		}
+	}
+	if resp := get(t, "/stats/delta?area=40,-130,50,-110&period_a=2011-06&period_b=2011-12"); resp.Code != 422 {
+		t.Error("A huge area should be over the limit: ", resp.Code)
+	}
+}
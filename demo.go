@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/abrookins/radar/alerts"
+)
+
+// The defaults of a demo server. A demo is shared by everyone who finds it,
+// so each client gets a small share of it.
+const (
+	DEFAULT_DEMO_RATE   = 300
+	DEMO_PRUNE_INTERVAL = 10 * time.Minute
+	DEMO_NOTICE         = "Demo data: a sample of crimes in Portland from 2011. Don't rely on it."
+)
+
+var demo = flag.Bool("demo", false, "serve the bundled sample data as a public demo, with rate limits, tight request limits and no admin endpoints")
+var demoRate = flag.Int("demo-rate", DEFAULT_DEMO_RATE, "requests an hour each client of a -demo server may make")
+var demoBehindProxy = flag.Bool("demo-behind-proxy", false, "rate limit a -demo server's clients by the last address in X-Forwarded-For, for servers behind a proxy")
+
+// demoLimits are the limits on the requests to a demo server, which keep
+// any one of them cheap.
+var demoLimits = limitPolicy{MaxRadiusMiles: 0.25, MaxBoundsSquareMiles: 25, MaxResults: 500}
+
+// demoClients limits how many requests each client of a demo server may
+// make. It's nil when the server isn't a demo.
+var demoClients *alerts.RateLimiter
+
+// clientAddress returns the address of the client that made r, without its
+// port. Behind a proxy, that's the last address in X-Forwarded-For, which
+// the proxy added. The ones before it are whatever the client sent.
+func clientAddress(r *http.Request) string {
+	if *demoBehindProxy {
+		forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if last := strings.TrimSpace(forwarded[len(forwarded)-1]); last != "" {
+			return last
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// limitDemo is middleware that marks the responses of a demo server as
+// coming from one, and responds to clients that have made too many
+// requests with 429 Too Many Requests and when to try again.
+func limitDemo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if demoClients == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("X-Radar-Demo", "true")
+		if !demoClients.Allow(clientAddress(r)) {
+			retry := math.Ceil(demoClients.Interval.Seconds())
+			w.Header().Set("Retry-After", fmt.Sprint(retry))
+			http.Error(w, http.StatusText(429), 429)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newDemoLimiter returns the limiter of a demo server's clients, at the
+// rate the flags ask for.
+func newDemoLimiter() *alerts.RateLimiter {
+	if *demoRate < 1 {
+		log.Fatal("A demo must allow each client at least 1 request an hour")
+	}
+	return alerts.NewRateLimiter(*demoRate)
+}
+
+// pruneDemoClients forgets the clients that haven't made requests lately,
+// every interval.
+func pruneDemoClients(interval time.Duration) {
+	for range time.Tick(interval) {
+		demoClients.Prune()
+	}
+}
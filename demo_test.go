@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abrookins/radar/alerts"
+)
+
+func TestClientAddress(t *testing.T) {
+	defer func(saved bool) { *demoBehindProxy = saved }(*demoBehindProxy)
+	req := httptest.NewRequest("GET", "/meta/bounds", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 5.6.7.8")
+
+	*demoBehindProxy = false
+	if address := clientAddress(req); address != "10.0.0.1" {
+		t.Error("Wrong address of a client: ", address)
+	}
+	*demoBehindProxy = true
+	if address := clientAddress(req); address != "5.6.7.8" {
+		t.Error("Wrong address of a client behind a proxy: ", address)
+	}
+}
+
+func TestDemo(t *testing.T) {
+	defer func(saved bool) { *demo = saved; demoClients = nil }(*demo)
+	*demo = true
+	demoClients = alerts.NewRateLimiter(1)
+
+	resp := get(t, "/meta/bounds")
+	if resp.Code != 200 || resp.Header().Get("X-Radar-Demo") != "true" {
+		t.Error("Wrong response from a demo: ", resp.Code, resp.Header())
+	}
+	var body struct {
+		Meta struct{ Notice string }
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil || body.Meta.Notice != DEMO_NOTICE {
+		t.Error("A demo's responses should have a notice: ", resp.Body.String())
+	}
+	if resp := get(t, "/meta/bounds"); resp.Code != 429 {
+		t.Error("A client over the limit should get 429: ", resp.Code)
+	}
+	if resp := get(t, "/meta/bounds"); resp.Header().Get("Retry-After") != "3600" {
+		t.Error("Wrong Retry-After: ", resp.Header())
+	}
+
+	demoClients = alerts.NewRateLimiter(10)
+	for _, url := range []string{"/admin/usage", "/admin/refresh", "/exports/1", "/geofences"} {
+		if resp := get(t, url); resp.Code != 404 {
+			t.Error("A demo shouldn't serve admin endpoints: ", url, resp.Code)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// The number of removed and corrected crimes a diff lists of each.
+const DIFF_LIST_LIMIT = 20
+
+var errDiffUsage = errors.New("usage: radar [flags] diff old.csv new.csv")
+
+// runDiff loads the two data files named in args with the options the flags
+// ask for and writes a report of how the second differs from the first to
+// w: how many crimes were added, removed and corrected, how far corrected
+// crimes moved and how the count of each type changed.
+func runDiff(args []string, w io.Writer) error {
+	if len(args) != 2 {
+		return errDiffUsage
+	}
+	options := loadOptions()
+	// Jitter would move every crime differently in each file.
+	options.JitterMiles = 0
+	// Each file's bad rows are quarantined next to it.
+	options.BadRows.Quarantine = ""
+	old, err := loadFile(args[0], options)
+	if err != nil {
+		return err
+	}
+	updated, err := loadFile(args[1], options)
+	if err != nil {
+		return err
+	}
+	changes := updated.Diff(&old)
+
+	moved, maxShift, totalShift := 0, 0.0, 0.0
+	for _, correction := range changes.Corrected {
+		if shift := correction.Shift(); shift > 0 {
+			moved += 1
+			totalShift += shift
+			if shift > maxShift {
+				maxShift = shift
+			}
+		}
+	}
+	fmt.Fprintf(w, "Old: %v (%v crimes)\n", args[0], old.Report.Crimes)
+	fmt.Fprintf(w, "New: %v (%v crimes)\n\n", args[1], updated.Report.Crimes)
+	fmt.Fprintf(w, "Added:     %v\n", changes.Added)
+	fmt.Fprintf(w, "Removed:   %v\n", len(changes.Removed))
+	fmt.Fprintf(w, "Corrected: %v\n", len(changes.Corrected))
+	if moved > 0 {
+		fmt.Fprintf(w, "Moved:     %v, by up to %.3f miles and %.3f on average\n", moved, maxShift, totalShift/float64(moved))
+	}
+
+	if len(changes.Removed) > 0 {
+		fmt.Fprintln(w, "\nRemoved crimes:")
+		for i, removed := range changes.Removed {
+			if i == DIFF_LIST_LIMIT {
+				fmt.Fprintf(w, "  and %v more\n", len(changes.Removed)-i)
+				break
+			}
+			fmt.Fprintf(w, "  %v\n", removed.Crime)
+		}
+	}
+	if len(changes.Corrected) > 0 {
+		fmt.Fprintln(w, "\nCorrected crimes:")
+		for i, correction := range changes.Corrected {
+			if i == DIFF_LIST_LIMIT {
+				fmt.Fprintf(w, "  and %v more\n", len(changes.Corrected)-i)
+				break
+			}
+			fmt.Fprintf(w, "  %v -> %v", correction.Before.Crime, correction.After.Crime)
+			if shift := correction.Shift(); shift > 0 {
+				fmt.Fprintf(w, ", moved %.3f miles", shift)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+
+	// Types whose counts changed, biggest change first.
+	oldCounts, counts := old.CountByType(), updated.CountByType()
+	types := make([]string, 0)
+	for crimeType := range oldCounts {
+		if counts[crimeType] != oldCounts[crimeType] {
+			types = append(types, crimeType)
+		}
+	}
+	for crimeType := range counts {
+		if _, ok := oldCounts[crimeType]; !ok {
+			types = append(types, crimeType)
+		}
+	}
+	delta := func(crimeType string) int { return counts[crimeType] - oldCounts[crimeType] }
+	abs := func(n int) int {
+		if n < 0 {
+			return -n
+		}
+		return n
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if abs(delta(types[i])) != abs(delta(types[j])) {
+			return abs(delta(types[i])) > abs(delta(types[j]))
+		}
+		return types[i] < types[j]
+	})
+	if len(types) > 0 {
+		fmt.Fprintln(w)
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(table, "Type\tOld\tNew\tChange\t")
+		for _, crimeType := range types {
+			fmt.Fprintf(table, "%v\t%v\t%v\t%+d\t\n", crimeType, oldCounts[crimeType], counts[crimeType], delta(crimeType))
+		}
+		table.Flush()
+	}
+	return nil
+}
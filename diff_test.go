@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abrookins/radar/crimes/sample"
+)
+
+func TestRunDiff(t *testing.T) {
+	data := sample.CSV
+	lines := strings.Split(string(data), "\n")
+	lines[2] = strings.Replace(strings.Replace(lines[2], "Liquor Laws", "Disorderly Conduct", 1), "45.53579735412487", "45.536", 1)
+	lines = append(lines[:1], lines[2:]...)
+	lines = append(lines, "99000001,12/31/2011,23:00:00,Burglary,,,,,45.531,-122.661")
+	updated := filepath.Join(t.TempDir(), "new.csv")
+	if err := os.WriteFile(updated, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(bytes.Buffer)
+	if err := runDiff([]string{"crimes/sample/crimes.csv", updated}, out); err != nil {
+		t.Fatal(err)
+	}
+	report := out.String()
+	for _, want := range []string{"Added:     1\n", "Removed:   1\n", "Corrected: 1\n", "Moved:     1", "Liquor Laws  472  470      -2"} {
+		if !strings.Contains(report, want) {
+			t.Error("Report is missing ", want, ": ", report)
+		}
+	}
+
+	if err := runDiff([]string{"crimes/sample/crimes.csv"}, out); err != errDiffUsage {
+		t.Error("Wrong error for missing file: ", err)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/abrookins/radar/client"
+	"github.com/abrookins/radar/crimes"
+)
+
+var exportsDir = flag.String("exports", os.TempDir(), "directory to write exports to")
+
+// How long a finished export can be downloaded before it's deleted.
+const EXPORT_TTL = time.Hour
+
+// The number of exports that are written at once. Others wait their turn.
+const EXPORT_WORKERS = 2
+
+// exportFilter returns the ExportFilter that request asks for, or false if
+// it's invalid.
+func exportFilter(request client.ExportRequest) (radar.ExportFilter, bool) {
+	filter := radar.ExportFilter{
+		Category:   request.Category,
+		Attributes: radar.AttributeFilter{Weapon: request.Weapon, Domestic: request.Domestic, Arrest: request.Arrest},
+	}
+	if !radar.IsExportFormat(request.Format) || request.Category != "" && !radar.IsCategory(request.Category) {
+		return filter, false
+	}
+	if request.Bounds != "" {
+		bounds, err := radar.ParseBounds(request.Bounds)
+		if err != nil {
+			return filter, false
+		}
+		filter.Bounds = &bounds
+	}
+	var err error
+	if request.From != "" {
+		if filter.From, err = time.Parse("2006-01-02", request.From); err != nil {
+			return filter, false
+		}
+	}
+	if request.To != "" {
+		if filter.To, err = time.Parse("2006-01-02", request.To); err != nil {
+			return filter, false
+		}
+	}
+	return filter, true
+}
+
+// An exportJob is an export that's written in the background to filename.
+type exportJob struct {
+	client.ExportJob
+	filename string
+}
+
+// exportsLock guards exports and the jobs in it.
+var exportsLock sync.Mutex
+
+// exports holds export jobs by id until they expire.
+var exports = make(map[string]*exportJob)
+
+// exportSlots limits the number of exports written at once.
+var exportSlots = make(chan struct{}, EXPORT_WORKERS)
+
+// The Content-Types of each export format.
+var exportContentTypes = map[string]string{
+	radar.CsvFormat:     "text/csv",
+	radar.GeoJSONFormat: "application/geo+json",
+}
+
+// runExport writes the export of job, then deletes it after EXPORT_TTL.
+func runExport(job *exportJob, filter radar.ExportFilter) {
+	exportSlots <- struct{}{}
+	defer func() { <-exportSlots }()
+	setStatus(job, client.ExportRunning, nil)
+
+	selected, err := selectExport(filter)
+	if err == nil {
+		selected.BaseURL = *baseURL
+		err = writeExportFile(job.filename, selected, job.Request.Format)
+	}
+	exportsLock.Lock()
+	job.Count = len(selected.Crimes())
+	exportsLock.Unlock()
+	setStatus(job, client.ExportDone, err)
+	time.AfterFunc(EXPORT_TTL, func() {
+		exportsLock.Lock()
+		delete(exports, job.Id)
+		exportsLock.Unlock()
+		os.Remove(job.filename)
+	})
+}
+
+// selectExport returns the crimes that filter selects. If the data is
+// split by year, the years it covers are loaded.
+func selectExport(filter radar.ExportFilter) (radar.SearchResult, error) {
+	if partitions != nil {
+		including, err := partitions.Including(partitions.Between(filter.From, filter.To)...)
+		if err != nil {
+			return radar.SearchResult{}, err
+		}
+		return including.Select(filter), nil
+	}
+	// The crimes are selected under the lock, but written without it, so
+	// that a big export doesn't hold up ingestion.
+	finderLock.RLock()
+	defer finderLock.RUnlock()
+	return finder.Select(filter), nil
+}
+
+// writeExportFile writes the crimes of result to filename in format.
+func writeExportFile(filename string, result radar.SearchResult, format string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := result.WriteExport(f, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// setStatus updates the status of job. A job that ended with err fails.
+func setStatus(job *exportJob, status string, err error) {
+	exportsLock.Lock()
+	defer exportsLock.Unlock()
+	job.Status = status
+	if status == client.ExportRunning {
+		return
+	}
+	now := time.Now()
+	job.Finished = &now
+	if err != nil {
+		job.Status = client.ExportFailed
+		job.Error = err.Error()
+		log.Println("Could not write an export:", err)
+		return
+	}
+	job.Download = "/exports/" + job.Id + "/download"
+}
+
+// getExport returns a copy of the export job with id.
+func getExport(id string) (exportJob, bool) {
+	exportsLock.Lock()
+	defer exportsLock.Unlock()
+	job, ok := exports[id]
+	if !ok {
+		return exportJob{}, false
+	}
+	return *job, true
+}
+
+// exportsHandler starts an export. It responds at once with 202 Accepted
+// and the job, whose status can be followed at the URL in its Location
+// header.
+func exportsHandler(w http.ResponseWriter, r *http.Request) {
+	var request client.ExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	filter, ok := exportFilter(request)
+	if !ok {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	job := &exportJob{ExportJob: client.ExportJob{
+		Id:      hex.EncodeToString(id),
+		Status:  client.ExportPending,
+		Request: request,
+		Created: time.Now(),
+	}}
+	job.filename = filepath.Join(*exportsDir, "radar-export-"+job.Id+"."+request.Format)
+	exportsLock.Lock()
+	exports[job.Id] = job
+	copied := job.ExportJob
+	exportsLock.Unlock()
+	go runExport(job, filter)
+	audits.Record(r, AuditExportCreated, map[string]interface{}{"id": job.Id, "format": request.Format})
+	w.Header().Set("Location", "/exports/"+job.Id)
+	writeValue(w, r, 202, copied, responseMeta{})
+}
+
+// exportHandler describes an export job.
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	job, ok := getExport(id)
+	if !ok {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+	writeValue(w, r, 200, job.ExportJob, responseMeta{Query: map[string]interface{}{"id": id}})
+}
+
+// exportDownloadHandler serves a finished export. An export that isn't
+// done yet gets a 409 Conflict.
+func exportDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	job, ok := getExport(mux.Vars(r)["id"])
+	if !ok {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+	if job.Status != client.ExportDone {
+		http.Error(w, http.StatusText(409), 409)
+		return
+	}
+	w.Header().Set("Content-Type", exportContentTypes[job.Request.Format])
+	w.Header().Set("Content-Disposition", `attachment; filename="crimes-`+job.Id+"."+job.Request.Format+`"`)
+	http.ServeFile(w, r, job.filename)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abrookins/radar/client"
+)
+
+func TestExports(t *testing.T) {
+	*exportsDir = t.TempDir()
+	body := `{"format":"csv","bounds":"45.52,-122.67,45.54,-122.65","category":"property","from":"2011-06-01","to":"2011-06-30"}`
+	resp := request(t, "POST", "/exports", body)
+	if resp.Code != 202 || !strings.HasPrefix(resp.Header().Get("Location"), "/exports/") {
+		t.Fatal("Wrong status code or Location: ", resp.Code, resp.Header().Get("Location"))
+	}
+	var job client.ExportJob
+	if err := json.Unmarshal(data(t, resp), &job); err != nil || job.Id == "" {
+		t.Fatal("Response did not have the job: ", resp.Body.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status != client.ExportDone && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		resp = get(t, "/exports/"+job.Id)
+		json.Unmarshal(data(t, resp), &job)
+	}
+	if job.Status != client.ExportDone || job.Count == 0 || job.Download == "" {
+		t.Fatal("Export did not finish: ", resp.Body.String())
+	}
+
+	resp = get(t, job.Download)
+	if resp.Code != 200 || resp.Header().Get("Content-Type") != "text/csv" {
+		t.Fatal("Wrong status code or Content-Type: ", resp.Code, resp.Header().Get("Content-Type"))
+	}
+	lines := strings.Split(strings.TrimSpace(resp.Body.String()), "\n")
+	if lines[0] != "id,date,time,type,address,neighborhood,precinct,district,lat,lng,weapon,domestic,arrest,case" || len(lines) != job.Count+1 {
+		t.Error("Export has the wrong rows: ", len(lines), job.Count)
+	}
+
+	if resp := get(t, "/exports/nope"); resp.Code != 404 {
+		t.Error("Wrong status code for a missing export: ", resp.Code)
+	}
+	for _, body := range []string{`{"format":"xml"}`, `{"format":"csv","category":"nope"}`, `{"format":"csv","from":"June"}`, `{`} {
+		if resp := request(t, "POST", "/exports", body); resp.Code != 400 {
+			t.Error("Wrong status code for ", body, ": ", resp.Code)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// DEFAULT_FORECAST_HORIZON is the number of months forecast when a request
+// doesn't say.
+const DEFAULT_FORECAST_HORIZON = 3
+
+// forecastsLock guards forecasts.
+var forecastsLock sync.Mutex
+
+// forecasts is the model trained on the data being served, or nil if it
+// hasn't been trained.
+var forecasts *radar.ForecastModel
+
+// trainForecasts trains the forecast model on the data being served.
+func trainForecasts() {
+	finderLock.RLock()
+	model := finder.TrainForecasts()
+	finderLock.RUnlock()
+	forecastsLock.Lock()
+	forecasts = model
+	forecastsLock.Unlock()
+}
+
+// parseHorizon parses a number of months, like "3m". A missing horizon is
+// DEFAULT_FORECAST_HORIZON.
+func parseHorizon(value string) (int, bool) {
+	if value == "" {
+		return DEFAULT_FORECAST_HORIZON, true
+	}
+	months, err := strconv.Atoi(strings.TrimSuffix(value, "m"))
+	return months, err == nil && months > 0 && months <= radar.MAX_FORECAST_HORIZON
+}
+
+// forecastHandler returns the forecast counts of crimes in a cell of the
+// grid for the months after the data ends. Searches of past data train a
+// model for the request.
+func forecastHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	cell, err := radar.ParseGridCell(params.Get("cell"))
+	if err != nil {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	horizon, ok := parseHorizon(params.Get("horizon"))
+	if !ok {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	forecastsLock.Lock()
+	model := forecasts
+	forecastsLock.Unlock()
+	if searched := finderFor(r); searched != &finder || model == nil {
+		model = searched.TrainForecasts()
+	}
+	resp, err := model.Forecast(cell, horizon).ToJson()
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	query := map[string]interface{}{"cell": cell.String(), "horizon": strconv.Itoa(horizon) + "m"}
+	writeJson(w, r, resp, responseMeta{Query: query})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestForecastHandler(t *testing.T) {
+	resp := get(t, "/stats/forecast?cell=6375:-16945&horizon=6m")
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	var forecast struct {
+		Cell   string
+		Method string
+		Months []struct {
+			Month               string
+			Count, Lower, Upper float64
+		}
+	}
+	if err := json.Unmarshal(data(t, resp), &forecast); err != nil {
+		t.Fatal("Response was not a forecast: ", resp.Body.String())
+	}
+	if forecast.Cell != "6375:-16945" || len(forecast.Months) != 6 || forecast.Months[0].Month != "2012-01" || forecast.Months[0].Count == 0 {
+		t.Error("Wrong forecast: ", forecast)
+	}
+	json.Unmarshal(data(t, get(t, "/stats/forecast?cell=6375:-16945")), &forecast)
+	if len(forecast.Months) != DEFAULT_FORECAST_HORIZON {
+		t.Error("Wrong default horizon: ", len(forecast.Months))
+	}
+	for _, path := range []string{"", "?cell=downtown", "?cell=1:2&horizon=0m", "?cell=1:2&horizon=25m", "?cell=1:2&horizon=3w"} {
+		if resp := get(t, "/stats/forecast"+path); resp.Code != 400 {
+			t.Error("Bad parameters should be a bad request: ", path, resp.Code)
+		}
+	}
+}
+
+func TestParseHorizon(t *testing.T) {
+	for value, expected := range map[string]int{"": DEFAULT_FORECAST_HORIZON, "1m": 1, "12": 12, "24m": 24} {
+		if months, ok := parseHorizon(value); !ok || months != expected {
+			t.Error("Wrong horizon: ", value, months)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+	"github.com/abrookins/radar/internal/objectstore"
+)
+
+var geocoderURL = flag.String("geocoder", "", "URL of a Nominatim-compatible search endpoint to geocode the addresses of crimes missing coordinates with")
+var geocodeCache = flag.String("geocode-cache", "", "file to keep geocoded addresses in across loads")
+var geocodeInterval = flag.Duration("geocode-interval", time.Second, "time to wait between requests to the geocoder")
+
+// The time a geocoder has to answer.
+const GEOCODE_TIMEOUT = 10 * time.Second
+
+// geocoder is the cached geocoder the flags ask for, once it's loaded.
+var geocoder *radar.CachedGeocoder
+
+// A nominatimGeocoder geocodes addresses with a search endpoint that
+// answers like OpenStreetMap's Nominatim.
+type nominatimGeocoder struct {
+	url    string
+	client *http.Client
+}
+
+func (g nominatimGeocoder) Geocode(address string) (radar.Point, error) {
+	values := url.Values{"q": {address}, "format": {"json"}, "limit": {"1"}}
+	req, err := http.NewRequest("GET", g.url+"?"+values.Encode(), nil)
+	if err != nil {
+		return radar.Point{}, err
+	}
+	// Nominatim's usage policy asks clients to identify themselves.
+	req.Header.Set("User-Agent", "radar")
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return radar.Point{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return radar.Point{}, fmt.Errorf("geocoder returned %v", resp.Status)
+	}
+	var places []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&places); err != nil {
+		return radar.Point{}, err
+	}
+	if len(places) == 0 {
+		return radar.Point{}, radar.ErrNotGeocoded
+	}
+	lat, err := strconv.ParseFloat(places[0].Lat, 64)
+	if err != nil {
+		return radar.Point{}, err
+	}
+	lng, err := strconv.ParseFloat(places[0].Lon, 64)
+	if err != nil {
+		return radar.Point{}, err
+	}
+	return radar.NewPoint(radar.Latitude(lat), radar.Longitude(lng))
+}
+
+// loadGeocoder returns the geocoder the flags ask for, or nil if they don't
+// ask for one.
+func loadGeocoder() radar.Geocoder {
+	if *geocoderURL == "" {
+		return nil
+	}
+	if geocoder == nil {
+		var err error
+		client := &http.Client{Timeout: GEOCODE_TIMEOUT}
+		geocoder, err = radar.NewCachedGeocoder(nominatimGeocoder{*geocoderURL, client}, *geocodeCache, *geocodeInterval)
+		if err != nil {
+			log.Fatal("Could not load the geocoding cache. ", err)
+		}
+	}
+	return geocoder
+}
+
+// loadFile loads a data file, which may be an object URL or a Socrata
+// dataset, with options, and saves the addresses it geocoded, if any.
+func loadFile(name string, options radar.LoadOptions) (radar.CrimeFinder, error) {
+	var loaded radar.CrimeFinder
+	var err error
+	switch {
+	case isSocrataURL(name):
+		loaded, err = loadSocrata(name, options)
+	case objectstore.IsURL(name):
+		var local string
+		if local, err = fetchObject(name); err != nil {
+			return radar.CrimeFinder{}, err
+		}
+		defer os.Remove(local)
+		options.BadRows.Quarantine = options.BadRows.QuarantineFile(localSource(name))
+		loaded, err = radar.NewCrimeFinderWithOptions(local, options)
+	default:
+		loaded, err = radar.NewCrimeFinderWithOptions(name, options)
+	}
+	if geocoder != nil {
+		if err := geocoder.Save(); err != nil {
+			log.Println("Could not save the geocoding cache. ", err)
+		}
+	}
+	return loaded, err
+}
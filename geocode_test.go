@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+func TestNominatimGeocoder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") != "json" || r.Header.Get("User-Agent") == "" {
+			http.Error(w, http.StatusText(400), 400)
+			return
+		}
+		if r.URL.Query().Get("q") == "NE SCHUYLER ST" {
+			w.Write([]byte(`[{"lat":"45.53579735412487","lon":"-122.66468312170824"}]`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	g := nominatimGeocoder{server.URL, server.Client()}
+
+	point, err := g.Geocode("NE SCHUYLER ST")
+	if err != nil || point.Lat != 45.53579735412487 || point.Lng != -122.66468312170824 {
+		t.Error("Wrong point for address: ", point, err)
+	}
+	if _, err := g.Geocode("NOWHERE AVE"); err != radar.ErrNotGeocoded {
+		t.Error("Unknown addresses should not be geocoded: ", err)
+	}
+}
+
+func TestLoadFileSavesGeocodeCache(t *testing.T) {
+	defer func(saved *radar.CachedGeocoder) { geocoder = saved }(geocoder)
+	dir := t.TempDir()
+	cache := filepath.Join(dir, "geocode.json")
+	lookups := 0
+	geocoder, _ = radar.NewCachedGeocoder(radar.GeocoderFunc(func(address string) (radar.Point, error) {
+		lookups++
+		return radar.Point{Lat: 45.5357, Lng: -122.6646}, nil
+	}), cache, 0)
+
+	data := filepath.Join(dir, "ungeocoded.csv")
+	os.WriteFile(data, []byte(`Record ID,Report Date,Report Time,Major Offense Type,Address,Neighborhood,Police Precinct,Police District,X Coordinate,Y Coordinate
+1,07/07/2011,18:30:00,Liquor Laws,NE SCHUYLER ST,ELIOT,PORTLAND PREC NO,590,,
+`), 0644)
+	loaded, err := loadFile(data, radar.LoadOptions{Geocoder: geocoder})
+	if err != nil || loaded.Report.Geocoded != 1 || lookups != 1 {
+		t.Fatal("Wrong geocoded load: ", err, loaded.Report.Geocoded, lookups)
+	}
+	if _, err := os.Stat(cache); err != nil {
+		t.Error("Loading should save the geocoding cache: ", err)
+	}
+}
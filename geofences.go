@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/abrookins/radar/alerts"
+	"github.com/abrookins/radar/crimes"
+)
+
+var geofencesFilename = flag.String("geofences", "", "file to keep geofences in across restarts")
+var smtpAddr = flag.String("smtp-addr", "", "host:port of the SMTP server for email alerts")
+var smtpFrom = flag.String("smtp-from", "radar@localhost", "sender address of email alerts")
+var twilioSid = flag.String("twilio-sid", "", "Twilio account SID for SMS alerts; the auth token is read from TWILIO_AUTH_TOKEN")
+var twilioFrom = flag.String("twilio-from", "", "phone number SMS alerts are sent from")
+var ingest = flag.Bool("ingest", false, "accept new crimes with POST /crimes")
+var createAPIKey = flag.String("create-api-key", "", "create an API key with this name in the -geofences file, print it and exit")
+var alertTemplateFilename = flag.String("alert-template", "", "file with a Go text/template for the text of alerts")
+var alertsPerHour = flag.Int("alerts-per-hour", 0, "most alerts an hour to each target, or 0 for no limit")
+
+// The time a webhook has to respond to an alert.
+const WEBHOOK_TIMEOUT = 10 * time.Second
+
+// finderLock keeps searches from running while crimes are ingested.
+var finderLock sync.RWMutex
+
+// The geofences that ingested crimes are checked against. Until main loads
+// them from a file, they're only kept in memory.
+var geofences, _ = alerts.NewStore("")
+var alerter = newAlerter(geofences)
+
+// The template of email, Slack and Discord alerts. If it's nil, they use
+// alerts.DefaultTemplate.
+var alertTemplate *template.Template
+
+// newAlerter creates an Alerter for store that sends email if the server
+// has an SMTP server, SMS if it has a Twilio account, and that's rate limited if -alerts-per-hour is set.
+func newAlerter(store *alerts.Store) *alerts.Alerter {
+	alerter := &alerts.Alerter{
+		Store:   store,
+		Webhook: alerts.NewWebhookNotifier(WEBHOOK_TIMEOUT),
+		Slack:   alerts.NewSlackNotifier(WEBHOOK_TIMEOUT, alertTemplate),
+		Discord: alerts.NewDiscordNotifier(WEBHOOK_TIMEOUT, alertTemplate),
+	}
+	if *smtpAddr != "" {
+		alerter.Email = &alerts.EmailNotifier{Addr: *smtpAddr, From: *smtpFrom, Template: alertTemplate}
+	}
+	if *twilioSid != "" {
+		alerter.SMS = alerts.NewSMSNotifier(WEBHOOK_TIMEOUT, *twilioSid, os.Getenv("TWILIO_AUTH_TOKEN"), *twilioFrom)
+	}
+	if *alertsPerHour > 0 {
+		alerter.Limiter = alerts.NewRateLimiter(*alertsPerHour)
+	}
+	return alerter
+}
+
+// readLocked wraps a handler that reads the finder so that it doesn't run
+// while crimes are ingested. A request with an "as_of" parameter reads the
+// data as it was then instead. A request with an "if_version" parameter
+// gets a 409 Conflict unless the data is still that version, so a client
+// that makes several requests can tell that the data changed between them.
+func readLocked(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		finderLock.RLock()
+		defer finderLock.RUnlock()
+		r, current := withHistory(w, r)
+		if r == nil {
+			return
+		}
+		if version := r.URL.Query().Get("if_version"); version != "" && version != current {
+			http.Error(w, http.StatusText(409), 409)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// requireKey wraps a handler that manages geofences so that, once the
+// store has API keys, a request needs one in its X-API-Key header. Requests
+// with a key, and without a valid one, are audited.
+func requireKey(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if geofences.HasKeys() {
+			details := map[string]interface{}{"method": r.Method, "path": r.URL.Path}
+			key, ok := r.Context().Value(apiKeyKey).(alerts.APIKey)
+			if !ok {
+				key, ok = geofences.FindKey(r.Header.Get("X-API-Key"))
+			}
+			if !ok {
+				audits.Record(r, AuditKeyRejected, details)
+				http.Error(w, http.StatusText(401), 401)
+				return
+			}
+			r = withAPIKey(r, key)
+			audits.Record(r, AuditKeyUsed, details)
+		}
+		handler(w, r)
+	}
+}
+
+// writeValue writes value, marshalled to JSON, with status.
+func writeValue(w http.ResponseWriter, r *http.Request, status int, value interface{}, meta responseMeta) {
+	resp, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	writeJsonStatus(w, r, status, resp, meta)
+}
+
+// readGeofence reads a geofence from the body of a request. Email and SMS
+// targets are refused if the server can't send them.
+func readGeofence(r *http.Request) (alerts.Geofence, bool) {
+	var geofence alerts.Geofence
+	if err := json.NewDecoder(r.Body).Decode(&geofence); err != nil {
+		return geofence, false
+	}
+	// Only the server says who owns a geofence.
+	geofence.Owner = ""
+	if geofence.Target.Email != "" && alerter.Email == nil {
+		return geofence, false
+	}
+	if geofence.Target.SMS != "" && alerter.SMS == nil {
+		return geofence, false
+	}
+	return geofence, geofence.Validate() == nil
+}
+
+// geofencesHandler lists geofences and creates them.
+func geofencesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		list := geofences.List()
+		count := len(list)
+		writeValue(w, r, 200, list, responseMeta{Count: &count})
+	case "POST":
+		geofence, ok := readGeofence(r)
+		if !ok {
+			http.Error(w, http.StatusText(400), 400)
+			return
+		}
+		if key, ok := r.Context().Value(apiKeyKey).(alerts.APIKey); ok {
+			geofence.Owner = key.Id
+		}
+		geofence, err := geofences.Create(geofence)
+		if err != nil {
+			http.Error(w, http.StatusText(500), 500)
+			log.Println(err)
+			return
+		}
+		audits.Record(r, AuditGeofenceCreated, map[string]interface{}{"id": geofence.Id})
+		writeValue(w, r, 201, geofence, responseMeta{})
+	default:
+		http.Error(w, http.StatusText(405), 405)
+	}
+}
+
+// geofenceHandler gets, replaces and deletes a geofence.
+func geofenceHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var err error
+	switch r.Method {
+	case "GET":
+		var geofence alerts.Geofence
+		if geofence, err = geofences.Get(id); err == nil {
+			writeValue(w, r, 200, geofence, responseMeta{Query: map[string]interface{}{"id": id}})
+			return
+		}
+	case "PUT":
+		geofence, ok := readGeofence(r)
+		if !ok {
+			http.Error(w, http.StatusText(400), 400)
+			return
+		}
+		geofence.Id = id
+		var previous alerts.Geofence
+		if previous, err = geofences.Get(id); err != nil {
+			break
+		}
+		geofence.Owner = previous.Owner
+		if err = geofences.Update(geofence); err == nil {
+			audits.Record(r, AuditGeofenceUpdated, map[string]interface{}{"id": id})
+			writeValue(w, r, 200, geofence, responseMeta{Query: map[string]interface{}{"id": id}})
+			return
+		}
+	case "DELETE":
+		if err = geofences.Delete(id); err == nil {
+			audits.Record(r, AuditGeofenceDeleted, map[string]interface{}{"id": id})
+			w.WriteHeader(204)
+			return
+		}
+	default:
+		http.Error(w, http.StatusText(405), 405)
+		return
+	}
+	if err == alerts.ErrNotFound {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+	http.Error(w, http.StatusText(500), 500)
+	log.Println(err)
+}
+
+// ingestHandler adds the crimes in the CSV body of a request to the data
+// and alerts the geofences they're inside. Crimes that were added are in
+// the ingest log, if there is one, before the response is sent.
+func ingestHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	finderLock.Lock()
+	added, rowErrors, err := finder.IngestWithPolicy(bytes.NewReader(body), mappedSchema, ingestRows)
+	added.BaseURL = *baseURL
+	var logErr error
+	if len(added.Locations) > 0 {
+		logErr = ingests.Append(body, added)
+	}
+	updateDatasetVersion()
+	finderLock.Unlock()
+	if logErr != nil {
+		// The crimes were added, but won't survive a restart.
+		http.Error(w, http.StatusText(500), 500)
+		log.Println("Could not log ingested crimes:", logErr)
+		return
+	}
+	if isBadRows(err) {
+		http.Error(w, http.StatusText(422)+": "+err.Error(), 422)
+		return
+	}
+	// The body has been read, so an error writing a file is the quarantine
+	// file's.
+	var pathError *os.PathError
+	if errors.As(err, &pathError) {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println("Could not quarantine ingested rows:", err)
+		return
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	crimes := len(added.Crimes())
+	log.Printf("Ingested %v crimes", crimes)
+	audits.Record(r, AuditIngest, map[string]interface{}{"crimes": crimes, "skipped": len(rowErrors)})
+	if crimes > 0 {
+		go warmCache()
+		go func() {
+			finderLock.RLock()
+			defer finderLock.RUnlock()
+			recordHistory()
+		}()
+		go func(added radar.SearchResult) {
+			for _, err := range alerter.Alert(added) {
+				log.Println("Could not send an alert:", err)
+			}
+		}(added)
+	}
+	writeValue(w, r, 201, struct {
+		Crimes  int `json:"crimes"`
+		Skipped int `json:"skipped"`
+	}{crimes, len(rowErrors)}, responseMeta{Count: &crimes})
+}
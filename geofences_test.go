@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abrookins/radar/alerts"
+	"github.com/abrookins/radar/crimes/sample"
+)
+
+// request makes a request with a body to the server's router and returns
+// the response.
+func request(t *testing.T, method string, url string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	resp := httptest.NewRecorder()
+	newRouter().ServeHTTP(resp, req)
+	return resp
+}
+
+func TestGeofences(t *testing.T) {
+	geofences, _ = alerts.NewStore("")
+	alerter = newAlerter(geofences)
+	body := `{"name":"home","center":{"lat":45.53,"lng":-122.66},"radius_miles":0.5,"target":{"webhook":"http://localhost/hook"}}`
+	resp := request(t, "POST", "/geofences", body)
+	if resp.Code != 201 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	var created alerts.Geofence
+	if err := json.Unmarshal(data(t, resp), &created); err != nil || created.Id == "" {
+		t.Fatal("Response did not have the geofence: ", resp.Body.String())
+	}
+
+	resp = request(t, "PUT", "/geofences/"+created.Id, strings.Replace(body, "home", "work", 1))
+	if resp.Code != 200 {
+		t.Error("Wrong status code for an update: ", resp.Code)
+	}
+	resp = get(t, "/geofences/"+created.Id)
+	var found alerts.Geofence
+	if err := json.Unmarshal(data(t, resp), &found); err != nil || found.Name != "work" {
+		t.Error("Response did not have the updated geofence: ", resp.Body.String())
+	}
+	resp = get(t, "/geofences")
+	var list []alerts.Geofence
+	if err := json.Unmarshal(data(t, resp), &list); err != nil || len(list) != 1 {
+		t.Error("Response did not list the geofence: ", resp.Body.String())
+	}
+
+	if resp = request(t, "DELETE", "/geofences/"+created.Id, ""); resp.Code != 204 {
+		t.Error("Wrong status code for a delete: ", resp.Code)
+	}
+	if resp = get(t, "/geofences/"+created.Id); resp.Code != 404 {
+		t.Error("Wrong status code for a deleted geofence: ", resp.Code)
+	}
+}
+
+func TestGeofencesInvalid(t *testing.T) {
+	bodies := []string{
+		`{"center":{"lat":45.53,"lng":-122.66},"target":{"webhook":"http://localhost/hook"}}`,
+		`{"center":{"lat":45.53,"lng":-122.66},"radius_miles":0.5,"target":{"email":"someone@example.com"}}`,
+		`{"center":{"lat":45.53,"lng":-122.66},"radius_miles":0.5,"target":{"sms":"+15035550100"}}`,
+		`not json`,
+	}
+	for _, body := range bodies {
+		if resp := request(t, "POST", "/geofences", body); resp.Code != 400 {
+			t.Error("Wrong status code for ", body, ": ", resp.Code)
+		}
+	}
+}
+
+func TestIngestAlerts(t *testing.T) {
+	defer func() {
+		*ingest = false
+		finder = sample.NewFinder()
+		updateDatasetVersion()
+	}()
+	notified := make(chan alerts.Geofence, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Geofence alerts.Geofence }
+		json.NewDecoder(r.Body).Decode(&body)
+		notified <- body.Geofence
+	}))
+	defer server.Close()
+	geofences, _ = alerts.NewStore("")
+	alerter = newAlerter(geofences)
+	geofence, _ := geofences.Create(alerts.Geofence{
+		Center:      &alerts.Point{Lat: 45.53, Lng: -122.66},
+		RadiusMiles: 0.5,
+		Target:      alerts.Target{Webhook: server.URL},
+	})
+
+	if resp := request(t, "POST", "/crimes", "99000001,12/31/2011,23:00:00,Burglary,,,,,45.531,-122.661\n"); resp.Code != 404 {
+		t.Error("Ingest should be off unless enabled: ", resp.Code)
+	}
+	*ingest = true
+	resp := request(t, "POST", "/crimes", "99000001,12/31/2011,23:00:00,Burglary,,,,,45.531,-122.661\n")
+	if resp.Code != 201 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	select {
+	case actual := <-notified:
+		if actual.Id != geofence.Id {
+			t.Error("Wrong geofence notified: ", actual)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Geofence was not notified")
+	}
+	if crime, _ := finder.FindByID(99000001); crime == nil {
+		t.Error("Ingested crime was not added")
+	}
+}
+
+func TestGeofencesAPIKeys(t *testing.T) {
+	defer func() {
+		geofences, _ = alerts.NewStore("")
+	}()
+	geofences, _ = alerts.NewStore("")
+	key, _ := geofences.CreateKey("test")
+
+	if resp := get(t, "/geofences"); resp.Code != 401 {
+		t.Error("Geofences should need a key once there are keys: ", resp.Code)
+	}
+	req := httptest.NewRequest("GET", "/geofences", nil)
+	req.Header.Set("X-API-Key", key.Key)
+	resp := httptest.NewRecorder()
+	newRouter().ServeHTTP(resp, req)
+	if resp.Code != 200 {
+		t.Error("Wrong status code with a key: ", resp.Code)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+var historyDir = flag.String("history", "", "directory to keep snapshots of earlier versions of the data in, for as_of searches")
+var historySize = flag.Int("history-size", 10, "number of versions of the data to keep in the -history directory")
+
+// The versions of the data that as_of searches run against. It's nil
+// unless the server has a -history directory.
+var history *radar.History
+
+// historicalKey is the key of the finder that a request with an "as_of"
+// parameter searches.
+const historicalKey contextKey = 1
+
+// recordHistory saves the finder's data to the history, if the server keeps
+// one. The caller must hold a lock on the finder.
+func recordHistory() {
+	if history == nil {
+		return
+	}
+	if err := history.Record(&finder); err != nil {
+		log.Println("Could not save the data to the history. ", err)
+	}
+}
+
+// parseAsOf parses the value of an "as_of" parameter: a date, which means
+// the end of that day in UTC, or an RFC 3339 time.
+func parseAsOf(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t.Add(24*time.Hour - time.Nanosecond), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// withHistory returns r with the finder that its "as_of" parameter asks
+// for, and that finder's version. Without the parameter, it returns r and
+// the current version. It writes an error and returns nil if the server
+// has no history or none from that time.
+func withHistory(w http.ResponseWriter, r *http.Request) (*http.Request, string) {
+	value := r.URL.Query().Get("as_of")
+	if value == "" {
+		return r, currentDatasetVersion()
+	}
+	asOf, err := parseAsOf(value)
+	if err != nil || history == nil {
+		http.Error(w, http.StatusText(400), 400)
+		return nil, ""
+	}
+	historical, err := history.At(asOf)
+	if err == radar.ErrNoHistory {
+		http.Error(w, http.StatusText(404), 404)
+		return nil, ""
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return nil, ""
+	}
+	return r.WithContext(context.WithValue(r.Context(), historicalKey, historical)), historical.Version()
+}
+
+// finderFor returns the finder that r searches: the one its "as_of"
+// parameter asks for, the one of the year it asks about if the data is
+// split by year, or the current one.
+func finderFor(r *http.Request) *radar.CrimeFinder {
+	if historical, ok := r.Context().Value(historicalKey).(*radar.CrimeFinder); ok {
+		return historical
+	}
+	if year, ok := r.Context().Value(yearKey).(*radar.CrimeFinder); ok {
+		return year
+	}
+	return &finder
+}
+
+// finderVersion returns the version of the data that r searches.
+func finderVersion(r *http.Request) string {
+	if historical, ok := r.Context().Value(historicalKey).(*radar.CrimeFinder); ok {
+		return historical.Version()
+	}
+	return currentDatasetVersion()
+}
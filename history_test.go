@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+	"github.com/abrookins/radar/crimes/sample"
+)
+
+func TestAsOf(t *testing.T) {
+	defer func() {
+		history = nil
+		finder = sample.NewFinder()
+		updateDatasetVersion()
+	}()
+	if resp := get(t, "/meta/bounds?as_of=2014-06-01"); resp.Code != 400 {
+		t.Error("Wrong status code without a history: ", resp.Code)
+	}
+
+	var err error
+	history, err = radar.NewHistory(t.TempDir(), 5)
+	if err != nil {
+		t.Fatal("Error creating a History: ", err)
+	}
+	recordHistory()
+	old := finder.Version()
+	time.Sleep(time.Millisecond)
+	if _, _, err := finder.Ingest(strings.NewReader("99000001,12/31/2011,23:00:00,Burglary,,,,,45.531,-122.661\n"), nil); err != nil {
+		t.Fatal("Error ingesting a crime: ", err)
+	}
+	updateDatasetVersion()
+	recordHistory()
+
+	asOf := history.Entries()[0].LoadedAt.Format(time.RFC3339Nano)
+	resp := get(t, "/crimes/99000001?as_of="+asOf)
+	if resp.Code != 404 {
+		t.Error("Crime should not exist in the old data: ", resp.Code)
+	}
+	resp = get(t, "/meta/bounds?as_of="+asOf)
+	var body struct {
+		Meta struct {
+			DatasetVersion string `json:"dataset_version"`
+		}
+	}
+	json.Unmarshal(resp.Body.Bytes(), &body)
+	if resp.Code != 200 || body.Meta.DatasetVersion != old {
+		t.Error("Response should have the old version: ", resp.Code, body.Meta.DatasetVersion)
+	}
+	if resp := get(t, "/crimes/99000001?as_of="+time.Now().UTC().Format("2006-01-02")); resp.Code != 200 {
+		t.Error("Crime should exist in today's data: ", resp.Code)
+	}
+	if resp := get(t, "/meta/bounds?as_of=2014-06-01"); resp.Code != 404 {
+		t.Error("Wrong status code for a time before the history: ", resp.Code)
+	}
+	if resp := get(t, "/meta/bounds?as_of=June"); resp.Code != 400 {
+		t.Error("Wrong status code for a bad time: ", resp.Code)
+	}
+	if resp := get(t, "/meta/bounds?as_of="+asOf+"&if_version="+old); resp.Code != 200 {
+		t.Error("if_version should match the old version: ", resp.Code)
+	}
+}
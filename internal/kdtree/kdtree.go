@@ -0,0 +1,191 @@
+// Package kdtree implements a static k-dimensional tree supporting range
+// searches over points.
+package kdtree
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// A Node is a point stored in the tree.
+type Node struct {
+	Coordinates []float64
+	Data        interface{}
+	Left        *Node
+	Right       *Node
+	axis        int
+}
+
+// A Range is an inclusive interval of values along one axis.
+type Range struct {
+	Min float64
+	Max float64
+}
+
+// A Tree is a kd-tree built from a set of Nodes.
+type Tree struct {
+	Root *Node
+	// Dimensions is the number of coordinates in each node.
+	Dimensions int
+	// Min and Max hold the smallest and largest coordinates along each axis.
+	Min []float64
+	Max []float64
+}
+
+// BuildTree builds a balanced tree from nodes. Every node must have the same
+// number of coordinates. The nodes slice is reordered in place.
+func BuildTree(nodes []*Node) *Tree {
+	tree := &Tree{}
+	if len(nodes) == 0 {
+		return tree
+	}
+	tree.Dimensions = len(nodes[0].Coordinates)
+	tree.Min = append([]float64{}, nodes[0].Coordinates...)
+	tree.Max = append([]float64{}, nodes[0].Coordinates...)
+	for _, node := range nodes {
+		for axis, value := range node.Coordinates {
+			tree.Min[axis] = math.Min(tree.Min[axis], value)
+			tree.Max[axis] = math.Max(tree.Max[axis], value)
+		}
+	}
+	tree.Root = build(nodes, 0, tree.Dimensions)
+	return tree
+}
+
+// build recursively splits nodes around the median along axis.
+func build(nodes []*Node, depth int, dims int) *Node {
+	if len(nodes) == 0 {
+		return nil
+	}
+	axis := depth % dims
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Coordinates[axis] < nodes[j].Coordinates[axis]
+	})
+	median := len(nodes) / 2
+	node := nodes[median]
+	node.axis = axis
+	node.Left = build(nodes[:median], depth+1, dims)
+	node.Right = build(nodes[median+1:], depth+1, dims)
+	return node
+}
+
+// FindRange returns all nodes whose coordinates fall within ranges, which
+// maps an axis to the Range of accepted values along it. Axes without a
+// Range are unbounded.
+func (t *Tree) FindRange(ranges map[int]Range) ([]*Node, error) {
+	results, _, err := t.FindRangeVisited(ranges)
+	return results, err
+}
+
+// FindRangeVisited works like FindRange and also returns the number of nodes
+// the search visited, which shows how much of the tree a range touches.
+func (t *Tree) FindRangeVisited(ranges map[int]Range) ([]*Node, int, error) {
+	for axis, r := range ranges {
+		if axis < 0 || (t.Root != nil && axis >= t.Dimensions) {
+			return nil, 0, errors.New("kdtree: range axis out of bounds")
+		}
+		if r.Min > r.Max {
+			return nil, 0, errors.New("kdtree: range minimum is greater than maximum")
+		}
+	}
+	results := make([]*Node, 0)
+	visited := 0
+	t.Root.findRange(ranges, &results, &visited)
+	return results, visited, nil
+}
+
+// findRange appends the nodes in this subtree that fall within ranges.
+func (n *Node) findRange(ranges map[int]Range, results *[]*Node, visited *int) {
+	if n == nil {
+		return
+	}
+	*visited += 1
+	r, bounded := ranges[n.axis]
+	value := n.Coordinates[n.axis]
+	if !bounded || r.Min <= value {
+		n.Left.findRange(ranges, results, visited)
+	}
+	if n.within(ranges) {
+		*results = append(*results, n)
+	}
+	if !bounded || value <= r.Max {
+		n.Right.findRange(ranges, results, visited)
+	}
+}
+
+// within reports whether the node's coordinates fall within ranges.
+func (n *Node) within(ranges map[int]Range) bool {
+	for axis, r := range ranges {
+		value := n.Coordinates[axis]
+		if value < r.Min || value > r.Max {
+			return false
+		}
+	}
+	return true
+}
+
+// Nodes returns every node in the tree.
+func (t *Tree) Nodes() []*Node {
+	nodes := make([]*Node, 0)
+	t.Root.walk(func(n *Node) { nodes = append(nodes, n) })
+	return nodes
+}
+
+// walk calls f on every node in this subtree, in order.
+func (n *Node) walk(f func(*Node)) {
+	if n == nil {
+		return
+	}
+	n.Left.walk(f)
+	f(n)
+	n.Right.walk(f)
+}
+
+// Nearest returns the node closest to coordinates by Euclidean distance, or
+// nil if the tree is empty.
+func (t *Tree) Nearest(coordinates []float64) *Node {
+	return t.nearestExcept(coordinates, nil)
+}
+
+// NearestOther returns the node closest to node that isn't node itself, or
+// nil if there is no other node.
+func (t *Tree) NearestOther(node *Node) *Node {
+	return t.nearestExcept(node.Coordinates, node)
+}
+
+// nearestExcept returns the node closest to coordinates other than except.
+func (t *Tree) nearestExcept(coordinates []float64, except *Node) *Node {
+	var best *Node
+	bestDistance := math.Inf(1)
+	t.Root.nearest(coordinates, except, &best, &bestDistance)
+	return best
+}
+
+// nearest searches this subtree for a node closer to coordinates than best,
+// skipping except and subtrees that can't hold a closer node.
+func (n *Node) nearest(coordinates []float64, except *Node, best **Node, bestDistance *float64) {
+	if n == nil {
+		return
+	}
+	if n != except {
+		distance := 0.0
+		for axis, value := range n.Coordinates {
+			d := value - coordinates[axis]
+			distance += d * d
+		}
+		if distance < *bestDistance {
+			*best = n
+			*bestDistance = distance
+		}
+	}
+	d := coordinates[n.axis] - n.Coordinates[n.axis]
+	near, far := n.Left, n.Right
+	if d > 0 {
+		near, far = n.Right, n.Left
+	}
+	near.nearest(coordinates, except, best, bestDistance)
+	if d*d <= *bestDistance {
+		far.nearest(coordinates, except, best, bestDistance)
+	}
+}
@@ -0,0 +1,179 @@
+package kdtree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func makeNodes(coords ...[]float64) []*Node {
+	nodes := make([]*Node, 0)
+	for _, c := range coords {
+		nodes = append(nodes, &Node{Coordinates: c})
+	}
+	return nodes
+}
+
+func TestBuildTreeEmpty(t *testing.T) {
+	tree := BuildTree(nil)
+	results, err := tree.FindRange(map[int]Range{0: {Min: 0, Max: 1}})
+	if err != nil {
+		t.Error("FindRange on an empty tree returned an error: ", err)
+	}
+	if len(results) != 0 {
+		t.Error("FindRange on an empty tree returned nodes: ", len(results))
+	}
+}
+
+func TestFindRange(t *testing.T) {
+	nodes := makeNodes(
+		[]float64{1, 1},
+		[]float64{2, 5},
+		[]float64{3, 3},
+		[]float64{4, 4},
+		[]float64{5, 2},
+	)
+	tree := BuildTree(nodes)
+	results, err := tree.FindRange(map[int]Range{0: {Min: 2, Max: 4}, 1: {Min: 2, Max: 4}})
+	if err != nil {
+		t.Error("FindRange returned an error: ", err)
+	}
+	if len(results) != 2 {
+		t.Error("FindRange returned the wrong number of nodes: ", len(results))
+	}
+	for _, n := range results {
+		if n.Coordinates[0] < 2 || n.Coordinates[0] > 4 || n.Coordinates[1] < 2 || n.Coordinates[1] > 4 {
+			t.Error("FindRange returned a node outside the range: ", n.Coordinates)
+		}
+	}
+}
+
+func TestFindRangeVisited(t *testing.T) {
+	nodes := make([]*Node, 0)
+	for i := 0; i < 100; i++ {
+		nodes = append(nodes, &Node{Coordinates: []float64{float64(i), float64(i)}})
+	}
+	tree := BuildTree(nodes)
+	results, visited, err := tree.FindRangeVisited(map[int]Range{0: {Min: 10, Max: 12}, 1: {Min: 10, Max: 12}})
+	if err != nil {
+		t.Error("FindRangeVisited returned an error: ", err)
+	}
+	if len(results) != 3 {
+		t.Error("FindRangeVisited returned the wrong number of nodes: ", len(results))
+	}
+	if visited < len(results) || visited >= 100 {
+		t.Error("FindRangeVisited should visit some, but not all, nodes: ", visited)
+	}
+}
+
+func TestFindRangeInclusive(t *testing.T) {
+	tree := BuildTree(makeNodes([]float64{1, 1}))
+	results, _ := tree.FindRange(map[int]Range{0: {Min: 1, Max: 1}, 1: {Min: 1, Max: 1}})
+	if len(results) != 1 {
+		t.Error("FindRange should include nodes on the edge of the range")
+	}
+}
+
+func TestFindRangeBadRange(t *testing.T) {
+	tree := BuildTree(makeNodes([]float64{1, 1}))
+	_, err := tree.FindRange(map[int]Range{0: {Min: 2, Max: 1}})
+	if err == nil {
+		t.Error("FindRange should return an error when Min is greater than Max")
+	}
+	_, err = tree.FindRange(map[int]Range{2: {Min: 0, Max: 1}})
+	if err == nil {
+		t.Error("FindRange should return an error for an axis the tree does not have")
+	}
+}
+
+// Compare range searches against a linear scan of random points.
+func TestFindRangeMatchesLinearScan(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	nodes := make([]*Node, 0)
+	for i := 0; i < 1000; i++ {
+		nodes = append(nodes, &Node{Coordinates: []float64{r.Float64(), r.Float64()}})
+	}
+	all := append([]*Node{}, nodes...)
+	tree := BuildTree(nodes)
+
+	for i := 0; i < 100; i++ {
+		x, y := r.Float64(), r.Float64()
+		ranges := map[int]Range{0: {Min: x - 0.1, Max: x + 0.1}, 1: {Min: y - 0.1, Max: y + 0.1}}
+		results, _ := tree.FindRange(ranges)
+		expected := 0
+		for _, n := range all {
+			if n.within(ranges) {
+				expected++
+			}
+		}
+		if len(results) != expected {
+			t.Error("FindRange returned ", len(results), " nodes, expected ", expected)
+		}
+	}
+}
+
+func TestTreeNodes(t *testing.T) {
+	tree := BuildTree(makeNodes([]float64{1, 5}, []float64{3, -2}, []float64{-4, 0}))
+	if len(tree.Nodes()) != 3 {
+		t.Error("Nodes returned the wrong number of nodes: ", len(tree.Nodes()))
+	}
+	if len(BuildTree(nil).Nodes()) != 0 {
+		t.Error("Nodes of an empty tree should be empty")
+	}
+}
+
+func TestTreeBounds(t *testing.T) {
+	tree := BuildTree(makeNodes([]float64{1, 5}, []float64{3, -2}, []float64{-4, 0}))
+	if tree.Min[0] != -4 || tree.Min[1] != -2 || tree.Max[0] != 3 || tree.Max[1] != 5 {
+		t.Error("Tree has the wrong bounds: ", tree.Min, tree.Max)
+	}
+}
+
+func TestNearestEmpty(t *testing.T) {
+	if BuildTree(nil).Nearest([]float64{0, 0}) != nil {
+		t.Error("Nearest on an empty tree should return nil")
+	}
+}
+
+// Compare nearest-neighbor searches against a linear scan of random points.
+func TestNearestMatchesLinearScan(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	nodes := make([]*Node, 0)
+	for i := 0; i < 1000; i++ {
+		nodes = append(nodes, &Node{Coordinates: []float64{r.Float64(), r.Float64()}})
+	}
+	all := append([]*Node{}, nodes...)
+	tree := BuildTree(nodes)
+
+	distance := func(n *Node, c []float64) float64 {
+		dx, dy := n.Coordinates[0]-c[0], n.Coordinates[1]-c[1]
+		return dx*dx + dy*dy
+	}
+	for i := 0; i < 100; i++ {
+		query := []float64{r.Float64()*2 - 0.5, r.Float64()*2 - 0.5}
+		expected := all[0]
+		for _, n := range all {
+			if distance(n, query) < distance(expected, query) {
+				expected = n
+			}
+		}
+		if tree.Nearest(query) != expected {
+			t.Error("Nearest returned the wrong node for ", query)
+		}
+	}
+}
+
+func TestNearestOther(t *testing.T) {
+	nodes := makeNodes([]float64{0, 0}, []float64{0, 0}, []float64{1, 1}, []float64{5, 5})
+	first, second, far := nodes[0], nodes[1], nodes[3]
+	tree := BuildTree(nodes)
+	if tree.NearestOther(first) != second || tree.NearestOther(second) != first {
+		t.Error("NearestOther should find a different node at the same coordinates")
+	}
+	if tree.NearestOther(far).Coordinates[0] != 1 {
+		t.Error("NearestOther returned the wrong node: ", tree.NearestOther(far).Coordinates)
+	}
+	single := makeNodes([]float64{0, 0})
+	if BuildTree(single).NearestOther(single[0]) != nil {
+		t.Error("NearestOther should return nil when there is no other node")
+	}
+}
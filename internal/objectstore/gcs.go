@@ -0,0 +1,201 @@
+package objectstore
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// The scope of the access tokens the client asks Cloud Storage for.
+const GCS_SCOPE = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// The time the metadata server has to answer, which it only does on Google
+// Cloud.
+const METADATA_TIMEOUT = 2 * time.Second
+
+// How long a client that couldn't get a token from the metadata server
+// sends requests without one before it asks again.
+const ANONYMOUS_INTERVAL = 5 * time.Minute
+
+var errServiceAccount = errors.New("objectstore: GOOGLE_APPLICATION_CREDENTIALS is not a service account key")
+
+// gcsEndpoint returns the base URL of Cloud Storage, and true if it's an
+// emulator named by STORAGE_EMULATOR_HOST, which needs no credentials.
+func gcsEndpoint() (string, bool) {
+	host := os.Getenv("STORAGE_EMULATOR_HOST")
+	if host == "" {
+		return "https://storage.googleapis.com", false
+	}
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	return strings.TrimSuffix(host, "/"), true
+}
+
+// gcsURL returns the URL of an object in Cloud Storage's JSON API, or the
+// URL to upload it to if upload is true.
+func gcsURL(object Object, upload bool) string {
+	base, _ := gcsEndpoint()
+	bucket := url.PathEscape(object.Bucket)
+	if upload {
+		return base + "/upload/storage/v1/b/" + bucket + "/o?uploadType=media&name=" + url.QueryEscape(object.Key)
+	}
+	return base + "/storage/v1/b/" + bucket + "/o/" + url.PathEscape(object.Key) + "?alt=media"
+}
+
+// gcsToken returns an access token for Cloud Storage, or "" to send
+// requests without one. The token is the first of:
+//
+//   - GOOGLE_OAUTH_ACCESS_TOKEN, if it's set.
+//   - A token for the service account whose key file is named by
+//     GOOGLE_APPLICATION_CREDENTIALS.
+//   - A token from the metadata server of the Google Cloud machine the
+//     server runs on, at GCE_METADATA_HOST if it's set.
+//
+// If none of them can be had, requests are sent without a token, which
+// works for public buckets. Tokens are kept until shortly before they
+// expire.
+func (c *Client) gcsToken() (string, error) {
+	if _, emulated := gcsEndpoint(); emulated {
+		return "", nil
+	}
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.now().Before(c.expires) {
+		return c.token, nil
+	}
+	var token string
+	var lifetime time.Duration
+	var err error
+	if filename := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); filename != "" {
+		if token, lifetime, err = c.serviceAccountToken(filename); err != nil {
+			return "", err
+		}
+	} else if token, lifetime, err = c.metadataToken(); err != nil {
+		token, lifetime = "", ANONYMOUS_INTERVAL
+	}
+	c.token, c.expires = token, c.now().Add(lifetime-time.Minute)
+	return c.token, nil
+}
+
+// tokenResponse is an access token from Google's OAuth server or the
+// metadata server.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// readToken reads the access token in resp.
+func readToken(resp *http.Response) (string, time.Duration, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return "", 0, fmt.Errorf("objectstore: %v getting an access token", resp.Status)
+	}
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", 0, err
+	}
+	if token.AccessToken == "" {
+		return "", 0, errors.New("objectstore: no access token in response")
+	}
+	return token.AccessToken, time.Duration(token.ExpiresIn) * time.Second, nil
+}
+
+// metadataToken gets a token for the machine's service account from the
+// metadata server.
+func (c *Client) metadataToken() (string, time.Duration, error) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	req, err := http.NewRequest("GET", "http://"+host+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := (&http.Client{Timeout: METADATA_TIMEOUT}).Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	return readToken(resp)
+}
+
+// serviceAccount is the part of a service account key file the client
+// uses.
+type serviceAccount struct {
+	Type        string `json:"type"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+func base64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// serviceAccountToken gets a token for the service account whose key is in
+// filename, by signing a JWT with its key and exchanging it at its token
+// URI.
+func (c *Client) serviceAccountToken(filename string) (string, time.Duration, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", 0, err
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(data, &account); err != nil || account.Type != "service_account" {
+		return "", 0, errServiceAccount
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return "", 0, errServiceAccount
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", 0, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", 0, errServiceAccount
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	now := c.now()
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   account.ClientEmail,
+		"scope": GCS_SCOPE,
+		"aud":   account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64URL([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + base64URL(claims)
+	hashed := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", 0, err
+	}
+	resp, err := c.http().PostForm(account.TokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {unsigned + "." + base64URL(signature)},
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	return readToken(resp)
+}
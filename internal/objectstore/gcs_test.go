@@ -0,0 +1,110 @@
+package objectstore
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGCSURL(t *testing.T) {
+	t.Setenv("STORAGE_EMULATOR_HOST", "")
+	object := Object{"gs", "radar", "pdx/crimes.csv"}
+	if url := gcsURL(object, false); url != "https://storage.googleapis.com/storage/v1/b/radar/o/pdx%2Fcrimes.csv?alt=media" {
+		t.Error("Wrong URL: ", url)
+	}
+	if url := gcsURL(object, true); url != "https://storage.googleapis.com/upload/storage/v1/b/radar/o?uploadType=media&name=pdx%2Fcrimes.csv" {
+		t.Error("Wrong upload URL: ", url)
+	}
+	t.Setenv("STORAGE_EMULATOR_HOST", "localhost:4443")
+	if url := gcsURL(object, false); !strings.HasPrefix(url, "http://localhost:4443/storage/v1/") {
+		t.Error("Wrong URL for an emulator: ", url)
+	}
+}
+
+func TestServiceAccountToken(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		parts := strings.Split(r.FormValue("assertion"), ".")
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || len(parts) != 3 {
+			http.Error(w, http.StatusText(400), 400)
+			return
+		}
+		hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		if rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hashed[:], signature) != nil {
+			http.Error(w, http.StatusText(401), 401)
+			return
+		}
+		w.Write([]byte(`{"access_token":"account-token","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	account, _ := json.Marshal(serviceAccount{
+		Type:        "service_account",
+		ClientEmail: "radar@example.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    server.URL,
+	})
+	filename := filepath.Join(t.TempDir(), "account.json")
+	os.WriteFile(filename, account, 0600)
+	t.Setenv("STORAGE_EMULATOR_HOST", "")
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "")
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filename)
+
+	c := &Client{}
+	for i := 0; i < 2; i++ {
+		if token, err := c.gcsToken(); token != "account-token" || err != nil {
+			t.Error("Wrong token for the service account: ", token, err)
+		}
+	}
+	if requests != 1 {
+		t.Error("Token should be kept until it expires: ", requests)
+	}
+
+	os.WriteFile(filename, []byte(`{"type":"authorized_user"}`), 0600)
+	if _, err := (&Client{}).gcsToken(); err != errServiceAccount {
+		t.Error("Wrong error for a key file that isn't a service account's: ", err)
+	}
+}
+
+func TestMetadataToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, http.StatusText(403), 403)
+			return
+		}
+		w.Write([]byte(`{"access_token":"machine-token","expires_in":3600}`))
+	}))
+	defer server.Close()
+	t.Setenv("STORAGE_EMULATOR_HOST", "")
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "")
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+	if token, err := (&Client{}).gcsToken(); token != "machine-token" || err != nil {
+		t.Error("Wrong token from the metadata server: ", token, err)
+	}
+
+	// Off Google Cloud, requests are sent without a token.
+	t.Setenv("GCE_METADATA_HOST", "127.0.0.1:1")
+	if token, err := (&Client{}).gcsToken(); token != "" || err != nil {
+		t.Error("There should be no token without a metadata server: ", token, err)
+	}
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "env-token")
+	if token, _ := (&Client{}).gcsToken(); token != "env-token" {
+		t.Error("Token from the environment should be used: ", token)
+	}
+}
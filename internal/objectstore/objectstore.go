@@ -0,0 +1,165 @@
+// Package objectstore gets and puts whole objects in Amazon S3 and Google
+// Cloud Storage, named by URLs like s3://bucket/key and gs://bucket/key,
+// with credentials from the environment. It speaks just enough of each
+// service's HTTP API for the server to load data and snapshots from them and
+// save snapshots to them.
+package objectstore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The time a request has to finish, including reading the object.
+const DEFAULT_TIMEOUT = 10 * time.Minute
+
+// ErrNotFound is returned for objects that don't exist.
+var ErrNotFound = errors.New("objectstore: object not found")
+
+var errURL = errors.New("objectstore: URL must be s3://bucket/key or gs://bucket/key")
+
+// IsURL returns true if name is the URL of an object rather than a file.
+func IsURL(name string) bool {
+	return strings.HasPrefix(name, "s3://") || strings.HasPrefix(name, "gs://")
+}
+
+// An Object is an object in a bucket of one of the services.
+type Object struct {
+	Scheme string
+	Bucket string
+	Key    string
+}
+
+// Parse parses an object URL.
+func Parse(url string) (Object, error) {
+	scheme, rest, ok := strings.Cut(url, "://")
+	if !ok || scheme != "s3" && scheme != "gs" {
+		return Object{}, errURL
+	}
+	bucket, key, ok := strings.Cut(rest, "/")
+	if !ok || bucket == "" || key == "" {
+		return Object{}, errURL
+	}
+	return Object{scheme, bucket, key}, nil
+}
+
+func (object Object) String() string {
+	return object.Scheme + "://" + object.Bucket + "/" + object.Key
+}
+
+// A Client gets and puts objects. The zero value reads its credentials from
+// the environment when it first needs them; see signS3 and gcsToken for
+// which variables each service uses. A Client is safe to use from several
+// goroutines.
+type Client struct {
+	// HTTP sends the requests, with a timeout of DEFAULT_TIMEOUT if nil.
+	HTTP *http.Client
+	// Now returns the time requests are signed at, time.Now if nil.
+	Now func() time.Time
+	mu  sync.Mutex
+	// token is the last access token for Cloud Storage, and expires when
+	// it can no longer be used.
+	token   string
+	expires time.Time
+}
+
+func (c *Client) http() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return &http.Client{Timeout: DEFAULT_TIMEOUT}
+}
+
+func (c *Client) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// Open returns a reader of the object at url, which the caller must close.
+func (c *Client) Open(url string) (io.ReadCloser, error) {
+	object, err := Parse(url)
+	if err != nil {
+		return nil, err
+	}
+	req, err := c.request("GET", object, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(resp, object); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Put stores data as the object at url, replacing it if it exists.
+func (c *Client) Put(url string, data []byte) error {
+	object, err := Parse(url)
+	if err != nil {
+		return err
+	}
+	req, err := c.request("PUT", object, data)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp, object)
+}
+
+// request returns an authorized request to get the object, or to put data
+// into it if method is PUT.
+func (c *Client) request(method string, object Object, data []byte) (*http.Request, error) {
+	if object.Scheme == "s3" {
+		req, err := http.NewRequest(method, s3URL(object), bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		signS3(req, data, c.now())
+		return req, nil
+	}
+	url := gcsURL(object, method == "PUT")
+	if method == "PUT" {
+		// Cloud Storage takes uploads as POSTs.
+		method = "POST"
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	token, err := c.gcsToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
+
+// checkResponse returns an error if resp isn't a success.
+func checkResponse(resp *http.Response, object Object) error {
+	if resp.StatusCode == 404 {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("objectstore: %v for %v: %s", resp.Status, object, bytes.TrimSpace(message))
+	}
+	return nil
+}
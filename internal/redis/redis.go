@@ -0,0 +1,341 @@
+// Package redis is a small Redis client, with just what the server needs to
+// share a cache and geofences between instances.
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The time a command has to get a reply, including waiting for a
+// connection, before it fails. A cache that answers slower than this isn't
+// worth waiting for.
+const DEFAULT_TIMEOUT = 100 * time.Millisecond
+
+// The most connections a Client opens at once.
+const DEFAULT_POOL_SIZE = 8
+
+// An Error is an error reply from Redis.
+type Error string
+
+func (e Error) Error() string {
+	return "redis: " + string(e)
+}
+
+var errProtocol = errors.New("redis: invalid reply")
+
+// ErrPoolTimeout is returned when a command's time runs out while it waits
+// for one of the other commands to finish with a connection.
+var ErrPoolTimeout = errors.New("redis: timed out waiting for a connection")
+
+// ErrAborted is returned when a transaction isn't run because a key it
+// depends on has changed.
+var ErrAborted = errors.New("redis: transaction aborted")
+
+// A conn is an open connection to the server.
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// A Client sends commands to a Redis server over a small pool of
+// connections, which it opens when they're needed and drops after network
+// errors. A Client is safe to use from several goroutines, up to PoolSize
+// of which send commands at once while the rest wait their turn.
+type Client struct {
+	Addr string
+	// Password, if set, is sent with AUTH when connecting.
+	Password string
+	// Timeout is the time a command has to get a reply, from when it's
+	// called.
+	Timeout  time.Duration
+	PoolSize int
+
+	once sync.Once
+	// slots holds a value for each command being sent, so that no more
+	// than PoolSize are at once.
+	slots chan struct{}
+	mu    sync.Mutex
+	idle  []*conn
+}
+
+// NewClient creates a Client for the server at addr, a host:port.
+func NewClient(addr string) *Client {
+	return &Client{Addr: addr, Timeout: DEFAULT_TIMEOUT, PoolSize: DEFAULT_POOL_SIZE}
+}
+
+// connect opens a connection that must be used by deadline.
+func (client *Client) connect(deadline time.Time) (*conn, error) {
+	dialer := net.Dialer{Deadline: deadline}
+	netConn, err := dialer.Dial("tcp", client.Addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &conn{netConn, bufio.NewReader(netConn)}
+	if client.Password != "" {
+		if _, err := c.do([]string{"AUTH", client.Password}, deadline); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// acquire waits until deadline for a turn to send a command and returns an
+// idle connection, or a new one if none are idle.
+func (client *Client) acquire(deadline time.Time) (*conn, error) {
+	client.once.Do(func() {
+		client.slots = make(chan struct{}, max(client.PoolSize, 1))
+	})
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case client.slots <- struct{}{}:
+	case <-timer.C:
+		return nil, ErrPoolTimeout
+	}
+	client.mu.Lock()
+	if n := len(client.idle); n > 0 {
+		c := client.idle[n-1]
+		client.idle = client.idle[:n-1]
+		client.mu.Unlock()
+		return c, nil
+	}
+	client.mu.Unlock()
+	c, err := client.connect(deadline)
+	if err != nil {
+		<-client.slots
+		return nil, err
+	}
+	return c, nil
+}
+
+// release returns c to the pool, or closes it if broken is true, and ends
+// the command's turn.
+func (client *Client) release(c *conn, broken bool) {
+	if broken {
+		c.Close()
+	} else {
+		client.mu.Lock()
+		client.idle = append(client.idle, c)
+		client.mu.Unlock()
+	}
+	<-client.slots
+}
+
+// Close closes the client's idle connections. Connections that are in use
+// are closed as they're released.
+func (client *Client) Close() {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	for _, c := range client.idle {
+		c.Close()
+	}
+	client.idle = nil
+}
+
+// Do sends a command and returns its reply: a string for simple strings,
+// an int64 for integers, a []byte or nil for bulk strings, and an
+// []interface{} or nil for arrays. Error replies are returned as an Error.
+// If there's no reply within Timeout, the error is ErrPoolTimeout or a
+// net.Error whose Timeout method returns true.
+func (client *Client) Do(args ...string) (interface{}, error) {
+	deadline := time.Now().Add(client.Timeout)
+	c, err := client.acquire(deadline)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := c.do(args, deadline)
+	_, isReply := err.(Error)
+	// After a network error, the connection may be in the middle of a
+	// reply, so it's dropped.
+	client.release(c, err != nil && !isReply)
+	return reply, err
+}
+
+// do sends a command on the connection and reads its reply by deadline.
+func (c *conn) do(args []string, deadline time.Time) (interface{}, error) {
+	c.SetDeadline(deadline)
+	writer := bufio.NewWriter(c)
+	if err := writeCommand(writer, args); err != nil {
+		return nil, err
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, err
+	}
+	return readReply(c.reader)
+}
+
+// writeCommand writes a command as an array of bulk strings.
+func writeCommand(w io.Writer, args []string) error {
+	if _, err := fmt.Fprintf(w, "*%d\r\n", len(args)); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if _, err := fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readReply reads one reply, or one command, which is an array of bulk
+// strings.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errProtocol
+	}
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, Error(body)
+	case ':':
+		n, err := strconv.ParseInt(body, 10, 64)
+		if err != nil {
+			return nil, errProtocol
+		}
+		return n, nil
+	case '$':
+		size, err := strconv.Atoi(body)
+		if err != nil || size < -1 {
+			return nil, errProtocol
+		}
+		if size == -1 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		count, err := strconv.Atoi(body)
+		if err != nil || count < -1 {
+			return nil, errProtocol
+		}
+		if count == -1 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, errProtocol
+}
+
+// Get returns the value of key, or nil if it isn't set.
+func (client *Client) Get(key string) ([]byte, error) {
+	reply, err := client.Do("GET", key)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, errProtocol
+	}
+	return value, nil
+}
+
+// Set sets key to value. If ttl is greater than zero, the key expires after
+// it.
+func (client *Client) Set(key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+	_, err := client.Do(args...)
+	return err
+}
+
+// Incr increments the integer value of key and returns the new value.
+func (client *Client) Incr(key string) (int64, error) {
+	reply, err := client.Do("INCR", key)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, errProtocol
+	}
+	return n, nil
+}
+
+// SetIfRevision sets key to value and increments the integer at revision,
+// as one transaction, but only if revision is still expected, so that a
+// change made since the caller read it isn't overwritten. It returns the
+// new revision, or ErrAborted if revision has changed.
+func (client *Client) SetIfRevision(key string, value []byte, revision string, expected int64) (int64, error) {
+	deadline := time.Now().Add(client.Timeout)
+	c, err := client.acquire(deadline)
+	if err != nil {
+		return 0, err
+	}
+	n, err := c.setIfRevision(key, value, revision, expected, deadline)
+	// After any error, the connection may still be watching the revision
+	// or in the middle of the transaction, so it's dropped.
+	client.release(c, err != nil && err != ErrAborted)
+	return n, err
+}
+
+func (c *conn) setIfRevision(key string, value []byte, revision string, expected int64, deadline time.Time) (int64, error) {
+	if _, err := c.do([]string{"WATCH", revision}, deadline); err != nil {
+		return 0, err
+	}
+	reply, err := c.do([]string{"GET", revision}, deadline)
+	if err != nil {
+		return 0, err
+	}
+	current := int64(0)
+	if reply != nil {
+		data, ok := reply.([]byte)
+		if !ok {
+			return 0, errProtocol
+		}
+		if current, err = strconv.ParseInt(string(data), 10, 64); err != nil {
+			return 0, errProtocol
+		}
+	}
+	if current != expected {
+		if _, err := c.do([]string{"UNWATCH"}, deadline); err != nil {
+			return 0, err
+		}
+		return 0, ErrAborted
+	}
+	for _, args := range [][]string{{"MULTI"}, {"SET", key, string(value)}, {"INCR", revision}} {
+		if _, err := c.do(args, deadline); err != nil {
+			return 0, err
+		}
+	}
+	reply, err = c.do([]string{"EXEC"}, deadline)
+	if err != nil {
+		return 0, err
+	}
+	if reply == nil {
+		return 0, ErrAborted
+	}
+	replies, ok := reply.([]interface{})
+	if !ok || len(replies) != 2 {
+		return 0, errProtocol
+	}
+	n, ok := replies[1].(int64)
+	if !ok {
+		return 0, errProtocol
+	}
+	return n, nil
+}
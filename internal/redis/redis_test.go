@@ -0,0 +1,247 @@
+package redis
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeServer serves GET, SET, INCR and AUTH from a map, like Redis, and
+// transactions with WATCH, UNWATCH, MULTI and EXEC, until its listener is
+// closed. It counts the connections it accepts in accepted.
+func fakeServer(t *testing.T) (net.Listener, *int64) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var accepted int64
+	values := make(map[string]string)
+	// versions counts the changes to each key, for WATCH.
+	versions := make(map[string]int)
+	run := func(args []interface{}) string {
+		key := ""
+		if len(args) > 1 {
+			key = string(args[1].([]byte))
+		}
+		switch string(args[0].([]byte)) {
+		case "AUTH":
+			return "+OK\r\n"
+		case "GET":
+			if value, ok := values[key]; ok {
+				return "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+			}
+			return "$-1\r\n"
+		case "SET":
+			values[key] = string(args[2].([]byte))
+			versions[key]++
+			return "+OK\r\n"
+		case "INCR":
+			n, _ := strconv.Atoi(values[key])
+			values[key] = strconv.Itoa(n + 1)
+			versions[key]++
+			return ":" + values[key] + "\r\n"
+		}
+		return "-ERR unknown command\r\n"
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt64(&accepted, 1)
+			go func() {
+				reader, writer := bufio.NewReader(conn), bufio.NewWriter(conn)
+				watched := make(map[string]int)
+				var queued [][]interface{}
+				inMulti := false
+				for {
+					command, err := readReply(reader)
+					if err != nil {
+						conn.Close()
+						break
+					}
+					args := command.([]interface{})
+					mu.Lock()
+					switch name := string(args[0].([]byte)); {
+					case name == "WATCH":
+						for _, key := range args[1:] {
+							watched[string(key.([]byte))] = versions[string(key.([]byte))]
+						}
+						writer.WriteString("+OK\r\n")
+					case name == "UNWATCH":
+						watched = make(map[string]int)
+						writer.WriteString("+OK\r\n")
+					case name == "MULTI":
+						inMulti = true
+						writer.WriteString("+OK\r\n")
+					case name == "EXEC":
+						aborted := false
+						for key, version := range watched {
+							aborted = aborted || versions[key] != version
+						}
+						if aborted {
+							writer.WriteString("*-1\r\n")
+						} else {
+							writer.WriteString("*" + strconv.Itoa(len(queued)) + "\r\n")
+							for _, args := range queued {
+								writer.WriteString(run(args))
+							}
+						}
+						watched, queued, inMulti = make(map[string]int), nil, false
+					case inMulti:
+						queued = append(queued, args)
+						writer.WriteString("+QUEUED\r\n")
+					default:
+						writer.WriteString(run(args))
+					}
+					mu.Unlock()
+					writer.Flush()
+				}
+			}()
+		}
+	}()
+	return listener, &accepted
+}
+
+func TestClient(t *testing.T) {
+	listener, _ := fakeServer(t)
+	defer listener.Close()
+	client := NewClient(listener.Addr().String())
+	client.Password = "secret"
+	defer client.Close()
+
+	if value, err := client.Get("missing"); err != nil || value != nil {
+		t.Error("Get of a missing key should return nil: ", value, err)
+	}
+	if err := client.Set("key", []byte("line one\r\nline two"), time.Minute); err != nil {
+		t.Fatal("Error setting a key: ", err)
+	}
+	if value, err := client.Get("key"); err != nil || string(value) != "line one\r\nline two" {
+		t.Error("Get returned the wrong value: ", string(value), err)
+	}
+	if n, err := client.Incr("counter"); err != nil || n != 1 {
+		t.Error("Incr returned the wrong value: ", n, err)
+	}
+	if _, err := client.Do("FLUSHALL"); err == nil {
+		t.Error("Do should return error replies")
+	} else if _, ok := err.(Error); !ok {
+		t.Error("Error replies should be an Error: ", err)
+	}
+	if n, err := client.Incr("counter"); err != nil || n != 2 {
+		t.Error("Client should keep working after an error reply: ", n, err)
+	}
+}
+
+func TestClientReconnects(t *testing.T) {
+	listener, _ := fakeServer(t)
+	defer listener.Close()
+	client := NewClient(listener.Addr().String())
+	client.Set("key", []byte("value"), 0)
+	client.mu.Lock()
+	for _, c := range client.idle {
+		c.Conn.Close()
+	}
+	client.mu.Unlock()
+
+	client.Get("key")
+	if value, err := client.Get("key"); err != nil || string(value) != "value" {
+		t.Error("Client should reconnect after a network error: ", string(value), err)
+	}
+}
+
+func TestClientPool(t *testing.T) {
+	listener, accepted := fakeServer(t)
+	defer listener.Close()
+	client := NewClient(listener.Addr().String())
+	client.PoolSize = 2
+	client.Timeout = 5 * time.Second
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Incr("counter"); err != nil {
+				t.Error("Error incrementing from several goroutines: ", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if value, err := client.Get("counter"); err != nil || string(value) != "20" {
+		t.Error("Wrong count from several goroutines: ", string(value), err)
+	}
+	if n := atomic.LoadInt64(accepted); n < 1 || n > 2 {
+		t.Error("The client should open at most PoolSize connections: ", n)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	// A server that accepts connections but never replies.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	client := NewClient(listener.Addr().String())
+	client.PoolSize = 1
+	client.Timeout = 50 * time.Millisecond
+	defer client.Close()
+
+	start := time.Now()
+	_, err = client.Get("key")
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Error("A command without a reply should time out: ", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Error("A command should fail once its time runs out: ", elapsed)
+	}
+
+	// While another command has the only connection, there's none to get.
+	held, err := client.acquire(time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatal("Error getting a connection: ", err)
+	}
+	defer client.release(held, true)
+	if _, err := client.Get("key"); err != ErrPoolTimeout {
+		t.Error("A command should time out waiting for a connection: ", err)
+	}
+}
+
+func TestClientSetIfRevision(t *testing.T) {
+	listener, _ := fakeServer(t)
+	defer listener.Close()
+	client := NewClient(listener.Addr().String())
+	defer client.Close()
+
+	if n, err := client.SetIfRevision("key", []byte("first"), "key:revision", 0); err != nil || n != 1 {
+		t.Fatal("Error setting a key at its revision: ", n, err)
+	}
+	if _, err := client.SetIfRevision("key", []byte("stale"), "key:revision", 0); err != ErrAborted {
+		t.Error("SetIfRevision should not set a key whose revision changed: ", err)
+	}
+	if value, _ := client.Get("key"); string(value) != "first" {
+		t.Error("An aborted SetIfRevision changed the key: ", string(value))
+	}
+	if n, err := client.SetIfRevision("key", []byte("second"), "key:revision", 1); err != nil || n != 2 {
+		t.Error("Error setting a key at its new revision: ", n, err)
+	}
+	if value, _ := client.Get("key"); string(value) != "second" {
+		t.Error("SetIfRevision set the wrong value: ", string(value))
+	}
+}
@@ -0,0 +1,270 @@
+// Package roaring implements compressed bitmaps of 32-bit integers in the
+// manner of Roaring bitmaps: the integers are split by their high 16 bits
+// into containers, each of which is a sorted array while it's sparse and a
+// bitmap once it's dense, so that sets of both kinds are small and fast to
+// intersect.
+package roaring
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// A container with more values than ARRAY_MAX is stored as a bitmap.
+const ARRAY_MAX = 4096
+
+// The number of 64-bit words in a bitmap container.
+const bitmapWords = 1 << 16 / 64
+
+// A container holds the low 16 bits of the values that share high bits.
+// It's an array if bitmap is nil.
+type container struct {
+	array  []uint16
+	bitmap []uint64
+	// count is the number of values in a bitmap container.
+	count int
+}
+
+func (c *container) cardinality() int {
+	if c.bitmap != nil {
+		return c.count
+	}
+	return len(c.array)
+}
+
+func (c *container) contains(low uint16) bool {
+	if c.bitmap != nil {
+		return c.bitmap[low/64]&(1<<(low%64)) != 0
+	}
+	i := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= low })
+	return i < len(c.array) && c.array[i] == low
+}
+
+func (c *container) add(low uint16) {
+	if c.bitmap != nil {
+		word, bit := low/64, uint64(1)<<(low%64)
+		if c.bitmap[word]&bit == 0 {
+			c.bitmap[word] |= bit
+			c.count++
+		}
+		return
+	}
+	// Values are usually added in order, so try appending first.
+	n := len(c.array)
+	if n == 0 || c.array[n-1] < low {
+		c.array = append(c.array, low)
+	} else {
+		i := sort.Search(n, func(i int) bool { return c.array[i] >= low })
+		if c.array[i] == low {
+			return
+		}
+		c.array = append(c.array, 0)
+		copy(c.array[i+1:], c.array[i:])
+		c.array[i] = low
+	}
+	if len(c.array) > ARRAY_MAX {
+		c.toBitmap()
+	}
+}
+
+// toBitmap converts an array container to a bitmap.
+func (c *container) toBitmap() {
+	c.bitmap = make([]uint64, bitmapWords)
+	for _, low := range c.array {
+		c.bitmap[low/64] |= 1 << (low % 64)
+	}
+	c.count = len(c.array)
+	c.array = nil
+}
+
+// toArray converts a bitmap container to an array.
+func (c *container) toArray() {
+	c.array = make([]uint16, 0, c.count)
+	c.each(func(low uint16) bool {
+		c.array = append(c.array, low)
+		return true
+	})
+	c.bitmap = nil
+	c.count = 0
+}
+
+// each calls visit with the container's values in order until it returns
+// false, and returns false if it did.
+func (c *container) each(visit func(low uint16) bool) bool {
+	if c.bitmap == nil {
+		for _, low := range c.array {
+			if !visit(low) {
+				return false
+			}
+		}
+		return true
+	}
+	for i, word := range c.bitmap {
+		for word != 0 {
+			low := uint16(i*64 + bits.TrailingZeros64(word))
+			if !visit(low) {
+				return false
+			}
+			word &= word - 1
+		}
+	}
+	return true
+}
+
+// and returns the values in both c and other.
+func (c *container) and(other *container) container {
+	switch {
+	case c.bitmap != nil && other.bitmap != nil:
+		result := container{bitmap: make([]uint64, bitmapWords)}
+		for i := range result.bitmap {
+			result.bitmap[i] = c.bitmap[i] & other.bitmap[i]
+			result.count += bits.OnesCount64(result.bitmap[i])
+		}
+		if result.count <= ARRAY_MAX {
+			result.toArray()
+		}
+		return result
+	case c.bitmap != nil:
+		return other.and(c)
+	case other.bitmap != nil:
+		result := container{array: make([]uint16, 0, len(c.array))}
+		for _, low := range c.array {
+			if other.contains(low) {
+				result.array = append(result.array, low)
+			}
+		}
+		return result
+	}
+	result := container{array: make([]uint16, 0, min(len(c.array), len(other.array)))}
+	i, j := 0, 0
+	for i < len(c.array) && j < len(other.array) {
+		switch {
+		case c.array[i] < other.array[j]:
+			i++
+		case c.array[i] > other.array[j]:
+			j++
+		default:
+			result.array = append(result.array, c.array[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// A Bitmap is a set of uint32 values. The zero value is an empty set.
+type Bitmap struct {
+	keys       []uint16
+	containers []container
+}
+
+// New creates an empty Bitmap.
+func New() *Bitmap {
+	return &Bitmap{}
+}
+
+// Of creates a Bitmap holding values.
+func Of(values ...uint32) *Bitmap {
+	b := New()
+	for _, value := range values {
+		b.Add(value)
+	}
+	return b
+}
+
+// find returns the index of the container for high, and whether there is
+// one.
+func (b *Bitmap) find(high uint16) (int, bool) {
+	n := len(b.keys)
+	if n > 0 && b.keys[n-1] == high {
+		return n - 1, true
+	}
+	i := sort.Search(n, func(i int) bool { return b.keys[i] >= high })
+	return i, i < n && b.keys[i] == high
+}
+
+// Add adds value to the set.
+func (b *Bitmap) Add(value uint32) {
+	high, low := uint16(value>>16), uint16(value)
+	i, ok := b.find(high)
+	if !ok {
+		b.keys = append(b.keys, 0)
+		copy(b.keys[i+1:], b.keys[i:])
+		b.keys[i] = high
+		b.containers = append(b.containers, container{})
+		copy(b.containers[i+1:], b.containers[i:])
+		b.containers[i] = container{}
+	}
+	b.containers[i].add(low)
+}
+
+// AddRange adds the values from start up to, but not including, end.
+func (b *Bitmap) AddRange(start uint32, end uint32) {
+	for value := start; value < end; value++ {
+		b.Add(value)
+	}
+}
+
+// Contains returns true if value is in the set.
+func (b *Bitmap) Contains(value uint32) bool {
+	i, ok := b.find(uint16(value >> 16))
+	return ok && b.containers[i].contains(uint16(value))
+}
+
+// Cardinality returns the number of values in the set.
+func (b *Bitmap) Cardinality() int {
+	count := 0
+	for i := range b.containers {
+		count += b.containers[i].cardinality()
+	}
+	return count
+}
+
+// IsEmpty returns true if the set has no values.
+func (b *Bitmap) IsEmpty() bool {
+	return len(b.keys) == 0
+}
+
+// And returns a new Bitmap of the values in both b and other.
+func (b *Bitmap) And(other *Bitmap) *Bitmap {
+	result := New()
+	i, j := 0, 0
+	for i < len(b.keys) && j < len(other.keys) {
+		switch {
+		case b.keys[i] < other.keys[j]:
+			i++
+		case b.keys[i] > other.keys[j]:
+			j++
+		default:
+			c := b.containers[i].and(&other.containers[j])
+			if c.cardinality() > 0 {
+				result.keys = append(result.keys, b.keys[i])
+				result.containers = append(result.containers, c)
+			}
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// Each calls visit with the values of the set in ascending order until it
+// returns false.
+func (b *Bitmap) Each(visit func(value uint32) bool) {
+	for i := range b.containers {
+		high := uint32(b.keys[i]) << 16
+		if !b.containers[i].each(func(low uint16) bool { return visit(high | uint32(low)) }) {
+			return
+		}
+	}
+}
+
+// ToArray returns the values of the set in ascending order.
+func (b *Bitmap) ToArray() []uint32 {
+	values := make([]uint32, 0, b.Cardinality())
+	b.Each(func(value uint32) bool {
+		values = append(values, value)
+		return true
+	})
+	return values
+}
@@ -0,0 +1,98 @@
+package roaring
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// randomSet returns n distinct random values below limit, sorted, and a
+// Bitmap of them.
+func randomSet(r *rand.Rand, n int, limit int) ([]uint32, *Bitmap) {
+	seen := make(map[uint32]bool)
+	for len(seen) < n {
+		seen[uint32(r.Intn(limit))] = true
+	}
+	values := make([]uint32, 0, n)
+	b := New()
+	for value := range seen {
+		values = append(values, value)
+		b.Add(value)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return values, b
+}
+
+func TestAddContains(t *testing.T) {
+	b := Of(5, 1, 70000, 5, 3)
+	if b.Cardinality() != 4 {
+		t.Error("Wrong cardinality: ", b.Cardinality())
+	}
+	for _, value := range []uint32{1, 3, 5, 70000} {
+		if !b.Contains(value) {
+			t.Error("Missing value: ", value)
+		}
+	}
+	if b.Contains(2) || b.Contains(70001) {
+		t.Error("Bitmap has a value that wasn't added")
+	}
+	if !reflect.DeepEqual(b.ToArray(), []uint32{1, 3, 5, 70000}) {
+		t.Error("Values are out of order: ", b.ToArray())
+	}
+	if !New().IsEmpty() || b.IsEmpty() {
+		t.Error("Wrong emptiness")
+	}
+}
+
+func TestDenseContainers(t *testing.T) {
+	b := New()
+	b.AddRange(0, ARRAY_MAX*2)
+	if b.containers[0].bitmap == nil {
+		t.Error("A dense container should be a bitmap")
+	}
+	if b.Cardinality() != ARRAY_MAX*2 || !b.Contains(ARRAY_MAX+1) || b.Contains(ARRAY_MAX*2) {
+		t.Error("Wrong values in a dense container: ", b.Cardinality())
+	}
+	sparse := Of(1, 2, ARRAY_MAX*3)
+	and := b.And(sparse)
+	if !reflect.DeepEqual(and.ToArray(), []uint32{1, 2}) || and.containers[0].bitmap != nil {
+		t.Error("Wrong intersection of dense and sparse containers: ", and.ToArray())
+	}
+}
+
+func TestAnd(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, sizes := range [][2]int{{10, 10}, {100, 20000}, {30000, 40000}, {50000, 5}} {
+		left, a := randomSet(r, sizes[0], 200000)
+		right, b := randomSet(r, sizes[1], 200000)
+		inRight := make(map[uint32]bool)
+		for _, value := range right {
+			inRight[value] = true
+		}
+		expected := make([]uint32, 0)
+		for _, value := range left {
+			if inRight[value] {
+				expected = append(expected, value)
+			}
+		}
+		actual := a.And(b).ToArray()
+		if !reflect.DeepEqual(actual, expected) {
+			t.Error("Wrong intersection for sizes: ", sizes, len(actual), len(expected))
+		}
+		if a.Cardinality() != len(left) {
+			t.Error("Wrong cardinality: ", a.Cardinality(), len(left))
+		}
+	}
+}
+
+func TestEachStops(t *testing.T) {
+	visited := 0
+	Of(1, 2, 3, 70000).Each(func(value uint32) bool {
+		visited++
+		return value < 2
+	})
+	if visited != 2 {
+		t.Error("Each should stop when visit returns false: ", visited)
+	}
+}
@@ -0,0 +1,174 @@
+// Package usage tracks where and when the server is queried, so operators
+// can see which areas are popular.
+package usage
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The size, in degrees, of the cells queries are counted in. 0.01 degrees
+// is about half a mile in Portland.
+const DEFAULT_CELL_SIZE = 0.01
+
+// A Cell is a square of the map, CellSize degrees on a side, numbered from
+// 0,0 at the equator and prime meridian.
+type Cell struct {
+	Row int64 `json:"row"`
+	Col int64 `json:"col"`
+}
+
+// A CellCount is the number of queries in a cell.
+type CellCount struct {
+	Cell
+	// Lat and Lng are the center of the cell.
+	Lat   float64 `json:"lat"`
+	Lng   float64 `json:"lng"`
+	Count int64   `json:"count"`
+}
+
+// A Report summarizes a Tracker's queries.
+type Report struct {
+	Total    int64       `json:"total"`
+	CellSize float64     `json:"cell_size"`
+	Cells    []CellCount `json:"cells"`
+	// Hours counts queries by UTC hour of the day, and Weekdays by UTC day
+	// of the week, starting on Sunday.
+	Hours    [24]int64 `json:"hours"`
+	Weekdays [7]int64  `json:"weekdays"`
+}
+
+// A Tracker counts queries by cell and by time. If it has a filename, Save
+// writes the counts there, as a Report of every cell, and NewTracker loads
+// them again. A Tracker is
+// safe to use from several goroutines.
+type Tracker struct {
+	filename string
+	mu       sync.Mutex
+	cellSize float64
+	total    int64
+	cells    map[Cell]int64
+	hours    [24]int64
+	weekdays [7]int64
+}
+
+// NewTracker creates a Tracker with cells cellSize degrees on a side that
+// saves to filename, loading the counts already saved there. If filename is
+// empty, counts are only kept in memory. Saved counts for a different cell
+// size are ignored.
+func NewTracker(cellSize float64, filename string) (*Tracker, error) {
+	tracker := &Tracker{filename: filename, cellSize: cellSize, cells: make(map[Cell]int64)}
+	if filename == "" {
+		return tracker, nil
+	}
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return tracker, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved Report
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	if saved.CellSize != cellSize {
+		return tracker, nil
+	}
+	tracker.total, tracker.hours, tracker.weekdays = saved.Total, saved.Hours, saved.Weekdays
+	for _, count := range saved.Cells {
+		tracker.cells[count.Cell] = count.Count
+	}
+	return tracker, nil
+}
+
+// CellSize returns the size of the tracker's cells in degrees.
+func (tracker *Tracker) CellSize() float64 {
+	return tracker.cellSize
+}
+
+// CellOf returns the cell that a point is in.
+func (tracker *Tracker) CellOf(lat float64, lng float64) Cell {
+	return Cell{int64(math.Floor(lat / tracker.cellSize)), int64(math.Floor(lng / tracker.cellSize))}
+}
+
+// Center returns the point at the center of a cell.
+func (tracker *Tracker) Center(cell Cell) (float64, float64) {
+	return (float64(cell.Row) + 0.5) * tracker.cellSize, (float64(cell.Col) + 0.5) * tracker.cellSize
+}
+
+// Record counts a query at a point at time at.
+func (tracker *Tracker) Record(lat float64, lng float64, at time.Time) {
+	cell := tracker.CellOf(lat, lng)
+	at = at.UTC()
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.total += 1
+	tracker.cells[cell] += 1
+	tracker.hours[at.Hour()] += 1
+	tracker.weekdays[at.Weekday()] += 1
+}
+
+// Top returns the n most queried cells, most queried first. If n is less
+// than 1, every cell is returned.
+func (tracker *Tracker) Top(n int) []CellCount {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	return tracker.top(n)
+}
+
+// top is Top for a caller that holds the lock.
+func (tracker *Tracker) top(n int) []CellCount {
+	counts := make([]CellCount, 0, len(tracker.cells))
+	for cell, count := range tracker.cells {
+		lat, lng := tracker.Center(cell)
+		counts = append(counts, CellCount{Cell: cell, Lat: lat, Lng: lng, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		if counts[i].Row != counts[j].Row {
+			return counts[i].Row < counts[j].Row
+		}
+		return counts[i].Col < counts[j].Col
+	})
+	if n > 0 && n < len(counts) {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// Report returns the tracker's counts, with its n most queried cells.
+func (tracker *Tracker) Report(n int) Report {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	return Report{
+		Total:    tracker.total,
+		CellSize: tracker.cellSize,
+		Cells:    tracker.top(n),
+		Hours:    tracker.hours,
+		Weekdays: tracker.weekdays,
+	}
+}
+
+// Save writes every count to the tracker's file, replacing it only once the
+// new file is complete.
+func (tracker *Tracker) Save() error {
+	if tracker.filename == "" {
+		return nil
+	}
+	data, err := json.Marshal(tracker.Report(0))
+	if err != nil {
+		return err
+	}
+	temp := tracker.filename + ".tmp"
+	if err := os.WriteFile(temp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temp, tracker.filename)
+}
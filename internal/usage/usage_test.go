@@ -0,0 +1,51 @@
+package usage
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	tracker, _ := NewTracker(DEFAULT_CELL_SIZE, "")
+	at := time.Date(2024, 1, 1, 18, 30, 0, 0, time.UTC)
+	tracker.Record(45.5184, -122.6554, at)
+	tracker.Record(45.5189, -122.6551, at)
+	tracker.Record(45.5321, -122.6554, at.Add(time.Hour))
+
+	top := tracker.Top(1)
+	if len(top) != 1 || top[0].Count != 2 {
+		t.Fatal("Wrong top cells: ", top)
+	}
+	if math.Abs(top[0].Lat-45.515) > 1e-9 || math.Abs(top[0].Lng+122.655) > 1e-9 {
+		t.Error("Wrong cell center: ", top[0].Lat, top[0].Lng)
+	}
+	report := tracker.Report(0)
+	if report.Total != 3 || len(report.Cells) != 2 {
+		t.Error("Wrong report: ", report)
+	}
+	if report.Hours[18] != 2 || report.Hours[19] != 1 || report.Weekdays[time.Monday] != 3 {
+		t.Error("Wrong busiest times: ", report.Hours, report.Weekdays)
+	}
+}
+
+func TestTrackerPersists(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "usage.json")
+	tracker, _ := NewTracker(DEFAULT_CELL_SIZE, filename)
+	tracker.Record(45.5184, -122.6554, time.Now())
+	if err := tracker.Save(); err != nil {
+		t.Fatal("Error saving usage: ", err)
+	}
+
+	reopened, err := NewTracker(DEFAULT_CELL_SIZE, filename)
+	if err != nil {
+		t.Fatal("Error loading usage: ", err)
+	}
+	if top := reopened.Top(0); len(top) != 1 || top[0].Count != 1 || reopened.Report(0).Total != 1 {
+		t.Error("Reloaded tracker has the wrong counts: ", top)
+	}
+	if resized, _ := NewTracker(0.1, filename); resized.Report(0).Total != 0 {
+		t.Error("Counts for another cell size should be ignored")
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"log"
+)
+
+var lazyIndexes = flag.Bool("lazy-indexes", false, "serve searches by scanning the data while its indexes build in the background")
+
+// awaitIndexes logs once the indexes of the finder being served have been
+// built in the background, and warms the search cache, which can't be
+// warmed without them.
+func awaitIndexes() {
+	finderLock.RLock()
+	loaded := finder
+	finderLock.RUnlock()
+	if loaded.IndexesReady() {
+		return
+	}
+	took := loaded.WaitForIndexes()
+	log.Printf("Built the indexes in the background in %v", took)
+	warmCache()
+}
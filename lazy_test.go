@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+	"github.com/abrookins/radar/crimes/sample"
+)
+
+func TestSearchWhileIndexesBuild(t *testing.T) {
+	defer func(saved radar.CrimeFinder) { finder = saved }(finder)
+	finder = sample.NewFinderWithOptions(radar.LoadOptions{LazyIndexes: true})
+	if resp := get(t, "/crimes/near/45.5184/-122.6554"); resp.Code != 200 {
+		t.Error("Wrong status code while indexes build: ", resp.Code)
+	}
+	awaitIndexes()
+	if !finder.IndexesReady() {
+		t.Error("Indexes should be ready once awaited")
+	}
+	if resp := get(t, "/crimes/near/45.5184/-122.6554"); resp.Code != 200 {
+		t.Error("Wrong status code once indexes are built: ", resp.Code)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// The default limits on requests. A city is rarely more than a few hundred
+// square miles.
+const (
+	DEFAULT_MAX_BBOX_AREA = 2500
+	DEFAULT_MAX_RESULTS   = 10000
+)
+
+var maxRadius = flag.Float64("max-radius", radar.MAX_SEARCH_RADIUS, "largest radius, in miles, a request may ask for")
+var maxBoundsArea = flag.Float64("max-bbox-area", DEFAULT_MAX_BBOX_AREA, "largest bounding box, in square miles, a request may ask for; 0 for no limit")
+var maxResults = flag.Int("max-results", DEFAULT_MAX_RESULTS, "most crimes a search may return; 0 for no limit")
+
+// A limitPolicy holds the limits on how much of the data one request may
+// ask for, so that a single request can't tie up the server. A zero limit
+// isn't enforced.
+type limitPolicy struct {
+	MaxRadiusMiles       float64
+	MaxBoundsSquareMiles float64
+	MaxResults           int
+}
+
+// limits is the policy the flags set.
+var limits = limitPolicy{radar.MAX_SEARCH_RADIUS, DEFAULT_MAX_BBOX_AREA, DEFAULT_MAX_RESULTS}
+
+// A limitError describes a request that's over one of the limits, and how
+// to ask for less.
+type limitError struct {
+	Limit    string
+	Value    float64
+	Max      float64
+	Guidance string
+}
+
+func (e *limitError) Error() string {
+	return fmt.Sprintf("%v of %v is over the limit of %v. %v", e.Limit, e.Value, e.Max, e.Guidance)
+}
+
+// newLimitPolicy returns the policy the flags ask for. Searches never reach
+// further than radar.MAX_SEARCH_RADIUS, so the radius limit can only lower
+// that.
+func newLimitPolicy() limitPolicy {
+	if *maxRadius <= 0 || *maxRadius > radar.MAX_SEARCH_RADIUS {
+		log.Fatal("The maximum radius must be more than 0 and at most ", radar.MAX_SEARCH_RADIUS, " miles")
+	}
+	return limitPolicy{
+		MaxRadiusMiles:       *maxRadius,
+		MaxBoundsSquareMiles: *maxBoundsArea,
+		MaxResults:           *maxResults,
+	}
+}
+
+// checkRadius returns an error if a request for radius miles is over the
+// policy's limit.
+func (policy limitPolicy) checkRadius(radius float64) error {
+	if policy.MaxRadiusMiles > 0 && radius > policy.MaxRadiusMiles {
+		return &limitError{"radius in miles", radius, policy.MaxRadiusMiles,
+			fmt.Sprintf("Ask for a radius of at most %v miles.", policy.MaxRadiusMiles)}
+	}
+	return nil
+}
+
+// checkBounds returns an error if a request for bounds covers more area
+// than the policy allows.
+func (policy limitPolicy) checkBounds(bounds radar.Bounds) error {
+	area := bounds.SquareMiles()
+	if policy.MaxBoundsSquareMiles > 0 && area > policy.MaxBoundsSquareMiles {
+		return &limitError{"bbox area in square miles", area, policy.MaxBoundsSquareMiles,
+			"Zoom in, or split the box into smaller ones."}
+	}
+	return nil
+}
+
+// checkResults returns an error if a search that found count crimes found
+// more than the policy allows.
+func (policy limitPolicy) checkResults(count int) error {
+	if policy.MaxResults > 0 && count > policy.MaxResults {
+		return &limitError{"result count", float64(count), float64(policy.MaxResults),
+			"Narrow the search with category, weapon, domestic or arrest."}
+	}
+	return nil
+}
+
+// writeLimitError responds to a request that's over a limit with 422
+// Unprocessable Entity and what to do instead.
+func writeLimitError(w http.ResponseWriter, err error) {
+	http.Error(w, http.StatusText(422)+": "+err.Error(), 422)
+}
+
+// writeSearchError responds to a request for a search whose options
+// couldn't be parsed: with 422 and what to do instead if they're over a
+// limit, and 400 Bad Request if they're invalid.
+func writeSearchError(w http.ResponseWriter, err error) {
+	var overLimit *limitError
+	if errors.As(err, &overLimit) {
+		writeLimitError(w, err)
+		return
+	}
+	http.Error(w, http.StatusText(400), 400)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+func TestLimitPolicy(t *testing.T) {
+	policy := limitPolicy{MaxRadiusMiles: 0.25, MaxBoundsSquareMiles: 10, MaxResults: 100}
+	if policy.checkRadius(0.25) != nil || policy.checkRadius(0.3) == nil {
+		t.Error("Wrong radius limit")
+	}
+	small := radar.Bounds{Min: radar.Point{Lat: 45.5, Lng: -122.7}, Max: radar.Point{Lat: 45.52, Lng: -122.68}}
+	large := radar.Bounds{Min: radar.Point{Lat: 45, Lng: -123}, Max: radar.Point{Lat: 46, Lng: -122}}
+	if policy.checkBounds(small) != nil || policy.checkBounds(large) == nil {
+		t.Error("Wrong bounds limit")
+	}
+	if policy.checkResults(100) != nil || policy.checkResults(101) == nil {
+		t.Error("Wrong result limit")
+	}
+	if err := (limitPolicy{}).checkResults(1000000); err != nil {
+		t.Error("A zero limit shouldn't be enforced: ", err)
+	}
+}
+
+func TestLimitResponses(t *testing.T) {
+	defer func(saved limitPolicy) { limits = saved }(limits)
+	limits = limitPolicy{MaxRadiusMiles: WIDGET_RADIUS, MaxBoundsSquareMiles: 10, MaxResults: 5}
+
+	resp := get(t, "/crimes/near/45.53435699129174/-122.66469510763777")
+	if resp.Code != 422 || !strings.Contains(resp.Body.String(), "Narrow the search") {
+		t.Error("Wrong response for too many results: ", resp.Code, resp.Body.String())
+	}
+	resp = get(t, "/clusters?bbox=45,-123,46,-122&zoom=10")
+	if resp.Code != 422 || !strings.Contains(resp.Body.String(), "over the limit of 10") {
+		t.Error("Wrong response for a bounding box over the limit: ", resp.Code, resp.Body.String())
+	}
+	if resp := get(t, "/clusters?bbox=45.5,-122.7,45.52,-122.68&zoom=10"); resp.Code != 200 {
+		t.Error("Wrong status code for a small bounding box: ", resp.Code)
+	}
+	if resp := get(t, "/crimes/near/45.5184/-122.6554?radius=0.6&limit=1"); resp.Code != 422 {
+		t.Error("Wrong status code for a radius over the limit: ", resp.Code)
+	}
+	limits.MaxRadiusMiles = 2
+	if resp := get(t, "/crimes/near/45.5184/-122.6554?radius=0.6&limit=1"); resp.Code != 200 {
+		t.Error("A radius under a raised limit should be searched: ", resp.Code)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// locationHandler responds with the history of the location whose key,
+// its latitude and longitude, is in the path, like
+// /locations/45.5231,-122.6765, so that a map can fetch a location's crimes
+// when its marker is clicked.
+func locationHandler(w http.ResponseWriter, r *http.Request) {
+	key, err := radar.ParseLocationKey(mux.Vars(r)["key"])
+	if err != nil {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	history, ok := finderFor(r).LocationHistory(key)
+	if !ok {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+	history.BaseURL = *baseURL
+	resp, err := history.ToJson()
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	count := len(history.Crimes)
+	writeJson(w, r, resp, responseMeta{Query: map[string]interface{}{"key": key.String()}, Count: &count})
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestLocation(t *testing.T) {
+	location := finder.Locations()[0]
+	resp := get(t, fmt.Sprintf("/locations/%v,%v", location.Point.Lat, location.Point.Lng))
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	var history struct {
+		Total  int
+		Years  []struct{ Year, Count int }
+		Crimes []struct{ Id int64 }
+	}
+	json.Unmarshal(data(t, resp), &history)
+	if history.Total != len(location.Crimes) || len(history.Crimes) != history.Total || len(history.Years) != 1 || history.Years[0].Count != history.Total {
+		t.Error("Wrong history: ", resp.Body.String())
+	}
+
+	if resp := get(t, "/locations/45.1,-122.1"); resp.Code != 404 {
+		t.Error("Wrong status code where there's no location: ", resp.Code)
+	}
+	if resp := get(t, "/locations/nowhere"); resp.Code != 400 {
+		t.Error("Wrong status code for an invalid key: ", resp.Code)
+	}
+}
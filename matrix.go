@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// The limits on the points a request may compare, so that comparing them
+// can't tie up the server. Every pair of points is scored, so the work
+// grows with the square of their number.
+const (
+	MAX_MATRIX_POINTS = 25
+	MAX_MATRIX_MILES  = 10
+)
+
+// A matrixRequest is the body of a request for a distance matrix.
+type matrixRequest struct {
+	Points []radar.Point `json:"points"`
+}
+
+// checkMatrix returns an error if points are too many or too far apart to
+// compare.
+func checkMatrix(points []radar.Point) error {
+	if len(points) > MAX_MATRIX_POINTS {
+		return &limitError{"number of points", float64(len(points)), MAX_MATRIX_POINTS,
+			"Compare fewer points at a time."}
+	}
+	for i := range points {
+		for j := i + 1; j < len(points); j++ {
+			if miles := points[i].GreatCircleDistance(&points[j]); miles > MAX_MATRIX_MILES {
+				return &limitError{"miles between points", miles, MAX_MATRIX_MILES,
+					"Compare points that are closer together."}
+			}
+		}
+	}
+	return nil
+}
+
+// distanceMatrixHandler compares every pair of a list of points by distance
+// and by the risk of crime between them.
+func distanceMatrixHandler(w http.ResponseWriter, r *http.Request) {
+	var request matrixRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request.Points) < 2 {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	for _, point := range request.Points {
+		if _, err := radar.NewPoint(radar.Latitude(point.Lat), radar.Longitude(point.Lng)); err != nil {
+			http.Error(w, http.StatusText(400), 400)
+			return
+		}
+	}
+	if err := checkMatrix(request.Points); err != nil {
+		writeLimitError(w, err)
+		return
+	}
+	matrix, err := riskModelFor(r).DistanceMatrix(request.Points)
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	resp, err := matrix.ToJson()
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	count := len(matrix.Points)
+	writeJson(w, r, resp, responseMeta{Count: &count})
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDistanceMatrixHandler(t *testing.T) {
+	resp := request(t, "POST", "/distance-matrix", `{"points":[{"lat":45.5231,"lng":-122.6765},{"lat":45.5262,"lng":-122.668},{"lat":45.535,"lng":-122.665}]}`)
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	var matrix struct {
+		Risk          []float64
+		Miles         [][]float64
+		WeightedMiles [][]float64 `json:"weighted_miles"`
+	}
+	json.Unmarshal(data(t, resp), &matrix)
+	if len(matrix.Risk) != 3 || len(matrix.Miles) != 3 || len(matrix.WeightedMiles[2]) != 3 || matrix.Miles[0][1] == 0 {
+		t.Error("Wrong matrix: ", resp.Body.String())
+	}
+
+	for _, body := range []string{`{"points":[{"lat":45.5231,"lng":-122.6765}]}`, `{"points":[{"lat":95,"lng":0},{"lat":45.5,"lng":-122.6}]}`, `points`} {
+		if resp := request(t, "POST", "/distance-matrix", body); resp.Code != 400 {
+			t.Error("Wrong status code for ", body, ": ", resp.Code)
+		}
+	}
+	many := make([]string, 0)
+	for i := 0; i <= MAX_MATRIX_POINTS; i++ {
+		many = append(many, fmt.Sprintf(`{"lat":45.5,"lng":%v}`, -122.6+float64(i)*0.001))
+	}
+	if resp := request(t, "POST", "/distance-matrix", `{"points":[`+strings.Join(many, ",")+`]}`); resp.Code != 422 {
+		t.Error("Too many points should be refused: ", resp.Code)
+	}
+	if resp := request(t, "POST", "/distance-matrix", `{"points":[{"lat":45.5,"lng":-122.6},{"lat":45.5,"lng":-121.6}]}`); resp.Code != 422 {
+		t.Error("Points too far apart should be refused: ", resp.Code)
+	}
+}
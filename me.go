@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/abrookins/radar/alerts"
+)
+
+// RECENT_KEY_REQUESTS is the number of its latest requests /me shows an
+// API key.
+const RECENT_KEY_REQUESTS = 20
+
+var keyRate = flag.Int("key-rate", 0, "requests an hour each API key may make, in bursts of up to that many; 0 for no limit")
+
+// keyClients limits how many requests each API key may make. It's nil if
+// keys aren't limited.
+var keyClients *alerts.RateLimiter
+
+// newKeyLimiter returns the limiter of API keys the flags ask for.
+func newKeyLimiter() *alerts.RateLimiter {
+	if *keyRate < 0 {
+		log.Fatal("The rate of API keys must be 0 or more")
+	}
+	if *keyRate == 0 {
+		return nil
+	}
+	return alerts.NewRateLimiter(*keyRate)
+}
+
+// A keyRequest is a request made with an API key, as /me shows it.
+type keyRequest struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Status int       `json:"status"`
+}
+
+// keyRequests holds the latest requests of each API key, newest last. It's
+// only kept in memory.
+var keyRequests = struct {
+	sync.Mutex
+	byKey map[string][]keyRequest
+}{byKey: make(map[string][]keyRequest)}
+
+// recordKeyRequest adds a request to the latest requests of the key with id.
+func recordKeyRequest(id string, request keyRequest) {
+	keyRequests.Lock()
+	defer keyRequests.Unlock()
+	recent := append(keyRequests.byKey[id], request)
+	if len(recent) > RECENT_KEY_REQUESTS {
+		recent = recent[len(recent)-RECENT_KEY_REQUESTS:]
+	}
+	keyRequests.byKey[id] = recent
+}
+
+// recentKeyRequests returns the latest requests of the key with id, newest
+// first.
+func recentKeyRequests(id string) []keyRequest {
+	keyRequests.Lock()
+	defer keyRequests.Unlock()
+	recent := keyRequests.byKey[id]
+	newest := make([]keyRequest, 0, len(recent))
+	for i := len(recent) - 1; i >= 0; i-- {
+		newest = append(newest, recent[i])
+	}
+	return newest
+}
+
+// statusWriter remembers the status of the response it writes.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// limitKeys is middleware for requests with an X-API-Key header. A request
+// with a key that isn't one of the store's gets 401 Unauthorized. The others
+// are rate limited by key, with X-RateLimit-Limit and X-RateLimit-Remaining
+// headers, and recorded for /me, which isn't limited so that a key that's
+// over its limit can find out why.
+func limitKeys(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("X-API-Key")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key, ok := geofences.FindKey(header)
+		if !ok {
+			http.Error(w, http.StatusText(401), 401)
+			return
+		}
+		r = withAPIKey(r, key)
+		if r.URL.Path == "/me" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		recorder := &statusWriter{ResponseWriter: w}
+		defer func() {
+			recordKeyRequest(key.Id, keyRequest{time.Now().UTC(), r.Method, r.URL.Path, recorder.status})
+		}()
+		if keyClients != nil {
+			allowed := keyClients.Allow(key.Id)
+			w.Header().Set("X-RateLimit-Limit", fmt.Sprint(keyClients.Burst))
+			w.Header().Set("X-RateLimit-Remaining", fmt.Sprint(keyClients.Remaining(key.Id)))
+			if !allowed {
+				w.Header().Set("Retry-After", fmt.Sprint(math.Ceil(keyClients.Interval.Seconds())))
+				http.Error(recorder, http.StatusText(429), 429)
+				return
+			}
+		}
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// A keyRateLimit is the rate limit of an API key, as /me shows it.
+type keyRateLimit struct {
+	PerHour   int `json:"per_hour"`
+	Remaining int `json:"remaining"`
+}
+
+// meHandler shows the API key a request was made with: its rate limit and
+// how many requests it has left, its latest requests and the geofences
+// created with it.
+func meHandler(w http.ResponseWriter, r *http.Request) {
+	key, ok := r.Context().Value(apiKeyKey).(alerts.APIKey)
+	if !ok {
+		http.Error(w, http.StatusText(401), 401)
+		return
+	}
+	var limit *keyRateLimit
+	if keyClients != nil {
+		limit = &keyRateLimit{keyClients.Burst, keyClients.Remaining(key.Id)}
+	}
+	owned := make([]alerts.Geofence, 0)
+	for _, geofence := range geofences.List() {
+		if geofence.Owner == key.Id {
+			owned = append(owned, geofence)
+		}
+	}
+	type keyJson struct {
+		Id      string    `json:"id"`
+		Name    string    `json:"name"`
+		Created time.Time `json:"created"`
+	}
+	writeValue(w, r, 200, struct {
+		Key       keyJson           `json:"key"`
+		RateLimit *keyRateLimit     `json:"rate_limit"`
+		Recent    []keyRequest      `json:"recent"`
+		Geofences []alerts.Geofence `json:"geofences"`
+	}{keyJson{key.Id, key.Name, key.Created}, limit, recentKeyRequests(key.Id), owned}, responseMeta{})
+}
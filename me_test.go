@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abrookins/radar/alerts"
+)
+
+// keyed makes a request to the server's router with an API key.
+func keyed(t *testing.T, method string, url string, key string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("X-API-Key", key)
+	resp := httptest.NewRecorder()
+	newRouter().ServeHTTP(resp, req)
+	return resp
+}
+
+func TestMe(t *testing.T) {
+	defer func() {
+		geofences, _ = alerts.NewStore("")
+		keyClients = nil
+	}()
+	geofences, _ = alerts.NewStore("")
+	key, _ := geofences.CreateKey("consumer")
+	other, _ := geofences.CreateKey("other")
+	keyClients = alerts.NewRateLimiter(3)
+
+	if resp := get(t, "/me"); resp.Code != 401 {
+		t.Error("/me should need a key: ", resp.Code)
+	}
+	if resp := keyed(t, "GET", "/me", "wrong", ""); resp.Code != 401 {
+		t.Error("A wrong key should get 401: ", resp.Code)
+	}
+	geofence := `{"center": {"lat": 45.5184, "lng": -122.6554}, "radius_miles": 0.5, "target": {"webhook": "http://example.com/hook"}, "owner": "someone"}`
+	if resp := keyed(t, "POST", "/geofences", key.Key, geofence); resp.Code != 201 {
+		t.Fatal("Wrong status code creating a geofence: ", resp.Code)
+	}
+	keyed(t, "POST", "/geofences", other.Key, geofence)
+	resp := keyed(t, "GET", "/meta/bounds", key.Key, "")
+	if resp.Code != 200 || resp.Header().Get("X-RateLimit-Limit") != "3" || resp.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Error("Wrong rate limit headers: ", resp.Code, resp.Header())
+	}
+	if resp := keyed(t, "GET", "/meta/bounds", key.Key, ""); resp.Code != 200 {
+		t.Error("Wrong status code within the limit: ", resp.Code)
+	}
+	if resp := keyed(t, "GET", "/meta/bounds", key.Key, ""); resp.Code != 429 || resp.Header().Get("Retry-After") != "1200" {
+		t.Error("A key over its limit should get 429: ", resp.Code, resp.Header())
+	}
+
+	resp = keyed(t, "GET", "/me", key.Key, "")
+	if resp.Code != 200 {
+		t.Fatal("/me should answer a key over its limit: ", resp.Code)
+	}
+	var me struct {
+		Key struct {
+			Id, Name, Hash string
+		}
+		RateLimit *keyRateLimit `json:"rate_limit"`
+		Recent    []keyRequest
+		Geofences []alerts.Geofence
+	}
+	if err := json.Unmarshal(data(t, resp), &me); err != nil {
+		t.Fatal("Response was not valid JSON: ", err)
+	}
+	if me.Key.Id != key.Id || me.Key.Name != "consumer" || strings.Contains(resp.Body.String(), "hash") {
+		t.Error("Wrong key: ", me.Key)
+	}
+	if me.RateLimit == nil || me.RateLimit.PerHour != 3 || me.RateLimit.Remaining != 0 {
+		t.Error("Wrong rate limit: ", me.RateLimit)
+	}
+	if len(me.Recent) != 4 || me.Recent[0].Status != 429 || me.Recent[3].Method != "POST" || me.Recent[3].Path != "/geofences" {
+		t.Error("Wrong recent requests: ", me.Recent)
+	}
+	if len(me.Geofences) != 1 || me.Geofences[0].Owner != key.Id {
+		t.Error("/me should list the key's own geofences: ", me.Geofences)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+var errMergeUsage = errors.New("usage: radar [flags] merge a.csv b.geojson -o merged.radar [-policy newer|first|both]")
+
+// mergeOutputFormats maps the extensions of merge output files to export
+// formats. Other extensions get a snapshot.
+var mergeOutputFormats = map[string]string{
+	".csv":     radar.CsvFormat,
+	".geojson": radar.GeoJSONFormat,
+	".json":    radar.GeoJSONFormat,
+}
+
+// runMerge merges the two data files named in args, which may be CSV or
+// GeoJSON, and writes the result to the file named by the -o flag in args:
+// a CSV or GeoJSON export if its extension is .csv or .geojson, and
+// otherwise a snapshot that the server can load with -snapshot. Flags may
+// come before or after the data files. A summary of the merge is written
+// to w.
+func runMerge(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("merge", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	output := flags.String("o", "", "file to write the merged data to")
+	policy := flags.String("policy", radar.PreferNewer, "which crime to keep when both files have its id: newer, first or both")
+	filenames := make([]string, 0)
+	for {
+		if err := flags.Parse(args); err != nil {
+			return fmt.Errorf("%v\n%v", err, errMergeUsage)
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		filenames = append(filenames, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if len(filenames) != 2 || *output == "" {
+		return errMergeUsage
+	}
+
+	options := loadOptions()
+	// The merged data is published later, and jitter is applied then.
+	options.JitterMiles = 0
+	// Each file's bad rows are quarantined next to it.
+	options.BadRows.Quarantine = ""
+	first, err := loadFile(filenames[0], options)
+	if err != nil {
+		return err
+	}
+	second, err := loadFile(filenames[1], options)
+	if err != nil {
+		return err
+	}
+	merged, report, err := radar.Merge(&first, &second, *policy)
+	if err != nil {
+		return err
+	}
+
+	if format, ok := mergeOutputFormats[strings.ToLower(filepath.Ext(*output))]; ok {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		if err := merged.All().WriteExport(f, format); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	} else if err := saveSnapshot(&merged, *output); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Merged %v crimes from %v and %v crimes from %v into %v crimes in %v\n",
+		first.Report.Crimes, filenames[0], second.Report.Crimes, filenames[1], merged.Report.Crimes, *output)
+	fmt.Fprintf(w, "%v crimes were in both, and %v were taken from %v\n", report.Conflicts, report.Replaced, filenames[1])
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+func TestRunMerge(t *testing.T) {
+	dir := t.TempDir()
+	second := filepath.Join(dir, "second.geojson")
+	data := `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-122.66469510763777, 45.53435699129174]},
+		 "properties": {"id": 13807517, "date": "12/02/2011", "time": "01:00:00", "type": "Disorderly Conduct"}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-122.661, 45.531]},
+		 "properties": {"id": 99000001, "date": "12/31/2011", "time": "23:00:00", "type": "Burglary"}}]}`
+	os.WriteFile(second, []byte(data), 0644)
+	output := filepath.Join(dir, "merged.radar")
+
+	out := new(bytes.Buffer)
+	if err := runMerge([]string{"crimes/sample/crimes.csv", second, "-o", output, "-policy", "first"}, out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "into 2322 crimes") || !strings.Contains(out.String(), "1 crimes were in both, and 0") {
+		t.Error("Wrong summary: ", out.String())
+	}
+	merged, err := radar.LoadSnapshot(output)
+	if err != nil {
+		t.Fatal("Error loading the merged snapshot: ", err)
+	}
+	if crime, _ := merged.FindByID(13807517); crime == nil || crime.Type != "Liquor Laws" {
+		t.Error("The first file's crime should be kept: ", crime)
+	}
+	if crime, _ := merged.FindByID(99000001); crime == nil {
+		t.Error("The second file's new crime should be merged")
+	}
+
+	csvOutput := filepath.Join(dir, "merged.csv")
+	if err := runMerge([]string{"-o", csvOutput, "crimes/sample/crimes.csv", second}, out); err != nil {
+		t.Fatal(err)
+	}
+	exported, err := radar.NewCrimeFinder(csvOutput)
+	if err != nil || exported.Report.Crimes != 2322 {
+		t.Fatal("Wrong CSV output: ", err, exported.Report.Crimes)
+	}
+	if crime, _ := exported.FindByID(13807517); crime == nil || crime.Type != "Disorderly Conduct" {
+		t.Error("The newer crime should be kept by default: ", crime)
+	}
+
+	if err := runMerge([]string{"crimes/sample/crimes.csv", second}, out); err != errMergeUsage {
+		t.Error("Wrong error without an output file: ", err)
+	}
+	if err := runMerge([]string{"crimes/sample/crimes.csv", second, "-o", output, "-policy", "last"}, out); err == nil {
+		t.Error("An unknown policy should be an error")
+	}
+}
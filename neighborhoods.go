@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+var neighborhoodsFilename = flag.String("neighborhoods", "", "GeoJSON file of neighborhood boundaries to count crimes in at /neighborhoods")
+
+var populationsFilename = flag.String("populations", "", "CSV file of the population of each neighborhood, with name and population columns, for per-capita rates at /neighborhoods and in the open data")
+
+// neighborhoods are the boundaries that /neighborhoods counts crimes in.
+var neighborhoods []radar.Neighborhood
+
+// populations are the numbers of residents of the neighborhoods, or nil if
+// they aren't known.
+var populations radar.Populations
+
+// loadNeighborhoods returns the neighborhoods in the -neighborhoods file, or
+// none if there isn't one.
+func loadNeighborhoods() []radar.Neighborhood {
+	if *neighborhoodsFilename == "" {
+		return nil
+	}
+	loaded, err := radar.LoadNeighborhoods(*neighborhoodsFilename)
+	if err != nil {
+		log.Fatal("Could not load neighborhoods. ", err)
+	}
+	return loaded
+}
+
+// loadPopulations returns the populations in the -populations file, or
+// none if there isn't one.
+func loadPopulations() radar.Populations {
+	if *populationsFilename == "" {
+		return nil
+	}
+	loaded, err := radar.LoadPopulations(*populationsFilename)
+	if err != nil {
+		log.Fatal("Could not load populations. ", err)
+	}
+	return loaded
+}
+
+// neighborhoodsHandler returns every neighborhood's boundary and the number
+// of crimes inside it, and their rate per capita if its population is
+// known, as a GeoJSON FeatureCollection.
+func neighborhoodsHandler(w http.ResponseWriter, r *http.Request) {
+	resp, err := finderFor(r).CountNeighborhoods(neighborhoods).WithPopulations(populations).ToJson()
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	writeJson(w, r, resp, responseMeta{})
+}
+
+// neighborhoodHandler returns the boundary of the neighborhood named in the
+// path, ignoring case, and the number of crimes inside it, as a GeoJSON
+// Feature like those of neighborhoodsHandler.
+func neighborhoodHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	for _, neighborhood := range neighborhoods {
+		if !strings.EqualFold(neighborhood.Name, name) {
+			continue
+		}
+		count := finderFor(r).CountNeighborhoods([]radar.Neighborhood{neighborhood}).WithPopulations(populations)[0]
+		resp, err := count.ToJson()
+		if err != nil {
+			http.Error(w, http.StatusText(500), 500)
+			log.Println(err)
+			return
+		}
+		writeJson(w, r, resp, responseMeta{Query: map[string]interface{}{"name": neighborhood.Name}, Count: &count.Crimes})
+		return
+	}
+	http.Error(w, http.StatusText(404), 404)
+}
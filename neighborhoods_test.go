@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+func TestNeighborhoods(t *testing.T) {
+	defer func() {
+		neighborhoods = nil
+		populations = nil
+	}()
+	if resp := get(t, "/neighborhoods"); resp.Code != 404 {
+		t.Error("Neighborhoods should be off without a boundary file: ", resp.Code)
+	}
+	geojson := `{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{"name":"Lloyd"},
+		 "geometry":{"type":"Polygon","coordinates":[[[-122.67,45.53],[-122.67,45.54],[-122.66,45.54],[-122.66,45.53],[-122.67,45.53]]]}}
+	]}`
+	filename := filepath.Join(t.TempDir(), "neighborhoods.geojson")
+	os.WriteFile(filename, []byte(geojson), 0644)
+	var err error
+	if neighborhoods, err = radar.LoadNeighborhoods(filename); err != nil {
+		t.Fatal("Could not load neighborhoods: ", err)
+	}
+
+	var collection struct {
+		Type     string
+		Features []struct{ Properties struct{ Name string } }
+	}
+	resp := get(t, "/neighborhoods?envelope=false")
+	if resp.Code != 200 || resp.Header().Get("Cache-Control") != "public, max-age=300" {
+		t.Fatal("Wrong response: ", resp.Code, resp.Header())
+	}
+	json.Unmarshal(resp.Body.Bytes(), &collection)
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 1 || collection.Features[0].Properties.Name != "Lloyd" {
+		t.Error("Wrong neighborhoods: ", resp.Body.String())
+	}
+
+	resp = get(t, "/neighborhoods/lloyd")
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	var envelope envelopeResponse
+	json.Unmarshal(resp.Body.Bytes(), &envelope)
+	var feature struct {
+		Type       string
+		Properties struct{ Crimes int }
+	}
+	json.Unmarshal(envelope.Data, &feature)
+	if feature.Type != "Feature" || feature.Properties.Crimes == 0 || envelope.Meta.Count == nil || *envelope.Meta.Count != feature.Properties.Crimes {
+		t.Error("Wrong neighborhood: ", resp.Body.String())
+	}
+	if resp := get(t, "/neighborhoods/nowhere"); resp.Code != 404 {
+		t.Error("Unknown neighborhoods should be 404: ", resp.Code)
+	}
+
+	populations = radar.Populations{"lloyd": feature.Properties.Crimes * 10}
+	var rated struct {
+		Properties struct {
+			Population    int
+			CrimesPer1000 float64 `json:"crimes_per_1000"`
+		}
+	}
+	json.Unmarshal(data(t, get(t, "/neighborhoods/Lloyd")), &rated)
+	if rated.Properties.Population != feature.Properties.Crimes*10 || rated.Properties.CrimesPer1000 != 100 {
+		t.Error("Wrong per-capita rate: ", rated.Properties)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path"
+
+	"github.com/abrookins/radar/internal/objectstore"
+)
+
+// objects gets and puts the data files and snapshots named by s3:// and
+// gs:// URLs, with credentials from the environment.
+var objects = &objectstore.Client{}
+
+// fetchObject downloads the object at url to a temporary file with the
+// same extension, so that it's read like a local file, and returns the
+// file's name. The caller removes the file.
+func fetchObject(url string) (string, error) {
+	r, err := objects.Open(url)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	f, err := os.CreateTemp("", "radar-*"+path.Ext(url))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// localSource returns the name of the file that files written beside the
+// data file named name, like its quarantine, are named after. For an
+// object URL or a Socrata dataset, that's its base name in the working
+// directory.
+func localSource(name string) string {
+	if objectstore.IsURL(name) || isSocrataURL(name) {
+		return path.Base(name)
+	}
+	return name
+}
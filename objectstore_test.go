@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+	"github.com/abrookins/radar/crimes/sample"
+)
+
+// bucketServer serves objects put into it by path, like S3 with path-style
+// addressing.
+func bucketServer() *httptest.Server {
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == "PUT" {
+			objects[r.URL.Path], _ = io.ReadAll(r.Body)
+			return
+		}
+		data, ok := objects[r.URL.Path]
+		if !ok {
+			http.Error(w, http.StatusText(404), 404)
+			return
+		}
+		w.Write(data)
+	}))
+}
+
+func TestObjectStorage(t *testing.T) {
+	server := bucketServer()
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_S3", server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+
+	data := sample.CSV
+	if err := objects.Put("s3://radar/pdx/test.csv", data); err != nil {
+		t.Fatal("Error putting data file: ", err)
+	}
+	loaded, err := loadFile("s3://radar/pdx/test.csv", radar.LoadOptions{})
+	if err != nil {
+		t.Fatal("Error loading data file from object storage: ", err)
+	}
+	if loaded.Fingerprint() != finder.Fingerprint() {
+		t.Error("Data file from object storage has different data: ", loaded.Report.Crimes)
+	}
+	if _, err := loadFile("s3://radar/missing.csv", radar.LoadOptions{}); err == nil {
+		t.Error("Missing data file should not load")
+	}
+
+	if err := saveSnapshot(&loaded, "s3://radar/pdx.snapshot"); err != nil {
+		t.Fatal("Error saving snapshot to object storage: ", err)
+	}
+	snapshot, err := loadSnapshot("s3://radar/pdx.snapshot", radar.LoadOptions{})
+	if err != nil || snapshot.Fingerprint() != finder.Fingerprint() {
+		t.Error("Snapshot from object storage has different data: ", err, snapshot.Report.Crimes)
+	}
+}
+
+func TestLocalSource(t *testing.T) {
+	if source := localSource("gs://radar/pdx/test.csv"); source != "test.csv" {
+		t.Error("Wrong local source for an object: ", source)
+	}
+	if source := localSource("data/test.csv"); source != "data/test.csv" {
+		t.Error("Wrong local source for a file: ", source)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abrookins/radar/crimes"
+	"github.com/abrookins/radar/internal/objectstore"
+)
+
+// The names of the open-data products, in the -open-data directory.
+const (
+	OPEN_DATA_COUNTS   = "neighborhood-months.csv"
+	OPEN_DATA_HOTSPOTS = "hotspots.geojson"
+)
+
+var hotspotHalfLife = flag.Duration("hotspot-half-life", 0, "age, relative to the latest crime, at which a crime counts half as much toward the published hotspots; 0 to count every crime fully")
+var openDataDir = flag.String("open-data", "", "directory, or s3:// or gs:// prefix, to publish monthly neighborhood counts and hotspots to whenever the data is loaded or refreshed")
+
+// openDataRequests asks publishOpenData to publish the products again. It
+// holds one request at most, so that refreshes made while the products are
+// being published are covered by one more publication. It's nil unless the
+// server publishes them.
+var openDataRequests chan struct{}
+
+// newOpenDataRequests returns the channel of requests to publish the
+// open-data products, or nil if the flags don't ask for them.
+func newOpenDataRequests() chan struct{} {
+	if *openDataDir == "" {
+		return nil
+	}
+	return make(chan struct{}, 1)
+}
+
+// requestOpenData asks for the open-data products to be published from the
+// data being served, if the server publishes them.
+func requestOpenData() {
+	select {
+	case openDataRequests <- struct{}{}:
+	default:
+	}
+}
+
+// publishOpenData publishes the open-data products whenever they're asked
+// for.
+func publishOpenData() {
+	for range openDataRequests {
+		if err := writeOpenData(*openDataDir); err != nil {
+			log.Println("Could not publish the open data. ", err)
+		}
+	}
+}
+
+// writeOpenData writes the open-data products of the data being served to
+// dir, a directory or object URL prefix. The products are made under the
+// finder's lock, but written without it.
+func writeOpenData(dir string) error {
+	var counts, spots bytes.Buffer
+	finderLock.RLock()
+	version := currentDatasetVersion()
+	err := finder.CountNeighborhoodMonths(neighborhoods).WithPopulations(populations).WriteCsv(&counts)
+	if err == nil {
+		var found radar.CellCounts
+		if *hotspotHalfLife > 0 {
+			found = finder.FindRecentHotspots(*hotspotHalfLife)
+		} else {
+			found = finder.FindHotspots()
+		}
+		var encoded []byte
+		encoded, err = found.ToJson()
+		spots.Write(encoded)
+	}
+	finderLock.RUnlock()
+	if err != nil {
+		return err
+	}
+	for name, data := range map[string][]byte{OPEN_DATA_COUNTS: counts.Bytes(), OPEN_DATA_HOTSPOTS: spots.Bytes()} {
+		if err := putOpenData(dir, name, data); err != nil {
+			return err
+		}
+	}
+	log.Println("Published the open data of version", version, "to", dir)
+	return nil
+}
+
+// putOpenData writes data to the product called name in dir. A product in
+// a directory is replaced all at once, so that it's never read half
+// written.
+func putOpenData(dir string, name string, data []byte) error {
+	if objectstore.IsURL(dir) {
+		return objects.Put(strings.TrimSuffix(dir, "/")+"/"+name, data)
+	}
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteOpenData(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeOpenData(dir); err != nil {
+		t.Fatal("writeOpenData returned an error: ", err)
+	}
+	counts, err := os.ReadFile(filepath.Join(dir, OPEN_DATA_COUNTS))
+	lines := strings.Split(strings.TrimSpace(string(counts)), "\n")
+	if err != nil || lines[0] != "month,neighborhood,crimes" || len(lines) < 2 {
+		t.Error("Wrong monthly counts: ", string(counts), err)
+	}
+	var collection struct {
+		Type     string
+		Features []struct{ Properties struct{ Cell string } }
+	}
+	spots, err := os.ReadFile(filepath.Join(dir, OPEN_DATA_HOTSPOTS))
+	if err != nil || json.Unmarshal(spots, &collection) != nil || collection.Type != "FeatureCollection" || len(collection.Features) == 0 {
+		t.Error("Wrong hotspots: ", string(spots), err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, OPEN_DATA_COUNTS+".tmp")); err == nil {
+		t.Error("Temporary files should be renamed")
+	}
+
+	server := bucketServer()
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_S3", server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	if err := writeOpenData("s3://radar/open/"); err != nil {
+		t.Fatal("writeOpenData returned an error for object storage: ", err)
+	}
+	r, err := objects.Open("s3://radar/open/" + OPEN_DATA_COUNTS)
+	if err != nil {
+		t.Fatal("Monthly counts weren't put in object storage: ", err)
+	}
+	defer r.Close()
+	if put, _ := io.ReadAll(r); string(put) != string(counts) {
+		t.Error("Wrong monthly counts in object storage: ", string(put))
+	}
+}
+
+func TestWriteOpenDataRecentHotspots(t *testing.T) {
+	defer func(saved time.Duration) { *hotspotHalfLife = saved }(*hotspotHalfLife)
+	*hotspotHalfLife = 30 * 24 * time.Hour
+	dir := t.TempDir()
+	if err := writeOpenData(dir); err != nil {
+		t.Fatal("writeOpenData returned an error: ", err)
+	}
+	var collection struct {
+		Features []struct {
+			Properties struct {
+				Crimes int
+				Weight float64
+			}
+		}
+	}
+	spots, err := os.ReadFile(filepath.Join(dir, OPEN_DATA_HOTSPOTS))
+	if err != nil || json.Unmarshal(spots, &collection) != nil || len(collection.Features) == 0 {
+		t.Fatal("Wrong hotspots: ", string(spots), err)
+	}
+	for _, feature := range collection.Features {
+		if feature.Properties.Weight <= 0 || feature.Properties.Weight > float64(feature.Properties.Crimes) {
+			t.Error("Recent hotspots should be weighed by age: ", feature.Properties)
+		}
+	}
+}
+
+func TestRequestOpenData(t *testing.T) {
+	defer func() {
+		openDataRequests = nil
+	}()
+	requestOpenData()
+	openDataRequests = make(chan struct{}, 1)
+	requestOpenData()
+	requestOpenData()
+	if len(openDataRequests) != 1 {
+		t.Error("Requests made while one is waiting should share it: ", len(openDataRequests))
+	}
+	<-openDataRequests
+	swapFinder(finder, false)
+	if len(openDataRequests) != 1 {
+		t.Error("Swapping in new data should publish the open data")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// The degrees of latitude and longitude that a crime page's map shows on
+// each side of the crime.
+const PAGE_MAP_SPAN = 0.004
+
+// The HTML page of a single crime, with a small map of where it occurred.
+const CRIME_PAGE = `<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Type}} on {{.Crime.Date}}</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 2em auto; padding: 0 1em; }
+dt { font-weight: bold; }
+iframe { width: 100%; height: 300px; border: 1px solid #ccc; }
+.removed { color: #a00; }
+</style>
+</head>
+<body>
+<h1>{{.Type}}</h1>
+{{if .Removed}}<p class="removed">The city has removed this crime from its data.</p>{{end}}
+<dl>
+<dt>When</dt><dd>{{.Crime.Date}} {{.Crime.Time}}</dd>
+<dt>Category</dt><dd>{{.Category}}</dd>
+<dt>Where</dt><dd>{{.Point.Lat}}, {{.Point.Lng}}</dd>
+{{if .Crime.Weapon}}<dt>Weapon</dt><dd>{{.Crime.Weapon}}</dd>{{end}}
+{{if .Crime.Domestic}}<dt>Domestic</dt><dd>{{if deref .Crime.Domestic}}Yes{{else}}No{{end}}</dd>{{end}}
+{{if .Crime.Arrest}}<dt>Arrest</dt><dd>{{if deref .Crime.Arrest}}Yes{{else}}No{{end}}</dd>{{end}}
+{{if .Crime.CaseNumber}}<dt>Case</dt><dd>{{.Crime.CaseNumber}}</dd>{{end}}
+{{if .Crime.Offenses}}<dt>Offenses</dt><dd><ul>{{range .Crime.Offenses}}<li>{{label .Type $.Lang}}</li>{{end}}</ul></dd>{{end}}
+</dl>
+<iframe src="{{.MapURL}}" title="Map of where the crime occurred"></iframe>
+<p><a href="{{.LargeMapURL}}">View a larger map</a> &middot; <a href="{{.Permalink}}">Permalink</a></p>
+</body>
+</html>
+`
+
+// crimePage is CRIME_PAGE, parsed.
+var crimePage = template.Must(template.New("crime").Funcs(template.FuncMap{
+	"deref": func(flag *bool) bool { return *flag },
+	"label": func(name string, language string) string { return translations.Translate(name, language) },
+}).Parse(CRIME_PAGE))
+
+// wantsHtml returns true if r asks for HTML, as a browser following a
+// permalink does.
+func wantsHtml(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// writeCrimePage writes the HTML page of a crime that occurred at point
+// with status, in the language r prefers. Its map is OpenStreetMap's
+// embeddable one, so the page needs no scripts of its own.
+func writeCrimePage(w http.ResponseWriter, r *http.Request, status int, crime *radar.Crime, point *radar.Point) {
+	bbox := fmt.Sprintf("%v,%v,%v,%v", point.Lng-PAGE_MAP_SPAN, point.Lat-PAGE_MAP_SPAN,
+		point.Lng+PAGE_MAP_SPAN, point.Lat+PAGE_MAP_SPAN)
+	language := requestLanguage(r)
+	page := struct {
+		Crime       *radar.Crime
+		Point       *radar.Point
+		Lang        string
+		Type        string
+		Category    string
+		Removed     bool
+		Permalink   string
+		MapURL      string
+		LargeMapURL string
+	}{
+		Crime:       crime,
+		Point:       point,
+		Lang:        pageLanguage(language),
+		Type:        translations.Translate(crime.Type, language),
+		Category:    translations.Translate(radar.Classify(crime.Type).Category, language),
+		Removed:     status == 410,
+		Permalink:   radar.Permalink(*baseURL, crime.Id),
+		MapURL:      fmt.Sprintf("https://www.openstreetmap.org/export/embed.html?bbox=%v&layer=mapnik&marker=%v,%v", bbox, point.Lat, point.Lng),
+		LargeMapURL: fmt.Sprintf("https://www.openstreetmap.org/?mlat=%v&mlon=%v#map=17/%v/%v", point.Lat, point.Lng, point.Lat, point.Lng),
+	}
+	var buf bytes.Buffer
+	if err := crimePage.Execute(&buf, page); err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	setLanguageHeaders(w, language)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCrimePage(t *testing.T) {
+	*baseURL = "https://radar.example.com"
+	defer func() {
+		*baseURL = ""
+	}()
+	expected := finder.Locations()[0].Crimes[0]
+	req := httptest.NewRequest("GET", fmt.Sprintf("/crimes/%v", expected.Id), nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+	resp := httptest.NewRecorder()
+	newRouter().ServeHTTP(resp, req)
+	if resp.Code != 200 || resp.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatal("Wrong status code or Content-Type: ", resp.Code, resp.Header().Get("Content-Type"))
+	}
+	body := resp.Body.String()
+	permalink := fmt.Sprintf("https://radar.example.com/crimes/%v", expected.Id)
+	if !strings.Contains(body, "<h1>"+expected.Type+"</h1>") || !strings.Contains(body, permalink) {
+		t.Error("Page is missing the crime or its permalink: ", body)
+	}
+	if !strings.Contains(body, `<iframe src="https://www.openstreetmap.org/export/embed.html?bbox=`) {
+		t.Error("Page is missing its map: ", body)
+	}
+
+	resp = get(t, fmt.Sprintf("/crimes/%v", expected.Id))
+	if !strings.Contains(resp.Body.String(), `"url":"`+permalink+`"`) {
+		t.Error("JSON is missing the permalink: ", resp.Body.String())
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+var poisFilename = flag.String("pois", "", "CSV file of points of interest, with name, kind, lat and lng columns, to give as context in scores and deltas")
+
+// pois are the points of interest near scored places and hotspots, or nil
+// if there aren't any.
+var pois radar.POIs
+
+// loadPOIs returns the points of interest in the -pois file, or none if
+// there isn't one.
+func loadPOIs() radar.POIs {
+	if *poisFilename == "" {
+		return nil
+	}
+	loaded, err := radar.LoadPOIs(*poisFilename)
+	if err != nil {
+		log.Fatal("Could not load points of interest. ", err)
+	}
+	return loaded
+}
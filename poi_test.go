@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+func TestLoadPOIs(t *testing.T) {
+	defer func() {
+		*poisFilename = ""
+	}()
+	if loadPOIs() != nil {
+		t.Error("There should be no points of interest without a file")
+	}
+	*poisFilename = filepath.Join(t.TempDir(), "pois.csv")
+	os.WriteFile(*poisFilename, []byte("name,kind,lat,lng\nLloyd Center Transit Center,transit,45.5313,-122.6556\n"), 0644)
+	if loaded := loadPOIs(); len(loaded) != 1 || loaded[0].Kind != "transit" {
+		t.Error("Wrong points of interest: ", loaded)
+	}
+}
+
+func TestSafeAreaHandlerWithPOIs(t *testing.T) {
+	defer func() {
+		pois = nil
+	}()
+	pois = radar.POIs{{Name: "Convention Center Station", Kind: "transit", Point: radar.Point{Lat: 45.5287, Lng: -122.6627}}}
+
+	resp := get(t, "/score/safe-area?lat=45.535&lng=-122.665&threshold=50")
+	var feature struct {
+		Properties struct {
+			Nearby []struct {
+				Name  string
+				Miles float64
+			}
+		}
+	}
+	if err := json.Unmarshal(data(t, resp), &feature); err != nil || len(feature.Properties.Nearby) != 1 {
+		t.Fatal("Safe area should have the points of interest near its center: ", resp.Body.String(), err)
+	}
+	if nearby := feature.Properties.Nearby[0]; nearby.Name != "Convention Center Station" || !(nearby.Miles > 0) {
+		t.Error("Wrong point of interest: ", nearby)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// QUERY_LOG_PLACES is the number of decimal places the points of logged
+// queries are rounded to, about 0.07 miles of latitude, so that the log
+// doesn't record where anyone is.
+const QUERY_LOG_PLACES = 3
+
+// QUERY_LOG_BUFFER is the number of searches that can wait to be logged.
+// Searches made while it's full aren't logged.
+const QUERY_LOG_BUFFER = 1000
+
+var queryLogFilename = flag.String("query-log", "", "file to record anonymized searches and their result counts in, for radar replay")
+
+// A loggedQuery is a search for crimes near a point, as the query log
+// records it: its rounded point, the options it ran with, after the
+// server's defaults were applied, and how many crimes it found.
+type loggedQuery struct {
+	Lat     float64             `json:"lat"`
+	Lng     float64             `json:"lng"`
+	Options radar.SearchOptions `json:"options"`
+	Count   int                 `json:"count"`
+}
+
+// A pendingQuery is a search waiting to be logged, with the finder it
+// searched.
+type pendingQuery struct {
+	searched *radar.CrimeFinder
+	point    radar.Point
+	options  radar.SearchOptions
+}
+
+// A queryLog appends searches to a file as lines of JSON. Searches are
+// logged by a goroutine of their own, off the path of the requests that
+// made them. It's safe to use from several goroutines.
+type queryLog struct {
+	pending chan pendingQuery
+	done    chan struct{}
+	encoder *json.Encoder
+	file    *os.File
+	mu      sync.Mutex
+	// dropped is the number of searches that weren't logged because the
+	// buffer was full, since that was last logged.
+	dropped int
+}
+
+// queries records searches if the flags ask for it, and is nil if they
+// don't.
+var queries *queryLog
+
+// openQueryLog opens filename to append searches to, creating it if it
+// doesn't exist, and starts logging them.
+func openQueryLog(filename string) (*queryLog, error) {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	l := &queryLog{
+		pending: make(chan pendingQuery, QUERY_LOG_BUFFER),
+		done:    make(chan struct{}),
+		encoder: json.NewEncoder(file),
+		file:    file,
+	}
+	go l.write()
+	return l, nil
+}
+
+// Record queues a search of searched near point with options to be logged.
+// It doesn't wait for the search to be logged, and drops it if too many are
+// waiting. It does nothing if the log is nil.
+func (l *queryLog) Record(searched *radar.CrimeFinder, point radar.Point, options radar.SearchOptions) {
+	if l == nil {
+		return
+	}
+	select {
+	case l.pending <- pendingQuery{searched, point, options}:
+	default:
+		l.mu.Lock()
+		l.dropped += 1
+		l.mu.Unlock()
+	}
+}
+
+// write logs the searches that are queued until the log is closed. The
+// count it logs is that of the search from the rounded point, which is run
+// again to find it, so that replaying the log repeats the same searches.
+func (l *queryLog) write() {
+	defer close(l.done)
+	scale := math.Pow(10, QUERY_LOG_PLACES)
+	for pending := range l.pending {
+		l.mu.Lock()
+		if l.dropped > 0 {
+			log.Printf("The query log fell behind and dropped %v searches", l.dropped)
+			l.dropped = 0
+		}
+		l.mu.Unlock()
+		query := loggedQuery{Lat: math.Round(pending.point.Lat*scale) / scale, Lng: math.Round(pending.point.Lng*scale) / scale, Options: pending.options}
+		nearby, err := pending.searched.FindNearWithOptions(radar.Point{Lat: query.Lat, Lng: query.Lng}, query.Options)
+		if err != nil {
+			log.Println("Could not log a query. ", err)
+			continue
+		}
+		query.Count = len(nearby.Crimes())
+		if err := l.encoder.Encode(query); err != nil {
+			log.Println("Could not log a query. ", err)
+		}
+	}
+}
+
+// Close logs the searches that are queued and closes the log's file. The
+// log can't be used afterward.
+func (l *queryLog) Close() error {
+	close(l.pending)
+	<-l.done
+	return l.file.Close()
+}
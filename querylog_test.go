@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+func TestQueryLog(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "queries.log")
+	var err error
+	queries, err = openQueryLog(filename)
+	if err != nil {
+		t.Fatal("Error opening the query log: ", err)
+	}
+	defer func() { queries = nil }()
+	get(t, "/crimes/near/45.53435699129174/-122.66469510763777?type=Liquor+Laws&api_key=secret")
+	get(t, "/crimes/near/45.5184/-122.6554")
+	if err := queries.Close(); err != nil {
+		t.Fatal("Error closing the query log: ", err)
+	}
+
+	contents, _ := os.ReadFile(filename)
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	if len(lines) != 2 || strings.Contains(string(contents), "secret") {
+		t.Fatal("Wrong query log: ", string(contents))
+	}
+	var query loggedQuery
+	json.Unmarshal([]byte(lines[0]), &query)
+	if query.Lat != 45.534 || query.Lng != -122.665 || query.Options.Filters.Filter.Type != "Liquor Laws" || query.Options.Sort != radar.LocationSort {
+		t.Error("Wrong logged query: ", lines[0])
+	}
+	if count, _ := replayQuery(&finder, query); query.Count == 0 || count != query.Count {
+		t.Error("The logged count should be that of the rounded point: ", query.Count, count)
+	}
+}
+
+// Searches are logged with the options they ran with, so a server with other
+// defaults replays them the same way.
+func TestReplayQueryIgnoresDefaults(t *testing.T) {
+	query := loggedQuery{Lat: 45.518, Lng: -122.655, Options: radar.SearchOptions{Limit: 2}}
+	before, err := replayQuery(&finder, query)
+	if err != nil {
+		t.Fatal("Error replaying a query: ", err)
+	}
+	previous := defaults
+	defer func() { defaults = previous }()
+	defaults = searchDefaults{Radius: 0.1, Limit: 1}
+	if after, _ := replayQuery(&finder, query); after != before {
+		t.Error("The server's defaults changed a replayed query: ", before, after)
+	}
+}
@@ -1,60 +1,861 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
+	"os"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
 
 	// Uncomment to profile
 	//_ "net/http/pprof"
 
 	"github.com/gorilla/mux"
 
+	"github.com/abrookins/radar/client"
 	"github.com/abrookins/radar/crimes"
+	"github.com/abrookins/radar/crimes/sample"
+	"github.com/abrookins/radar/internal/usage"
 )
 
 var finder radar.CrimeFinder
 var port = flag.Int("p", 8081, "port number")
-var filename = flag.String("f", "", "data filename")
+var filename = flag.String("f", "", "data filename, the s3:// or gs:// URL of a data file, or the socrata:// URL of a dataset")
+var jitter = flag.Float64("jitter", 0, "miles to randomly move each location, for anonymity")
+var maxAgeYears = flag.Int("max-age-years", 0, "drop crimes older than this many years")
+var excludeTypes = flag.String("exclude-types", "", "comma-separated crime types to drop")
+var zonesFilename = flag.String("exclusion-zones", "", "GeoJSON file of areas to remove or aggregate")
+var schema = flag.String("schema", "auto", "layout of the data file: auto, legacy, pdx2015, seattle, chicago or radar")
+var columnMappingFilename = flag.String("column-mapping", "", "JSON file mapping the columns of a data file that none of the schemas describe, used instead of -schema and for crimes sent to POST /crimes")
+
+// mappedSchema reads data with the -column-mapping, if there is one.
+var mappedSchema *radar.Schema
+var idStrategy = flag.String("ids", "source", "how crimes get their ids: source, hash or sequential")
+var order = flag.String("order", "detect", "coordinate column order: detect, latlng or lnglat")
+var snapshotFilename = flag.String("snapshot", "", "snapshot file or s3:// or gs:// URL to load instead of a data file")
+var saveSnapshotFilename = flag.String("save-snapshot", "", "file or s3:// or gs:// URL to save a snapshot of the loaded data to")
+var baseURL = flag.String("base-url", "", "public URL of the server, for the permalinks of crimes")
+var groupByCase = flag.Bool("group-by-case", false, "merge crimes with the same case number into one incident")
+var loadWorkers = flag.Int("load-workers", 0, "goroutines to parse the rows of a data file on; 0 for one for each CPU")
+
+// Values for the -order flag.
+var coordinateOrders = map[string]int{
+	"detect": radar.DetectCoordinateOrder,
+	"latlng": radar.LatLngOrder,
+	"lnglat": radar.LngLatOrder,
+}
+
+// parsePoint parses latitude and longitude strings from a request into a
+// Point. The route's regex only matches numbers, but they may not fit in a
+// float64 or be valid coordinates.
+func parsePoint(latValue string, lngValue string) (radar.Point, error) {
+	lat, err := strconv.ParseFloat(latValue, 64)
+	if err != nil {
+		return radar.Point{}, err
+	}
+	lng, err := strconv.ParseFloat(lngValue, 64)
+	if err != nil {
+		return radar.Point{}, err
+	}
+	return radar.NewPoint(radar.Latitude(lat), radar.Longitude(lng))
+}
 
 func handler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	// I trust that the regex gave us float-worthy values.
-	lat, _ := strconv.ParseFloat(vars["lat"], 64)
-	lng, _ := strconv.ParseFloat(vars["lng"], 64)
-
-	query := radar.Point{lat, lng}
-	nearby, err := finder.FindNear(query)
+	query, err := parsePoint(vars["lat"], vars["lng"])
+	if err != nil {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	search, err := parseNearSearch(r)
+	if err != nil {
+		writeSearchError(w, err)
+		return
+	}
+	tracker.Record(query.Lat, query.Lng, time.Now())
+	applied := appliedSearch(query, search)
+	explain := r.URL.Query().Get("explain") == "true"
+	if explain {
+		applied["explain"] = true
+	}
+	// Explanations describe a single search, so they aren't cached.
+	cacheKey := ""
+	if responses != nil && !explain {
+		cacheKey = responses.key(finderFor(r), applied)
+		resp, count, hit, err := responses.get(cacheKey)
+		if err != nil {
+			// Redis didn't answer in time, so the results aren't cached
+			// either, which would wait on it again.
+			cacheKey = ""
+		} else if hit {
+			if err := limits.checkResults(count); err != nil {
+				writeLimitError(w, err)
+				return
+			}
+			queries.Record(finderFor(r), query, search)
+			writeSearchJson(w, r, resp, responseMeta{Query: applied, Count: &count})
+			return
+		}
+	}
+	nearby, err := runSearch(finderFor(r), query, search, explain)
 	if err != nil {
 		http.Error(w, http.StatusText(500), 500)
 		log.Fatal(err)
 		return
 	}
+	if err := limits.checkResults(len(nearby.Crimes())); err != nil {
+		writeLimitError(w, err)
+		return
+	}
+	nearby.BaseURL = *baseURL
 	resp, err := nearby.ToJson()
 	if err != nil {
 		http.Error(w, http.StatusText(500), 500)
 		log.Fatal(err)
 		return
 	}
-	w.Write(resp)
+	count := len(nearby.Crimes())
+	if cacheKey != "" {
+		responses.set(cacheKey, resp, count)
+	}
+	queries.Record(finderFor(r), query, search)
+	writeSearchJson(w, r, resp, responseMeta{Query: applied, Count: &count})
 	defer r.Body.Close()
 }
 
-func main() {
+// parseNearSearch reads the options of a search for crimes near a point
+// from a request: its filters, and the radius, limit, sort,
+// include_distance, max_per_location and exclude_types parameters. The
+// server's search defaults apply to the radius, limit, sort and excluded
+// types that the request leaves out.
+func parseNearSearch(r *http.Request) (radar.SearchOptions, error) {
+	options := defaults.options()
 	var err error
-	flag.Parse()
+	params := r.URL.Query()
+	options.Filters.Category = params.Get("category")
+	if options.Filters.Attributes, err = parseAttributeFilter(r); err != nil {
+		return options, err
+	}
+	if options.Filters.Filter, err = parseSearchFilter(r); err != nil {
+		return options, err
+	}
+	if value := params.Get("radius"); value != "" {
+		if options.Radius, err = strconv.ParseFloat(value, 64); err != nil || !(options.Radius > 0) {
+			return options, fmt.Errorf("invalid radius: %q", value)
+		}
+		// A radius over the limit is told how to ask for less before it's
+		// checked against the most a search can reach.
+		if err := limits.checkRadius(options.Radius); err != nil {
+			return options, err
+		}
+	}
+	for name, number := range map[string]*int{"limit": &options.Limit, "max_per_location": &options.MaxPerLocation} {
+		if value := params.Get(name); value != "" {
+			if *number, err = strconv.Atoi(value); err != nil || *number < 1 {
+				return options, fmt.Errorf("invalid %v: %q", name, value)
+			}
+		}
+	}
+	if params.Has("sort") {
+		options.Sort = radar.SearchSort(params.Get("sort"))
+	}
+	if params.Has("exclude_types") {
+		options.Filters.ExcludedTypes = parseTypes(params.Get("exclude_types"))
+	}
+	options.IncludeDistance = params.Get("include_distance") == "true"
+	return options, options.Validate()
+}
+
+// searchParams returns the parameters of a request for a search with
+// options, the reverse of parseNearSearch.
+func searchParams(options radar.SearchOptions) url.Values {
+	params := make(url.Values)
+	set := func(name string, value string) {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	set("category", options.Filters.Category)
+	set("type", options.Filters.Filter.Type)
+	set("month", options.Filters.Filter.Month)
+	set("neighborhood", options.Filters.Filter.Neighborhood)
+	set("weapon", options.Filters.Attributes.Weapon)
+	for name, flag := range map[string]*bool{"domestic": options.Filters.Attributes.Domestic, "arrest": options.Filters.Attributes.Arrest} {
+		if flag != nil {
+			params.Set(name, strconv.FormatBool(*flag))
+		}
+	}
+	if options.Radius > 0 {
+		params.Set("radius", strconv.FormatFloat(options.Radius, 'f', -1, 64))
+	}
+	if options.Limit > 0 {
+		params.Set("limit", strconv.Itoa(options.Limit))
+	}
+	set("sort", string(options.Sort))
+	if options.IncludeDistance {
+		params.Set("include_distance", "true")
+	}
+	if options.MaxPerLocation > 0 {
+		params.Set("max_per_location", strconv.Itoa(options.MaxPerLocation))
+	}
+	set("exclude_types", strings.Join(options.Filters.ExcludedTypes, ","))
+	return params
+}
+
+// appliedSearch returns the query of a search near query as it's described
+// in the envelope of a response: its point and the options it applied.
+func appliedSearch(query radar.Point, options radar.SearchOptions) map[string]interface{} {
+	applied := map[string]interface{}{"lat": query.Lat, "lng": query.Lng}
+	for name, values := range searchParams(options) {
+		applied[name] = values[0]
+	}
+	// Numbers and flags are described as JSON numbers and booleans.
+	if options.Filters.Attributes.Domestic != nil {
+		applied["domestic"] = *options.Filters.Attributes.Domestic
+	}
+	if options.Filters.Attributes.Arrest != nil {
+		applied["arrest"] = *options.Filters.Attributes.Arrest
+	}
+	if options.Radius > 0 {
+		applied["radius"] = options.Radius
+	}
+	for name, number := range map[string]int{"limit": options.Limit, "max_per_location": options.MaxPerLocation} {
+		if number > 0 {
+			applied[name] = number
+		}
+	}
+	if options.IncludeDistance {
+		applied["include_distance"] = true
+	}
+	return applied
+}
 
-	finder, err = radar.NewCrimeFinder(*filename)
+// runSearch finds the crimes near query in searched with options,
+// explaining how it found them if explain is true.
+func runSearch(searched *radar.CrimeFinder, query radar.Point, options radar.SearchOptions, explain bool) (radar.SearchResult, error) {
+	if explain {
+		return searched.FindNearWithOptionsExplained(query, options)
+	}
+	return searched.FindNearWithOptions(query, options)
+}
+
+// parseAttributeFilter reads the weapon, domestic and arrest parameters of
+// a request.
+func parseAttributeFilter(r *http.Request) (radar.AttributeFilter, error) {
+	params := r.URL.Query()
+	filter := radar.AttributeFilter{Weapon: params.Get("weapon")}
+	for name, flag := range map[string]**bool{"domestic": &filter.Domestic, "arrest": &filter.Arrest} {
+		value := params.Get(name)
+		if value == "" {
+			continue
+		}
+		if *flag = radar.ParseFlag(value); *flag == nil {
+			return filter, fmt.Errorf("invalid %v: %q", name, value)
+		}
+	}
+	return filter, nil
+}
+
+// parseSearchFilter reads the type, month and neighborhood parameters of a
+// request.
+func parseSearchFilter(r *http.Request) (radar.SearchFilter, error) {
+	params := r.URL.Query()
+	filter := radar.SearchFilter{Type: params.Get("type"), Month: params.Get("month"), Neighborhood: params.Get("neighborhood")}
+	if filter.Month != "" {
+		if _, err := time.Parse(radar.MONTH_LAYOUT, filter.Month); err != nil {
+			return filter, fmt.Errorf("invalid month: %q", filter.Month)
+		}
+	}
+	return filter, nil
+}
+
+// contextKey is the type of keys of values the server stores in a request's
+// context.
+type contextKey int
+
+// startKey is the key of the time the server started handling a request.
+const startKey contextKey = 0
+
+// timeRequests is middleware that records when the server started handling
+// each request, for the took_ms of the response's envelope.
+func timeRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), startKey, time.Now())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// responseMeta describes a response in its envelope. It's the client
+// package's type, so that Go clients decode exactly what the server writes.
+type responseMeta = client.Meta
+
+// datasetVersion holds the version of the finder's data, so that responses
+// can report it without locking the finder.
+var datasetVersion atomic.Value
+
+// updateDatasetVersion records the version of the finder's data. It's
+// called whenever the data changes, with the finder locked for writing.
+func updateDatasetVersion() {
+	datasetVersion.Store(finder.Version())
+}
+
+// currentDatasetVersion returns the version of the finder's data.
+func currentDatasetVersion() string {
+	version, _ := datasetVersion.Load().(string)
+	return version
+}
+
+// envelope wraps resp in an object with meta, as {"meta": ..., "data": ...}.
+func envelope(r *http.Request, resp []byte, meta responseMeta) ([]byte, error) {
+	if meta.Query == nil {
+		meta.Query = make(map[string]interface{})
+	}
+	// A meta that was already timed, like one for a response that's cut
+	// down to size, keeps its time, so that its size doesn't change.
+	if start, ok := r.Context().Value(startKey).(time.Time); ok && meta.TookMs == 0 {
+		meta.TookMs = float64(time.Since(start)) / float64(time.Millisecond)
+	}
+	meta.DatasetVersion = finderVersion(r)
+	if *demo {
+		meta.Notice = DEMO_NOTICE
+	}
+	return json.Marshal(struct {
+		Meta responseMeta    `json:"meta"`
+		Data json.RawMessage `json:"data"`
+	}{meta, resp})
+}
+
+// writeJson writes resp, the output of a ToJson method, in an envelope with
+// meta unless the request's "envelope" parameter is "false", and with the
+// field naming that its "case" parameter asks for.
+func writeJson(w http.ResponseWriter, r *http.Request, resp []byte, meta responseMeta) {
+	writeJsonStatus(w, r, 200, resp, meta)
+}
+
+// writeJsonStatus works like writeJson with a status code other than 200.
+func writeJsonStatus(w http.ResponseWriter, r *http.Request, status int, resp []byte, meta responseMeta) {
+	body, errStatus, err := encodeJson(r, resp, meta)
 	if err != nil {
-		log.Fatal("Could not open data file.", err, *filename)
+		writeEncodeError(w, errStatus, err)
+		return
+	}
+	writeBody(w, r, status, body)
+}
+
+// encodeJson returns the body that writeJson writes for resp: in an
+// envelope with meta, translated, and with the field naming the request
+// asks for. If it can't, it returns the status to respond with instead.
+func encodeJson(r *http.Request, resp []byte, meta responseMeta) ([]byte, int, error) {
+	var err error
+	if r.URL.Query().Get("envelope") != "false" {
+		resp, err = envelope(r, resp, meta)
+		if err != nil {
+			return nil, 500, err
+		}
+	}
+	resp, err = radar.TranslateValues(resp, translations, requestLanguage(r))
+	if err != nil {
+		return nil, 500, err
+	}
+	resp, err = radar.RenameKeys(resp, r.URL.Query().Get("case"))
+	if err != nil {
+		return nil, 400, err
+	}
+	return resp, 200, nil
+}
+
+// writeEncodeError responds with status to a request whose response
+// couldn't be encoded. Only the server's own errors are logged.
+func writeEncodeError(w http.ResponseWriter, status int, err error) {
+	http.Error(w, http.StatusText(status), status)
+	if status == 500 {
+		log.Println(err)
+	}
+}
+
+// writeBody writes body, the output of encodeJson, with status.
+func writeBody(w http.ResponseWriter, r *http.Request, status int, body []byte) {
+	setLanguageHeaders(w, requestLanguage(r))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// newProgressPrinter returns a function that prints the progress of loading
+// data to w: a line that updates as the file is read, then how long each
+// phase took.
+func newProgressPrinter(w io.Writer) func(radar.LoadProgress) {
+	return func(progress radar.LoadProgress) {
+		if !progress.Done {
+			fmt.Fprintf(w, "\rReading data: %3.0f%% (%v lines, %.0f lines/sec)",
+				progress.Percent(), progress.Rows, progress.RowsPerSecond())
+			return
+		}
+		switch progress.Phase {
+		case radar.ReadPhase:
+			fmt.Fprintf(w, "\rReading data: 100%% (%v rows, %.0f rows/sec)\n",
+				progress.Rows, progress.RowsPerSecond())
+			fmt.Fprintf(w, "Read %v rows in %v\n", progress.Rows, progress.Elapsed.Round(time.Millisecond))
+		case radar.ParsePhase:
+			fmt.Fprintf(w, "Parsed %v rows in %v\n", progress.Rows, progress.Elapsed.Round(time.Millisecond))
+		case radar.IndexPhase:
+			fmt.Fprintf(w, "Built the index in %v\n", progress.Elapsed.Round(time.Millisecond))
+		}
+	}
+}
+
+// crimeHandler looks up a crime by id. A crime that a refresh removed is
+// gone, with a 410.
+func crimeHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+	crime, location := finderFor(r).FindByID(id)
+	if crime == nil {
+		// Historical data has no record of what was removed since.
+		if finderFor(r) == &finder {
+			if removed, ok := removedCrime(id); ok {
+				writeCrime(w, r, 410, removed.Crime, removed.Point)
+				return
+			}
+		}
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+	writeCrime(w, r, 200, crime, location.Point)
+}
+
+// writeCrime writes a crime that occurred at point with status: as an HTML
+// page if the request asks for one, and otherwise as JSON in the same form
+// as a search.
+func writeCrime(w http.ResponseWriter, r *http.Request, status int, crime *radar.Crime, point *radar.Point) {
+	w.Header().Set("Vary", "Accept")
+	if wantsHtml(r) {
+		writeCrimePage(w, r, status, crime, point)
+		return
+	}
+	result := radar.SearchResult{
+		Query:     point,
+		Locations: []*radar.CrimeLocation{{Point: point, Crimes: []*radar.Crime{crime}}},
+		BaseURL:   *baseURL,
+	}
+	resp, err := result.ToJson()
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	count := 1
+	writeJsonStatus(w, r, status, resp, responseMeta{Query: map[string]interface{}{"id": crime.Id}, Count: &count})
+}
+
+// boundsHandler describes the coverage of the data.
+func boundsHandler(w http.ResponseWriter, r *http.Request) {
+	resp, err := finderFor(r).Summary().ToJson()
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	writeJson(w, r, resp, responseMeta{})
+}
+
+// The default bucket size, in miles, of the nearest-neighbor histogram.
+const DEFAULT_BUCKET_MILES = 0.05
+
+// nearestNeighborsHandler returns a histogram of the distances between each
+// location and its nearest neighbor. The "bucket_miles" parameter sets the
+// width of the histogram's buckets.
+func nearestNeighborsHandler(w http.ResponseWriter, r *http.Request) {
+	bucketMiles := DEFAULT_BUCKET_MILES
+	if value := r.URL.Query().Get("bucket_miles"); value != "" {
+		var err error
+		bucketMiles, err = strconv.ParseFloat(value, 64)
+		if err != nil || !(bucketMiles > 0) || math.IsInf(bucketMiles, 1) {
+			http.Error(w, http.StatusText(400), 400)
+			return
+		}
+	}
+	histogram := radar.NewHistogram(finderFor(r).NearestNeighborDistances(), bucketMiles)
+	resp, err := histogram.ToJson()
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
 		return
 	}
+	query := map[string]interface{}{"bucket_miles": bucketMiles}
+	writeJson(w, r, resp, responseMeta{Query: query, Count: &histogram.Count})
+}
 
+// newRouter returns a router with all of the server's routes.
+func newRouter() *mux.Router {
 	r := mux.NewRouter()
-	r.HandleFunc("/crimes/near/{lat:[-+]?[0-9]*.?[0-9]+.}/{lng:[-+]?[0-9]*.?[0-9]+.}", handler)
-	http.Handle("/", r)
+	r.Use(timeRequests)
+	r.Use(limitDemo)
+	r.Use(limitKeys)
+	r.Use(cacheHeaders)
+	r.Use(loadYears)
+	if len(shards) > 0 {
+		// A router only searches; it doesn't hold crimes or geofences.
+		r.HandleFunc(`/crimes/near/{lat:[-+]?[0-9]*\.?[0-9]+}/{lng:[-+]?[0-9]*\.?[0-9]+}`, routedNearHandler)
+		r.HandleFunc("/crimes/{id:[0-9]+}", routedCrimeHandler)
+		return r
+	}
+	r.HandleFunc(`/crimes/near/{lat:[-+]?[0-9]*\.?[0-9]+}/{lng:[-+]?[0-9]*\.?[0-9]+}`, readLocked(handler))
+	r.HandleFunc(`/crimes/near/{lat:[-+]?[0-9]*\.?[0-9]+}/{lng:[-+]?[0-9]*\.?[0-9]+}/recent`, readLocked(recentHandler))
+	r.HandleFunc("/crimes/{id:[0-9]+}", readLocked(crimeHandler))
+	r.HandleFunc("/locations/{key}", readLocked(locationHandler))
+	r.HandleFunc("/meta/bounds", readLocked(boundsHandler))
+	r.HandleFunc("/meta/nearest-neighbors", readLocked(nearestNeighborsHandler))
+	r.HandleFunc("/clusters", pooled(readLocked(clustersHandler)))
+	r.HandleFunc("/stats/anomalies", pooled(readLocked(anomaliesHandler)))
+	r.HandleFunc("/stats/autocorrelation", pooled(readLocked(autocorrelationHandler)))
+	r.HandleFunc("/stats/forecast", pooled(readLocked(forecastHandler)))
+	r.HandleFunc("/stats/delta", pooled(readLocked(deltaHandler)))
+	r.HandleFunc("/score/route", readLocked(routeScoreHandler)).Methods("POST")
+	r.HandleFunc("/distance-matrix", pooled(readLocked(distanceMatrixHandler))).Methods("POST")
+	r.HandleFunc("/score/safe-area", pooled(readLocked(safeAreaHandler)))
+	r.HandleFunc("/meta/changes", changesHandler)
+	r.HandleFunc("/suggest", readLocked(suggestHandler))
+	r.HandleFunc("/me", meHandler)
+	r.HandleFunc("/widget", readLocked(widgetHandler))
+	r.HandleFunc("/widget.js", widgetScriptHandler)
+	// A demo is public, so it has nothing that needs a key.
+	if !*demo {
+		r.HandleFunc("/admin/usage", requireKey(usageHandler))
+		r.HandleFunc("/admin/refresh", requireKey(refreshHandler)).Methods("GET", "POST")
+		r.HandleFunc("/exports", requireKey(exportsHandler)).Methods("POST")
+		r.HandleFunc("/exports/{id}", requireKey(exportHandler))
+		r.HandleFunc("/exports/{id}/download", requireKey(exportDownloadHandler))
+		r.HandleFunc("/geofences", requireKey(geofencesHandler))
+		r.HandleFunc("/geofences/{id}", requireKey(geofenceHandler))
+		if audits != nil {
+			r.HandleFunc("/admin/audit", requireKey(auditHandler))
+		}
+	}
+	if len(neighborhoods) > 0 {
+		r.HandleFunc("/neighborhoods", readLocked(neighborhoodsHandler))
+		r.HandleFunc("/neighborhoods/{name}", readLocked(neighborhoodHandler))
+	}
+	if *ingest {
+		r.HandleFunc("/crimes", ingestHandler).Methods("POST")
+	}
+	if *publishSnapshots {
+		r.HandleFunc("/snapshot", requireKey(readLocked(snapshotHandler)))
+	}
+	return r
+}
+
+// loadOptions returns the options for loading data that the flags ask for.
+func loadOptions() radar.LoadOptions {
+	var err error
+	coordinateOrder, ok := coordinateOrders[*order]
+	if !ok {
+		log.Fatal("Unknown coordinate order: ", *order)
+	}
+	retention := radar.RetentionPolicy{MaxAgeYears: *maxAgeYears}
+	if *excludeTypes != "" {
+		retention.ExcludedTypes = strings.Split(*excludeTypes, ",")
+	}
+	// A nil schema is detected from the file's header.
+	dataSchema, ok := radar.Schemas[*schema]
+	if !ok && *schema != "auto" {
+		log.Fatal("Unknown schema: ", *schema)
+	}
+	if mappedSchema != nil {
+		dataSchema = mappedSchema
+	}
+	ids, ok := radar.IDStrategies[*idStrategy]
+	if !ok {
+		log.Fatal("Unknown id strategy: ", *idStrategy)
+	}
+	options := radar.LoadOptions{
+		Schema:          dataSchema,
+		Geocoder:        loadGeocoder(),
+		CoordinateOrder: coordinateOrder,
+		JitterMiles:     *jitter,
+		Retention:       retention,
+		GroupByCase:     *groupByCase,
+		IDs:             ids,
+		Progress:        newProgressPrinter(os.Stderr),
+		BadRows:         badRowPolicy(*badRows, *quarantine),
+		LazyIndexes:     *lazyIndexes,
+		Workers:         *loadWorkers,
+	}
+	if *shardFlag != "" {
+		shard, err := radar.ParseBounds(*shardFlag)
+		if err != nil {
+			log.Fatal("Invalid shard. ", err)
+		}
+		options.Shard = &shard
+	}
+	if *zonesFilename != "" {
+		options.ExclusionZones, err = radar.LoadExclusionZones(*zonesFilename)
+		if err != nil {
+			log.Fatal("Could not load exclusion zones. ", err)
+		}
+	}
+	return options
+}
+
+// loadColumnMapping returns the schema of the -column-mapping file, or nil
+// if there isn't one.
+func loadColumnMapping() *radar.Schema {
+	if *columnMappingFilename == "" {
+		return nil
+	}
+	mapped, err := radar.LoadColumnMapping(*columnMappingFilename)
+	if err != nil {
+		log.Fatal("Could not load the column mapping. ", err)
+	}
+	return mapped
+}
+
+// commands run instead of the server when their name follows the flags.
+var commands = map[string]func(args []string, w io.Writer) error{
+	"diff":   runDiff,
+	"merge":  runMerge,
+	"replay": runReplay,
+}
+
+// loadCsv loads the data file named by the flags into finder.
+func loadCsv() {
+	var err error
+	options := loadOptions()
+	finder, err = loadFile(*filename, options)
+	if err != nil {
+		log.Fatal("Could not open data file.", err, *filename)
+		return
+	}
+	logBadRows(&finder, options.BadRows, *filename)
+	if finder.Report.Dropped > 0 {
+		log.Printf("Dropped %v crimes because of the retention policy", finder.Report.Dropped)
+	}
+	if finder.Report.OutsideShard > 0 {
+		log.Printf("Skipped %v crimes outside the shard", finder.Report.OutsideShard)
+	}
+	if finder.Report.Geocoded > 0 {
+		log.Printf("Geocoded %v crimes that were missing coordinates", finder.Report.Geocoded)
+	}
+}
+
+func main() {
+	var err error
+	flag.Parse()
+
+	if command, ok := commands[flag.Arg(0)]; ok {
+		if err := command(flag.Args()[1:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	limits = newLimitPolicy()
+	riskScorer = loadScorer()
+	translations = loadTranslations()
+	cacheRules = loadCachePolicy()
+	neighborhoods = loadNeighborhoods()
+	populations = loadPopulations()
+	pois = loadPOIs()
+	defaults = loadSearchDefaults()
+	mappedSchema = loadColumnMapping()
+	ingestRows = newIngestPolicy()
+	audits = loadAuditLog()
+	analytics = newAnalyticsPool()
+	keyClients = newKeyLimiter()
+	openDataRequests = newOpenDataRequests()
+	refreshWebhooks = loadRefreshWebhooks()
+	if *demo {
+		if *filename != "" || *snapshotFilename != "" || *yearsDir != "" || *replicateFrom != "" || *shardsFilename != "" || *ingest || *publishSnapshots {
+			log.Fatal("-demo serves the bundled sample data and nothing else, so it can't be used with -f, -snapshot, -years, -replicate-from, -shards, -ingest or -publish-snapshots.")
+			return
+		}
+		limits = demoLimits
+		demoClients = newDemoLimiter()
+	}
+
+	if *createAPIKey != "" {
+		if *geofencesFilename == "" && *redisAddr == "" {
+			log.Fatal("-create-api-key needs a -geofences file or -redis server to keep the key in.")
+			return
+		}
+		store, err := openStore()
+		if err != nil {
+			log.Fatal("Could not load geofences. ", err)
+			return
+		}
+		key, err := store.CreateKey(*createAPIKey)
+		if err != nil {
+			log.Fatal("Could not create an API key. ", err)
+			return
+		}
+		audits.Record(nil, AuditKeyCreated, map[string]interface{}{"id": key.Id, "name": key.Name})
+		fmt.Println(key.Key)
+		return
+	}
+
+	if *shardsFilename != "" {
+		shards, err = loadShards(*shardsFilename)
+		if err != nil {
+			log.Fatal("Could not load shards. ", err)
+			return
+		}
+		http.Handle("/", newRouter())
+		log.Println("Routing searches to", len(shards), "shards on port", *port)
+		log.Fatal(http.ListenAndServe(fmt.Sprintf(":%v", *port), nil))
+		return
+	}
+
+	if *demo {
+		finder = sample.NewFinder()
+		log.Printf("Serving a demo of %v sample crimes", finder.Report.Crimes)
+		go pruneDemoClients(DEMO_PRUNE_INTERVAL)
+	} else if *replicateFrom != "" {
+		if *ingest {
+			log.Fatal("Replicas are read-only, so -replicate-from can't be used with -ingest.")
+			return
+		}
+		var version string
+		finder, version, err = fetchSnapshot(*replicateFrom, "")
+		if err != nil {
+			log.Fatal("Could not replicate a snapshot. ", err)
+			return
+		}
+		log.Printf("Replicated %v crimes from version %v", finder.Report.Crimes, version)
+		go replicate(*replicateFrom, version, *replicateInterval)
+	} else if *snapshotFilename != "" {
+		finder, err = loadSnapshot(*snapshotFilename, radar.LoadOptions{LazyIndexes: *lazyIndexes})
+		if err != nil {
+			log.Fatal("Could not load snapshot. ", err)
+			return
+		}
+	} else if *yearsDir != "" {
+		if *ingest {
+			log.Fatal("Ingested crimes aren't saved to the years they belong to, so -years can't be used with -ingest.")
+			return
+		}
+		partitions, err = radar.OpenYearPartitions(*yearsDir, *residentYears)
+		if err != nil {
+			log.Fatal("Could not open the years. ", err)
+			return
+		}
+		finder = partitions.Finder()
+		log.Printf("Serving %v crimes from %v of the years %v", finder.Report.Crimes, partitions.Loaded(), partitions.Years())
+		go evictYears(YEAR_EVICT_INTERVAL)
+	} else if *filename == "" && *ingest {
+		finder = radar.NewEmptyCrimeFinder(loadOptions())
+		log.Println("Starting with no crimes. Add them with POST /crimes.")
+	} else {
+		loadCsv()
+	}
+	if *ingestLogFilename != "" {
+		if !*ingest {
+			log.Fatal("-ingest-wal logs crimes sent to POST /crimes, so it needs -ingest.")
+			return
+		}
+		replayed, size, err := replayIngestLog(*ingestLogFilename, &finder)
+		if err != nil {
+			log.Fatal("Could not replay the ingest log. ", err)
+			return
+		}
+		log.Printf("Replayed %v ingested crimes from %v", replayed, *ingestLogFilename)
+		if ingests, err = openIngestLog(*ingestLogFilename, size); err != nil {
+			log.Fatal("Could not open the ingest log. ", err)
+			return
+		}
+	}
+	updateDatasetVersion()
+	if *geofencesFilename != "" || *redisAddr != "" {
+		geofences, err = openStore()
+		if err != nil {
+			log.Fatal("Could not load geofences. ", err)
+			return
+		}
+	}
+	if *redisAddr != "" {
+		responses = &responseCache{client: newRedisClient(*redisTimeout), prefix: *redisPrefix, ttl: *cacheTTL}
+	}
+	if *alertTemplateFilename != "" {
+		alertTemplate, err = template.ParseFiles(*alertTemplateFilename)
+		if err != nil {
+			log.Fatal("Could not load alert template. ", err)
+			return
+		}
+	}
+	alerter = newAlerter(geofences)
+	if *historyDir != "" {
+		history, err = radar.NewHistory(*historyDir, *historySize)
+		if err != nil {
+			log.Fatal("Could not load the history. ", err)
+			return
+		}
+		// Compare the data with what was loaded last time, in case the
+		// city corrected old records.
+		if previous, err := history.At(time.Now()); err == nil {
+			recordChanges(finder.Diff(previous), *replicateFrom == "")
+		}
+		recordHistory()
+	}
+	if *usageFilename != "" {
+		tracker, err = usage.NewTracker(usage.DEFAULT_CELL_SIZE, *usageFilename)
+		if err != nil {
+			log.Fatal("Could not load usage. ", err)
+			return
+		}
+		go saveUsage(USAGE_SAVE_INTERVAL)
+	}
+	if *queryLogFilename != "" {
+		queries, err = openQueryLog(*queryLogFilename)
+		if err != nil {
+			log.Fatal("Could not open the query log. ", err)
+			return
+		}
+	}
+	if *warmCells > 0 {
+		finder.EnableCache(tracker.CellSize())
+		warmCache()
+		go rewarmCache(WARM_INTERVAL)
+	}
+	if *saveSnapshotFilename != "" {
+		if err = saveSnapshot(&finder, *saveSnapshotFilename); err != nil {
+			log.Fatal("Could not save snapshot. ", err)
+			return
+		}
+		log.Println("Saved a snapshot to", *saveSnapshotFilename)
+	}
+	if *saveYearsDir != "" {
+		years, err := finder.SaveYearPartitions(*saveYearsDir)
+		if err != nil {
+			log.Fatal("Could not save the years. ", err)
+			return
+		}
+		log.Println("Saved the years", years, "to", *saveYearsDir)
+	}
+
+	trainForecasts()
+	if *anomalyInterval > 0 {
+		analyzeAnomalies(false)
+		go reanalyzeAnomalies(*anomalyInterval, *replicateFrom == "")
+	}
+	if openDataRequests != nil {
+		go publishOpenData()
+		requestOpenData()
+	}
+	go awaitIndexes()
+	go reloadOnSignal()
+
+	http.Handle("/", newRouter())
 
 	log.Println("Running server on port", *port)
 	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%v", *port), nil))
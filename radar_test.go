@@ -0,0 +1,537 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+	"github.com/abrookins/radar/crimes/sample"
+)
+
+// TestMain loads the small test dataset for the server's handlers.
+func TestMain(m *testing.M) {
+	finder = sample.NewFinder()
+	updateDatasetVersion()
+	os.Exit(m.Run())
+}
+
+// get makes a GET request to the server's router and returns the response.
+func get(t *testing.T, url string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", url, nil)
+	resp := httptest.NewRecorder()
+	newRouter().ServeHTTP(resp, req)
+	return resp
+}
+
+// The envelope that responses are wrapped in.
+type envelopeResponse struct {
+	Meta struct {
+		Query  map[string]interface{}
+		TookMs *float64 `json:"took_ms"`
+		Count  *int
+	}
+	Data json.RawMessage
+}
+
+// data returns the data in the envelope of a response.
+func data(t *testing.T, resp *httptest.ResponseRecorder) []byte {
+	var body envelopeResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal("Response was not valid JSON: ", err)
+	}
+	return body.Data
+}
+
+// The shape of a response from /crimes/near.
+type nearResponse struct {
+	Query struct {
+		Lat *float64
+		Lng *float64
+	}
+	Locations []struct {
+		Point struct {
+			Lat *float64
+			Lng *float64
+		}
+		Crimes []struct {
+			Id   *int64
+			Date *string
+			Time *string
+			Type *string
+		}
+	}
+}
+
+func TestCrimesNear(t *testing.T) {
+	resp := get(t, "/crimes/near/45.53435699129174/-122.66469510763777")
+	if resp.Code != 200 {
+		t.Error("Wrong status code: ", resp.Code)
+	}
+	if resp.Header().Get("Content-Type") != "application/json" {
+		t.Error("Wrong Content-Type: ", resp.Header().Get("Content-Type"))
+	}
+	var body nearResponse
+	if err := json.Unmarshal(data(t, resp), &body); err != nil {
+		t.Fatal("Response was not valid JSON: ", err)
+	}
+	if body.Query.Lat == nil || *body.Query.Lat != 45.53435699129174 || body.Query.Lng == nil {
+		t.Error("Response had the wrong query: ", resp.Body.String())
+	}
+	if len(body.Locations) != 14 {
+		t.Error("Response had the wrong number of locations: ", len(body.Locations))
+	}
+	for _, location := range body.Locations {
+		if location.Point.Lat == nil || location.Point.Lng == nil || len(location.Crimes) == 0 {
+			t.Error("Location is missing fields")
+		}
+		for _, crime := range location.Crimes {
+			if crime.Id == nil || crime.Date == nil || crime.Time == nil || crime.Type == nil {
+				t.Error("Crime is missing fields")
+			}
+		}
+	}
+}
+
+func TestCrimesNearExplain(t *testing.T) {
+	resp := get(t, "/crimes/near/45.53435699129174/-122.66469510763777?explain=true")
+	var body struct {
+		Explain *struct {
+			Index        *string
+			NodesVisited *int `json:"nodes_visited"`
+			Candidates   *int
+			Results      *int
+			Cache        *string
+			Timings      []struct {
+				Phase *string
+				Ms    *float64
+			}
+		}
+	}
+	if err := json.Unmarshal(data(t, resp), &body); err != nil {
+		t.Fatal("Response was not valid JSON: ", err)
+	}
+	e := body.Explain
+	if e == nil || e.Index == nil || e.NodesVisited == nil || e.Candidates == nil || e.Results == nil || e.Cache == nil {
+		t.Fatal("Response is missing explain fields: ", resp.Body.String())
+	}
+	if *e.Results != 14 || len(e.Timings) == 0 {
+		t.Error("Response has the wrong explanation: ", resp.Body.String())
+	}
+
+	resp = get(t, "/crimes/near/45.53435699129174/-122.66469510763777")
+	if strings.Contains(resp.Body.String(), `"explain"`) {
+		t.Error("Response should only explain the query when asked")
+	}
+}
+
+func TestCrimesNearCategory(t *testing.T) {
+	resp := get(t, "/crimes/near/45.53435699129174/-122.66469510763777?category=society")
+	if resp.Code != 200 {
+		t.Error("Wrong status code: ", resp.Code)
+	}
+	var body nearResponse
+	if err := json.Unmarshal(data(t, resp), &body); err != nil {
+		t.Fatal("Response was not valid JSON: ", err)
+	}
+	if len(body.Locations) == 0 {
+		t.Error("Response should have had locations")
+	}
+	for _, location := range body.Locations {
+		for _, crime := range location.Crimes {
+			if radar.Classify(*crime.Type).Category != radar.SocietyCategory {
+				t.Error("Response has a crime in the wrong category: ", *crime.Type)
+			}
+		}
+	}
+	resp = get(t, "/crimes/near/45.53435699129174/-122.66469510763777?category=nope")
+	if resp.Code != 400 {
+		t.Error("Wrong status code for an unknown category: ", resp.Code)
+	}
+}
+
+func TestCrimesNearAttributes(t *testing.T) {
+	// The legacy test data has no attributes, so a filter on one excludes
+	// every crime.
+	resp := get(t, "/crimes/near/45.53435699129174/-122.66469510763777?arrest=yes")
+	if resp.Code != 200 {
+		t.Error("Wrong status code: ", resp.Code)
+	}
+	var body nearResponse
+	if err := json.Unmarshal(data(t, resp), &body); err != nil {
+		t.Fatal("Response was not valid JSON: ", err)
+	}
+	if len(body.Locations) != 0 {
+		t.Error("Response should not have had any locations")
+	}
+	resp = get(t, "/crimes/near/45.53435699129174/-122.66469510763777?domestic=sometimes")
+	if resp.Code != 400 {
+		t.Error("Wrong status code for an invalid flag: ", resp.Code)
+	}
+}
+
+func TestCrimesNearSearchFilter(t *testing.T) {
+	resp := get(t, "/crimes/near/45.53435699129174/-122.66469510763777?type=liquor+laws&month=2011-05")
+	if resp.Code != 200 {
+		t.Error("Wrong status code: ", resp.Code)
+	}
+	var body nearResponse
+	if err := json.Unmarshal(data(t, resp), &body); err != nil {
+		t.Fatal("Response was not valid JSON: ", err)
+	}
+	if len(body.Locations) == 0 {
+		t.Error("Response should have had locations")
+	}
+	for _, location := range body.Locations {
+		for _, crime := range location.Crimes {
+			if *crime.Type != "Liquor Laws" || !strings.HasPrefix(*crime.Date, "05/") {
+				t.Error("Response has a crime that doesn't match the filter: ", *crime.Type, *crime.Date)
+			}
+		}
+	}
+	resp = get(t, "/crimes/near/45.53435699129174/-122.66469510763777?type=homicide&explain=true")
+	if !strings.Contains(resp.Body.String(), `"plan":"attributes"`) || !strings.Contains(resp.Body.String(), `"estimates":{`) {
+		t.Error("Explanation should show the plan of a filtered search: ", resp.Body.String())
+	}
+	resp = get(t, "/crimes/near/45.53435699129174/-122.66469510763777?month=May")
+	if resp.Code != 400 {
+		t.Error("Wrong status code for an invalid month: ", resp.Code)
+	}
+}
+
+func TestCrimesNearOptions(t *testing.T) {
+	resp := get(t, "/crimes/near/45.53435699129174/-122.66469510763777?radius=0.2&sort=distance&limit=2&max_per_location=1&include_distance=true")
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	var body struct {
+		Meta struct {
+			Query map[string]interface{} `json:"query"`
+		} `json:"meta"`
+		Data struct {
+			Locations []struct {
+				DistanceMiles *float64          `json:"distance_miles"`
+				Crimes        []json.RawMessage `json:"crimes"`
+			} `json:"locations"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal("Response was not valid JSON: ", err)
+	}
+	if len(body.Data.Locations) != 2 {
+		t.Fatal("Wrong number of locations: ", len(body.Data.Locations))
+	}
+	for i, location := range body.Data.Locations {
+		if location.DistanceMiles == nil || *location.DistanceMiles > 0.2 || len(location.Crimes) != 1 {
+			t.Error("Wrong location: ", location.DistanceMiles, len(location.Crimes))
+		}
+		if i > 0 && *location.DistanceMiles < *body.Data.Locations[i-1].DistanceMiles {
+			t.Error("Locations should be nearest first")
+		}
+	}
+	if body.Meta.Query["radius"] != 0.2 || body.Meta.Query["sort"] != "distance" || body.Meta.Query["limit"] != 2.0 {
+		t.Error("Envelope should describe the options: ", body.Meta.Query)
+	}
+	for _, params := range []string{"radius=0", "limit=none", "max_per_location=0", "sort=alphabetical"} {
+		if resp := get(t, "/crimes/near/45.53435699129174/-122.66469510763777?"+params); resp.Code != 400 {
+			t.Error("Wrong status code for invalid options: ", params, resp.Code)
+		}
+	}
+	// A radius further than a search can reach is over the limit, and is
+	// told what to ask for.
+	resp = get(t, "/crimes/near/45.53435699129174/-122.66469510763777?radius=6")
+	if resp.Code != 422 || !strings.Contains(resp.Body.String(), "Ask for a radius of at most 5 miles.") {
+		t.Error("Wrong response for a radius over the limit: ", resp.Code, resp.Body.String())
+	}
+}
+
+func TestEmptyDataset(t *testing.T) {
+	defer func() {
+		*ingest = false
+		finder = sample.NewFinder()
+		updateDatasetVersion()
+	}()
+	*ingest = true
+	finder = radar.NewEmptyCrimeFinder(radar.LoadOptions{})
+	updateDatasetVersion()
+	for _, url := range []string{"/crimes/near/45.531/-122.661", "/crimes/near/45.531/-122.661/recent", "/meta/bounds", "/stats/anomalies", "/meta/nearest-neighbors"} {
+		if resp := get(t, url); resp.Code != 200 {
+			t.Error("Wrong status code for an empty dataset: ", url, resp.Code)
+		}
+	}
+	body := "99000001,12/31/2011,23:00:00,Burglary,,,,,45.531,-122.661\n"
+	if resp := request(t, "POST", "/crimes", body); resp.Code != 201 {
+		t.Fatal("Wrong status code for an ingest: ", resp.Code)
+	}
+	var near nearResponse
+	if err := json.Unmarshal(data(t, get(t, "/crimes/near/45.531/-122.661")), &near); err != nil || len(near.Locations) != 1 {
+		t.Error("The ingested crime should be found: ", near.Locations, err)
+	}
+}
+
+func TestCrimeById(t *testing.T) {
+	expected := finder.Locations()[0].Crimes[0]
+	resp := get(t, fmt.Sprintf("/crimes/%v", expected.Id))
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	var body nearResponse
+	if err := json.Unmarshal(data(t, resp), &body); err != nil {
+		t.Fatal("Response was not valid JSON: ", err)
+	}
+	if len(body.Locations) != 1 || len(body.Locations[0].Crimes) != 1 || *body.Locations[0].Crimes[0].Id != expected.Id {
+		t.Error("Response did not have the crime: ", resp.Body.String())
+	}
+	resp = get(t, "/crimes/99999999999")
+	if resp.Code != 404 {
+		t.Error("Wrong status code for a missing crime: ", resp.Code)
+	}
+}
+
+func TestFieldNaming(t *testing.T) {
+	resp := get(t, "/meta/nearest-neighbors?case=camel")
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	if !strings.Contains(resp.Body.String(), `"percentilesMiles"`) || strings.Contains(resp.Body.String(), "_miles") {
+		t.Error("Response does not have camelCase fields: ", resp.Body.String())
+	}
+	resp = get(t, "/meta/nearest-neighbors?case=snake")
+	if !strings.Contains(resp.Body.String(), `"percentiles_miles"`) {
+		t.Error("Response does not have snake_case fields: ", resp.Body.String())
+	}
+	resp = get(t, "/meta/nearest-neighbors?case=kebab")
+	if resp.Code != 400 {
+		t.Error("Wrong status code for an unknown naming: ", resp.Code)
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	resp := get(t, "/crimes/near/45.53435699129174/-122.66469510763777?category=society&arrest=false")
+	var body envelopeResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal("Response was not valid JSON: ", err)
+	}
+	query := body.Meta.Query
+	if query["lat"] != 45.53435699129174 || query["category"] != "society" || query["arrest"] != false {
+		t.Error("Envelope has the wrong query: ", query)
+	}
+	if body.Meta.TookMs == nil || body.Meta.Count == nil || *body.Meta.Count != 0 {
+		t.Error("Envelope is missing the time or count: ", resp.Body.String())
+	}
+
+	resp = get(t, "/meta/nearest-neighbors")
+	body = envelopeResponse{}
+	json.Unmarshal(resp.Body.Bytes(), &body)
+	if body.Meta.Query["bucket_miles"] != DEFAULT_BUCKET_MILES {
+		t.Error("Envelope should have the default bucket size: ", body.Meta.Query)
+	}
+}
+
+func TestDatasetVersion(t *testing.T) {
+	resp := get(t, "/crimes/near/45.53435699129174/-122.66469510763777")
+	var body struct {
+		Meta struct {
+			DatasetVersion string `json:"dataset_version"`
+		}
+	}
+	json.Unmarshal(resp.Body.Bytes(), &body)
+	version := body.Meta.DatasetVersion
+	if version == "" || version != finder.Version() {
+		t.Fatal("Envelope has the wrong dataset version: ", resp.Body.String())
+	}
+	if resp := get(t, "/crimes/near/45.53435699129174/-122.66469510763777?if_version="+version); resp.Code != 200 {
+		t.Error("Wrong status code for the current version: ", resp.Code)
+	}
+	if resp := get(t, "/meta/bounds?if_version=stale"); resp.Code != 409 {
+		t.Error("Wrong status code for an old version: ", resp.Code)
+	}
+}
+
+func TestEnvelopeOptOut(t *testing.T) {
+	resp := get(t, "/crimes/near/45.53435699129174/-122.66469510763777?envelope=false")
+	var body nearResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal("Response was not valid JSON: ", err)
+	}
+	if len(body.Locations) == 0 || strings.Contains(resp.Body.String(), `"meta"`) {
+		t.Error("Response should not have an envelope: ", resp.Body.String())
+	}
+}
+
+func TestCrimesNearNoResults(t *testing.T) {
+	resp := get(t, "/crimes/near/10.0/10.0")
+	if resp.Code != 200 {
+		t.Error("Wrong status code: ", resp.Code)
+	}
+	var body nearResponse
+	if err := json.Unmarshal(data(t, resp), &body); err != nil {
+		t.Fatal("Response was not valid JSON: ", err)
+	}
+	if len(body.Locations) != 0 {
+		t.Error("Response should not have had any locations")
+	}
+}
+
+func TestCrimesNearBadCoordinates(t *testing.T) {
+	for _, url := range []string{"/crimes/near/95.1/-122.6554", "/crimes/near/45.5/-181"} {
+		resp := get(t, url)
+		if resp.Code != 400 {
+			t.Error("Wrong status code for ", url, ": ", resp.Code)
+		}
+	}
+}
+
+func TestMetaBounds(t *testing.T) {
+	resp := get(t, "/meta/bounds")
+	if resp.Code != 200 {
+		t.Error("Wrong status code: ", resp.Code)
+	}
+	if resp.Header().Get("Content-Type") != "application/json" {
+		t.Error("Wrong Content-Type: ", resp.Header().Get("Content-Type"))
+	}
+	var body struct {
+		Bounds *struct {
+			Min *struct{ Lat, Lng *float64 }
+			Max *struct{ Lat, Lng *float64 }
+		}
+		Centroid *struct{ Lat, Lng *float64 }
+		Dates    *struct{ First, Last *string }
+		Crimes   *int
+		Density  *struct {
+			CrimesPerSquareMile *float64 `json:"crimes_per_square_mile"`
+		}
+	}
+	if err := json.Unmarshal(data(t, resp), &body); err != nil {
+		t.Fatal("Response was not valid JSON: ", err)
+	}
+	if body.Bounds == nil || body.Bounds.Min == nil || body.Bounds.Max == nil || body.Centroid == nil {
+		t.Error("Response is missing the bounds or centroid: ", resp.Body.String())
+	}
+	if body.Dates == nil || *body.Dates.First != "01/01/2011" || body.Crimes == nil || body.Density == nil {
+		t.Error("Response is missing the dates, count or density: ", resp.Body.String())
+	}
+}
+
+func TestMetaNearestNeighbors(t *testing.T) {
+	resp := get(t, "/meta/nearest-neighbors?bucket_miles=0.1")
+	if resp.Code != 200 {
+		t.Error("Wrong status code: ", resp.Code)
+	}
+	var body struct {
+		Count       int
+		Percentiles map[string]float64 `json:"percentiles_miles"`
+		Buckets     []struct {
+			Min   float64 `json:"min_miles"`
+			Max   float64 `json:"max_miles"`
+			Count int
+		}
+	}
+	if err := json.Unmarshal(data(t, resp), &body); err != nil {
+		t.Fatal("Response was not valid JSON: ", err)
+	}
+	if body.Count != 224 || len(body.Buckets) == 0 || body.Buckets[0].Max != 0.1 {
+		t.Error("Response has the wrong histogram: ", resp.Body.String())
+	}
+	if _, ok := body.Percentiles["p50"]; !ok {
+		t.Error("Response is missing the median: ", resp.Body.String())
+	}
+	for _, value := range []string{"0", "-1", "abc", "NaN", "Inf"} {
+		resp := get(t, "/meta/nearest-neighbors?bucket_miles="+value)
+		if resp.Code != 400 {
+			t.Error("Wrong status code for bucket_miles=", value, ": ", resp.Code)
+		}
+	}
+}
+
+func TestUnknownRoute(t *testing.T) {
+	for _, url := range []string{"/crimes/far/45.5/-122.6", "/crimes/near/45x5184/-122.6554"} {
+		resp := get(t, url)
+		if resp.Code != 404 {
+			t.Error("Wrong status code for ", url, ": ", resp.Code)
+		}
+	}
+}
+
+func TestParsePoint(t *testing.T) {
+	point, err := parsePoint("45.5184", "-122.6554")
+	if err != nil {
+		t.Error("parsePoint returned an error: ", err)
+	}
+	if point.Lat != 45.5184 || point.Lng != -122.6554 {
+		t.Error("parsePoint returned the wrong point: ", point)
+	}
+}
+
+func TestParsePointBadValues(t *testing.T) {
+	bad := [][]string{
+		{"45x5184", "-122.6554"},
+		{"45.5184", "-122.6554z"},
+		{"", ""},
+		{"91", "-122.6554"},
+		{"45.5184", "-181"},
+		{"NaN", "-122.6554"},
+	}
+	for _, values := range bad {
+		_, err := parsePoint(values[0], values[1])
+		if err == nil {
+			t.Error("parsePoint should have returned an error for: ", values)
+		}
+	}
+}
+
+func FuzzParsePoint(f *testing.F) {
+	f.Add("45.5184", "-122.6554")
+	f.Add("45x5184", "-122.6554")
+	f.Add("1e400", "-0")
+	f.Add("NaN", "Inf")
+	f.Fuzz(func(t *testing.T, lat string, lng string) {
+		point, err := parsePoint(lat, lng)
+		if err != nil {
+			return
+		}
+		if math.IsNaN(point.Lat) || math.Abs(point.Lat) > 90 || math.Abs(point.Lng) > 180 {
+			t.Error("parsePoint accepted an invalid point: ", point)
+		}
+	})
+}
+
+func TestCrimesNearConcurrentRequests(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				resp := get(t, "/crimes/near/45.53435699129174/-122.66469510763777")
+				if resp.Code != 200 {
+					t.Error("Wrong status code from a goroutine: ", resp.Code)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestProgressPrinter(t *testing.T) {
+	var out bytes.Buffer
+	printProgress := newProgressPrinter(&out)
+	printProgress(radar.LoadProgress{Phase: radar.ReadPhase, Bytes: 50, TotalBytes: 100, Rows: 10, Elapsed: time.Second})
+	printProgress(radar.LoadProgress{Phase: radar.ReadPhase, Bytes: 100, TotalBytes: 100, Rows: 20, Elapsed: 2 * time.Second, Done: true})
+	printProgress(radar.LoadProgress{Phase: radar.IndexPhase, Elapsed: 1500 * time.Microsecond, Done: true})
+	expected := "\rReading data:  50% (10 lines, 10 lines/sec)" +
+		"\rReading data: 100% (20 rows, 10 rows/sec)\n" +
+		"Read 20 rows in 2s\n" +
+		"Built the index in 2ms\n"
+	if out.String() != expected {
+		t.Errorf("Wrong progress output: %q", out.String())
+	}
+}
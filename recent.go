@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// DEFAULT_RECENT_LIMIT is the number of crimes /crimes/near/{lat}/{lng}/recent
+// lists unless it's given a limit.
+const DEFAULT_RECENT_LIMIT = 50
+
+// recentHandler lists the crimes near a point newest first, across every
+// location, with the limit parameter's number of crimes. It takes the
+// filters /crimes/near does.
+func recentHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	query, err := parsePoint(vars["lat"], vars["lng"])
+	if err != nil {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	search, err := parseNearSearch(r)
+	if err != nil {
+		writeSearchError(w, err)
+		return
+	}
+	// The feed's limit is of crimes, not locations.
+	search.Limit = 0
+	limit := DEFAULT_RECENT_LIMIT
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			http.Error(w, http.StatusText(400), 400)
+			return
+		}
+	}
+	if err := limits.checkResults(limit); err != nil {
+		writeLimitError(w, err)
+		return
+	}
+	tracker.Record(query.Lat, query.Lng, time.Now())
+	nearby, err := finderFor(r).FindNearWithOptions(query, search)
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	queries.Record(finderFor(r), query, search)
+	nearby.BaseURL = *baseURL
+	recent := nearby.Recent(limit)
+	resp, err := recent.ToJson()
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	applied := appliedSearch(query, search)
+	applied["limit"] = limit
+	count := len(recent.Crimes)
+	writeJson(w, r, resp, responseMeta{Query: applied, Count: &count})
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRecent(t *testing.T) {
+	resp := get(t, "/crimes/near/45.5184/-122.6554/recent?limit=5&category=property")
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	var recent struct {
+		Total  int
+		Crimes []struct {
+			Id       int64
+			Date     string
+			Category string
+		}
+	}
+	json.Unmarshal(data(t, resp), &recent)
+	if len(recent.Crimes) != 5 || recent.Total <= 5 {
+		t.Fatal("Wrong number of recent crimes: ", resp.Body.String())
+	}
+	for _, crime := range recent.Crimes {
+		if crime.Category != "property" {
+			t.Error("Recent crimes should be filtered: ", crime)
+		}
+	}
+
+	if resp := get(t, "/crimes/near/45.5184/-122.6554/recent"); resp.Code != 200 {
+		t.Error("Wrong status code without a limit: ", resp.Code)
+	}
+	for _, limit := range []string{"0", "-1", "many"} {
+		if resp := get(t, "/crimes/near/45.5184/-122.6554/recent?limit="+limit); resp.Code != 400 {
+			t.Error("Wrong status code for limit ", limit, ": ", resp.Code)
+		}
+	}
+	if resp := get(t, "/crimes/near/45.5184/-122.6554/recent?limit=1000000"); resp.Code != 422 {
+		t.Error("A limit over the most results should be refused: ", resp.Code)
+	}
+}
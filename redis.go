@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/abrookins/radar/alerts"
+	"github.com/abrookins/radar/crimes"
+	"github.com/abrookins/radar/internal/redis"
+)
+
+var redisAddr = flag.String("redis", "", "host:port of a Redis server to share geofences and cached responses with other instances; the password is read from REDIS_PASSWORD")
+var redisPrefix = flag.String("redis-prefix", "radar:", "prefix of the keys the server uses in Redis")
+var redisTimeout = flag.Duration("redis-timeout", redis.DEFAULT_TIMEOUT, "how long a command to -redis has to get a reply before a search goes on without the cache")
+var redisStoreTimeout = flag.Duration("redis-store-timeout", 5*time.Second, "how long a command to -redis has to get a reply before a change to the geofences fails")
+var redisPoolSize = flag.Int("redis-pool-size", redis.DEFAULT_POOL_SIZE, "most connections to -redis that are open at once")
+var cacheTTL = flag.Duration("cache-ttl", 5*time.Minute, "how long responses are cached in Redis")
+
+// newRedisClient creates a client for the -redis server whose commands
+// fail after timeout.
+func newRedisClient(timeout time.Duration) *redis.Client {
+	client := redis.NewClient(*redisAddr)
+	client.Password = os.Getenv("REDIS_PASSWORD")
+	client.Timeout = timeout
+	client.PoolSize = *redisPoolSize
+	return client
+}
+
+// openStore opens the geofence store named by the flags: in Redis if there
+// is a -redis server, or else in the -geofences file. The store has its own
+// client, since a change to the geofences is worth waiting longer for than
+// a cached response.
+func openStore() (*alerts.Store, error) {
+	if *redisAddr != "" {
+		return alerts.NewStoreWithBackend(newRedisBackend(newRedisClient(*redisStoreTimeout), *redisPrefix+"geofences"))
+	}
+	return alerts.NewStore(*geofencesFilename)
+}
+
+// keyValues is the part of a Redis client that the server uses. Tests use
+// a map instead.
+type keyValues interface {
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Incr(key string) (int64, error)
+	SetIfRevision(key string, value []byte, revision string, expected int64) (int64, error)
+}
+
+// A redisBackend keeps geofences in a Redis key, shared by every instance
+// of the server. Another key counts the saves, so instances can tell when
+// the geofences have changed without loading them, and so a save fails
+// instead of overwriting one made since this instance loaded them.
+type redisBackend struct {
+	client   keyValues
+	key      string
+	revision string
+	// seen is the revision this instance last loaded or saved.
+	seen int64
+}
+
+func newRedisBackend(client keyValues, key string) *redisBackend {
+	return &redisBackend{client: client, key: key, revision: key + ":revision"}
+}
+
+// currentRevision returns the number of times the geofences have been
+// saved.
+func (backend *redisBackend) currentRevision() (int64, error) {
+	value, err := backend.client.Get(backend.revision)
+	if err != nil || value == nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(value), 10, 64)
+}
+
+func (backend *redisBackend) Load() ([]byte, error) {
+	revision, err := backend.currentRevision()
+	if err != nil {
+		return nil, err
+	}
+	data, err := backend.client.Get(backend.key)
+	if err != nil {
+		return nil, err
+	}
+	atomic.StoreInt64(&backend.seen, revision)
+	return data, nil
+}
+
+func (backend *redisBackend) Save(data []byte) error {
+	revision, err := backend.client.SetIfRevision(backend.key, data, backend.revision, atomic.LoadInt64(&backend.seen))
+	if err == redis.ErrAborted {
+		return alerts.ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&backend.seen, revision)
+	return nil
+}
+
+func (backend *redisBackend) Changed() (bool, error) {
+	revision, err := backend.currentRevision()
+	return revision != atomic.LoadInt64(&backend.seen), err
+}
+
+// A responseCache keeps encoded search results in Redis, so an instance
+// can answer a query that another instance has already answered. Keys
+// include the fingerprint of the data, so instances with different data
+// don't share results.
+type responseCache struct {
+	client keyValues
+	prefix string
+	ttl    time.Duration
+}
+
+// responses caches search results, if the server has a Redis server.
+var responses *responseCache
+
+// key returns the key of the results of a search with query for finder.
+func (cache *responseCache) key(finder *radar.CrimeFinder, query map[string]interface{}) string {
+	encoded, _ := json.Marshal(query)
+	return cache.prefix + "near:" + finder.Fingerprint() + ":" + string(encoded)
+}
+
+// get returns the cached results of a search and their count, and true if
+// there were any. Failing to reach Redis in time is logged and returned as
+// an error, so that the search can go on without the cache.
+func (cache *responseCache) get(key string) ([]byte, int, bool, error) {
+	value, err := cache.client.Get(key)
+	if err != nil {
+		log.Println("Could not read the response cache. ", err)
+		return nil, 0, false, err
+	}
+	// Values are the count, a newline and the results.
+	newline := bytes.IndexByte(value, '\n')
+	if newline < 0 {
+		return nil, 0, false, nil
+	}
+	count, err := strconv.Atoi(string(value[:newline]))
+	if err != nil {
+		return nil, 0, false, nil
+	}
+	return value[newline+1:], count, true, nil
+}
+
+// set caches the results of a search and their count.
+func (cache *responseCache) set(key string, results []byte, count int) {
+	value := append([]byte(strconv.Itoa(count)+"\n"), results...)
+	if err := cache.client.Set(key, value, cache.ttl); err != nil {
+		log.Println("Could not write the response cache. ", err)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abrookins/radar/alerts"
+	"github.com/abrookins/radar/internal/redis"
+)
+
+// mapKeyValues is keyValues in memory.
+type mapKeyValues struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func newMapKeyValues() *mapKeyValues {
+	return &mapKeyValues{values: make(map[string][]byte)}
+}
+
+func (kv *mapKeyValues) Get(key string) ([]byte, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	return kv.values[key], nil
+}
+
+func (kv *mapKeyValues) Set(key string, value []byte, ttl time.Duration) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.values[key] = value
+	return nil
+}
+
+func (kv *mapKeyValues) Incr(key string) (int64, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	n, _ := strconv.ParseInt(string(kv.values[key]), 10, 64)
+	kv.values[key] = []byte(strconv.FormatInt(n+1, 10))
+	return n + 1, nil
+}
+
+func (kv *mapKeyValues) SetIfRevision(key string, value []byte, revision string, expected int64) (int64, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	n, _ := strconv.ParseInt(string(kv.values[revision]), 10, 64)
+	if n != expected {
+		return 0, redis.ErrAborted
+	}
+	kv.values[key] = value
+	kv.values[revision] = []byte(strconv.FormatInt(n+1, 10))
+	return n + 1, nil
+}
+
+// slowKeyValues is keyValues for a Redis that never answers in time.
+type slowKeyValues struct {
+	sets int
+}
+
+func (kv *slowKeyValues) Get(key string) ([]byte, error) {
+	return nil, redis.ErrPoolTimeout
+}
+
+func (kv *slowKeyValues) Set(key string, value []byte, ttl time.Duration) error {
+	kv.sets += 1
+	return redis.ErrPoolTimeout
+}
+
+func (kv *slowKeyValues) Incr(key string) (int64, error) {
+	return 0, redis.ErrPoolTimeout
+}
+
+func (kv *slowKeyValues) SetIfRevision(key string, value []byte, revision string, expected int64) (int64, error) {
+	return 0, redis.ErrPoolTimeout
+}
+
+func TestRedisBackend(t *testing.T) {
+	kv := newMapKeyValues()
+	first, _ := alerts.NewStoreWithBackend(newRedisBackend(kv, "radar:geofences"))
+	second, _ := alerts.NewStoreWithBackend(newRedisBackend(kv, "radar:geofences"))
+	created, err := first.Create(alerts.Geofence{
+		Center:      &alerts.Point{Lat: 45.53, Lng: -122.66},
+		RadiusMiles: 0.5,
+		Target:      alerts.Target{Webhook: "http://localhost/hook"},
+	})
+	if err != nil {
+		t.Fatal("Error creating a geofence: ", err)
+	}
+	if found, err := second.Get(created.Id); err != nil || found.Id != created.Id {
+		t.Error("Instances should share geofences: ", err)
+	}
+
+	// Both instances change the geofences without seeing each other's
+	// change first, and neither change is lost.
+	stale := newRedisBackend(kv, "radar:geofences")
+	third, _ := alerts.NewStoreWithBackend(stale)
+	if _, err := second.Create(alerts.Geofence{Polygon: created.Polygon, Center: created.Center, RadiusMiles: 1, Target: created.Target}); err != nil {
+		t.Fatal("Error creating a geofence: ", err)
+	}
+	if err := stale.Save([]byte(`{"version": 2}`)); err != alerts.ErrConflict {
+		t.Error("Saving over a newer revision should conflict: ", err)
+	}
+	if err := third.Delete(created.Id); err != nil {
+		t.Fatal("Error deleting a geofence: ", err)
+	}
+	if geofences := first.List(); len(geofences) != 1 || geofences[0].RadiusMiles != 1 {
+		t.Error("A change made at the same time was lost: ", geofences)
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	defer func() {
+		responses = nil
+	}()
+	kv := newMapKeyValues()
+	responses = &responseCache{client: kv, prefix: "radar:", ttl: time.Minute}
+
+	first := get(t, "/crimes/near/45.5184/-122.6554")
+	if len(kv.values) != 1 {
+		t.Fatal("Search should be cached: ", kv.values)
+	}
+	for key := range kv.values {
+		kv.values[key] = []byte("1\n{\"cached\":true}")
+	}
+	second := get(t, "/crimes/near/45.5184/-122.6554")
+	if first.Code != 200 || string(data(t, second)) != `{"cached":true}` {
+		t.Error("Search should come from the cache: ", second.Body.String())
+	}
+	explained := get(t, "/crimes/near/45.5184/-122.6554?explain=true")
+	if string(data(t, explained)) == `{"cached":true}` || len(kv.values) != 1 {
+		t.Error("Explained searches should not be cached")
+	}
+}
+
+func TestResponseCacheTimeout(t *testing.T) {
+	defer func() {
+		responses = nil
+	}()
+	kv := &slowKeyValues{}
+	responses = &responseCache{client: kv, prefix: "radar:", ttl: time.Minute}
+
+	resp := get(t, "/crimes/near/45.5184/-122.6554")
+	if resp.Code != 200 || !strings.Contains(string(data(t, resp)), `"locations"`) {
+		t.Error("A search should go on without a cache that times out: ", resp.Code)
+	}
+	if kv.sets != 0 {
+		t.Error("Results should not be cached when the cache can't be read")
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// The number of changed queries a replay lists.
+const REPLAY_LIST_LIMIT = 20
+
+var errReplayUsage = errors.New("usage: radar [flags] replay queries.log data.csv")
+
+// A replayError reports how many replayed queries found a different number
+// of crimes than they did when they were logged.
+type replayError struct {
+	changed int
+}
+
+func (err *replayError) Error() string {
+	return fmt.Sprintf("%v replayed queries changed", err.changed)
+}
+
+// runReplay loads the data file named in args with the options the flags
+// ask for, runs the searches of the query log named in args against it and
+// writes a report to w of those whose result counts differ from the log's.
+// It returns a *replayError if any do, so that a build that changes search
+// results fails.
+func runReplay(args []string, w io.Writer) error {
+	if len(args) != 2 {
+		return errReplayUsage
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	loaded, err := loadFile(args[1], loadOptions())
+	if err != nil {
+		return err
+	}
+
+	total, changed := 0, 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var query loggedQuery
+		if err := json.Unmarshal(scanner.Bytes(), &query); err != nil {
+			return fmt.Errorf("invalid query on line %v of %v: %v", total+1, args[0], err)
+		}
+		total += 1
+		count, err := replayQuery(&loaded, query)
+		if err == nil && count == query.Count {
+			continue
+		}
+		changed += 1
+		if changed > REPLAY_LIST_LIMIT {
+			continue
+		}
+		if changed == 1 {
+			fmt.Fprintln(w, "Changed queries:")
+		}
+		if err != nil {
+			fmt.Fprintf(w, "  %v: %v -> %v\n", describeQuery(query), query.Count, err)
+		} else {
+			fmt.Fprintf(w, "  %v: %v -> %v (%+d)\n", describeQuery(query), query.Count, count, count-query.Count)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if changed > REPLAY_LIST_LIMIT {
+		fmt.Fprintf(w, "  and %v more\n", changed-REPLAY_LIST_LIMIT)
+	}
+	if changed > 0 {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "Replayed %v queries against %v (%v crimes): %v changed\n", total, args[1], loaded.Report.Crimes, changed)
+	if changed > 0 {
+		return &replayError{changed}
+	}
+	return nil
+}
+
+// replayQuery runs a logged search against searched, with the options it
+// was logged with, and returns the number of crimes it finds.
+func replayQuery(searched *radar.CrimeFinder, query loggedQuery) (int, error) {
+	if err := query.Options.Validate(); err != nil {
+		return 0, err
+	}
+	point, err := radar.NewPoint(radar.Latitude(query.Lat), radar.Longitude(query.Lng))
+	if err != nil {
+		return 0, err
+	}
+	nearby, err := searched.FindNearWithOptions(point, query.Options)
+	if err != nil {
+		return 0, err
+	}
+	return len(nearby.Crimes()), nil
+}
+
+// describeQuery returns the point and options of a logged search, like
+// "45.518,-122.655 type=Larceny".
+func describeQuery(query loggedQuery) string {
+	parts := []string{fmt.Sprintf("%v,%v", query.Lat, query.Lng)}
+	params := searchParams(query.Options)
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%v=%v", name, params.Get(name)))
+	}
+	return strings.Join(parts, " ")
+}
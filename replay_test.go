@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+func TestRunReplay(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "queries.log")
+	count, _ := replayQuery(&finder, loggedQuery{Lat: 45.518, Lng: -122.655, Options: radar.SearchOptions{}})
+	log := `{"lat":45.518,"lng":-122.655,"options":{},"count":` + strconv.Itoa(count) + `}
+{"lat":45.534,"lng":-122.665,"options":{"filters":{"category":"society"}},"count":1000}
+`
+	os.WriteFile(filename, []byte(log), 0644)
+	out := new(bytes.Buffer)
+	err := runReplay([]string{filename, "crimes/sample/crimes.csv"}, out)
+	if replayErr, ok := err.(*replayError); !ok || replayErr.changed != 1 {
+		t.Fatal("One replayed query should have changed: ", err)
+	}
+	if !strings.Contains(out.String(), "45.534,-122.665 category=society: 1000 -> ") || !strings.Contains(out.String(), "Replayed 2 queries") {
+		t.Error("Wrong replay report: ", out.String())
+	}
+
+	if err := runReplay([]string{filename}, out); err != errReplayUsage {
+		t.Error("Replay needs a log and a data file: ", err)
+	}
+}
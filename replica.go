@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+var publishSnapshots = flag.Bool("publish-snapshots", false, "serve snapshots of the data at /snapshot for replicas")
+var replicateFrom = flag.String("replicate-from", "", "URL of a snapshot to load and keep polling, like a primary's /snapshot, instead of loading a data file")
+var replicateInterval = flag.Duration("replicate-interval", time.Minute, "how often a replica checks for a new snapshot")
+
+// The time a replica has to download a snapshot.
+const REPLICATE_TIMEOUT = 5 * time.Minute
+
+// A publication is an encoded snapshot of one version of the data.
+type publication struct {
+	version string
+	data    []byte
+	sum     string
+}
+
+// published is the latest snapshot a primary has encoded. It's encoded
+// again when a replica asks for it after the data has changed.
+var published struct {
+	sync.Mutex
+	publication
+}
+
+// snapshotVersion returns the ETag of a version of the data.
+func snapshotVersion(fingerprint string) string {
+	return `"` + fingerprint + `"`
+}
+
+// latestPublication returns the snapshot of the current data, encoding it
+// if the data has changed since it was last encoded. The caller must hold
+// a read lock on the finder.
+func latestPublication() (publication, error) {
+	published.Lock()
+	defer published.Unlock()
+	version := snapshotVersion(finder.Fingerprint())
+	if published.version == version {
+		return published.publication, nil
+	}
+	var buf bytes.Buffer
+	if err := writeSnapshot(&finder, &buf); err != nil {
+		return publication{}, err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	published.publication = publication{version, buf.Bytes(), hex.EncodeToString(sum[:])}
+	return published.publication, nil
+}
+
+// snapshotHandler serves a snapshot of the data. Replicas send the ETag of
+// the version they have in If-None-Match, and get a 304 until it changes.
+func snapshotHandler(w http.ResponseWriter, r *http.Request) {
+	latest, err := latestPublication()
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	w.Header().Set("ETag", latest.version)
+	if r.Header.Get("If-None-Match") == latest.version {
+		w.WriteHeader(304)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("X-Snapshot-Sha256", latest.sum)
+	w.Write(latest.data)
+}
+
+var errNotModified = errors.New("snapshot not modified")
+
+var replicaClient = &http.Client{Timeout: REPLICATE_TIMEOUT}
+
+// fetchSnapshot downloads the snapshot at url unless its ETag is still
+// version, in which case it returns errNotModified. The snapshot is
+// checked against its X-Snapshot-Sha256 header, if it has one, so it works
+// from a primary or from a blob store that serves a copy of its snapshots.
+// It returns the finder and the snapshot's ETag.
+func fetchSnapshot(url string, version string) (radar.CrimeFinder, string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return radar.CrimeFinder{}, "", err
+	}
+	if version != "" {
+		req.Header.Set("If-None-Match", version)
+	}
+	if key := os.Getenv("RADAR_API_KEY"); key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	resp, err := replicaClient.Do(req)
+	if err != nil {
+		return radar.CrimeFinder{}, "", err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == 304:
+		return radar.CrimeFinder{}, version, errNotModified
+	case resp.StatusCode != 200:
+		return radar.CrimeFinder{}, "", fmt.Errorf("snapshot request returned %v", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return radar.CrimeFinder{}, "", err
+	}
+	if expected := resp.Header.Get("X-Snapshot-Sha256"); expected != "" {
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != expected {
+			return radar.CrimeFinder{}, "", errors.New("snapshot does not match its checksum")
+		}
+	}
+	loaded, err := radar.ReadSnapshot(bytes.NewReader(data))
+	if err != nil {
+		return loaded, "", err
+	}
+	return loaded, resp.Header.Get("ETag"), nil
+}
+
+// replicate polls url for new snapshots every interval, forever, starting
+// from version, and stages each new one to be swapped in for the finder.
+// Searches see either the old data or the new, never a mix.
+func replicate(url string, version string, interval time.Duration) {
+	for range time.Tick(interval) {
+		loaded, latest, err := fetchSnapshot(url, version)
+		if err == errNotModified {
+			continue
+		}
+		if err != nil {
+			log.Println("Could not replicate a snapshot. ", err)
+			continue
+		}
+		// A version that isn't promoted isn't fetched again; the next one
+		// will be.
+		version = latest
+		// Replicas don't alert about changes, since the primary does.
+		if result := stageFinder(loaded, false); result.Promoted {
+			log.Printf("Replicated %v crimes from version %v", loaded.Report.Crimes, version)
+		}
+	}
+}
+
+// swapFinder replaces the finder with loaded, keeping its search cache,
+// after replaying any ingested crimes it doesn't have. If alert is set,
+// geofences are alerted about crimes the new data removed or corrected.
+// The open data is published again, if the server publishes it, and the
+// refresh webhooks are told about the new data.
+func swapFinder(loaded radar.CrimeFinder, alert bool) {
+	if *warmCells > 0 {
+		loaded.EnableCache(tracker.CellSize())
+	}
+	finderLock.Lock()
+	if ingests != nil {
+		// Crimes ingested since loaded was staged are in the ingest log,
+		// and are replayed before it replaces the finder they went to.
+		if _, _, err := replayIngestLog(*ingestLogFilename, &loaded); err != nil {
+			log.Println("Could not replay the ingest log into the new data. ", err)
+		}
+	}
+	previous := finder
+	// The changes, and the summary of them, are found before loaded is
+	// published, while nothing ingests into either finder.
+	changes := loaded.Diff(&previous)
+	summary := newRefreshSummary(&previous, &loaded, changes)
+	finder = loaded
+	updateDatasetVersion()
+	finderLock.Unlock()
+	recordChanges(changes, alert)
+	trainForecasts()
+	clearRiskModel()
+	if *anomalyInterval > 0 {
+		analyzeAnomalies(alert)
+	}
+	warmCache()
+	finderLock.RLock()
+	recordHistory()
+	finderLock.RUnlock()
+	requestOpenData()
+	notifyRefresh(summary)
+}
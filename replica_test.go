@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abrookins/radar/crimes/sample"
+)
+
+func TestReplication(t *testing.T) {
+	defer func() {
+		*publishSnapshots = false
+		finder = sample.NewFinder()
+		updateDatasetVersion()
+	}()
+	*publishSnapshots = true
+	primary := httptest.NewServer(newRouter())
+	defer primary.Close()
+
+	loaded, version, err := fetchSnapshot(primary.URL+"/snapshot", "")
+	if err != nil {
+		t.Fatal("Error fetching a snapshot: ", err)
+	}
+	if loaded.Fingerprint() != finder.Fingerprint() || version != snapshotVersion(finder.Fingerprint()) {
+		t.Error("Replica has different data: ", loaded.Report.Crimes, version)
+	}
+	if _, _, err := fetchSnapshot(primary.URL+"/snapshot", version); err != errNotModified {
+		t.Error("Snapshot should not be fetched again until it changes: ", err)
+	}
+
+	*ingest = true
+	defer func() {
+		*ingest = false
+	}()
+	request(t, "POST", "/crimes", "99000001,12/31/2011,23:00:00,Burglary,,,,,45.531,-122.661\n")
+	updated, latest, err := fetchSnapshot(primary.URL+"/snapshot", version)
+	if err != nil || latest == version || updated.Report.Crimes != loaded.Report.Crimes+1 {
+		t.Fatal("Replica should fetch the new version: ", err, updated.Report.Crimes)
+	}
+
+	swapFinder(updated, false)
+	if crime, _ := finder.FindByID(99000001); crime == nil {
+		t.Error("Swapped finder should have the new crime")
+	}
+}
+
+func TestReplicationChecksum(t *testing.T) {
+	var buf bytes.Buffer
+	finder.WriteSnapshot(&buf)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Snapshot-Sha256", "0000")
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+	if _, _, err := fetchSnapshot(server.URL, ""); err == nil {
+		t.Error("fetchSnapshot should refuse a snapshot that doesn't match its checksum")
+	}
+}
+
+func TestSwapFinderWhileIngesting(t *testing.T) {
+	defer func() {
+		*ingest = false
+		finder = sample.NewFinder()
+		updateDatasetVersion()
+	}()
+	*ingest = true
+	swapped, done := make(chan bool), make(chan bool)
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-swapped:
+				return
+			default:
+			}
+			request(t, "POST", "/crimes", fmt.Sprintf("%v,12/31/2011,23:00:00,Burglary,,,,,45.531,-122.661\n", 99100000+i))
+		}
+	}()
+	// Under -race, this fails if an ingest can change the new finder while
+	// its changes are being found or summarized.
+	for i := 0; i < 5; i++ {
+		swapFinder(sample.NewFinder(), false)
+	}
+	close(swapped)
+	<-done
+}
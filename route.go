@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// The limits on the routes a request may score, so that scoring one can't
+// tie up the server.
+const (
+	MAX_ROUTE_MILES  = 100
+	MAX_ROUTE_POINTS = 5000
+)
+
+// A routeRequest is the body of a request to score a route: either an
+// encoded polyline, with its precision (5 by default), or its points.
+type routeRequest struct {
+	Polyline  string        `json:"polyline"`
+	Precision int           `json:"precision"`
+	Points    []radar.Point `json:"points"`
+}
+
+// routePoints returns the points of the route a request asks to score.
+func (request routeRequest) routePoints() ([]radar.Point, error) {
+	if request.Polyline == "" {
+		for _, point := range request.Points {
+			if _, err := radar.NewPoint(radar.Latitude(point.Lat), radar.Longitude(point.Lng)); err != nil {
+				return nil, err
+			}
+		}
+		return request.Points, nil
+	}
+	if request.Points != nil {
+		return nil, fmt.Errorf("a route is either a polyline or points")
+	}
+	precision := request.Precision
+	if precision == 0 {
+		precision = 5
+	}
+	if precision < 1 || precision > 7 {
+		return nil, fmt.Errorf("invalid precision: %v", precision)
+	}
+	return radar.DecodePolyline(request.Polyline, precision)
+}
+
+// checkRoute returns an error if a route is too long to score.
+func checkRoute(points []radar.Point) error {
+	if len(points) > MAX_ROUTE_POINTS {
+		return &limitError{"number of points", float64(len(points)), MAX_ROUTE_POINTS,
+			"Simplify the route, or score it in pieces."}
+	}
+	if miles := radar.RouteMiles(points); miles > MAX_ROUTE_MILES {
+		return &limitError{"route length in miles", miles, MAX_ROUTE_MILES, "Score the route in pieces."}
+	}
+	return nil
+}
+
+// routeScoreHandler scores each segment of a route by the risk of crime
+// along it, and the whole route by the safety of its segments.
+func routeScoreHandler(w http.ResponseWriter, r *http.Request) {
+	var request routeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	points, err := request.routePoints()
+	if err != nil || len(points) < 2 {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	if err := checkRoute(points); err != nil {
+		writeLimitError(w, err)
+		return
+	}
+	score, err := riskModelFor(r).ScoreRoute(points)
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	resp, err := score.WithPOIs(pois).ToJson()
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	count := len(score.Segments)
+	writeJson(w, r, resp, responseMeta{Count: &count})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRouteScoreHandler(t *testing.T) {
+	type scoreResponse struct {
+		Safety   float64
+		Segments []struct{ Safety float64 }
+	}
+	var polyline, points scoreResponse
+	resp := request(t, "POST", "/score/route", `{"polyline":"kfztGbgwkVkRct@_v@wQ"}`)
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	json.Unmarshal(data(t, resp), &polyline)
+	if len(polyline.Segments) != 2 || polyline.Safety <= 0 || polyline.Safety >= 100 {
+		t.Error("Wrong route score: ", polyline)
+	}
+	resp = request(t, "POST", "/score/route", `{"points":[{"lat":45.5231,"lng":-122.6765},{"lat":45.5262,"lng":-122.668},{"lat":45.535,"lng":-122.665}]}`)
+	json.Unmarshal(data(t, resp), &points)
+	if fmt.Sprint(points) != fmt.Sprint(polyline) {
+		t.Error("Points should score like the same polyline: ", points, polyline)
+	}
+	six := request(t, "POST", "/score/route", `{"polyline":"whoyuAfpq~hFw`+"`"+`EgrO_ePozD","precision":6}`)
+	json.Unmarshal(data(t, six), &points)
+	if six.Code != 200 || fmt.Sprint(points) != fmt.Sprint(polyline) {
+		t.Error("Wrong score at precision 6: ", six.Code, points)
+	}
+
+	for _, body := range []string{"", `{"polyline":"kfztG"}`, `{"points":[{"lat":45.5,"lng":-122.6}]}`, `{"points":[{"lat":95,"lng":-122.6},{"lat":45.5,"lng":-122.6}]}`, `{"polyline":"kfztGbgwkVkRct@_v@wQ","precision":9}`} {
+		if resp := request(t, "POST", "/score/route", body); resp.Code != 400 {
+			t.Error("Bad route should be a bad request: ", body, resp.Code)
+		}
+	}
+	long := `{"points":[{"lat":45.5,"lng":-122.6},{"lat":47.6,"lng":-122.3}]}`
+	if resp := request(t, "POST", "/score/route", long); resp.Code != 422 || !strings.Contains(resp.Body.String(), "route length") {
+		t.Error("A long route should be over the limit: ", resp.Code, resp.Body.String())
+	}
+}
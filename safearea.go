@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// The distance a safe area is searched for, in miles, by default and at
+// most, so that a low threshold can't send the search across the city.
+const (
+	DEFAULT_SAFE_AREA_MILES = 1
+	MAX_SAFE_AREA_MILES     = 2
+)
+
+// safeAreaHandler returns, as a GeoJSON Feature, the area around the "lat"
+// and "lng" parameters whose risk scores stay below the "threshold"
+// parameter, out to the "max_miles" parameter.
+func safeAreaHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	center, err := parsePoint(params.Get("lat"), params.Get("lng"))
+	if err != nil {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	threshold, err := strconv.ParseFloat(params.Get("threshold"), 64)
+	if err != nil || !(threshold > 0 && threshold <= 100) {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	maxMiles := float64(DEFAULT_SAFE_AREA_MILES)
+	if value := params.Get("max_miles"); value != "" {
+		maxMiles, err = strconv.ParseFloat(value, 64)
+		if err != nil || !(maxMiles > 0) {
+			http.Error(w, http.StatusText(400), 400)
+			return
+		}
+		if maxMiles > MAX_SAFE_AREA_MILES {
+			writeLimitError(w, &limitError{"max_miles", maxMiles, MAX_SAFE_AREA_MILES, "Ask for a smaller area."})
+			return
+		}
+	}
+	area, err := riskModelFor(r).SafeArea(center, threshold, maxMiles)
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	resp, err := area.WithPOIs(pois).ToGeoJson()
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	query := map[string]interface{}{"lat": center.Lat, "lng": center.Lng, "threshold": threshold, "max_miles": maxMiles}
+	writeJson(w, r, resp, responseMeta{Query: query})
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSafeAreaHandler(t *testing.T) {
+	resp := get(t, "/score/safe-area?lat=45.535&lng=-122.665&threshold=50")
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	var feature struct {
+		Geometry struct {
+			Type        string
+			Coordinates [][][]float64
+		}
+		Properties struct {
+			Max_Miles float64
+		}
+	}
+	json.Unmarshal(data(t, resp), &feature)
+	if feature.Geometry.Type != "Polygon" || feature.Properties.Max_Miles != DEFAULT_SAFE_AREA_MILES {
+		t.Error("Wrong safe area: ", feature)
+	}
+	for _, path := range []string{"?lat=45.535&threshold=50", "?lat=45.535&lng=-122.665", "?lat=45.535&lng=-122.665&threshold=0", "?lat=45.535&lng=-122.665&threshold=101", "?lat=45.535&lng=-122.665&threshold=50&max_miles=-1"} {
+		if resp := get(t, "/score/safe-area"+path); resp.Code != 400 {
+			t.Error("Bad parameters should be a bad request: ", path, resp.Code)
+		}
+	}
+	if resp := get(t, "/score/safe-area?lat=45.535&lng=-122.665&threshold=50&max_miles=5"); resp.Code != 422 {
+		t.Error("A large area should be over the limit: ", resp.Code)
+	}
+}
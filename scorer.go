@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+var scorerName = flag.String("scorer", "distance", "how risk is scored: distance, severity, recent or severity-recent, or a JSON file describing a scorer")
+
+// riskScorer is the scorer the flags ask for.
+var riskScorer = radar.DefaultScorer
+
+// riskLock guards riskModel.
+var riskLock sync.Mutex
+
+// riskModel is the RiskModel of the data being served under riskScorer, or
+// nil until it's needed. swapFinder clears it when the data changes.
+var riskModel *radar.RiskModel
+
+// loadScorer returns the scorer the flags ask for: a built-in one by name,
+// or else one described by a file.
+func loadScorer() radar.Scorer {
+	if scorer, ok := radar.Scorers[*scorerName]; ok {
+		return scorer
+	}
+	scorer, err := radar.LoadScorer(*scorerName)
+	if err != nil {
+		log.Fatal("Unknown scorer: ", err)
+	}
+	log.Println("Scoring risk with the scorer in", *scorerName)
+	return scorer
+}
+
+// riskModelFor returns the RiskModel of the data a request searches. The
+// model of the data being served is built once and kept until the data
+// changes; searches of past data build one for the request.
+func riskModelFor(r *http.Request) *radar.RiskModel {
+	searched := finderFor(r)
+	if searched != &finder {
+		return searched.NewRiskModel(riskScorer)
+	}
+	riskLock.Lock()
+	defer riskLock.Unlock()
+	if riskModel == nil {
+		riskModel = finder.NewRiskModel(riskScorer)
+	}
+	return riskModel
+}
+
+// clearRiskModel drops the RiskModel of the data being served, so that the
+// next request builds one from the new data.
+func clearRiskModel() {
+	riskLock.Lock()
+	riskModel = nil
+	riskLock.Unlock()
+}
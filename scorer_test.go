@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+func TestRiskScorer(t *testing.T) {
+	defer func() {
+		riskScorer = radar.DefaultScorer
+		clearRiskModel()
+	}()
+	route := `{"points":[{"lat":45.5231,"lng":-122.6765},{"lat":45.5262,"lng":-122.668}]}`
+	var byDistance, bySeverity struct{ Segments []struct{ Risk float64 } }
+	json.Unmarshal(data(t, request(t, "POST", "/score/route", route)), &byDistance)
+	if riskModel == nil {
+		t.Error("The risk model should be kept")
+	}
+
+	riskScorer = radar.Product{radar.DefaultScorer, radar.SeverityWeights{Types: map[string]float64{"larceny": 0}}}
+	clearRiskModel()
+	json.Unmarshal(data(t, request(t, "POST", "/score/route", route)), &bySeverity)
+	if len(bySeverity.Segments) != 1 || bySeverity.Segments[0].Risk == byDistance.Segments[0].Risk {
+		t.Error("The scorer should change the risk: ", bySeverity, byDistance)
+	}
+}
+
+func TestLoadScorer(t *testing.T) {
+	defer func(name string) { *scorerName = name }(*scorerName)
+	*scorerName = "severity"
+	if _, ok := loadScorer().(radar.Product); !ok {
+		t.Error("Built-in scorers should be found by name")
+	}
+}
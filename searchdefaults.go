@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+var searchDefaultsFilename = flag.String("search-defaults", "", "JSON file of the radius, limit, sort and excluded types of searches whose requests don't give them")
+
+// searchDefaults are the options of searches near a point whose requests
+// leave them out, so a deployment can tune its searches without changing
+// its clients.
+type searchDefaults struct {
+	Radius       float64          `json:"radius"`
+	Limit        int              `json:"limit"`
+	Sort         radar.SearchSort `json:"sort"`
+	ExcludeTypes []string         `json:"exclude_types"`
+}
+
+// defaults are the search defaults of the server. The zero value searches
+// the way FindNear does.
+var defaults searchDefaults
+
+// options returns the search options of a request that gives none.
+func (d searchDefaults) options() radar.SearchOptions {
+	options := radar.SearchOptions{Radius: d.Radius, Limit: d.Limit, Sort: d.Sort}
+	options.Filters.ExcludedTypes = d.ExcludeTypes
+	return options
+}
+
+// loadSearchDefaults returns the defaults in the -search-defaults file, or
+// none if there isn't one.
+func loadSearchDefaults() searchDefaults {
+	if *searchDefaultsFilename == "" {
+		return searchDefaults{}
+	}
+	loaded, err := readSearchDefaults(*searchDefaultsFilename)
+	if err != nil {
+		log.Fatal("Could not load search defaults. ", err)
+	}
+	return loaded
+}
+
+// readSearchDefaults reads search defaults from a JSON file like
+// {"radius": 0.25, "limit": 50, "sort": "recent", "exclude_types": ["Liquor Laws"]}.
+func readSearchDefaults(filename string) (searchDefaults, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return searchDefaults{}, err
+	}
+	var loaded searchDefaults
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&loaded); err != nil {
+		return searchDefaults{}, err
+	}
+	if loaded.Radius < 0 || loaded.Limit < 0 {
+		return searchDefaults{}, fmt.Errorf("invalid search defaults: %+v", loaded)
+	}
+	return loaded, loaded.options().Validate()
+}
+
+// parseTypes splits a comma-separated list of crime types, dropping empty
+// ones.
+func parseTypes(value string) []string {
+	types := make([]string, 0)
+	for _, crimeType := range strings.Split(value, ",") {
+		if crimeType = strings.TrimSpace(crimeType); crimeType != "" {
+			types = append(types, crimeType)
+		}
+	}
+	return types
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+func TestSearchDefaults(t *testing.T) {
+	defer func() {
+		defaults = searchDefaults{}
+	}()
+	defaults = searchDefaults{Radius: 0.25, Limit: 2, Sort: radar.DistanceSort, ExcludeTypes: []string{"Larceny"}}
+	resp := get(t, "/crimes/near/45.5184/-122.6554")
+	var envelope envelopeResponse
+	json.Unmarshal(resp.Body.Bytes(), &envelope)
+	query := envelope.Meta.Query
+	if query["radius"] != 0.25 || query["limit"] != 2.0 || query["sort"] != "distance" || query["exclude_types"] != "Larceny" {
+		t.Error("Defaults should apply to a search that leaves them out: ", query)
+	}
+	var result struct {
+		Locations []struct{ Crimes []struct{ Type string } }
+	}
+	json.Unmarshal(envelope.Data, &result)
+	if len(result.Locations) != 2 {
+		t.Error("Wrong number of locations: ", len(result.Locations))
+	}
+	for _, location := range result.Locations {
+		for _, crime := range location.Crimes {
+			if crime.Type == "Larceny" {
+				t.Error("Excluded type was returned")
+			}
+		}
+	}
+
+	resp = get(t, "/crimes/near/45.5184/-122.6554?limit=5&sort=&exclude_types=")
+	envelope = envelopeResponse{}
+	json.Unmarshal(resp.Body.Bytes(), &envelope)
+	query = envelope.Meta.Query
+	if query["limit"] != 5.0 || query["sort"] != nil || query["exclude_types"] != nil || query["radius"] != 0.25 {
+		t.Error("Parameters should override defaults: ", query)
+	}
+}
+
+func TestReadSearchDefaults(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "defaults.json")
+	os.WriteFile(filename, []byte(`{"radius": 0.25, "limit": 50, "sort": "recent", "exclude_types": ["Liquor Laws"]}`), 0644)
+	loaded, err := readSearchDefaults(filename)
+	if err != nil || loaded.Radius != 0.25 || loaded.Limit != 50 || loaded.Sort != radar.RecentSort || len(loaded.ExcludeTypes) != 1 {
+		t.Error("Wrong defaults: ", loaded, err)
+	}
+	for _, bad := range []string{`{"radius": 6}`, `{"limit": -1}`, `{"sort": "alphabetical"}`, `{"radus": 0.1}`} {
+		os.WriteFile(filename, []byte(bad), 0644)
+		if _, err := readSearchDefaults(filename); err == nil {
+			t.Error("Invalid defaults should be an error: ", bad)
+		}
+	}
+}
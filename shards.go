@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+var shardFlag = flag.String("shard", "", "minLat,minLng,maxLat,maxLng of the area this server loads crimes for, as one shard of a sharded deployment")
+var shardsFilename = flag.String("shards", "", "JSON file listing shard servers and their bounds; the server routes searches to them instead of loading data")
+
+// The time a shard has to answer a routed request.
+const SHARD_TIMEOUT = 10 * time.Second
+
+// A shard is a server that holds the crimes in Bounds.
+type shard struct {
+	URL    string       `json:"url"`
+	Bounds radar.Bounds `json:"bounds"`
+}
+
+// shards are the servers that searches are routed to, if this server is a
+// router.
+var shards []shard
+
+var shardClient = &http.Client{Timeout: SHARD_TIMEOUT}
+
+// loadShards reads the list of shards from a JSON file.
+func loadShards(filename string) ([]shard, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]shard, 0)
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, errors.New("no shards listed")
+	}
+	for _, target := range list {
+		if target.URL == "" {
+			return nil, errors.New("shard has no URL")
+		}
+	}
+	return list, nil
+}
+
+// A shardResponse is the response of one shard to a routed request.
+type shardResponse struct {
+	status int
+	body   []byte
+	err    error
+}
+
+// shardEnvelope is the envelope of a shard's response.
+type shardEnvelope struct {
+	Meta struct {
+		Query map[string]interface{} `json:"query"`
+		Count int                    `json:"count"`
+	} `json:"meta"`
+	Data json.RawMessage `json:"data"`
+}
+
+// fetchShards sends a GET for the path and query of r to each shard at once
+// and returns their responses in the same order. Shards always answer in
+// their default envelope and naming, which the router then applies to
+// the merged response.
+func fetchShards(targets []shard, r *http.Request) []shardResponse {
+	params := r.URL.Query()
+	for _, name := range []string{"envelope", "case", "explain"} {
+		params.Del(name)
+	}
+	responses := make([]shardResponse, len(targets))
+	var wait sync.WaitGroup
+	for i, target := range targets {
+		wait.Add(1)
+		go func(i int, target shard) {
+			defer wait.Done()
+			url := target.URL + r.URL.Path
+			if len(params) > 0 {
+				url += "?" + params.Encode()
+			}
+			resp, err := shardClient.Get(url)
+			if err != nil {
+				responses[i].err = err
+				return
+			}
+			defer resp.Body.Close()
+			responses[i].status = resp.StatusCode
+			responses[i].body, responses[i].err = io.ReadAll(resp.Body)
+		}(i, target)
+	}
+	wait.Wait()
+	return responses
+}
+
+// mergeLocations sorts, cuts and limits the locations that the shards
+// found as search asks, the way a search of all the data would have, since
+// each shard only did so for its own. It returns the locations that are
+// left and the number of their crimes.
+func mergeLocations(query radar.Point, found []json.RawMessage, search radar.SearchOptions) ([]json.RawMessage, int, error) {
+	merged := radar.SearchResult{Query: &query, Locations: make([]*radar.CrimeLocation, 0, len(found))}
+	// The router only reads the parts of locations and crimes that they're
+	// sorted and cut by, and keeps the rest as the shards sent it.
+	objects := make(map[*radar.Point]orderedObject, len(found))
+	crimes := make(map[*radar.Crime]json.RawMessage)
+	for _, data := range found {
+		var object orderedObject
+		var location struct {
+			Point  radar.Point       `json:"point"`
+			Crimes []json.RawMessage `json:"crimes"`
+		}
+		if err := json.Unmarshal(data, &object); err != nil {
+			return nil, 0, err
+		}
+		if err := json.Unmarshal(data, &location); err != nil {
+			return nil, 0, err
+		}
+		point := location.Point
+		parsed := &radar.CrimeLocation{Point: &point, Crimes: make([]*radar.Crime, 0, len(location.Crimes))}
+		for _, crimeData := range location.Crimes {
+			var fields struct {
+				Id   int64  `json:"id"`
+				Date string `json:"date"`
+				Time string `json:"time"`
+			}
+			if err := json.Unmarshal(crimeData, &fields); err != nil {
+				return nil, 0, err
+			}
+			crime := &radar.Crime{Id: fields.Id, Date: fields.Date, Time: fields.Time}
+			crimes[crime] = crimeData
+			parsed.Crimes = append(parsed.Crimes, crime)
+		}
+		objects[&point] = object
+		merged.Locations = append(merged.Locations, parsed)
+	}
+	merged = search.Apply(merged)
+	locations := make([]json.RawMessage, 0, len(merged.Locations))
+	count := 0
+	for _, location := range merged.Locations {
+		kept := make([]json.RawMessage, 0, len(location.Crimes))
+		for _, crime := range location.Crimes {
+			kept = append(kept, crimes[crime])
+		}
+		encoded, err := json.Marshal(kept)
+		if err != nil {
+			return nil, 0, err
+		}
+		object := objects[location.Point]
+		object.set("crimes", encoded)
+		data, err := json.Marshal(object)
+		if err != nil {
+			return nil, 0, err
+		}
+		locations = append(locations, data)
+		count += len(location.Crimes)
+	}
+	return locations, count, nil
+}
+
+// routedNearHandler searches every shard that a search could cover and
+// merges their locations.
+func routedNearHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	query, err := parsePoint(vars["lat"], vars["lng"])
+	if err != nil {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	search, err := parseNearSearch(r)
+	if err != nil {
+		writeSearchError(w, err)
+		return
+	}
+	covered := radar.SearchBounds(query)
+	if search.Radius > 0 {
+		covered = radar.RadiusBounds(query, search.Radius)
+	}
+	targets := make([]shard, 0)
+	for _, target := range shards {
+		if target.Bounds.Intersects(covered) {
+			targets = append(targets, target)
+		}
+	}
+	merged := struct {
+		Query       radar.Point       `json:"query"`
+		Locations   []json.RawMessage `json:"locations"`
+		Diagnostics json.RawMessage   `json:"diagnostics,omitempty"`
+	}{Query: query, Locations: make([]json.RawMessage, 0)}
+	meta := responseMeta{Query: map[string]interface{}{"lat": query.Lat, "lng": query.Lng}}
+	for i, resp := range fetchShards(targets, r) {
+		if resp.err != nil || resp.status != 200 {
+			routingFailed(w, targets[i], resp)
+			return
+		}
+		var body shardEnvelope
+		var data struct {
+			Locations   []json.RawMessage `json:"locations"`
+			Diagnostics json.RawMessage   `json:"diagnostics"`
+		}
+		if err := json.Unmarshal(resp.body, &body); err != nil || json.Unmarshal(body.Data, &data) != nil {
+			routingFailed(w, targets[i], shardResponse{err: err})
+			return
+		}
+		merged.Locations = append(merged.Locations, data.Locations...)
+		// Every shard applies the same query parameters.
+		meta.Query = body.Meta.Query
+		// The shard that holds the query's own cell knows the most about
+		// why nothing is near it.
+		if data.Diagnostics != nil && (merged.Diagnostics == nil || targets[i].Bounds.InShard(query)) {
+			merged.Diagnostics = data.Diagnostics
+		}
+	}
+	var count int
+	if merged.Locations, count, err = mergeLocations(query, merged.Locations, search); err != nil {
+		http.Error(w, http.StatusText(502), 502)
+		log.Println("Could not merge the shards' locations:", err)
+		return
+	}
+	if err := limits.checkResults(count); err != nil {
+		writeLimitError(w, err)
+		return
+	}
+	if len(merged.Locations) > 0 {
+		merged.Diagnostics = nil
+	}
+	resp, err := json.Marshal(merged)
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	meta.Count = &count
+	writeJson(w, r, resp, meta)
+}
+
+// routedCrimeHandler asks every shard for a crime by id, since ids don't
+// say where a crime is.
+func routedCrimeHandler(w http.ResponseWriter, r *http.Request) {
+	for i, resp := range fetchShards(shards, r) {
+		if resp.err != nil || resp.status != 200 && resp.status != 404 {
+			routingFailed(w, shards[i], resp)
+			return
+		}
+		if resp.status != 200 {
+			continue
+		}
+		var body shardEnvelope
+		if err := json.Unmarshal(resp.body, &body); err != nil {
+			routingFailed(w, shards[i], shardResponse{err: err})
+			return
+		}
+		writeJson(w, r, body.Data, responseMeta{Query: body.Meta.Query, Count: &body.Meta.Count})
+		return
+	}
+	http.Error(w, http.StatusText(404), 404)
+}
+
+// routingFailed responds to a request that a shard couldn't answer. Bad
+// requests are the client's fault, and anything else is the shard's.
+func routingFailed(w http.ResponseWriter, target shard, resp shardResponse) {
+	if resp.err == nil && resp.status == 400 {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	// The shard's limits are the router's, so pass on its guidance.
+	if resp.err == nil && resp.status == 422 {
+		http.Error(w, strings.TrimSpace(string(resp.body)), 422)
+		return
+	}
+	http.Error(w, http.StatusText(502), 502)
+	if resp.err != nil {
+		log.Println("Shard", target.URL, "failed:", resp.err)
+	} else {
+		log.Println("Shard", target.URL, "returned", resp.status)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// shardLocations are the locations that each fake shard finds: west's is
+// farther from the query, and has the newer crime.
+var shardLocations = map[string]string{
+	"west": `{"name":"west","point":{"lat":45.5,"lng":-122.603},"crimes":[{"id":1,"date":"12/01/2011","time":"10:00:00"},{"id":2,"date":"12/30/2011","time":"10:00:00"}]}`,
+	"east": `{"name":"east","point":{"lat":45.5,"lng":-122.5995},"crimes":[{"id":3,"date":"12/02/2011","time":"10:00:00"},{"id":4,"date":"11/01/2011","time":"10:00:00"}]}`,
+	"far":  `{"name":"far","point":{"lat":40.5,"lng":-79.5},"crimes":[]}`,
+}
+
+// fakeShard serves a search with one location, or a 404 for anything else,
+// and counts its requests.
+func fakeShard(name string, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests += 1
+		if r.URL.Query().Get("envelope") != "" || r.URL.Query().Get("case") != "" {
+			w.WriteHeader(400)
+			return
+		}
+		if r.URL.Path == "/crimes/1" && name == "east" {
+			fmt.Fprint(w, `{"meta":{"query":{"id":1},"count":1},"data":{"locations":[{"name":"east"}]}}`)
+			return
+		}
+		if r.URL.Path != "/crimes/near/45.5/-122.6" {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Fprintf(w, `{"meta":{"query":{"lat":45.5,"lng":-122.6},"count":2},"data":{"query":{"Lat":45.5,"Lng":-122.6},"locations":[%v]}}`, shardLocations[name])
+	}))
+}
+
+func TestRoutedSearch(t *testing.T) {
+	defer func() {
+		shards = nil
+	}()
+	var westRequests, eastRequests, farRequests int
+	west, east, far := fakeShard("west", &westRequests), fakeShard("east", &eastRequests), fakeShard("far", &farRequests)
+	defer west.Close()
+	defer east.Close()
+	defer far.Close()
+	shards = []shard{
+		{west.URL, radar.Bounds{Min: radar.Point{Lat: 45, Lng: -123}, Max: radar.Point{Lat: 46, Lng: -122.6}}},
+		{east.URL, radar.Bounds{Min: radar.Point{Lat: 45, Lng: -122.6}, Max: radar.Point{Lat: 46, Lng: -122}}},
+		{far.URL, radar.Bounds{Min: radar.Point{Lat: 40, Lng: -80}, Max: radar.Point{Lat: 41, Lng: -79}}},
+	}
+
+	resp := get(t, "/crimes/near/45.5/-122.6?case=camel")
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	var body struct {
+		Meta struct{ Count int }
+		Data struct{ Locations []struct{ Name string } }
+	}
+	json.Unmarshal(resp.Body.Bytes(), &body)
+	if body.Meta.Count != 4 || len(body.Data.Locations) != 2 || body.Data.Locations[0].Name != "west" {
+		t.Error("Router should merge the shards' results: ", resp.Body.String())
+	}
+	if farRequests != 0 {
+		t.Error("Router should not search shards the search doesn't cover")
+	}
+
+	// The merged locations are sorted, cut and limited as if one server
+	// had searched them all.
+	resp = get(t, "/crimes/near/45.5/-122.6?sort=distance&limit=1&max_per_location=1")
+	var limited struct {
+		Meta struct{ Count int }
+		Data struct {
+			Locations []struct {
+				Name   string
+				Crimes []struct{ Id int }
+			}
+		}
+	}
+	json.Unmarshal(resp.Body.Bytes(), &limited)
+	if limited.Meta.Count != 1 || len(limited.Data.Locations) != 1 || limited.Data.Locations[0].Name != "east" {
+		t.Fatal("Router should sort and limit the merged locations: ", resp.Body.String())
+	}
+	if crimes := limited.Data.Locations[0].Crimes; len(crimes) != 1 || crimes[0].Id != 3 {
+		t.Error("Router should keep the newest crimes of each location: ", crimes)
+	}
+	resp = get(t, "/crimes/near/45.5/-122.6?sort=recent&limit=1")
+	json.Unmarshal(resp.Body.Bytes(), &limited)
+	if len(limited.Data.Locations) != 1 || limited.Data.Locations[0].Name != "west" || limited.Meta.Count != 2 {
+		t.Error("Router should sort the merged locations by their newest crimes: ", resp.Body.String())
+	}
+	if resp = get(t, "/crimes/near/45.5/-122.6?sort=nowhere"); resp.Code != 400 {
+		t.Error("Router should refuse an invalid search: ", resp.Code)
+	}
+
+	resp = get(t, "/crimes/1")
+	if resp.Code != 200 || len(data(t, resp)) == 0 {
+		t.Error("Router should find a crime on any shard: ", resp.Code)
+	}
+	if resp = get(t, "/crimes/2"); resp.Code != 404 {
+		t.Error("Wrong status code for a missing crime: ", resp.Code)
+	}
+}
+
+func TestRoutedSearchShardDown(t *testing.T) {
+	defer func() {
+		shards = nil
+	}()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	}))
+	defer down.Close()
+	shards = []shard{{down.URL, radar.Bounds{Min: radar.Point{Lat: 45, Lng: -123}, Max: radar.Point{Lat: 46, Lng: -122}}}}
+	if resp := get(t, "/crimes/near/45.5/-122.6"); resp.Code != 502 {
+		t.Error("Wrong status code when a shard fails: ", resp.Code)
+	}
+}
+
+func TestLoadShards(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "shards.json")
+	os.WriteFile(filename, []byte(`[{"url":"http://localhost:8082","bounds":{"min":{"lat":45,"lng":-123},"max":{"lat":46,"lng":-122}}}]`), 0600)
+	list, err := loadShards(filename)
+	if err != nil || len(list) != 1 || list[0].Bounds.Max.Lng != -122 {
+		t.Error("Wrong shards: ", list, err)
+	}
+	os.WriteFile(filename, []byte(`[]`), 0600)
+	if _, err := loadShards(filename); err == nil {
+		t.Error("loadShards should need at least one shard")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"log"
+
+	"github.com/abrookins/radar/crimes"
+	"github.com/abrookins/radar/internal/objectstore"
+)
+
+var compressSnapshots = flag.Bool("compress-snapshots", false, "compress the snapshots the server saves and publishes, which makes them smaller but unable to be mapped into memory")
+
+// writeSnapshot writes a snapshot of f to w, compressed if the flags ask
+// for it.
+func writeSnapshot(f *radar.CrimeFinder, w io.Writer) error {
+	if *compressSnapshots {
+		return f.WriteCompressedSnapshot(w)
+	}
+	return f.WriteSnapshot(w)
+}
+
+// saveSnapshot saves a snapshot of f to filename, which may be an object
+// URL, compressed if the flags ask for it.
+func saveSnapshot(f *radar.CrimeFinder, filename string) error {
+	if objectstore.IsURL(filename) {
+		var buf bytes.Buffer
+		if err := writeSnapshot(f, &buf); err != nil {
+			return err
+		}
+		return objects.Put(filename, buf.Bytes())
+	}
+	if *compressSnapshots {
+		return f.SaveCompressedSnapshot(filename)
+	}
+	return f.SaveSnapshot(filename)
+}
+
+// loadSnapshot loads the snapshot in filename, which may be an object URL.
+// A snapshot from object storage is read into memory rather than mapped.
+func loadSnapshot(filename string, options radar.LoadOptions) (radar.CrimeFinder, error) {
+	if !objectstore.IsURL(filename) {
+		return radar.LoadSnapshotWithOptions(filename, options)
+	}
+	r, err := objects.Open(filename)
+	if err != nil {
+		return radar.CrimeFinder{}, err
+	}
+	defer r.Close()
+	loaded, err := radar.ReadSnapshotWithOptions(r, options)
+	if err != nil {
+		return loaded, err
+	}
+	log.Printf("Loaded %v crimes and %v locations from a snapshot in %v", loaded.Report.Crimes, loaded.Report.Locations, filename)
+	return loaded, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+func TestSaveCompressedSnapshot(t *testing.T) {
+	defer func() { *compressSnapshots = false }()
+	dir := t.TempDir()
+	plain, compressed := filepath.Join(dir, "plain.snapshot"), filepath.Join(dir, "compressed.snapshot")
+	if err := saveSnapshot(&finder, plain); err != nil {
+		t.Fatal("Error saving snapshot: ", err)
+	}
+	*compressSnapshots = true
+	if err := saveSnapshot(&finder, compressed); err != nil {
+		t.Fatal("Error saving compressed snapshot: ", err)
+	}
+	data, _ := os.ReadFile(compressed)
+	if string(data[:len(radar.COMPRESSED_SNAPSHOT_MAGIC)]) != radar.COMPRESSED_SNAPSHOT_MAGIC {
+		t.Error("Snapshot should be compressed: ", string(data[:8]))
+	}
+	if info, _ := os.Stat(plain); int64(len(data)) >= info.Size() {
+		t.Error("Compressed snapshot should be smaller: ", len(data), info.Size())
+	}
+	loaded, err := radar.LoadSnapshot(compressed)
+	if err != nil || loaded.Fingerprint() != finder.Fingerprint() {
+		t.Error("Compressed snapshot has different data: ", err, loaded.Report.Crimes)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// SOCRATA_SCHEME starts the names of Socrata datasets given to -f, like
+// socrata://data.cityofchicago.org/ijzp-q8t2.
+const SOCRATA_SCHEME = "socrata://"
+
+var errSocrataURL = errors.New("a Socrata dataset must be named like socrata://data.cityofchicago.org/ijzp-q8t2")
+
+// isSocrataURL returns true if name is a Socrata dataset rather than a file.
+func isSocrataURL(name string) bool {
+	return strings.HasPrefix(name, SOCRATA_SCHEME)
+}
+
+// loadSocrata loads the Socrata dataset named by url with options, with
+// the app token in SOCRATA_APP_TOKEN, if it's set. Its bad rows are
+// quarantined in the working directory, in a file named after the
+// dataset's id.
+func loadSocrata(url string, options radar.LoadOptions) (radar.CrimeFinder, error) {
+	domain, id, ok := strings.Cut(strings.TrimPrefix(url, SOCRATA_SCHEME), "/")
+	if !ok || domain == "" || id == "" || strings.Contains(id, "/") {
+		return radar.CrimeFinder{}, errSocrataURL
+	}
+	options.BadRows.Quarantine = options.BadRows.QuarantineFile(localSource(url))
+	return radar.NewCrimeFinderFromSocrataWithOptions(domain, id, os.Getenv("SOCRATA_APP_TOKEN"), options)
+}
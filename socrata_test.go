@@ -0,0 +1,18 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+func TestLoadSocrataURL(t *testing.T) {
+	for _, bad := range []string{"socrata://data.cityofchicago.org", "socrata:///ijzp-q8t2", "socrata://data.cityofchicago.org/resource/ijzp-q8t2"} {
+		if _, err := loadFile(bad, radar.LoadOptions{}); err != errSocrataURL {
+			t.Error("Wrong error for ", bad, ": ", err)
+		}
+	}
+	if source := localSource("socrata://data.cityofchicago.org/ijzp-q8t2"); source != "ijzp-q8t2" {
+		t.Error("Bad rows of a dataset should be named after its id: ", source)
+	}
+}
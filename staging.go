@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+var refreshTolerance = flag.Float64("refresh-tolerance", 0.2, "fraction by which the number of crimes in refreshed data may differ from the data being served")
+
+// A stageResult describes an attempt to replace the data being served with
+// a refreshed version.
+type stageResult struct {
+	Version  string    `json:"version"`
+	Crimes   int       `json:"crimes"`
+	Promoted bool      `json:"promoted"`
+	Reason   string    `json:"reason,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+var (
+	// stagingLock makes refreshes take turns.
+	stagingLock sync.Mutex
+	lastStage   *stageResult
+)
+
+var errNoDataFile = errors.New("only a server that loaded a data file or snapshot can reload it")
+
+var errIngestNotLogged = errors.New("a reload would lose the ingested crimes, which are only kept with -ingest-wal")
+
+// validateStaged returns an error if staged doesn't look like a refresh of
+// active: if it has no crimes, if its number of crimes differs from
+// active's by more than tolerance, if more than tolerance of its rows
+// couldn't be loaded, or if a search where it has crimes doesn't find them.
+func validateStaged(staged *radar.CrimeFinder, active *radar.CrimeFinder, tolerance float64) error {
+	crimes := staged.Report.Crimes
+	if crimes == 0 {
+		return errors.New("it has no crimes")
+	}
+	if active.Report.Crimes > 0 {
+		change := float64(crimes-active.Report.Crimes) / float64(active.Report.Crimes)
+		if math.Abs(change) > tolerance {
+			return fmt.Errorf("it has %v crimes, %+.0f%% from the %v being served", crimes, change*100, active.Report.Crimes)
+		}
+	}
+	if skipped := len(staged.Report.Errors); float64(skipped) > tolerance*float64(crimes+skipped) {
+		return fmt.Errorf("%v of its %v rows couldn't be loaded", skipped, crimes+skipped)
+	}
+	location := staged.Locations()[0]
+	nearby, err := staged.FindNear(*location.Point)
+	if err != nil {
+		return err
+	}
+	if len(nearby.Locations) == 0 {
+		return errors.New("searches don't find its crimes")
+	}
+	return nil
+}
+
+// stageFinder checks staged, a refreshed version of the data, against the
+// data being served, and swaps it in if it passes. Otherwise the data being
+// served stays, and staged is thrown away.
+func stageFinder(staged radar.CrimeFinder, alert bool) stageResult {
+	stagingLock.Lock()
+	defer stagingLock.Unlock()
+	result := stageResult{Version: staged.Fingerprint(), Crimes: staged.Report.Crimes, At: time.Now()}
+	finderLock.RLock()
+	err := validateStaged(&staged, &finder, *refreshTolerance)
+	finderLock.RUnlock()
+	if err != nil {
+		result.Reason = fmt.Sprintf("Kept the data being served, because %v", err)
+		log.Printf("Did not promote version %v. %v", result.Version, result.Reason)
+	} else {
+		swapFinder(staged, alert)
+		result.Promoted = true
+	}
+	lastStage = &result
+	return result
+}
+
+// reloadData loads the data file or snapshot the server started with again
+// and stages it, with the crimes in the ingest log replayed into it so that
+// they aren't lost. A server that ingests crimes without a log has nowhere
+// to replay them from, so it doesn't reload.
+func reloadData() (stageResult, error) {
+	var loaded radar.CrimeFinder
+	var err error
+	switch {
+	case *replicateFrom != "" || *filename == "" && *snapshotFilename == "":
+		return stageResult{}, errNoDataFile
+	case *ingest && ingests == nil:
+		return stageResult{}, errIngestNotLogged
+	case *snapshotFilename != "":
+		loaded, err = loadSnapshot(*snapshotFilename, radar.LoadOptions{})
+	default:
+		loaded, err = loadFile(*filename, loadOptions())
+	}
+	if err != nil {
+		return stageResult{}, err
+	}
+	// Data that's already being served is replaced once it's indexed,
+	// rather than scanned while it is.
+	loaded.WaitForIndexes()
+	if ingests != nil {
+		// Crimes ingested from now until the swap are caught up by
+		// swapFinder.
+		replayed, _, err := replayIngestLog(*ingestLogFilename, &loaded)
+		if err != nil {
+			return stageResult{}, err
+		}
+		log.Printf("Replayed %v ingested crimes into the reloaded data", replayed)
+	}
+	return stageFinder(loaded, true), nil
+}
+
+// reloadOnSignal reloads the data whenever the server gets a SIGHUP.
+func reloadOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		result, err := reloadData()
+		if err != nil {
+			log.Println("Could not reload the data. ", err)
+			continue
+		}
+		auditReload(nil, result)
+	}
+}
+
+// refreshHandler describes the last refresh of the data for a GET, and
+// reloads the data for a POST. A reload that isn't promoted gets 422
+// Unprocessable Entity, with the reason.
+func refreshHandler(w http.ResponseWriter, r *http.Request) {
+	var result stageResult
+	status := 200
+	if r.Method == "POST" {
+		var err error
+		result, err = reloadData()
+		if err == errNoDataFile || err == errIngestNotLogged {
+			http.Error(w, http.StatusText(409), 409)
+			return
+		}
+		if err != nil {
+			http.Error(w, http.StatusText(500), 500)
+			log.Println("Could not reload the data. ", err)
+			return
+		}
+		auditReload(r, result)
+		if !result.Promoted {
+			status = 422
+		}
+	} else {
+		stagingLock.Lock()
+		if lastStage == nil {
+			stagingLock.Unlock()
+			http.Error(w, http.StatusText(404), 404)
+			return
+		}
+		result = *lastStage
+		stagingLock.Unlock()
+	}
+	resp, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	writeJsonStatus(w, r, status, resp, responseMeta{})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+	"github.com/abrookins/radar/crimes/sample"
+)
+
+func TestValidateStaged(t *testing.T) {
+	active := sample.NewFinder()
+	if err := validateStaged(&active, &active, 0.2); err != nil {
+		t.Error("The same data should pass: ", err)
+	}
+	empty := radar.CrimeFinder{}
+	if err := validateStaged(&empty, &active, 0.2); err == nil {
+		t.Error("Data without crimes should fail")
+	}
+
+	contents := sample.CSV
+	lines := strings.Split(string(contents), "\n")
+	filename := filepath.Join(t.TempDir(), "half.csv")
+	os.WriteFile(filename, []byte(strings.Join(lines[:len(lines)/2], "\n")), 0644)
+	half, _ := radar.NewCrimeFinder(filename)
+	if err := validateStaged(&half, &active, 0.2); err == nil || !strings.Contains(err.Error(), "-50%") {
+		t.Error("Data with half the crimes should fail: ", err)
+	}
+	if err := validateStaged(&half, &active, 0.6); err != nil {
+		t.Error("Data within the tolerance should pass: ", err)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	defer func(saved string) {
+		*filename = saved
+		finder = sample.NewFinder()
+		updateDatasetVersion()
+	}(*filename)
+	if resp := request(t, "POST", "/admin/refresh", ""); resp.Code != 409 {
+		t.Error("A server without a data file shouldn't reload: ", resp.Code)
+	}
+
+	// Data that lost most of its rows is staged but not promoted.
+	contents := sample.CSV
+	lines := strings.Split(string(contents), "\n")
+	*filename = filepath.Join(t.TempDir(), "truncated.csv")
+	os.WriteFile(*filename, []byte(strings.Join(lines[:100], "\n")), 0644)
+	crimes := finder.Report.Crimes
+	resp := request(t, "POST", "/admin/refresh", "")
+	if resp.Code != 422 || !strings.Contains(resp.Body.String(), `"promoted":false`) || finder.Report.Crimes != crimes {
+		t.Fatal("Truncated data shouldn't be promoted: ", resp.Code, resp.Body.String(), finder.Report.Crimes)
+	}
+	if resp := get(t, "/admin/refresh"); resp.Code != 200 || !strings.Contains(resp.Body.String(), "Kept the data being served") {
+		t.Error("Wrong description of the last refresh: ", resp.Code, resp.Body.String())
+	}
+
+	// A small correction is promoted.
+	lines[2] = strings.Replace(lines[2], "Liquor Laws", "Disorderly Conduct", 1)
+	os.WriteFile(*filename, []byte(strings.Join(lines, "\n")), 0644)
+	resp = request(t, "POST", "/admin/refresh", "")
+	if resp.Code != 200 || !strings.Contains(resp.Body.String(), `"promoted":true`) {
+		t.Fatal("Refreshed data should be promoted: ", resp.Code, resp.Body.String())
+	}
+	if crime, _ := finder.FindByID(13716403); crime == nil || crime.Type != "Disorderly Conduct" {
+		t.Error("The refreshed data should be served: ", crime)
+	}
+}
+
+func TestRefreshKeepsIngestedCrimes(t *testing.T) {
+	defer func(saved string) {
+		*filename = saved
+		*ingest = false
+		*ingestLogFilename = ""
+		ingests = nil
+		finder = sample.NewFinder()
+		updateDatasetVersion()
+	}(*filename)
+	*filename = filepath.Join(t.TempDir(), "crimes.csv")
+	os.WriteFile(*filename, sample.CSV, 0644)
+	*ingest = true
+	if resp := request(t, "POST", "/admin/refresh", ""); resp.Code != 409 {
+		t.Error("A reload would lose crimes ingested without a log: ", resp.Code)
+	}
+
+	*ingestLogFilename = filepath.Join(t.TempDir(), "ingest.wal")
+	var err error
+	if ingests, err = openIngestLog(*ingestLogFilename, 0); err != nil {
+		t.Fatal("Could not open the ingest log: ", err)
+	}
+	if resp := request(t, "POST", "/crimes", walCrime); resp.Code != 201 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	if resp := request(t, "POST", "/admin/refresh", ""); resp.Code != 200 {
+		t.Fatal("Refreshed data should be promoted: ", resp.Code, resp.Body.String())
+	}
+	if crime, _ := finder.FindByID(99000001); crime == nil {
+		t.Error("The refreshed data should have the ingested crime")
+	}
+	resp := get(t, "/crimes/near/45.531/-122.661")
+	if !strings.Contains(resp.Body.String(), `"id":99000001`) {
+		t.Error("Searches should find the ingested crime after a refresh: ", resp.Body.String())
+	}
+}
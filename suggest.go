@@ -0,0 +1,34 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// suggestHandler returns the crime types, categories and neighborhoods with
+// a word that starts with the "q" parameter, ranked by how many crimes they
+// have, for search boxes. The "limit" parameter sets how many to return.
+func suggestHandler(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	limit := radar.MAX_SUGGESTIONS
+	if value := params.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > radar.MAX_SUGGESTIONS {
+			http.Error(w, http.StatusText(400), 400)
+			return
+		}
+	}
+	suggestions := finderFor(r).Suggest(params.Get("q"), limit)
+	resp, err := suggestions.ToJson()
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	count := len(suggestions)
+	writeJson(w, r, resp, responseMeta{Query: map[string]interface{}{"q": params.Get("q"), "limit": limit}, Count: &count})
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	resp := get(t, "/suggest?q=bur&limit=3")
+	if resp.Code != 200 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+	var suggestions []struct {
+		Text  string
+		Kind  string
+		Count int
+	}
+	json.Unmarshal(data(t, resp), &suggestions)
+	if len(suggestions) == 0 || len(suggestions) > 3 || suggestions[0].Text != "Burglary" || suggestions[0].Kind != "type" {
+		t.Error("Wrong suggestions: ", suggestions)
+	}
+	for _, limit := range []string{"0", "26", "a"} {
+		if resp := get(t, "/suggest?q=bur&limit="+limit); resp.Code != 400 {
+			t.Error("Invalid limit should be 400: ", limit, " ", resp.Code)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+var translationsFilename = flag.String("translations", "", "JSON file of labels for crime types and categories by language, added to the built-in ones")
+
+// translations holds the labels that responses can translate crime types and
+// categories into.
+var translations = radar.BuiltInTranslations
+
+// loadTranslations returns the built-in translations with those of the
+// -translations file, if there is one, added.
+func loadTranslations() radar.Translations {
+	if *translationsFilename == "" {
+		return radar.BuiltInTranslations
+	}
+	loaded, err := radar.LoadTranslations(*translationsFilename)
+	if err != nil {
+		log.Fatal("Could not load translations. ", err)
+	}
+	return radar.BuiltInTranslations.Merge(loaded)
+}
+
+// requestLanguage returns the language to translate the response to r into:
+// the "lang" parameter, or else the language its Accept-Language header
+// prefers. It's "" if the response shouldn't be translated.
+func requestLanguage(r *http.Request) string {
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		return translations.Language(lang)
+	}
+	return translations.Language(r.Header.Get("Accept-Language"))
+}
+
+// setLanguageHeaders tells caches that the response to r depends on its
+// Accept-Language header, and readers what language it's in.
+func setLanguageHeaders(w http.ResponseWriter, language string) {
+	w.Header().Add("Vary", "Accept-Language")
+	if language != "" {
+		w.Header().Set("Content-Language", language)
+	}
+}
+
+// pageLanguage returns the lang attribute of an HTML page translated into
+// language.
+func pageLanguage(language string) string {
+	if language == "" {
+		return radar.SOURCE_LANGUAGE
+	}
+	return language
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTranslatedResponses(t *testing.T) {
+	resp := get(t, "/crimes/near/45.53435699129174/-122.66469510763777?lang=es")
+	if !strings.Contains(resp.Body.String(), `"type":"Leyes de alcohol"`) || strings.Contains(resp.Body.String(), `"type":"Liquor Laws"`) {
+		t.Error("Crime types should be translated: ", resp.Body.String())
+	}
+	if resp.Header().Get("Content-Language") != "es" {
+		t.Error("Wrong Content-Language: ", resp.Header().Get("Content-Language"))
+	}
+
+	req := httptest.NewRequest("GET", "/meta/bounds", nil)
+	req.Header.Set("Accept-Language", "es-MX,es;q=0.9,en;q=0.5")
+	resp = httptest.NewRecorder()
+	newRouter().ServeHTTP(resp, req)
+	if !strings.Contains(resp.Body.String(), `"contra la sociedad":`) {
+		t.Error("Categories should be translated: ", resp.Body.String())
+	}
+	if resp.Header().Get("Vary") != "Accept-Language" {
+		t.Error("Wrong Vary: ", resp.Header().Get("Vary"))
+	}
+
+	resp = get(t, "/crimes/near/45.53435699129174/-122.66469510763777?lang=en")
+	if !strings.Contains(resp.Body.String(), `"type":"Liquor Laws"`) || resp.Header().Get("Content-Language") != "" {
+		t.Error("English shouldn't be translated: ", resp.Body.String())
+	}
+}
+
+func TestTranslatedPages(t *testing.T) {
+	expected := finder.Locations()[0].Crimes[0]
+	req := httptest.NewRequest("GET", fmt.Sprintf("/crimes/%v?lang=es", expected.Id), nil)
+	req.Header.Set("Accept", "text/html")
+	resp := httptest.NewRecorder()
+	newRouter().ServeHTTP(resp, req)
+	label := translations.Translate(expected.Type, "es")
+	if label == expected.Type || !strings.Contains(resp.Body.String(), "<h1>"+label+"</h1>") {
+		t.Error("The crime page should be translated: ", resp.Body.String())
+	}
+	if !strings.Contains(resp.Body.String(), `<html lang="es">`) {
+		t.Error("The crime page should be marked as Spanish")
+	}
+
+	resp = get(t, "/widget?lat=45.5231&lng=-122.6765&lang=es")
+	if !strings.Contains(resp.Body.String(), "contra la propiedad") {
+		t.Error("The widget should be translated: ", resp.Body.String())
+	}
+}
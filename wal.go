@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+var ingestLogFilename = flag.String("ingest-wal", "", "file to log crimes sent to POST /crimes to before they're acknowledged, replayed at startup so that they survive a restart")
+
+// An ingestEntry is an ingest that was accepted, as the ingest log records
+// it: the CSV data that was sent and the ids of the crimes it added.
+type ingestEntry struct {
+	Body string  `json:"body"`
+	IDs  []int64 `json:"ids"`
+}
+
+// An ingestLog is a write-ahead log of ingests. Each accepted ingest is
+// appended as a line of JSON and synced to disk before it's acknowledged,
+// so crimes that were only added in memory aren't lost if the server
+// stops. It's safe to use from several goroutines.
+type ingestLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// ingests logs ingests if the flags ask for it, and is nil if they don't.
+var ingests *ingestLog
+
+// openIngestLog opens filename to append ingests to, creating it if it
+// doesn't exist. The file is cut to size bytes first, which drops a
+// partial entry left by a crash in the middle of a write.
+func openIngestLog(filename string, size int64) (*ingestLog, error) {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &ingestLog{file: file}, nil
+}
+
+// Append logs an ingest of body that added the crimes in added, and waits
+// until it's on disk. It does nothing if the log is nil.
+func (l *ingestLog) Append(body []byte, added radar.SearchResult) error {
+	if l == nil {
+		return nil
+	}
+	entry := ingestEntry{Body: string(body), IDs: make([]int64, 0)}
+	for _, crime := range added.Crimes() {
+		entry.IDs = append(entry.IDs, crime.Id)
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// replayIngestLog ingests the entries of the log in filename into
+// replayed, in order, and returns the number of crimes added and the size
+// of the log's complete entries. An entry whose crimes replayed already
+// has, like one whose crimes are in a snapshot saved after it was logged,
+// is skipped. A log that doesn't exist is empty. Rows that can't be added
+// are skipped, since they were the first time.
+func replayIngestLog(filename string, replayed *radar.CrimeFinder) (int, int64, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	added := 0
+	var size int64
+	for {
+		var entry ingestEntry
+		if err := decoder.Decode(&entry); err == io.EOF {
+			break
+		} else if err != nil {
+			// Only the last entry can be partial, since each is synced
+			// before the next is written.
+			log.Println("Ignoring a partial entry at the end of the ingest log. ", err)
+			break
+		}
+		size = decoder.InputOffset() + 1
+		if hasCrimes(replayed, entry.IDs) {
+			continue
+		}
+		result, _, err := replayed.Ingest(bytes.NewReader([]byte(entry.Body)), mappedSchema)
+		if err != nil {
+			return added, size, err
+		}
+		added += len(result.Crimes())
+	}
+	return added, min(size, int64(len(data))), nil
+}
+
+// hasCrimes returns true if searched has a crime with every one of ids.
+func hasCrimes(searched *radar.CrimeFinder, ids []int64) bool {
+	if len(ids) == 0 {
+		return false
+	}
+	for _, id := range ids {
+		if crime, _ := searched.FindByID(id); crime == nil {
+			return false
+		}
+	}
+	return true
+}
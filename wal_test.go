@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abrookins/radar/crimes/sample"
+)
+
+const walCrime = "99000001,12/31/2011,23:00:00,Burglary,,,,,45.531,-122.661\n"
+
+func TestIngestLogReplay(t *testing.T) {
+	defer func() {
+		*ingest = false
+		ingests = nil
+		finder = sample.NewFinder()
+		updateDatasetVersion()
+	}()
+	filename := filepath.Join(t.TempDir(), "ingest.wal")
+	var err error
+	if ingests, err = openIngestLog(filename, 0); err != nil {
+		t.Fatal("Could not open the ingest log: ", err)
+	}
+	*ingest = true
+	if resp := request(t, "POST", "/crimes", walCrime); resp.Code != 201 {
+		t.Fatal("Wrong status code: ", resp.Code)
+	}
+
+	replayed := sample.NewFinder()
+	added, size, err := replayIngestLog(filename, &replayed)
+	if err != nil || added != 1 {
+		t.Fatal("Wrong number of crimes replayed: ", added, err)
+	}
+	if crime, _ := replayed.FindByID(99000001); crime == nil {
+		t.Error("Replayed crime was not added")
+	}
+	if info, _ := os.Stat(filename); info.Size() != size {
+		t.Error("Wrong size of complete entries: ", size)
+	}
+
+	// The crimes are already there, as if they'd been saved in a snapshot.
+	if added, _, err := replayIngestLog(filename, &replayed); err != nil || added != 0 {
+		t.Error("Crimes that are already there should be skipped: ", added, err)
+	}
+}
+
+func TestIngestLogPartialEntry(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "ingest.wal")
+	wal, _ := openIngestLog(filename, 0)
+	ingested := sample.NewFinder()
+	result, _, _ := ingested.Ingest(strings.NewReader(walCrime), nil)
+	if err := wal.Append([]byte(walCrime), result); err != nil {
+		t.Fatal("Could not append: ", err)
+	}
+	info, _ := os.Stat(filename)
+	complete := info.Size()
+	wal.file.WriteString(`{"body":"99000002,12/31`)
+	wal.file.Close()
+
+	replayed := sample.NewFinder()
+	added, size, err := replayIngestLog(filename, &replayed)
+	if err != nil || added != 1 || size != complete {
+		t.Fatal("A partial entry should be ignored: ", added, size, err)
+	}
+	if _, err := openIngestLog(filename, size); err != nil {
+		t.Fatal("Could not reopen the ingest log: ", err)
+	}
+	if info, _ := os.Stat(filename); info.Size() != complete {
+		t.Error("The partial entry should be cut off: ", info.Size())
+	}
+}
+
+func TestIngestLogMissing(t *testing.T) {
+	replayed := sample.NewFinder()
+	added, size, err := replayIngestLog(filepath.Join(t.TempDir(), "none.wal"), &replayed)
+	if err != nil || added != 0 || size != 0 {
+		t.Error("A missing log should be empty: ", added, size, err)
+	}
+}
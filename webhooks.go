@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+var refreshWebhooksFlag = flag.String("refresh-webhooks", "", "comma-separated URLs to POST a summary of the data to after each refresh")
+
+// refreshWebhooks are the URLs that are told about each refresh.
+var refreshWebhooks []string
+
+// refreshClient sends the summaries of refreshes.
+var refreshClient = &http.Client{Timeout: WEBHOOK_TIMEOUT}
+
+// loadRefreshWebhooks returns the URLs of -refresh-webhooks, which must be
+// http or https.
+func loadRefreshWebhooks() []string {
+	if *refreshWebhooksFlag == "" {
+		return nil
+	}
+	hooks := make([]string, 0)
+	for _, hook := range strings.Split(*refreshWebhooksFlag, ",") {
+		hook = strings.TrimSpace(hook)
+		u, err := url.Parse(hook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			log.Fatal("Invalid refresh webhook: ", hook)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks
+}
+
+// A refreshSummary tells a webhook that the data being served was
+// replaced: the versions before and after, how many crimes and locations
+// there are now, and how the crimes changed.
+type refreshSummary struct {
+	Event           string    `json:"event"`
+	Version         string    `json:"version"`
+	PreviousVersion string    `json:"previous_version"`
+	Crimes          int       `json:"crimes"`
+	Locations       int       `json:"locations"`
+	CrimesChange    int       `json:"crimes_change"`
+	Added           int       `json:"added"`
+	Removed         int       `json:"removed"`
+	Corrected       int       `json:"corrected"`
+	At              time.Time `json:"at"`
+}
+
+// newRefreshSummary summarizes the replacement of previous with loaded,
+// which changed the data by changes.
+func newRefreshSummary(previous *radar.CrimeFinder, loaded *radar.CrimeFinder, changes radar.Changes) refreshSummary {
+	return refreshSummary{
+		Event:           "refresh",
+		Version:         changes.To,
+		PreviousVersion: changes.From,
+		Crimes:          loaded.Report.Crimes,
+		Locations:       len(loaded.LocationLookup),
+		CrimesChange:    loaded.Report.Crimes - previous.Report.Crimes,
+		Added:           changes.Added,
+		Removed:         len(changes.Removed),
+		Corrected:       len(changes.Corrected),
+		At:              time.Now().UTC(),
+	}
+}
+
+// postRefreshSummary POSTs summary to each of hooks, and returns the
+// errors of those that failed or didn't respond with a 2xx status.
+func postRefreshSummary(hooks []string, summary refreshSummary) []error {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return []error{err}
+	}
+	var errs []error
+	for _, hook := range hooks {
+		resp, err := refreshClient.Post(hook, "application/json", bytes.NewReader(body))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			errs = append(errs, fmt.Errorf("refresh webhook %v returned %v", hook, resp.Status))
+		}
+	}
+	return errs
+}
+
+// notifyRefresh tells the refresh webhooks about summary in the background.
+func notifyRefresh(summary refreshSummary) {
+	hooks := refreshWebhooks
+	if len(hooks) == 0 {
+		return
+	}
+	go func() {
+		for _, err := range postRefreshSummary(hooks, summary) {
+			log.Println("Could not notify a refresh webhook:", err)
+		}
+	}()
+}
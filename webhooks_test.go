@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+	"github.com/abrookins/radar/crimes/sample"
+)
+
+func TestRefreshWebhooks(t *testing.T) {
+	summaries := make(chan refreshSummary, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var summary refreshSummary
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &summary)
+		summaries <- summary
+	}))
+	defer server.Close()
+	refreshWebhooks = []string{server.URL}
+	defer func() {
+		refreshWebhooks = nil
+		finder = sample.NewFinder()
+		updateDatasetVersion()
+		lastChanges = nil
+		removed = make(map[int64]radar.ChangedCrime)
+	}()
+
+	// The city removes the first crime.
+	lines := strings.Split(string(sample.CSV), "\n")
+	lines = append(lines[:1], lines[2:]...)
+	filename := filepath.Join(t.TempDir(), "refreshed.csv")
+	os.WriteFile(filename, []byte(strings.Join(lines, "\n")), 0644)
+	refreshed, err := radar.NewCrimeFinder(filename)
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	previous := finder
+	swapFinder(refreshed, false)
+
+	select {
+	case summary := <-summaries:
+		if summary.Event != "refresh" || summary.Version != refreshed.Version() || summary.PreviousVersion != previous.Version() {
+			t.Error("Wrong versions: ", summary)
+		}
+		if summary.Crimes != previous.Report.Crimes-1 || summary.CrimesChange != -1 || summary.Removed != 1 || summary.Added != 0 || summary.Locations == 0 {
+			t.Error("Wrong counts: ", summary)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("The webhook wasn't told about the refresh")
+	}
+}
+
+func TestPostRefreshSummaryErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(500), 500)
+	}))
+	defer server.Close()
+	if errs := postRefreshSummary([]string{server.URL, "http://127.0.0.1:0/hook"}, refreshSummary{}); len(errs) != 2 {
+		t.Error("Both webhooks should fail: ", errs)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+// The radius, in miles, of a widget that doesn't ask for one.
+const WIDGET_RADIUS = 0.5
+
+// The number of recent crimes a widget lists.
+const WIDGET_RECENT = 5
+
+// How long browsers and proxies may cache a widget, under the default
+// cache policy.
+const WIDGET_MAX_AGE = 5 * time.Minute
+
+// The width and height of a widget's map, in pixels.
+const WIDGET_MAP_SIZE = 200
+
+// The colors of each category's dots on a widget's map.
+var widgetColors = map[string]string{
+	radar.PersonCategory:   "#d33",
+	radar.PropertyCategory: "#e90",
+	radar.SocietyCategory:  "#36c",
+	radar.OtherCategory:    "#888",
+}
+
+// The page that /widget serves to be embedded in an iframe: a map of the
+// crimes near a location, drawn as SVG so that it needs no scripts or
+// tiles, and a summary of them.
+const WIDGET_PAGE = `<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<title>Crimes near {{.Lat}}, {{.Lng}}</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; margin: 8px; }
+svg { float: left; margin-right: 12px; background: #f6f6f4; }
+ul { padding-left: 1.2em; margin: 4px 0; }
+.swatch { display: inline-block; width: 8px; height: 8px; border-radius: 4px; }
+</style>
+</head>
+<body>
+<svg width="{{.Size}}" height="{{.Size}}" viewBox="0 0 {{.Size}} {{.Size}}" role="img" aria-label="Map of crimes">
+<circle cx="{{.Half}}" cy="{{.Half}}" r="{{.Half}}" fill="#fff" stroke="#ccc"/>
+{{range .Dots}}<circle cx="{{.X}}" cy="{{.Y}}" r="{{.R}}" fill="{{.Color}}" fill-opacity="0.7"><title>{{.Count}} crimes</title></circle>
+{{end}}<circle cx="{{.Half}}" cy="{{.Half}}" r="3" fill="#000"/>
+</svg>
+<strong>{{.Count}} crimes within {{.Radius}} miles</strong>
+<ul>
+{{range .Categories}}<li><span class="swatch" style="background: {{.Color}}"></span> {{.Count}} {{.Name}}</li>
+{{end}}</ul>
+{{if .Recent}}<strong>Most recent</strong>
+<ul>
+{{range .Recent}}<li><a href="{{.URL}}" target="_blank" rel="noopener">{{.Type}}</a>, {{.Date}}</li>
+{{end}}</ul>{{end}}
+</body>
+</html>
+`
+
+// widgetPage is WIDGET_PAGE, parsed.
+var widgetPage = template.Must(template.New("widget").Parse(WIDGET_PAGE))
+
+// The script that embeds a widget where it's included, reading the
+// location from its data attributes:
+//
+//	<script src="https://radar.example.com/widget.js" data-lat="45.5184" data-lng="-122.6554" data-radius="0.25"></script>
+const WIDGET_SCRIPT = `(function () {
+  var script = document.currentScript;
+  var params = ["lat", "lng", "radius"].filter(function (name) {
+    return script.dataset[name];
+  }).map(function (name) {
+    return name + "=" + encodeURIComponent(script.dataset[name]);
+  });
+  var frame = document.createElement("iframe");
+  frame.src = new URL("/widget?" + params.join("&"), script.src).href;
+  frame.width = script.dataset.width || "480";
+  frame.height = script.dataset.height || "220";
+  frame.style.border = "0";
+  frame.title = "Crimes nearby";
+  script.parentNode.insertBefore(frame, script);
+})();
+`
+
+// A widgetDot is a location on a widget's map.
+type widgetDot struct {
+	X, Y, R float64
+	Color   string
+	Count   int
+}
+
+// widgetHandler serves a widget for the "lat" and "lng" parameters, with
+// the crimes within the "radius" parameter, in miles.
+func widgetHandler(w http.ResponseWriter, r *http.Request) {
+	query, err := parsePoint(r.URL.Query().Get("lat"), r.URL.Query().Get("lng"))
+	if err != nil {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	radius := math.Min(WIDGET_RADIUS, limits.MaxRadiusMiles)
+	if value := r.URL.Query().Get("radius"); value != "" {
+		radius, err = strconv.ParseFloat(value, 64)
+		if err != nil || !(radius > 0) {
+			http.Error(w, http.StatusText(400), 400)
+			return
+		}
+		if err := limits.checkRadius(radius); err != nil {
+			writeLimitError(w, err)
+			return
+		}
+	}
+	nearby, err := finderFor(r).FindWithinRadius(query, radius)
+	if err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	tracker.Record(query.Lat, query.Lng, time.Now())
+	language := requestLanguage(r)
+
+	type category struct {
+		Name, Color string
+		Count       int
+	}
+	type recent struct {
+		Type, Date, URL string
+		date            time.Time
+	}
+	half := float64(WIDGET_MAP_SIZE) / 2
+	counts := make(map[string]int)
+	dots := make([]widgetDot, 0)
+	recents := make([]recent, 0)
+	total := 0
+	for _, location := range nearby.Locations {
+		// The location's dot is the color of its most common category.
+		located := make(map[string]int)
+		for _, crime := range location.Crimes {
+			name := radar.Classify(crime.Type).Category
+			located[name] += 1
+			counts[name] += 1
+			date, _ := time.Parse(radar.DATE_LAYOUT, crime.Date)
+			recents = append(recents, recent{translations.Translate(crime.Type, language), crime.Date, radar.Permalink(*baseURL, crime.Id), date})
+		}
+		total += len(location.Crimes)
+		most := radar.OtherCategory
+		for _, name := range radar.Categories {
+			if located[name] > located[most] {
+				most = name
+			}
+		}
+		// The offsets of the location, in miles.
+		dx := (location.Point.Lng - query.Lng) * radar.MILES_PER_DEGREE_LAT * math.Cos(query.Lat*math.Pi/180)
+		dy := (location.Point.Lat - query.Lat) * radar.MILES_PER_DEGREE_LAT
+		dots = append(dots, widgetDot{
+			X:     half + dx/radius*half,
+			Y:     half - dy/radius*half,
+			R:     2 + math.Min(float64(len(location.Crimes)), 16)/2,
+			Color: widgetColors[most],
+			Count: len(location.Crimes),
+		})
+	}
+	sort.SliceStable(recents, func(i, j int) bool { return recents[i].date.After(recents[j].date) })
+	if len(recents) > WIDGET_RECENT {
+		recents = recents[:WIDGET_RECENT]
+	}
+	categories := make([]category, 0, len(radar.Categories))
+	for _, name := range radar.Categories {
+		if counts[name] > 0 {
+			categories = append(categories, category{translations.Translate(name, language), widgetColors[name], counts[name]})
+		}
+	}
+	page := struct {
+		Lang             string
+		Lat, Lng, Radius float64
+		Size             int
+		Half             float64
+		Count            int
+		Dots             []widgetDot
+		Categories       []category
+		Recent           []recent
+	}{pageLanguage(language), query.Lat, query.Lng, radius, WIDGET_MAP_SIZE, half, total, dots, categories, recents}
+	var buf bytes.Buffer
+	if err := widgetPage.Execute(&buf, page); err != nil {
+		http.Error(w, http.StatusText(500), 500)
+		log.Println(err)
+		return
+	}
+	setLanguageHeaders(w, language)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// widgetScriptHandler serves the script that embeds widgets.
+func widgetScriptHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Write([]byte(WIDGET_SCRIPT))
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWidget(t *testing.T) {
+	resp := get(t, "/widget?lat=45.53435699129174&lng=-122.66469510763777&radius=0.25")
+	if resp.Code != 200 || resp.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatal("Wrong status code or Content-Type: ", resp.Code, resp.Header().Get("Content-Type"))
+	}
+	body := resp.Body.String()
+	if !strings.Contains(body, "crimes within 0.25 miles") || !strings.Contains(body, "<svg") || !strings.Contains(body, `href="/crimes/`) {
+		t.Error("Widget is missing its summary, map or links: ", body)
+	}
+	if strings.Count(body, "<li>") == 0 {
+		t.Error("Widget should list crimes: ", body)
+	}
+
+	for _, url := range []string{"/widget?lat=45.5", "/widget?lat=45.5&lng=-122.6&radius=0"} {
+		if resp := get(t, url); resp.Code != 400 {
+			t.Error("Wrong status code for ", url, ": ", resp.Code)
+		}
+	}
+	if resp := get(t, "/widget?lat=45.5&lng=-122.6&radius=6"); resp.Code != 422 {
+		t.Error("Wrong status code for a radius over the limit: ", resp.Code)
+	}
+
+	resp = get(t, "/widget.js")
+	if resp.Code != 200 || !strings.Contains(resp.Body.String(), "iframe") {
+		t.Error("Wrong widget script: ", resp.Code)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/abrookins/radar/crimes"
+)
+
+var yearsDir = flag.String("years", "", "directory of data split by year with -save-years to load instead of a data file, keeping only the most recent years in memory and loading others when a search asks for them")
+var residentYears = flag.Int("resident-years", 2, "number of the most recent years of -years to keep in memory")
+var yearIdle = flag.Duration("year-idle", 10*time.Minute, "how long a year of -years that isn't resident stays in memory after a search last asked for it")
+var saveYearsDir = flag.String("save-years", "", "directory to save the loaded data to, split by year, for -years")
+
+// YEAR_EVICT_INTERVAL is how often years that searches stopped asking for
+// are unloaded.
+const YEAR_EVICT_INTERVAL = time.Minute
+
+// partitions holds the data split by year, if it was loaded with -years.
+var partitions *radar.YearPartitions
+
+// yearKey is the context key of the finder of a year that isn't resident,
+// which a request for a month in that year searches.
+const yearKey contextKey = 3
+
+// requestedYear returns the year that r asks about, with a month like
+// "2011-07", and false if it doesn't ask about one.
+func requestedYear(r *http.Request) (int, bool) {
+	month, err := time.Parse(radar.MONTH_LAYOUT, r.URL.Query().Get("month"))
+	if err != nil {
+		return 0, false
+	}
+	return month.Year(), true
+}
+
+// loadYears is middleware that loads the year a request asks about before
+// it's handled, if the data is split by year, and has the request search
+// it. Loading a year doesn't change the finder other requests search, or
+// the dataset version.
+func loadYears(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if year, ok := requestedYear(r); ok && partitions != nil {
+			loaded, err := partitions.Year(year)
+			if err != nil {
+				log.Println("Could not load years. ", err)
+			} else if loaded != nil {
+				r = r.WithContext(context.WithValue(r.Context(), yearKey, loaded))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// evictYears unloads years that searches stopped asking for every
+// interval.
+func evictYears(interval time.Duration) {
+	for range time.Tick(interval) {
+		if partitions.Evict(*yearIdle) {
+			log.Println("Serving crimes from", partitions.Loaded())
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/abrookins/radar/crimes"
+	"github.com/abrookins/radar/crimes/sample"
+)
+
+func TestLoadYears(t *testing.T) {
+	defer func() {
+		partitions = nil
+		finder = sample.NewFinder()
+		updateDatasetVersion()
+	}()
+	csv := `Record ID,Report Date,Report Time,Major Offense Type,Address,Neighborhood,Police Precinct,Police District,X Coordinate,Y Coordinate
+1,03/01/2009,01:00:00,Burglary,,LLOYD,PORTLAND PREC NO,690,45.5343,-122.6646
+2,07/01/2010,01:00:00,Burglary,,LLOYD,PORTLAND PREC NO,690,45.5343,-122.6646
+3,11/01/2011,01:00:00,Burglary,,LLOYD,PORTLAND PREC NO,690,45.5343,-122.6646
+`
+	all, err := radar.NewCrimeFinderFromReader(strings.NewReader(csv), radar.LoadOptions{})
+	if err != nil {
+		t.Fatal("Error creating CrimeFinder: ", err)
+	}
+	dir := filepath.Join(t.TempDir(), "years")
+	if _, err := all.SaveYearPartitions(dir); err != nil {
+		t.Fatal("Error saving years: ", err)
+	}
+	if partitions, err = radar.OpenYearPartitions(dir, 1); err != nil {
+		t.Fatal("Error opening years: ", err)
+	}
+	finder = partitions.Finder()
+	updateDatasetVersion()
+	version := currentDatasetVersion()
+
+	url := "/crimes/near/45.5343/-122.6646?month=2009-03&if_version=" + version
+	if resp := get(t, url); resp.Code != 200 || !strings.Contains(string(data(t, resp)), `"id":1`) {
+		t.Error("A search for a month should load its year: ", resp.Code)
+	}
+	if loaded := partitions.Loaded(); !reflect.DeepEqual(loaded, []int{2009, 2011}) {
+		t.Error("Wrong years loaded: ", loaded)
+	}
+	if crime, _ := finder.FindByID(2); crime != nil {
+		t.Error("A year no search asked for should not be loaded")
+	}
+	if crime, _ := finder.FindByID(1); crime != nil || currentDatasetVersion() != version {
+		t.Error("Loading a year should not change the finder other searches use")
+	}
+	if resp := get(t, "/crimes/near/45.5343/-122.6646?if_version="+version); resp.Code != 200 || strings.Contains(string(data(t, resp)), `"id":1`) {
+		t.Error("A search without a month should search the resident years: ", resp.Code)
+	}
+}